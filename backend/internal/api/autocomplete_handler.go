@@ -0,0 +1,110 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/parsing"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/query"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/tables"
+)
+
+// defaultAutocompleteAttributes caps the attributes returned, most frequent first
+const defaultAutocompleteAttributes = 500
+
+// AutocompleteAttribute is an attribute key with the expression reading it
+// as its observed type
+type AutocompleteAttribute struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Expression string `json:"expression"`
+	Count      int64  `json:"count"`
+}
+
+// AutocompleteHandler serves the metadata the SQL editor completes from
+type AutocompleteHandler struct {
+	db     *database.DB
+	schema *parsing.SchemaRegistry
+}
+
+// NewAutocompleteHandler creates a new autocomplete handler
+func NewAutocompleteHandler(db *database.DB, schema *parsing.SchemaRegistry) *AutocompleteHandler {
+	return &AutocompleteHandler{
+		db:     db,
+		schema: schema,
+	}
+}
+
+// GetMetadata returns the tables with their columns, the attribute keys
+// seen during ingestion, ClickHouse functions and keywords. It is built
+// from the live registries on each request; the ETag lets editors poll
+// cheaply. Supports ?attribute_limit=.
+func (h *AutocompleteHandler) GetMetadata(w http.ResponseWriter, r *http.Request) {
+	queryEngine := h.db.GetQueryEngine()
+	if queryEngine == nil {
+		http.Error(w, "Query engine not available", http.StatusInternalServerError)
+		return
+	}
+
+	limit := defaultAutocompleteAttributes
+	if n, err := strconv.Atoi(r.URL.Query().Get("attribute_limit")); err == nil && n > 0 {
+		limit = n
+	}
+
+	attributes := []AutocompleteAttribute{}
+	for _, attr := range h.schema.List("") {
+		if len(attributes) >= limit {
+			break
+		}
+		attributes = append(attributes, AutocompleteAttribute{
+			Name:       attr.Name,
+			Type:       attr.Type,
+			Expression: attributeExpression(attr.Name, attr.Type),
+			Count:      attr.Count,
+		})
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"tables":        queryEngine.GetTables().List(),
+		"default_table": tables.DefaultTable,
+		"attributes":    attributes,
+		"functions":     query.Functions(),
+		"keywords":      query.SQLKeywords,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	hash := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(hash[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// attributeExpression reads an attribute from the String map as its type
+func attributeExpression(name, attrType string) string {
+	value := fmt.Sprintf("attributes['%s']", strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(name))
+	switch attrType {
+	case "number":
+		return "toFloat64OrZero(" + value + ")"
+	case "boolean":
+		return "(" + value + " = 'true')"
+	case "timestamp":
+		return "parseDateTime64BestEffortOrNull(" + value + ")"
+	default:
+		return value
+	}
+}
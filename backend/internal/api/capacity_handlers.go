@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/monitoring"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/storage"
+)
+
+// defaultForecastDays is the horizon of a capacity forecast when none is
+// asked for
+const defaultForecastDays = 30
+
+// GetCapacityForecast returns the disk usage projected over ?days=, 30 by
+// default, and when the disk fills up
+func GetCapacityForecast(forecaster *storage.CapacityForecaster) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if forecaster == nil {
+			http.Error(w, "capacity forecasting is not available", http.StatusServiceUnavailable)
+			return
+		}
+
+		days := defaultForecastDays
+		if value := r.URL.Query().Get("days"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > storage.MaxForecastDays {
+				http.Error(w, "days must be between 1 and "+strconv.Itoa(storage.MaxForecastDays), http.StatusBadRequest)
+				return
+			}
+			days = n
+		}
+
+		forecast, err := forecaster.Forecast(days)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to forecast capacity")
+			http.Error(w, "Failed to forecast capacity", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"forecast":  forecast,
+			"timestamp": time.Now().UTC(),
+		})
+	}
+}
+
+// ListCapacityRules returns the capacity rules
+func ListCapacityRules(manager *monitoring.AlertManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rules := manager.CapacityRules()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"rules": rules,
+			"total": len(rules),
+		})
+	}
+}
+
+// CreateCapacityRule adds an alert rule firing when the disk is forecast
+// to fill up within a number of days
+func CreateCapacityRule(manager *monitoring.AlertManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := auth.UserFromContext(r.Context())
+		if !user.IsAdmin() {
+			http.Error(w, "only admins can add capacity rules", http.StatusForbidden)
+			return
+		}
+
+		var rule monitoring.CapacityRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		rule.CreatedBy = user.ID
+		if err := manager.AddCapacityRule(&rule); err != nil {
+			http.Error(w, err.Error(), ruleSetErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(rule)
+	}
+}
+
+// DeleteCapacityRule removes a capacity rule, resolving its alert
+func DeleteCapacityRule(manager *monitoring.AlertManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.UserFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "only admins can delete capacity rules", http.StatusForbidden)
+			return
+		}
+
+		if err := manager.DeleteCapacityRule(chi.URLParam(r, "name")); err != nil {
+			http.Error(w, err.Error(), ruleSetErrorStatus(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/dashboard"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// ListDashboardAnnotations lists the annotations of a dashboard over the
+// time range given by relative, or start and end, else the dashboard's.
+// ?tags=a,b keeps those with one of the tags.
+func ListDashboardAnnotations(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		timeRange, err := widgetTimeRange(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var tags []string
+		if raw := r.URL.Query().Get("tags"); raw != "" {
+			tags = strings.Split(raw, ",")
+		}
+
+		annotations, err := service.ListAnnotations(r.Context(), chi.URLParam(r, "id"), timeRange, tags)
+		if err != nil {
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"annotations": annotations,
+			"count":       len(annotations),
+		})
+	}
+}
+
+// CreateDashboardAnnotation adds an event or deploy annotation to a dashboard
+func CreateDashboardAnnotation(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var annotation models.Annotation
+		if err := json.NewDecoder(r.Body).Decode(&annotation); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		annotation.DashboardID = chi.URLParam(r, "id")
+
+		createAnnotation(service, w, r, &annotation)
+	}
+}
+
+// CreateAnnotation records an annotation, e.g. a deploy marker sent by a
+// CI pipeline. Without a dashboard_id it appears on every dashboard.
+func CreateAnnotation(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var annotation models.Annotation
+		if err := json.NewDecoder(r.Body).Decode(&annotation); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		createAnnotation(service, w, r, &annotation)
+	}
+}
+
+// ReceiveAlertmanagerWebhook records the alerts of an Alertmanager webhook
+// receiver as annotations of every dashboard. Admins may call it, as may
+// callers presenting token as a bearer token when it is set, which is how
+// Alertmanager's http_config authorization sends credentials.
+func ReceiveAlertmanagerWebhook(service *dashboard.Service, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.UserFromContext(r.Context()).IsAdmin() && !validBearerToken(r, token) {
+			http.Error(w, "only admins or the webhook token can send alerts", http.StatusForbidden)
+			return
+		}
+
+		var webhook dashboard.AlertmanagerWebhook
+		if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		annotations, err := service.RecordAlertmanagerAlerts(&webhook)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"annotations": annotations,
+			"count":       len(annotations),
+		})
+	}
+}
+
+// validBearerToken reports whether the request carries token as a bearer
+// token. An empty token is never valid.
+func validBearerToken(r *http.Request, token string) bool {
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// DeleteAnnotation deletes an annotation
+func DeleteAnnotation(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		annotationID := chi.URLParam(r, "id")
+		if err := service.DeleteAnnotation(r.Context(), annotationID); err != nil {
+			log.Error().Err(err).Str("annotation_id", annotationID).Msg("Failed to delete annotation")
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func createAnnotation(service *dashboard.Service, w http.ResponseWriter, r *http.Request, annotation *models.Annotation) {
+	if err := service.CreateAnnotation(r.Context(), annotation); err != nil {
+		log.Error().Err(err).Str("dashboard_id", annotation.DashboardID).Msg("Failed to create annotation")
+		http.Error(w, err.Error(), dashboardErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(annotation)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/dashboard"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// GetWidgetDrillDown returns the log search link behind a clicked series
+// or point of a widget: value is the clicked dimension and time, RFC3339,
+// the clicked time bucket. The time picker parameters apply as they do to
+// the widget's data.
+func GetWidgetDrillDown(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dashboardID := chi.URLParam(r, "dashboard_id")
+		widgetID := chi.URLParam(r, "widget_id")
+
+		dashboardObj, err := service.GetDashboard(r.Context(), dashboardID)
+		if err != nil {
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+		var targetWidget *models.DashboardWidget
+		for i := range dashboardObj.Widgets {
+			if dashboardObj.Widgets[i].ID == widgetID {
+				targetWidget = &dashboardObj.Widgets[i]
+				break
+			}
+		}
+		if targetWidget == nil {
+			http.Error(w, "Widget not found", http.StatusNotFound)
+			return
+		}
+
+		var at *time.Time
+		if clicked := r.URL.Query().Get("time"); clicked != "" {
+			t, err := time.Parse(time.RFC3339, clicked)
+			if err != nil {
+				http.Error(w, "time must be an RFC3339 time", http.StatusBadRequest)
+				return
+			}
+			at = &t
+		}
+
+		timeRange, err := widgetTimeRange(r)
+		if err == nil {
+			timeRange, err = service.DashboardTimeRange(dashboardObj, timeRange)
+		}
+		if err == nil {
+			targetWidget, err = service.ApplyTimeRange(targetWidget, timeRange)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		link, err := service.DrillDown(targetWidget, timeRange, r.URL.Query().Get("value"), at)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(link)
+	}
+}
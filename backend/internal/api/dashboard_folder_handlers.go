@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/dashboard"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// ListDashboardFolders lists the folders accessible to the user
+func ListDashboardFolders(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		folders := service.ListFolders(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"folders": folders,
+			"count":   len(folders),
+		})
+	}
+}
+
+// CreateDashboardFolder creates a folder
+func CreateDashboardFolder(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var folder models.DashboardFolder
+		if err := json.NewDecoder(r.Body).Decode(&folder); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := service.CreateFolder(r.Context(), &folder); err != nil {
+			log.Error().Err(err).Msg("Failed to create dashboard folder")
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(folder)
+	}
+}
+
+// GetDashboardFolder retrieves a folder by ID
+func GetDashboardFolder(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		folder, err := service.GetFolder(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(folder)
+	}
+}
+
+// UpdateDashboardFolder renames, moves or reshares a folder
+func UpdateDashboardFolder(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		folderID := chi.URLParam(r, "id")
+
+		var updates map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		folder, err := service.UpdateFolder(r.Context(), folderID, updates)
+		if err != nil {
+			log.Error().Err(err).Str("folder_id", folderID).Msg("Failed to update dashboard folder")
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(folder)
+	}
+}
+
+// DeleteDashboardFolder deletes an empty folder
+func DeleteDashboardFolder(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		folderID := chi.URLParam(r, "id")
+
+		if err := service.DeleteFolder(r.Context(), folderID); err != nil {
+			log.Error().Err(err).Str("folder_id", folderID).Msg("Failed to delete dashboard folder")
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetDashboardFolderPermissions returns the roles granted on a folder and
+// the caller's own role
+func GetDashboardFolderPermissions(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		folderID := chi.URLParam(r, "id")
+
+		folder, err := service.GetFolder(r.Context(), folderID)
+		if err != nil {
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+		role, err := service.FolderRole(r.Context(), folderID)
+		if err != nil {
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		permissions := folder.Permissions
+		if permissions == nil {
+			permissions = []models.DashboardPermission{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"role":        role,
+			"permissions": permissions,
+		})
+	}
+}
+
+// SetDashboardFolderPermissions replaces the roles granted on a folder
+func SetDashboardFolderPermissions(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		folderID := chi.URLParam(r, "id")
+
+		var req struct {
+			Permissions []models.DashboardPermission `json:"permissions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		updated, err := service.SetFolderPermissions(r.Context(), folderID, req.Permissions)
+		if err != nil {
+			log.Error().Err(err).Str("folder_id", folderID).Msg("Failed to set dashboard folder permissions")
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(updated)
+	}
+}
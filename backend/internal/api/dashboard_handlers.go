@@ -2,7 +2,9 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -21,11 +23,9 @@ func CreateDashboard(service *dashboard.Service) http.HandlerFunc {
 			return
 		}
 
-		userID := getUserID(r) // TODO: Get from authentication context
-
-		if err := service.CreateDashboard(r.Context(), &dashboardReq, userID); err != nil {
+		if err := service.CreateDashboard(r.Context(), &dashboardReq); err != nil {
 			log.Error().Err(err).Msg("Failed to create dashboard")
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
 			return
 		}
 
@@ -42,12 +42,11 @@ func GetDashboard(service *dashboard.Service) http.HandlerFunc {
 			http.Error(w, "Dashboard ID required", http.StatusBadRequest)
 			return
 		}
-		userID := getUserID(r)
 
-		dashboard, err := service.GetDashboard(r.Context(), dashboardID, userID)
+		dashboard, err := service.GetDashboard(r.Context(), dashboardID)
 		if err != nil {
 			log.Error().Err(err).Str("dashboard_id", dashboardID).Msg("Failed to get dashboard")
-			http.Error(w, err.Error(), http.StatusNotFound)
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
 			return
 		}
 
@@ -71,16 +70,14 @@ func UpdateDashboard(service *dashboard.Service) http.HandlerFunc {
 			return
 		}
 
-		userID := getUserID(r)
-
-		if err := service.UpdateDashboard(r.Context(), dashboardID, updates, userID); err != nil {
+		if err := service.UpdateDashboard(r.Context(), dashboardID, updates); err != nil {
 			log.Error().Err(err).Str("dashboard_id", dashboardID).Msg("Failed to update dashboard")
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
 			return
 		}
 
 		// Return updated dashboard
-		updatedDashboard, _ := service.GetDashboard(r.Context(), dashboardID, userID)
+		updatedDashboard, _ := service.GetDashboard(r.Context(), dashboardID)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(updatedDashboard)
 	}
@@ -95,11 +92,9 @@ func DeleteDashboard(service *dashboard.Service) http.HandlerFunc {
 			return
 		}
 
-		userID := getUserID(r)
-
-		if err := service.DeleteDashboard(r.Context(), dashboardID, userID); err != nil {
+		if err := service.DeleteDashboard(r.Context(), dashboardID); err != nil {
 			log.Error().Err(err).Str("dashboard_id", dashboardID).Msg("Failed to delete dashboard")
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
 			return
 		}
 
@@ -107,18 +102,37 @@ func DeleteDashboard(service *dashboard.Service) http.HandlerFunc {
 	}
 }
 
-// ListDashboards lists dashboards accessible to the user
+// ListDashboards lists dashboards accessible to the user. With ?folder_id=
+// only those directly in that folder are listed, the top level ones when empty.
 func ListDashboards(service *dashboard.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		userID := getUserID(r)
-
-		dashboards, err := service.ListDashboards(r.Context(), userID)
+		dashboards, err := service.ListDashboards(r.Context())
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to list dashboards")
 			http.Error(w, "Failed to list dashboards", http.StatusInternalServerError)
 			return
 		}
 
+		if query := r.URL.Query(); query.Has("folder_id") {
+			folderID := query.Get("folder_id")
+			inFolder := make([]*models.Dashboard, 0, len(dashboards))
+			for _, d := range dashboards {
+				if d.FolderID == folderID {
+					inFolder = append(inFolder, d)
+				}
+			}
+			dashboards = inFolder
+		}
+		if r.URL.Query().Get("templates_only") == "true" {
+			templates := make([]*models.Dashboard, 0, len(dashboards))
+			for _, d := range dashboards {
+				if d.IsTemplate {
+					templates = append(templates, d)
+				}
+			}
+			dashboards = templates
+		}
+
 		if dashboards == nil {
 			dashboards = []*models.Dashboard{}
 		}
@@ -133,6 +147,70 @@ func ListDashboards(service *dashboard.Service) http.HandlerFunc {
 	}
 }
 
+// MoveDashboard moves a dashboard into another folder
+func MoveDashboard(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dashboardID := chi.URLParam(r, "id")
+		if dashboardID == "" {
+			http.Error(w, "Dashboard ID required", http.StatusBadRequest)
+			return
+		}
+
+		var moveReq struct {
+			FolderID string `json:"folder_id"` // top level when empty
+		}
+		if err := json.NewDecoder(r.Body).Decode(&moveReq); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		moved, err := service.MoveDashboard(r.Context(), dashboardID, moveReq.FolderID)
+		if err != nil {
+			log.Error().Err(err).Str("dashboard_id", dashboardID).Msg("Failed to move dashboard")
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(moved)
+	}
+}
+
+// CloneDashboard copies a dashboard into a new one owned by the user,
+// instantiating templates with the chosen variables
+func CloneDashboard(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dashboardID := chi.URLParam(r, "id")
+		if dashboardID == "" {
+			http.Error(w, "Dashboard ID required", http.StatusBadRequest)
+			return
+		}
+
+		var cloneReq struct {
+			Name     string  `json:"name,omitempty"`
+			FolderID  *string           `json:"folder_id,omitempty"` // the original's folder when omitted
+			Variables map[string]string `json:"variables,omitempty"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&cloneReq); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		clone, err := service.CloneDashboard(r.Context(), dashboardID, cloneReq.Name, cloneReq.FolderID, cloneReq.Variables)
+		if err != nil {
+			log.Error().Err(err).Str("dashboard_id", dashboardID).Msg("Failed to clone dashboard")
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(clone)
+	}
+}
+
 // ExecuteWidgetQuery executes a query for a specific widget
 func ExecuteWidgetQuery(service *dashboard.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -144,12 +222,10 @@ func ExecuteWidgetQuery(service *dashboard.Service) http.HandlerFunc {
 			return
 		}
 
-		userID := getUserID(r)
-
 		// Get dashboard
-		dashboardObj, err := service.GetDashboard(r.Context(), dashboardID, userID)
+		dashboardObj, err := service.GetDashboard(r.Context(), dashboardID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
 			return
 		}
 
@@ -167,9 +243,27 @@ func ExecuteWidgetQuery(service *dashboard.Service) http.HandlerFunc {
 			return
 		}
 
+		// The dashboard time range, or the picker's, replaces the widget's own
+		timeRange, err := widgetTimeRange(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if timeRange, err = service.DashboardTimeRange(dashboardObj, timeRange); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if targetWidget, err = service.ApplyTimeRange(targetWidget, timeRange); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		// Execute widget query
 		result, err := service.ExecuteWidgetQuery(r.Context(), targetWidget)
 		if err != nil {
+			if writeAdmissionError(w, err) {
+				return
+			}
 			log.Error().Err(err).
 				Str("dashboard_id", dashboardID).
 				Str("widget_id", widgetID).
@@ -194,12 +288,10 @@ func GetWidgetData(service *dashboard.Service) http.HandlerFunc {
 			return
 		}
 
-		userID := getUserID(r)
-
 		// Get dashboard
-		dashboardObj, err := service.GetDashboard(r.Context(), dashboardID, userID)
+		dashboardObj, err := service.GetDashboard(r.Context(), dashboardID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
 			return
 		}
 
@@ -217,9 +309,45 @@ func GetWidgetData(service *dashboard.Service) http.HandlerFunc {
 			return
 		}
 
-		// Generate widget data
-		data, err := service.GenerateWidgetData(r.Context(), targetWidget)
+		// The dashboard time range, or the picker's, replaces the widget's own
+		timeRange, err := widgetTimeRange(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if timeRange, err = service.DashboardTimeRange(dashboardObj, timeRange); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		applied, err := service.ApplyTimeRange(targetWidget, timeRange)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Text widgets with content are filled in and rendered as markdown
+		if targetWidget.Type == "text" && targetWidget.Config.Content != "" {
+			text, err := service.TextWidgetData(r.Context(), dashboardObj, targetWidget, timeRange)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"widget_id": widgetID,
+				"type":      targetWidget.Type,
+				"data":      text,
+			})
+			return
+		}
+
+		// Generate widget data, or serve it from the cache unless ?refresh=true
+		refresh := r.URL.Query().Get("refresh") == "true"
+		data, cache, err := service.CachedWidgetData(r.Context(), targetWidget, timeRange, refresh)
 		if err != nil {
+			if writeAdmissionError(w, err) {
+				return
+			}
 			log.Error().Err(err).
 				Str("dashboard_id", dashboardID).
 				Str("widget_id", widgetID).
@@ -232,6 +360,18 @@ func GetWidgetData(service *dashboard.Service) http.HandlerFunc {
 			"widget_id": widgetID,
 			"type":      targetWidget.Type,
 			"data":      data,
+			"cache":     cache,
+		}
+
+		// Annotations are drawn over the data, which is still shown without them
+		annotations, err := service.WidgetAnnotations(r.Context(), dashboardID, applied, timeRange)
+		if err != nil {
+			log.Warn().Err(err).
+				Str("dashboard_id", dashboardID).
+				Str("widget_id", widgetID).
+				Msg("Failed to load widget annotations")
+		} else if annotations != nil {
+			response["annotations"] = annotations
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -239,6 +379,63 @@ func GetWidgetData(service *dashboard.Service) http.HandlerFunc {
 	}
 }
 
+// GetDashboardData runs all widgets of a dashboard, or those in
+// ?widgets=a,b, in one request. Widgets whose query fails carry their
+// error, so one failing widget does not fail the dashboard.
+func GetDashboardData(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dashboardID := chi.URLParam(r, "id")
+
+		var widgetIDs []string
+		if raw := r.URL.Query().Get("widgets"); raw != "" {
+			widgetIDs = strings.Split(raw, ",")
+		}
+		timeRange, err := widgetTimeRange(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		refresh := r.URL.Query().Get("refresh") == "true"
+		widgets, timeRange, err := service.DashboardWidgetData(r.Context(), dashboardID, timeRange, widgetIDs, refresh)
+		if err != nil {
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"dashboard_id": dashboardID,
+			"time_range":   timeRange,
+			"widgets":      widgets,
+		})
+	}
+}
+
+// widgetTimeRange reads the time range picked for a dashboard from the
+// relative, or start and end (RFC3339), query parameters. It returns nil
+// when none are given.
+func widgetTimeRange(r *http.Request) (*models.QueryTimeRange, error) {
+	query := r.URL.Query()
+	if relative := query.Get("relative"); relative != "" {
+		return &models.QueryTimeRange{Relative: relative}, nil
+	}
+	start, end := query.Get("start"), query.Get("end")
+	if start == "" && end == "" {
+		return nil, nil
+	}
+
+	timeRange := &models.QueryTimeRange{}
+	var err error
+	if timeRange.Start, err = time.Parse(time.RFC3339, start); err != nil {
+		return nil, fmt.Errorf("start must be an RFC3339 time")
+	}
+	if timeRange.End, err = time.Parse(time.RFC3339, end); err != nil {
+		return nil, fmt.Errorf("end must be an RFC3339 time")
+	}
+	return timeRange, nil
+}
+
 // ShareDashboard creates a share link for a dashboard
 func ShareDashboard(service *dashboard.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -258,8 +455,6 @@ func ShareDashboard(service *dashboard.Service) http.HandlerFunc {
 			return
 		}
 
-		userID := getUserID(r)
-
 		// Parse expiration time if provided
 		var expiresAt *time.Time
 		if shareReq.ExpiresAt != nil {
@@ -268,10 +463,10 @@ func ShareDashboard(service *dashboard.Service) http.HandlerFunc {
 			}
 		}
 
-		share, err := service.ShareDashboard(r.Context(), dashboardID, shareReq.Permissions, expiresAt, userID)
+		share, err := service.ShareDashboard(r.Context(), dashboardID, shareReq.Permissions, expiresAt)
 		if err != nil {
 			log.Error().Err(err).Str("dashboard_id", dashboardID).Msg("Failed to share dashboard")
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
 			return
 		}
 
@@ -291,6 +486,7 @@ func GetSharedDashboard(service *dashboard.Service) http.HandlerFunc {
 
 
 		dashboard, err := service.GetDashboardByShareToken(r.Context(), shareToken)
+		recordShareAccess(service, r, shareToken, "dashboard", err)
 		if err != nil {
 			log.Error().Err(err).Str("share_token", shareToken).Msg("Failed to get shared dashboard")
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -302,10 +498,72 @@ func GetSharedDashboard(service *dashboard.Service) http.HandlerFunc {
 	}
 }
 
-// getUserID extracts user ID from request context
-// TODO: Implement proper authentication and extract from JWT/session
-func getUserID(r *http.Request) string {
-	// For now, return a default user ID
-	// In production, this would extract from JWT token or session
-	return "default-user"
-}
\ No newline at end of file
+// GetDashboardPermissions returns the roles granted on a dashboard and the
+// caller's own role
+func GetDashboardPermissions(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dashboardID := chi.URLParam(r, "id")
+
+		dashboardObj, err := service.GetDashboard(r.Context(), dashboardID)
+		if err != nil {
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+		role, err := service.DashboardRole(r.Context(), dashboardID)
+		if err != nil {
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		permissions := dashboardObj.Permissions
+		if permissions == nil {
+			permissions = []models.DashboardPermission{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"role":        role,
+			"permissions": permissions,
+		})
+	}
+}
+
+// SetDashboardPermissions replaces the roles granted on a dashboard
+func SetDashboardPermissions(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dashboardID := chi.URLParam(r, "id")
+
+		var req struct {
+			Permissions []models.DashboardPermission `json:"permissions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		updated, err := service.SetDashboardPermissions(r.Context(), dashboardID, req.Permissions)
+		if err != nil {
+			log.Error().Err(err).Str("dashboard_id", dashboardID).Msg("Failed to set dashboard permissions")
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(updated)
+	}
+}
+
+// dashboardErrorStatus maps dashboard and folder errors to a status code
+func dashboardErrorStatus(err error) int {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "access denied"):
+		return http.StatusForbidden
+	case strings.Contains(msg, "not found"):
+		return http.StatusNotFound
+	case strings.HasPrefix(msg, "failed to"):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
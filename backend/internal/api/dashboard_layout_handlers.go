@@ -0,0 +1,37 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/dashboard"
+)
+
+// GetDashboardLayout returns where the widgets of a dashboard sit on a
+// screen ?width= pixels wide, the breakpoint it falls in, or the widgets'
+// own positions without a width
+func GetDashboardLayout(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		width := 0
+		if raw := r.URL.Query().Get("width"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed < 0 {
+				http.Error(w, "Invalid width", http.StatusBadRequest)
+				return
+			}
+			width = parsed
+		}
+
+		dashboardObj, err := service.GetDashboard(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(service.LayoutFor(dashboardObj, width))
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/dashboard"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/report"
+)
+
+// GetDashboardReport renders a dashboard as a PDF, or a PNG with
+// ?format=png, over the dashboard's time range or the time picker's
+func GetDashboardReport(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dashboardID := chi.URLParam(r, "id")
+
+		format, err := report.ParseFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		timeRange, err := widgetTimeRange(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Rendered in full first so that failures still get an error status
+		var buf bytes.Buffer
+		if err := service.RenderReport(r.Context(), dashboardID, timeRange, format, &buf); err != nil {
+			log.Error().Err(err).Str("dashboard_id", dashboardID).Msg("Failed to render dashboard report")
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		filename := fmt.Sprintf("dashboard_%s_%s.%s", dashboardID, time.Now().UTC().Format("20060102_150405"), format)
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		w.Write(buf.Bytes())
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/dashboard"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// ListDashboardShares lists the share links of a dashboard, including
+// revoked and expired ones not yet cleaned up
+func ListDashboardShares(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shares, err := service.ListShares(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"shares": shares,
+			"total":  len(shares),
+		})
+	}
+}
+
+// UpdateDashboardShare changes the permissions and expiry of a share link.
+// A missing expires_at makes the link never expire.
+func UpdateDashboardShare(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Permissions []string   `json:"permissions"`
+			ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		share, err := service.UpdateShare(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "share_id"), req.Permissions, req.ExpiresAt)
+		if err != nil {
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(share)
+	}
+}
+
+// RevokeDashboardShare stops a share link from working
+func RevokeDashboardShare(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := service.RevokeShare(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "share_id")); err != nil {
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetDashboardShareAccesses returns the audit of a share link's use
+func GetDashboardShareAccesses(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		audit, err := service.ShareAccessAudit(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "share_id"))
+		if err != nil {
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(audit)
+	}
+}
+
+// recordShareAccess audits a request made with a share token, refused
+// with err if not nil
+func recordShareAccess(service *dashboard.Service, r *http.Request, token, resource string, err error) {
+	access := models.ShareAccess{
+		At:         time.Now(),
+		Resource:   resource,
+		RemoteAddr: r.RemoteAddr,
+		UserAgent:  r.UserAgent(),
+	}
+	if err != nil {
+		access.Denied = err.Error()
+	}
+	service.RecordShareAccess(token, access)
+}
+
+// SharedDashboardRoute serves next to the holder of a share link. The
+// request acts on the link's dashboard, whose ID stands in for the token,
+// with the permissions of the link, and is recorded in its audit as
+// resource.
+func SharedDashboardRoute(service *dashboard.Service, resource string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shareToken := chi.URLParam(r, "token")
+		ctx, share, err := service.ShareContext(r.Context(), shareToken)
+		recordShareAccess(service, r, shareToken, resource, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		if routeCtx := chi.RouteContext(ctx); routeCtx != nil {
+			routeCtx.URLParams.Add("id", share.DashboardID)
+			routeCtx.URLParams.Add("dashboard_id", share.DashboardID)
+		}
+		next(w, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/dashboard"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// snapshotSummary describes a snapshot in lists, without its data
+type snapshotSummary struct {
+	ID          string                 `json:"id"`
+	DashboardID string                 `json:"dashboard_id"`
+	Name        string                 `json:"name"`
+	TimeRange   *models.QueryTimeRange `json:"time_range,omitempty"`
+	ShareToken  string                 `json:"share_token"`
+	ExpiresAt   *time.Time             `json:"expires_at,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	CreatedBy   string                 `json:"created_by"`
+}
+
+// CreateDashboardSnapshot runs the widgets of a dashboard once and stores
+// their data behind a share link
+func CreateDashboardSnapshot(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dashboardID := chi.URLParam(r, "id")
+
+		var snapshotReq struct {
+			Name      string                 `json:"name"`
+			TimeRange *models.QueryTimeRange `json:"time_range,omitempty"`
+			ExpiresAt *string                `json:"expires_at,omitempty"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&snapshotReq); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var expiresAt *time.Time
+		if snapshotReq.ExpiresAt != nil {
+			t, err := time.Parse(time.RFC3339, *snapshotReq.ExpiresAt)
+			if err != nil {
+				http.Error(w, "expires_at must be an RFC3339 time", http.StatusBadRequest)
+				return
+			}
+			expiresAt = &t
+		}
+
+		snapshot, err := service.CreateSnapshot(r.Context(), dashboardID, snapshotReq.Name, snapshotReq.TimeRange, expiresAt)
+		if err != nil {
+			log.Error().Err(err).Str("dashboard_id", dashboardID).Msg("Failed to create dashboard snapshot")
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(snapshot)
+	}
+}
+
+// ListDashboardSnapshots lists the snapshots of a dashboard, without their data
+func ListDashboardSnapshots(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snapshots, err := service.ListSnapshots(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		summaries := make([]snapshotSummary, 0, len(snapshots))
+		for _, snapshot := range snapshots {
+			summaries = append(summaries, snapshotSummary{
+				ID:          snapshot.ID,
+				DashboardID: snapshot.DashboardID,
+				Name:        snapshot.Name,
+				TimeRange:   snapshot.TimeRange,
+				ShareToken:  snapshot.ShareToken,
+				ExpiresAt:   snapshot.ExpiresAt,
+				CreatedAt:   snapshot.CreatedAt,
+				CreatedBy:   snapshot.CreatedBy,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"snapshots": summaries,
+			"count":     len(summaries),
+		})
+	}
+}
+
+// GetDashboardSnapshot retrieves a snapshot with its data
+func GetDashboardSnapshot(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snapshot, err := service.GetSnapshot(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snapshot)
+	}
+}
+
+// DeleteDashboardSnapshot deletes a snapshot and its share link
+func DeleteDashboardSnapshot(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snapshotID := chi.URLParam(r, "id")
+		if err := service.DeleteSnapshot(r.Context(), snapshotID); err != nil {
+			log.Error().Err(err).Str("snapshot_id", snapshotID).Msg("Failed to delete dashboard snapshot")
+			http.Error(w, err.Error(), dashboardErrorStatus(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetSharedSnapshot retrieves a snapshot by share token. Its data is
+// served as stored, without running any query.
+func GetSharedSnapshot(service *dashboard.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shareToken := chi.URLParam(r, "token")
+
+		snapshot, err := service.GetSnapshotByShareToken(r.Context(), shareToken)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snapshot)
+	}
+}
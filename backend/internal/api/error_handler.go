@@ -3,13 +3,20 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"sort"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/errors"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/tracing"
 )
 
 // ErrorHandler handles error detection API endpoints
 type ErrorHandler struct {
 	errorDetector *errors.ErrorDetector
+	traceManager  *tracing.TraceManager
 }
 
 // NewErrorHandler creates a new error handler
@@ -19,7 +26,12 @@ func NewErrorHandler(errorDetector *errors.ErrorDetector) *ErrorHandler {
 	}
 }
 
-// GetErrorStats returns error statistics
+// SetTraceManager enables the traces of error groups in their incident view
+func (h *ErrorHandler) SetTraceManager(traceManager *tracing.TraceManager) {
+	h.traceManager = traceManager
+}
+
+// GetErrorStats returns error statistics, the most frequent patterns first
 func (h *ErrorHandler) GetErrorStats(w http.ResponseWriter, r *http.Request) {
 	stats := h.errorDetector.GetErrorStats()
 	
@@ -28,6 +40,12 @@ func (h *ErrorHandler) GetErrorStats(w http.ResponseWriter, r *http.Request) {
 	for _, stat := range stats {
 		statsList = append(statsList, stat)
 	}
+	sort.Slice(statsList, func(i, j int) bool {
+		if statsList[i].Count != statsList[j].Count {
+			return statsList[i].Count > statsList[j].Count
+		}
+		return statsList[i].Key < statsList[j].Key
+	})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -47,6 +65,180 @@ func (h *ErrorHandler) GetErrorAnomalies(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// GetErrorSamples returns the latest logs matching an error pattern, newest
+// first. The pattern is the key of its stats, such as database:QueryError,
+// or a pattern name for all its categories. ?service= keeps the samples of
+// a service and ?limit= caps how many are returned.
+func (h *ErrorHandler) GetErrorSamples(w http.ResponseWriter, r *http.Request) {
+	pattern := chi.URLParam(r, "pattern")
+	samples, ok := h.errorDetector.GetSamples(pattern)
+	if !ok {
+		http.Error(w, "error pattern not found: "+pattern, http.StatusNotFound)
+		return
+	}
+	
+	service := r.URL.Query().Get("service")
+	result := make([]errors.ErrorSample, 0, len(samples))
+	for i := len(samples) - 1; i >= 0; i-- {
+		if service == "" || samples[i].Service == service {
+			result = append(result, samples[i])
+		}
+	}
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 && limit < len(result) {
+		result = result[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pattern": pattern,
+		"samples": result,
+		"count":   len(result),
+	})
+}
+
+// ListErrorGroups returns the errors grouped by message template, the most
+// frequent first. Supports ?service=, ?status=, ?assignee=, ?sort=last_seen
+// for the most recent first and ?limit=, 100 by default.
+func (h *ErrorHandler) ListErrorGroups(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := errors.GroupFilter{
+		Service:  query.Get("service"),
+		Status:   query.Get("status"),
+		Assignee: query.Get("assignee"),
+		Sort:     query.Get("sort"),
+		Limit:    100,
+	}
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = n
+	}
+	groups := h.errorDetector.GetErrorGroups(filter)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"groups": groups,
+		"count":  len(groups),
+	})
+}
+
+// GetErrorGroup returns an error group by fingerprint. Its logs carry the
+// fingerprint in their error_fingerprint attribute.
+func (h *ErrorHandler) GetErrorGroup(w http.ResponseWriter, r *http.Request) {
+	fingerprint := chi.URLParam(r, "fingerprint")
+	group, ok := h.errorDetector.GetErrorGroup(fingerprint)
+	if !ok {
+		http.Error(w, "error group not found: "+fingerprint, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
+
+// GetErrorGroupIncident returns an error group with the summaries of the
+// latest traces it occurred in, newest first, and how many of those traces
+// went through each service. Traces neither cached nor stored are counted
+// as missing.
+func (h *ErrorHandler) GetErrorGroupIncident(w http.ResponseWriter, r *http.Request) {
+	fingerprint := chi.URLParam(r, "fingerprint")
+	group, ok := h.errorDetector.GetErrorGroup(fingerprint)
+	if !ok {
+		http.Error(w, "error group not found: "+fingerprint, http.StatusNotFound)
+		return
+	}
+
+	traces := []*tracing.TraceSummary{}
+	services := make(map[string]int)
+	failedServices := make(map[string]int)
+	missing := len(group.TraceIDs)
+	if h.traceManager != nil {
+		for i := len(group.TraceIDs) - 1; i >= 0; i-- {
+			summary, ok := h.traceManager.GetTraceSummary(group.TraceIDs[i])
+			if !ok {
+				continue
+			}
+			traces = append(traces, summary)
+			for _, service := range summary.Services {
+				services[service]++
+			}
+			for _, service := range summary.FailedServices {
+				failedServices[service]++
+			}
+		}
+		missing -= len(traces)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"group":           group,
+		"traces":          traces,
+		"missing_traces":  missing,
+		"services":        services,
+		"failed_services": failedServices,
+	})
+}
+
+// UpdateErrorGroup changes the status or the assignee of an error group.
+// A group resolved with a resolved_in_version only reopens on occurrences
+// logged by that version or a later one.
+func (h *ErrorHandler) UpdateErrorGroup(w http.ResponseWriter, r *http.Request) {
+	var update errors.GroupUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user := auth.UserFromContext(r.Context())
+	group, err := h.errorDetector.UpdateGroup(chi.URLParam(r, "fingerprint"), update, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), ruleSetErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
+
+// ListServiceBaselines compares the error rate of each service in the
+// current hour with the rates it has on the same hour of previous days and
+// weeks, the most deviating first. ?deviating=true keeps the services whose
+// error rate is unusually high.
+func (h *ErrorHandler) ListServiceBaselines(w http.ResponseWriter, r *http.Request) {
+	baselines := h.errorDetector.GetServiceBaselines()
+	if r.URL.Query().Get("deviating") == "true" {
+		deviating := baselines[:0]
+		for _, baseline := range baselines {
+			if baseline.Deviating {
+				deviating = append(deviating, baseline)
+			}
+		}
+		baselines = deviating
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"baselines": baselines,
+		"count":     len(baselines),
+	})
+}
+
+// GetServiceBaseline returns the error rate baselines of a service
+func (h *ErrorHandler) GetServiceBaseline(w http.ResponseWriter, r *http.Request) {
+	service := chi.URLParam(r, "service")
+	baseline, ok := h.errorDetector.GetServiceBaseline(service)
+	if !ok {
+		http.Error(w, "no logs from service: "+service, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(baseline)
+}
+
 // GetErrorTrends returns error trends over time
 func (h *ErrorHandler) GetErrorTrends(w http.ResponseWriter, r *http.Request) {
 	stats := h.errorDetector.GetErrorStats()
@@ -91,4 +283,89 @@ func (h *ErrorHandler) GetErrorTrends(w http.ResponseWriter, r *http.Request) {
 		"trends":     trends,
 		"categories": len(categories),
 	})
-}
\ No newline at end of file
+}
+
+// ListPatterns returns the error patterns in the order they are matched,
+// with how many logs each matched
+func (h *ErrorHandler) ListPatterns(w http.ResponseWriter, r *http.Request) {
+	patterns := h.errorDetector.Patterns()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"patterns": patterns,
+		"count":    len(patterns),
+	})
+}
+
+// GetPattern returns an error pattern by name
+func (h *ErrorHandler) GetPattern(w http.ResponseWriter, r *http.Request) {
+	pattern, err := h.errorDetector.GetPattern(chi.URLParam(r, "name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pattern)
+}
+
+// CreatePattern adds an error pattern, enabled unless the body says
+// otherwise
+func (h *ErrorHandler) CreatePattern(w http.ResponseWriter, r *http.Request) {
+	user := auth.UserFromContext(r.Context())
+	if !user.IsAdmin() {
+		http.Error(w, "only admins can add error patterns", http.StatusForbidden)
+		return
+	}
+
+	pattern := errors.ErrorPattern{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&pattern); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	pattern.CreatedBy = user.ID
+	if err := h.errorDetector.AddPattern(&pattern); err != nil {
+		http.Error(w, err.Error(), ruleSetErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(pattern)
+}
+
+// UpdatePattern replaces an error pattern; set enabled to false to stop
+// matching it without losing it
+func (h *ErrorHandler) UpdatePattern(w http.ResponseWriter, r *http.Request) {
+	if !auth.UserFromContext(r.Context()).IsAdmin() {
+		http.Error(w, "only admins can change error patterns", http.StatusForbidden)
+		return
+	}
+
+	pattern := errors.ErrorPattern{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&pattern); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.errorDetector.UpdatePattern(chi.URLParam(r, "name"), &pattern); err != nil {
+		http.Error(w, err.Error(), ruleSetErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pattern)
+}
+
+// DeletePattern removes an error pattern
+func (h *ErrorHandler) DeletePattern(w http.ResponseWriter, r *http.Request) {
+	if !auth.UserFromContext(r.Context()).IsAdmin() {
+		http.Error(w, "only admins can delete error patterns", http.StatusForbidden)
+		return
+	}
+
+	if err := h.errorDetector.DeletePattern(chi.URLParam(r, "name")); err != nil {
+		http.Error(w, err.Error(), ruleSetErrorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
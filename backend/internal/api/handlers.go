@@ -8,10 +8,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
 
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/database"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/ingestion"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/monitoring"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/parsing"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/websocket"
 )
@@ -42,12 +46,22 @@ func HealthCheck(db *database.DB) http.HandlerFunc {
 	}
 }
 
-// IngestLogs handles log ingestion with parsing support
-func IngestLogs(db *database.DB) http.HandlerFunc {
+// IngestLogs handles log ingestion with parsing support. The ruleset applied
+// to each log is chosen by the X-API-Key header or the log's service, and
+// registered plugins run after parsing. Logs rejected by validation are
+// counted per rule and handed to the quarantine. The others go through the
+// log processor, which correlates traces, detects errors and records
+// attributes in the schema registry, before they are stored; the services
+// of stored logs are recorded in the absence monitor. The parsing stages
+// record their metrics in metrics.
+func IngestLogs(db *database.DB, ruleSets *parsing.RuleSetRegistry, plugins *parsing.PluginStage, quarantine *parsing.Quarantine, processor *ingestion.LogProcessor, live *websocket.Hub, metrics *monitoring.MetricsCollector, absence *monitoring.AbsenceMonitor) http.HandlerFunc {
 	// Initialize parsing manager with parsers
 	parseManager := parsing.NewManager()
 	parseManager.RegisterParser(parsing.NewJSONParser())
 	parseManager.RegisterParser(parsing.NewRegexParser())
+	if metrics != nil {
+		parseManager.SetMetrics(metrics)
+	}
 	
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Handle both bulk and single log requests
@@ -76,17 +90,22 @@ func IngestLogs(db *database.DB) http.HandlerFunc {
 		successCount := 0
 		parseFailures := 0
 		validationFailures := 0
+		pluginDrops := 0
 		
 		// Check if parsing is enabled
 		enableParsing := requestBody.Options["enable_parsing"]
 		enableValidation := requestBody.Options["enable_validation"]
+		apiKey := r.Header.Get("X-API-Key")
+		ruleSetUsage := make(map[string]int)
 
 		for _, logEntry := range logs {
 			processedLog := &logEntry
+			ruleSet := ruleSets.Resolve(logEntry.Service, apiKey)
 			
 			// Apply parsing if enabled and message looks like it needs parsing
 			if enableParsing && (logEntry.Message != "" && (isJSONLike(logEntry.Message) || needsRegexParsing(logEntry.Message))) {
-				parseResult := parseManager.Parse(logEntry.Message)
+				ruleSetUsage[ruleSet.Name]++
+				parseResult := parseManager.ParseFromSource(logEntry.Message, logEntry.Service, ruleSet.Rules)
 				if parseResult.Success {
 					// Use parsed log instead
 					processedLog = parseResult.Log
@@ -131,21 +150,39 @@ func IngestLogs(db *database.DB) http.HandlerFunc {
 				processedLog.Service = "unknown"
 			}
 			
+			// Run user plugins, which may rewrite or drop the log
+			if plugins.Apply(processedLog) {
+				pluginDrops++
+				continue
+			}
+			
 			// Validate if enabled
 			if enableValidation {
-				rules := parseManager.GetRules()
-				if err := rules.Validate(processedLog); err != nil {
+				// Re-resolve in case parsing revealed the service
+				ruleSet = ruleSets.Resolve(processedLog.Service, apiKey)
+				ruleSet.Rules.NormalizeLevel(processedLog)
+				if err := ruleSet.Rules.Validate(processedLog); err != nil {
 					validationFailures++
+					quarantine.Reject(ruleSet.Name, processedLog, err)
 					log.Debug().Err(err).Msg("Log validation failed")
 					continue // Skip invalid logs
 				}
 			}
 
+			processor.ProcessLog(processedLog)
 			if err := db.InsertLog(ctx, processedLog); err != nil {
 				log.Error().Err(err).Msg("Failed to insert log")
 				continue
 			}
+			if absence != nil {
+				absence.Observe(processedLog.Service)
+			}
 			successCount++
+
+			// Tail it once validated and stored; processedLog may point
+			// at the loop variable
+			tailed := *processedLog
+			live.BroadcastLog(&tailed)
 		}
 
 		response := map[string]interface{}{
@@ -159,6 +196,9 @@ func IngestLogs(db *database.DB) http.HandlerFunc {
 		if validationFailures > 0 {
 			response["validation_failures"] = validationFailures
 		}
+		if pluginDrops > 0 {
+			response["plugin_drops"] = pluginDrops
+		}
 		
 		// Add parsing stats if parsing was used
 		if enableParsing {
@@ -168,6 +208,11 @@ func IngestLogs(db *database.DB) http.HandlerFunc {
 				"success_count": stats.SuccessCount,
 				"failure_count": stats.FailureCount,
 				"parser_usage":  stats.ParserUsage,
+				"ruleset_usage": ruleSetUsage,
+				"format_cache": map[string]int64{
+					"hits":   stats.CacheHits,
+					"misses": stats.CacheMisses,
+				},
 			}
 		}
 
@@ -284,11 +329,42 @@ func StorageStats(db *database.DB) http.HandlerFunc {
 func WebSocketStats(hub *websocket.Hub) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		stats := map[string]interface{}{
-			"active_clients": hub.GetConnectedClients(),
-			"timestamp":      time.Now(),
+			"active_clients":       hub.GetConnectedClients(),
+			"active_subscriptions": hub.GetSubscriptionCount(),
+			"watched_dashboards":   hub.GetDashboardWatchCount(),
+			"replay_buffer":        hub.GetReplayStats(),
+			"live":                 hub.GetLiveStats(),
+			"delivery":             hub.GetDeliveryStats(),
+			"send_queues":          hub.GetSendQueueStats(),
+			"rates":                hub.GetConnectionRates(),
+			"clients":              hub.ListClients(auth.UserFromContext(r.Context())),
+			"timestamp":            time.Now(),
 		}
 		
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(stats)
 	}
-}
\ No newline at end of file
+}
+
+// DisconnectWebSocketClient closes the connection of a WebSocket client
+func DisconnectWebSocketClient(hub *websocket.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.UserFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "only admins can disconnect WebSocket clients", http.StatusForbidden)
+			return
+		}
+		if err := hub.DisconnectClient(chi.URLParam(r, "id")); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// WebSocketSchema returns the JSON schema of the WebSocket messages
+func WebSocketSchema() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/schema+json")
+		w.Write(websocket.ProtocolSchema)
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/mining"
+)
+
+// defaultLogPatternLimit is how many log patterns are listed when no limit
+// is asked for
+const defaultLogPatternLimit = 100
+
+// ListLogPatterns returns the templates discovered in the messages of the
+// logs ingested since the server started, the most frequent first.
+// Supports ?service=, ?level=, ?search= in the template, ?sort=first_seen
+// for the newest patterns first or last_seen, and ?limit=, 100 by default.
+func ListLogPatterns(miner *mining.Miner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := mining.Filter{
+			Service: query.Get("service"),
+			Level:   query.Get("level"),
+			Search:  query.Get("search"),
+			Sort:    query.Get("sort"),
+			Limit:   defaultLogPatternLimit,
+		}
+		switch filter.Sort {
+		case "", "count", "first_seen", "last_seen":
+		default:
+			http.Error(w, "sort must be count, first_seen or last_seen", http.StatusBadRequest)
+			return
+		}
+		if value := query.Get("limit"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+				return
+			}
+			filter.Limit = n
+		}
+
+		patterns, logs := miner.Patterns(filter)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"patterns": patterns,
+			"count":    len(patterns),
+			"logs":     logs,
+		})
+	}
+}
+
+// GetLogPattern returns a log pattern by ID
+func GetLogPattern(miner *mining.Miner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		pattern, ok := miner.Pattern(id)
+		if !ok {
+			http.Error(w, "log pattern not found: "+id, http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(pattern)
+	}
+}
@@ -3,7 +3,13 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/monitoring"
 )
 
@@ -22,6 +28,39 @@ func GetMetrics(collector *monitoring.MetricsCollector) http.HandlerFunc {
 	}
 }
 
+// GetMetricCardinality returns the labeled metrics with the most series
+// first. Supports ?limit= to return only the top ones.
+func GetMetricCardinality(collector *monitoring.MetricsCollector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		top := 0
+		if limit := r.URL.Query().Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n <= 0 {
+				http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+				return
+			}
+			top = n
+		}
+
+		stats := collector.Cardinality(0)
+		total := 0
+		for _, s := range stats {
+			total += s.Series
+		}
+		if top > 0 && len(stats) > top {
+			stats = stats[:top]
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"metrics":      stats,
+			"total_series": total,
+		}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
 // GetAlerts returns all alerts
 func GetAlerts(manager *monitoring.AlertManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -50,4 +89,198 @@ func GetActiveAlerts(manager *monitoring.AlertManager) http.HandlerFunc {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
 	}
-}
\ No newline at end of file
+}
+// GetAlertHistory returns alert state transitions, newest first. Supports
+// ?name=, ?severity=, ?source=, ?since= and ?until= (RFC3339) and ?limit=.
+func GetAlertHistory(manager *monitoring.AlertManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, ok := alertHistoryFilter(w, r)
+		if !ok {
+			return
+		}
+
+		transitions, err := manager.History().List(filter)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list alert history")
+			http.Error(w, "Failed to list alert history", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"transitions": transitions,
+			"count":       len(transitions),
+		})
+	}
+}
+
+// GetAlertTimeline returns when each alert that changed state in a range
+// was pending, fired and resolved. Takes the same parameters as
+// GetAlertHistory; the range is the last 24 hours by default.
+func GetAlertTimeline(manager *monitoring.AlertManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, ok := alertHistoryFilter(w, r)
+		if !ok {
+			return
+		}
+		if filter.Since.IsZero() {
+			filter.Since = time.Now().Add(-24 * time.Hour)
+		}
+
+		episodes, err := manager.History().Timeline(filter)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to build alert timeline")
+			http.Error(w, "Failed to build alert timeline", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"episodes": episodes,
+			"count":    len(episodes),
+			"since":    filter.Since,
+		})
+	}
+}
+
+// alertHistoryFilter reads the alert history filter of a request, writing
+// an error if it is invalid
+func alertHistoryFilter(w http.ResponseWriter, r *http.Request) (monitoring.AlertHistoryFilter, bool) {
+	params := r.URL.Query()
+	filter := monitoring.AlertHistoryFilter{
+		Name:     params.Get("name"),
+		Severity: monitoring.AlertSeverity(params.Get("severity")),
+		Source:   params.Get("source"),
+	}
+	for name, target := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := params.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, name+" must be an RFC3339 time", http.StatusBadRequest)
+				return filter, false
+			}
+			*target = t
+		}
+	}
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return filter, false
+		}
+		filter.Limit = n
+	}
+	return filter, true
+}
+
+// GetIngestionStats returns the state of the ingestion batch queue: the
+// logs waiting, the batches being written, flush latency percentiles and
+// the last write error
+func GetIngestionStats(queue monitoring.BatchQueue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := queue.QueueStats()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"batch_queue":       stats,
+			"flush_interval_ms": stats.FlushInterval.Milliseconds(),
+			"timestamp":         time.Now().UTC(),
+		})
+	}
+}
+
+// ListServiceActivity returns when each service was last heard from and
+// which are silent for longer than they may be, silent services first.
+// ?absent=true lists only those.
+func ListServiceActivity(absence *monitoring.AbsenceMonitor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		services := absence.Services(time.Now())
+		if r.URL.Query().Get("absent") == "true" {
+			absent := services[:0]
+			for _, s := range services {
+				if s.Absent {
+					absent = append(absent, s)
+				}
+			}
+			services = absent
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"services": services,
+			"total":    len(services),
+		})
+	}
+}
+
+// ListAnomalyRules returns the anomaly rules and the baselines they have
+// learnt
+func ListAnomalyRules(manager *monitoring.AlertManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rules := manager.AnomalyRules()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"rules": rules,
+			"total": len(rules),
+		})
+	}
+}
+
+// GetAnomalyRule returns an anomaly rule by name, with its baseline
+func GetAnomalyRule(manager *monitoring.AlertManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rule, err := manager.GetAnomalyRule(chi.URLParam(r, "name"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rule)
+	}
+}
+
+// CreateAnomalyRule adds an alert rule flagging a metric or query series
+// when it deviates from its baseline. Query series run any SQL, so only
+// admins can add rules.
+func CreateAnomalyRule(manager *monitoring.AlertManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := auth.UserFromContext(r.Context())
+		if !user.IsAdmin() {
+			http.Error(w, "only admins can add anomaly rules", http.StatusForbidden)
+			return
+		}
+
+		var rule monitoring.AnomalyRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		rule.CreatedBy = user.ID
+		if err := manager.AddAnomalyRule(&rule); err != nil {
+			http.Error(w, err.Error(), ruleSetErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(rule)
+	}
+}
+
+// DeleteAnomalyRule removes an anomaly rule, resolving its alert
+func DeleteAnomalyRule(manager *monitoring.AlertManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.UserFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "only admins can delete anomaly rules", http.StatusForbidden)
+			return
+		}
+
+		if err := manager.DeleteAnomalyRule(chi.URLParam(r, "name")); err != nil {
+			http.Error(w, err.Error(), ruleSetErrorStatus(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/notification"
+)
+
+// ListNotificationChannels lists the channels alerts can be sent to, with
+// their secrets masked
+func ListNotificationChannels(notifier *notification.Notifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		channels := notifier.ListChannels()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"channels": channels,
+			"total":    len(channels),
+		})
+	}
+}
+
+// GetNotificationChannel returns a notification channel by name
+func GetNotificationChannel(notifier *notification.Notifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		channel, err := notifier.GetChannel(chi.URLParam(r, "name"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(channel)
+	}
+}
+
+// CreateNotificationChannel adds a notification channel
+func CreateNotificationChannel(notifier *notification.Notifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := auth.UserFromContext(r.Context())
+		if !user.IsAdmin() {
+			http.Error(w, "only admins can add notification channels", http.StatusForbidden)
+			return
+		}
+
+		var channel notification.Channel
+		if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		channel.CreatedBy = user.ID
+		if err := notifier.CreateChannel(&channel); err != nil {
+			http.Error(w, err.Error(), notificationErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(channel)
+	}
+}
+
+// UpdateNotificationChannel replaces the configuration of a notification
+// channel. Masked secrets keep their stored values.
+func UpdateNotificationChannel(notifier *notification.Notifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.UserFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "only admins can change notification channels", http.StatusForbidden)
+			return
+		}
+
+		var channel notification.Channel
+		if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := notifier.UpdateChannel(chi.URLParam(r, "name"), &channel); err != nil {
+			http.Error(w, err.Error(), notificationErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(channel)
+	}
+}
+
+// DeleteNotificationChannel removes a notification channel no route uses
+func DeleteNotificationChannel(notifier *notification.Notifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.UserFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "only admins can delete notification channels", http.StatusForbidden)
+			return
+		}
+
+		if err := notifier.DeleteChannel(chi.URLParam(r, "name")); err != nil {
+			http.Error(w, err.Error(), notificationErrorStatus(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// TestNotificationChannel sends a test notification to a stored channel
+func TestNotificationChannel(notifier *notification.Notifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.UserFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "only admins can test notification channels", http.StatusForbidden)
+			return
+		}
+
+		if err := notifier.TestChannel(r.Context(), chi.URLParam(r, "name")); err != nil {
+			http.Error(w, err.Error(), notificationTestErrorStatus(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"sent": true})
+	}
+}
+
+// TestNotificationConfig sends a test notification to the channel in the
+// request body without storing it, so a channel can be tried while it is
+// edited
+func TestNotificationConfig(notifier *notification.Notifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.UserFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "only admins can test notification channels", http.StatusForbidden)
+			return
+		}
+
+		var channel notification.Channel
+		if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := notifier.TestConfig(r.Context(), &channel); err != nil {
+			http.Error(w, err.Error(), notificationTestErrorStatus(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"sent": true})
+	}
+}
+
+// GetNotificationRoutes returns the routes alerts are matched against, in
+// order
+func GetNotificationRoutes(notifier *notification.Notifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		routes := notifier.Routes()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"routes": routes,
+			"total":  len(routes),
+		})
+	}
+}
+
+// SetNotificationRoutes replaces the notification routes
+func SetNotificationRoutes(notifier *notification.Notifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.UserFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "only admins can change notification routes", http.StatusForbidden)
+			return
+		}
+
+		var req struct {
+			Routes []notification.Route `json:"routes"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := notifier.SetRoutes(req.Routes); err != nil {
+			http.Error(w, err.Error(), notificationErrorStatus(err))
+			return
+		}
+
+		routes := notifier.Routes()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"routes": routes,
+			"total":  len(routes),
+		})
+	}
+}
+
+// ListNotificationDeliveries returns the latest notifications sent and
+// their outcome, newest first
+func ListNotificationDeliveries(notifier *notification.Notifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deliveries := notifier.Deliveries()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"deliveries": deliveries,
+			"total":      len(deliveries),
+		})
+	}
+}
+
+// notificationErrorStatus maps notifier errors to HTTP status codes
+func notificationErrorStatus(err error) int {
+	if strings.Contains(err.Error(), "still used") {
+		return http.StatusConflict
+	}
+	return ruleSetErrorStatus(err)
+}
+
+// notificationTestErrorStatus maps the errors of test notifications to
+// HTTP status codes: a channel that could not be reached is a bad gateway
+func notificationTestErrorStatus(err error) int {
+	status := ruleSetErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		return http.StatusBadGateway
+	}
+	return status
+}
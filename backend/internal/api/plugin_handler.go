@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/parsing"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/scripting"
+)
+
+// PluginHandler handles parsing plugin API endpoints
+type PluginHandler struct {
+	stage *parsing.PluginStage
+}
+
+// NewPluginHandler creates a new plugin handler
+func NewPluginHandler(stage *parsing.PluginStage) *PluginHandler {
+	return &PluginHandler{
+		stage: stage,
+	}
+}
+
+// ListPlugins returns all registered plugins
+func (h *PluginHandler) ListPlugins(w http.ResponseWriter, r *http.Request) {
+	plugins := h.stage.List()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"plugins": plugins,
+		"count":   len(plugins),
+	})
+}
+
+// GetPlugin returns a plugin by name
+func (h *PluginHandler) GetPlugin(w http.ResponseWriter, r *http.Request) {
+	plugin, err := h.stage.Get(chi.URLParam(r, "name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plugin)
+}
+
+// SavePlugin creates or replaces a plugin. The name comes from the URL on PUT
+// and from the body on POST.
+func (h *PluginHandler) SavePlugin(w http.ResponseWriter, r *http.Request) {
+	if !auth.UserFromContext(r.Context()).IsAdmin() {
+		http.Error(w, "only admins can change plugins", http.StatusForbidden)
+		return
+	}
+
+	var plugin parsing.Plugin
+	if err := json.NewDecoder(r.Body).Decode(&plugin); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if name := chi.URLParam(r, "name"); name != "" {
+		plugin.Name = name
+	}
+
+	if err := h.stage.Register(&plugin); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodPost {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(&plugin)
+}
+
+// DeletePlugin removes a plugin
+func (h *PluginHandler) DeletePlugin(w http.ResponseWriter, r *http.Request) {
+	if !auth.UserFromContext(r.Context()).IsAdmin() {
+		http.Error(w, "only admins can delete plugins", http.StatusForbidden)
+		return
+	}
+
+	if err := h.stage.Delete(chi.URLParam(r, "name")); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestPlugin runs a script against a sample log without registering it
+func (h *PluginHandler) TestPlugin(w http.ResponseWriter, r *http.Request) {
+	if !auth.UserFromContext(r.Context()).IsAdmin() {
+		http.Error(w, "only admins can test plugins", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		Source string     `json:"source"`
+		Log    models.Log `json:"log"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	script, err := scripting.Compile(req.Source)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Log.Timestamp.IsZero() {
+		req.Log.Timestamp = time.Now()
+	}
+	if req.Log.Attributes == nil {
+		req.Log.Attributes = make(map[string]interface{})
+	}
+
+	response := map[string]interface{}{}
+	dropped, err := parsing.RunPlugin(script, scripting.DefaultLimits(), &req.Log)
+	if err != nil {
+		response["error"] = strings.TrimSpace(err.Error())
+	}
+	response["dropped"] = dropped
+	response["log"] = req.Log
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/parsing"
+)
+
+// QuarantineHandler handles browsing of logs rejected by validation
+type QuarantineHandler struct {
+	quarantine *parsing.Quarantine
+}
+
+// NewQuarantineHandler creates a new quarantine handler
+func NewQuarantineHandler(quarantine *parsing.Quarantine) *QuarantineHandler {
+	return &QuarantineHandler{
+		quarantine: quarantine,
+	}
+}
+
+// ListQuarantined returns quarantined logs matching the query filters
+func (h *QuarantineHandler) ListQuarantined(w http.ResponseWriter, r *http.Request) {
+	if !h.quarantine.Enabled() {
+		http.Error(w, "Quarantine storage is disabled", http.StatusNotFound)
+		return
+	}
+
+	filter := parseQuarantineFilter(r)
+	entries, err := h.quarantine.List(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"logs":   entries,
+		"count":  len(entries),
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+// GetQuarantineStats returns rejection counts per ruleset and rule
+func (h *QuarantineHandler) GetQuarantineStats(w http.ResponseWriter, r *http.Request) {
+	if !h.quarantine.Enabled() {
+		http.Error(w, "Quarantine storage is disabled", http.StatusNotFound)
+		return
+	}
+
+	stats, err := h.quarantine.Stats(parseQuarantineFilter(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"stats": stats,
+	})
+}
+
+func parseQuarantineFilter(r *http.Request) parsing.QuarantineFilter {
+	q := r.URL.Query()
+	filter := parsing.QuarantineFilter{
+		Rule:    q.Get("rule"),
+		RuleSet: q.Get("ruleset"),
+		Service: q.Get("service"),
+		Limit:   100,
+	}
+	if limit, err := strconv.Atoi(q.Get("limit")); err == nil && limit > 0 {
+		filter.Limit = limit
+	}
+	if offset, err := strconv.Atoi(q.Get("offset")); err == nil && offset > 0 {
+		filter.Offset = offset
+	}
+	if since, err := time.Parse(time.RFC3339, q.Get("since")); err == nil {
+		filter.Since = since
+	}
+	return filter
+}
@@ -2,13 +2,19 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
 
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/database"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/export"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/query"
 )
 
@@ -21,9 +27,23 @@ func ExecuteQuery(db *database.DB) http.HandlerFunc {
 			return
 		}
 
+		// CSV, TSV and Parquet are streamed as a download
+		format, download, err := export.QueryResultFormatFor(req.Format, r.Header.Get("Accept"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if download {
+			downloadQueryResults(w, r, db, &req, format)
+			return
+		}
+
 		// Execute query
 		response, err := db.ExecuteQuery(r.Context(), &req)
 		if err != nil {
+			if writeAdmissionError(w, err) {
+				return
+			}
 			log.Error().Err(err).Str("query", req.Query).Msg("Query execution failed")
 			// Return error in response rather than HTTP error
 			response.Error = err.Error()
@@ -34,6 +54,54 @@ func ExecuteQuery(db *database.DB) http.HandlerFunc {
 	}
 }
 
+// downloadWriter sets the download headers on the first write, so errors
+// raised before any data can still be answered with an error status
+type downloadWriter struct {
+	w          http.ResponseWriter
+	format     export.QueryResultFormat
+	started    bool
+	flusher    http.Flusher
+	sinceFlush int
+}
+
+func (d *downloadWriter) Write(p []byte) (int, error) {
+	if !d.started {
+		d.w.Header().Set("Content-Type", d.format.ContentType)
+		d.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`,
+			d.format.FileName("query_results_"+time.Now().Format("20060102_150405"))))
+		d.w.Header().Set("X-Content-Type-Options", "nosniff")
+		d.started = true
+	}
+	n, err := d.w.Write(p)
+	d.sinceFlush += n
+	if d.flusher != nil && d.sinceFlush >= 256*1024 {
+		d.flusher.Flush()
+		d.sinceFlush = 0
+	}
+	return n, err
+}
+
+// downloadQueryResults streams a query result in a download format
+func downloadQueryResults(w http.ResponseWriter, r *http.Request, db *database.DB, req *query.QueryRequest, format export.QueryResultFormat) {
+	dw := &downloadWriter{w: w, format: format}
+	dw.flusher, _ = w.(http.Flusher)
+
+	if err := export.StreamQueryResults(r.Context(), db, req, format, dw); err != nil {
+		log.Error().Err(err).Str("query", req.Query).Str("format", string(format.Format)).Msg("Query download failed")
+		if !dw.started {
+			if !writeAdmissionError(w, err) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			}
+		}
+		// Otherwise the download is cut short; the client sees a truncated file
+		return
+	}
+	if !dw.started {
+		// Empty result, e.g. Parquet writes nothing for zero rows
+		dw.Write(nil)
+	}
+}
+
 // SaveQuery saves a query
 func SaveQuery(db *database.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -43,8 +111,20 @@ func SaveQuery(db *database.DB) http.HandlerFunc {
 			return
 		}
 
-		// TODO: Get user from context
-		savedQuery.CreatedBy = "user"
+		user := auth.UserFromContext(r.Context())
+		savedQuery.ID = ""
+		savedQuery.Shares = nil
+		savedQuery.CreatedBy = user.ID
+		if savedQuery.Visibility == "" {
+			savedQuery.Visibility = query.VisibilityPrivate
+		}
+		if savedQuery.Visibility == query.VisibilityTeam && savedQuery.Team == "" && len(user.Teams) == 1 {
+			savedQuery.Team = user.Teams[0]
+		}
+		if savedQuery.Team != "" && !user.InTeam(savedQuery.Team) && !user.IsAdmin() {
+			http.Error(w, "Not a member of team "+savedQuery.Team, http.StatusForbidden)
+			return
+		}
 
 		queryEngine := db.GetQueryEngine()
 		if queryEngine == nil {
@@ -80,7 +160,7 @@ func GetQuery(db *database.DB) http.HandlerFunc {
 		}
 
 		queryStore := queryEngine.GetQueryStore()
-		savedQuery, err := queryStore.Get(queryID)
+		savedQuery, err := queryStore.GetFor(r.Context(), queryID)
 		if err != nil {
 			http.Error(w, "Query not found", http.StatusNotFound)
 			return
@@ -121,7 +201,7 @@ func ListQueries(db *database.DB) http.HandlerFunc {
 			filters = append(filters, query.TemplateFilter{TemplatesOnly: true})
 		}
 
-		queries, err := queryStore.List(filters...)
+		queries, err := queryStore.ListFor(r.Context(), filters...)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to list queries")
 			http.Error(w, "Failed to list queries", http.StatusInternalServerError)
@@ -164,15 +244,23 @@ func UpdateQuery(db *database.DB) http.HandlerFunc {
 			return
 		}
 
+		if team, ok := updates["team"].(string); ok && team != "" {
+			user := auth.UserFromContext(r.Context())
+			if !user.InTeam(team) && !user.IsAdmin() {
+				http.Error(w, "Not a member of team "+team, http.StatusForbidden)
+				return
+			}
+		}
+
 		queryStore := queryEngine.GetQueryStore()
-		if err := queryStore.Update(queryID, updates); err != nil {
+		if err := queryStore.UpdateFor(r.Context(), queryID, updates); err != nil {
 			log.Error().Err(err).Str("id", queryID).Msg("Failed to update query")
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeQueryStoreError(w, err)
 			return
 		}
 
 		// Return updated query
-		updatedQuery, _ := queryStore.Get(queryID)
+		updatedQuery, _ := queryStore.GetFor(r.Context(), queryID)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(updatedQuery)
 	}
@@ -194,9 +282,9 @@ func DeleteQuery(db *database.DB) http.HandlerFunc {
 		}
 
 		queryStore := queryEngine.GetQueryStore()
-		if err := queryStore.Delete(queryID); err != nil {
+		if err := queryStore.DeleteFor(r.Context(), queryID); err != nil {
 			log.Error().Err(err).Str("id", queryID).Msg("Failed to delete query")
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeQueryStoreError(w, err)
 			return
 		}
 
@@ -221,46 +309,682 @@ func ExecuteSavedQuery(db *database.DB) http.HandlerFunc {
 
 		// Get saved query
 		queryStore := queryEngine.GetQueryStore()
-		savedQuery, err := queryStore.Get(queryID)
+		savedQuery, err := queryStore.GetFor(r.Context(), queryID)
 		if err != nil {
 			http.Error(w, "Query not found", http.StatusNotFound)
 			return
 		}
 
-		// Parse parameters from request
-		var params map[string]interface{}
-		if r.Method == "POST" {
-			if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-				http.Error(w, "Invalid parameters", http.StatusBadRequest)
-				return
+		executeSavedQuery(w, r, db, savedQuery)
+	}
+}
+
+// executeSavedQuery runs a saved query with parameters from the request
+func executeSavedQuery(w http.ResponseWriter, r *http.Request, db *database.DB, savedQuery *query.SavedQuery) {
+	// Parse parameters from request
+	var params map[string]interface{}
+	if r.Method == "POST" {
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Invalid parameters", http.StatusBadRequest)
+			return
+		}
+	} else {
+		// GET request - parse from query string
+		params = make(map[string]interface{})
+		for key, values := range r.URL.Query() {
+			if len(values) > 0 {
+				params[key] = values[0]
 			}
-		} else {
-			// GET request - parse from query string
-			params = make(map[string]interface{})
-			for key, values := range r.URL.Query() {
-				if len(values) > 0 {
-					params[key] = values[0]
+		}
+		// Multi-select parameters repeat, e.g. ?service=api&service=web
+		for _, def := range savedQuery.Parameters {
+			if values := r.URL.Query()[def.Name]; def.Widget == query.WidgetMultiSelect && len(values) > 0 {
+				items := make([]interface{}, len(values))
+				for i, value := range values {
+					items[i] = value
 				}
+				params[def.Name] = items
 			}
 		}
+	}
+
+	// Load dynamic options and check the values before running anything
+	defs, err := db.GetQueryEngine().ResolveParameters(r.Context(), savedQuery.Parameters)
+	if err != nil {
+		if writeAdmissionError(w, err) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := query.ValidateParameters(defs, params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Create query request
+	req := &query.QueryRequest{
+		Query:         savedQuery.Query,
+		Parameters:    params,
+		ParameterDefs: defs,
+	}
 
-		// Create query request
-		req := &query.QueryRequest{
-			Query:      savedQuery.Query,
-			Parameters: params,
+	// Execute query
+	response, err := db.ExecuteQuery(r.Context(), req)
+	if err != nil {
+		if writeAdmissionError(w, err) {
+			return
 		}
+		log.Error().Err(err).Str("query_id", savedQuery.ID).Msg("Failed to execute saved query")
+		response.Error = err.Error()
+	}
 
-		// Execute query
-		response, err := db.ExecuteQuery(r.Context(), req)
+	// Add query metadata to response
+	response.Query = savedQuery.Name // Show query name instead of SQL
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetQueryParameters returns the parameters of a saved query for rendering
+// its form, with widgets chosen and dynamic options loaded
+func GetQueryParameters(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		queryID := chi.URLParam(r, "id")
+		savedQuery, err := queryEngine.GetQueryStore().GetFor(r.Context(), queryID)
 		if err != nil {
-			log.Error().Err(err).Str("query_id", queryID).Msg("Failed to execute saved query")
-			response.Error = err.Error()
+			http.Error(w, "Query not found", http.StatusNotFound)
+			return
+		}
+
+		defs, err := queryEngine.ResolveParameters(r.Context(), savedQuery.Parameters)
+		if err != nil {
+			if writeAdmissionError(w, err) {
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"query_id":   savedQuery.ID,
+			"parameters": defs,
+		})
+	}
+}
+
+// ShareQuery creates a share link for a saved query
+func ShareQuery(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryID := chi.URLParam(r, "id")
+		if queryID == "" {
+			http.Error(w, "Query ID required", http.StatusBadRequest)
+			return
+		}
+
+		var shareReq struct {
+			ExpiresAt *string `json:"expires_at,omitempty"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&shareReq); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var expiresAt *time.Time
+		if shareReq.ExpiresAt != nil {
+			t, err := time.Parse(time.RFC3339, *shareReq.ExpiresAt)
+			if err != nil {
+				http.Error(w, "expires_at must be an RFC3339 time", http.StatusBadRequest)
+				return
+			}
+			expiresAt = &t
+		}
+
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		share, err := queryEngine.GetQueryStore().Share(r.Context(), queryID, expiresAt)
+		if err != nil {
+			log.Error().Err(err).Str("id", queryID).Msg("Failed to share query")
+			writeQueryStoreError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(share)
+	}
+}
+
+// GetSharedQuery retrieves a saved query by share token
+func GetSharedQuery(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		savedQuery, err := queryEngine.GetQueryStore().GetByShareToken(chi.URLParam(r, "token"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
 		}
 
-		// Add query metadata to response
-		response.Query = savedQuery.Name // Show query name instead of SQL
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(savedQuery)
+	}
+}
+
+// ExecuteSharedQuery executes a saved query reached through a share link
+func ExecuteSharedQuery(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		savedQuery, err := queryEngine.GetQueryStore().GetByShareToken(chi.URLParam(r, "token"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		executeSavedQuery(w, r, db, savedQuery)
+	}
+}
+
+// writeQueryStoreError maps saved query store errors to HTTP statuses
+func writeQueryStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, query.ErrQueryAccessDenied):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// writeAdmissionError answers 429 with a Retry-After header if err means the
+// user has too many queries in flight. It reports whether it wrote a response.
+func writeAdmissionError(w http.ResponseWriter, err error) bool {
+	var admissionErr *query.AdmissionError
+	if !errors.As(err, &admissionErr) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(int(admissionErr.RetryAfter.Seconds())))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   admissionErr.Error(),
+		"running": admissionErr.Running,
+		"queued":  admissionErr.Queued,
+		"limit":   admissionErr.Limit,
+	})
+	return true
+}
+
+// SubmitQueryJob starts a query in the background and returns the job
+func SubmitQueryJob(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req query.QueryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		job, err := queryEngine.GetJobManager().Submit(r.Context(), &req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job)
+	}
+}
+
+// ListQueryJobs lists the caller's query jobs
+func ListQueryJobs(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		jobs := queryEngine.GetJobManager().List(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jobs":  jobs,
+			"count": len(jobs),
+		})
+	}
+}
+
+// GetQueryJob returns the status and progress of a query job
+func GetQueryJob(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		job, err := queryEngine.GetJobManager().Get(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job)
+	}
+}
+
+// GetQueryJobResults returns the results of a completed query job
+func GetQueryJobResults(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		jobs := queryEngine.GetJobManager()
+		jobID := chi.URLParam(r, "id")
+		if _, err := jobs.Get(r.Context(), jobID); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		response, err := jobs.Result(r.Context(), jobID)
+		if err != nil {
+			// Not finished yet, failed or cancelled
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
 	}
-}
\ No newline at end of file
+}
+
+// CancelQueryJob cancels a pending or running query job
+func CancelQueryJob(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		job, err := queryEngine.GetJobManager().Cancel(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to cancel query job")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job)
+	}
+}
+
+// StreamQuery executes a query and writes rows as newline delimited JSON
+// while they arrive. If the query fails after rows were sent, the last line
+// is an object with an "error" field.
+func StreamQuery(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req query.QueryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		flusher, _ := w.(http.Flusher)
+		encoder := json.NewEncoder(w)
+		started := false
+		written := 0
+		const flushEvery = 500
+
+		count, err := db.StreamQuery(r.Context(), &req, func(row map[string]interface{}) error {
+			if !started {
+				w.Header().Set("Content-Type", "application/x-ndjson")
+				w.Header().Set("X-Content-Type-Options", "nosniff")
+				started = true
+			}
+			if err := encoder.Encode(row); err != nil {
+				return err
+			}
+			written++
+			if flusher != nil && (written == 1 || written%flushEvery == 0) {
+				flusher.Flush()
+			}
+			return nil
+		})
+
+		if err != nil {
+			log.Error().Err(err).Str("query", req.Query).Int("rows", count).Msg("Streaming query failed")
+			if !started {
+				if !writeAdmissionError(w, err) {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				}
+				return
+			}
+			encoder.Encode(map[string]interface{}{"error": err.Error()})
+		}
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// GetQueryAdmissionStats returns running and queued queries per user
+func GetQueryAdmissionStats(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"users": queryEngine.GetAdmissionStats(),
+		})
+	}
+}
+
+// GetQueryCacheStats reports query result cache hits, misses and size
+func GetQueryCacheStats(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(queryEngine.GetCacheStats())
+	}
+}
+
+// ExplainQuery returns the optimized plan of a query with ClickHouse's
+// estimate of the parts, rows and marks it would read
+func ExplainQuery(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req query.QueryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		plan, err := queryEngine.Explain(r.Context(), &req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(plan)
+	}
+}
+
+// ListSlowQueries returns captured slow queries, slowest first. Admins see
+// every user's queries and may filter with ?user_id=; others see their own.
+// Supports ?since= (RFC3339) and ?limit=.
+func ListSlowQueries(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		user := auth.UserFromContext(r.Context())
+		filter := query.SlowQueryFilter{UserID: user.ID}
+		if user.IsAdmin() {
+			filter.UserID = r.URL.Query().Get("user_id")
+		}
+		if since := r.URL.Query().Get("since"); since != "" {
+			t, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				http.Error(w, "since must be an RFC3339 time", http.StatusBadRequest)
+				return
+			}
+			filter.Since = t
+		}
+		if limit := r.URL.Query().Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n <= 0 {
+				http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+				return
+			}
+			filter.Limit = n
+		}
+
+		slowLog := queryEngine.GetSlowQueryLog()
+		queries, err := slowLog.List(filter)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list slow queries")
+			http.Error(w, "Failed to list slow queries", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"queries":      queries,
+			"count":        len(queries),
+			"threshold_ms": slowLog.Threshold().Milliseconds(),
+		})
+	}
+}
+
+// GetSlowQuery returns a captured slow query with its plan and index usage
+func GetSlowQuery(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		slowQuery, err := queryEngine.GetSlowQueryLog().Get(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		// Other users' queries are reported as missing
+		user := auth.UserFromContext(r.Context())
+		if !user.IsAdmin() && slowQuery.UserID != user.ID {
+			http.Error(w, "slow query not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(slowQuery)
+	}
+}
+
+// ListQueryPatterns returns execution statistics per query fingerprint.
+// Supports ?sort= (count, p99, avg, rows or errors) and ?limit=.
+func ListQueryPatterns(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		limit := 100
+		if l := r.URL.Query().Get("limit"); l != "" {
+			n, err := strconv.Atoi(l)
+			if err != nil || n <= 0 {
+				http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		patterns := queryEngine.GetQueryStats().List(r.URL.Query().Get("sort"), limit)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"patterns": patterns,
+			"count":    len(patterns),
+		})
+	}
+}
+
+// GetQueryPattern returns the statistics of one query fingerprint
+func GetQueryPattern(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		fingerprint := chi.URLParam(r, "fingerprint")
+		stats, found := queryEngine.GetQueryStats().Get(fingerprint)
+		if !found {
+			http.Error(w, "query pattern not found: "+fingerprint, http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	}
+}
+
+// QueryTimeSeries returns a gap-filled time series of an aggregation over
+// logs, choosing the bucket interval from the requested range
+func QueryTimeSeries(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req query.TimeSeriesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		series, err := queryEngine.TimeSeries(r.Context(), &req)
+		if err != nil {
+			if writeAdmissionError(w, err) {
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(series)
+	}
+}
+
+// ListTables returns the tables that can be queried with their schemas
+func ListTables(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		tables := queryEngine.GetTables().List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"tables": tables,
+			"count":  len(tables),
+		})
+	}
+}
+
+// GetTable returns the schema of one table
+func GetTable(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+
+		name := chi.URLParam(r, "name")
+		table, found := queryEngine.GetTables().Get(name)
+		if !found {
+			http.Error(w, "table not found: "+name, http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(table)
+	}
+}
+
+// RefreshTables rediscovers the queryable tables, e.g. right after a new
+// tenant table was created. Admin only.
+func RefreshTables(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+		if !auth.UserFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "only admins can refresh tables", http.StatusForbidden)
+			return
+		}
+
+		registry := queryEngine.GetTables()
+		if err := registry.Refresh(); err != nil {
+			log.Error().Err(err).Msg("Failed to refresh table registry")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		tables := registry.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"tables": tables,
+			"count":  len(tables),
+		})
+	}
+}
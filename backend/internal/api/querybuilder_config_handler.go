@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/query"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/querybuilder"
+)
+
+// QueryBuilderConfigHandler handles saved query builder configurations
+type QueryBuilderConfigHandler struct {
+	db    *database.DB
+	store *querybuilder.ConfigStore
+}
+
+// NewQueryBuilderConfigHandler creates a new query builder configuration handler
+func NewQueryBuilderConfigHandler(db *database.DB, store *querybuilder.ConfigStore) *QueryBuilderConfigHandler {
+	return &QueryBuilderConfigHandler{
+		db:    db,
+		store: store,
+	}
+}
+
+// ListConfigs returns the caller's configurations. Supports ?tag=.
+func (h *QueryBuilderConfigHandler) ListConfigs(w http.ResponseWriter, r *http.Request) {
+	configs := h.store.List(r.Context(), r.URL.Query().Get("tag"))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"configs": configs,
+		"count":   len(configs),
+	})
+}
+
+// CreateConfig saves a configuration
+func (h *QueryBuilderConfigHandler) CreateConfig(w http.ResponseWriter, r *http.Request) {
+	var qb models.QueryBuilder
+	if err := json.NewDecoder(r.Body).Decode(&qb); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.store.Create(r.Context(), &qb)
+	if err != nil {
+		http.Error(w, err.Error(), configErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetConfig returns a configuration
+func (h *QueryBuilderConfigHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	qb, err := h.store.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(qb)
+}
+
+// UpdateConfig replaces a configuration
+func (h *QueryBuilderConfigHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
+	var qb models.QueryBuilder
+	if err := json.NewDecoder(r.Body).Decode(&qb); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.store.Update(r.Context(), chi.URLParam(r, "id"), &qb)
+	if err != nil {
+		http.Error(w, err.Error(), configErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteConfig removes a configuration
+func (h *QueryBuilderConfigHandler) DeleteConfig(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), configErrorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ConvertToSavedQuery saves the SQL a configuration generates as a private
+// saved query of the caller. The saved query does not follow later edits
+// of the configuration; its metadata records where it came from.
+func (h *QueryBuilderConfigHandler) ConvertToSavedQuery(w http.ResponseWriter, r *http.Request) {
+	qb, err := h.store.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	sql, err := newQueryBuilder(h.db).GenerateSQL(qb)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	queryEngine := h.db.GetQueryEngine()
+	if queryEngine == nil {
+		http.Error(w, "Query engine not available", http.StatusInternalServerError)
+		return
+	}
+
+	savedQuery := &query.SavedQuery{
+		Name:        qb.Name,
+		Description: qb.Description,
+		Query:       sql,
+		Tags:        qb.Tags,
+		CreatedBy:   auth.UserFromContext(r.Context()).ID,
+		Visibility:  query.VisibilityPrivate,
+		Metadata:    map[string]interface{}{"query_builder_id": qb.ID},
+	}
+	if err := queryEngine.GetQueryStore().Save(savedQuery); err != nil {
+		log.Error().Err(err).Str("config_id", qb.ID).Msg("Failed to convert query builder configuration")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(savedQuery)
+}
+
+func configErrorStatus(err error) int {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "not found"):
+		return http.StatusNotFound
+	case strings.HasPrefix(msg, "failed to"):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
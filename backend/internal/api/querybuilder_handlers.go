@@ -3,23 +3,102 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
 
 	"github.com/your-username/click-lite-log-analytics/backend/internal/database"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/parsing"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/query"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/querybuilder"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/tables"
 )
 
-// GetAvailableFields returns the available fields for query building
-func GetAvailableFields(db *database.DB) http.HandlerFunc {
+// newQueryBuilder creates a query builder knowing the tables of the engine
+func newQueryBuilder(db *database.DB) *querybuilder.Service {
+	if queryEngine := db.GetQueryEngine(); queryEngine != nil {
+		return querybuilder.NewService(queryEngine.GetTables())
+	}
+	return querybuilder.NewService(nil)
+}
+
+// GetAvailableFields returns the available fields for query building,
+// of the logs table unless ?table= names another, along with the
+// attribute fields the schema registry has seen
+func GetAvailableFields(db *database.DB, schema *parsing.SchemaRegistry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		service := querybuilder.NewService()
-		fields := service.GetAvailableFields()
+		service := newQueryBuilder(db)
+		service.SetSchema(schema)
+		table := r.URL.Query().Get("table")
+		fields, err := service.GetTableFields(table)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if table == "" {
+			table = tables.DefaultTable
+		}
 
 		response := models.AvailableFields{
-			Fields: fields,
+			Table:      table,
+			Fields:     fields,
+			Tables:     service.GetTables(),
+			Attributes: service.GetAttributeFields(table),
+			Lookups:    service.GetLookups(table),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetFieldValues suggests values of a field for filter dropdowns, the
+// most frequent of the last day first. Supports ?table=, ?prefix= matched
+// case insensitively, and ?limit=.
+func GetFieldValues(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		field, err := url.PathUnescape(chi.URLParam(r, "field"))
+		if err != nil {
+			http.Error(w, "Invalid field", http.StatusBadRequest)
+			return
+		}
+		table := r.URL.Query().Get("table")
+
+		service := newQueryBuilder(db)
+		sql, err := service.FieldValuesSQL(table, field)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		queryEngine := db.GetQueryEngine()
+		if queryEngine == nil {
+			http.Error(w, "Query engine not available", http.StatusInternalServerError)
+			return
+		}
+		result, err := queryEngine.Execute(r.Context(), &query.QueryRequest{Query: sql, Timeout: 10, UseCache: true})
+		if err != nil {
+			if writeAdmissionError(w, err) {
+				return
+			}
+			log.Error().Err(err).Str("sql", sql).Msg("Field values query failed")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if table == "" {
+			table = tables.DefaultTable
+		}
+		prefix := r.URL.Query().Get("prefix")
+		response := models.FieldValues{
+			Table:  table,
+			Field:  field,
+			Prefix: prefix,
+			Values: querybuilder.FilterFieldValues(result.Rows, prefix, limit),
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -36,7 +115,7 @@ func GenerateSQL(db *database.DB) http.HandlerFunc {
 			return
 		}
 
-		service := querybuilder.NewService()
+		service := newQueryBuilder(db)
 
 		// Validate query builder
 		if err := service.ValidateQueryBuilder(&qb); err != nil {
@@ -72,7 +151,7 @@ func ExecuteQueryBuilder(db *database.DB) http.HandlerFunc {
 			return
 		}
 
-		service := querybuilder.NewService()
+		service := newQueryBuilder(db)
 
 		// Validate query builder
 		if err := service.ValidateQueryBuilder(&qb); err != nil {
@@ -96,13 +175,20 @@ func ExecuteQueryBuilder(db *database.DB) http.HandlerFunc {
 			return
 		}
 
+		// A freshness tolerance lets identical configurations share a
+		// cached result
 		req := &query.QueryRequest{
-			Query:   sql,
-			Timeout: 30,
+			Query:    sql,
+			Timeout:  30,
+			UseCache: qb.Freshness > 0,
+			MaxAge:   qb.Freshness,
 		}
 
 		result, err := queryEngine.Execute(r.Context(), req)
 		if err != nil {
+			if writeAdmissionError(w, err) {
+				return
+			}
 			log.Error().Err(err).Str("sql", sql).Msg("Query execution failed")
 			response := &models.QueryBuilderResponse{
 				SQL:   sql,
@@ -130,6 +216,19 @@ func ExecuteQueryBuilder(db *database.DB) http.HandlerFunc {
 			})
 		}
 
+		// Report the range read, and run the query over the period it is
+		// compared with
+		response.TimeRange, _ = service.ResolvedTimeRange(&qb)
+		if comparisonSQL, err := service.GenerateComparisonSQL(&qb); err == nil && comparisonSQL != "" {
+			comparison, err := queryEngine.Execute(r.Context(), &query.QueryRequest{Query: comparisonSQL, Timeout: 30, UseCache: req.UseCache, MaxAge: req.MaxAge})
+			if err != nil {
+				log.Error().Err(err).Str("sql", comparisonSQL).Msg("Comparison query execution failed")
+				response.Error = "comparison query failed: " + err.Error()
+			} else {
+				response.ComparisonRows = comparison.Rows
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
 	}
@@ -144,7 +243,7 @@ func ValidateQueryBuilder(db *database.DB) http.HandlerFunc {
 			return
 		}
 
-		service := querybuilder.NewService()
+		service := newQueryBuilder(db)
 
 		// Validate query builder
 		err := service.ValidateQueryBuilder(&qb)
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/parsing"
+)
+
+// RuleSetHandler handles parsing ruleset API endpoints
+type RuleSetHandler struct {
+	registry *parsing.RuleSetRegistry
+}
+
+// NewRuleSetHandler creates a new ruleset handler
+func NewRuleSetHandler(registry *parsing.RuleSetRegistry) *RuleSetHandler {
+	return &RuleSetHandler{
+		registry: registry,
+	}
+}
+
+// ListRuleSets returns all named rulesets, API keys masked
+func (h *RuleSetHandler) ListRuleSets(w http.ResponseWriter, r *http.Request) {
+	ruleSets := h.registry.List()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rulesets": ruleSets,
+		"count":    len(ruleSets),
+	})
+}
+
+// GetRuleSet returns a ruleset by name
+func (h *RuleSetHandler) GetRuleSet(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	ruleSet, err := h.registry.Get(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ruleSet)
+}
+
+// CreateRuleSet creates a new named ruleset
+func (h *RuleSetHandler) CreateRuleSet(w http.ResponseWriter, r *http.Request) {
+	if !auth.UserFromContext(r.Context()).IsAdmin() {
+		http.Error(w, "only admins can add rulesets", http.StatusForbidden)
+		return
+	}
+
+	var ruleSet parsing.NamedRuleSet
+	if err := json.NewDecoder(r.Body).Decode(&ruleSet); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.registry.Create(&ruleSet); err != nil {
+		http.Error(w, err.Error(), ruleSetErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ruleSet)
+}
+
+// UpdateRuleSet replaces an existing ruleset. Masked API keys keep their
+// stored values.
+func (h *RuleSetHandler) UpdateRuleSet(w http.ResponseWriter, r *http.Request) {
+	if !auth.UserFromContext(r.Context()).IsAdmin() {
+		http.Error(w, "only admins can change rulesets", http.StatusForbidden)
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+
+	var ruleSet parsing.NamedRuleSet
+	if err := json.NewDecoder(r.Body).Decode(&ruleSet); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.registry.Update(name, &ruleSet); err != nil {
+		http.Error(w, err.Error(), ruleSetErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ruleSet)
+}
+
+// DeleteRuleSet removes a ruleset
+func (h *RuleSetHandler) DeleteRuleSet(w http.ResponseWriter, r *http.Request) {
+	if !auth.UserFromContext(r.Context()).IsAdmin() {
+		http.Error(w, "only admins can delete rulesets", http.StatusForbidden)
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+
+	if err := h.registry.Delete(name); err != nil {
+		http.Error(w, err.Error(), ruleSetErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetRuleSetVersions returns the revision history of a ruleset
+func (h *RuleSetHandler) GetRuleSetVersions(w http.ResponseWriter, r *http.Request) {
+	if !auth.UserFromContext(r.Context()).IsAdmin() {
+		http.Error(w, "only admins can view ruleset history", http.StatusForbidden)
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+
+	versions, err := h.registry.History(name)
+	if err != nil {
+		http.Error(w, err.Error(), ruleSetErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":     name,
+		"versions": versions,
+		"count":    len(versions),
+	})
+}
+
+// RollbackRuleSet restores an earlier revision of a ruleset
+func (h *RuleSetHandler) RollbackRuleSet(w http.ResponseWriter, r *http.Request) {
+	if !auth.UserFromContext(r.Context()).IsAdmin() {
+		http.Error(w, "only admins can roll back rulesets", http.StatusForbidden)
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+
+	var req struct {
+		Version int `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Version <= 0 {
+		http.Error(w, "A positive version is required", http.StatusBadRequest)
+		return
+	}
+
+	ruleSet, err := h.registry.Rollback(name, req.Version)
+	if err != nil {
+		http.Error(w, err.Error(), ruleSetErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ruleSet)
+}
+
+// ResolveRuleSet shows which ruleset would be applied for a service and API key
+func (h *RuleSetHandler) ResolveRuleSet(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+	apiKey := r.Header.Get("X-API-Key")
+
+	ruleSet := h.registry.Resolve(service, apiKey)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"service": service,
+		"ruleset": ruleSet.Name,
+	})
+}
+
+// ruleSetErrorStatus maps registry errors to HTTP status codes
+func ruleSetErrorStatus(err error) int {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "not found"):
+		return http.StatusNotFound
+	case strings.Contains(msg, "already"):
+		return http.StatusConflict
+	case strings.Contains(msg, "failed to"):
+		return http.StatusInternalServerError
+	case strings.Contains(msg, "does not keep version history"):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusBadRequest
+	}
+}
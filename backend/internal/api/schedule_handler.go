@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/scheduler"
+)
+
+// ScheduleHandler handles scheduled query API endpoints
+type ScheduleHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+// NewScheduleHandler creates a new schedule handler
+func NewScheduleHandler(s *scheduler.Scheduler) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduler: s,
+	}
+}
+
+// ListSchedules returns the caller's scheduled queries
+func (h *ScheduleHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules := h.scheduler.List(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"schedules": schedules,
+		"count":     len(schedules),
+	})
+}
+
+// CreateSchedule schedules a saved query
+func (h *ScheduleHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var sq scheduler.ScheduledQuery
+	if err := json.NewDecoder(r.Body).Decode(&sq); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.scheduler.Create(r.Context(), &sq)
+	if err != nil {
+		http.Error(w, err.Error(), scheduleErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetSchedule returns a scheduled query
+func (h *ScheduleHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	sq, err := h.scheduler.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sq)
+}
+
+// UpdateSchedule replaces the definition of a scheduled query
+func (h *ScheduleHandler) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	var sq scheduler.ScheduledQuery
+	if err := json.NewDecoder(r.Body).Decode(&sq); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.scheduler.Update(r.Context(), chi.URLParam(r, "id"), &sq)
+	if err != nil {
+		http.Error(w, err.Error(), scheduleErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteSchedule removes a scheduled query
+func (h *ScheduleHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	if err := h.scheduler.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), scheduleErrorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunSchedule runs a scheduled query now and returns the run
+func (h *ScheduleHandler) RunSchedule(w http.ResponseWriter, r *http.Request) {
+	run, err := h.scheduler.RunNow(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), scheduleErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// GetScheduleRuns returns the recent runs of a scheduled query
+func (h *ScheduleHandler) GetScheduleRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.scheduler.Runs(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"runs":  runs,
+		"count": len(runs),
+	})
+}
+
+// GetScheduleResults returns the stored rows of a run, the latest by default.
+// Supports ?run_id= to select a specific run.
+func (h *ScheduleHandler) GetScheduleResults(w http.ResponseWriter, r *http.Request) {
+	runID, rows, err := h.scheduler.Results(r.Context(), chi.URLParam(r, "id"), r.URL.Query().Get("run_id"))
+	if err != nil {
+		http.Error(w, err.Error(), scheduleErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"run_id": runID,
+		"rows":   rows,
+		"count":  len(rows),
+	})
+}
+
+func scheduleErrorStatus(err error) int {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "not found"):
+		return http.StatusNotFound
+	case strings.Contains(msg, "already running"):
+		return http.StatusConflict
+	case strings.HasPrefix(msg, "failed to"):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/parsing"
+)
+
+// SchemaHandler exposes the attribute schema observed during ingestion
+type SchemaHandler struct {
+	registry *parsing.SchemaRegistry
+}
+
+// NewSchemaHandler creates a new schema handler
+func NewSchemaHandler(registry *parsing.SchemaRegistry) *SchemaHandler {
+	return &SchemaHandler{
+		registry: registry,
+	}
+}
+
+// GetAttributes returns known attribute names with their inferred types.
+// Supports ?prefix= to narrow by name, ?type= to filter by dominant type and ?limit=.
+func (h *SchemaHandler) GetAttributes(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	attributes := h.registry.List(q.Get("prefix"))
+
+	if typeFilter := q.Get("type"); typeFilter != "" {
+		filtered := attributes[:0]
+		for _, attr := range attributes {
+			if attr.Type == typeFilter {
+				filtered = append(filtered, attr)
+			}
+		}
+		attributes = filtered
+	}
+
+	if limit, err := strconv.Atoi(q.Get("limit")); err == nil && limit > 0 && limit < len(attributes) {
+		attributes = attributes[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"attributes": attributes,
+		"count":      len(attributes),
+		"stats":      h.registry.Stats(),
+	})
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/search"
+)
+
+// SearchLogs searches logs with the search syntax in ?q=, e.g.
+// service:auth level:error "connection refused" -health
+func SearchLogs(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+
+		parsed, err := search.Parse(params.Get("q"))
+		if err != nil {
+			http.Error(w, "Invalid search: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		query := &models.LogQuery{
+			StartTime: time.Now().Add(-24 * time.Hour),
+			EndTime:   time.Now(),
+			Filter:    parsed.SQL(),
+			Limit:     100,
+		}
+
+		if start := params.Get("start_time"); start != "" {
+			if t, err := time.Parse(time.RFC3339, start); err == nil {
+				query.StartTime = t
+			}
+		}
+
+		if end := params.Get("end_time"); end != "" {
+			if t, err := time.Parse(time.RFC3339, end); err == nil {
+				query.EndTime = t
+			}
+		}
+
+		if limit, err := strconv.Atoi(params.Get("limit")); err == nil && limit > 0 && limit <= 1000 {
+			query.Limit = limit
+		}
+
+		if offset, err := strconv.Atoi(params.Get("offset")); err == nil && offset >= 0 {
+			query.Offset = offset
+		}
+
+		logs, err := db.QueryLogs(r.Context(), query)
+		if err != nil {
+			log.Error().Err(err).Str("q", params.Get("q")).Msg("Failed to search logs")
+			http.Error(w, "Failed to search logs", http.StatusInternalServerError)
+			return
+		}
+
+		if logs == nil {
+			logs = []models.Log{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"logs":   logs,
+			"count":  len(logs),
+			"search": parsed,
+			"filter": query.Filter,
+		})
+	}
+}
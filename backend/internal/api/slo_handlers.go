@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/slo"
+)
+
+// ListSLOs lists the service level objectives
+func ListSLOs(manager *slo.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slos := manager.List()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"slos":  slos,
+			"total": len(slos),
+		})
+	}
+}
+
+// GetSLO returns an SLO by name
+func GetSLO(manager *slo.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		objective, err := manager.Get(chi.URLParam(r, "name"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(objective)
+	}
+}
+
+// CreateSLO adds an SLO along with its burn-rate alert rules
+func CreateSLO(manager *slo.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := auth.UserFromContext(r.Context())
+		if !user.IsAdmin() {
+			http.Error(w, "only admins can add SLOs", http.StatusForbidden)
+			return
+		}
+
+		var objective slo.SLO
+		if err := json.NewDecoder(r.Body).Decode(&objective); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		objective.CreatedBy = user.ID
+		if err := manager.Create(&objective); err != nil {
+			http.Error(w, err.Error(), ruleSetErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(objective)
+	}
+}
+
+// UpdateSLO replaces the definition of an SLO
+func UpdateSLO(manager *slo.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.UserFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "only admins can change SLOs", http.StatusForbidden)
+			return
+		}
+
+		var objective slo.SLO
+		if err := json.NewDecoder(r.Body).Decode(&objective); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := manager.Update(chi.URLParam(r, "name"), &objective); err != nil {
+			http.Error(w, err.Error(), ruleSetErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(objective)
+	}
+}
+
+// DeleteSLO removes an SLO and resolves its burn-rate alerts
+func DeleteSLO(manager *slo.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.UserFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "only admins can delete SLOs", http.StatusForbidden)
+			return
+		}
+
+		if err := manager.Delete(chi.URLParam(r, "name")); err != nil {
+			http.Error(w, err.Error(), ruleSetErrorStatus(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListSLOStatuses returns where every SLO stands: its compliance, the
+// error budget left and the burn rate over recent windows
+func ListSLOStatuses(manager *slo.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses := manager.Statuses()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"statuses": statuses,
+			"total":    len(statuses),
+		})
+	}
+}
+
+// GetSLOStatus returns where an SLO stands
+func GetSLOStatus(manager *slo.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, err := manager.Status(chi.URLParam(r, "name"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	}
+}
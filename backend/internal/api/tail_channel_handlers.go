@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/websocket"
+)
+
+// ListTailChannels lists the channels live tail clients can join
+func ListTailChannels(hub *websocket.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		channels := hub.Channels().List()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"channels": channels,
+			"total":    len(channels),
+		})
+	}
+}
+
+// GetTailChannel returns a tail channel by name
+func GetTailChannel(hub *websocket.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		channel, err := hub.Channels().Get(chi.URLParam(r, "name"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(channel)
+	}
+}
+
+// CreateTailChannel defines a new tail channel
+func CreateTailChannel(hub *websocket.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := auth.UserFromContext(r.Context())
+		if !user.IsAdmin() {
+			http.Error(w, "only admins can define tail channels", http.StatusForbidden)
+			return
+		}
+
+		var channel websocket.Channel
+		if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		channel.CreatedBy = user.ID
+		if err := hub.Channels().Create(&channel); err != nil {
+			http.Error(w, err.Error(), ruleSetErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(channel)
+	}
+}
+
+// UpdateTailChannel replaces the filter and highlights of a tail channel
+func UpdateTailChannel(hub *websocket.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.UserFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "only admins can change tail channels", http.StatusForbidden)
+			return
+		}
+
+		var channel websocket.Channel
+		if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := hub.Channels().Update(chi.URLParam(r, "name"), &channel); err != nil {
+			http.Error(w, err.Error(), ruleSetErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(channel)
+	}
+}
+
+// DeleteTailChannel removes a tail channel
+func DeleteTailChannel(hub *websocket.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.UserFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "only admins can delete tail channels", http.StatusForbidden)
+			return
+		}
+
+		if err := hub.Channels().Delete(chi.URLParam(r, "name")); err != nil {
+			http.Error(w, err.Error(), ruleSetErrorStatus(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/export"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/websocket"
+)
+
+// exportContentTypes are the content types of the export formats
+var exportContentTypes = map[export.ExportFormat]string{
+	export.FormatCSV:   "text/csv",
+	export.FormatJSON:  "application/json",
+	export.FormatExcel: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ListTailRecordings lists the live tail recordings of the user
+func ListTailRecordings(hub *websocket.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordings := hub.ListRecordings(auth.UserFromContext(r.Context()))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"recordings": recordings,
+			"total":      len(recordings),
+		})
+	}
+}
+
+// GetTailRecording returns a live tail recording and the logs it captured
+func GetTailRecording(hub *websocket.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		info, logs, err := hub.GetRecording(auth.UserFromContext(r.Context()), chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"recording": info,
+			"logs":      logs,
+		})
+	}
+}
+
+// DeleteTailRecording drops a live tail recording
+func DeleteTailRecording(hub *websocket.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := hub.DeleteRecording(auth.UserFromContext(r.Context()), chi.URLParam(r, "id")); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ExportTailRecording exports the logs of a live tail recording, in the
+// format and with the fields and headers query parameters of ExportLogs
+func (h *ExportHandler) ExportTailRecording(hub *websocket.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		info, logs, err := hub.GetRecording(auth.UserFromContext(r.Context()), chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		options := h.parseQueryOptions(r)
+		if options.Format == "" {
+			options.Format = export.FormatCSV
+		}
+		contentType, ok := exportContentTypes[options.Format]
+		if !ok {
+			http.Error(w, "Unsupported export format", http.StatusBadRequest)
+			return
+		}
+
+		name := info.Name
+		if name == "" {
+			name = info.ID
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=tail_%s_%s.%s",
+			exportFileName(name), info.StartedAt.Format("20060102_150405"), options.Format))
+		w.Header().Set("X-Export-Rows", fmt.Sprintf("%d", len(logs)))
+
+		if _, err := h.exporter.ExportLogs(w, logs, options); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// exportFileName keeps the letters, digits, dashes and underscores of a
+// name, for use in a file name
+func exportFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r == ' ':
+			return '_'
+		}
+		return -1
+	}, name)
+}
@@ -2,8 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/tracing"
@@ -21,7 +24,8 @@ func NewTraceHandler(traceManager *tracing.TraceManager) *TraceHandler {
 	}
 }
 
-// GetTrace retrieves a specific trace by ID
+// GetTrace retrieves a specific trace by ID, its spans nested under the
+// spans that called them
 func (h *TraceHandler) GetTrace(w http.ResponseWriter, r *http.Request) {
 	traceID := chi.URLParam(r, "traceID")
 	if traceID == "" {
@@ -29,34 +33,107 @@ func (h *TraceHandler) GetTrace(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	trace, err := h.traceManager.GetTrace(traceID)
+	tree, err := h.traceManager.GetTraceTree(traceID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		http.Error(w, err.Error(), traceErrorStatus(err))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(trace)
+	json.NewEncoder(w).Encode(tree)
 }
 
-// GetTraces retrieves all active traces
+// GetTraces searches the active traces by service, duration, error count
+// and start time, returning their summaries with the latest first
 func (h *TraceHandler) GetTraces(w http.ResponseWriter, r *http.Request) {
-	limit := 100
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
+	query := r.URL.Query()
+	filter := tracing.TraceFilter{
+		Service: query.Get("service"),
+		Limit:   100,
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = l
+	}
+	if minErrors := query.Get("min_errors"); minErrors != "" {
+		n, err := strconv.Atoi(minErrors)
+		if err != nil || n < 0 {
+			http.Error(w, "min_errors must be a non-negative number", http.StatusBadRequest)
+			return
 		}
+		filter.MinErrors = n
 	}
 
-	traces := h.traceManager.GetTraces(limit)
+	var err error
+	if filter.MinDuration, err = parseDurationParam(query.Get("min_duration")); err != nil {
+		http.Error(w, "invalid min_duration: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.MaxDuration, err = parseDurationParam(query.Get("max_duration")); err != nil {
+		http.Error(w, "invalid max_duration: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if start := query.Get("start"); start != "" {
+		if filter.Start, err = time.Parse(time.RFC3339, start); err != nil {
+			http.Error(w, "start must be an RFC3339 time", http.StatusBadRequest)
+			return
+		}
+	}
+	if end := query.Get("end"); end != "" {
+		if filter.End, err = time.Parse(time.RFC3339, end); err != nil {
+			http.Error(w, "end must be an RFC3339 time", http.StatusBadRequest)
+			return
+		}
+	}
+
+	traces, total, err := h.traceManager.SearchTraces(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"traces": traces,
 		"count":  len(traces),
+		"total":  total,
 	})
 }
 
+// traceErrorStatus maps trace lookup errors to HTTP status codes
+func traceErrorStatus(err error) int {
+	if errors.Is(err, tracing.ErrTraceNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// parseDurationParam parses a duration such as 250ms or 1.5s; plain numbers
+// are milliseconds
+func parseDurationParam(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if ms, err := strconv.ParseFloat(value, 64); err == nil {
+		if ms < 0 {
+			return 0, fmt.Errorf("duration must not be negative")
+		}
+		return time.Duration(ms * float64(time.Millisecond)), nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("duration must not be negative")
+	}
+	return d, nil
+}
+
 // GetTraceTimeline retrieves trace timeline visualization data
 func (h *TraceHandler) GetTraceTimeline(w http.ResponseWriter, r *http.Request) {
 	traceID := chi.URLParam(r, "traceID")
@@ -65,46 +142,52 @@ func (h *TraceHandler) GetTraceTimeline(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	trace, err := h.traceManager.GetTrace(traceID)
+	tree, err := h.traceManager.GetTraceTree(traceID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		http.Error(w, err.Error(), traceErrorStatus(err))
 		return
 	}
 
 	// Convert to timeline format
-	timeline := h.buildTimeline(trace)
+	timeline := h.buildTimeline(tree)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(timeline)
 }
 
 // buildTimeline builds timeline visualization data
-func (h *TraceHandler) buildTimeline(trace *tracing.Trace) map[string]interface{} {
+func (h *TraceHandler) buildTimeline(tree *tracing.TraceTree) map[string]interface{} {
 	events := []map[string]interface{}{}
 
-	// Add spans as timeline events
-	for _, span := range trace.Spans {
-		events = append(events, map[string]interface{}{
-			"id":        span.SpanID,
-			"parent_id": span.ParentID,
-			"service":   span.Service,
-			"operation": span.Operation,
-			"start":     span.StartTime.UnixMilli(),
-			"end":       span.EndTime.UnixMilli(),
-			"duration":  span.Duration.Milliseconds(),
-			"status":    span.Status,
-			"logs":      len(span.Logs),
-		})
+	// Add spans as timeline events, each after the span that called it
+	var addSpans func(spans []*tracing.SpanNode)
+	addSpans = func(spans []*tracing.SpanNode) {
+		for _, span := range spans {
+			events = append(events, map[string]interface{}{
+				"id":        span.SpanID,
+				"parent_id": span.ParentID,
+				"service":   span.Service,
+				"operation": span.Operation,
+				"start":     span.StartTime.UnixMilli(),
+				"end":       span.EndTime.UnixMilli(),
+				"duration":  int64(span.DurationMs),
+				"status":    span.Status,
+				"logs":      len(span.Logs),
+				"reported":  span.Reported,
+			})
+			addSpans(span.Children)
+		}
 	}
+	addSpans(tree.Spans)
 
 	return map[string]interface{}{
-		"trace_id":      trace.TraceID,
-		"start_time":    trace.StartTime.UnixMilli(),
-		"end_time":      trace.EndTime.UnixMilli(),
-		"duration":      trace.Duration.Milliseconds(),
-		"service_count": trace.ServiceCount,
-		"span_count":    trace.SpanCount,
-		"error_count":   trace.ErrorCount,
+		"trace_id":      tree.TraceID,
+		"start_time":    tree.StartTime.UnixMilli(),
+		"end_time":      tree.EndTime.UnixMilli(),
+		"duration":      int64(tree.DurationMs),
+		"service_count": len(tree.Services),
+		"span_count":    tree.SpanCount,
+		"error_count":   tree.ErrorCount,
 		"events":        events,
 	}
 }
\ No newline at end of file
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Roles recognised by the query engine and API
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// DefaultUserID is used when a request does not identify its user
+const DefaultUserID = "default-user"
+
+// User identifies the caller of a request
+type User struct {
+	ID    string   `json:"id"`
+	Role  string   `json:"role"`
+	Teams []string `json:"teams,omitempty"`
+}
+
+// SystemUser is used for queries issued by the server itself
+var SystemUser = User{ID: "system", Role: RoleAdmin}
+
+// IsAdmin reports whether the user has the admin role
+func (u User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// InTeam reports whether the user belongs to the team
+func (u User) InTeam(team string) bool {
+	for _, t := range u.Teams {
+		if t == team {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// WithUser returns a context carrying the user
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// UserFromContext returns the user of the context, or the default
+// non-admin user if none was set
+func UserFromContext(ctx context.Context) User {
+	if user, ok := ctx.Value(contextKey{}).(User); ok {
+		return user
+	}
+	return User{ID: DefaultUserID, Role: RoleUser}
+}
+
+// Middleware identifies the user from the X-User-ID header and their teams
+// from the comma separated X-Team-ID header. Requests that
+// present the configured X-Admin-Token get the admin role; with no token
+// configured nobody is an admin.
+func Middleware(adminToken string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := User{ID: r.Header.Get("X-User-ID"), Role: RoleUser}
+			if user.ID == "" {
+				user.ID = DefaultUserID
+			}
+			for _, team := range strings.Split(r.Header.Get("X-Team-ID"), ",") {
+				if team = strings.TrimSpace(team); team != "" {
+					user.Teams = append(user.Teams, team)
+				}
+			}
+			if token := r.Header.Get("X-Admin-Token"); adminToken != "" && token != "" &&
+				subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1 {
+				user.Role = RoleAdmin
+			}
+			next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+		})
+	}
+}
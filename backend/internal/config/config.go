@@ -2,12 +2,31 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 )
 
 type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
 	JWT      JWTConfig
+	Parsing  ParsingConfig
+	Auth     AuthConfig
+	Query    QueryConfig
+	Live     LiveConfig
+
+	Notifications NotificationsConfig
+	Alerts        AlertsConfig
+	SLO           SLOConfig
+	Health        HealthConfig
+	StatsD        StatsDConfig
+	Telemetry     TelemetryConfig
+	SelfMonitor   SelfMonitorConfig
+	Metrics       MetricsConfig
+	Capacity      CapacityConfig
+	Errors        ErrorsConfig
+	Patterns      PatternsConfig
+	Traces        TracesConfig
 }
 
 type ServerConfig struct {
@@ -26,6 +45,123 @@ type JWTConfig struct {
 	Secret string
 }
 
+type ParsingConfig struct {
+	RuleSetsStorage string // "clickhouse", "file" or "memory"
+	RuleSetsFile    string
+	Quarantine      bool
+}
+
+type AuthConfig struct {
+	AdminToken string // requests presenting it in X-Admin-Token get the admin role
+}
+
+type QueryConfig struct {
+	Storage               string // saved query, query builder configuration, dashboard and slow query log storage: "clickhouse" or "memory"
+	MaxLimit              int    // LIMIT applied to, and maximum allowed in, user queries
+	MaxConcurrentPerUser  int
+	MaxQueuedPerUser      int
+	QueueTimeoutSeconds   int
+	CacheTTLSeconds       int // result cache lifetime for historical ranges
+	CacheBucketSeconds    int // time literals and now() are rounded to this in cache keys
+	CacheRecentSeconds    int // results covering the last N seconds count as recent
+	CacheRecentTTLSeconds int
+	SlowThresholdMs       int      // queries running at least this long are captured; 0 disables
+	Tables                []string // name patterns of the tables users may query, e.g. app_logs_*
+	Lookups               []string // dimension tables the builder may join, as table:key or table:key=field
+}
+
+type LiveConfig struct {
+	ChannelsFile      string // where the tail channels clients join are kept
+	Compression       bool   // permessage-deflate of WebSocket frames
+	CompressionLevel  int
+	BatchMaxLatencyMs int // how long messages to a busy client wait to share a frame
+	BatchMaxMessages  int
+	SendQueueSize     int // messages queued per client before the oldest are dropped
+	SlowClientSeconds int // clients whose queue stays full this long are disconnected
+}
+
+type NotificationsConfig struct {
+	File string // where notification channels, with their secrets, and routes are kept
+}
+
+type AlertsConfig struct {
+	AnomalyRulesFile string // where the anomaly rules added through the API are kept
+	// AbsenceMinutes is how long a service that normally logs may log
+	// nothing before an alert is raised, 0 to only watch AbsenceServices
+	AbsenceMinutes  int
+	AbsenceServices []string // service:minutes pairs, minutes 0 to never alert
+	// WebhookToken lets Alertmanager send alerts as a bearer token, without
+	// the admin token, when set
+	WebhookToken string
+	// CapacityRulesFile is where the capacity rules added through the API
+	// are kept
+	CapacityRulesFile string
+}
+
+type SLOConfig struct {
+	File string // where service level objectives are kept
+}
+
+type HealthConfig struct {
+	ClickHouseTimeoutMs        int
+	ClickHouseSlowMs           int // pings slower than this degrade ClickHouse
+	BatchQueueDegradedDepth    int // logs waiting to be written
+	BatchQueueDownDepth        int
+	StorageDegradedFreePercent int // of the disk the data directory is on
+	StorageDownFreePercent     int
+}
+
+type StatsDConfig struct {
+	Address         string   // host:port of the agent; empty disables the sink
+	Prefix          string   // prepended to metric names
+	Tags            []string // key:value tags added to every metric
+	FlushIntervalMs int
+	DogStatsD       bool // send labels as tags rather than in metric names
+}
+
+type TelemetryConfig struct {
+	OTLPEndpoint     string // base URL of the OTLP/HTTP collector; empty disables OpenTelemetry
+	ServiceName      string
+	SamplePercent    int // of the traces started by this server
+	MetricIntervalMs int
+}
+
+type SelfMonitorConfig struct {
+	Enabled      bool   // ingest the server's own logs
+	Service      string // service the server's logs are tagged with
+	Level        string // lowest level ingested
+	MaxPerSecond int
+}
+
+type MetricsConfig struct {
+	MaxSeriesPerMetric int      // label sets a metric may have
+	SeriesLimits       []string // metric:limit pairs overriding MaxSeriesPerMetric
+	SeriesTTLSeconds   int      // how long a series is kept without updates
+	HistogramBuckets   []string // metric:bound bound... pairs overriding the default buckets
+}
+
+type ErrorsConfig struct {
+	PatternsFile              string // where the error detection patterns are kept once changed through the API
+	GroupsFile                string // where the status and assignee of error groups are kept
+	BaselinesFile             string // where the hourly counts behind the error rate baselines of services are kept
+	CheckpointIntervalSeconds int    // how often the error stats are checkpointed to ClickHouse
+}
+
+type PatternsConfig struct {
+	MaxPatterns       int // log patterns kept, the least recently seen dropped first
+	SimilarityPercent int // tokens a message shares with a pattern to join it
+}
+
+type TracesConfig struct {
+	RetentionDays        int // how long traces stay searchable in ClickHouse, as long as logs by default
+	FlushIntervalSeconds int // how often changed traces are written to ClickHouse
+}
+
+type CapacityConfig struct {
+	SampleIntervalSeconds int // how often table sizes are sampled for the forecast
+	HistoryDays           int // days of samples growth is estimated from
+}
+
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
@@ -41,6 +177,101 @@ func Load() *Config {
 		JWT: JWTConfig{
 			Secret: getEnv("JWT_SECRET", "your-secret-key"),
 		},
+		Parsing: ParsingConfig{
+			RuleSetsStorage: getEnv("PARSING_RULESETS_STORAGE", "clickhouse"),
+			RuleSetsFile:    getEnv("PARSING_RULESETS_FILE", "./data/rulesets.json"),
+			Quarantine:      getEnv("PARSING_QUARANTINE_ENABLED", "false") == "true",
+		},
+		Auth: AuthConfig{
+			AdminToken: getEnv("AUTH_ADMIN_TOKEN", ""),
+		},
+		Query: QueryConfig{
+			Storage:               getEnv("QUERY_STORAGE", "clickhouse"),
+			MaxLimit:              getEnvInt("QUERY_MAX_LIMIT", 10000),
+			MaxConcurrentPerUser:  getEnvInt("QUERY_MAX_CONCURRENT_PER_USER", 4),
+			MaxQueuedPerUser:      getEnvInt("QUERY_MAX_QUEUED_PER_USER", 8),
+			QueueTimeoutSeconds:   getEnvInt("QUERY_QUEUE_TIMEOUT_SECONDS", 10),
+			CacheTTLSeconds:       getEnvInt("QUERY_CACHE_TTL_SECONDS", 600),
+			CacheBucketSeconds:    getEnvInt("QUERY_CACHE_BUCKET_SECONDS", 60),
+			CacheRecentSeconds:    getEnvInt("QUERY_CACHE_RECENT_WINDOW_SECONDS", 300),
+			CacheRecentTTLSeconds: getEnvInt("QUERY_CACHE_RECENT_TTL_SECONDS", 15),
+			SlowThresholdMs:       getEnvInt("QUERY_SLOW_THRESHOLD_MS", 1000),
+			Tables:                getEnvList("QUERY_TABLES"),
+			Lookups:               getEnvList("QUERY_LOOKUPS"),
+		},
+		Live: LiveConfig{
+			ChannelsFile:      getEnv("LIVE_CHANNELS_FILE", "./data/tail_channels.json"),
+			Compression:       getEnv("LIVE_WS_COMPRESSION", "true") == "true",
+			CompressionLevel:  getEnvInt("LIVE_WS_COMPRESSION_LEVEL", 1),
+			BatchMaxLatencyMs: getEnvInt("LIVE_WS_BATCH_MAX_LATENCY_MS", 50),
+			BatchMaxMessages:  getEnvInt("LIVE_WS_BATCH_MAX_MESSAGES", 256),
+			SendQueueSize:     getEnvInt("LIVE_WS_SEND_QUEUE_SIZE", 256),
+			SlowClientSeconds: getEnvInt("LIVE_WS_SLOW_CLIENT_TIMEOUT_SECONDS", 30),
+		},
+		Notifications: NotificationsConfig{
+			File: getEnv("NOTIFICATIONS_FILE", "./data/notifications.json"),
+		},
+		Alerts: AlertsConfig{
+			AnomalyRulesFile:  getEnv("ALERTS_ANOMALY_RULES_FILE", "./data/anomaly_rules.json"),
+			AbsenceMinutes:    getEnvInt("ALERTS_ABSENCE_MINUTES", 0),
+			AbsenceServices:   getEnvList("ALERTS_ABSENCE_SERVICES"),
+			WebhookToken:      getEnv("ALERTS_WEBHOOK_TOKEN", ""),
+			CapacityRulesFile: getEnv("ALERTS_CAPACITY_RULES_FILE", "./data/capacity_rules.json"),
+		},
+		SLO: SLOConfig{
+			File: getEnv("SLO_FILE", "./data/slos.json"),
+		},
+		Health: HealthConfig{
+			ClickHouseTimeoutMs:        getEnvInt("HEALTH_CLICKHOUSE_TIMEOUT_MS", 2000),
+			ClickHouseSlowMs:           getEnvInt("HEALTH_CLICKHOUSE_SLOW_MS", 500),
+			BatchQueueDegradedDepth:    getEnvInt("HEALTH_BATCH_QUEUE_DEGRADED_DEPTH", 10000),
+			BatchQueueDownDepth:        getEnvInt("HEALTH_BATCH_QUEUE_DOWN_DEPTH", 100000),
+			StorageDegradedFreePercent: getEnvInt("HEALTH_STORAGE_DEGRADED_FREE_PERCENT", 10),
+			StorageDownFreePercent:     getEnvInt("HEALTH_STORAGE_DOWN_FREE_PERCENT", 2),
+		},
+		StatsD: StatsDConfig{
+			Address:         getEnv("STATSD_ADDRESS", ""),
+			Prefix:          getEnv("STATSD_PREFIX", "click_lite."),
+			Tags:            getEnvList("STATSD_TAGS"),
+			FlushIntervalMs: getEnvInt("STATSD_FLUSH_INTERVAL_MS", 10000),
+			DogStatsD:       getEnv("STATSD_DOGSTATSD", "true") == "true",
+		},
+		Telemetry: TelemetryConfig{
+			OTLPEndpoint:     getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+			ServiceName:      getEnv("OTEL_SERVICE_NAME", "click-lite"),
+			SamplePercent:    getEnvInt("OTEL_TRACES_SAMPLE_PERCENT", 100),
+			MetricIntervalMs: getEnvInt("OTEL_METRIC_EXPORT_INTERVAL", 60000),
+		},
+		SelfMonitor: SelfMonitorConfig{
+			Enabled:      getEnv("SELF_MONITOR_ENABLED", "false") == "true",
+			Service:      getEnv("SELF_MONITOR_SERVICE", "clicklite"),
+			Level:        getEnv("SELF_MONITOR_LEVEL", "info"),
+			MaxPerSecond: getEnvInt("SELF_MONITOR_MAX_PER_SECOND", 100),
+		},
+		Metrics: MetricsConfig{
+			MaxSeriesPerMetric: getEnvInt("METRICS_MAX_SERIES_PER_METRIC", 1000),
+			SeriesLimits:       getEnvList("METRICS_SERIES_LIMITS"),
+			SeriesTTLSeconds:   getEnvInt("METRICS_SERIES_TTL_SECONDS", 3600),
+			HistogramBuckets:   getEnvList("METRICS_HISTOGRAM_BUCKETS"),
+		},
+		Capacity: CapacityConfig{
+			SampleIntervalSeconds: getEnvInt("CAPACITY_SAMPLE_INTERVAL_SECONDS", 300),
+			HistoryDays:           getEnvInt("CAPACITY_HISTORY_DAYS", 7),
+		},
+		Errors: ErrorsConfig{
+			PatternsFile:              getEnv("ERRORS_PATTERNS_FILE", "./data/error_patterns.json"),
+			GroupsFile:                getEnv("ERRORS_GROUPS_FILE", "./data/error_groups.json"),
+			BaselinesFile:             getEnv("ERRORS_BASELINES_FILE", "./data/error_baselines.json"),
+			CheckpointIntervalSeconds: getEnvInt("ERRORS_CHECKPOINT_INTERVAL_SECONDS", 60),
+		},
+		Patterns: PatternsConfig{
+			MaxPatterns:       getEnvInt("PATTERNS_MAX", 5000),
+			SimilarityPercent: getEnvInt("PATTERNS_SIMILARITY_PERCENT", 40),
+		},
+		Traces: TracesConfig{
+			RetentionDays:        getEnvInt("TRACES_RETENTION_DAYS", 30),
+			FlushIntervalSeconds: getEnvInt("TRACES_FLUSH_INTERVAL_SECONDS", 10),
+		},
 	}
 }
 
@@ -49,4 +280,22 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvList splits a comma-separated variable, returning nil when unset
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,268 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/monitoring"
+)
+
+// WidgetAlertSource is the source of the alerts raised by widgets
+const WidgetAlertSource = "dashboard"
+
+const (
+	defaultWidgetAlertInterval = 60 * time.Second
+	minWidgetAlertInterval     = 15 * time.Second
+)
+
+// WidgetAlerter evaluates the alerts of dashboard widgets on their
+// interval and raises or resolves them in the alert manager. Widget
+// queries run as the dashboard's owner over the dashboard's time range.
+type WidgetAlerter struct {
+	service *Service
+	alerts  *monitoring.AlertManager
+
+	// Only used by the Start goroutine
+	nextRun map[string]time.Time                // by alert name
+	raised  map[string]monitoring.AlertSeverity // active alerts by name
+}
+
+// widgetAlertCheck is a widget with an alert and the dashboard it is on
+type widgetAlertCheck struct {
+	dashboardID   string
+	dashboardName string
+	owner         string
+	timeRange     *models.QueryTimeRange
+	widget        models.DashboardWidget
+}
+
+// NewWidgetAlerter creates an alerter raising alerts in alerts
+func NewWidgetAlerter(service *Service, alerts *monitoring.AlertManager) *WidgetAlerter {
+	return &WidgetAlerter{
+		service: service,
+		alerts:  alerts,
+		nextRun: make(map[string]time.Time),
+		raised:  make(map[string]monitoring.AlertSeverity),
+	}
+}
+
+// Start evaluates due widget alerts until ctx is cancelled
+func (wa *WidgetAlerter) Start(ctx context.Context) {
+	ticker := time.NewTicker(minWidgetAlertInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			wa.runDue(ctx, time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runDue evaluates the widget alerts whose interval has passed and
+// resolves the alerts of widgets that no longer have one
+func (wa *WidgetAlerter) runDue(ctx context.Context, now time.Time) {
+	current := make(map[string]bool)
+	for _, check := range wa.service.alertingWidgets() {
+		name := widgetAlertName(check.dashboardID, check.widget.ID)
+		current[name] = true
+		if next, ok := wa.nextRun[name]; ok && now.Before(next) {
+			continue
+		}
+		wa.nextRun[name] = now.Add(widgetAlertInterval(check.widget.Config.Alert))
+		wa.evaluate(ctx, check, name)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	for name := range wa.nextRun {
+		if current[name] {
+			continue
+		}
+		delete(wa.nextRun, name)
+		if _, ok := wa.raised[name]; ok {
+			wa.alerts.ResolveAlert(name)
+			delete(wa.raised, name)
+		}
+	}
+}
+
+// evaluate runs a widget's query and raises, escalates or resolves its alert
+func (wa *WidgetAlerter) evaluate(ctx context.Context, check widgetAlertCheck, name string) {
+	alert := check.widget.Config.Alert
+	widget, err := wa.service.ApplyTimeRange(&check.widget, check.timeRange)
+	var result *models.QueryBuilderResponse
+	if err == nil {
+		owner := auth.User{ID: check.owner, Role: auth.RoleUser}
+		result, err = wa.service.ExecuteWidgetQuery(auth.WithUser(ctx, owner), widget)
+	}
+	if err == nil && result.Error != "" {
+		err = fmt.Errorf("query error: %s", result.Error)
+	}
+	var field string
+	var value float64
+	var ok bool
+	if err == nil {
+		field, value, ok, err = widgetAlertValue(widget, result)
+	}
+	if err != nil {
+		log.Warn().Err(err).
+			Str("dashboard_id", check.dashboardID).
+			Str("widget_id", check.widget.ID).
+			Msg("Failed to evaluate widget alert")
+		return
+	}
+	if !ok {
+		return
+	}
+
+	severity, threshold := crossedThreshold(alert, value)
+	previous, active := wa.raised[name]
+	if severity == "" {
+		if active {
+			wa.alerts.ResolveAlert(name)
+			delete(wa.raised, name)
+		}
+		return
+	}
+	// A new severity starts a new alert
+	if active && previous != severity {
+		wa.alerts.ResolveAlert(name)
+	}
+
+	wa.alerts.RaiseAlert(name, severity, WidgetAlertSource,
+		fmt.Sprintf("%s / %s: %s is %g (%s %g)", check.dashboardName, check.widget.Title, field, value, alert.Operator, threshold),
+		map[string]interface{}{
+			"dashboard_id": check.dashboardID,
+			"widget_id":    check.widget.ID,
+			"value":        value,
+			"threshold":    threshold,
+			"link":         fmt.Sprintf("/dashboard/%s?widget=%s", url.PathEscape(check.dashboardID), url.QueryEscape(check.widget.ID)),
+		})
+	wa.raised[name] = severity
+}
+
+// alertingWidgets returns the widgets with an enabled alert. Built-in
+// dashboards have no owner to run them as.
+func (s *Service) alertingWidgets() []widgetAlertCheck {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var checks []widgetAlertCheck
+	for _, dashboard := range s.dashboards {
+		if dashboard.CreatedBy == BuiltInOwner {
+			continue
+		}
+		for _, widget := range dashboard.Widgets {
+			if alert := widget.Config.Alert; alert == nil || alert.Disabled || !alertableWidgetTypes[widget.Type] {
+				continue
+			}
+			checks = append(checks, widgetAlertCheck{
+				dashboardID:   dashboard.ID,
+				dashboardName: dashboard.Name,
+				owner:         dashboard.CreatedBy,
+				timeRange:     dashboard.Settings.TimeRange,
+				widget:        widget,
+			})
+		}
+	}
+	return checks
+}
+
+// alertableWidgetTypes are the widgets with a single current value
+var alertableWidgetTypes = map[string]bool{
+	"metric": true,
+	"chart":  true,
+}
+
+// widgetAlertValue returns the column an alert reads and its value, from
+// the first row of a metric widget or the last row of a chart. It reports
+// false when the query returned no rows or the value is not a number.
+func widgetAlertValue(widget *models.DashboardWidget, result *models.QueryBuilderResponse) (string, float64, bool, error) {
+	if len(result.Rows) == 0 {
+		return "", 0, false, nil
+	}
+	field := widget.Config.Alert.Field
+	if field == "" {
+		field = widget.Config.ValueField
+	}
+	field, err := valueColumn(field, resultColumns(result), result.Rows)
+	if err != nil {
+		return "", 0, false, err
+	}
+
+	row := result.Rows[len(result.Rows)-1]
+	if widget.Type == "metric" {
+		row = result.Rows[0]
+	}
+	value, ok := toFloat(row[field])
+	return field, value, ok, nil
+}
+
+// crossedThreshold returns the severity of the highest threshold value
+// crosses and that threshold, or "" when it crosses none
+func crossedThreshold(alert *models.WidgetAlert, value float64) (monitoring.AlertSeverity, float64) {
+	if alert.Critical != nil && crosses(value, alert.Operator, *alert.Critical) {
+		return monitoring.SeverityCritical, *alert.Critical
+	}
+	if alert.Warning != nil && crosses(value, alert.Operator, *alert.Warning) {
+		return monitoring.SeverityWarning, *alert.Warning
+	}
+	return "", 0
+}
+
+func crosses(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	}
+	return false
+}
+
+func validateWidgetAlert(widget *models.DashboardWidget) error {
+	alert := widget.Config.Alert
+	if !alertableWidgetTypes[widget.Type] {
+		return fmt.Errorf("alerts are only supported on metric and chart widgets")
+	}
+	switch alert.Operator {
+	case ">", ">=", "<", "<=":
+	default:
+		return fmt.Errorf("invalid alert operator: %s (must be >, >=, < or <=)", alert.Operator)
+	}
+	if alert.Warning == nil && alert.Critical == nil {
+		return fmt.Errorf("alert needs a warning or critical threshold")
+	}
+	if alert.Interval < 0 {
+		return fmt.Errorf("alert interval cannot be negative")
+	}
+	return nil
+}
+
+func widgetAlertInterval(alert *models.WidgetAlert) time.Duration {
+	if alert.Interval <= 0 {
+		return defaultWidgetAlertInterval
+	}
+	interval := time.Duration(alert.Interval) * time.Second
+	if interval < minWidgetAlertInterval {
+		interval = minWidgetAlertInterval
+	}
+	return interval
+}
+
+func widgetAlertName(dashboardID, widgetID string) string {
+	return fmt.Sprintf("dashboard_widget_%s_%s", dashboardID, widgetID)
+}
@@ -0,0 +1,351 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// Kinds of annotations. Events and deploys are created through the API,
+// query annotations are derived from a dashboard's annotation queries each
+// time they are read, and alert annotations record the alerts external
+// systems send through their webhook.
+const (
+	AnnotationEvent  = "event"
+	AnnotationDeploy = "deploy"
+	AnnotationQuery  = "query"
+	AnnotationAlert  = "alert"
+)
+
+const (
+	// maxQueryAnnotations bounds the annotations one query derives
+	maxQueryAnnotations = 500
+	// defaultAnnotationWindow is the time range annotations are read for
+	// on dashboards without one
+	defaultAnnotationWindow = 24 * time.Hour
+)
+
+// annotatedWidgetTypes are the widgets with a time axis to draw annotations on
+var annotatedWidgetTypes = map[string]bool{
+	"chart":   true,
+	"heatmap": true,
+}
+
+// annotationTimeLayouts are the forms ClickHouse returns Date and DateTime
+// columns in
+var annotationTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	time.RFC3339Nano,
+	"2006-01-02",
+}
+
+// CreateAnnotation records an event or deploy annotation. Annotations of a
+// dashboard need the editor role on it; those without one appear on every
+// dashboard and need the global admin role.
+func (s *Service) CreateAnnotation(ctx context.Context, annotation *models.Annotation) error {
+	if annotation.Kind == "" {
+		annotation.Kind = AnnotationEvent
+	}
+	if annotation.Kind != AnnotationEvent && annotation.Kind != AnnotationDeploy {
+		return fmt.Errorf("invalid annotation kind: %s (must be event or deploy)", annotation.Kind)
+	}
+	if annotation.Title == "" {
+		return fmt.Errorf("annotation title is required")
+	}
+	if annotation.Time.IsZero() {
+		annotation.Time = time.Now().UTC()
+	}
+	if annotation.EndTime != nil && annotation.EndTime.Before(annotation.Time) {
+		return fmt.Errorf("annotation end_time is before its time")
+	}
+
+	user := auth.UserFromContext(ctx)
+	annotation.ID = uuid.New().String()
+	annotation.QueryID = ""
+	annotation.CreatedAt = time.Now()
+	annotation.CreatedBy = user.ID
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if annotation.DashboardID != "" {
+		if _, err := s.lookupDashboard(ctx, annotation.DashboardID, RoleEditor); err != nil {
+			return err
+		}
+	} else if !user.IsAdmin() {
+		return fmt.Errorf("access denied to annotations of every dashboard")
+	}
+
+	if s.repository != nil {
+		if err := s.repository.SaveAnnotation(annotation); err != nil {
+			return fmt.Errorf("failed to save annotation: %w", err)
+		}
+	}
+	s.annotations[annotation.ID] = annotation
+
+	log.Info().
+		Str("annotation_id", annotation.ID).
+		Str("dashboard_id", annotation.DashboardID).
+		Str("kind", annotation.Kind).
+		Str("user_id", user.ID).
+		Msg("Dashboard annotation created")
+
+	return nil
+}
+
+// DeleteAnnotation deletes an annotation. Its creator and the editors of
+// its dashboard may delete it, or global admins when it has none.
+func (s *Service) DeleteAnnotation(ctx context.Context, annotationID string) error {
+	user := auth.UserFromContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	annotation, exists := s.annotations[annotationID]
+	if !exists {
+		return fmt.Errorf("annotation not found: %s", annotationID)
+	}
+	if annotation.CreatedBy != user.ID && !user.IsAdmin() {
+		if annotation.DashboardID == "" {
+			return fmt.Errorf("access denied to annotation: %s", annotationID)
+		}
+		if _, err := s.lookupDashboard(ctx, annotation.DashboardID, RoleEditor); err != nil {
+			return err
+		}
+	}
+
+	if s.repository != nil {
+		if err := s.repository.DeleteAnnotation(annotationID); err != nil {
+			return fmt.Errorf("failed to delete annotation: %w", err)
+		}
+	}
+	delete(s.annotations, annotationID)
+
+	log.Info().
+		Str("annotation_id", annotationID).
+		Str("user_id", user.ID).
+		Msg("Dashboard annotation deleted")
+
+	return nil
+}
+
+// ListAnnotations returns the annotations of a dashboard, those of every
+// dashboard and those its annotation queries derive over timeRange, or
+// the dashboard's time range when nil, or else the last day. They are
+// ordered by time. With tags, only annotations with one of them are
+// returned. Annotation queries that fail are left out.
+func (s *Service) ListAnnotations(ctx context.Context, dashboardID string, timeRange *models.QueryTimeRange, tags []string) ([]*models.Annotation, error) {
+	s.mu.RLock()
+	dashboard, err := s.lookupDashboard(ctx, dashboardID, RoleViewer)
+	if err != nil {
+		s.mu.RUnlock()
+		return nil, err
+	}
+	if timeRange == nil {
+		timeRange = dashboard.Settings.TimeRange
+	}
+	end := time.Now()
+	start := end.Add(-defaultAnnotationWindow)
+	if timeRange != nil {
+		if start, end, err = s.queryBuilder.ResolveTimeRange(timeRange); err != nil {
+			s.mu.RUnlock()
+			return nil, err
+		}
+	}
+
+	annotations := make([]*models.Annotation, 0)
+	for _, annotation := range s.annotations {
+		if (annotation.DashboardID == "" || annotation.DashboardID == dashboardID) &&
+			annotationInRange(annotation, start, end) && hasAnyTag(annotation.Tags, tags) {
+			annotations = append(annotations, annotation)
+		}
+	}
+	queries := dashboard.AnnotationQueries
+	s.mu.RUnlock()
+
+	for i := range queries {
+		q := &queries[i]
+		if q.Disabled || (len(tags) > 0 && !hasAnyTag(q.Tags, tags)) {
+			continue
+		}
+		derived, err := s.queryAnnotations(ctx, dashboardID, q, start, end)
+		if err != nil {
+			log.Warn().Err(err).
+				Str("dashboard_id", dashboardID).
+				Str("annotation_query", q.Name).
+				Msg("Annotation query failed")
+			continue
+		}
+		annotations = append(annotations, derived...)
+	}
+
+	sort.SliceStable(annotations, func(i, j int) bool { return annotations[i].Time.Before(annotations[j].Time) })
+	return annotations, nil
+}
+
+// WidgetAnnotations returns the annotations to draw on a widget, as
+// returned by ApplyTimeRange, over the time range its query builder reads
+// or else timeRange. Widgets without a time axis have none.
+func (s *Service) WidgetAnnotations(ctx context.Context, dashboardID string, widget *models.DashboardWidget, timeRange *models.QueryTimeRange) ([]*models.Annotation, error) {
+	if !annotatedWidgetTypes[widget.Type] {
+		return nil, nil
+	}
+	if qb := widget.DataSource.QueryBuilder; widget.DataSource.Type == "query_builder" && qb != nil && qb.TimeRange != nil {
+		timeRange = qb.TimeRange
+	}
+	return s.ListAnnotations(ctx, dashboardID, timeRange, widget.Config.AnnotationTags)
+}
+
+// queryAnnotations runs an annotation query between start and end and
+// turns each of its rows into an annotation
+func (s *Service) queryAnnotations(ctx context.Context, dashboardID string, q *models.AnnotationQuery, start, end time.Time) ([]*models.Annotation, error) {
+	widget, err := s.ApplyTimeRange(&models.DashboardWidget{
+		ID:         q.ID,
+		Type:       "table",
+		Title:      q.Name,
+		DataSource: q.DataSource,
+	}, &models.QueryTimeRange{Start: start, End: end})
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.ExecuteWidgetQuery(ctx, widget)
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("query error: %s", result.Error)
+	}
+	if len(result.Rows) == 0 {
+		return nil, nil
+	}
+
+	columns := resultColumns(result)
+	timeField := q.TimeField
+	if timeField == "" {
+		timeField = columns[0]
+	}
+	for _, field := range []string{timeField, q.EndField, q.TitleField, q.TextField} {
+		if field != "" && !containsColumn(columns, field) {
+			return nil, fmt.Errorf("annotation query %s returns no column %s", q.Name, field)
+		}
+	}
+
+	annotations := make([]*models.Annotation, 0, len(result.Rows))
+	for i, row := range result.Rows {
+		if len(annotations) == maxQueryAnnotations {
+			break
+		}
+		at, ok := annotationTime(row[timeField])
+		if !ok {
+			continue
+		}
+		annotation := &models.Annotation{
+			ID:          fmt.Sprintf("%s-%d", q.ID, i),
+			DashboardID: dashboardID,
+			Kind:        AnnotationQuery,
+			Title:       q.Name,
+			Tags:        q.Tags,
+			Time:        at,
+			QueryID:     q.ID,
+		}
+		if q.EndField != "" {
+			if endAt, ok := annotationTime(row[q.EndField]); ok && !endAt.Before(at) {
+				annotation.EndTime = &endAt
+			}
+		}
+		if q.TitleField != "" && row[q.TitleField] != nil {
+			annotation.Title = fmt.Sprintf("%v", row[q.TitleField])
+		}
+		if q.TextField != "" && row[q.TextField] != nil {
+			annotation.Text = fmt.Sprintf("%v", row[q.TextField])
+		}
+		if annotationInRange(annotation, start, end) {
+			annotations = append(annotations, annotation)
+		}
+	}
+	return annotations, nil
+}
+
+// prepareAnnotationQueries gives new annotation queries an ID
+func prepareAnnotationQueries(queries []models.AnnotationQuery) {
+	for i := range queries {
+		if queries[i].ID == "" {
+			queries[i].ID = uuid.New().String()
+		}
+	}
+}
+
+func validateAnnotationQueries(queries []models.AnnotationQuery) error {
+	ids := make(map[string]bool, len(queries))
+	for _, q := range queries {
+		if q.Name == "" {
+			return fmt.Errorf("annotation query name is required")
+		}
+		if ids[q.ID] {
+			return fmt.Errorf("duplicate annotation query id: %s", q.ID)
+		}
+		ids[q.ID] = true
+
+		switch q.DataSource.Type {
+		case "query_builder":
+			if q.DataSource.QueryBuilder == nil {
+				return fmt.Errorf("annotation query %s: query builder configuration missing", q.Name)
+			}
+		case "saved_query", "custom_sql":
+		default:
+			return fmt.Errorf("annotation query %s: unsupported data source type: %s", q.Name, q.DataSource.Type)
+		}
+	}
+	return nil
+}
+
+// annotationInRange reports whether an annotation overlaps [start, end].
+// Alerts without an end are still firing.
+func annotationInRange(annotation *models.Annotation, start, end time.Time) bool {
+	last := annotation.Time
+	if annotation.EndTime != nil {
+		last = *annotation.EndTime
+	} else if annotation.Kind == AnnotationAlert {
+		last = time.Now()
+	}
+	return !annotation.Time.After(end) && !last.Before(start)
+}
+
+// hasAnyTag reports whether tags include one of wanted, or wanted is empty
+func hasAnyTag(tags []string, wanted []string) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		for _, w := range wanted {
+			if strings.EqualFold(tag, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// annotationTime reads a time column, a number being Unix seconds. Times
+// without a zone are UTC, as the bounds widget queries get.
+func annotationTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range annotationTimeLayouts {
+			if t, err := time.ParseInLocation(layout, v, time.UTC); err == nil {
+				return t, true
+			}
+		}
+	}
+	if seconds, ok := toFloat(value); ok {
+		return time.Unix(0, int64(seconds*float64(time.Second))).UTC(), true
+	}
+	return time.Time{}, false
+}
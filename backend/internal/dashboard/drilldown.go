@@ -0,0 +1,124 @@
+package dashboard
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/querybuilder"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/search"
+)
+
+const (
+	// drillDownSearchPath is the log search endpoint drill-downs link to
+	drillDownSearchPath = "/api/v1/search"
+	// defaultDrillDownBucket is the bucket of a clicked time on widgets
+	// without a time bucket
+	defaultDrillDownBucket = time.Minute
+	// defaultDrillDownWindow is searched when the widget has no time range
+	defaultDrillDownWindow = 24 * time.Hour
+)
+
+// DrillDown returns the log search behind a clicked series or point of a
+// widget, as returned by ApplyTimeRange. The search has the widget's
+// drill-down terms and matches value on the drill-down field, unless value
+// is empty. It covers the time bucket of at, or without at the range the
+// widget reads: its query builder's, else timeRange, else the last day.
+func (s *Service) DrillDown(widget *models.DashboardWidget, timeRange *models.QueryTimeRange, value string, at *time.Time) (*models.DrillDownLink, error) {
+	drillDown := widget.Config.DrillDown
+	if drillDown == nil {
+		return nil, fmt.Errorf("widget %s has no drill-down", widget.ID)
+	}
+
+	var qb *models.QueryBuilder
+	if widget.DataSource.Type == "query_builder" {
+		qb = widget.DataSource.QueryBuilder
+	}
+	if qb != nil && qb.TimeRange != nil {
+		timeRange = qb.TimeRange
+	}
+	end := time.Now()
+	start := end.Add(-defaultDrillDownWindow)
+	if timeRange != nil {
+		var err error
+		if start, end, err = s.queryBuilder.ResolveTimeRange(timeRange); err != nil {
+			return nil, err
+		}
+	}
+	if at != nil {
+		bucket := drillDownBucket(drillDown, qb, end.Sub(start))
+		start = querybuilder.AlignToInterval(*at, bucket)
+		end = start.Add(bucket)
+	}
+
+	terms := make([]string, 0, 2)
+	if q := strings.TrimSpace(drillDown.Query); q != "" {
+		terms = append(terms, q)
+	}
+	if value != "" {
+		field := drillDownField(widget, qb)
+		if field == "" {
+			return nil, fmt.Errorf("widget %s has no drill-down field", widget.ID)
+		}
+		terms = append(terms, searchTerm(field, value))
+	}
+	query := strings.Join(terms, " ")
+	if _, err := search.Parse(query); err != nil {
+		return nil, fmt.Errorf("invalid drill-down search: %w", err)
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("start_time", start.UTC().Format(time.RFC3339))
+	params.Set("end_time", end.UTC().Format(time.RFC3339))
+	return &models.DrillDownLink{
+		URL:       drillDownSearchPath + "?" + params.Encode(),
+		Query:     query,
+		StartTime: start.UTC(),
+		EndTime:   end.UTC(),
+	}, nil
+}
+
+// drillDownField returns the field a clicked value filters
+func drillDownField(widget *models.DashboardWidget, qb *models.QueryBuilder) string {
+	if field := widget.Config.DrillDown.Field; field != "" {
+		return field
+	}
+	if qb != nil && len(qb.GroupBy) > 0 {
+		return qb.GroupBy[0]
+	}
+	return widget.Config.LabelField
+}
+
+// drillDownBucket returns the configured bucket, or the interval the
+// query builder buckets span by
+func drillDownBucket(drillDown *models.WidgetDrillDown, qb *models.QueryBuilder, span time.Duration) time.Duration {
+	if drillDown.Bucket > 0 {
+		return time.Duration(drillDown.Bucket) * time.Second
+	}
+	if qb != nil && qb.TimeBucket != nil {
+		if interval, err := querybuilder.ChooseInterval(qb.TimeBucket.Interval, span, qb.TimeBucket.MaxPoints); err == nil {
+			return interval
+		}
+	}
+	return defaultDrillDownBucket
+}
+
+// searchTerm matches value on field. The search syntax has no escapes, so
+// quotes in value become wildcards.
+func searchTerm(field, value string) string {
+	return fmt.Sprintf(`%s:"%s"`, field, strings.ReplaceAll(value, `"`, "*"))
+}
+
+func validateWidgetDrillDown(widget *models.DashboardWidget) error {
+	drillDown := widget.Config.DrillDown
+	if drillDown.Bucket < 0 {
+		return fmt.Errorf("drill-down bucket cannot be negative")
+	}
+	if _, err := search.Parse(drillDown.Query); err != nil {
+		return fmt.Errorf("invalid drill-down query: %w", err)
+	}
+	return nil
+}
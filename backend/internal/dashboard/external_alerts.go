@@ -0,0 +1,161 @@
+package dashboard
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// ExternalAlertTag tags every annotation recording an external alert
+const ExternalAlertTag = "alertmanager"
+
+// maxWebhookAlerts bounds the alerts one webhook call records
+const maxWebhookAlerts = 1000
+
+// AlertmanagerWebhook is the payload of an Alertmanager webhook receiver,
+// version 4
+type AlertmanagerWebhook struct {
+	Version           string              `json:"version"`
+	GroupKey          string              `json:"groupKey"`
+	TruncatedAlerts   int                 `json:"truncatedAlerts"`
+	Status            string              `json:"status"` // firing or resolved
+	Receiver          string              `json:"receiver"`
+	GroupLabels       map[string]string   `json:"groupLabels"`
+	CommonLabels      map[string]string   `json:"commonLabels"`
+	CommonAnnotations map[string]string   `json:"commonAnnotations"`
+	ExternalURL       string              `json:"externalURL"`
+	Alerts            []AlertmanagerAlert `json:"alerts"`
+}
+
+// AlertmanagerAlert is one alert of an Alertmanager webhook payload
+type AlertmanagerAlert struct {
+	Status       string            `json:"status"` // firing or resolved
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"` // zero, or in the future, while firing
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// RecordAlertmanagerAlerts records the alerts of an Alertmanager webhook as
+// annotations of every dashboard, spanning from when each alert started to
+// when it resolved, so that they show next to the logs of the same time.
+// Alertmanager sends firing alerts again until they resolve: each alert
+// episode keeps a single annotation, updated as it changes. Tags are the
+// alerts' labels, as name=value, for widgets to pick the alerts of their
+// services. It returns the annotations recorded.
+func (s *Service) RecordAlertmanagerAlerts(webhook *AlertmanagerWebhook) ([]*models.Annotation, error) {
+	if len(webhook.Alerts) == 0 {
+		return nil, fmt.Errorf("webhook has no alerts")
+	}
+	if len(webhook.Alerts) > maxWebhookAlerts {
+		return nil, fmt.Errorf("webhook has %d alerts, at most %d are accepted", len(webhook.Alerts), maxWebhookAlerts)
+	}
+
+	now := time.Now().UTC()
+	annotations := make([]*models.Annotation, 0, len(webhook.Alerts))
+	for i := range webhook.Alerts {
+		annotation, err := externalAlertAnnotation(&webhook.Alerts[i], now)
+		if err != nil {
+			return nil, err
+		}
+		annotations = append(annotations, annotation)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, annotation := range annotations {
+		if existing, ok := s.annotations[annotation.ID]; ok {
+			annotation.CreatedAt = existing.CreatedAt
+		}
+		if s.repository != nil {
+			if err := s.repository.SaveAnnotation(annotation); err != nil {
+				return nil, fmt.Errorf("failed to save annotation: %w", err)
+			}
+		}
+		s.annotations[annotation.ID] = annotation
+	}
+
+	log.Info().
+		Str("receiver", webhook.Receiver).
+		Str("status", webhook.Status).
+		Int("alerts", len(annotations)).
+		Msg("External alerts recorded")
+
+	return annotations, nil
+}
+
+// externalAlertAnnotation turns an Alertmanager alert into the annotation
+// of its episode
+func externalAlertAnnotation(alert *AlertmanagerAlert, now time.Time) (*models.Annotation, error) {
+	name := alert.Labels["alertname"]
+	if name == "" {
+		return nil, fmt.Errorf("alert has no alertname label")
+	}
+	if alert.StartsAt.IsZero() {
+		return nil, fmt.Errorf("alert %s has no startsAt", name)
+	}
+
+	annotation := &models.Annotation{
+		ID:        externalAlertID(alert),
+		Kind:      AnnotationAlert,
+		Title:     name,
+		Text:      alert.Annotations["summary"],
+		Tags:      externalAlertTags(alert.Labels),
+		Time:      alert.StartsAt.UTC(),
+		CreatedAt: now,
+		CreatedBy: ExternalAlertTag,
+	}
+	if annotation.Text == "" {
+		annotation.Text = alert.Annotations["description"]
+	}
+	if alert.GeneratorURL != "" {
+		annotation.Text = strings.TrimSpace(annotation.Text + "\n" + alert.GeneratorURL)
+	}
+	// Firing alerts have no end yet, whatever endsAt Alertmanager predicts
+	if alert.Status == "resolved" {
+		end := alert.EndsAt.UTC()
+		if alert.EndsAt.IsZero() || end.After(now) {
+			end = now
+		}
+		if end.Before(annotation.Time) {
+			end = annotation.Time
+		}
+		annotation.EndTime = &end
+	}
+	return annotation, nil
+}
+
+// externalAlertID identifies an alert episode: the alert, by fingerprint
+// or else its labels, and when it started
+func externalAlertID(alert *AlertmanagerAlert) string {
+	fingerprint := alert.Fingerprint
+	if fingerprint == "" {
+		hash := sha256.New()
+		for _, tag := range externalAlertTags(alert.Labels)[1:] {
+			hash.Write([]byte(tag))
+			hash.Write([]byte{0})
+		}
+		fingerprint = hex.EncodeToString(hash.Sum(nil))[:16]
+	}
+	return fmt.Sprintf("alertmanager-%s-%d", fingerprint, alert.StartsAt.Unix())
+}
+
+// externalAlertTags returns the alertmanager tag followed by the labels as
+// sorted name=value pairs
+func externalAlertTags(labels map[string]string) []string {
+	tags := make([]string, 0, len(labels)+1)
+	for name, value := range labels {
+		tags = append(tags, name+"="+value)
+	}
+	sort.Strings(tags)
+	return append([]string{ExternalAlertTag}, tags...)
+}
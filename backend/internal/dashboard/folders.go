@@ -0,0 +1,333 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// maxFolderDepth bounds how deeply folders can be nested
+const maxFolderDepth = 10
+
+// CreateFolder creates a folder owned by the user in ctx, inside ParentID
+// when set, which requires the editor role on the parent
+func (s *Service) CreateFolder(ctx context.Context, folder *models.DashboardFolder) error {
+	if folder.Name == "" {
+		return fmt.Errorf("folder name is required")
+	}
+	if err := validatePermissions(folder.Permissions); err != nil {
+		return err
+	}
+
+	user := auth.UserFromContext(ctx)
+	folder.ID = uuid.New().String()
+	folder.CreatedAt = time.Now()
+	folder.UpdatedAt = folder.CreatedAt
+	folder.CreatedBy = user.ID
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if folder.ParentID != "" {
+		if _, err := s.lookupFolder(ctx, folder.ParentID, RoleEditor); err != nil {
+			return err
+		}
+		if s.folderDepth(folder.ParentID)+1 > maxFolderDepth {
+			return fmt.Errorf("folders cannot be nested more than %d deep", maxFolderDepth)
+		}
+	}
+
+	if s.repository != nil {
+		if err := s.repository.SaveFolder(folder); err != nil {
+			return fmt.Errorf("failed to save folder: %w", err)
+		}
+	}
+	s.folders[folder.ID] = folder
+
+	log.Info().
+		Str("folder_id", folder.ID).
+		Str("name", folder.Name).
+		Str("user_id", user.ID).
+		Msg("Dashboard folder created")
+
+	return nil
+}
+
+// GetFolder retrieves a folder by ID
+func (s *Service) GetFolder(ctx context.Context, folderID string) (*models.DashboardFolder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lookupFolder(ctx, folderID, RoleViewer)
+}
+
+// UpdateFolder renames, moves or reshares a folder. Renaming needs the
+// editor role, the rest the admin role. Moving a folder takes its
+// dashboards and subfolders with it.
+func (s *Service) UpdateFolder(ctx context.Context, folderID string, updates map[string]interface{}) (*models.DashboardFolder, error) {
+	role := RoleEditor
+	for _, key := range []string{"parent_id", "is_public", "shared_with"} {
+		if _, ok := updates[key]; ok {
+			role = RoleAdmin
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, err := s.lookupFolder(ctx, folderID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *current
+	folder := &updated
+	if name, ok := updates["name"].(string); ok {
+		if name == "" {
+			return nil, fmt.Errorf("folder name is required")
+		}
+		folder.Name = name
+	}
+	if description, ok := updates["description"].(string); ok {
+		folder.Description = description
+	}
+	if isPublic, ok := updates["is_public"].(bool); ok {
+		folder.IsPublic = isPublic
+	}
+	if sharedWith, ok := updates["shared_with"]; ok {
+		if data, err := json.Marshal(sharedWith); err == nil {
+			var users []string
+			if err := json.Unmarshal(data, &users); err == nil {
+				folder.SharedWith = users
+			}
+		}
+	}
+	if parentID, ok := updates["parent_id"].(string); ok && parentID != folder.ParentID {
+		if err := s.checkFolderParent(ctx, folderID, parentID); err != nil {
+			return nil, err
+		}
+		folder.ParentID = parentID
+	}
+
+	folder.UpdatedAt = time.Now()
+
+	if s.repository != nil {
+		if err := s.repository.SaveFolder(folder); err != nil {
+			return nil, fmt.Errorf("failed to save folder: %w", err)
+		}
+	}
+	s.folders[folderID] = folder
+
+	log.Info().
+		Str("folder_id", folderID).
+		Str("user_id", auth.UserFromContext(ctx).ID).
+		Msg("Dashboard folder updated")
+
+	return folder, nil
+}
+
+// DeleteFolder deletes an empty folder
+func (s *Service) DeleteFolder(ctx context.Context, folderID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.lookupFolder(ctx, folderID, RoleAdmin); err != nil {
+		return err
+	}
+
+	// Contents may belong to other users, so they are never deleted with it
+	for _, child := range s.folders {
+		if child.ParentID == folderID {
+			return fmt.Errorf("folder is not empty: %s", folderID)
+		}
+	}
+	for _, dashboard := range s.dashboards {
+		if dashboard.FolderID == folderID {
+			return fmt.Errorf("folder is not empty: %s", folderID)
+		}
+	}
+
+	if s.repository != nil {
+		if err := s.repository.DeleteFolder(folderID); err != nil {
+			return fmt.Errorf("failed to delete folder: %w", err)
+		}
+	}
+	delete(s.folders, folderID)
+
+	log.Info().
+		Str("folder_id", folderID).
+		Str("user_id", auth.UserFromContext(ctx).ID).
+		Msg("Dashboard folder deleted")
+
+	return nil
+}
+
+// ListFolders lists the folders accessible to the user in ctx, sorted by
+// name. The hierarchy is given by their ParentID.
+func (s *Service) ListFolders(ctx context.Context) []*models.DashboardFolder {
+	user := auth.UserFromContext(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	folders := make([]*models.DashboardFolder, 0)
+	for _, folder := range s.folders {
+		if s.folderRole(folder, user) != "" {
+			folders = append(folders, folder)
+		}
+	}
+	sort.Slice(folders, func(i, j int) bool { return folders[i].Name < folders[j].Name })
+	return folders
+}
+
+// MoveDashboard moves a dashboard into a folder, or to the top level when
+// folderID is empty. This needs the admin role on the dashboard, since
+// the folder's permissions then apply to it, and the editor role on the
+// folder.
+func (s *Service) MoveDashboard(ctx context.Context, dashboardID string, folderID string) (*models.Dashboard, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, err := s.lookupDashboard(ctx, dashboardID, RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if folderID != "" {
+		if _, err := s.lookupFolder(ctx, folderID, RoleEditor); err != nil {
+			return nil, err
+		}
+	}
+
+	updated := *current
+	updated.FolderID = folderID
+	updated.UpdatedAt = time.Now()
+
+	if s.repository != nil {
+		if err := s.repository.SaveDashboard(&updated); err != nil {
+			return nil, fmt.Errorf("failed to save dashboard: %w", err)
+		}
+	}
+	s.dashboards[dashboardID] = &updated
+
+	log.Info().
+		Str("dashboard_id", dashboardID).
+		Str("folder_id", folderID).
+		Str("user_id", auth.UserFromContext(ctx).ID).
+		Msg("Dashboard moved")
+
+	return &updated, nil
+}
+
+// CloneDashboard copies a dashboard the user in ctx can view into a new
+// private dashboard owned by them. The copy is named name, or after the
+// original when empty, and placed in folderID, or the original's folder
+// when nil and the user may add dashboards to it. Variables set the values
+// of the original's variables in the copy, which is how templates are
+// instantiated.
+func (s *Service) CloneDashboard(ctx context.Context, dashboardID string, name string, folderID *string, variables map[string]string) (*models.Dashboard, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	source, err := s.lookupDashboard(ctx, dashboardID, RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	// A JSON round trip copies the widgets and settings without sharing slices or maps
+	data, err := json.Marshal(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy dashboard: %w", err)
+	}
+	var clone models.Dashboard
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, fmt.Errorf("failed to copy dashboard: %w", err)
+	}
+
+	user := auth.UserFromContext(ctx)
+	clone.ID = uuid.New().String()
+	clone.Name = name
+	if clone.Name == "" {
+		clone.Name = source.Name + " (copy)"
+	}
+	if err := applyVariables(&clone, variables); err != nil {
+		return nil, err
+	}
+	clone.SharedWith = nil
+	clone.IsPublic = false
+	clone.IsTemplate = false
+	clone.Permissions = nil
+	clone.CreatedAt = time.Now()
+	clone.UpdatedAt = clone.CreatedAt
+	clone.CreatedBy = user.ID
+
+	if folderID != nil {
+		clone.FolderID = *folderID
+		if clone.FolderID != "" {
+			if _, err := s.lookupFolder(ctx, clone.FolderID, RoleEditor); err != nil {
+				return nil, err
+			}
+		}
+	} else if clone.FolderID != "" {
+		if _, err := s.lookupFolder(ctx, clone.FolderID, RoleEditor); err != nil {
+			clone.FolderID = ""
+		}
+	}
+
+	if s.repository != nil {
+		if err := s.repository.SaveDashboard(&clone); err != nil {
+			return nil, fmt.Errorf("failed to save dashboard: %w", err)
+		}
+	}
+	s.dashboards[clone.ID] = &clone
+
+	log.Info().
+		Str("dashboard_id", clone.ID).
+		Str("source_id", dashboardID).
+		Str("user_id", user.ID).
+		Msg("Dashboard cloned")
+
+	return &clone, nil
+}
+
+// checkFolderParent checks that a folder can be moved under parentID
+// without creating a cycle or nesting too deeply. Callers hold the lock.
+func (s *Service) checkFolderParent(ctx context.Context, folderID string, parentID string) error {
+	if parentID == "" {
+		return nil
+	}
+	if _, err := s.lookupFolder(ctx, parentID, RoleEditor); err != nil {
+		return err
+	}
+	for folder := s.folders[parentID]; folder != nil; folder = s.folders[folder.ParentID] {
+		if folder.ID == folderID {
+			return fmt.Errorf("folder cannot be moved into itself or its subfolders")
+		}
+	}
+	if s.folderDepth(parentID)+s.folderHeight(folderID) > maxFolderDepth {
+		return fmt.Errorf("folders cannot be nested more than %d deep", maxFolderDepth)
+	}
+	return nil
+}
+
+// folderDepth counts the folders from the top level down to folderID
+func (s *Service) folderDepth(folderID string) int {
+	depth := 0
+	for folder := s.folders[folderID]; folder != nil; folder = s.folders[folder.ParentID] {
+		depth++
+	}
+	return depth
+}
+
+// folderHeight counts the levels of folderID and its deepest subfolders
+func (s *Service) folderHeight(folderID string) int {
+	height := 0
+	for _, folder := range s.folders {
+		if folder.ParentID == folderID {
+			if h := s.folderHeight(folder.ID); h > height {
+				height = h
+			}
+		}
+	}
+	return height + 1
+}
@@ -0,0 +1,203 @@
+package dashboard
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+const (
+	// defaultLayoutColumns is the width of a dashboard grid without columns
+	defaultLayoutColumns = 12
+	// maxLayoutColumns bounds the columns of a grid
+	maxLayoutColumns = 48
+	// defaultLayoutName names the widgets' own layout, of the widest screens
+	defaultLayoutName = "default"
+)
+
+// LayoutFor returns where the widgets of a dashboard sit on a screen width
+// pixels wide: the narrowest breakpoint the screen fits in, or the widgets'
+// own positions on screens wider than every breakpoint. A width of 0 gets
+// the widgets' own positions.
+func (s *Service) LayoutFor(dashboard *models.Dashboard, width int) models.LayoutBreakpoint {
+	if width > 0 {
+		var best *models.LayoutBreakpoint
+		for i := range dashboard.Layout.Breakpoints {
+			breakpoint := &dashboard.Layout.Breakpoints[i]
+			if width <= breakpoint.MaxWidth && (best == nil || breakpoint.MaxWidth < best.MaxWidth) {
+				best = breakpoint
+			}
+		}
+		if best != nil {
+			return *best
+		}
+	}
+
+	placements := make([]models.WidgetPlacement, len(dashboard.Widgets))
+	for i := range dashboard.Widgets {
+		placements[i] = widgetPlacement(&dashboard.Widgets[i])
+	}
+	return models.LayoutBreakpoint{
+		Name:       defaultLayoutName,
+		Columns:    layoutColumns(dashboard.Layout),
+		Placements: placements,
+	}
+}
+
+// prepareLayout orders the breakpoints of a dashboard widest first and
+// makes each place exactly its widgets: placements of removed widgets are
+// dropped and widgets without one are added below the others, their width
+// scaled from their own to the breakpoint's columns.
+func prepareLayout(dashboard *models.Dashboard) {
+	if len(dashboard.Layout.Breakpoints) == 0 {
+		return
+	}
+	columns := layoutColumns(dashboard.Layout)
+	widgets := make(map[string]bool, len(dashboard.Widgets))
+	for _, widget := range dashboard.Widgets {
+		widgets[widget.ID] = true
+	}
+
+	// The breakpoints may be shared with the dashboard this one replaces
+	breakpoints := make([]models.LayoutBreakpoint, len(dashboard.Layout.Breakpoints))
+	for i, breakpoint := range dashboard.Layout.Breakpoints {
+		placed := make(map[string]bool, len(breakpoint.Placements))
+		placements := make([]models.WidgetPlacement, 0, len(dashboard.Widgets))
+		bottom := 0
+		for _, placement := range breakpoint.Placements {
+			if !widgets[placement.WidgetID] {
+				continue
+			}
+			placed[placement.WidgetID] = true
+			placements = append(placements, placement)
+			if end := placement.Y + placement.Height; end > bottom {
+				bottom = end
+			}
+		}
+
+		for j := range dashboard.Widgets {
+			widget := &dashboard.Widgets[j]
+			if placed[widget.ID] || breakpoint.Columns <= 0 {
+				continue
+			}
+			width := int(math.Round(float64(widget.Size.Width) * float64(breakpoint.Columns) / float64(columns)))
+			width = int(math.Max(1, math.Min(float64(width), float64(breakpoint.Columns))))
+			height := widget.Size.Height
+			if height < 1 {
+				height = 1
+			}
+			placements = append(placements, models.WidgetPlacement{
+				WidgetID: widget.ID,
+				Y:        bottom,
+				Width:    width,
+				Height:   height,
+			})
+			bottom += height
+		}
+
+		breakpoint.Placements = placements
+		breakpoints[i] = breakpoint
+	}
+	sort.SliceStable(breakpoints, func(i, j int) bool {
+		return breakpoints[i].MaxWidth > breakpoints[j].MaxWidth
+	})
+	dashboard.Layout.Breakpoints = breakpoints
+}
+
+// validateLayout checks that the widgets of a dashboard, and the
+// placements of each breakpoint, fit their grid without overlapping.
+// Widgets of auto layouts flow on the page, so only their own widths are
+// checked.
+func validateLayout(dashboard *models.Dashboard) error {
+	layout := dashboard.Layout
+	if layout.Columns < 0 || layout.Columns > maxLayoutColumns {
+		return fmt.Errorf("invalid layout columns: %d (must be between 1 and %d)", layout.Columns, maxLayoutColumns)
+	}
+	columns := layoutColumns(layout)
+
+	widgets := make(map[string]bool, len(dashboard.Widgets))
+	placements := make([]models.WidgetPlacement, len(dashboard.Widgets))
+	for i := range dashboard.Widgets {
+		widgets[dashboard.Widgets[i].ID] = true
+		placements[i] = widgetPlacement(&dashboard.Widgets[i])
+	}
+	if err := validatePlacements(defaultLayoutName, placements, columns, !layout.AutoLayout); err != nil {
+		return err
+	}
+
+	names := make(map[string]bool, len(layout.Breakpoints))
+	widths := make(map[int]bool, len(layout.Breakpoints))
+	for _, breakpoint := range layout.Breakpoints {
+		switch {
+		case breakpoint.Name == "" || breakpoint.Name == defaultLayoutName:
+			return fmt.Errorf("layout breakpoint name is required and cannot be %s", defaultLayoutName)
+		case names[breakpoint.Name]:
+			return fmt.Errorf("duplicate layout breakpoint: %s", breakpoint.Name)
+		case breakpoint.MaxWidth <= 0 || widths[breakpoint.MaxWidth]:
+			return fmt.Errorf("layout breakpoint %s needs a max width of its own", breakpoint.Name)
+		case breakpoint.Columns <= 0 || breakpoint.Columns > maxLayoutColumns:
+			return fmt.Errorf("layout breakpoint %s has invalid columns: %d (must be between 1 and %d)", breakpoint.Name, breakpoint.Columns, maxLayoutColumns)
+		}
+		names[breakpoint.Name] = true
+		widths[breakpoint.MaxWidth] = true
+
+		placed := make(map[string]bool, len(breakpoint.Placements))
+		for _, placement := range breakpoint.Placements {
+			if !widgets[placement.WidgetID] {
+				return fmt.Errorf("layout breakpoint %s places unknown widget: %s", breakpoint.Name, placement.WidgetID)
+			}
+			if placed[placement.WidgetID] {
+				return fmt.Errorf("layout breakpoint %s places widget %s twice", breakpoint.Name, placement.WidgetID)
+			}
+			placed[placement.WidgetID] = true
+		}
+		if err := validatePlacements(breakpoint.Name, breakpoint.Placements, breakpoint.Columns, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePlacements checks that placements fit a grid of columns and,
+// with overlap, that no two share a cell
+func validatePlacements(layout string, placements []models.WidgetPlacement, columns int, overlap bool) error {
+	for _, p := range placements {
+		if p.X < 0 || p.Y < 0 || p.Width < 1 || p.Height < 1 {
+			return fmt.Errorf("layout %s: widget %s needs a position of at least 0 and a size of at least 1", layout, p.WidgetID)
+		}
+		if p.X+p.Width > columns {
+			return fmt.Errorf("layout %s: widget %s does not fit in %d columns", layout, p.WidgetID, columns)
+		}
+	}
+	if !overlap {
+		return nil
+	}
+	for i, a := range placements {
+		for _, b := range placements[i+1:] {
+			if a.X < b.X+b.Width && b.X < a.X+a.Width && a.Y < b.Y+b.Height && b.Y < a.Y+a.Height {
+				return fmt.Errorf("layout %s: widgets %s and %s overlap", layout, a.WidgetID, b.WidgetID)
+			}
+		}
+	}
+	return nil
+}
+
+func widgetPlacement(widget *models.DashboardWidget) models.WidgetPlacement {
+	return models.WidgetPlacement{
+		WidgetID: widget.ID,
+		X:        widget.Position.X,
+		Y:        widget.Position.Y,
+		Width:    widget.Size.Width,
+		Height:   widget.Size.Height,
+	}
+}
+
+// layoutColumns is the width of a dashboard's own grid
+func layoutColumns(layout models.DashboardLayout) int {
+	if layout.Columns <= 0 {
+		return defaultLayoutColumns
+	}
+	return layout.Columns
+}
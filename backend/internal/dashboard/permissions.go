@@ -0,0 +1,231 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// Roles that can be granted on dashboards and folders, each including the
+// ones before it. A role on a folder applies to everything inside it.
+const (
+	RoleViewer = "viewer" // sees the dashboard and runs its widgets
+	RoleEditor = "editor" // changes widgets, layout and settings, adds dashboards to a folder
+	RoleAdmin  = "admin"  // deletes, moves, shares and manages permissions
+)
+
+var roleLevels = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// DashboardRole returns the role the user in ctx has on a dashboard
+func (s *Service) DashboardRole(ctx context.Context, dashboardID string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	dashboard, err := s.lookupDashboard(ctx, dashboardID, RoleViewer)
+	if err != nil {
+		return "", err
+	}
+	return s.dashboardRole(dashboard, auth.UserFromContext(ctx)), nil
+}
+
+// FolderRole returns the role the user in ctx has on a folder
+func (s *Service) FolderRole(ctx context.Context, folderID string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	folder, err := s.lookupFolder(ctx, folderID, RoleViewer)
+	if err != nil {
+		return "", err
+	}
+	return s.folderRole(folder, auth.UserFromContext(ctx)), nil
+}
+
+// SetDashboardPermissions replaces the roles granted on a dashboard
+func (s *Service) SetDashboardPermissions(ctx context.Context, dashboardID string, permissions []models.DashboardPermission) (*models.Dashboard, error) {
+	if err := validatePermissions(permissions); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, err := s.lookupDashboard(ctx, dashboardID, RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *current
+	updated.Permissions = permissions
+	updated.UpdatedAt = time.Now()
+
+	if s.repository != nil {
+		if err := s.repository.SaveDashboard(&updated); err != nil {
+			return nil, fmt.Errorf("failed to save dashboard: %w", err)
+		}
+	}
+	s.dashboards[dashboardID] = &updated
+
+	log.Info().
+		Str("dashboard_id", dashboardID).
+		Str("user_id", auth.UserFromContext(ctx).ID).
+		Int("permissions", len(permissions)).
+		Msg("Dashboard permissions updated")
+
+	return &updated, nil
+}
+
+// SetFolderPermissions replaces the roles granted on a folder
+func (s *Service) SetFolderPermissions(ctx context.Context, folderID string, permissions []models.DashboardPermission) (*models.DashboardFolder, error) {
+	if err := validatePermissions(permissions); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, err := s.lookupFolder(ctx, folderID, RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *current
+	updated.Permissions = permissions
+	updated.UpdatedAt = time.Now()
+
+	if s.repository != nil {
+		if err := s.repository.SaveFolder(&updated); err != nil {
+			return nil, fmt.Errorf("failed to save folder: %w", err)
+		}
+	}
+	s.folders[folderID] = &updated
+
+	log.Info().
+		Str("folder_id", folderID).
+		Str("user_id", auth.UserFromContext(ctx).ID).
+		Int("permissions", len(permissions)).
+		Msg("Dashboard folder permissions updated")
+
+	return &updated, nil
+}
+
+// lookupDashboard finds a dashboard on which the user in ctx has at least
+// role. Callers hold the lock.
+func (s *Service) lookupDashboard(ctx context.Context, dashboardID string, role string) (*models.Dashboard, error) {
+	dashboard, exists := s.dashboards[dashboardID]
+	if !exists {
+		return nil, fmt.Errorf("dashboard not found: %s", dashboardID)
+	}
+	granted := s.dashboardRole(dashboard, auth.UserFromContext(ctx))
+	if shared := shareRole(ctx, dashboardID); roleLevels[shared] > roleLevels[granted] {
+		granted = shared
+	}
+	if granted == "" {
+		return nil, fmt.Errorf("access denied to dashboard: %s", dashboardID)
+	}
+	if roleLevels[granted] < roleLevels[role] {
+		return nil, fmt.Errorf("%s access denied to dashboard: %s", role, dashboardID)
+	}
+	return dashboard, nil
+}
+
+// lookupFolder finds a folder on which the user in ctx has at least role.
+// Callers hold the lock.
+func (s *Service) lookupFolder(ctx context.Context, folderID string, role string) (*models.DashboardFolder, error) {
+	folder, exists := s.folders[folderID]
+	if !exists {
+		return nil, fmt.Errorf("folder not found: %s", folderID)
+	}
+	granted := s.folderRole(folder, auth.UserFromContext(ctx))
+	if granted == "" {
+		return nil, fmt.Errorf("access denied to folder: %s", folderID)
+	}
+	if roleLevels[granted] < roleLevels[role] {
+		return nil, fmt.Errorf("%s access denied to folder: %s", role, folderID)
+	}
+	return folder, nil
+}
+
+// dashboardRole returns the highest role the user has on a dashboard,
+// directly or through its folders, or "" when they cannot see it. Owners
+// and global admins are admins; built-in dashboards are read-only.
+// Callers hold the lock.
+func (s *Service) dashboardRole(dashboard *models.Dashboard, user auth.User) string {
+	if dashboard.CreatedBy == BuiltInOwner {
+		if dashboard.IsPublic {
+			return RoleViewer
+		}
+		return ""
+	}
+	if user.IsAdmin() || dashboard.CreatedBy == user.ID {
+		return RoleAdmin
+	}
+
+	role := grantedRole(dashboard.Permissions, dashboard.SharedWith, dashboard.IsPublic, user)
+	if folder, exists := s.folders[dashboard.FolderID]; exists {
+		role = higherRole(role, s.folderRole(folder, user))
+	}
+	return role
+}
+
+// folderRole returns the highest role the user has on a folder or any
+// folder containing it, or "" when they cannot see it. Callers hold the lock.
+func (s *Service) folderRole(folder *models.DashboardFolder, user auth.User) string {
+	if user.IsAdmin() {
+		return RoleAdmin
+	}
+
+	role := ""
+	for depth := 0; folder != nil && depth < maxFolderDepth; depth++ {
+		if folder.CreatedBy == user.ID {
+			return RoleAdmin
+		}
+		role = higherRole(role, grantedRole(folder.Permissions, folder.SharedWith, folder.IsPublic, user))
+		folder = s.folders[folder.ParentID]
+	}
+	return role
+}
+
+// grantedRole returns the highest role granted to the user or one of their
+// teams. Public items and users in sharedWith can view.
+func grantedRole(permissions []models.DashboardPermission, sharedWith []string, isPublic bool, user auth.User) string {
+	role := ""
+	if isPublic {
+		role = RoleViewer
+	}
+	for _, sharedUser := range sharedWith {
+		if sharedUser == user.ID {
+			role = RoleViewer
+		}
+	}
+	for _, permission := range permissions {
+		if (permission.User != "" && permission.User == user.ID) ||
+			(permission.Team != "" && user.InTeam(permission.Team)) {
+			role = higherRole(role, permission.Role)
+		}
+	}
+	return role
+}
+
+func higherRole(a, b string) string {
+	if roleLevels[b] > roleLevels[a] {
+		return b
+	}
+	return a
+}
+
+func validatePermissions(permissions []models.DashboardPermission) error {
+	for _, permission := range permissions {
+		if _, ok := roleLevels[permission.Role]; !ok {
+			return fmt.Errorf("invalid role: %s (must be viewer, editor or admin)", permission.Role)
+		}
+		if (permission.User == "") == (permission.Team == "") {
+			return fmt.Errorf("permission must name either a user or a team")
+		}
+	}
+	return nil
+}
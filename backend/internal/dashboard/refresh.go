@@ -0,0 +1,332 @@
+package dashboard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// RefreshUserID is the user widget queries scheduled by the server run as.
+// Queries of widgets watched by admins run with the admin role.
+const RefreshUserID = "dashboard-refresh"
+
+// minRefreshInterval bounds how often a widget query is scheduled
+const minRefreshInterval = 5 * time.Second
+
+// RefreshScheduler runs the queries of watched dashboard widgets on the
+// server and pushes their data to the watchers. Widgets with the same data
+// source share one query and its latest result, however many dashboards
+// and viewers show them, and it runs at the shortest interval any of them
+// asks for. Watchers keep the widgets and time range the dashboard had
+// when they started watching.
+type RefreshScheduler struct {
+	service *Service
+
+	mu      sync.Mutex
+	jobs    map[string]*refreshJob
+	stopped bool
+}
+
+// refreshJob is a widget query shared by its viewers
+type refreshJob struct {
+	widget    models.DashboardWidget // the widget of the first viewer
+	timeRange *models.QueryTimeRange // applied to the widget on every run
+	admin     bool
+	viewers   map[*widgetViewer]bool
+	cancel    context.CancelFunc
+	wake      chan struct{} // signalled when a viewer shortens the interval
+
+	ran      bool
+	result   *models.QueryBuilderResponse
+	err      error
+	ranAt    time.Time
+	lastHash [sha256.Size]byte
+}
+
+// widgetViewer is a widget of a dashboard a client watches
+type widgetViewer struct {
+	dashboardID string
+	widget      models.DashboardWidget
+	interval    time.Duration // 0 runs the query once
+	send        func(models.WidgetUpdate)
+}
+
+// NewRefreshScheduler creates a scheduler running widget queries with service
+func NewRefreshScheduler(service *Service) *RefreshScheduler {
+	return &RefreshScheduler{
+		service: service,
+		jobs:    make(map[string]*refreshJob),
+	}
+}
+
+// Watch sends the data of every widget of a dashboard the user in ctx can
+// view, then again each time it changes, until the returned function is
+// called. Widgets refresh at their RefreshRate, or the dashboard's
+// RefreshInterval when zero, and read timeRange, or the dashboard's time
+// range when nil. Relative time ranges move forward on every refresh.
+func (rs *RefreshScheduler) Watch(ctx context.Context, dashboardID string, timeRange *models.QueryTimeRange, send func(models.WidgetUpdate)) (func(), error) {
+	dashboard, err := rs.service.GetDashboard(ctx, dashboardID)
+	if err != nil {
+		return nil, err
+	}
+	if timeRange, err = rs.service.DashboardTimeRange(dashboard, timeRange); err != nil {
+		return nil, err
+	}
+	admin := auth.UserFromContext(ctx).IsAdmin()
+
+	type cached struct {
+		viewer *widgetViewer
+		result *models.QueryBuilderResponse
+		err    error
+		ranAt  time.Time
+	}
+	var viewers []*widgetViewer
+	var ready []cached
+
+	rs.mu.Lock()
+	if rs.stopped {
+		rs.mu.Unlock()
+		return nil, fmt.Errorf("dashboard refresh is stopped")
+	}
+	for _, widget := range dashboard.Widgets {
+		if widget.Type == "text" {
+			continue
+		}
+		widgetTimeRange := timeRange
+		if widget.IgnoreDashboardTimeRange {
+			widgetTimeRange = nil
+		}
+		key, err := refreshKey(widget.DataSource, widgetTimeRange, admin)
+		if err != nil {
+			continue
+		}
+
+		viewer := &widgetViewer{
+			dashboardID: dashboardID,
+			widget:      widget,
+			interval:    refreshInterval(dashboard, widget),
+			send:        send,
+		}
+		viewers = append(viewers, viewer)
+
+		job, exists := rs.jobs[key]
+		if !exists {
+			jobCtx, cancel := context.WithCancel(context.Background())
+			job = &refreshJob{
+				widget:    widget,
+				timeRange: widgetTimeRange,
+				admin:     admin,
+				viewers:   make(map[*widgetViewer]bool),
+				cancel:    cancel,
+				wake:      make(chan struct{}, 1),
+			}
+			rs.jobs[key] = job
+			go rs.run(jobCtx, job)
+		}
+
+		previous := job.interval()
+		job.viewers[viewer] = true
+		if current := job.interval(); current > 0 && (previous == 0 || current < previous) {
+			select {
+			case job.wake <- struct{}{}:
+			default:
+			}
+		}
+		if job.ran {
+			ready = append(ready, cached{viewer: viewer, result: job.result, err: job.err, ranAt: job.ranAt})
+		}
+	}
+	rs.mu.Unlock()
+
+	// Viewers joining a running query get its latest result right away
+	for _, c := range ready {
+		c.viewer.send(rs.update(c.viewer, c.result, c.err, c.ranAt))
+	}
+
+	log.Debug().
+		Str("dashboard_id", dashboardID).
+		Int("widgets", len(viewers)).
+		Msg("Dashboard watched")
+
+	return func() { rs.unwatch(viewers) }, nil
+}
+
+// Stop cancels every scheduled widget query
+func (rs *RefreshScheduler) Stop() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.stopped = true
+	for key, job := range rs.jobs {
+		job.cancel()
+		delete(rs.jobs, key)
+	}
+}
+
+// unwatch removes viewers, cancelling the queries nobody watches any more
+func (rs *RefreshScheduler) unwatch(viewers []*widgetViewer) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for key, job := range rs.jobs {
+		for _, viewer := range viewers {
+			delete(job.viewers, viewer)
+		}
+		if len(job.viewers) == 0 {
+			job.cancel()
+			delete(rs.jobs, key)
+		}
+	}
+}
+
+// run refreshes a job right away and then at its interval until cancelled
+func (rs *RefreshScheduler) run(ctx context.Context, job *refreshJob) {
+	for {
+		rs.refresh(ctx, job)
+		if !rs.wait(ctx, job, time.Now()) {
+			return
+		}
+	}
+}
+
+// wait returns once the job's interval has passed since lastRun, or false
+// when it is cancelled. The interval is read again whenever viewers
+// shorten it.
+func (rs *RefreshScheduler) wait(ctx context.Context, job *refreshJob, lastRun time.Time) bool {
+	for {
+		rs.mu.Lock()
+		interval := job.interval()
+		rs.mu.Unlock()
+
+		var timer *time.Timer
+		var due <-chan time.Time
+		if interval > 0 {
+			timer = time.NewTimer(time.Until(lastRun.Add(interval)))
+			due = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return false
+		case <-job.wake:
+			if timer != nil {
+				timer.Stop()
+			}
+		case <-due:
+			return true
+		}
+	}
+}
+
+// refresh runs a job's query and sends the result to its viewers if it
+// changed since the last run
+func (rs *RefreshScheduler) refresh(ctx context.Context, job *refreshJob) {
+	user := auth.User{ID: RefreshUserID, Role: auth.RoleUser}
+	if job.admin {
+		user.Role = auth.RoleAdmin
+	}
+	widget, err := rs.service.ApplyTimeRange(&job.widget, job.timeRange)
+	var result *models.QueryBuilderResponse
+	if err == nil {
+		result, err = rs.service.ExecuteWidgetQuery(auth.WithUser(ctx, user), widget)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	var hash [sha256.Size]byte
+	if err == nil {
+		if encoded, encodeErr := json.Marshal(result.Rows); encodeErr == nil {
+			hash = sha256.Sum256(encoded)
+		}
+	}
+	ranAt := time.Now().UTC()
+
+	rs.mu.Lock()
+	unchanged := job.ran && err == nil && job.err == nil && hash == job.lastHash
+	job.ran = true
+	job.result, job.err, job.ranAt, job.lastHash = result, err, ranAt, hash
+	viewers := make([]*widgetViewer, 0, len(job.viewers))
+	for viewer := range job.viewers {
+		viewers = append(viewers, viewer)
+	}
+	rs.mu.Unlock()
+
+	if unchanged {
+		return
+	}
+	for _, viewer := range viewers {
+		viewer.send(rs.update(viewer, result, err, ranAt))
+	}
+}
+
+// update renders a query result as the data of a viewer's widget
+func (rs *RefreshScheduler) update(viewer *widgetViewer, result *models.QueryBuilderResponse, err error, ranAt time.Time) models.WidgetUpdate {
+	update := models.WidgetUpdate{
+		DashboardID: viewer.dashboardID,
+		WidgetID:    viewer.widget.ID,
+		Type:        viewer.widget.Type,
+		UpdatedAt:   ranAt,
+	}
+	if err == nil {
+		update.Data, err = rs.service.renderWidgetData(&viewer.widget, result)
+	}
+	if err != nil {
+		update.Error = err.Error()
+	}
+	return update
+}
+
+// interval returns the shortest refresh interval of the job's viewers, 0
+// when none refreshes. Callers hold the scheduler's lock.
+func (job *refreshJob) interval() time.Duration {
+	var shortest time.Duration
+	for viewer := range job.viewers {
+		if viewer.interval > 0 && (shortest == 0 || viewer.interval < shortest) {
+			shortest = viewer.interval
+		}
+	}
+	return shortest
+}
+
+// refreshInterval returns how often a widget refreshes, 0 for never
+func refreshInterval(dashboard *models.Dashboard, widget models.DashboardWidget) time.Duration {
+	seconds := widget.RefreshRate
+	if seconds <= 0 {
+		seconds = dashboard.Settings.RefreshInterval
+	}
+	if seconds <= 0 {
+		return 0
+	}
+	interval := time.Duration(seconds) * time.Second
+	if interval < minRefreshInterval {
+		interval = minRefreshInterval
+	}
+	return interval
+}
+
+// refreshKey identifies the widgets whose queries return the same result.
+// Admins may read tables other users may not, so their queries are kept
+// apart.
+func refreshKey(dataSource models.WidgetDataSource, timeRange *models.QueryTimeRange, admin bool) (string, error) {
+	encoded, err := json.Marshal(struct {
+		DataSource models.WidgetDataSource `json:"data_source"`
+		TimeRange  *models.QueryTimeRange  `json:"time_range"`
+		Admin      bool                    `json:"admin"`
+	}{dataSource, timeRange, admin})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,49 @@
+package dashboard
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/report"
+)
+
+// RenderReport runs the widgets of a dashboard the user in ctx can view
+// over timeRange, or the dashboard's time range when nil, and writes them
+// to w as a PDF or PNG report. Widgets whose query fails show the error.
+func (s *Service) RenderReport(ctx context.Context, dashboardID string, timeRange *models.QueryTimeRange, format report.Format, w io.Writer) error {
+	dashboard, err := s.GetDashboard(ctx, dashboardID)
+	if err != nil {
+		return err
+	}
+	if timeRange, err = s.absoluteTimeRange(dashboard, timeRange); err != nil {
+		return err
+	}
+
+	r := &report.Report{
+		Title:       dashboard.Name,
+		Description: dashboard.Description,
+		GeneratedAt: time.Now(),
+		Columns:     dashboard.Layout.Columns,
+		RowHeight:   dashboard.Layout.RowHeight,
+	}
+	if timeRange != nil {
+		r.Start, r.End = &timeRange.Start, &timeRange.End
+	}
+	for i, data := range s.runWidgets(ctx, dashboard, timeRange, widgetRun{}) {
+		widget := dashboard.Widgets[i]
+		r.Widgets = append(r.Widgets, report.Widget{
+			Title:     widget.Title,
+			Type:      widget.Type,
+			ChartType: widget.Config.ChartType,
+			X:         widget.Position.X,
+			Y:         widget.Position.Y,
+			Width:     widget.Size.Width,
+			Height:    widget.Size.Height,
+			Data:      data.Data,
+			Error:     data.Error,
+		})
+	}
+	return report.Render(w, r, format)
+}
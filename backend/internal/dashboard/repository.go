@@ -0,0 +1,278 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// DashboardRepository persists dashboards, their folders, share links,
+// annotations and snapshots
+type DashboardRepository interface {
+	SaveDashboard(dashboard *models.Dashboard) error
+	DeleteDashboard(id string) error
+	LoadDashboards() ([]*models.Dashboard, error)
+	SaveShare(share *models.DashboardShare) error
+	DeleteShare(token string) error
+	LoadShares() ([]*models.DashboardShare, error)
+	SaveFolder(folder *models.DashboardFolder) error
+	DeleteFolder(id string) error
+	LoadFolders() ([]*models.DashboardFolder, error)
+	SaveAnnotation(annotation *models.Annotation) error
+	DeleteAnnotation(id string) error
+	LoadAnnotations() ([]*models.Annotation, error)
+	SaveSnapshot(snapshot *models.DashboardSnapshot) error
+	DeleteSnapshot(id string) error
+	LoadSnapshots() ([]*models.DashboardSnapshot, error)
+}
+
+// SQLExecutor is the subset of the database used to persist dashboards
+type SQLExecutor interface {
+	Execute(ctx context.Context, query string) error
+	ExecuteSQL(sql string) ([]map[string]interface{}, error)
+}
+
+// ClickHouseRepository keeps dashboards, folders, shares, annotations and
+// snapshots in ClickHouse, one row per save with the latest row winning;
+// deletions are tombstone rows.
+type ClickHouseRepository struct {
+	db          SQLExecutor
+	dashboards  string
+	shares      string
+	folders     string
+	annotations string
+	snapshots   string
+}
+
+// NewClickHouseRepository creates the dashboard, share, folder, annotation
+// and snapshot tables if needed
+func NewClickHouseRepository(db SQLExecutor) (*ClickHouseRepository, error) {
+	r := &ClickHouseRepository{
+		db:          db,
+		dashboards:  "dashboards",
+		shares:      "dashboard_shares",
+		folders:     "dashboard_folders",
+		annotations: "dashboard_annotations",
+		snapshots:   "dashboard_snapshots",
+	}
+
+	for _, table := range []string{r.dashboards, r.shares, r.folders, r.annotations, r.snapshots} {
+		ddl := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id String,
+			definition String,
+			deleted UInt8 DEFAULT 0,
+			version UInt64
+		) ENGINE = ReplacingMergeTree(version)
+		ORDER BY id
+		`, table)
+		if err := db.Execute(context.Background(), ddl); err != nil {
+			return nil, fmt.Errorf("failed to create %s table: %w", table, err)
+		}
+	}
+	return r, nil
+}
+
+// SaveDashboard writes the current definition of a dashboard
+func (r *ClickHouseRepository) SaveDashboard(dashboard *models.Dashboard) error {
+	definition, err := json.Marshal(dashboard)
+	if err != nil {
+		return fmt.Errorf("failed to encode dashboard: %w", err)
+	}
+	return r.insert(r.dashboards, dashboard.ID, string(definition), false)
+}
+
+// DeleteDashboard records a tombstone for a dashboard
+func (r *ClickHouseRepository) DeleteDashboard(id string) error {
+	return r.insert(r.dashboards, id, "", true)
+}
+
+// LoadDashboards returns every dashboard that has not been deleted
+func (r *ClickHouseRepository) LoadDashboards() ([]*models.Dashboard, error) {
+	definitions, err := r.load(r.dashboards)
+	if err != nil {
+		return nil, err
+	}
+	dashboards := make([]*models.Dashboard, 0, len(definitions))
+	for id, definition := range definitions {
+		var dashboard models.Dashboard
+		if err := json.Unmarshal([]byte(definition), &dashboard); err != nil {
+			log.Warn().Err(err).Str("dashboard_id", id).Msg("Skipping unreadable dashboard")
+			continue
+		}
+		dashboards = append(dashboards, &dashboard)
+	}
+	return dashboards, nil
+}
+
+// SaveShare writes a share link, keyed by its token
+func (r *ClickHouseRepository) SaveShare(share *models.DashboardShare) error {
+	definition, err := json.Marshal(share)
+	if err != nil {
+		return fmt.Errorf("failed to encode share: %w", err)
+	}
+	return r.insert(r.shares, share.ShareToken, string(definition), false)
+}
+
+// DeleteShare records a tombstone for a share link
+func (r *ClickHouseRepository) DeleteShare(token string) error {
+	return r.insert(r.shares, token, "", true)
+}
+
+// LoadShares returns every share link that has not been deleted
+func (r *ClickHouseRepository) LoadShares() ([]*models.DashboardShare, error) {
+	definitions, err := r.load(r.shares)
+	if err != nil {
+		return nil, err
+	}
+	shares := make([]*models.DashboardShare, 0, len(definitions))
+	for _, definition := range definitions {
+		var share models.DashboardShare
+		if err := json.Unmarshal([]byte(definition), &share); err != nil {
+			log.Warn().Err(err).Msg("Skipping unreadable dashboard share")
+			continue
+		}
+		shares = append(shares, &share)
+	}
+	return shares, nil
+}
+
+// SaveFolder writes the current definition of a folder
+func (r *ClickHouseRepository) SaveFolder(folder *models.DashboardFolder) error {
+	definition, err := json.Marshal(folder)
+	if err != nil {
+		return fmt.Errorf("failed to encode folder: %w", err)
+	}
+	return r.insert(r.folders, folder.ID, string(definition), false)
+}
+
+// DeleteFolder records a tombstone for a folder
+func (r *ClickHouseRepository) DeleteFolder(id string) error {
+	return r.insert(r.folders, id, "", true)
+}
+
+// LoadFolders returns every folder that has not been deleted
+func (r *ClickHouseRepository) LoadFolders() ([]*models.DashboardFolder, error) {
+	definitions, err := r.load(r.folders)
+	if err != nil {
+		return nil, err
+	}
+	folders := make([]*models.DashboardFolder, 0, len(definitions))
+	for id, definition := range definitions {
+		var folder models.DashboardFolder
+		if err := json.Unmarshal([]byte(definition), &folder); err != nil {
+			log.Warn().Err(err).Str("folder_id", id).Msg("Skipping unreadable dashboard folder")
+			continue
+		}
+		folders = append(folders, &folder)
+	}
+	return folders, nil
+}
+
+// SaveAnnotation writes the current definition of an annotation
+func (r *ClickHouseRepository) SaveAnnotation(annotation *models.Annotation) error {
+	definition, err := json.Marshal(annotation)
+	if err != nil {
+		return fmt.Errorf("failed to encode annotation: %w", err)
+	}
+	return r.insert(r.annotations, annotation.ID, string(definition), false)
+}
+
+// DeleteAnnotation records a tombstone for an annotation
+func (r *ClickHouseRepository) DeleteAnnotation(id string) error {
+	return r.insert(r.annotations, id, "", true)
+}
+
+// LoadAnnotations returns every annotation that has not been deleted
+func (r *ClickHouseRepository) LoadAnnotations() ([]*models.Annotation, error) {
+	definitions, err := r.load(r.annotations)
+	if err != nil {
+		return nil, err
+	}
+	annotations := make([]*models.Annotation, 0, len(definitions))
+	for id, definition := range definitions {
+		var annotation models.Annotation
+		if err := json.Unmarshal([]byte(definition), &annotation); err != nil {
+			log.Warn().Err(err).Str("annotation_id", id).Msg("Skipping unreadable dashboard annotation")
+			continue
+		}
+		annotations = append(annotations, &annotation)
+	}
+	return annotations, nil
+}
+
+// SaveSnapshot writes a snapshot with the data of its widgets
+func (r *ClickHouseRepository) SaveSnapshot(snapshot *models.DashboardSnapshot) error {
+	definition, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return r.insert(r.snapshots, snapshot.ID, string(definition), false)
+}
+
+// DeleteSnapshot records a tombstone for a snapshot
+func (r *ClickHouseRepository) DeleteSnapshot(id string) error {
+	return r.insert(r.snapshots, id, "", true)
+}
+
+// LoadSnapshots returns every snapshot that has not been deleted
+func (r *ClickHouseRepository) LoadSnapshots() ([]*models.DashboardSnapshot, error) {
+	definitions, err := r.load(r.snapshots)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make([]*models.DashboardSnapshot, 0, len(definitions))
+	for id, definition := range definitions {
+		var snapshot models.DashboardSnapshot
+		if err := json.Unmarshal([]byte(definition), &snapshot); err != nil {
+			log.Warn().Err(err).Str("snapshot_id", id).Msg("Skipping unreadable dashboard snapshot")
+			continue
+		}
+		snapshots = append(snapshots, &snapshot)
+	}
+	return snapshots, nil
+}
+
+// load returns the latest definition of each row of a table by ID,
+// resolving versions with argMax so the result does not depend on merges
+func (r *ClickHouseRepository) load(table string) (map[string]string, error) {
+	rows, err := r.db.ExecuteSQL(fmt.Sprintf(`
+		SELECT id,
+			argMax(definition, version) AS definition,
+			argMax(deleted, version) AS deleted
+		FROM %s
+		GROUP BY id
+	`, table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", table, err)
+	}
+
+	definitions := make(map[string]string, len(rows))
+	for _, row := range rows {
+		if chsql.ToInt64(row["deleted"]) != 0 {
+			continue
+		}
+		id := fmt.Sprintf("%v", row["id"])
+		definitions[id], _ = row["definition"].(string)
+	}
+	return definitions, nil
+}
+
+func (r *ClickHouseRepository) insert(table, id, definition string, deleted bool) error {
+	deletedFlag := 0
+	if deleted {
+		deletedFlag = 1
+	}
+	statement := fmt.Sprintf("INSERT INTO %s (id, definition, deleted, version) VALUES (%s, %s, %d, %d)",
+		table, chsql.Quote(id), chsql.Quote(definition), deletedFlag, time.Now().UnixNano())
+	if err := r.db.Execute(context.Background(), statement); err != nil {
+		return fmt.Errorf("failed to store in %s: %w", table, err)
+	}
+	return nil
+}
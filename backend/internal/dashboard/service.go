@@ -4,11 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/database"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/query"
@@ -19,36 +21,188 @@ import (
 type Service struct {
 	db              *database.DB
 	queryBuilder    *querybuilder.Service
+	repository      DashboardRepository // nil keeps dashboards in memory only
+	mu              sync.RWMutex
 	dashboards      map[string]*models.Dashboard
 	dashboardShares map[string]*models.DashboardShare
+	folders         map[string]*models.DashboardFolder
+	annotations     map[string]*models.Annotation
+	snapshots       map[string]*models.DashboardSnapshot
+	widgetCache     *widgetCache
+	shareAccesses   *shareAccessLog
 }
 
 // NewService creates a new dashboard service
 func NewService(db *database.DB) *Service {
-	return &Service{
+	s := &Service{
 		db:              db,
-		queryBuilder:    querybuilder.NewService(),
+		queryBuilder:    querybuilder.NewService(db.GetQueryEngine().GetTables()),
 		dashboards:      make(map[string]*models.Dashboard),
 		dashboardShares: make(map[string]*models.DashboardShare),
+		folders:         make(map[string]*models.DashboardFolder),
+		annotations:     make(map[string]*models.Annotation),
+		snapshots:       make(map[string]*models.DashboardSnapshot),
+		widgetCache:     newWidgetCache(),
+		shareAccesses:   newShareAccessLog(),
 	}
+
+	for _, dashboard := range builtInDashboards() {
+		s.dashboards[dashboard.ID] = dashboard
+	}
+
+	return s
+}
+
+// SetRepository sets the persistence backend and loads the dashboards,
+// folders, share links, annotations and snapshots it holds. Those created before, while only in memory, are
+// written to it so their IDs and share tokens keep working. Built-in
+// dashboards are never stored.
+func (s *Service) SetRepository(repository DashboardRepository) error {
+	dashboards, err := repository.LoadDashboards()
+	if err != nil {
+		return err
+	}
+	shares, err := repository.LoadShares()
+	if err != nil {
+		return err
+	}
+	folders, err := repository.LoadFolders()
+	if err != nil {
+		return err
+	}
+	annotations, err := repository.LoadAnnotations()
+	if err != nil {
+		return err
+	}
+	snapshots, err := repository.LoadSnapshots()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := make(map[string]bool, len(dashboards))
+	for _, dashboard := range dashboards {
+		stored[dashboard.ID] = true
+	}
+	for id, dashboard := range s.dashboards {
+		if stored[id] || dashboard.CreatedBy == BuiltInOwner {
+			continue
+		}
+		if err := repository.SaveDashboard(dashboard); err != nil {
+			return fmt.Errorf("failed to migrate dashboard %s: %w", id, err)
+		}
+	}
+	storedShares := make(map[string]bool, len(shares))
+	for _, share := range shares {
+		storedShares[share.ShareToken] = true
+	}
+	for token, share := range s.dashboardShares {
+		if storedShares[token] {
+			continue
+		}
+		if err := repository.SaveShare(share); err != nil {
+			return fmt.Errorf("failed to migrate share of dashboard %s: %w", share.DashboardID, err)
+		}
+	}
+	storedFolders := make(map[string]bool, len(folders))
+	for _, folder := range folders {
+		storedFolders[folder.ID] = true
+	}
+	for id, folder := range s.folders {
+		if storedFolders[id] {
+			continue
+		}
+		if err := repository.SaveFolder(folder); err != nil {
+			return fmt.Errorf("failed to migrate folder %s: %w", id, err)
+		}
+	}
+	storedAnnotations := make(map[string]bool, len(annotations))
+	for _, annotation := range annotations {
+		storedAnnotations[annotation.ID] = true
+	}
+	for id, annotation := range s.annotations {
+		if storedAnnotations[id] {
+			continue
+		}
+		if err := repository.SaveAnnotation(annotation); err != nil {
+			return fmt.Errorf("failed to migrate annotation %s: %w", id, err)
+		}
+	}
+	storedSnapshots := make(map[string]bool, len(snapshots))
+	for _, snapshot := range snapshots {
+		storedSnapshots[snapshot.ID] = true
+	}
+	for id, snapshot := range s.snapshots {
+		if storedSnapshots[id] {
+			continue
+		}
+		if err := repository.SaveSnapshot(snapshot); err != nil {
+			return fmt.Errorf("failed to migrate snapshot %s: %w", id, err)
+		}
+	}
+
+	s.repository = repository
+	for _, dashboard := range dashboards {
+		if dashboard.CreatedBy != BuiltInOwner {
+			s.dashboards[dashboard.ID] = dashboard
+		}
+	}
+	for _, share := range shares {
+		s.dashboardShares[share.ShareToken] = share
+	}
+	for _, folder := range folders {
+		s.folders[folder.ID] = folder
+	}
+	for _, annotation := range annotations {
+		s.annotations[annotation.ID] = annotation
+	}
+	for _, snapshot := range snapshots {
+		s.snapshots[snapshot.ID] = snapshot
+	}
+	log.Info().Int("dashboards", len(dashboards)).Int("folders", len(folders)).Int("shares", len(shares)).
+		Int("annotations", len(annotations)).Int("snapshots", len(snapshots)).Msg("Loaded dashboards")
+	return nil
 }
 
-// CreateDashboard creates a new dashboard
-func (s *Service) CreateDashboard(ctx context.Context, dashboard *models.Dashboard, userID string) error {
+// CreateDashboard creates a new dashboard owned by the user in ctx. Creating
+// it in a folder requires the editor role on the folder.
+func (s *Service) CreateDashboard(ctx context.Context, dashboard *models.Dashboard) error {
 	if dashboard.ID == "" {
 		dashboard.ID = uuid.New().String()
 	}
 
+	userID := auth.UserFromContext(ctx).ID
 	dashboard.CreatedAt = time.Now()
 	dashboard.UpdatedAt = time.Now()
 	dashboard.CreatedBy = userID
+	prepareAnnotationQueries(dashboard.AnnotationQueries)
+	prepareLayout(dashboard)
 
 	// Validate dashboard
 	if err := s.validateDashboard(dashboard); err != nil {
 		return fmt.Errorf("dashboard validation failed: %w", err)
 	}
+	if err := s.checkSavedQueries(ctx, dashboard, nil); err != nil {
+		return err
+	}
 
-	// Store in memory (in production, this would be in a database)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.dashboards[dashboard.ID]; exists {
+		return fmt.Errorf("dashboard already exists: %s", dashboard.ID)
+	}
+	if dashboard.FolderID != "" {
+		if _, err := s.lookupFolder(ctx, dashboard.FolderID, RoleEditor); err != nil {
+			return err
+		}
+	}
+	if s.repository != nil {
+		if err := s.repository.SaveDashboard(dashboard); err != nil {
+			return fmt.Errorf("failed to save dashboard: %w", err)
+		}
+	}
 	s.dashboards[dashboard.ID] = dashboard
 
 	log.Info().
@@ -60,34 +214,25 @@ func (s *Service) CreateDashboard(ctx context.Context, dashboard *models.Dashboa
 	return nil
 }
 
-// GetDashboard retrieves a dashboard by ID
-func (s *Service) GetDashboard(ctx context.Context, dashboardID string, userID string) (*models.Dashboard, error) {
-	dashboard, exists := s.dashboards[dashboardID]
-	if !exists {
-		return nil, fmt.Errorf("dashboard not found: %s", dashboardID)
-	}
-
-	// Check permissions
-	if !s.canAccessDashboard(dashboard, userID) {
-		return nil, fmt.Errorf("access denied to dashboard: %s", dashboardID)
-	}
-
-	return dashboard, nil
+// GetDashboard retrieves a dashboard the user in ctx can view
+func (s *Service) GetDashboard(ctx context.Context, dashboardID string) (*models.Dashboard, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lookupDashboard(ctx, dashboardID, RoleViewer)
 }
 
-// UpdateDashboard updates an existing dashboard
-func (s *Service) UpdateDashboard(ctx context.Context, dashboardID string, updates map[string]interface{}, userID string) error {
-	dashboard, exists := s.dashboards[dashboardID]
-	if !exists {
-		return fmt.Errorf("dashboard not found: %s", dashboardID)
-	}
-
-	// Check permissions
-	if !s.canEditDashboard(dashboard, userID) {
-		return fmt.Errorf("edit access denied to dashboard: %s", dashboardID)
+// UpdateDashboard updates an existing dashboard, which requires the editor role
+func (s *Service) UpdateDashboard(ctx context.Context, dashboardID string, updates map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, err := s.lookupDashboard(ctx, dashboardID, RoleEditor)
+	if err != nil {
+		return err
 	}
 
-	// Apply updates
+	// Apply updates to a copy, replacing the stored dashboard once saved
+	updated := *current
+	dashboard := &updated
 	if name, ok := updates["name"].(string); ok {
 		dashboard.Name = name
 	}
@@ -110,45 +255,135 @@ func (s *Service) UpdateDashboard(ctx context.Context, dashboardID string, updat
 			}
 		}
 	}
+	if settings, ok := updates["settings"]; ok {
+		var newSettings models.DashboardSettings
+		if settingsData, err := json.Marshal(settings); err == nil {
+			if err := json.Unmarshal(settingsData, &newSettings); err != nil {
+				return fmt.Errorf("invalid settings: %w", err)
+			}
+		}
+		if newSettings.TimeRange != nil {
+			if _, _, err := s.queryBuilder.ResolveTimeRange(newSettings.TimeRange); err != nil {
+				return err
+			}
+		}
+		dashboard.Settings = newSettings
+	}
+	if queries, ok := updates["annotation_queries"]; ok {
+		var newQueries []models.AnnotationQuery
+		if queriesData, err := json.Marshal(queries); err == nil {
+			if err := json.Unmarshal(queriesData, &newQueries); err != nil {
+				return fmt.Errorf("invalid annotation queries: %w", err)
+			}
+		}
+		prepareAnnotationQueries(newQueries)
+		if err := validateAnnotationQueries(newQueries); err != nil {
+			return err
+		}
+		dashboard.AnnotationQueries = newQueries
+	}
+	// Breakpoints follow the widgets added and removed, and every layout
+	// must still fit its grid
+	_, widgetsUpdated := updates["widgets"]
+	_, layoutUpdated := updates["layout"]
+	if widgetsUpdated || layoutUpdated {
+		prepareLayout(dashboard)
+		if err := validateLayout(dashboard); err != nil {
+			return err
+		}
+	}
+
+	if err := s.checkSavedQueries(ctx, dashboard, current); err != nil {
+		return err
+	}
 
 	dashboard.UpdatedAt = time.Now()
 
+	if s.repository != nil {
+		if err := s.repository.SaveDashboard(dashboard); err != nil {
+			return fmt.Errorf("failed to save dashboard: %w", err)
+		}
+	}
+	s.dashboards[dashboardID] = dashboard
+
 	log.Info().
 		Str("dashboard_id", dashboardID).
-		Str("user_id", userID).
+		Str("user_id", auth.UserFromContext(ctx).ID).
 		Msg("Dashboard updated")
 
 	return nil
 }
 
-// DeleteDashboard deletes a dashboard
-func (s *Service) DeleteDashboard(ctx context.Context, dashboardID string, userID string) error {
-	dashboard, exists := s.dashboards[dashboardID]
-	if !exists {
-		return fmt.Errorf("dashboard not found: %s", dashboardID)
+// DeleteDashboard deletes a dashboard, which requires the admin role
+func (s *Service) DeleteDashboard(ctx context.Context, dashboardID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.lookupDashboard(ctx, dashboardID, RoleAdmin); err != nil {
+		return err
 	}
 
-	// Check permissions
-	if dashboard.CreatedBy != userID {
-		return fmt.Errorf("delete access denied to dashboard: %s", dashboardID)
+	// Share links of the dashboard go with it
+	for token, share := range s.dashboardShares {
+		if share.DashboardID != dashboardID {
+			continue
+		}
+		if s.repository != nil {
+			if err := s.repository.DeleteShare(token); err != nil {
+				return fmt.Errorf("failed to delete dashboard share: %w", err)
+			}
+		}
+		delete(s.dashboardShares, token)
+		s.shareAccesses.mu.Lock()
+		delete(s.shareAccesses.audits, token)
+		s.shareAccesses.mu.Unlock()
+	}
+	// So are its annotations and snapshots
+	for id, annotation := range s.annotations {
+		if annotation.DashboardID != dashboardID {
+			continue
+		}
+		if s.repository != nil {
+			if err := s.repository.DeleteAnnotation(id); err != nil {
+				return fmt.Errorf("failed to delete dashboard annotation: %w", err)
+			}
+		}
+		delete(s.annotations, id)
+	}
+	for id, snapshot := range s.snapshots {
+		if snapshot.DashboardID != dashboardID {
+			continue
+		}
+		if s.repository != nil {
+			if err := s.repository.DeleteSnapshot(id); err != nil {
+				return fmt.Errorf("failed to delete dashboard snapshot: %w", err)
+			}
+		}
+		delete(s.snapshots, id)
+	}
+	if s.repository != nil {
+		if err := s.repository.DeleteDashboard(dashboardID); err != nil {
+			return fmt.Errorf("failed to delete dashboard: %w", err)
+		}
 	}
-
 	delete(s.dashboards, dashboardID)
 
 	log.Info().
 		Str("dashboard_id", dashboardID).
-		Str("user_id", userID).
+		Str("user_id", auth.UserFromContext(ctx).ID).
 		Msg("Dashboard deleted")
 
 	return nil
 }
 
-// ListDashboards lists dashboards accessible to a user
-func (s *Service) ListDashboards(ctx context.Context, userID string) ([]*models.Dashboard, error) {
+// ListDashboards lists dashboards the user in ctx can view
+func (s *Service) ListDashboards(ctx context.Context) ([]*models.Dashboard, error) {
 	var dashboards []*models.Dashboard
 
+	user := auth.UserFromContext(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	for _, dashboard := range s.dashboards {
-		if s.canAccessDashboard(dashboard, userID) {
+		if s.dashboardRole(dashboard, user) != "" {
 			dashboards = append(dashboards, dashboard)
 		}
 	}
@@ -159,11 +394,13 @@ func (s *Service) ListDashboards(ctx context.Context, userID string) ([]*models.
 // ExecuteWidgetQuery executes a query for a specific widget
 func (s *Service) ExecuteWidgetQuery(ctx context.Context, widget *models.DashboardWidget) (*models.QueryBuilderResponse, error) {
 	var sql string
+	var maxAge int
 	var err error
 
 	switch widget.DataSource.Type {
 	case "query_builder":
 		if widget.DataSource.QueryBuilder != nil {
+			maxAge = widget.DataSource.QueryBuilder.Freshness
 			sql, err = s.queryBuilder.GenerateSQL(widget.DataSource.QueryBuilder)
 			if err != nil {
 				return nil, fmt.Errorf("failed to generate SQL from query builder: %w", err)
@@ -179,7 +416,8 @@ func (s *Service) ExecuteWidgetQuery(ctx context.Context, widget *models.Dashboa
 			return nil, fmt.Errorf("query engine not available")
 		}
 
-		savedQuery, err := queryEngine.GetQueryStore().Get(widget.DataSource.QueryID)
+		// Run the query only if the user in ctx may view it
+		savedQuery, err := queryEngine.GetQueryStore().GetFor(ctx, widget.DataSource.QueryID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get saved query: %w", err)
 		}
@@ -202,6 +440,8 @@ func (s *Service) ExecuteWidgetQuery(ctx context.Context, widget *models.Dashboa
 		Query:      sql,
 		Parameters: widget.DataSource.Parameters,
 		Timeout:    30, // 30 seconds
+		UseCache:   true,
+		MaxAge:     maxAge,
 	}
 
 	result, err := queryEngine.Execute(ctx, req)
@@ -226,6 +466,23 @@ func (s *Service) ExecuteWidgetQuery(ctx context.Context, widget *models.Dashboa
 		})
 	}
 
+	if qb := widget.DataSource.QueryBuilder; widget.DataSource.Type == "query_builder" && qb.TimeRange != nil {
+		if response.TimeRange, err = s.queryBuilder.ResolvedTimeRange(qb); err != nil {
+			return nil, err
+		}
+		comparisonSQL, err := s.queryBuilder.GenerateComparisonSQL(qb)
+		if err != nil {
+			return nil, err
+		}
+		if comparisonSQL != "" {
+			comparison, err := queryEngine.Execute(ctx, &query.QueryRequest{Query: comparisonSQL, Timeout: 30, UseCache: true, MaxAge: maxAge})
+			if err != nil {
+				return nil, fmt.Errorf("comparison query execution failed: %w", err)
+			}
+			response.ComparisonRows = comparison.Rows
+		}
+	}
+
 	return response, nil
 }
 
@@ -236,6 +493,11 @@ func (s *Service) GenerateWidgetData(ctx context.Context, widget *models.Dashboa
 		return nil, err
 	}
 
+	return s.renderWidgetData(widget, queryResult)
+}
+
+// renderWidgetData turns a widget's query result into the data it displays
+func (s *Service) renderWidgetData(widget *models.DashboardWidget, queryResult *models.QueryBuilderResponse) (interface{}, error) {
 	if queryResult.Error != "" {
 		return nil, fmt.Errorf("query error: %s", queryResult.Error)
 	}
@@ -247,21 +509,30 @@ func (s *Service) GenerateWidgetData(ctx context.Context, widget *models.Dashboa
 		return s.generateMetricData(widget, queryResult)
 	case "table":
 		return queryResult.Rows, nil
+	case "histogram":
+		return s.generateHistogramData(widget, queryResult)
+	case "heatmap":
+		return s.generateHeatmapData(widget, queryResult)
+	case "pie":
+		return s.generatePieData(widget, queryResult)
+	case "log_stream":
+		return s.generateLogStreamData(widget, queryResult)
 	default:
 		return queryResult.Rows, nil
 	}
 }
 
-// ShareDashboard creates a share link for a dashboard
-func (s *Service) ShareDashboard(ctx context.Context, dashboardID string, permissions []string, expiresAt *time.Time, userID string) (*models.DashboardShare, error) {
-	dashboard, exists := s.dashboards[dashboardID]
-	if !exists {
-		return nil, fmt.Errorf("dashboard not found: %s", dashboardID)
+// ShareDashboard creates a share link for a dashboard, which requires the
+// admin role
+func (s *Service) ShareDashboard(ctx context.Context, dashboardID string, permissions []string, expiresAt *time.Time) (*models.DashboardShare, error) {
+	if err := validateSharePermissions(permissions); err != nil {
+		return nil, err
 	}
 
-	// Check permissions
-	if dashboard.CreatedBy != userID {
-		return nil, fmt.Errorf("share access denied to dashboard: %s", dashboardID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.lookupDashboard(ctx, dashboardID, RoleAdmin); err != nil {
+		return nil, err
 	}
 
 	share := &models.DashboardShare{
@@ -271,9 +542,14 @@ func (s *Service) ShareDashboard(ctx context.Context, dashboardID string, permis
 		ExpiresAt:   expiresAt,
 		Permissions: permissions,
 		CreatedAt:   time.Now(),
-		CreatedBy:   userID,
+		CreatedBy:   auth.UserFromContext(ctx).ID,
 	}
 
+	if s.repository != nil {
+		if err := s.repository.SaveShare(share); err != nil {
+			return nil, fmt.Errorf("failed to save dashboard share: %w", err)
+		}
+	}
 	s.dashboardShares[share.ShareToken] = share
 
 	return share, nil
@@ -281,14 +557,16 @@ func (s *Service) ShareDashboard(ctx context.Context, dashboardID string, permis
 
 // GetDashboardByShareToken retrieves a dashboard by share token
 func (s *Service) GetDashboardByShareToken(ctx context.Context, shareToken string) (*models.Dashboard, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	share, exists := s.dashboardShares[shareToken]
 	if !exists {
 		return nil, fmt.Errorf("invalid share token")
 	}
 
-	// Check expiration
-	if share.ExpiresAt != nil && time.Now().After(*share.ExpiresAt) {
-		return nil, fmt.Errorf("share link has expired")
+	// Check expiration and revocation
+	if err := shareDenial(share); err != nil {
+		return nil, err
 	}
 
 	dashboard, exists := s.dashboards[share.DashboardID]
@@ -305,6 +583,15 @@ func (s *Service) validateDashboard(dashboard *models.Dashboard) error {
 	if dashboard.Name == "" {
 		return fmt.Errorf("dashboard name is required")
 	}
+	if err := validatePermissions(dashboard.Permissions); err != nil {
+		return err
+	}
+	if err := validateAnnotationQueries(dashboard.AnnotationQueries); err != nil {
+		return err
+	}
+	if err := validateLayout(dashboard); err != nil {
+		return err
+	}
 
 	for _, widget := range dashboard.Widgets {
 		if err := s.validateWidget(&widget); err != nil {
@@ -315,12 +602,72 @@ func (s *Service) validateDashboard(dashboard *models.Dashboard) error {
 	return nil
 }
 
+// checkSavedQueries ensures the user in ctx may view the saved queries a
+// dashboard runs, so that editors cannot run queries hidden from them by
+// putting them on a dashboard. Queries previous already ran are not checked
+// again, so editors can still save dashboards showing queries of others.
+func (s *Service) checkSavedQueries(ctx context.Context, dashboard, previous *models.Dashboard) error {
+	known := make(map[string]bool)
+	if previous != nil {
+		for _, id := range savedQueryIDs(previous) {
+			known[id] = true
+		}
+	}
+
+	var queryStore *query.QueryStore
+	for _, id := range savedQueryIDs(dashboard) {
+		if known[id] {
+			continue
+		}
+		if queryStore == nil {
+			queryEngine := s.db.GetQueryEngine()
+			if queryEngine == nil {
+				return fmt.Errorf("query engine not available")
+			}
+			queryStore = queryEngine.GetQueryStore()
+		}
+		if _, err := queryStore.GetFor(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// savedQueryIDs returns the IDs of the saved queries the widgets and
+// annotation queries of a dashboard run, in order and without duplicates
+func savedQueryIDs(dashboard *models.Dashboard) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, widget := range dashboard.Widgets {
+		if widget.DataSource.Type == "saved_query" {
+			add(widget.DataSource.QueryID)
+		}
+		if widget.Type == "text" {
+			for _, id := range textQueryIDs(widget.Config.Content) {
+				add(id)
+			}
+		}
+	}
+	for _, q := range dashboard.AnnotationQueries {
+		if q.DataSource.Type == "saved_query" {
+			add(q.DataSource.QueryID)
+		}
+	}
+	return ids
+}
+
 func (s *Service) validateWidget(widget *models.DashboardWidget) error {
 	if widget.Title == "" {
 		return fmt.Errorf("widget title is required")
 	}
 
-	validTypes := []string{"chart", "table", "metric", "text"}
+	validTypes := []string{"chart", "table", "metric", "text", "histogram", "heatmap", "pie", "log_stream"}
 	validType := false
 	for _, t := range validTypes {
 		if widget.Type == t {
@@ -331,34 +678,23 @@ func (s *Service) validateWidget(widget *models.DashboardWidget) error {
 	if !validType {
 		return fmt.Errorf("invalid widget type: %s", widget.Type)
 	}
-
-	return nil
-}
-
-func (s *Service) canAccessDashboard(dashboard *models.Dashboard, userID string) bool {
-	// Owner can always access
-	if dashboard.CreatedBy == userID {
-		return true
+	if widget.Config.Alert != nil {
+		if err := validateWidgetAlert(widget); err != nil {
+			return err
+		}
 	}
-
-	// Check if dashboard is public
-	if dashboard.IsPublic {
-		return true
+	if widget.Config.DrillDown != nil {
+		if err := validateWidgetDrillDown(widget); err != nil {
+			return err
+		}
 	}
-
-	// Check if user is in shared list
-	for _, sharedUser := range dashboard.SharedWith {
-		if sharedUser == userID {
-			return true
+	if widget.Type == "text" {
+		if err := validateTextWidget(widget); err != nil {
+			return err
 		}
 	}
 
-	return false
-}
-
-func (s *Service) canEditDashboard(dashboard *models.Dashboard, userID string) bool {
-	// Only owner can edit
-	return dashboard.CreatedBy == userID
+	return nil
 }
 
 func (s *Service) generateChartData(widget *models.DashboardWidget, queryResult *models.QueryBuilderResponse) (*models.ChartData, error) {
@@ -0,0 +1,29 @@
+package dashboard
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+func TestSavedQueryIDsCoversEveryRoute(t *testing.T) {
+	dashboard := &models.Dashboard{
+		Widgets: []models.DashboardWidget{
+			{Type: "chart", DataSource: models.WidgetDataSource{Type: "saved_query", QueryID: "errors-by-service"}},
+			{Type: "table", DataSource: models.WidgetDataSource{Type: "custom_sql", SQL: "SELECT 1"}},
+			{
+				Type:   "text",
+				Config: models.WidgetConfig{Content: "{{query:revenue.total}} of {{ query:revenue }} and {{query:errors-by-service}}, {{env}}"},
+			},
+		},
+		AnnotationQueries: []models.AnnotationQuery{
+			{DataSource: models.WidgetDataSource{Type: "saved_query", QueryID: "deploys"}},
+		},
+	}
+
+	got := strings.Join(savedQueryIDs(dashboard), ",")
+	if want := "errors-by-service,revenue,deploys"; got != want {
+		t.Fatalf("saved query IDs = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,334 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+const (
+	// shareRetention is how long share links are kept after they expire or
+	// are revoked, so that their use can still be audited
+	shareRetention = 7 * 24 * time.Hour
+	// maxShareAccesses is how many of the latest accesses of a share link
+	// are kept
+	maxShareAccesses = 100
+)
+
+// sharePermissionRoles are the roles share link permissions grant on their
+// dashboard
+var sharePermissionRoles = map[string]string{
+	"view": RoleViewer,
+	"edit": RoleEditor,
+}
+
+type shareContextKey struct{}
+
+// shareAccessLog records the use of share links, by token, in memory
+type shareAccessLog struct {
+	mu     sync.Mutex
+	audits map[string]*models.ShareAccessAudit
+}
+
+func newShareAccessLog() *shareAccessLog {
+	return &shareAccessLog{audits: make(map[string]*models.ShareAccessAudit)}
+}
+
+// ListShares returns the share links of a dashboard, newest first, which
+// requires the admin role
+func (s *Service) ListShares(ctx context.Context, dashboardID string) ([]*models.DashboardShare, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, err := s.lookupDashboard(ctx, dashboardID, RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	shares := make([]*models.DashboardShare, 0)
+	for _, share := range s.dashboardShares {
+		if share.DashboardID == dashboardID {
+			shares = append(shares, share)
+		}
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		return shares[i].CreatedAt.After(shares[j].CreatedAt)
+	})
+	return shares, nil
+}
+
+// UpdateShare changes the permissions and expiry of a share link, which
+// requires the admin role on its dashboard. Revoked links stay revoked.
+func (s *Service) UpdateShare(ctx context.Context, dashboardID, shareID string, permissions []string, expiresAt *time.Time) (*models.DashboardShare, error) {
+	if err := validateSharePermissions(permissions); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	share, err := s.lookupShare(ctx, dashboardID, shareID)
+	if err != nil {
+		return nil, err
+	}
+	if share.RevokedAt != nil {
+		return nil, fmt.Errorf("share link has been revoked")
+	}
+
+	now := time.Now()
+	updated := *share
+	updated.Permissions = permissions
+	updated.ExpiresAt = expiresAt
+	updated.UpdatedAt = &now
+	if s.repository != nil {
+		if err := s.repository.SaveShare(&updated); err != nil {
+			return nil, fmt.Errorf("failed to save dashboard share: %w", err)
+		}
+	}
+	s.dashboardShares[updated.ShareToken] = &updated
+
+	log.Info().
+		Str("dashboard_id", dashboardID).
+		Str("share_id", shareID).
+		Str("user_id", auth.UserFromContext(ctx).ID).
+		Msg("Dashboard share updated")
+
+	return &updated, nil
+}
+
+// RevokeShare stops a share link from working, which requires the admin
+// role on its dashboard. The link is kept for shareRetention so its use
+// can still be audited.
+func (s *Service) RevokeShare(ctx context.Context, dashboardID, shareID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	share, err := s.lookupShare(ctx, dashboardID, shareID)
+	if err != nil {
+		return err
+	}
+	if share.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	revoked := *share
+	revoked.RevokedAt = &now
+	if s.repository != nil {
+		if err := s.repository.SaveShare(&revoked); err != nil {
+			return fmt.Errorf("failed to save dashboard share: %w", err)
+		}
+	}
+	s.dashboardShares[revoked.ShareToken] = &revoked
+
+	log.Info().
+		Str("dashboard_id", dashboardID).
+		Str("share_id", shareID).
+		Str("user_id", auth.UserFromContext(ctx).ID).
+		Msg("Dashboard share revoked")
+
+	return nil
+}
+
+// ShareAccessAudit returns how a share link has been used since the server
+// started, which requires the admin role on its dashboard
+func (s *Service) ShareAccessAudit(ctx context.Context, dashboardID, shareID string) (*models.ShareAccessAudit, error) {
+	s.mu.RLock()
+	share, err := s.lookupShare(ctx, dashboardID, shareID)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	s.shareAccesses.mu.Lock()
+	defer s.shareAccesses.mu.Unlock()
+	audit := models.ShareAccessAudit{ShareID: shareID, Accesses: []models.ShareAccess{}}
+	if recorded, ok := s.shareAccesses.audits[share.ShareToken]; ok {
+		audit = *recorded
+		audit.ShareID = shareID
+		audit.Accesses = make([]models.ShareAccess, len(recorded.Accesses))
+		for i, access := range recorded.Accesses {
+			audit.Accesses[len(recorded.Accesses)-1-i] = access
+		}
+	}
+	return &audit, nil
+}
+
+// ShareContext returns a context in which the holder of a share link acts
+// on its dashboard: the requests read the dashboard with the roles the
+// link's permissions grant, as a user of the link rather than of whoever
+// sent them, so they never run with admin rights.
+func (s *Service) ShareContext(ctx context.Context, shareToken string) (context.Context, *models.DashboardShare, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	share, exists := s.dashboardShares[shareToken]
+	if !exists {
+		return nil, nil, fmt.Errorf("invalid share token")
+	}
+	if err := shareDenial(share); err != nil {
+		return nil, nil, err
+	}
+	if _, exists := s.dashboards[share.DashboardID]; !exists {
+		return nil, nil, fmt.Errorf("dashboard not found")
+	}
+
+	ctx = auth.WithUser(ctx, auth.User{ID: "share:" + share.ID, Role: auth.RoleUser})
+	return context.WithValue(ctx, shareContextKey{}, share), share, nil
+}
+
+// shareRole returns the role the share link in ctx, if any, grants on a
+// dashboard
+func shareRole(ctx context.Context, dashboardID string) string {
+	share, ok := ctx.Value(shareContextKey{}).(*models.DashboardShare)
+	if !ok || share.DashboardID != dashboardID {
+		return ""
+	}
+	granted := ""
+	for _, permission := range share.Permissions {
+		if role := sharePermissionRoles[permission]; roleLevels[role] > roleLevels[granted] {
+			granted = role
+		}
+	}
+	return granted
+}
+
+// RecordShareAccess adds an access through a share link to its audit.
+// Tokens of no share link are not recorded.
+func (s *Service) RecordShareAccess(shareToken string, access models.ShareAccess) {
+	s.mu.RLock()
+	share, exists := s.dashboardShares[shareToken]
+	s.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	event := log.Info()
+	if access.Denied != "" {
+		event = log.Warn().Str("denied", access.Denied)
+	}
+	event.
+		Str("dashboard_id", share.DashboardID).
+		Str("share_id", share.ID).
+		Str("resource", access.Resource).
+		Str("remote_addr", access.RemoteAddr).
+		Msg("Dashboard share accessed")
+
+	s.shareAccesses.mu.Lock()
+	defer s.shareAccesses.mu.Unlock()
+	audit, ok := s.shareAccesses.audits[shareToken]
+	if !ok {
+		audit = &models.ShareAccessAudit{ShareID: share.ID}
+		s.shareAccesses.audits[shareToken] = audit
+	}
+	if access.Denied != "" {
+		audit.DeniedCount++
+	} else {
+		audit.AccessCount++
+		at := access.At
+		audit.LastAccessedAt = &at
+	}
+	audit.Accesses = append(audit.Accesses, access)
+	if len(audit.Accesses) > maxShareAccesses {
+		audit.Accesses = append([]models.ShareAccess(nil), audit.Accesses[len(audit.Accesses)-maxShareAccesses:]...)
+	}
+}
+
+// CleanupShares deletes share links that expired or were revoked more than
+// shareRetention before now, and returns how many it deleted
+func (s *Service) CleanupShares(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for token, share := range s.dashboardShares {
+		ended := shareEnd(share)
+		if ended == nil || now.Sub(*ended) < shareRetention {
+			continue
+		}
+		if s.repository != nil {
+			if err := s.repository.DeleteShare(token); err != nil {
+				return deleted, fmt.Errorf("failed to delete dashboard share: %w", err)
+			}
+		}
+		delete(s.dashboardShares, token)
+		s.shareAccesses.mu.Lock()
+		delete(s.shareAccesses.audits, token)
+		s.shareAccesses.mu.Unlock()
+		deleted++
+	}
+	return deleted, nil
+}
+
+// StartShareCleanup deletes ended share links every interval until ctx is
+// cancelled
+func (s *Service) StartShareCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			deleted, err := s.CleanupShares(time.Now())
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to clean up dashboard shares")
+			}
+			if deleted > 0 {
+				log.Info().Int("deleted", deleted).Msg("Cleaned up expired dashboard shares")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// lookupShare finds a share link of a dashboard on which the user in ctx
+// has the admin role. Callers hold the lock.
+func (s *Service) lookupShare(ctx context.Context, dashboardID, shareID string) (*models.DashboardShare, error) {
+	if _, err := s.lookupDashboard(ctx, dashboardID, RoleAdmin); err != nil {
+		return nil, err
+	}
+	for _, share := range s.dashboardShares {
+		if share.ID == shareID && share.DashboardID == dashboardID {
+			return share, nil
+		}
+	}
+	return nil, fmt.Errorf("share not found: %s", shareID)
+}
+
+// shareEnd returns when a share link stopped working, if it has: when it
+// was revoked or, if earlier, when it expired
+func shareEnd(share *models.DashboardShare) *time.Time {
+	var ended *time.Time
+	if share.ExpiresAt != nil && time.Now().After(*share.ExpiresAt) {
+		ended = share.ExpiresAt
+	}
+	if share.RevokedAt != nil && (ended == nil || share.RevokedAt.Before(*ended)) {
+		ended = share.RevokedAt
+	}
+	return ended
+}
+
+// shareDenial returns why a share link can no longer be used, if it can't
+func shareDenial(share *models.DashboardShare) error {
+	if share.RevokedAt != nil {
+		return fmt.Errorf("share link has been revoked")
+	}
+	if share.ExpiresAt != nil && time.Now().After(*share.ExpiresAt) {
+		return fmt.Errorf("share link has expired")
+	}
+	return nil
+}
+
+func validateSharePermissions(permissions []string) error {
+	if len(permissions) == 0 {
+		return fmt.Errorf("at least one share permission is required")
+	}
+	for _, permission := range permissions {
+		if _, ok := sharePermissionRoles[permission]; !ok {
+			return fmt.Errorf("invalid share permission: %s (must be view or edit)", permission)
+		}
+	}
+	return nil
+}
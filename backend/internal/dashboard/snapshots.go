@@ -0,0 +1,160 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// CreateSnapshot runs every widget of a dashboard the user in ctx can view
+// once and stores the data with a share link. Widgets read timeRange, or
+// the dashboard's time range when nil, resolved to absolute times so the
+// snapshot records exactly what it shows. Widgets whose query fails keep
+// the error instead of data.
+func (s *Service) CreateSnapshot(ctx context.Context, dashboardID string, name string, timeRange *models.QueryTimeRange, expiresAt *time.Time) (*models.DashboardSnapshot, error) {
+	dashboard, err := s.GetDashboard(ctx, dashboardID)
+	if err != nil {
+		return nil, err
+	}
+	if timeRange, err = s.absoluteTimeRange(dashboard, timeRange); err != nil {
+		return nil, err
+	}
+
+	// A JSON round trip copies the widgets and settings without sharing slices or maps
+	data, err := json.Marshal(dashboard)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy dashboard: %w", err)
+	}
+	var frozen models.Dashboard
+	if err := json.Unmarshal(data, &frozen); err != nil {
+		return nil, fmt.Errorf("failed to copy dashboard: %w", err)
+	}
+	frozen.SharedWith = nil
+	frozen.Permissions = nil
+
+	user := auth.UserFromContext(ctx)
+	snapshot := &models.DashboardSnapshot{
+		ID:          uuid.New().String(),
+		DashboardID: dashboardID,
+		Name:        name,
+		Dashboard:   frozen,
+		TimeRange:   timeRange,
+		Widgets:     s.runWidgets(ctx, dashboard, timeRange, widgetRun{annotations: true}),
+		ShareToken:  uuid.New().String(),
+		ExpiresAt:   expiresAt,
+		CreatedAt:   time.Now(),
+		CreatedBy:   user.ID,
+	}
+	if snapshot.Name == "" {
+		snapshot.Name = fmt.Sprintf("%s (%s)", dashboard.Name, snapshot.CreatedAt.UTC().Format("2006-01-02 15:04 MST"))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The dashboard may have been deleted while its widgets ran
+	if _, err := s.lookupDashboard(ctx, dashboardID, RoleViewer); err != nil {
+		return nil, err
+	}
+	if s.repository != nil {
+		if err := s.repository.SaveSnapshot(snapshot); err != nil {
+			return nil, fmt.Errorf("failed to save snapshot: %w", err)
+		}
+	}
+	s.snapshots[snapshot.ID] = snapshot
+
+	log.Info().
+		Str("snapshot_id", snapshot.ID).
+		Str("dashboard_id", dashboardID).
+		Str("user_id", user.ID).
+		Int("widgets", len(snapshot.Widgets)).
+		Msg("Dashboard snapshot created")
+
+	return snapshot, nil
+}
+
+// ListSnapshots lists the snapshots of a dashboard, newest first
+func (s *Service) ListSnapshots(ctx context.Context, dashboardID string) ([]*models.DashboardSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, err := s.lookupDashboard(ctx, dashboardID, RoleViewer); err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]*models.DashboardSnapshot, 0)
+	for _, snapshot := range s.snapshots {
+		if snapshot.DashboardID == dashboardID {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt) })
+	return snapshots, nil
+}
+
+// GetSnapshot retrieves a snapshot of a dashboard the user in ctx can view
+func (s *Service) GetSnapshot(ctx context.Context, snapshotID string) (*models.DashboardSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot, exists := s.snapshots[snapshotID]
+	if !exists {
+		return nil, fmt.Errorf("snapshot not found: %s", snapshotID)
+	}
+	if _, err := s.lookupDashboard(ctx, snapshot.DashboardID, RoleViewer); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// DeleteSnapshot deletes a snapshot, which its creator and the admins of
+// its dashboard may do
+func (s *Service) DeleteSnapshot(ctx context.Context, snapshotID string) error {
+	user := auth.UserFromContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot, exists := s.snapshots[snapshotID]
+	if !exists {
+		return fmt.Errorf("snapshot not found: %s", snapshotID)
+	}
+	if snapshot.CreatedBy != user.ID {
+		if _, err := s.lookupDashboard(ctx, snapshot.DashboardID, RoleAdmin); err != nil {
+			return err
+		}
+	}
+
+	if s.repository != nil {
+		if err := s.repository.DeleteSnapshot(snapshotID); err != nil {
+			return fmt.Errorf("failed to delete snapshot: %w", err)
+		}
+	}
+	delete(s.snapshots, snapshotID)
+
+	log.Info().
+		Str("snapshot_id", snapshotID).
+		Str("user_id", user.ID).
+		Msg("Dashboard snapshot deleted")
+
+	return nil
+}
+
+// GetSnapshotByShareToken retrieves a snapshot by its share token
+func (s *Service) GetSnapshotByShareToken(ctx context.Context, shareToken string) (*models.DashboardSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, snapshot := range s.snapshots {
+		if snapshot.ShareToken != shareToken {
+			continue
+		}
+		if snapshot.ExpiresAt != nil && time.Now().After(*snapshot.ExpiresAt) {
+			return nil, fmt.Errorf("snapshot link has expired")
+		}
+		return snapshot, nil
+	}
+	return nil, fmt.Errorf("invalid share token")
+}
@@ -0,0 +1,388 @@
+package dashboard
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// BuiltInOwner owns the dashboards shipped with the server. Nobody can edit
+// or delete them since no request runs as this user.
+const BuiltInOwner = "builtin"
+
+// SlowQueriesDashboardID is the ID of the built-in slow query dashboard
+const SlowQueriesDashboardID = "builtin-slow-queries"
+
+// IDs of the built-in dashboard templates
+const (
+	ServiceOverviewTemplateID = "template-service-overview"
+	ErrorAnalysisTemplateID   = "template-error-analysis"
+	IngestionHealthTemplateID = "template-ingestion-health"
+	AlertHistoryTemplateID    = "template-alert-history"
+)
+
+// builtInDashboards returns the dashboards registered by NewService
+func builtInDashboards() []*models.Dashboard {
+	dashboards := []*models.Dashboard{slowQueriesDashboard()}
+	for _, template := range []*models.Dashboard{
+		serviceOverviewTemplate(),
+		errorAnalysisTemplate(),
+		ingestionHealthTemplate(),
+		alertHistoryTemplate(),
+	} {
+		// Templates show the data of their default variables
+		applyVariables(template, nil)
+		dashboards = append(dashboards, template)
+	}
+	return dashboards
+}
+
+// slowQueriesDashboard reviews the slow query log. Its widgets read the
+// slow_queries table, which only admins may query.
+func slowQueriesDashboard() *models.Dashboard {
+	now := time.Now()
+	return &models.Dashboard{
+		ID:          SlowQueriesDashboardID,
+		Name:        "Slow Queries",
+		Description: "Queries captured by the slow query log over the last 24 hours, with the rows ClickHouse estimated to read and the indexes used. Requires admin access.",
+		IsPublic:    true,
+		CreatedBy:   BuiltInOwner,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Layout: models.DashboardLayout{
+			Columns:   12,
+			RowHeight: 60,
+			GridGap:   10,
+		},
+		Settings: models.DashboardSettings{
+			RefreshInterval: 60,
+		},
+		Widgets: []models.DashboardWidget{
+			{
+				ID:       "slow-query-count",
+				Type:     "metric",
+				Title:    "Slow queries (24h)",
+				Position: models.WidgetPosition{X: 0, Y: 0},
+				Size:     models.WidgetSize{Width: 3, Height: 2},
+				DataSource: models.WidgetDataSource{
+					Type: "custom_sql",
+					SQL:  "SELECT toFloat64(count()) AS value FROM slow_queries WHERE captured_at >= now() - INTERVAL 1 DAY",
+				},
+			},
+			{
+				ID:       "slow-query-p95",
+				Type:     "metric",
+				Title:    "p95 duration ms (24h)",
+				Position: models.WidgetPosition{X: 3, Y: 0},
+				Size:     models.WidgetSize{Width: 3, Height: 2},
+				Config:   models.WidgetConfig{ValueFormat: "ms"},
+				DataSource: models.WidgetDataSource{
+					Type: "custom_sql",
+					SQL:  "SELECT toFloat64(quantile(0.95)(duration_ms)) AS value FROM slow_queries WHERE captured_at >= now() - INTERVAL 1 DAY",
+				},
+			},
+			{
+				ID:       "slow-queries-per-hour",
+				Type:     "chart",
+				Title:    "Slow queries per hour",
+				Position: models.WidgetPosition{X: 6, Y: 0},
+				Size:     models.WidgetSize{Width: 6, Height: 4},
+				Config:   models.WidgetConfig{ChartType: "bar", ShowGrid: true},
+				DataSource: models.WidgetDataSource{
+					Type: "custom_sql",
+					SQL: `SELECT toStartOfHour(captured_at) AS hour, toFloat64(count()) AS queries
+FROM slow_queries
+WHERE captured_at >= now() - INTERVAL 1 DAY
+GROUP BY hour
+ORDER BY hour`,
+				},
+			},
+			{
+				ID:       "slow-queries-by-user",
+				Type:     "chart",
+				Title:    "Slow queries by user",
+				Position: models.WidgetPosition{X: 0, Y: 2},
+				Size:     models.WidgetSize{Width: 6, Height: 2},
+				Config:   models.WidgetConfig{ChartType: "pie", ShowLegend: true},
+				DataSource: models.WidgetDataSource{
+					Type: "custom_sql",
+					SQL: `SELECT user_id, toFloat64(count()) AS queries
+FROM slow_queries
+WHERE captured_at >= now() - INTERVAL 1 DAY
+GROUP BY user_id
+ORDER BY queries DESC
+LIMIT 10`,
+				},
+			},
+			{
+				ID:       "slowest-queries",
+				Type:     "table",
+				Title:    "Slowest queries",
+				Position: models.WidgetPosition{X: 0, Y: 4},
+				Size:     models.WidgetSize{Width: 12, Height: 6},
+				DataSource: models.WidgetDataSource{
+					Type: "custom_sql",
+					SQL: `SELECT id, captured_at, user_id, duration_ms, row_count, read_rows, indexes_used, error, query
+FROM slow_queries
+WHERE captured_at >= now() - INTERVAL 1 DAY
+ORDER BY duration_ms DESC
+LIMIT 50`,
+				},
+			},
+		},
+	}
+}
+
+// applyVariables sets the variables of a dashboard to values, keeping the
+// default of those not given, and passes them to its saved query and SQL
+// widgets and annotation queries as parameters
+func applyVariables(dashboard *models.Dashboard, values map[string]string) error {
+	variables := dashboard.Settings.Variables
+	for name := range values {
+		found := false
+		for _, variable := range variables {
+			found = found || variable.Name == name
+		}
+		if !found {
+			return fmt.Errorf("unknown dashboard variable: %s", name)
+		}
+	}
+
+	parameters := make(map[string]interface{}, len(variables))
+	for i := range variables {
+		variable := &variables[i]
+		if value, ok := values[variable.Name]; ok {
+			if variable.Type == "select" && len(variable.Options) > 0 && !containsColumn(variable.Options, value) {
+				return fmt.Errorf("invalid value for dashboard variable %s: %s", variable.Name, value)
+			}
+			variable.DefaultValue = value
+		}
+		parameters[variable.Name] = variable.DefaultValue
+	}
+	if len(parameters) == 0 {
+		return nil
+	}
+
+	setParameters := func(dataSource *models.WidgetDataSource) {
+		if dataSource.Type != "saved_query" && dataSource.Type != "custom_sql" {
+			return
+		}
+		merged := make(map[string]interface{}, len(dataSource.Parameters)+len(parameters))
+		for name, value := range dataSource.Parameters {
+			merged[name] = value
+		}
+		for name, value := range parameters {
+			merged[name] = value
+		}
+		dataSource.Parameters = merged
+	}
+	for i := range dashboard.Widgets {
+		setParameters(&dashboard.Widgets[i].DataSource)
+	}
+	for i := range dashboard.AnnotationQueries {
+		setParameters(&dashboard.AnnotationQueries[i].DataSource)
+	}
+	return nil
+}
+
+// serviceVariable narrows a template to one service, all when empty
+var serviceVariable = models.DashboardVariable{
+	Name:  "service",
+	Type:  "text",
+	Label: "Service (all when empty)",
+}
+
+// templateLogFilter selects the logs of a template's time range and service
+const templateLogFilter = "timestamp BETWEEN :start AND :end AND (:service = '' OR service = :service)"
+
+// newTemplate returns a template reading the last 24 hours by default
+func newTemplate(id, name, category, description string, variables []models.DashboardVariable, widgets []models.DashboardWidget) *models.Dashboard {
+	now := time.Now()
+	return &models.Dashboard{
+		ID:          id,
+		Name:        name,
+		Description: description,
+		IsPublic:    true,
+		IsTemplate:  true,
+		Category:    category,
+		CreatedBy:   BuiltInOwner,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Layout: models.DashboardLayout{
+			Columns:   12,
+			RowHeight: 60,
+			GridGap:   10,
+		},
+		Settings: models.DashboardSettings{
+			RefreshInterval: 60,
+			TimeRange:       &models.QueryTimeRange{Relative: "last_24h"},
+			Variables:       variables,
+		},
+		Widgets: widgets,
+	}
+}
+
+func sqlWidget(id, widgetType, title string, x, y, width, height int, config models.WidgetConfig, sql string) models.DashboardWidget {
+	return models.DashboardWidget{
+		ID:         id,
+		Type:       widgetType,
+		Title:      title,
+		Position:   models.WidgetPosition{X: x, Y: y},
+		Size:       models.WidgetSize{Width: width, Height: height},
+		Config:     config,
+		DataSource: models.WidgetDataSource{Type: "custom_sql", SQL: sql},
+	}
+}
+
+// serviceOverviewTemplate shows the volume, errors and recent failures of
+// one service or all of them
+func serviceOverviewTemplate() *models.Dashboard {
+	return newTemplate(ServiceOverviewTemplateID, "Service Overview", "Services",
+		"Log volume, error rate and recent errors of a service, or of every service when none is chosen.",
+		[]models.DashboardVariable{serviceVariable},
+		[]models.DashboardWidget{
+			sqlWidget("logs", "metric", "Logs", 0, 0, 3, 2, models.WidgetConfig{},
+				"SELECT toFloat64(count()) AS value FROM logs WHERE "+templateLogFilter),
+			sqlWidget("error-rate", "metric", "Error rate", 3, 0, 3, 2, models.WidgetConfig{ValueFormat: "percent"},
+				"SELECT toFloat64(if(count() = 0, 0, countIf(level IN ('error', 'fatal')) * 100 / count())) AS value FROM logs WHERE "+templateLogFilter),
+			sqlWidget("traces", "metric", "Traces", 6, 0, 3, 2, models.WidgetConfig{},
+				"SELECT toFloat64(uniqExact(trace_id)) AS value FROM logs WHERE trace_id != '' AND "+templateLogFilter),
+			sqlWidget("logs-per-minute", "chart", "Logs per minute", 0, 2, 8, 4, models.WidgetConfig{ChartType: "line", ShowGrid: true},
+				`SELECT toStartOfMinute(timestamp) AS minute, toFloat64(count()) AS logs
+FROM logs
+WHERE `+templateLogFilter+`
+GROUP BY minute
+ORDER BY minute`),
+			sqlWidget("logs-by-level", "pie", "Logs by level", 8, 2, 4, 4, models.WidgetConfig{LabelField: "level", ValueField: "logs", ShowLegend: true},
+				`SELECT level, toFloat64(count()) AS logs
+FROM logs
+WHERE `+templateLogFilter+`
+GROUP BY level`),
+			sqlWidget("recent-errors", "table", "Recent errors", 0, 6, 12, 6, models.WidgetConfig{},
+				`SELECT timestamp, level, service, message, trace_id
+FROM logs
+WHERE level IN ('error', 'fatal') AND `+templateLogFilter+`
+ORDER BY timestamp DESC
+LIMIT 50`),
+		})
+}
+
+// errorAnalysisTemplate breaks the errors of a level down by time, service
+// and message
+func errorAnalysisTemplate() *models.Dashboard {
+	levelFilter := "level = :level AND " + templateLogFilter
+	return newTemplate(ErrorAnalysisTemplateID, "Error Analysis", "Error Analysis",
+		"When errors happen, which services raise them and the most frequent messages.",
+		[]models.DashboardVariable{
+			serviceVariable,
+			{Name: "level", Type: "select", Label: "Level", DefaultValue: "error", Options: []string{"error", "fatal", "warn"}},
+		},
+		[]models.DashboardWidget{
+			sqlWidget("errors", "metric", "Errors", 0, 0, 3, 2, models.WidgetConfig{},
+				"SELECT toFloat64(count()) AS value FROM logs WHERE "+levelFilter),
+			sqlWidget("failing-services", "metric", "Services with errors", 3, 0, 3, 2, models.WidgetConfig{},
+				"SELECT toFloat64(uniqExact(service)) AS value FROM logs WHERE "+levelFilter),
+			sqlWidget("errors-per-minute", "chart", "Errors per minute", 6, 0, 6, 4, models.WidgetConfig{ChartType: "bar", ShowGrid: true},
+				`SELECT toStartOfMinute(timestamp) AS minute, toFloat64(count()) AS errors
+FROM logs
+WHERE `+levelFilter+`
+GROUP BY minute
+ORDER BY minute`),
+			sqlWidget("errors-by-service", "heatmap", "Errors by service", 0, 4, 12, 4, models.WidgetConfig{TimeField: "time", LabelField: "service", ValueField: "errors"},
+				`SELECT toStartOfFiveMinutes(timestamp) AS time, service, toFloat64(count()) AS errors
+FROM logs
+WHERE `+levelFilter+`
+GROUP BY time, service
+ORDER BY time`),
+			sqlWidget("top-messages", "table", "Most frequent messages", 0, 8, 12, 6, models.WidgetConfig{},
+				`SELECT substring(message, 1, 200) AS message, count() AS occurrences, uniqExact(service) AS services, max(timestamp) AS last_seen
+FROM logs
+WHERE `+levelFilter+`
+GROUP BY message
+ORDER BY occurrences DESC
+LIMIT 25`),
+		})
+}
+
+// ingestionHealthTemplate shows whether logs keep arriving. The quarantine
+// widget needs the quarantine to be enabled.
+func ingestionHealthTemplate() *models.Dashboard {
+	return newTemplate(IngestionHealthTemplateID, "Ingestion Health", "Ingestion",
+		"Ingestion rate, services that stopped sending logs and logs rejected into the quarantine.",
+		[]models.DashboardVariable{serviceVariable},
+		[]models.DashboardWidget{
+			sqlWidget("logs-per-second", "metric", "Logs per second", 0, 0, 3, 2, models.WidgetConfig{},
+				"SELECT toFloat64(count() / greatest(dateDiff('second', toDateTime(:start), toDateTime(:end)), 1)) AS value FROM logs WHERE "+templateLogFilter),
+			sqlWidget("last-log", "metric", "Seconds since last log", 3, 0, 3, 2, models.WidgetConfig{ValueFormat: "s"},
+				"SELECT toFloat64(dateDiff('second', max(timestamp), now64(3))) AS value FROM logs WHERE (:service = '' OR service = :service)"),
+			sqlWidget("ingested-per-minute", "chart", "Logs ingested per minute", 6, 0, 6, 4, models.WidgetConfig{ChartType: "area", ShowGrid: true},
+				`SELECT toStartOfMinute(timestamp) AS minute, toFloat64(count()) AS logs
+FROM logs
+WHERE `+templateLogFilter+`
+GROUP BY minute
+ORDER BY minute`),
+			sqlWidget("services", "table", "Services", 0, 4, 12, 5, models.WidgetConfig{},
+				`SELECT service, count() AS logs, max(timestamp) AS last_log, dateDiff('second', max(timestamp), now64(3)) AS seconds_since_last_log
+FROM logs
+WHERE `+templateLogFilter+`
+GROUP BY service
+ORDER BY seconds_since_last_log DESC`),
+			sqlWidget("quarantined-by-rule", "chart", "Quarantined logs by rule", 0, 9, 12, 4, models.WidgetConfig{ChartType: "bar", ShowGrid: true},
+				`SELECT rule, toFloat64(count()) AS logs
+FROM quarantined_logs
+WHERE quarantined_at BETWEEN :start AND :end AND (:service = '' OR service = :service)
+GROUP BY rule
+ORDER BY logs DESC
+LIMIT 20`),
+		})
+}
+
+// alertHistoryTemplate shows how often alerts fire and for how long. Its
+// widgets read the alert_history table, which is only kept with ClickHouse
+// storage and only admins may query.
+func alertHistoryTemplate() *models.Dashboard {
+	rangeFilter := "changed_at BETWEEN :start AND :end AND (:severity = '' OR severity = :severity)"
+	return newTemplate(AlertHistoryTemplateID, "Alert History", "Monitoring",
+		"How often alerts fired over time, the most frequent ones and their latest state changes. Requires admin access.",
+		[]models.DashboardVariable{
+			{Name: "severity", Type: "select", Label: "Severity (all when empty)", DefaultValue: "", Options: []string{"", "info", "warning", "critical"}},
+		},
+		[]models.DashboardWidget{
+			sqlWidget("fired", "metric", "Alerts fired", 0, 0, 3, 2, models.WidgetConfig{},
+				"SELECT toFloat64(countIf(to_status = 'active')) AS value FROM alert_history WHERE "+rangeFilter),
+			sqlWidget("distinct-alerts", "metric", "Distinct alerts", 3, 0, 3, 2, models.WidgetConfig{},
+				"SELECT toFloat64(uniqExactIf(name, to_status = 'active')) AS value FROM alert_history WHERE "+rangeFilter),
+			sqlWidget("fired-per-hour", "chart", "Alerts fired per hour", 6, 0, 6, 4, models.WidgetConfig{ChartType: "bar", ShowGrid: true, ShowLegend: true},
+				`SELECT toStartOfHour(changed_at) AS hour,
+	toFloat64(countIf(severity = 'critical')) AS critical,
+	toFloat64(countIf(severity = 'warning')) AS warning,
+	toFloat64(countIf(severity = 'info')) AS info
+FROM alert_history
+WHERE to_status = 'active' AND `+rangeFilter+`
+GROUP BY hour
+ORDER BY hour`),
+			sqlWidget("fired-by-alert", "heatmap", "Alerts fired by name", 0, 4, 12, 4, models.WidgetConfig{TimeField: "time", LabelField: "name", ValueField: "fired"},
+				`SELECT toStartOfHour(changed_at) AS time, name, toFloat64(count()) AS fired
+FROM alert_history
+WHERE to_status = 'active' AND `+rangeFilter+`
+GROUP BY time, name
+ORDER BY time`),
+			sqlWidget("most-frequent", "table", "Most frequent alerts", 0, 8, 12, 5, models.WidgetConfig{},
+				`SELECT name, any(severity) AS severity, any(source) AS source,
+	countIf(to_status = 'active') AS fired,
+	countIf(to_status = 'resolved' AND from_status = 'pending') AS cleared_while_pending,
+	max(changed_at) AS last_change
+FROM alert_history
+WHERE `+rangeFilter+`
+GROUP BY name
+ORDER BY fired DESC
+LIMIT 25`),
+			sqlWidget("recent-transitions", "table", "Recent state changes", 0, 13, 12, 6, models.WidgetConfig{},
+				`SELECT changed_at, name, severity, from_status, to_status, value, message
+FROM alert_history
+WHERE `+rangeFilter+`
+ORDER BY changed_at DESC
+LIMIT 100`),
+		})
+}
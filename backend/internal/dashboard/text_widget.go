@@ -0,0 +1,178 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/markdown"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// maxTextQueries bounds the saved queries one text widget runs
+const maxTextQueries = 10
+
+// textPlaceholder matches the {{name}} and {{query:id}} placeholders of a
+// text widget's content
+var textPlaceholder = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// textQueryPrefix marks a placeholder filled in by a saved query
+const textQueryPrefix = "query:"
+
+// TextWidgetData fills in the content of a text widget with the values of
+// the dashboard's variables and the results of the saved queries it
+// names, run over timeRange like saved query widgets, and renders it.
+// Placeholders of unknown variables are left as they are; those of failed
+// queries read n/a, with the error in the data.
+func (s *Service) TextWidgetData(ctx context.Context, dashboard *models.Dashboard, widget *models.DashboardWidget, timeRange *models.QueryTimeRange) (*models.TextData, error) {
+	variables := make(map[string]string, len(dashboard.Settings.Variables))
+	for _, variable := range dashboard.Settings.Variables {
+		variables[variable.Name] = variable.DefaultValue
+	}
+
+	data := &models.TextData{}
+	results := make(map[string]*models.QueryBuilderResponse)
+	failed := make(map[string]bool)
+	data.Markdown = textPlaceholder.ReplaceAllStringFunc(widget.Config.Content, func(placeholder string) string {
+		name := textPlaceholder.FindStringSubmatch(placeholder)[1]
+		if !strings.HasPrefix(name, textQueryPrefix) {
+			if value, ok := variables[name]; ok {
+				return value
+			}
+			return placeholder
+		}
+
+		queryID, column := textQueryRef(name)
+		if failed[queryID] {
+			return "n/a"
+		}
+		result, ok := results[queryID]
+		if !ok {
+			var err error
+			if result, err = s.runTextQuery(ctx, widget, queryID, variables, timeRange); err != nil {
+				failed[queryID] = true
+				data.Errors = append(data.Errors, fmt.Sprintf("query %s: %v", queryID, err))
+				return "n/a"
+			}
+			results[queryID] = result
+		}
+		return textQueryValue(result, column)
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data.HTML = markdown.ToHTML(data.Markdown)
+	return data, nil
+}
+
+// runTextQuery runs a saved query for a text widget, with the dashboard's
+// variables and time range as its parameters
+func (s *Service) runTextQuery(ctx context.Context, widget *models.DashboardWidget, queryID string, variables map[string]string, timeRange *models.QueryTimeRange) (*models.QueryBuilderResponse, error) {
+	parameters := make(map[string]interface{}, len(widget.DataSource.Parameters)+len(variables))
+	for name, value := range widget.DataSource.Parameters {
+		parameters[name] = value
+	}
+	for name, value := range variables {
+		parameters[name] = value
+	}
+
+	query, err := s.ApplyTimeRange(&models.DashboardWidget{
+		ID:    widget.ID,
+		Type:  "table",
+		Title: widget.Title,
+		DataSource: models.WidgetDataSource{
+			Type:       "saved_query",
+			QueryID:    queryID,
+			Parameters: parameters,
+		},
+		IgnoreDashboardTimeRange: widget.IgnoreDashboardTimeRange,
+	}, timeRange)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.ExecuteWidgetQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("query error: %s", result.Error)
+	}
+	return result, nil
+}
+
+// textQueryRef splits the name of a query placeholder into the saved
+// query's ID and the column after the dot, if any
+func textQueryRef(name string) (string, string) {
+	ref := strings.TrimSpace(strings.TrimPrefix(name, textQueryPrefix))
+	if i := strings.Index(ref, "."); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// textQueryValue formats column, or the first column, of the first row of
+// a result, n/a when there is none
+func textQueryValue(result *models.QueryBuilderResponse, column string) string {
+	if len(result.Rows) == 0 {
+		return "n/a"
+	}
+	if column == "" {
+		if len(result.Columns) == 0 {
+			return "n/a"
+		}
+		column = result.Columns[0].Name
+	}
+	value, ok := result.Rows[0][column]
+	if !ok || value == nil {
+		return "n/a"
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return strconv.FormatFloat(v, 'f', 0, 64)
+		}
+		return strconv.FormatFloat(math.Round(v*10000)/10000, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(math.Round(float64(v)*10000)/10000, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// textQueryIDs returns the IDs of the saved queries named by the
+// placeholders of a text widget's content
+func textQueryIDs(content string) []string {
+	var ids []string
+	for _, match := range textPlaceholder.FindAllStringSubmatch(content, -1) {
+		if !strings.HasPrefix(match[1], textQueryPrefix) {
+			continue
+		}
+		if queryID, _ := textQueryRef(match[1]); queryID != "" {
+			ids = append(ids, queryID)
+		}
+	}
+	return ids
+}
+
+// validateTextWidget checks the query placeholders of a text widget
+func validateTextWidget(widget *models.DashboardWidget) error {
+	queries := make(map[string]bool)
+	for _, match := range textPlaceholder.FindAllStringSubmatch(widget.Config.Content, -1) {
+		if !strings.HasPrefix(match[1], textQueryPrefix) {
+			continue
+		}
+		queryID, _ := textQueryRef(match[1])
+		if queryID == "" {
+			return fmt.Errorf("text widget query placeholder needs a saved query ID: %s", match[0])
+		}
+		queries[queryID] = true
+	}
+	if len(queries) > maxTextQueries {
+		return fmt.Errorf("text widget runs %d queries (max %d)", len(queries), maxTextQueries)
+	}
+	return nil
+}
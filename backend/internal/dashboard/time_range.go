@@ -0,0 +1,75 @@
+package dashboard
+
+import (
+	"fmt"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// widgetTimeFormat is how the bounds of a dashboard time range are passed
+// to saved query and SQL widgets
+const widgetTimeFormat = "2006-01-02 15:04:05"
+
+// DashboardTimeRange returns the time range a dashboard's widgets read:
+// override when set, e.g. from the time picker, else the dashboard's own.
+// It returns nil when neither is set.
+func (s *Service) DashboardTimeRange(dashboard *models.Dashboard, override *models.QueryTimeRange) (*models.QueryTimeRange, error) {
+	timeRange := override
+	if timeRange == nil {
+		timeRange = dashboard.Settings.TimeRange
+	}
+	if timeRange == nil {
+		return nil, nil
+	}
+	if _, _, err := s.queryBuilder.ResolveTimeRange(timeRange); err != nil {
+		return nil, err
+	}
+	return timeRange, nil
+}
+
+// ApplyTimeRange returns the widget as it runs under a dashboard time
+// range. Query builder widgets read that range instead of their own,
+// keeping their comparison, when their table has a time column. Saved
+// query and SQL widgets get its bounds as the start and end parameters.
+// Widgets that ignore the dashboard time range, and all widgets when
+// timeRange is nil, are returned unchanged.
+func (s *Service) ApplyTimeRange(widget *models.DashboardWidget, timeRange *models.QueryTimeRange) (*models.DashboardWidget, error) {
+	if timeRange == nil || widget.IgnoreDashboardTimeRange {
+		return widget, nil
+	}
+
+	applied := *widget
+	switch widget.DataSource.Type {
+	case "query_builder":
+		if widget.DataSource.QueryBuilder == nil {
+			return widget, nil
+		}
+		if _, err := s.queryBuilder.TimeColumn(widget.DataSource.QueryBuilder.Table); err != nil {
+			return widget, nil
+		}
+		qb := *widget.DataSource.QueryBuilder
+		qb.TimeRange = &models.QueryTimeRange{
+			Start:    timeRange.Start,
+			End:      timeRange.End,
+			Relative: timeRange.Relative,
+		}
+		if widget.DataSource.QueryBuilder.TimeRange != nil {
+			qb.TimeRange.Compare = widget.DataSource.QueryBuilder.TimeRange.Compare
+		}
+		applied.DataSource.QueryBuilder = &qb
+
+	case "saved_query", "custom_sql":
+		start, end, err := s.queryBuilder.ResolveTimeRange(timeRange)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dashboard time range: %w", err)
+		}
+		parameters := make(map[string]interface{}, len(widget.DataSource.Parameters)+2)
+		for name, value := range widget.DataSource.Parameters {
+			parameters[name] = value
+		}
+		parameters["start"] = start.UTC().Format(widgetTimeFormat)
+		parameters["end"] = end.UTC().Format(widgetTimeFormat)
+		applied.DataSource.Parameters = parameters
+	}
+	return &applied, nil
+}
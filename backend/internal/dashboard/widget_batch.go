@@ -0,0 +1,146 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// widgetConcurrency bounds the widget queries one request for the data of
+// a whole dashboard runs at once
+const widgetConcurrency = 4
+
+// DashboardWidgetData runs the widgets of a dashboard the user in ctx can
+// view in one go, instead of a request per widget, over timeRange or the
+// dashboard's time range when nil. It returns the data and annotations of
+// each widget, or of those in widgetIDs when set, and the absolute time
+// range they read. Widgets whose query fails get the error and do not fail
+// the others. Data comes from the widget cache, with its age, unless
+// refresh is set.
+func (s *Service) DashboardWidgetData(ctx context.Context, dashboardID string, timeRange *models.QueryTimeRange, widgetIDs []string, refresh bool) ([]models.SnapshotWidget, *models.QueryTimeRange, error) {
+	dashboard, err := s.GetDashboard(ctx, dashboardID)
+	if err != nil {
+		return nil, nil, err
+	}
+	picked, err := s.DashboardTimeRange(dashboard, timeRange)
+	if err != nil {
+		return nil, nil, err
+	}
+	if timeRange, err = s.absoluteTimeRange(dashboard, timeRange); err != nil {
+		return nil, nil, err
+	}
+
+	if len(widgetIDs) > 0 {
+		selected := *dashboard
+		selected.Widgets = make([]models.DashboardWidget, 0, len(widgetIDs))
+		for _, id := range widgetIDs {
+			found := false
+			for _, widget := range dashboard.Widgets {
+				if widget.ID == id {
+					selected.Widgets = append(selected.Widgets, widget)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, nil, fmt.Errorf("widget not found: %s", id)
+			}
+		}
+		dashboard = &selected
+	}
+	return s.runWidgets(ctx, dashboard, timeRange, widgetRun{
+		annotations: true,
+		cached:      true,
+		cacheRange:  picked,
+		refresh:     refresh,
+	}), timeRange, nil
+}
+
+// absoluteTimeRange returns the time range a dashboard's widgets read, as
+// DashboardTimeRange does, resolved to absolute UTC times
+func (s *Service) absoluteTimeRange(dashboard *models.Dashboard, override *models.QueryTimeRange) (*models.QueryTimeRange, error) {
+	timeRange, err := s.DashboardTimeRange(dashboard, override)
+	if err != nil || timeRange == nil {
+		return nil, err
+	}
+	start, end, err := s.queryBuilder.ResolveTimeRange(timeRange)
+	if err != nil {
+		return nil, err
+	}
+	return &models.QueryTimeRange{Start: start.UTC(), End: end.UTC()}, nil
+}
+
+// widgetRun says how runWidgets gets the data of widgets
+type widgetRun struct {
+	annotations bool // also load the widgets' annotations
+	// cached serves data from the widget cache, keyed by cacheRange, the
+	// time range as picked rather than resolved; refresh loads it first
+	cached     bool
+	cacheRange *models.QueryTimeRange
+	refresh    bool
+}
+
+// runWidgets runs the widgets of a dashboard, a few at a time, and returns
+// their data, and their annotations if asked, in the dashboard's order.
+// Widgets not run before ctx is done get its error.
+func (s *Service) runWidgets(ctx context.Context, dashboard *models.Dashboard, timeRange *models.QueryTimeRange, run widgetRun) []models.SnapshotWidget {
+	widgets := make([]models.SnapshotWidget, len(dashboard.Widgets))
+	slots := make(chan struct{}, widgetConcurrency)
+	var wg sync.WaitGroup
+	for i := range dashboard.Widgets {
+		widget := &dashboard.Widgets[i]
+		widgets[i] = models.SnapshotWidget{WidgetID: widget.ID, Type: widget.Type}
+		if widget.Type == "text" && widget.Config.Content == "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			widgets[i].Error = err.Error()
+			continue
+		}
+
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(result *models.SnapshotWidget) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if widget.Type == "text" {
+				text, err := s.TextWidgetData(ctx, dashboard, widget, timeRange)
+				if err != nil {
+					result.Error = err.Error()
+					return
+				}
+				result.Data = text
+				return
+			}
+
+			applied, err := s.ApplyTimeRange(widget, timeRange)
+			if err == nil {
+				if run.cached {
+					result.Data, result.Cache, err = s.CachedWidgetData(ctx, widget, run.cacheRange, run.refresh)
+				} else {
+					result.Data, err = s.GenerateWidgetData(ctx, applied)
+				}
+			}
+			if err != nil {
+				result.Error = err.Error()
+				return
+			}
+			if !run.annotations {
+				return
+			}
+			if result.Annotations, err = s.WidgetAnnotations(ctx, dashboard.ID, applied, timeRange); err != nil {
+				log.Warn().Err(err).
+					Str("dashboard_id", dashboard.ID).
+					Str("widget_id", widget.ID).
+					Msg("Failed to load widget annotations")
+			}
+		}(&widgets[i])
+	}
+	wg.Wait()
+	return widgets
+}
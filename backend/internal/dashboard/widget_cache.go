@@ -0,0 +1,202 @@
+package dashboard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+const (
+	// defaultWidgetFreshness is how long widget data is served without
+	// refreshing it, for widgets without a freshness or refresh rate
+	defaultWidgetFreshness = 30 * time.Second
+	// maxWidgetFreshness caps how long widget data is served as is
+	maxWidgetFreshness = time.Hour
+	// maxWidgetStaleness is how long past its freshness widget data may be
+	// served while it is refreshed in the background; older data is loaded
+	// again first
+	maxWidgetStaleness = 10 * time.Minute
+	// maxWidgetCacheEntries bounds the cache, the oldest data going first
+	maxWidgetCacheEntries = 1000
+	// widgetRevalidateTimeout bounds a background refresh
+	widgetRevalidateTimeout = time.Minute
+)
+
+// widgetCache holds the latest data of widgets by what determines it
+type widgetCache struct {
+	mu         sync.Mutex
+	entries    map[string]*widgetCacheEntry
+	refreshing map[string]bool
+}
+
+type widgetCacheEntry struct {
+	data     interface{}
+	storedAt time.Time
+}
+
+func newWidgetCache() *widgetCache {
+	return &widgetCache{
+		entries:    make(map[string]*widgetCacheEntry),
+		refreshing: make(map[string]bool),
+	}
+}
+
+// CachedWidgetData returns the data of a widget under timeRange, as
+// GenerateWidgetData does after ApplyTimeRange, served from the cache
+// while it is fresh. Data at most maxWidgetStaleness past its freshness
+// is returned at once and refreshed in the background. With refresh, or without usable data, it is loaded first.
+// The returned info says how old the data is.
+func (s *Service) CachedWidgetData(ctx context.Context, widget *models.DashboardWidget, timeRange *models.QueryTimeRange, refresh bool) (interface{}, *models.WidgetCacheInfo, error) {
+	key, err := widgetCacheKey(widget, timeRange, auth.UserFromContext(ctx).IsAdmin())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if !refresh {
+		s.widgetCache.mu.Lock()
+		entry, ok := s.widgetCache.entries[key]
+		s.widgetCache.mu.Unlock()
+		if ok {
+			age, freshness := now.Sub(entry.storedAt), widgetFreshness(widget)
+			if age <= freshness {
+				return entry.data, cacheInfo(entry.storedAt, age, false), nil
+			}
+			if age <= freshness+maxWidgetStaleness {
+				s.revalidateWidget(ctx, key, widget, timeRange)
+				return entry.data, cacheInfo(entry.storedAt, age, true), nil
+			}
+		}
+	}
+
+	data, err := s.loadWidgetData(ctx, key, widget, timeRange)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, cacheInfo(now, 0, false), nil
+}
+
+// loadWidgetData runs a widget and caches its data
+func (s *Service) loadWidgetData(ctx context.Context, key string, widget *models.DashboardWidget, timeRange *models.QueryTimeRange) (interface{}, error) {
+	applied, err := s.ApplyTimeRange(widget, timeRange)
+	if err != nil {
+		return nil, err
+	}
+	storedAt := time.Now()
+	data, err := s.GenerateWidgetData(ctx, applied)
+	if err != nil {
+		return nil, err
+	}
+	s.widgetCache.store(key, data, storedAt)
+	return data, nil
+}
+
+// revalidateWidget refreshes a widget's cached data in the background as
+// the user in ctx, unless it is already being refreshed
+func (s *Service) revalidateWidget(ctx context.Context, key string, widget *models.DashboardWidget, timeRange *models.QueryTimeRange) {
+	s.widgetCache.mu.Lock()
+	if s.widgetCache.refreshing[key] {
+		s.widgetCache.mu.Unlock()
+		return
+	}
+	s.widgetCache.refreshing[key] = true
+	s.widgetCache.mu.Unlock()
+
+	user := auth.UserFromContext(ctx)
+	copied := *widget
+	go func() {
+		defer func() {
+			s.widgetCache.mu.Lock()
+			delete(s.widgetCache.refreshing, key)
+			s.widgetCache.mu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(auth.WithUser(context.Background(), user), widgetRevalidateTimeout)
+		defer cancel()
+		if _, err := s.loadWidgetData(ctx, key, &copied, timeRange); err != nil {
+			log.Warn().Err(err).Str("widget_id", copied.ID).Msg("Failed to refresh cached widget data")
+		}
+	}()
+}
+
+// store caches data, dropping data too old to serve under any freshness
+// and, when full, the oldest entry
+func (c *widgetCache) store(key string, data interface{}, storedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if current, ok := c.entries[key]; ok && current.storedAt.After(storedAt) {
+		return
+	}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxWidgetCacheEntries {
+		var oldestKey string
+		var oldest time.Time
+		for k, entry := range c.entries {
+			if time.Since(entry.storedAt) > maxWidgetFreshness+maxWidgetStaleness {
+				delete(c.entries, k)
+				continue
+			}
+			if oldestKey == "" || entry.storedAt.Before(oldest) {
+				oldestKey, oldest = k, entry.storedAt
+			}
+		}
+		if len(c.entries) >= maxWidgetCacheEntries {
+			delete(c.entries, oldestKey)
+		}
+	}
+	c.entries[key] = &widgetCacheEntry{data: data, storedAt: storedAt}
+}
+
+// widgetCacheKey identifies the data of a widget: its type, configuration
+// and data source and the time range it reads, which for relative ranges
+// is their name so that newer data replaces older. Admins may read tables
+// other users may not, so their data is kept apart.
+func widgetCacheKey(widget *models.DashboardWidget, timeRange *models.QueryTimeRange, admin bool) (string, error) {
+	if widget.IgnoreDashboardTimeRange {
+		timeRange = nil
+	}
+	encoded, err := json.Marshal(struct {
+		Type       string                  `json:"type"`
+		Config     models.WidgetConfig     `json:"config"`
+		DataSource models.WidgetDataSource `json:"data_source"`
+		TimeRange  *models.QueryTimeRange  `json:"time_range"`
+		Admin      bool                    `json:"admin"`
+	}{widget.Type, widget.Config, widget.DataSource, timeRange, admin})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// widgetFreshness is how long a widget's data is served as is: the
+// freshness of its query builder, else its refresh rate, up to
+// maxWidgetFreshness
+func widgetFreshness(widget *models.DashboardWidget) time.Duration {
+	freshness := defaultWidgetFreshness
+	if qb := widget.DataSource.QueryBuilder; widget.DataSource.Type == "query_builder" && qb != nil && qb.Freshness > 0 {
+		freshness = time.Duration(qb.Freshness) * time.Second
+	} else if widget.RefreshRate > 0 {
+		freshness = time.Duration(widget.RefreshRate) * time.Second
+	}
+	if freshness > maxWidgetFreshness {
+		return maxWidgetFreshness
+	}
+	return freshness
+}
+
+func cacheInfo(storedAt time.Time, age time.Duration, stale bool) *models.WidgetCacheInfo {
+	return &models.WidgetCacheInfo{
+		CachedAt: storedAt,
+		Age:      age.Seconds(),
+		Stale:    stale,
+	}
+}
@@ -0,0 +1,315 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+const (
+	defaultHistogramBins = 20
+	maxHistogramBins     = 200
+	defaultPieSlices     = 10
+	otherSliceLabel      = "other"
+)
+
+// generateHistogramData buckets the values of a widget's value column
+func (s *Service) generateHistogramData(widget *models.DashboardWidget, queryResult *models.QueryBuilderResponse) (*models.HistogramData, error) {
+	data := &models.HistogramData{Buckets: []models.HistogramBucket{}}
+	if len(queryResult.Rows) == 0 {
+		return data, nil
+	}
+
+	valueField, err := valueColumn(widget.Config.ValueField, resultColumns(queryResult), queryResult.Rows)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, 0, len(queryResult.Rows))
+	for _, row := range queryResult.Rows {
+		if v, ok := toFloat(row[valueField]); ok {
+			values = append(values, v)
+		}
+	}
+	data.Count = len(values)
+	if len(values) == 0 {
+		return data, nil
+	}
+
+	data.Min, data.Max = values[0], values[0]
+	for _, v := range values {
+		data.Min = math.Min(data.Min, v)
+		data.Max = math.Max(data.Max, v)
+	}
+
+	bins := widget.Config.Bins
+	if bins <= 0 {
+		bins = defaultHistogramBins
+	}
+	if bins > maxHistogramBins {
+		bins = maxHistogramBins
+	}
+	if data.Min == data.Max {
+		bins = 1
+	}
+
+	width := (data.Max - data.Min) / float64(bins)
+	data.Buckets = make([]models.HistogramBucket, bins)
+	for i := range data.Buckets {
+		data.Buckets[i].Lower = data.Min + float64(i)*width
+		data.Buckets[i].Upper = data.Min + float64(i+1)*width
+	}
+	data.Buckets[bins-1].Upper = data.Max
+
+	for _, v := range values {
+		i := bins - 1
+		if width > 0 {
+			i = int((v - data.Min) / width)
+		}
+		if i >= bins {
+			i = bins - 1
+		}
+		data.Buckets[i].Count++
+	}
+	return data, nil
+}
+
+// generateHeatmapData lays out a widget's values by time and category,
+// summing rows that fall in the same cell. Categories with the largest
+// totals come first.
+func (s *Service) generateHeatmapData(widget *models.DashboardWidget, queryResult *models.QueryBuilderResponse) (*models.HeatmapData, error) {
+	data := &models.HeatmapData{X: []string{}, Y: []string{}, Values: [][]float64{}}
+	if len(queryResult.Rows) == 0 {
+		return data, nil
+	}
+	columns := resultColumns(queryResult)
+
+	timeField := widget.Config.TimeField
+	if timeField == "" {
+		timeField = columns[0]
+	} else if !containsColumn(columns, timeField) {
+		return nil, fmt.Errorf("unknown time field: %s", timeField)
+	}
+	valueField, err := valueColumn(widget.Config.ValueField, columns, queryResult.Rows, timeField)
+	if err != nil {
+		return nil, err
+	}
+	labelField, err := labelColumn(widget.Config.LabelField, columns, timeField, valueField)
+	if err != nil {
+		return nil, err
+	}
+
+	cells := make(map[string]map[string]float64)
+	times := make(map[string]bool)
+	totals := make(map[string]float64)
+	for _, row := range queryResult.Rows {
+		value, ok := toFloat(row[valueField])
+		if !ok {
+			continue
+		}
+		x := fmt.Sprintf("%v", row[timeField])
+		y := fmt.Sprintf("%v", row[labelField])
+		if cells[y] == nil {
+			cells[y] = make(map[string]float64)
+		}
+		cells[y][x] += value
+		times[x] = true
+		totals[y] += value
+	}
+
+	for x := range times {
+		data.X = append(data.X, x)
+	}
+	sort.Strings(data.X)
+	for y := range cells {
+		data.Y = append(data.Y, y)
+	}
+	sort.Slice(data.Y, func(i, j int) bool {
+		if totals[data.Y[i]] != totals[data.Y[j]] {
+			return totals[data.Y[i]] > totals[data.Y[j]]
+		}
+		return data.Y[i] < data.Y[j]
+	})
+
+	for _, y := range data.Y {
+		row := make([]float64, len(data.X))
+		for i, x := range data.X {
+			row[i] = cells[y][x]
+			data.Max = math.Max(data.Max, row[i])
+		}
+		data.Values = append(data.Values, row)
+	}
+	return data, nil
+}
+
+// generatePieData sums a widget's values by label, grouping the labels
+// past MaxSlices into one other slice. Negative values are left out.
+func (s *Service) generatePieData(widget *models.DashboardWidget, queryResult *models.QueryBuilderResponse) (*models.PieData, error) {
+	data := &models.PieData{Slices: []models.PieSlice{}, Donut: widget.Config.Donut}
+	if len(queryResult.Rows) == 0 {
+		return data, nil
+	}
+	columns := resultColumns(queryResult)
+
+	valueField, err := valueColumn(widget.Config.ValueField, columns, queryResult.Rows)
+	if err != nil {
+		return nil, err
+	}
+	labelField, err := labelColumn(widget.Config.LabelField, columns, valueField)
+	if err != nil {
+		return nil, err
+	}
+
+	sums := make(map[string]float64)
+	for _, row := range queryResult.Rows {
+		value, ok := toFloat(row[valueField])
+		if !ok || value < 0 {
+			continue
+		}
+		sums[fmt.Sprintf("%v", row[labelField])] += value
+	}
+
+	for label, value := range sums {
+		data.Slices = append(data.Slices, models.PieSlice{Label: label, Value: value})
+		data.Total += value
+	}
+	sort.Slice(data.Slices, func(i, j int) bool {
+		if data.Slices[i].Value != data.Slices[j].Value {
+			return data.Slices[i].Value > data.Slices[j].Value
+		}
+		return data.Slices[i].Label < data.Slices[j].Label
+	})
+
+	maxSlices := widget.Config.MaxSlices
+	if maxSlices <= 0 {
+		maxSlices = defaultPieSlices
+	}
+	if len(data.Slices) > maxSlices {
+		other := models.PieSlice{Label: otherSliceLabel}
+		for _, slice := range data.Slices[maxSlices-1:] {
+			other.Value += slice.Value
+		}
+		data.Slices = append(data.Slices[:maxSlices-1], other)
+	}
+
+	for i := range data.Slices {
+		if data.Total > 0 {
+			data.Slices[i].Percent = data.Slices[i].Value / data.Total * 100
+		}
+	}
+	return data, nil
+}
+
+// generateLogStreamData returns the logs a log_stream widget starts with
+// and the filters of the logs appended to it
+func (s *Service) generateLogStreamData(widget *models.DashboardWidget, queryResult *models.QueryBuilderResponse) (*models.LogStreamData, error) {
+	logs := queryResult.Rows
+	if logs == nil {
+		logs = []map[string]interface{}{}
+	}
+	return &models.LogStreamData{
+		Logs:    logs,
+		Filters: widget.Config.LogFilters,
+	}, nil
+}
+
+// resultColumns returns the column names of a result in order, falling
+// back to the sorted keys of its first row
+func resultColumns(queryResult *models.QueryBuilderResponse) []string {
+	columns := make([]string, 0, len(queryResult.Columns))
+	for _, column := range queryResult.Columns {
+		columns = append(columns, column.Name)
+	}
+	if len(columns) == 0 && len(queryResult.Rows) > 0 {
+		for name := range queryResult.Rows[0] {
+			columns = append(columns, name)
+		}
+		sort.Strings(columns)
+	}
+	return columns
+}
+
+// valueColumn returns field, or the last column other than exclude whose
+// values are numbers
+func valueColumn(field string, columns []string, rows []map[string]interface{}, exclude ...string) (string, error) {
+	if field != "" {
+		if !containsColumn(columns, field) {
+			return "", fmt.Errorf("unknown value field: %s", field)
+		}
+		return field, nil
+	}
+	for i := len(columns) - 1; i >= 0; i-- {
+		if containsColumn(exclude, columns[i]) {
+			continue
+		}
+		if numericColumn(columns[i], rows) {
+			return columns[i], nil
+		}
+	}
+	return "", fmt.Errorf("widget query returns no numeric column")
+}
+
+// labelColumn returns field, or the first column other than exclude
+func labelColumn(field string, columns []string, exclude ...string) (string, error) {
+	if field != "" {
+		if !containsColumn(columns, field) {
+			return "", fmt.Errorf("unknown label field: %s", field)
+		}
+		return field, nil
+	}
+	for _, column := range columns {
+		if !containsColumn(exclude, column) {
+			return column, nil
+		}
+	}
+	return "", fmt.Errorf("widget query returns no label column")
+}
+
+// numericColumn reports whether the first non-null value of a column is a number
+func numericColumn(column string, rows []map[string]interface{}) bool {
+	for _, row := range rows {
+		if value, ok := row[column]; ok && value != nil {
+			_, numeric := toFloat(value)
+			return numeric
+		}
+	}
+	return false
+}
+
+func containsColumn(columns []string, name string) bool {
+	for _, column := range columns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// toFloat reads a number, which ClickHouse quotes in JSON when it is a 64-bit integer
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	default:
+		return 0, false
+	}
+}
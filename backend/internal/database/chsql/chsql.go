@@ -0,0 +1,30 @@
+// Package chsql holds the helpers shared by the stores that build
+// ClickHouse SQL by hand and read its JSON results.
+package chsql
+
+import (
+	"strconv"
+	"strings"
+)
+
+var quoter = strings.NewReplacer(`\`, `\\`, "'", `\'`)
+
+// Quote returns s as a ClickHouse string literal. Backslashes are escaped
+// too, since ClickHouse reads them as escapes.
+func Quote(s string) string {
+	return "'" + quoter.Replace(s) + "'"
+}
+
+// ToInt64 reads a number of a JSON result, which ClickHouse quotes when it
+// is a 64-bit integer. Anything else reads as 0.
+func ToInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case float64:
+		return int64(v)
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,37 @@
+package chsql
+
+import "testing"
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"":             "''",
+		"api":          "'api'",
+		"it's":         `'it\'s'`,
+		`C:\logs`:      `'C:\\logs'`,
+		`\' OR 1=1 --`: `'\\\' OR 1=1 --'`,
+		"line\nbreak":  "'line\nbreak'",
+	}
+	for in, want := range tests {
+		if got := Quote(in); got != want {
+			t.Errorf("Quote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestToInt64(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  int64
+	}{
+		{float64(42), 42},
+		{"9007199254740993", 9007199254740993},
+		{"not a number", 0},
+		{nil, 0},
+		{true, 0},
+	}
+	for _, tt := range tests {
+		if got := ToInt64(tt.value); got != tt.want {
+			t.Errorf("ToInt64(%#v) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
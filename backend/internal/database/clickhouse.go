@@ -11,6 +11,7 @@ import (
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/config"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/query"
@@ -77,7 +78,12 @@ func New(cfg config.DatabaseConfig) (*DB, error) {
 
 func (db *DB) ping(ctx context.Context) error {
 	query := "SELECT 1"
-	resp, err := db.client.Post(db.baseURL, "text/plain", strings.NewReader(query))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, db.baseURL, strings.NewReader(query))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := db.client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -159,7 +165,8 @@ func (db *DB) Query(ctx context.Context, queryStr string) ([]map[string]interfac
 		Query: queryStr,
 	}
 	
-	response, err := db.queryEngine.Execute(ctx, req)
+	// Internal queries may read system tables
+	response, err := db.queryEngine.Execute(auth.WithUser(ctx, auth.SystemUser), req)
 	if err != nil {
 		return nil, err
 	}
@@ -230,6 +237,10 @@ func (db *DB) QueryLogs(ctx context.Context, query *models.LogQuery) ([]models.L
 		q += fmt.Sprintf(" AND position(lower(message), lower('%s')) > 0", strings.ReplaceAll(query.Search, "'", "\\'"))
 	}
 
+	if query.Filter != "" {
+		q += " AND (" + query.Filter + ")"
+	}
+
 	q += " ORDER BY timestamp DESC"
 	
 	if query.Limit > 0 {
@@ -311,6 +322,12 @@ func (db *DB) GetStorageStats() (*storage.StorageStats, error) {
 	return db.storageManager.GetStorageStats()
 }
 
+// NewCapacityForecaster creates a forecaster of the disk usage of the
+// database, estimating growth from historyDays of samples
+func (db *DB) NewCapacityForecaster(historyDays int) (*storage.CapacityForecaster, error) {
+	return storage.NewCapacityForecaster(storage.NewClickHouseAdapter(db.baseURL), db.database, historyDays)
+}
+
 // GetQueryEngine returns the query engine
 func (db *DB) GetQueryEngine() *query.Engine {
 	return db.queryEngine
@@ -321,6 +338,11 @@ func (db *DB) ExecuteQuery(ctx context.Context, req *query.QueryRequest) (*query
 	return db.queryEngine.Execute(ctx, req)
 }
 
+// StreamQuery executes a query through the query engine, calling fn per row
+func (db *DB) StreamQuery(ctx context.Context, req *query.QueryRequest, fn func(row map[string]interface{}) error) (int, error) {
+	return db.queryEngine.Stream(ctx, req, fn)
+}
+
 // ExecuteSQL executes a raw SQL query and returns results
 func (db *DB) ExecuteSQL(sql string) ([]map[string]interface{}, error) {
 	ctx := context.Background()
@@ -1,12 +1,17 @@
 package database
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+
+	queryengine "github.com/your-username/click-lite-log-analytics/backend/internal/query"
 )
 
 // QueryAdapter implements the QueryExecutor interface for ClickHouse
@@ -34,8 +39,26 @@ func (qa *QueryAdapter) ExecuteQuery(ctx context.Context, query string) ([]map[s
 		query += " FORMAT JSONEachRow"
 	}
 	
+	// Tagged queries can be tracked in system.processes and killed, and are
+	// cancelled by ClickHouse when the client goes away
+	params := url.Values{}
+	if queryID := queryengine.QueryIDFromContext(ctx); queryID != "" {
+		params.Set("query_id", queryID)
+		params.Set("cancel_http_readonly_queries_on_client_close", "1")
+	}
+	// The summary header only holds the final counts once the whole result
+	// has been computed
+	summary := queryengine.ExecutionSummaryFromContext(ctx)
+	if summary != nil {
+		params.Set("wait_end_of_query", "1")
+	}
+	endpoint := qa.baseURL
+	if len(params) > 0 {
+		endpoint += "/?" + params.Encode()
+	}
+	
 	// Create request
-	req, err := http.NewRequestWithContext(ctx, "POST", qa.baseURL, strings.NewReader(query))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(query))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -52,6 +75,9 @@ func (qa *QueryAdapter) ExecuteQuery(ctx context.Context, query string) ([]map[s
 		body, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("ClickHouse error: %s", string(body))
 	}
+	if summary != nil {
+		readSummary(resp.Header.Get("X-ClickHouse-Summary"), summary)
+	}
 	
 	// Parse response
 	body, err := io.ReadAll(resp.Body)
@@ -78,4 +104,108 @@ func (qa *QueryAdapter) ExecuteQuery(ctx context.Context, query string) ([]map[s
 	}
 	
 	return results, nil
-}
\ No newline at end of file
+}
+
+// StreamQuery executes a SQL query and calls fn for each row as ClickHouse
+// sends it, so large results are never held in memory
+func (qa *QueryAdapter) StreamQuery(ctx context.Context, query string, fn func(row map[string]interface{}) error) error {
+	if !strings.Contains(strings.ToUpper(query), "FORMAT") {
+		query += " FORMAT JSONEachRow"
+	}
+	
+	endpoint := qa.baseURL
+	if queryID := queryengine.QueryIDFromContext(ctx); queryID != "" {
+		params := url.Values{}
+		params.Set("query_id", queryID)
+		params.Set("cancel_http_readonly_queries_on_client_close", "1")
+		endpoint += "/?" + params.Encode()
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(query))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	
+	resp, err := qa.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ClickHouse error: %s", string(body))
+	}
+	
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		
+		var row map[string]interface{}
+		if err := json.Unmarshal(line, &row); err != nil {
+			// ClickHouse reports errors raised mid-stream as plain text
+			return fmt.Errorf("ClickHouse error: %s", string(line))
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// StreamRaw executes a SQL query with ClickHouse encoding the result in
+// format (e.g. CSVWithNames or Parquet) and copies it to w as it arrives
+func (qa *QueryAdapter) StreamRaw(ctx context.Context, query, format string, w io.Writer) error {
+	query += " FORMAT " + format
+
+	params := url.Values{}
+	if queryID := queryengine.QueryIDFromContext(ctx); queryID != "" {
+		params.Set("query_id", queryID)
+		params.Set("cancel_http_readonly_queries_on_client_close", "1")
+	}
+	if format == "Parquet" {
+		// Strings are written as binary by default, which most readers show as bytes
+		params.Set("output_format_parquet_string_as_string", "1")
+	}
+	endpoint := qa.baseURL
+	if len(params) > 0 {
+		endpoint += "/?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(query))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := qa.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ClickHouse error: %s", string(body))
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// readSummary parses the X-ClickHouse-Summary header, whose counters are
+// JSON strings, e.g. {"read_rows":"1000","read_bytes":"8000",...}
+func readSummary(header string, summary *queryengine.ExecutionSummary) {
+	if header == "" {
+		return
+	}
+	var counters map[string]string
+	if err := json.Unmarshal([]byte(header), &counters); err != nil {
+		return
+	}
+	summary.ReadRows, _ = strconv.ParseInt(counters["read_rows"], 10, 64)
+	summary.ReadBytes, _ = strconv.ParseInt(counters["read_bytes"], 10, 64)
+}
@@ -0,0 +1,426 @@
+package errors
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// baselineWeeks is how many weeks of hourly counts are kept per
+	// service: the current one and the four before it for the hour-of-week
+	// baseline
+	baselineWeeks = 5
+	baselineHours = baselineWeeks * 7 * 24
+	// dailyBaselineDays is how many previous days the daily baseline
+	// averages
+	dailyBaselineDays = 7
+	// minBaselineLogs is how many logs an hour needs for its error rate to
+	// count, so that a couple of failures in a quiet hour are not taken
+	// for a rate
+	minBaselineLogs = 20
+	// weeklyMinSamples is how many weeks the weekly baseline needs to score
+	// an hour: two already tell a Monday morning from a Sunday night, which
+	// the daily baseline cannot
+	weeklyMinSamples = 2
+	// dailyMinSamples is how many days the daily baseline needs to score an
+	// hour while there are too few weeks
+	dailyMinSamples = 3
+	// maxBaselineServices bounds the services tracked; the one that logged
+	// least recently is dropped when full
+	maxBaselineServices = 1000
+)
+
+// ServiceBaseline compares the error rate of a service in the current hour,
+// errors per log, with the rates it usually has at that time
+type ServiceBaseline struct {
+	Service   string    `json:"service"`
+	Hour      time.Time `json:"hour"` // start of the current hour, UTC
+	Logs      int64     `json:"logs"`
+	Errors    int64     `json:"errors"`
+	ErrorRate float64   `json:"error_rate"`
+	// Daily is the baseline of the same hour on the previous days
+	Daily *RateBaseline `json:"daily,omitempty"`
+	// Weekly is the baseline of the same hour of the week on the previous
+	// weeks, such as Tuesday 14:00
+	Weekly *RateBaseline `json:"weekly,omitempty"`
+	// DayOverDay is the error rate over the one of the same hour yesterday,
+	// omitted when either hour has too few logs
+	DayOverDay *float64 `json:"day_over_day,omitempty"`
+	// Score is the z-score of the error rate against the weekly baseline,
+	// or the daily one while there are too few weeks, omitted while there
+	// is too little data on either hour
+	Score     *float64 `json:"score,omitempty"`
+	Deviating bool     `json:"deviating"`
+}
+
+// RateBaseline is the error rate a service usually has at a time
+type RateBaseline struct {
+	Mean    float64 `json:"mean"`
+	StdDev  float64 `json:"std_dev"`
+	Samples int     `json:"samples"` // hours with enough logs
+	// Ratio is the current error rate over the mean, 3 for an error rate
+	// three times the usual one; omitted when the mean is 0
+	Ratio *float64 `json:"ratio,omitempty"`
+	// ExpectedErrors is how many errors the logs of the current hour would
+	// hold at the mean rate, the error budget of the hour
+	ExpectedErrors float64 `json:"expected_errors"`
+	ZScore         float64 `json:"z_score"`
+}
+
+// rateFloors floor the standard deviation of the error rate baselines at a
+// share of their mean, since a rate that barely moves would otherwise make
+// small changes look like large deviations, and absolutely for services
+// that usually log no errors
+var rateFloors = AnomalyOptions{MinStdDev: 0.001, MinRelativeStdDev: 0.25}
+
+// rateOptions returns how a baseline holding at least minSamples hours
+// scores error rates
+func rateOptions(minSamples int) AnomalyOptions {
+	options := rateFloors
+	options.MinSamples = minSamples
+	return options
+}
+
+// HourlyCounts are the logs and errors a service sent in an hour
+type HourlyCounts struct {
+	Hour   time.Time `json:"hour"`
+	Logs   int64     `json:"logs"`
+	Errors int64     `json:"errors"`
+}
+
+// BaselineStorage persists the hourly counts of the services, so that
+// baselines survive restarts
+type BaselineStorage interface {
+	Load() (map[string][]HourlyCounts, error)
+	Save(counts map[string][]HourlyCounts) error
+}
+
+// baselineTracker counts the logs and errors of each service per hour, in
+// rings ending at the hour of their latest log. Once an hour ends, its
+// error rate goes to seasonal detectors by hour of the day and of the week,
+// which the current hour is compared with.
+type baselineTracker struct {
+	mu       sync.Mutex
+	services map[string]*serviceHours
+	storage  BaselineStorage
+}
+
+type serviceHours struct {
+	hours      [baselineHours]hourCounts
+	lastHour   int64 // unix hour of the latest log
+	closedHour int64 // unix hour of the latest one in the baselines
+	daily      *SeasonalDetector
+	weekly     *SeasonalDetector
+}
+
+func newServiceHours(hour int64) *serviceHours {
+	return &serviceHours{
+		lastHour:   hour,
+		closedHour: hour - 1,
+		daily:      NewSeasonalDetectorWithOptions(24*time.Hour, time.Hour, dailyBaselineDays, rateOptions(dailyMinSamples)),
+		weekly:     NewSeasonalDetectorWithOptions(7*24*time.Hour, time.Hour, baselineWeeks-1, rateOptions(weeklyMinSamples)),
+	}
+}
+
+type hourCounts struct {
+	logs, errors int64
+}
+
+func newBaselineTracker() *baselineTracker {
+	return &baselineTracker{services: make(map[string]*serviceHours)}
+}
+
+// record counts a log of service, received at now. Logs are counted by
+// arrival, like error groups, so that backfilled logs do not land in past
+// hours already compared.
+func (bt *baselineTracker) record(service string, isError bool, now time.Time) {
+	if service == "" {
+		return
+	}
+	hour := now.Unix() / 3600
+
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	s := bt.service(service, hour)
+	if hour <= s.lastHour-baselineHours {
+		return
+	}
+	s.hours[hour%baselineHours].logs++
+	if isError {
+		s.hours[hour%baselineHours].errors++
+	}
+}
+
+// service returns the hours of service advanced to hour, tracking it if
+// needed. Callers hold the lock.
+func (bt *baselineTracker) service(service string, hour int64) *serviceHours {
+	s, ok := bt.services[service]
+	if !ok {
+		if len(bt.services) >= maxBaselineServices {
+			bt.evictOldest()
+		}
+		s = newServiceHours(hour)
+		bt.services[service] = s
+	}
+	s.closeBefore(hour)
+	for h := s.lastHour + 1; h <= hour && h <= s.lastHour+baselineHours; h++ {
+		s.hours[h%baselineHours] = hourCounts{}
+	}
+	if hour > s.lastHour {
+		s.lastHour = hour
+	}
+	return s
+}
+
+// evictOldest drops the service that logged least recently. Callers hold
+// the lock.
+func (bt *baselineTracker) evictOldest() {
+	var oldest string
+	var oldestHour int64
+	for service, s := range bt.services {
+		if oldest == "" || s.lastHour < oldestHour {
+			oldest = service
+			oldestHour = s.lastHour
+		}
+	}
+	delete(bt.services, oldest)
+}
+
+// closeBefore adds the error rate of the latest hour with logs to the
+// baselines once hour is past it. Callers hold the lock.
+func (s *serviceHours) closeBefore(hour int64) {
+	if s.lastHour >= hour || s.closedHour >= s.lastHour {
+		return
+	}
+	s.closedHour = s.lastHour
+	if rate, ok := s.rate(s.lastHour); ok {
+		at := time.Unix(s.lastHour*3600, 0).UTC()
+		s.daily.AddDataPoint(at, rate)
+		s.weekly.AddDataPoint(at, rate)
+	}
+}
+
+// counts returns the counts of an hour, none when it is outside the ring
+func (s *serviceHours) counts(hour int64) hourCounts {
+	if hour > s.lastHour || hour <= s.lastHour-baselineHours {
+		return hourCounts{}
+	}
+	return s.hours[hour%baselineHours]
+}
+
+// rate returns the error rate of an hour and false when it has too few
+// logs
+func (s *serviceHours) rate(hour int64) (float64, bool) {
+	c := s.counts(hour)
+	if c.logs < minBaselineLogs {
+		return 0, false
+	}
+	return float64(c.errors) / float64(c.logs), true
+}
+
+// rateBaseline summarizes the error rates a seasonal detector holds for the
+// hour at, and scores the current one against them. It returns false when
+// the current hour has too few logs or the baseline too few hours to score.
+func rateBaseline(sd *SeasonalDetector, at time.Time, current hourCounts) (*RateBaseline, bool) {
+	mean, stdDev, samples := sd.Baseline(at)
+	if samples == 0 {
+		return nil, false
+	}
+
+	b := &RateBaseline{
+		Mean:           mean,
+		StdDev:         stdDev,
+		Samples:        samples,
+		ExpectedErrors: mean * float64(current.logs),
+	}
+	if current.logs < minBaselineLogs {
+		return b, false
+	}
+	rate := float64(current.errors) / float64(current.logs)
+	if mean > 0 {
+		ratio := rate / mean
+		b.Ratio = &ratio
+	}
+	score, ok := sd.ZScore(at, rate)
+	b.ZScore = score
+	return b, ok
+}
+
+// snapshot compares the current hour of a service with its baselines.
+// Callers hold the lock.
+func (s *serviceHours) snapshot(service string, now time.Time, threshold float64) ServiceBaseline {
+	hour := now.Unix() / 3600
+	s.closeBefore(hour)
+	current := s.counts(hour)
+	at := time.Unix(hour*3600, 0).UTC()
+	daily, dailyScored := rateBaseline(s.daily, at, current)
+	weekly, weeklyScored := rateBaseline(s.weekly, at, current)
+	sb := ServiceBaseline{
+		Service: service,
+		Hour:    at,
+		Logs:    current.logs,
+		Errors:  current.errors,
+		Daily:   daily,
+		Weekly:  weekly,
+	}
+	if current.logs > 0 {
+		sb.ErrorRate = float64(current.errors) / float64(current.logs)
+	}
+	if current.logs < minBaselineLogs {
+		return sb
+	}
+
+	if yesterday, ok := s.rate(hour - 24); ok && yesterday > 0 {
+		dayOverDay := sb.ErrorRate / yesterday
+		sb.DayOverDay = &dayOverDay
+	}
+
+	if weeklyScored || dailyScored {
+		baseline, _ := sb.scoredBaseline()
+		score := baseline.ZScore
+		sb.Score = &score
+		sb.Deviating = score >= threshold
+	}
+	return sb
+}
+
+// scoredBaseline returns the baseline the score is against, the weekly one
+// unless it has too few weeks, and the season it covers
+func (sb ServiceBaseline) scoredBaseline() (*RateBaseline, string) {
+	if sb.Weekly != nil && sb.Weekly.Samples >= weeklyMinSamples {
+		return sb.Weekly, sb.Hour.Format("Monday 15:04 MST")
+	}
+	return sb.Daily, sb.Hour.Format("15:04 MST")
+}
+
+// SetBaselineStorage persists the hourly counts the error rate baselines
+// are computed from, restoring those it holds
+func (ed *ErrorDetector) SetBaselineStorage(storage BaselineStorage) error {
+	stored, err := storage.Load()
+	if err != nil {
+		return err
+	}
+
+	bt := ed.baselines
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	bt.storage = storage
+	now := time.Now().Unix() / 3600
+	for service, counts := range stored {
+		sort.Slice(counts, func(i, j int) bool { return counts[i].Hour.Before(counts[j].Hour) })
+		for _, c := range counts {
+			hour := c.Hour.Unix() / 3600
+			if service == "" || hour > now || hour <= now-baselineHours || c.Logs < 0 || c.Errors < 0 {
+				continue
+			}
+			s := bt.service(service, hour)
+			s.hours[hour%baselineHours].logs += c.Logs
+			s.hours[hour%baselineHours].errors += c.Errors
+		}
+	}
+	return nil
+}
+
+// saveBaselines persists the hourly counts of the services, dropping the
+// services that sent nothing in the hours kept
+func (ed *ErrorDetector) saveBaselines() {
+	bt := ed.baselines
+	bt.mu.Lock()
+	now := time.Now().Unix() / 3600
+	counts := make(map[string][]HourlyCounts, len(bt.services))
+	for service, s := range bt.services {
+		if s.lastHour <= now-baselineHours {
+			delete(bt.services, service)
+			continue
+		}
+		for h := s.lastHour - baselineHours + 1; h <= s.lastHour; h++ {
+			if c := s.counts(h); c.logs > 0 {
+				counts[service] = append(counts[service], HourlyCounts{
+					Hour:   time.Unix(h*3600, 0).UTC(),
+					Logs:   c.logs,
+					Errors: c.errors,
+				})
+			}
+		}
+	}
+	storage := bt.storage
+	bt.mu.Unlock()
+
+	if storage == nil {
+		return
+	}
+	if err := storage.Save(counts); err != nil {
+		log.Error().Err(err).Msg("Failed to save error rate baselines")
+	}
+}
+
+// GetServiceBaselines returns the error rate of each service against its
+// baselines, the most deviating first
+func (ed *ErrorDetector) GetServiceBaselines() []ServiceBaseline {
+	now := time.Now()
+	bt := ed.baselines
+	bt.mu.Lock()
+	baselines := make([]ServiceBaseline, 0, len(bt.services))
+	for service, s := range bt.services {
+		baselines = append(baselines, s.snapshot(service, now, ed.alertThresholds.AnomalyStdDev))
+	}
+	bt.mu.Unlock()
+
+	sort.Slice(baselines, func(i, j int) bool {
+		si, sj := baselines[i].Score, baselines[j].Score
+		if (si == nil) != (sj == nil) {
+			return si != nil
+		}
+		if si != nil && *si != *sj {
+			return *si > *sj
+		}
+		return baselines[i].Service < baselines[j].Service
+	})
+	return baselines
+}
+
+// GetServiceBaseline returns the error rate of a service against its
+// baselines
+func (ed *ErrorDetector) GetServiceBaseline(service string) (*ServiceBaseline, bool) {
+	bt := ed.baselines
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+
+	s, ok := bt.services[service]
+	if !ok {
+		return nil, false
+	}
+	sb := s.snapshot(service, time.Now(), ed.alertThresholds.AnomalyStdDev)
+	return &sb, true
+}
+
+// baselineAnomaly describes a service whose error rate deviates from its
+// baseline
+func baselineAnomaly(sb ServiceBaseline, threshold float64) ErrorAnomaly {
+	baseline, season := sb.scoredBaseline()
+	stdDev := rateFloors.scoringStdDev(baseline.Mean, baseline.StdDev)
+
+	severity := "warning"
+	if *sb.Score >= 2*threshold {
+		severity = "critical"
+	}
+	message := fmt.Sprintf("Error rate of %s is %.1f%% of logs, %.1f std devs above normal for %s",
+		sb.Service, sb.ErrorRate*100, *sb.Score, season)
+	if baseline.Ratio != nil {
+		message = fmt.Sprintf("Error rate of %s is %.1fx normal for %s (%.1f%% of logs)",
+			sb.Service, *baseline.Ratio, season, sb.ErrorRate*100)
+	}
+	return ErrorAnomaly{
+		Type:        "error_rate_baseline",
+		Category:    "service",
+		Service:     sb.Service,
+		CurrentRate: sb.ErrorRate,
+		Threshold:   baseline.Mean + threshold*stdDev,
+		Severity:    severity,
+		Message:     message,
+	}
+}
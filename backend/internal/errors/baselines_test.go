@@ -0,0 +1,115 @@
+package errors
+
+import (
+	"testing"
+	"time"
+)
+
+// recordHour records logs of service at an hour, errors of them failing
+func recordHour(bt *baselineTracker, service string, at time.Time, logs, errors int) {
+	for i := 0; i < logs; i++ {
+		bt.record(service, i < errors, at)
+	}
+}
+
+func TestServiceBaselineScoresAgainstSameHourOfWeek(t *testing.T) {
+	// A Tuesday, 14:00 UTC
+	now := time.Date(2026, 10, 13, 14, 30, 0, 0, time.UTC)
+	bt := newBaselineTracker()
+	recordHour(bt, "api", now.Add(-14*24*time.Hour), 100, 2)
+	recordHour(bt, "api", now.Add(-7*24*time.Hour), 100, 2)
+	recordHour(bt, "api", now, 100, 6)
+
+	sb := bt.services["api"].snapshot("api", now, 2)
+	if sb.Weekly == nil || sb.Weekly.Samples != 2 {
+		t.Fatalf("weekly baseline = %+v, want 2 samples", sb.Weekly)
+	}
+	if sb.Weekly.Ratio == nil || *sb.Weekly.Ratio < 2.99 || *sb.Weekly.Ratio > 3.01 {
+		t.Fatalf("weekly ratio = %v, want 3", sb.Weekly.Ratio)
+	}
+	// The rates never moved, so the score is against the floor of a quarter
+	// of the mean: (0.06 - 0.02) / 0.005
+	if sb.Score == nil || *sb.Score < 7.99 || *sb.Score > 8.01 {
+		t.Fatalf("score = %v, want 8", sb.Score)
+	}
+	if !sb.Deviating {
+		t.Fatal("want the service deviating")
+	}
+
+	anomaly := baselineAnomaly(sb, 2)
+	if want := 0.02 + 2*0.005; anomaly.Threshold < want-1e-9 || anomaly.Threshold > want+1e-9 {
+		t.Fatalf("anomaly threshold = %v, want %v", anomaly.Threshold, want)
+	}
+}
+
+func TestServiceBaselineNeedsEnoughHours(t *testing.T) {
+	now := time.Date(2026, 10, 13, 14, 30, 0, 0, time.UTC)
+	bt := newBaselineTracker()
+	recordHour(bt, "api", now.Add(-7*24*time.Hour), 100, 2)
+	// Too few logs for the rate of the hour to count
+	recordHour(bt, "api", now.Add(-48*time.Hour), minBaselineLogs-1, minBaselineLogs-1)
+	recordHour(bt, "api", now.Add(-24*time.Hour), 100, 2)
+	recordHour(bt, "api", now, 100, 50)
+
+	sb := bt.services["api"].snapshot("api", now, 2)
+	if sb.Weekly == nil || sb.Weekly.Samples != 1 {
+		t.Fatalf("weekly baseline = %+v, want 1 sample", sb.Weekly)
+	}
+	if sb.Daily == nil || sb.Daily.Samples != 2 {
+		t.Fatalf("daily baseline = %+v, want 2 samples", sb.Daily)
+	}
+	if sb.Score != nil || sb.Deviating {
+		t.Fatalf("score = %v, want none with 1 week and 2 days", sb.Score)
+	}
+	if sb.DayOverDay == nil || *sb.DayOverDay != 25 {
+		t.Fatalf("day over day = %v, want 25", sb.DayOverDay)
+	}
+}
+
+func TestServiceBaselineClosesQuietHours(t *testing.T) {
+	now := time.Date(2026, 10, 13, 14, 30, 0, 0, time.UTC)
+	bt := newBaselineTracker()
+	recordHour(bt, "api", now.Add(-24*time.Hour), 100, 1)
+
+	// The service logged nothing since, yet its last hour ended and counts
+	// towards the baseline of the same hour today
+	s := bt.services["api"]
+	sb := s.snapshot("api", now, 2)
+	if sb.Daily == nil || sb.Daily.Samples != 1 || sb.Daily.Mean != 0.01 {
+		t.Fatalf("daily baseline = %+v, want 1 sample of 0.01", sb.Daily)
+	}
+	// Taking snapshots is not logging: the hour of the latest log, which
+	// the service is expired by, stays
+	if want := now.Add(-24*time.Hour).Unix() / 3600; s.lastHour != want {
+		t.Fatalf("last hour = %d, want %d", s.lastHour, want)
+	}
+	// nor does the hour count twice
+	s.snapshot("api", now.Add(time.Hour), 2)
+	if _, _, samples := s.daily.Baseline(now.Add(-24 * time.Hour)); samples != 1 {
+		t.Fatalf("daily samples = %d, want 1", samples)
+	}
+}
+
+func TestAnomalyDetectorFloors(t *testing.T) {
+	plain := NewAnomalyDetector(10)
+	floored := NewAnomalyDetectorWithOptions(10, AnomalyOptions{MinSamples: 3, MinRelativeStdDev: 0.5})
+	for i := 0; i < 3; i++ {
+		plain.AddDataPoint(4)
+		floored.AddDataPoint(4)
+	}
+
+	if _, ok := plain.ZScore(8); ok {
+		t.Fatal("want no score before the minimum samples")
+	}
+	score, ok := floored.ZScore(8)
+	if !ok || score != 2 {
+		t.Fatalf("score = %v, %v, want 2 against a floor of half the mean", score, ok)
+	}
+
+	for i := 0; i < minAnomalySamples; i++ {
+		plain.AddDataPoint(4)
+	}
+	if _, ok := plain.ZScore(8); ok {
+		t.Fatal("want no score of a series that never moved without floors")
+	}
+}
@@ -0,0 +1,108 @@
+package errors
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SetStatsStorage checkpoints the error statistics and the anomaly history
+// to storage, restoring the latest checkpoint so that first-seen dates and
+// trends carry over restarts
+func (ed *ErrorDetector) SetStatsStorage(storage StatsStorage) error {
+	stored, history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	ed.statsStorage = storage
+	now := time.Now()
+	for _, st := range stored {
+		if st.Key == "" || now.Sub(st.LastSeen) > statsRetention {
+			continue
+		}
+		existing, ok := ed.errorStats[st.Key]
+		if !ok {
+			if st.Services == nil {
+				st.Services = make(map[string]int64)
+			}
+			if len(st.Samples) > maxErrorSamples {
+				st.Samples = st.Samples[len(st.Samples)-maxErrorSamples:]
+			}
+			if minutes := now.Sub(st.FirstSeen).Minutes(); minutes > 0 {
+				st.Rate = float64(st.Count) / minutes
+			}
+			ed.errorStats[st.Key] = st
+			continue
+		}
+
+		// Logs were counted before the checkpoint was loaded
+		existing.Count += st.Count
+		if st.FirstSeen.Before(existing.FirstSeen) {
+			existing.FirstSeen = st.FirstSeen
+		}
+		for service, count := range st.Services {
+			existing.Services[service] += count
+		}
+		if minutes := now.Sub(existing.FirstSeen).Minutes(); minutes > 0 {
+			existing.Rate = float64(existing.Count) / minutes
+		}
+	}
+	if len(history) > 0 {
+		ed.anomalyDetector.restore(history)
+	}
+	return nil
+}
+
+// Checkpoint writes the statistics changed since the last checkpoint and
+// the anomaly history to storage
+func (ed *ErrorDetector) Checkpoint() error {
+	ed.mu.Lock()
+	if ed.statsStorage == nil || len(ed.dirtyStats) == 0 {
+		ed.mu.Unlock()
+		return nil
+	}
+	changed := make([]*ErrorStats, 0, len(ed.dirtyStats))
+	for key := range ed.dirtyStats {
+		if stats, ok := ed.errorStats[key]; ok {
+			changed = append(changed, stats.copy())
+		}
+	}
+	ed.dirtyStats = make(map[string]bool)
+	storage := ed.statsStorage
+	ed.mu.Unlock()
+
+	if err := storage.Save(changed, ed.anomalyDetector.snapshot()); err != nil {
+		// Mark them dirty again so the next checkpoint retries
+		ed.mu.Lock()
+		for _, stats := range changed {
+			ed.dirtyStats[stats.Key] = true
+		}
+		ed.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Start checkpoints the statistics periodically until the context is
+// cancelled, and a last time then
+func (ed *ErrorDetector) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := ed.Checkpoint(); err != nil {
+				log.Error().Err(err).Msg("Failed to checkpoint error stats")
+			}
+		case <-ctx.Done():
+			if err := ed.Checkpoint(); err != nil {
+				log.Error().Err(err).Msg("Failed to checkpoint error stats")
+			}
+			return
+		}
+	}
+}
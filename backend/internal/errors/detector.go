@@ -2,9 +2,12 @@ package errors
 
 import (
 	"fmt"
+	"math"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
@@ -13,8 +16,15 @@ import (
 // ErrorDetector detects and analyzes error patterns in logs
 type ErrorDetector struct {
 	mu               sync.RWMutex
-	patterns         []ErrorPattern
+	patterns         []*ErrorPattern // replaced, never modified, so that they can be matched unlocked
+	patternStorage   PatternStorage
 	errorStats       map[string]*ErrorStats
+	dirtyStats       map[string]bool // keys changed since the last checkpoint
+	statsStorage     StatsStorage
+	groups           map[string]*errorGroup // by fingerprint
+	groupStates      map[string]*GroupState // by fingerprint, outliving the groups
+	stateStorage     GroupStateStorage
+	baselines        *baselineTracker
 	anomalyDetector  *AnomalyDetector
 	windowSize       time.Duration
 	alertThresholds  AlertThresholds
@@ -22,15 +32,24 @@ type ErrorDetector struct {
 
 // ErrorPattern defines patterns for detecting errors
 type ErrorPattern struct {
-	Name        string
-	Pattern     *regexp.Regexp
-	Severity    string
-	Category    string
-	Description string
+	Name        string         `json:"name"`
+	Regex       string         `json:"regex"`
+	Pattern     *regexp.Regexp `json:"-"`
+	Severity    string         `json:"severity"` // low, medium, high or critical
+	Category    string         `json:"category"`
+	Description string         `json:"description,omitempty"`
+	Enabled     bool           `json:"enabled"`
+	Matches     uint64         `json:"matches"` // logs matched since the server started
+	CreatedBy   string         `json:"created_by,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+
+	matches *atomic.Uint64
 }
 
 // ErrorStats tracks error statistics
 type ErrorStats struct {
+	Key          string                 `json:"key"` // category:pattern, identifying the stats
 	Pattern      string                 `json:"pattern"`
 	Category     string                 `json:"category"`
 	Count        int64                  `json:"count"`
@@ -42,6 +61,14 @@ type ErrorStats struct {
 	Trend        string                 `json:"trend"` // increasing, decreasing, stable
 }
 
+// maxErrorSamples is how many of the latest logs of a pattern are kept as
+// samples
+const maxErrorSamples = 10
+
+// statsRetention is how long statistics of a pattern no longer seen are
+// kept
+const statsRetention = 24 * time.Hour
+
 // ErrorSample represents a sample error log
 type ErrorSample struct {
 	LogID     string    `json:"log_id"`
@@ -65,108 +92,25 @@ type AnomalyDetector struct {
 	mean           float64
 	stdDev         float64
 	windowSize     int
+	options        AnomalyOptions
 	lastUpdate     time.Time
 }
 
 // NewErrorDetector creates a new error detector
 func NewErrorDetector() *ErrorDetector {
 	ed := &ErrorDetector{
-		errorStats: make(map[string]*ErrorStats),
-		windowSize: 5 * time.Minute,
+		errorStats:  make(map[string]*ErrorStats),
+		dirtyStats:  make(map[string]bool),
+		groups:      make(map[string]*errorGroup),
+		groupStates: make(map[string]*GroupState),
+		baselines:   newBaselineTracker(),
+		windowSize:  5 * time.Minute,
 		alertThresholds: AlertThresholds{
 			ErrorRatePerMinute: 10.0,
 			ErrorBurstSize:     50,
 			AnomalyStdDev:      2.0,
 		},
-		patterns: []ErrorPattern{
-			// Application errors
-			{
-				Name:     "Exception",
-				Pattern:  regexp.MustCompile(`(?i)(exception|error):\s*(.+)`),
-				Severity: "high",
-				Category: "application",
-			},
-			{
-				Name:     "StackTrace",
-				Pattern:  regexp.MustCompile(`(?i)^\s*at\s+[\w.$]+\(.*\)|\s+at\s+.+:\d+`),
-				Severity: "high",
-				Category: "application",
-			},
-			{
-				Name:     "NullPointer",
-				Pattern:  regexp.MustCompile(`(?i)(null\s*pointer|null\s*reference|nil\s*pointer)`),
-				Severity: "high",
-				Category: "application",
-			},
-			{
-				Name:     "OutOfMemory",
-				Pattern:  regexp.MustCompile(`(?i)(out\s*of\s*memory|oom|memory\s*exhausted)`),
-				Severity: "critical",
-				Category: "resource",
-			},
-			
-			// HTTP errors
-			{
-				Name:     "HTTP4xx",
-				Pattern:  regexp.MustCompile(`\b4\d{2}\b|(?i)(bad\s*request|unauthorized|forbidden|not\s*found)`),
-				Severity: "medium",
-				Category: "http",
-			},
-			{
-				Name:     "HTTP5xx",
-				Pattern:  regexp.MustCompile(`\b5\d{2}\b|(?i)(internal\s*server|gateway|service\s*unavailable)`),
-				Severity: "high",
-				Category: "http",
-			},
-			
-			// Database errors
-			{
-				Name:     "DatabaseConnection",
-				Pattern:  regexp.MustCompile(`(?i)(connection\s*(refused|failed|timeout)|can't\s*connect|lost\s*connection)`),
-				Severity: "high",
-				Category: "database",
-			},
-			{
-				Name:     "QueryError",
-				Pattern:  regexp.MustCompile(`(?i)(sql\s*error|query\s*failed|syntax\s*error|deadlock)`),
-				Severity: "medium",
-				Category: "database",
-			},
-			
-			// System errors
-			{
-				Name:     "DiskSpace",
-				Pattern:  regexp.MustCompile(`(?i)(disk\s*(full|space)|no\s*space\s*left)`),
-				Severity: "critical",
-				Category: "system",
-			},
-			{
-				Name:     "Permission",
-				Pattern:  regexp.MustCompile(`(?i)(permission\s*denied|access\s*denied|unauthorized\s*access)`),
-				Severity: "medium",
-				Category: "security",
-			},
-			{
-				Name:     "Timeout",
-				Pattern:  regexp.MustCompile(`(?i)(timeout|timed?\s*out|deadline\s*exceeded)`),
-				Severity: "medium",
-				Category: "network",
-			},
-			
-			// Generic patterns
-			{
-				Name:     "Failed",
-				Pattern:  regexp.MustCompile(`(?i)\bfailed?\b|\bfailure\b`),
-				Severity: "medium",
-				Category: "generic",
-			},
-			{
-				Name:     "Critical",
-				Pattern:  regexp.MustCompile(`(?i)\bcritical\b|\bfatal\b|\bpanic\b`),
-				Severity: "critical",
-				Category: "generic",
-			},
-		},
+		patterns: mustCompilePatterns(defaultPatterns()),
 	}
 
 	ed.anomalyDetector = NewAnomalyDetector(100) // 100 data points window
@@ -177,8 +121,10 @@ func NewErrorDetector() *ErrorDetector {
 	return ed
 }
 
-// ProcessLog analyzes a log entry for errors
-func (ed *ErrorDetector) ProcessLog(log *models.Log) []string {
+// ProcessLog analyzes a log entry for errors. It returns what flagged the
+// log as an error and, when something did, the fingerprint of the error
+// group its message joined.
+func (ed *ErrorDetector) ProcessLog(log *models.Log) ([]string, string) {
 	detectedErrors := []string{}
 
 	// Check log level first
@@ -188,8 +134,15 @@ func (ed *ErrorDetector) ProcessLog(log *models.Log) []string {
 	}
 
 	// Check message against patterns
-	for _, pattern := range ed.patterns {
+	ed.mu.RLock()
+	patterns := ed.patterns
+	ed.mu.RUnlock()
+	for _, pattern := range patterns {
+		if !pattern.Enabled {
+			continue
+		}
 		if pattern.Pattern.MatchString(log.Message) {
+			pattern.matches.Add(1)
 			ed.recordError(pattern.Name, pattern.Category, pattern.Category, log)
 			detectedErrors = append(detectedErrors, fmt.Sprintf("%s:%s", pattern.Category, pattern.Name))
 		}
@@ -214,7 +167,15 @@ func (ed *ErrorDetector) ProcessLog(log *models.Log) []string {
 		}
 	}
 
-	return detectedErrors
+	ed.baselines.record(log.Service, len(detectedErrors) > 0, time.Now())
+	if len(detectedErrors) == 0 {
+		return detectedErrors, ""
+	}
+	fingerprint, template := Fingerprint(log.Message)
+	ed.mu.Lock()
+	ed.recordGroup(fingerprint, template, detectedErrors, log, time.Now())
+	ed.mu.Unlock()
+	return detectedErrors, fingerprint
 }
 
 // isErrorLevel checks if log level indicates an error
@@ -238,30 +199,34 @@ func (ed *ErrorDetector) recordError(pattern, category, errorType string, log *m
 	stats, exists := ed.errorStats[key]
 	if !exists {
 		stats = &ErrorStats{
+			Key:       key,
 			Pattern:   pattern,
 			Category:  category,
 			FirstSeen: log.Timestamp,
 			Services:  make(map[string]int64),
-			Samples:   make([]ErrorSample, 0, 10),
+			Samples:   make([]ErrorSample, 0, maxErrorSamples),
 		}
 		ed.errorStats[key] = stats
 	}
 
 	// Update stats
+	ed.dirtyStats[key] = true
 	stats.Count++
 	stats.LastSeen = log.Timestamp
 	stats.Services[log.Service]++
 
-	// Keep up to 10 recent samples
-	if len(stats.Samples) < 10 {
-		stats.Samples = append(stats.Samples, ErrorSample{
-			LogID:     log.ID,
-			Timestamp: log.Timestamp,
-			Service:   log.Service,
-			Message:   log.Message,
-			TraceID:   log.TraceID,
-		})
+	// Keep the latest samples, oldest first
+	if len(stats.Samples) == maxErrorSamples {
+		copy(stats.Samples, stats.Samples[1:])
+		stats.Samples = stats.Samples[:maxErrorSamples-1]
 	}
+	stats.Samples = append(stats.Samples, ErrorSample{
+		LogID:     log.ID,
+		Timestamp: log.Timestamp,
+		Service:   log.Service,
+		Message:   log.Message,
+		TraceID:   log.TraceID,
+	})
 
 	// Update rate (errors per minute)
 	duration := time.Since(stats.FirstSeen).Minutes()
@@ -273,17 +238,51 @@ func (ed *ErrorDetector) recordError(pattern, category, errorType string, log *m
 	ed.anomalyDetector.AddDataPoint(stats.Rate)
 }
 
-// GetErrorStats returns current error statistics
+// GetErrorStats returns a copy of the current error statistics by key
 func (ed *ErrorDetector) GetErrorStats() map[string]*ErrorStats {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+
+	result := make(map[string]*ErrorStats, len(ed.errorStats))
+	for key, stats := range ed.errorStats {
+		stats.Trend = ed.calculateTrend(stats)
+		result[key] = stats.copy()
+	}
+	return result
+}
+
+// GetSamples returns the latest logs matching pattern, oldest first.
+// pattern is either the key of the stats, category:pattern, or a pattern
+// name, which gathers the samples of every category it is seen in.
+func (ed *ErrorDetector) GetSamples(pattern string) ([]ErrorSample, bool) {
 	ed.mu.RLock()
 	defer ed.mu.RUnlock()
 
-	// Calculate trends
+	if stats, ok := ed.errorStats[pattern]; ok {
+		return append([]ErrorSample(nil), stats.Samples...), true
+	}
+	var samples []ErrorSample
+	found := false
 	for _, stats := range ed.errorStats {
-		stats.Trend = ed.calculateTrend(stats)
+		if stats.Pattern == pattern {
+			samples = append(samples, stats.Samples...)
+			found = true
+		}
 	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Timestamp.Before(samples[j].Timestamp) })
+	return samples, found
+}
 
-	return ed.errorStats
+// copy returns a copy of the stats that does not share its services or
+// samples
+func (s *ErrorStats) copy() *ErrorStats {
+	c := *s
+	c.Services = make(map[string]int64, len(s.Services))
+	for service, count := range s.Services {
+		c.Services[service] = count
+	}
+	c.Samples = append([]ErrorSample(nil), s.Samples...)
+	return &c
 }
 
 // calculateTrend calculates error trend
@@ -308,7 +307,6 @@ func (ed *ErrorDetector) calculateTrend(stats *ErrorStats) string {
 // GetAnomalies detects anomalies in error rates
 func (ed *ErrorDetector) GetAnomalies() []ErrorAnomaly {
 	ed.mu.RLock()
-	defer ed.mu.RUnlock()
 
 	anomalies := []ErrorAnomaly{}
 	
@@ -339,6 +337,15 @@ func (ed *ErrorDetector) GetAnomalies() []ErrorAnomaly {
 			})
 		}
 	}
+	ed.mu.RUnlock()
+
+	// Services whose share of error logs is unusual for the time of day
+	// or week, however high or low their usual share
+	for _, baseline := range ed.GetServiceBaselines() {
+		if baseline.Deviating {
+			anomalies = append(anomalies, baselineAnomaly(baseline, ed.alertThresholds.AnomalyStdDev))
+		}
+	}
 
 	return anomalies
 }
@@ -348,6 +355,7 @@ type ErrorAnomaly struct {
 	Type        string  `json:"type"`
 	Pattern     string  `json:"pattern"`
 	Category    string  `json:"category"`
+	Service     string  `json:"service,omitempty"`
 	CurrentRate float64 `json:"current_rate"`
 	Threshold   float64 `json:"threshold"`
 	Severity    string  `json:"severity"`
@@ -363,19 +371,64 @@ func (ed *ErrorDetector) cleanupOldStats() {
 		ed.mu.Lock()
 		now := time.Now()
 		for key, stats := range ed.errorStats {
-			if now.Sub(stats.LastSeen) > 24*time.Hour {
+			if now.Sub(stats.LastSeen) > statsRetention {
 				delete(ed.errorStats, key)
+				delete(ed.dirtyStats, key)
+			}
+		}
+		for fingerprint, group := range ed.groups {
+			if now.Sub(group.received) > 24*time.Hour {
+				delete(ed.groups, fingerprint)
 			}
 		}
 		ed.mu.Unlock()
+		ed.saveBaselines()
+	}
+}
+
+// minAnomalySamples is how many data points a detector needs before it
+// flags anything
+const minAnomalySamples = 10
+
+// AnomalyOptions tune when a detector scores values
+type AnomalyOptions struct {
+	// MinSamples is how many data points are needed before values are
+	// scored, minAnomalySamples when 0
+	MinSamples int
+	// MinStdDev and MinRelativeStdDev floor the standard deviation values
+	// are scored against, absolutely and as a share of the mean, so that a
+	// series that barely moves does not make small changes look like large
+	// deviations. Without them such a series scores nothing.
+	MinStdDev         float64
+	MinRelativeStdDev float64
+}
+
+// minSamples returns how many data points are needed before scoring
+func (o AnomalyOptions) minSamples() int {
+	if o.MinSamples <= 0 {
+		return minAnomalySamples
 	}
+	return o.MinSamples
+}
+
+// scoringStdDev returns the standard deviation values are scored against,
+// stdDev raised to the floors
+func (o AnomalyOptions) scoringStdDev(mean, stdDev float64) float64 {
+	return math.Max(stdDev, math.Max(math.Abs(mean)*o.MinRelativeStdDev, o.MinStdDev))
 }
 
 // NewAnomalyDetector creates a new anomaly detector
 func NewAnomalyDetector(windowSize int) *AnomalyDetector {
+	return NewAnomalyDetectorWithOptions(windowSize, AnomalyOptions{})
+}
+
+// NewAnomalyDetectorWithOptions creates an anomaly detector scoring values
+// as the options tell
+func NewAnomalyDetectorWithOptions(windowSize int, options AnomalyOptions) *AnomalyDetector {
 	return &AnomalyDetector{
 		history:    make([]float64, 0, windowSize),
 		windowSize: windowSize,
+		options:    options,
 	}
 }
 
@@ -393,6 +446,27 @@ func (ad *AnomalyDetector) AddDataPoint(value float64) {
 	ad.lastUpdate = time.Now()
 }
 
+// snapshot returns a copy of the data points of the window, oldest first
+func (ad *AnomalyDetector) snapshot() []float64 {
+	ad.mu.RLock()
+	defer ad.mu.RUnlock()
+	return append([]float64(nil), ad.history...)
+}
+
+// restore replaces the data points of the window with history, oldest
+// first, keeping the latest ones that fit
+func (ad *AnomalyDetector) restore(history []float64) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	if len(history) > ad.windowSize {
+		history = history[len(history)-ad.windowSize:]
+	}
+	ad.history = append(make([]float64, 0, ad.windowSize), history...)
+	ad.updateStats()
+	ad.lastUpdate = time.Now()
+}
+
 // updateStats recalculates mean and standard deviation
 func (ad *AnomalyDetector) updateStats() {
 	if len(ad.history) == 0 {
@@ -413,22 +487,36 @@ func (ad *AnomalyDetector) updateStats() {
 	}
 	ad.stdDev = 0.0
 	if len(ad.history) > 1 {
-		ad.stdDev = variance / float64(len(ad.history)-1)
-		if ad.stdDev > 0 {
-			ad.stdDev = ad.stdDev * ad.stdDev // sqrt
-		}
+		ad.stdDev = math.Sqrt(variance / float64(len(ad.history)-1))
 	}
 }
 
 // IsAnomaly checks if a value is anomalous
 func (ad *AnomalyDetector) IsAnomaly(value float64, stdDevThreshold float64) bool {
+	deviation, ok := ad.ZScore(value)
+	if !ok {
+		return false // Not enough data
+	}
+	return deviation > stdDevThreshold || deviation < -stdDevThreshold
+}
+
+// ZScore returns how many standard deviations a value is from the mean of
+// the window, and false while there is not enough data to tell
+func (ad *AnomalyDetector) ZScore(value float64) (float64, bool) {
 	ad.mu.RLock()
 	defer ad.mu.RUnlock()
 
-	if len(ad.history) < 10 || ad.stdDev == 0 {
-		return false // Not enough data
+	stdDev := ad.options.scoringStdDev(ad.mean, ad.stdDev)
+	if len(ad.history) < ad.options.minSamples() || stdDev == 0 {
+		return 0, false
 	}
+	return (value - ad.mean) / stdDev, true
+}
 
-	deviation := (value - ad.mean) / ad.stdDev
-	return deviation > stdDevThreshold || deviation < -stdDevThreshold
+// Baseline returns the mean and standard deviation of the window and the
+// number of data points in it
+func (ad *AnomalyDetector) Baseline() (mean, stdDev float64, samples int) {
+	ad.mu.RLock()
+	defer ad.mu.RUnlock()
+	return ad.mean, ad.stdDev, len(ad.history)
 }
\ No newline at end of file
@@ -0,0 +1,16 @@
+package errors
+
+import (
+	"github.com/your-username/click-lite-log-analytics/backend/internal/parsing"
+)
+
+// Fingerprint reduces an error message to its template with the template
+// parsing rule's normalization, so that occurrences of an error differing
+// only in IDs, numbers or quoted values share a template, and a stack
+// trace is grouped by the line naming its exception, the last one of a
+// Python traceback. It returns the hash of the template,
+// the same one the rule stores, and the template itself.
+func Fingerprint(message string) (string, string) {
+	template := parsing.MessageTemplate(message)
+	return parsing.TemplateHash(template), template
+}
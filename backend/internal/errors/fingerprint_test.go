@@ -0,0 +1,58 @@
+package errors
+
+import (
+	"testing"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/parsing"
+)
+
+func TestFingerprintMatchesMessageTemplate(t *testing.T) {
+	message := "failed to charge order 4821 for user 550e8400-e29b-41d4-a716-446655440000\n\tat billing.charge(Billing.java:42)"
+	fingerprint, template := Fingerprint(message)
+
+	want := parsing.MessageTemplate(message)
+	if template != want {
+		t.Errorf("template = %q, want %q", template, want)
+	}
+	if fingerprint != parsing.TemplateHash(want) {
+		t.Errorf("fingerprint = %q, want the template hash %q", fingerprint, parsing.TemplateHash(want))
+	}
+	if template != "failed to charge order <n> for user <uuid>" {
+		t.Errorf("template = %q, want the first line with placeholders", template)
+	}
+
+	other, _ := Fingerprint("failed to charge order 17 for user 123e4567-e89b-12d3-a456-426614174000\n\tat billing.retry(Billing.java:90)")
+	if other != fingerprint {
+		t.Errorf("occurrences differing in IDs and frames have fingerprints %q and %q", fingerprint, other)
+	}
+}
+
+func TestFingerprintSeparatesPythonExceptions(t *testing.T) {
+	keyError := "Traceback (most recent call last):\n" +
+		"  File \"/app/handlers.py\", line 42, in handle\n" +
+		"    user = users[request.user_id]\n" +
+		"KeyError: 'user_id'"
+	zeroDivision := "Traceback (most recent call last):\n" +
+		"  File \"/app/report.py\", line 7, in ratio\n" +
+		"    return done / total\n" +
+		"ZeroDivisionError: division by zero"
+
+	first, firstTemplate := Fingerprint(keyError)
+	second, secondTemplate := Fingerprint(zeroDivision)
+	if first == second {
+		t.Fatalf("different Python exceptions share fingerprint %q (templates %q and %q)", first, firstTemplate, secondTemplate)
+	}
+	if firstTemplate != "KeyError: '<str>'" {
+		t.Errorf("template = %q, want the exception line", firstTemplate)
+	}
+
+	// Another occurrence of the same exception from another call path groups
+	// with the first
+	again, _ := Fingerprint("Traceback (most recent call last):\n" +
+		"  File \"/app/admin.py\", line 90, in lookup\n" +
+		"    user = users[key]\n" +
+		"KeyError: 'account_id'")
+	if again != first {
+		t.Errorf("occurrences of a KeyError have fingerprints %q and %q", first, again)
+	}
+}
@@ -0,0 +1,270 @@
+package errors
+
+import (
+	"sort"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+const (
+	// maxErrorGroups bounds the groups tracked; the least recently seen
+	// group is dropped when full
+	maxErrorGroups = 10000
+	// maxGroupDetections bounds the detections remembered per group
+	maxGroupDetections = 20
+	// maxGroupTraces is how many of the latest traces an error occurred in
+	// a group remembers
+	maxGroupTraces = 10
+	// groupTrendHours is how many hours of occurrences a group keeps to
+	// compute its trend
+	groupTrendHours = 24
+)
+
+// ErrorGroup gathers the occurrences of an error whose messages share a
+// template
+type ErrorGroup struct {
+	Fingerprint string           `json:"fingerprint"`
+	Template    string           `json:"template"`
+	Count       int64            `json:"count"`
+	FirstSeen   time.Time        `json:"first_seen"`
+	LastSeen    time.Time        `json:"last_seen"`
+	Services    map[string]int64 `json:"services"`
+	// Detections are what flagged the occurrences as errors, such as
+	// level:error or database:QueryError
+	Detections  []string `json:"detections"`
+	LastMessage string   `json:"last_message"`
+	LastLogID   string   `json:"last_log_id,omitempty"`
+	LastTraceID string   `json:"last_trace_id,omitempty"`
+	// TraceIDs are the latest distinct traces the error occurred in, oldest
+	// first
+	TraceIDs []string `json:"trace_ids,omitempty"`
+	// Hourly counts the occurrences received in each of the last 24 hours,
+	// oldest first, the current hour last
+	Hourly []int64 `json:"hourly"`
+	Trend  string  `json:"trend"` // new, increasing, decreasing or stable
+	GroupState
+}
+
+// errorGroup is a group with the occurrences it received per hour, in a
+// ring ending at the hour of the latest one
+type errorGroup struct {
+	ErrorGroup
+	hours     [groupTrendHours]int64
+	lastHour  int64 // unix hour of the latest occurrence
+	firstHour int64 // unix hour the group was first received
+	received  time.Time
+}
+
+// recordGroup adds an error log to the group of its message. Callers hold
+// the lock.
+func (ed *ErrorDetector) recordGroup(fingerprint, template string, detected []string, log *models.Log, now time.Time) {
+	group, exists := ed.groups[fingerprint]
+	if !exists {
+		if len(ed.groups) >= maxErrorGroups {
+			ed.evictOldestGroup()
+		}
+		group = &errorGroup{
+			ErrorGroup: ErrorGroup{
+				Fingerprint: fingerprint,
+				Template:    template,
+				FirstSeen:   log.Timestamp,
+				LastSeen:    log.Timestamp,
+				Services:    make(map[string]int64),
+			},
+			firstHour: now.Unix() / 3600,
+			lastHour:  now.Unix() / 3600,
+		}
+		ed.groups[fingerprint] = group
+	}
+
+	group.Count++
+	if log.Timestamp.Before(group.FirstSeen) {
+		group.FirstSeen = log.Timestamp
+	}
+	if log.Timestamp.After(group.LastSeen) {
+		group.LastSeen = log.Timestamp
+	}
+	group.Services[log.Service]++
+	for _, detection := range detected {
+		if len(group.Detections) < maxGroupDetections && !containsString(group.Detections, detection) {
+			group.Detections = append(group.Detections, detection)
+		}
+	}
+	group.LastMessage = log.Message
+	group.LastLogID = log.ID
+	group.LastTraceID = log.TraceID
+	if log.TraceID != "" {
+		group.addTrace(log.TraceID)
+	}
+
+	// Occurrences are counted by arrival, so that backfilled logs do not
+	// skew the trend
+	group.advance(now)
+	group.hours[group.lastHour%groupTrendHours]++
+	group.received = now
+	ed.reopenIfRegressed(fingerprint, log, now)
+}
+
+// addTrace remembers a trace the error occurred in, as the latest one
+func (g *errorGroup) addTrace(traceID string) {
+	for i, id := range g.TraceIDs {
+		if id == traceID {
+			g.TraceIDs = append(g.TraceIDs[:i], g.TraceIDs[i+1:]...)
+			break
+		}
+	}
+	if len(g.TraceIDs) == maxGroupTraces {
+		g.TraceIDs = g.TraceIDs[1:]
+	}
+	g.TraceIDs = append(g.TraceIDs, traceID)
+}
+
+// advance moves the ring to the hour of now, clearing the hours skipped
+func (g *errorGroup) advance(now time.Time) {
+	hour := now.Unix() / 3600
+	for h := g.lastHour + 1; h <= hour && h <= g.lastHour+groupTrendHours; h++ {
+		g.hours[h%groupTrendHours] = 0
+	}
+	if hour > g.lastHour {
+		g.lastHour = hour
+	}
+}
+
+// snapshot returns a copy of the group as of now, with its hourly counts
+// and trend
+func (g *errorGroup) snapshot(now time.Time) ErrorGroup {
+	hour := now.Unix() / 3600
+	c := g.ErrorGroup
+	c.Services = make(map[string]int64, len(g.Services))
+	for service, count := range g.Services {
+		c.Services[service] = count
+	}
+	c.Detections = append([]string(nil), g.Detections...)
+	c.TraceIDs = append([]string(nil), g.TraceIDs...)
+
+	c.Hourly = make([]int64, groupTrendHours)
+	for i := range c.Hourly {
+		h := hour - int64(groupTrendHours-1-i)
+		if h <= g.lastHour && h > g.lastHour-groupTrendHours {
+			c.Hourly[i] = g.hours[h%groupTrendHours]
+		}
+	}
+	c.Trend = groupTrend(c.Hourly, hour-g.firstHour, now)
+	return c
+}
+
+// groupTrend compares the rate of the current hour so far with the
+// average of the previous hours the group existed, age hours at most
+func groupTrend(hourly []int64, age int64, now time.Time) string {
+	if age == 0 {
+		return "new"
+	}
+	previous := hourly[:len(hourly)-1]
+	if age < int64(len(previous)) {
+		previous = previous[len(previous)-int(age):]
+	}
+	var total int64
+	for _, count := range previous {
+		total += count
+	}
+	baseline := float64(total) / float64(len(previous))
+
+	// The current hour has only begun: extrapolate it, but not from the
+	// first minutes alone
+	elapsed := now.Sub(now.Truncate(time.Hour)).Hours()
+	if elapsed < 5.0/60 {
+		elapsed = 5.0 / 60
+	}
+	current := float64(hourly[len(hourly)-1]) / elapsed
+
+	switch {
+	case current > baseline*1.5:
+		return "increasing"
+	case current < baseline*0.5:
+		return "decreasing"
+	default:
+		return "stable"
+	}
+}
+
+// GroupFilter selects error groups
+type GroupFilter struct {
+	Service  string // groups affecting the service
+	Status   string
+	Assignee string
+	Sort     string // count, the default, or last_seen for the most recent first
+	Limit    int    // unlimited when not positive
+}
+
+// GetErrorGroups returns the error groups matching filter, the most
+// frequent first unless sorted otherwise
+func (ed *ErrorDetector) GetErrorGroups(filter GroupFilter) []ErrorGroup {
+	now := time.Now()
+	ed.mu.RLock()
+	groups := make([]ErrorGroup, 0, len(ed.groups))
+	for _, group := range ed.groups {
+		if filter.Service != "" && group.Services[filter.Service] == 0 {
+			continue
+		}
+		snapshot := ed.groupSnapshot(group, now)
+		if filter.Status != "" && snapshot.Status != filter.Status {
+			continue
+		}
+		if filter.Assignee != "" && snapshot.Assignee != filter.Assignee {
+			continue
+		}
+		groups = append(groups, snapshot)
+	}
+	ed.mu.RUnlock()
+
+	sort.Slice(groups, func(i, j int) bool {
+		if filter.Sort == "last_seen" && !groups[i].LastSeen.Equal(groups[j].LastSeen) {
+			return groups[i].LastSeen.After(groups[j].LastSeen)
+		}
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Fingerprint < groups[j].Fingerprint
+	})
+	if filter.Limit > 0 && len(groups) > filter.Limit {
+		groups = groups[:filter.Limit]
+	}
+	return groups
+}
+
+// GetErrorGroup returns an error group by fingerprint
+func (ed *ErrorDetector) GetErrorGroup(fingerprint string) (*ErrorGroup, bool) {
+	ed.mu.RLock()
+	defer ed.mu.RUnlock()
+
+	group, ok := ed.groups[fingerprint]
+	if !ok {
+		return nil, false
+	}
+	snapshot := ed.groupSnapshot(group, time.Now())
+	return &snapshot, true
+}
+
+// evictOldestGroup drops the group received least recently. Callers hold
+// the lock.
+func (ed *ErrorDetector) evictOldestGroup() {
+	var oldest string
+	var oldestAt time.Time
+	for fingerprint, group := range ed.groups {
+		if oldest == "" || group.received.Before(oldestAt) {
+			oldest = fingerprint
+			oldestAt = group.received
+		}
+	}
+	delete(ed.groups, oldest)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
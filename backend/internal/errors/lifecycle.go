@@ -0,0 +1,249 @@
+package errors
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// States of an error group
+const (
+	GroupNew          = "new"
+	GroupAcknowledged = "acknowledged"
+	GroupResolved     = "resolved"
+	GroupMuted        = "muted" // still counted, never reopened
+)
+
+// versionAttributes are the log attributes read, in order, for the version
+// of the service that logged
+var versionAttributes = []string{"service.version", "service_version", "app_version", "release"}
+
+// GroupState is where an error group is in its triage. Groups nobody
+// triaged yet are new.
+type GroupState struct {
+	Status     string     `json:"status"`
+	Assignee   string     `json:"assignee,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy string     `json:"resolved_by,omitempty"`
+	// ResolvedInVersion is the version that fixes the error: occurrences
+	// logged by older versions, still running during a rollout, leave the
+	// group resolved
+	ResolvedInVersion string `json:"resolved_in_version,omitempty"`
+	// Regressions counts the times the group reopened after being resolved
+	Regressions int        `json:"regressions,omitempty"`
+	RegressedAt *time.Time `json:"regressed_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	UpdatedBy   string     `json:"updated_by,omitempty"`
+}
+
+// GroupUpdate changes the state of an error group; empty fields are kept
+type GroupUpdate struct {
+	Status            string  `json:"status,omitempty"`
+	Assignee          *string `json:"assignee,omitempty"` // "" to unassign
+	ResolvedInVersion string  `json:"resolved_in_version,omitempty"`
+}
+
+// SetGroupStateStorage persists the states of error groups, restoring
+// those it holds
+func (ed *ErrorDetector) SetGroupStateStorage(storage GroupStateStorage) error {
+	states, err := storage.Load()
+	if err != nil {
+		return err
+	}
+
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	ed.stateStorage = storage
+	for fingerprint, state := range states {
+		if state == nil || !validGroupStatus(state.Status) {
+			log.Warn().Str("fingerprint", fingerprint).Msg("Skipping invalid error group state")
+			continue
+		}
+		ed.groupStates[fingerprint] = state
+	}
+	return nil
+}
+
+// UpdateGroup changes the state of the error group with fingerprint on
+// behalf of user and returns the group
+func (ed *ErrorDetector) UpdateGroup(fingerprint string, update GroupUpdate, user string) (*ErrorGroup, error) {
+	if update.Status != "" && !validGroupStatus(update.Status) {
+		return nil, fmt.Errorf("invalid status %q: use new, acknowledged, resolved or muted", update.Status)
+	}
+	if update.ResolvedInVersion != "" && update.Status != GroupResolved {
+		return nil, fmt.Errorf("resolved_in_version requires the resolved status")
+	}
+
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	group, tracked := ed.groups[fingerprint]
+	previous, known := ed.groupStates[fingerprint]
+	if !tracked && !known {
+		return nil, fmt.Errorf("error group not found: %s", fingerprint)
+	}
+
+	state := GroupState{Status: GroupNew}
+	if known {
+		state = *previous
+	}
+	now := time.Now().UTC()
+	if update.Status != "" && update.Status != state.Status {
+		state.Status = update.Status
+		state.ResolvedAt = nil
+		state.ResolvedBy = ""
+		state.ResolvedInVersion = ""
+		if update.Status == GroupResolved {
+			state.ResolvedAt = &now
+			state.ResolvedBy = user
+		}
+	}
+	if update.Status == GroupResolved {
+		state.ResolvedInVersion = update.ResolvedInVersion
+	}
+	if update.Assignee != nil {
+		state.Assignee = *update.Assignee
+	}
+	state.UpdatedAt = &now
+	state.UpdatedBy = user
+
+	ed.groupStates[fingerprint] = &state
+	if err := ed.saveGroupStates(); err != nil {
+		if known {
+			ed.groupStates[fingerprint] = previous
+		} else {
+			delete(ed.groupStates, fingerprint)
+		}
+		return nil, err
+	}
+
+	if !tracked {
+		// Known from an earlier run, but not seen since the server started
+		return &ErrorGroup{Fingerprint: fingerprint, GroupState: state}, nil
+	}
+	snapshot := ed.groupSnapshot(group, time.Now())
+	return &snapshot, nil
+}
+
+// reopenIfRegressed reopens the resolved group with fingerprint when entry
+// is an occurrence of a version the fix is in, or of an unknown version.
+// Callers hold the lock.
+func (ed *ErrorDetector) reopenIfRegressed(fingerprint string, entry *models.Log, now time.Time) {
+	state, ok := ed.groupStates[fingerprint]
+	if !ok || state.Status != GroupResolved {
+		return
+	}
+	if state.ResolvedInVersion != "" {
+		if version := logVersion(entry); version != "" && compareVersions(version, state.ResolvedInVersion) < 0 {
+			return
+		}
+	}
+
+	previous := *state
+	at := now.UTC()
+	state.Status = GroupNew
+	state.ResolvedAt = nil
+	state.ResolvedBy = ""
+	state.ResolvedInVersion = ""
+	state.Regressions++
+	state.RegressedAt = &at
+	state.UpdatedAt = &at
+	state.UpdatedBy = ""
+
+	logEvent := log.Warn().
+		Str("fingerprint", fingerprint).
+		Str("service", entry.Service).
+		Int("regressions", state.Regressions)
+	if previous.ResolvedInVersion != "" {
+		logEvent = logEvent.Str("resolved_in_version", previous.ResolvedInVersion)
+	}
+	logEvent.Msg("Resolved error group regressed")
+
+	if err := ed.saveGroupStates(); err != nil {
+		log.Error().Err(err).Str("fingerprint", fingerprint).Msg("Failed to save regressed error group")
+	}
+}
+
+// groupSnapshot returns a copy of group with its state. Callers hold the
+// lock.
+func (ed *ErrorDetector) groupSnapshot(group *errorGroup, now time.Time) ErrorGroup {
+	snapshot := group.snapshot(now)
+	snapshot.GroupState = GroupState{Status: GroupNew}
+	if state, ok := ed.groupStates[group.Fingerprint]; ok {
+		snapshot.GroupState = *state
+	}
+	return snapshot
+}
+
+// saveGroupStates persists the states of the groups. Callers hold the
+// lock.
+func (ed *ErrorDetector) saveGroupStates() error {
+	if ed.stateStorage == nil {
+		return nil
+	}
+	states := make(map[string]*GroupState, len(ed.groupStates))
+	for fingerprint, state := range ed.groupStates {
+		copied := *state
+		states[fingerprint] = &copied
+	}
+	if err := ed.stateStorage.Save(states); err != nil {
+		return fmt.Errorf("failed to save error group states: %w", err)
+	}
+	return nil
+}
+
+func validGroupStatus(status string) bool {
+	switch status {
+	case GroupNew, GroupAcknowledged, GroupResolved, GroupMuted:
+		return true
+	}
+	return false
+}
+
+// logVersion returns the version of the service that logged entry, ""
+// when it does not say
+func logVersion(entry *models.Log) string {
+	for _, name := range versionAttributes {
+		if version, ok := entry.Attributes[name].(string); ok && version != "" {
+			return version
+		}
+	}
+	return ""
+}
+
+// compareVersions compares versions such as v1.4.2 or 2.0.0-rc.1 part by
+// part, numerically where both parts are numbers, and returns -1, 0 or 1
+func compareVersions(a, b string) int {
+	split := func(v string) []string {
+		v = strings.TrimPrefix(strings.TrimPrefix(v, "v"), "V")
+		return strings.FieldsFunc(v, func(r rune) bool { return r == '.' || r == '-' || r == '+' || r == '_' })
+	}
+	pa, pb := split(a), split(b)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				return compareInts(na, nb)
+			}
+		case pa[i] != pb[i]:
+			return strings.Compare(pa[i], pb[i])
+		}
+	}
+	return compareInts(len(pa), len(pb))
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,342 @@
+package errors
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxPatternLength bounds the regular expression of a pattern
+const maxPatternLength = 1000
+
+// DefaultPatternCategory is the category of the patterns added without one
+const DefaultPatternCategory = "custom"
+
+var (
+	patternNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,100}$`)
+	patternSeverities  = map[string]bool{"low": true, "medium": true, "high": true, "critical": true}
+)
+
+// defaultPatterns are the patterns the detector starts with until patterns
+// are managed through its storage
+func defaultPatterns() []ErrorPattern {
+	return []ErrorPattern{
+		// Application errors
+		{
+			Name:     "Exception",
+			Regex:    `(?i)(exception|error):\s*(.+)`,
+			Severity: "high",
+			Category: "application",
+			Enabled:  true,
+		},
+		{
+			Name:     "StackTrace",
+			Regex:    `(?i)^\s*at\s+[\w.$]+\(.*\)|\s+at\s+.+:\d+`,
+			Severity: "high",
+			Category: "application",
+			Enabled:  true,
+		},
+		{
+			Name:     "NullPointer",
+			Regex:    `(?i)(null\s*pointer|null\s*reference|nil\s*pointer)`,
+			Severity: "high",
+			Category: "application",
+			Enabled:  true,
+		},
+		{
+			Name:     "OutOfMemory",
+			Regex:    `(?i)(out\s*of\s*memory|oom|memory\s*exhausted)`,
+			Severity: "critical",
+			Category: "resource",
+			Enabled:  true,
+		},
+
+		// HTTP errors
+		{
+			Name:     "HTTP4xx",
+			Regex:    `\b4\d{2}\b|(?i)(bad\s*request|unauthorized|forbidden|not\s*found)`,
+			Severity: "medium",
+			Category: "http",
+			Enabled:  true,
+		},
+		{
+			Name:     "HTTP5xx",
+			Regex:    `\b5\d{2}\b|(?i)(internal\s*server|gateway|service\s*unavailable)`,
+			Severity: "high",
+			Category: "http",
+			Enabled:  true,
+		},
+
+		// Database errors
+		{
+			Name:     "DatabaseConnection",
+			Regex:    `(?i)(connection\s*(refused|failed|timeout)|can't\s*connect|lost\s*connection)`,
+			Severity: "high",
+			Category: "database",
+			Enabled:  true,
+		},
+		{
+			Name:     "QueryError",
+			Regex:    `(?i)(sql\s*error|query\s*failed|syntax\s*error|deadlock)`,
+			Severity: "medium",
+			Category: "database",
+			Enabled:  true,
+		},
+
+		// System errors
+		{
+			Name:     "DiskSpace",
+			Regex:    `(?i)(disk\s*(full|space)|no\s*space\s*left)`,
+			Severity: "critical",
+			Category: "system",
+			Enabled:  true,
+		},
+		{
+			Name:     "Permission",
+			Regex:    `(?i)(permission\s*denied|access\s*denied|unauthorized\s*access)`,
+			Severity: "medium",
+			Category: "security",
+			Enabled:  true,
+		},
+		{
+			Name:     "Timeout",
+			Regex:    `(?i)(timeout|timed?\s*out|deadline\s*exceeded)`,
+			Severity: "medium",
+			Category: "network",
+			Enabled:  true,
+		},
+
+		// Generic patterns
+		{
+			Name:     "Failed",
+			Regex:    `(?i)\bfailed?\b|\bfailure\b`,
+			Severity: "medium",
+			Category: "generic",
+			Enabled:  true,
+		},
+		{
+			Name:     "Critical",
+			Regex:    `(?i)\bcritical\b|\bfatal\b|\bpanic\b`,
+			Severity: "critical",
+			Category: "generic",
+			Enabled:  true,
+		},
+	}
+}
+
+// validate fills in defaults, checks the pattern and compiles its regular
+// expression
+func (p *ErrorPattern) validate() error {
+	if !patternNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("invalid pattern name %q: use up to 100 letters, digits, '_', '.' or '-'", p.Name)
+	}
+	if p.Regex == "" {
+		return fmt.Errorf("regex is required")
+	}
+	if len(p.Regex) > maxPatternLength {
+		return fmt.Errorf("regex cannot be longer than %d characters", maxPatternLength)
+	}
+	compiled, err := regexp.Compile(p.Regex)
+	if err != nil {
+		return fmt.Errorf("invalid regex: %w", err)
+	}
+	if p.Severity == "" {
+		p.Severity = "medium"
+	}
+	if !patternSeverities[p.Severity] {
+		return fmt.Errorf("invalid severity %q: use low, medium, high or critical", p.Severity)
+	}
+	if p.Category == "" {
+		p.Category = DefaultPatternCategory
+	}
+	if !patternNamePattern.MatchString(p.Category) {
+		return fmt.Errorf("invalid category %q: use up to 100 letters, digits, '_', '.' or '-'", p.Category)
+	}
+	p.Pattern = compiled
+	if p.matches == nil {
+		p.matches = new(atomic.Uint64)
+	}
+	return nil
+}
+
+// snapshot returns a copy of the pattern with its match count
+func (p *ErrorPattern) snapshot() ErrorPattern {
+	c := *p
+	c.Matches = p.matches.Load()
+	return c
+}
+
+// SetPatternStorage persists the patterns. The patterns it holds replace
+// the default ones; while it holds none, the defaults are kept.
+func (ed *ErrorDetector) SetPatternStorage(storage PatternStorage) error {
+	stored, err := storage.Load()
+	if err != nil {
+		return err
+	}
+
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	ed.patternStorage = storage
+	if stored == nil {
+		return nil
+	}
+	patterns := make([]*ErrorPattern, 0, len(stored))
+	for _, p := range stored {
+		if err := p.validate(); err != nil {
+			log.Warn().Err(err).Str("pattern", p.Name).Msg("Skipping invalid error pattern")
+			continue
+		}
+		patterns = append(patterns, p)
+	}
+	ed.patterns = patterns
+	return nil
+}
+
+// Patterns returns the patterns in the order they are matched, with how
+// often each matched since the server started
+func (ed *ErrorDetector) Patterns() []ErrorPattern {
+	ed.mu.RLock()
+	defer ed.mu.RUnlock()
+
+	patterns := make([]ErrorPattern, len(ed.patterns))
+	for i, p := range ed.patterns {
+		patterns[i] = p.snapshot()
+	}
+	return patterns
+}
+
+// GetPattern returns a pattern by name
+func (ed *ErrorDetector) GetPattern(name string) (*ErrorPattern, error) {
+	ed.mu.RLock()
+	defer ed.mu.RUnlock()
+
+	i := ed.patternIndex(name)
+	if i < 0 {
+		return nil, fmt.Errorf("error pattern not found: %s", name)
+	}
+	p := ed.patterns[i].snapshot()
+	return &p, nil
+}
+
+// AddPattern adds a pattern, matched after the existing ones
+func (ed *ErrorDetector) AddPattern(p *ErrorPattern) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	if ed.patternIndex(p.Name) >= 0 {
+		return fmt.Errorf("error pattern already exists: %s", p.Name)
+	}
+	now := time.Now()
+	p.CreatedAt = now
+	p.UpdatedAt = now
+
+	stored := *p
+	previous := ed.patterns
+	ed.patterns = append(append([]*ErrorPattern(nil), previous...), &stored)
+	if err := ed.savePatterns(); err != nil {
+		ed.patterns = previous
+		return err
+	}
+	return nil
+}
+
+// UpdatePattern replaces the definition of a pattern, keeping its place
+// and match count. Disabling a pattern keeps it without matching it.
+func (ed *ErrorDetector) UpdatePattern(name string, p *ErrorPattern) error {
+	p.Name = name
+	if err := p.validate(); err != nil {
+		return err
+	}
+
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	i := ed.patternIndex(name)
+	if i < 0 {
+		return fmt.Errorf("error pattern not found: %s", name)
+	}
+	existing := ed.patterns[i]
+	p.CreatedBy = existing.CreatedBy
+	p.CreatedAt = existing.CreatedAt
+	p.UpdatedAt = time.Now()
+	p.matches = existing.matches
+	p.Matches = existing.matches.Load()
+
+	stored := *p
+	previous := ed.patterns
+	ed.patterns = append([]*ErrorPattern(nil), previous...)
+	ed.patterns[i] = &stored
+	if err := ed.savePatterns(); err != nil {
+		ed.patterns = previous
+		return err
+	}
+	return nil
+}
+
+// DeletePattern removes a pattern. The stats of the errors it matched are
+// kept until they age out.
+func (ed *ErrorDetector) DeletePattern(name string) error {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+
+	i := ed.patternIndex(name)
+	if i < 0 {
+		return fmt.Errorf("error pattern not found: %s", name)
+	}
+	previous := ed.patterns
+	patterns := make([]*ErrorPattern, 0, len(previous)-1)
+	patterns = append(patterns, previous[:i]...)
+	ed.patterns = append(patterns, previous[i+1:]...)
+	if err := ed.savePatterns(); err != nil {
+		ed.patterns = previous
+		return err
+	}
+	return nil
+}
+
+// patternIndex returns the index of the pattern named name, -1 if there is
+// none. Callers hold the lock.
+func (ed *ErrorDetector) patternIndex(name string) int {
+	for i, p := range ed.patterns {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// savePatterns persists the patterns, without their match counts. Callers
+// hold the lock.
+func (ed *ErrorDetector) savePatterns() error {
+	if ed.patternStorage == nil {
+		return nil
+	}
+	patterns := make([]*ErrorPattern, len(ed.patterns))
+	for i, p := range ed.patterns {
+		c := *p
+		c.Matches = 0
+		patterns[i] = &c
+	}
+	if err := ed.patternStorage.Save(patterns); err != nil {
+		return fmt.Errorf("failed to save error patterns: %w", err)
+	}
+	return nil
+}
+
+// mustCompilePatterns compiles the default patterns
+func mustCompilePatterns(defs []ErrorPattern) []*ErrorPattern {
+	patterns := make([]*ErrorPattern, 0, len(defs))
+	for i := range defs {
+		p := defs[i]
+		if err := p.validate(); err != nil {
+			panic(err)
+		}
+		patterns = append(patterns, &p)
+	}
+	return patterns
+}
@@ -0,0 +1,77 @@
+package errors
+
+import (
+	"sync"
+	"time"
+)
+
+// SeasonalDetector detects anomalies against a seasonal baseline: each data
+// point is compared with the ones seen at the same time of the period, such
+// as the same hour of the day, rather than with the most recent ones
+type SeasonalDetector struct {
+	mu         sync.Mutex
+	period     time.Duration
+	bucket     time.Duration
+	windowSize int
+	options    AnomalyOptions
+	buckets    map[int64]*AnomalyDetector
+}
+
+// NewSeasonalDetector creates a detector splitting the period into buckets
+// of the given size, each keeping up to windowSize data points
+func NewSeasonalDetector(period, bucket time.Duration, windowSize int) *SeasonalDetector {
+	return NewSeasonalDetectorWithOptions(period, bucket, windowSize, AnomalyOptions{})
+}
+
+// NewSeasonalDetectorWithOptions creates a seasonal detector whose buckets
+// score values as the options tell
+func NewSeasonalDetectorWithOptions(period, bucket time.Duration, windowSize int, options AnomalyOptions) *SeasonalDetector {
+	if bucket <= 0 || bucket > period {
+		bucket = period
+	}
+	return &SeasonalDetector{
+		period:     period,
+		bucket:     bucket,
+		windowSize: windowSize,
+		options:    options,
+		buckets:    make(map[int64]*AnomalyDetector),
+	}
+}
+
+// AddDataPoint adds a data point to the bucket of its time
+func (sd *SeasonalDetector) AddDataPoint(at time.Time, value float64) {
+	sd.detector(at).AddDataPoint(value)
+}
+
+// ZScore returns how many standard deviations a value is from the mean of
+// the bucket of its time, and false while that bucket has too little data
+func (sd *SeasonalDetector) ZScore(at time.Time, value float64) (float64, bool) {
+	return sd.detector(at).ZScore(value)
+}
+
+// Baseline returns the mean and standard deviation of the bucket of a time
+// and the number of data points in it
+func (sd *SeasonalDetector) Baseline(at time.Time) (mean, stdDev float64, samples int) {
+	return sd.detector(at).Baseline()
+}
+
+// detector returns the detector of the bucket a time falls in. Buckets are
+// counted from the Unix epoch, which started on a Thursday, so weekly
+// periods are shifted to start on Monday.
+func (sd *SeasonalDetector) detector(at time.Time) *AnomalyDetector {
+	_, offset := at.Zone()
+	elapsed := time.Duration(at.Unix()+int64(offset)) * time.Second
+	if sd.period%(7*24*time.Hour) == 0 {
+		elapsed += 3 * 24 * time.Hour
+	}
+	index := int64((elapsed % sd.period) / sd.bucket)
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	detector, ok := sd.buckets[index]
+	if !ok {
+		detector = NewAnomalyDetectorWithOptions(sd.windowSize, sd.options)
+		sd.buckets[index] = detector
+	}
+	return detector
+}
@@ -0,0 +1,153 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// PatternStorage persists error patterns
+type PatternStorage interface {
+	Load() ([]*ErrorPattern, error)
+	Save(patterns []*ErrorPattern) error
+}
+
+// PatternFileStorage persists error patterns as a JSON document on disk
+type PatternFileStorage struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewPatternFileStorage creates a file-backed error pattern storage
+func NewPatternFileStorage(path string) *PatternFileStorage {
+	return &PatternFileStorage{path: path}
+}
+
+// Load reads the patterns from the file, nil if it does not exist
+func (s *PatternFileStorage) Load() ([]*ErrorPattern, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	patterns := []*ErrorPattern{}
+	found, err := readJSONFile(s.path, "error patterns", &patterns)
+	if err != nil || !found {
+		return nil, err
+	}
+	return patterns, nil
+}
+
+// Save replaces the patterns in the file
+func (s *PatternFileStorage) Save(patterns []*ErrorPattern) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeJSONFile(s.path, "error patterns", patterns)
+}
+
+// GroupStateStorage persists the states of error groups
+type GroupStateStorage interface {
+	Load() (map[string]*GroupState, error)
+	Save(states map[string]*GroupState) error
+}
+
+// GroupStateFileStorage persists the states of error groups as a JSON
+// document on disk, keyed by fingerprint
+type GroupStateFileStorage struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewGroupStateFileStorage creates a file-backed error group state storage
+func NewGroupStateFileStorage(path string) *GroupStateFileStorage {
+	return &GroupStateFileStorage{path: path}
+}
+
+// Load reads the states from the file, none if it does not exist
+func (s *GroupStateFileStorage) Load() (map[string]*GroupState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := make(map[string]*GroupState)
+	if _, err := readJSONFile(s.path, "error group states", &states); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// Save replaces the states in the file
+func (s *GroupStateFileStorage) Save(states map[string]*GroupState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeJSONFile(s.path, "error group states", states)
+}
+
+// BaselineFileStorage persists the hourly counts of the services as a JSON
+// document on disk, keyed by service
+type BaselineFileStorage struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewBaselineFileStorage creates a file-backed baseline storage
+func NewBaselineFileStorage(path string) *BaselineFileStorage {
+	return &BaselineFileStorage{path: path}
+}
+
+// Load reads the counts from the file, none if it does not exist
+func (s *BaselineFileStorage) Load() (map[string][]HourlyCounts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string][]HourlyCounts)
+	if _, err := readJSONFile(s.path, "error baselines", &counts); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// Save replaces the counts in the file
+func (s *BaselineFileStorage) Save(counts map[string][]HourlyCounts) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeJSONFile(s.path, "error baselines", counts)
+}
+
+// readJSONFile decodes the file at path into v. It reports false when the
+// file does not exist or is empty.
+func readJSONFile(path, kind string, v interface{}) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read %s file: %w", kind, err)
+	}
+	if len(data) == 0 {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to decode %s file: %w", kind, err)
+	}
+	return true, nil
+}
+
+// writeJSONFile replaces the file at path with v, through a temporary file
+// so that it is never left half written
+func writeJSONFile(path, kind string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", kind, err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s directory: %w", kind, err)
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s file: %w", kind, err)
+	}
+	return os.Rename(tmp, path)
+}
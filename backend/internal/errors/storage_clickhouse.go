@@ -0,0 +1,180 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+)
+
+// SQLExecutor is the subset of the database used to checkpoint the error
+// statistics
+type SQLExecutor interface {
+	Execute(ctx context.Context, query string) error
+	ExecuteSQL(sql string) ([]map[string]interface{}, error)
+}
+
+// StatsStorage checkpoints the error statistics and the history of the
+// anomaly detector
+type StatsStorage interface {
+	Save(stats []*ErrorStats, history []float64) error
+	Load() ([]*ErrorStats, []float64, error)
+}
+
+// clickHouseTimeFormat is how DateTime64(3) values are written and read
+const clickHouseTimeFormat = "2006-01-02 15:04:05.000"
+
+// ClickHouseStatsStorage keeps one row per error statistics key, replaced
+// on every checkpoint, and the anomaly history as a single row. Rows of
+// statistics not seen for a day expire, as they do in memory.
+type ClickHouseStatsStorage struct {
+	db           SQLExecutor
+	statsTable   string
+	historyTable string
+}
+
+// NewClickHouseStatsStorage creates the error statistics tables if needed
+func NewClickHouseStatsStorage(db SQLExecutor) (*ClickHouseStatsStorage, error) {
+	s := &ClickHouseStatsStorage{
+		db:           db,
+		statsTable:   "error_stats",
+		historyTable: "error_anomaly_history",
+	}
+
+	ctx := context.Background()
+	if err := db.Execute(ctx, fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		key String,
+		pattern String,
+		category LowCardinality(String),
+		count UInt64,
+		first_seen DateTime64(3),
+		last_seen DateTime64(3),
+		services String,
+		samples String,
+		checkpointed_at DateTime64(3)
+	) ENGINE = ReplacingMergeTree(checkpointed_at)
+	ORDER BY key
+	TTL toDateTime(last_seen) + INTERVAL 1 DAY
+	`, s.statsTable)); err != nil {
+		return nil, fmt.Errorf("failed to create error stats table: %w", err)
+	}
+	if err := db.Execute(ctx, fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		id UInt8,
+		history String,
+		checkpointed_at DateTime64(3)
+	) ENGINE = ReplacingMergeTree(checkpointed_at)
+	ORDER BY id
+	`, s.historyTable)); err != nil {
+		return nil, fmt.Errorf("failed to create error anomaly history table: %w", err)
+	}
+	return s, nil
+}
+
+// Save writes the current totals of the given statistics and the anomaly
+// history
+func (s *ClickHouseStatsStorage) Save(stats []*ErrorStats, history []float64) error {
+	now := chsql.Quote(time.Now().UTC().Format(clickHouseTimeFormat))
+
+	if len(stats) > 0 {
+		values := make([]string, 0, len(stats))
+		for _, st := range stats {
+			services, err := json.Marshal(st.Services)
+			if err != nil {
+				return fmt.Errorf("failed to encode error stats services: %w", err)
+			}
+			samples, err := json.Marshal(st.Samples)
+			if err != nil {
+				return fmt.Errorf("failed to encode error stats samples: %w", err)
+			}
+			values = append(values, fmt.Sprintf("(%s, %s, %s, %d, %s, %s, %s, %s, %s)",
+				chsql.Quote(st.Key),
+				chsql.Quote(st.Pattern),
+				chsql.Quote(st.Category),
+				st.Count,
+				chsql.Quote(st.FirstSeen.UTC().Format(clickHouseTimeFormat)),
+				chsql.Quote(st.LastSeen.UTC().Format(clickHouseTimeFormat)),
+				chsql.Quote(string(services)),
+				chsql.Quote(string(samples)),
+				now,
+			))
+		}
+		query := fmt.Sprintf("INSERT INTO %s (key, pattern, category, count, first_seen, last_seen, services, samples, checkpointed_at) VALUES %s",
+			s.statsTable, strings.Join(values, ", "))
+		if err := s.db.Execute(context.Background(), query); err != nil {
+			return fmt.Errorf("failed to save error stats: %w", err)
+		}
+	}
+
+	encoded, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to encode error anomaly history: %w", err)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (id, history, checkpointed_at) VALUES (0, %s, %s)",
+		s.historyTable, chsql.Quote(string(encoded)), now)
+	if err := s.db.Execute(context.Background(), query); err != nil {
+		return fmt.Errorf("failed to save error anomaly history: %w", err)
+	}
+	return nil
+}
+
+// Load returns the latest checkpoint of every statistics key and the
+// anomaly history
+func (s *ClickHouseStatsStorage) Load() ([]*ErrorStats, []float64, error) {
+	rows, err := s.db.ExecuteSQL(fmt.Sprintf(`
+		SELECT key,
+			argMax(pattern, checkpointed_at) AS pattern,
+			argMax(category, checkpointed_at) AS category,
+			argMax(count, checkpointed_at) AS count,
+			min(first_seen) AS first_seen,
+			max(last_seen) AS last_seen,
+			argMax(services, checkpointed_at) AS services,
+			argMax(samples, checkpointed_at) AS samples
+		FROM %s
+		GROUP BY key
+	`, s.statsTable))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load error stats: %w", err)
+	}
+
+	stats := make([]*ErrorStats, 0, len(rows))
+	for _, row := range rows {
+		st := &ErrorStats{Services: make(map[string]int64)}
+		st.Key, _ = row["key"].(string)
+		st.Pattern, _ = row["pattern"].(string)
+		st.Category, _ = row["category"].(string)
+		st.Count = chsql.ToInt64(row["count"])
+		if services, ok := row["services"].(string); ok {
+			json.Unmarshal([]byte(services), &st.Services)
+		}
+		if samples, ok := row["samples"].(string); ok {
+			json.Unmarshal([]byte(samples), &st.Samples)
+		}
+		if ts, ok := row["first_seen"].(string); ok {
+			st.FirstSeen, _ = time.Parse(clickHouseTimeFormat, ts)
+		}
+		if ts, ok := row["last_seen"].(string); ok {
+			st.LastSeen, _ = time.Parse(clickHouseTimeFormat, ts)
+		}
+		stats = append(stats, st)
+	}
+
+	rows, err = s.db.ExecuteSQL(fmt.Sprintf(
+		"SELECT argMax(history, checkpointed_at) AS history FROM %s", s.historyTable))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load error anomaly history: %w", err)
+	}
+	var history []float64
+	if len(rows) > 0 {
+		if encoded, ok := rows[0]["history"].(string); ok && encoded != "" {
+			if err := json.Unmarshal([]byte(encoded), &history); err != nil {
+				return nil, nil, fmt.Errorf("failed to decode error anomaly history: %w", err)
+			}
+		}
+	}
+	return stats, history, nil
+}
@@ -58,9 +58,6 @@ func NewExporter(db *database.DB) *Exporter {
 // Export exports data based on options
 func (e *Exporter) Export(writer io.Writer, options ExportOptions) (*ExportResult, error) {
 	start := time.Now()
-	result := &ExportResult{
-		Format: options.Format,
-	}
 
 	// Execute query to get data
 	logs, err := e.fetchLogs(options)
@@ -68,9 +65,26 @@ func (e *Exporter) Export(writer io.Writer, options ExportOptions) (*ExportResul
 		return nil, fmt.Errorf("failed to fetch logs: %w", err)
 	}
 
-	result.RowCount = len(logs)
+	result, err := e.ExportLogs(writer, logs, options)
+	if err != nil {
+		return nil, err
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// ExportLogs exports logs already at hand, such as a recorded live tail,
+// in the format and with the fields of options. The query, filters and time
+// range of options are not used.
+func (e *Exporter) ExportLogs(writer io.Writer, logs []models.Log, options ExportOptions) (*ExportResult, error) {
+	start := time.Now()
+	result := &ExportResult{
+		Format:   options.Format,
+		RowCount: len(logs),
+	}
 
 	// Export based on format
+	var err error
 	switch options.Format {
 	case FormatCSV:
 		err = e.exportCSV(writer, logs, options)
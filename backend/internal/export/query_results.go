@@ -0,0 +1,76 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/query"
+)
+
+// Query result download formats
+const (
+	FormatTSV     ExportFormat = "tsv"
+	FormatParquet ExportFormat = "parquet"
+)
+
+// QueryResultFormat describes a format query results can be downloaded in.
+// ClickHouse encodes the rows, so column order and types are kept and the
+// server never holds the result.
+type QueryResultFormat struct {
+	Format           ExportFormat
+	ContentType      string
+	ClickHouseFormat string
+}
+
+var queryResultFormats = []QueryResultFormat{
+	{Format: FormatCSV, ContentType: "text/csv", ClickHouseFormat: "CSVWithNames"},
+	{Format: FormatTSV, ContentType: "text/tab-separated-values", ClickHouseFormat: "TSVWithNames"},
+	{Format: FormatParquet, ContentType: "application/vnd.apache.parquet", ClickHouseFormat: "Parquet"},
+}
+
+// QueryResultFormatFor picks the download format named by format, or else
+// the first one accepted by an Accept header. It reports false when the
+// caller asked for neither, i.e. wants the regular JSON response.
+func QueryResultFormatFor(format, accept string) (QueryResultFormat, bool, error) {
+	if format != "" && format != string(FormatJSON) {
+		for _, f := range queryResultFormats {
+			if string(f.Format) == strings.ToLower(format) {
+				return f, true, nil
+			}
+		}
+		return QueryResultFormat{}, false, fmt.Errorf("unsupported format: %s", format)
+	}
+	if format == "" {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			for _, f := range queryResultFormats {
+				if f.ContentType == mediaType {
+					return f, true, nil
+				}
+			}
+		}
+	}
+	return QueryResultFormat{}, false, nil
+}
+
+// FileName returns the name downloads of the format are saved under
+func (f QueryResultFormat) FileName(base string) string {
+	return fmt.Sprintf("%s.%s", base, f.Format)
+}
+
+// StreamQueryResults runs a query through the query engine and writes its
+// result to w in the given format as ClickHouse produces it
+func StreamQueryResults(ctx context.Context, db *database.DB, req *query.QueryRequest, format QueryResultFormat, w io.Writer) error {
+	queryEngine := db.GetQueryEngine()
+	if queryEngine == nil {
+		return fmt.Errorf("query engine not available")
+	}
+	return queryEngine.StreamFormat(ctx, req, format.ClickHouseFormat, w)
+}
@@ -6,8 +6,13 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/database"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/monitoring"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/telemetry"
 )
 
 // BatchProcessor handles batching of logs for efficient writes
@@ -21,6 +26,29 @@ type BatchProcessor struct {
 	stopChan     chan struct{}
 	wg           sync.WaitGroup
 	processor    *LogProcessor
+	live         LiveSink
+	metrics      *monitoring.MetricsCollector
+	absence      *monitoring.AbsenceMonitor
+	
+	// The outcome of the latest flushes, for health checks
+	statsMu             sync.Mutex
+	lastFlush           time.Time
+	lastError           string
+	lastErrorAt         time.Time
+	consecutiveFailures int
+	droppedLogs         uint64
+	flushedBatches      uint64
+	inFlightBatches     int
+	inFlightLogs        int
+	latencies           flushLatencies
+	
+	queueDepth metric.Registration // reports the queue depth to OpenTelemetry
+}
+
+// LiveSink receives the logs the pipeline accepts, as they arrive, for
+// live tailing
+type LiveSink interface {
+	BroadcastLog(log *models.Log)
 }
 
 // NewBatchProcessor creates a new batch processor
@@ -32,8 +60,11 @@ func NewBatchProcessor(db *database.DB, batchSize int, flushInterval time.Durati
 		buffer:        make([]models.Log, 0, batchSize),
 		flushChan:     make(chan struct{}, 1),
 		stopChan:      make(chan struct{}),
+		lastFlush:     time.Now(),
 	}
 	
+	bp.queueDepth = observeQueueDepth(bp)
+	
 	bp.wg.Add(1)
 	go bp.run()
 	
@@ -45,12 +76,37 @@ func (bp *BatchProcessor) SetProcessor(processor *LogProcessor) {
 	bp.processor = processor
 }
 
+// SetMetrics records how long batches take to write and their size in
+// metrics, as the batch_write_duration_ms and ingestion_batch_size
+// histograms
+func (bp *BatchProcessor) SetMetrics(metrics *monitoring.MetricsCollector) {
+	bp.metrics = metrics
+}
+
+// SetAbsenceMonitor records in monitor the services of the logs added from
+// now on, so that it notices when one goes silent
+func (bp *BatchProcessor) SetAbsenceMonitor(monitor *monitoring.AbsenceMonitor) {
+	bp.absence = monitor
+}
+
+// SetLiveSink publishes the logs added from now on to sink
+func (bp *BatchProcessor) SetLiveSink(sink LiveSink) {
+	bp.live = sink
+}
+
 // Add adds a log to the batch
 func (bp *BatchProcessor) Add(log models.Log) {
 	// Process log through analyzers
 	if bp.processor != nil {
 		bp.processor.ProcessLog(&log)
 	}
+	if bp.live != nil {
+		live := log
+		bp.live.BroadcastLog(&live)
+	}
+	if bp.absence != nil {
+		bp.absence.Observe(log.Service)
+	}
 	
 	bp.bufferMu.Lock()
 	bp.buffer = append(bp.buffer, log)
@@ -67,6 +123,35 @@ func (bp *BatchProcessor) Add(log models.Log) {
 
 // AddBatch adds multiple logs to the batch
 func (bp *BatchProcessor) AddBatch(logs []models.Log) {
+	if bp.processor != nil {
+		bp.processor.ProcessBatch(logs)
+	}
+	if bp.live != nil {
+		for i := range logs {
+			live := logs[i]
+			bp.live.BroadcastLog(&live)
+		}
+	}
+	bp.enqueue(logs)
+}
+
+// AddBatchQuietly adds multiple logs to the batch without publishing them
+// to live tails
+func (bp *BatchProcessor) AddBatchQuietly(logs []models.Log) {
+	if bp.processor != nil {
+		bp.processor.ProcessBatch(logs)
+	}
+	bp.enqueue(logs)
+}
+
+// enqueue adds processed logs to the batch
+func (bp *BatchProcessor) enqueue(logs []models.Log) {
+	if bp.absence != nil {
+		for i := range logs {
+			bp.absence.Observe(logs[i].Service)
+		}
+	}
+	
 	bp.bufferMu.Lock()
 	bp.buffer = append(bp.buffer, logs...)
 	shouldFlush := len(bp.buffer) >= bp.batchSize
@@ -105,6 +190,7 @@ func (bp *BatchProcessor) flush() {
 	bp.bufferMu.Lock()
 	if len(bp.buffer) == 0 {
 		bp.bufferMu.Unlock()
+		bp.recordFlush(nil, 0, 0)
 		return
 	}
 	
@@ -112,27 +198,126 @@ func (bp *BatchProcessor) flush() {
 	batch := make([]models.Log, len(bp.buffer))
 	copy(batch, bp.buffer)
 	bp.buffer = bp.buffer[:0]
+	bp.startFlush(len(batch))
 	bp.bufferMu.Unlock()
 	
-	// Write batch with retries
-	ctx := context.Background()
+	// Write batch with retries. Logging about it is kept out of self
+	// monitoring, as every batch of the server's own logs would log again.
+	ctx, span := tracer.Start(context.Background(), "ingestion.flush",
+		trace.WithAttributes(attribute.Int("clicklite.batch.size", len(batch))))
+	defer span.End()
+	start := time.Now()
 	maxRetries := 3
 	backoff := time.Second
 	
+	var err error
 	for i := 0; i < maxRetries; i++ {
-		if err := bp.writeBatch(ctx, batch); err != nil {
-			log.Error().Err(err).Int("attempt", i+1).Int("batch_size", len(batch)).Msg("Failed to write batch")
+		if err = bp.writeBatch(ctx, batch); err != nil {
+			log.Error().Bool(SelfMonitoringField, false).Err(err).Int("attempt", i+1).Int("batch_size", len(batch)).Msg("Failed to write batch")
+			span.AddEvent("write failed", trace.WithAttributes(
+				attribute.Int("attempt", i+1), attribute.String("error", err.Error())))
 			if i < maxRetries-1 {
 				time.Sleep(backoff)
 				backoff *= 2
 			}
 			continue
 		}
-		log.Info().Int("batch_size", len(batch)).Msg("Successfully wrote batch")
+		log.Info().Bool(SelfMonitoringField, false).Int("batch_size", len(batch)).Msg("Successfully wrote batch")
+		bp.recordFlush(nil, len(batch), time.Since(start))
+		bp.observeFlush(ctx, span, start, len(batch), i+1, nil)
 		return
 	}
 	
-	log.Error().Int("batch_size", len(batch)).Msg("Failed to write batch after all retries")
+	log.Error().Bool(SelfMonitoringField, false).Int("batch_size", len(batch)).Msg("Failed to write batch after all retries")
+	bp.recordFlush(err, len(batch), time.Since(start))
+	bp.observeFlush(ctx, span, start, len(batch), maxRetries, err)
+}
+
+// observeFlush records a flush that wrote, or gave up on, size logs in
+// its span and metrics
+func (bp *BatchProcessor) observeFlush(ctx context.Context, span trace.Span, start time.Time, size, attempts int, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+		logsDropped.Add(ctx, int64(size))
+	} else {
+		logsWritten.Add(ctx, int64(size))
+	}
+	span.SetAttributes(attribute.Int("clicklite.batch.attempts", attempts))
+	telemetry.SpanError(span, err)
+	
+	duration := time.Since(start)
+	attributes := metric.WithAttributes(attribute.String("outcome", outcome))
+	flushDuration.Record(ctx, duration.Seconds(), attributes)
+	batchSize.Record(ctx, int64(size), attributes)
+	
+	if bp.metrics != nil {
+		bp.metrics.RecordHistogram("batch_write_duration_ms", float64(duration.Microseconds())/1000)
+		bp.metrics.RecordHistogram("ingestion_batch_size", float64(size))
+	}
+}
+
+// startFlush records a batch of size logs leaving the queue to be written.
+// It is called with the buffer locked, so that the logs are always counted
+// either in the queue or in flight.
+func (bp *BatchProcessor) startFlush(size int) {
+	bp.statsMu.Lock()
+	defer bp.statsMu.Unlock()
+	bp.inFlightBatches++
+	bp.inFlightLogs += size
+}
+
+// recordFlush records the outcome of a flush of size logs that took
+// duration, size 0 when the queue was empty. The logs of a failed flush
+// are given up on.
+func (bp *BatchProcessor) recordFlush(err error, size int, duration time.Duration) {
+	bp.statsMu.Lock()
+	defer bp.statsMu.Unlock()
+	if size > 0 {
+		bp.inFlightBatches--
+		bp.inFlightLogs -= size
+		bp.latencies.add(duration)
+	}
+	if err != nil {
+		bp.lastError = err.Error()
+		bp.lastErrorAt = time.Now()
+		bp.consecutiveFailures++
+		bp.droppedLogs += uint64(size)
+		return
+	}
+	bp.lastFlush = time.Now()
+	bp.consecutiveFailures = 0
+	if size > 0 {
+		bp.flushedBatches++
+	}
+}
+
+// QueueStats returns the logs waiting to be written, the batches being
+// written and the outcome and latency of the latest flushes
+func (bp *BatchProcessor) QueueStats() monitoring.BatchQueueStats {
+	bp.bufferMu.Lock()
+	defer bp.bufferMu.Unlock()
+	bp.statsMu.Lock()
+	defer bp.statsMu.Unlock()
+	
+	stats := monitoring.BatchQueueStats{
+		Depth:               len(bp.buffer),
+		BatchSize:           bp.batchSize,
+		FlushInterval:       bp.flushInterval,
+		InFlightBatches:     bp.inFlightBatches,
+		InFlightLogs:        bp.inFlightLogs,
+		LastFlush:           bp.lastFlush,
+		LastError:           bp.lastError,
+		ConsecutiveFailures: bp.consecutiveFailures,
+		DroppedLogs:         bp.droppedLogs,
+		FlushedBatches:      bp.flushedBatches,
+		FlushLatency:        bp.latencies.summary(),
+	}
+	if !bp.lastErrorAt.IsZero() {
+		lastErrorAt := bp.lastErrorAt
+		stats.LastErrorAt = &lastErrorAt
+	}
+	return stats
 }
 
 // writeBatch writes a batch of logs to the database
@@ -149,4 +334,7 @@ func (bp *BatchProcessor) writeBatch(ctx context.Context, batch []models.Log) er
 func (bp *BatchProcessor) Stop() {
 	close(bp.stopChan)
 	bp.wg.Wait()
+	if bp.queueDepth != nil {
+		bp.queueDepth.Unregister()
+	}
 }
\ No newline at end of file
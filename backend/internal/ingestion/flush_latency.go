@@ -0,0 +1,56 @@
+package ingestion
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/monitoring"
+)
+
+// flushLatencySamples is how many of the latest flushes latency
+// percentiles are computed over
+const flushLatencySamples = 512
+
+// flushLatencies keeps how long the latest flushes took in a ring
+type flushLatencies struct {
+	samples []time.Duration
+	next    int
+}
+
+func (l *flushLatencies) add(d time.Duration) {
+	if len(l.samples) < flushLatencySamples {
+		l.samples = append(l.samples, d)
+		return
+	}
+	l.samples[l.next] = d
+	l.next = (l.next + 1) % flushLatencySamples
+}
+
+func (l *flushLatencies) summary() monitoring.FlushLatency {
+	if len(l.samples) == 0 {
+		return monitoring.FlushLatency{}
+	}
+	sorted := append([]time.Duration(nil), l.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return monitoring.FlushLatency{
+		Samples: len(sorted),
+		P50Ms:   milliseconds(percentile(sorted, 0.50)),
+		P95Ms:   milliseconds(percentile(sorted, 0.95)),
+		P99Ms:   milliseconds(percentile(sorted, 0.99)),
+		MaxMs:   milliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -9,20 +9,17 @@ import (
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
-	"github.com/your-username/click-lite-log-analytics/backend/internal/websocket"
 )
 
 // HTTPHandler handles HTTP log ingestion with batching
 type HTTPHandler struct {
 	batchProcessor *BatchProcessor
-	wsHub          *websocket.Hub
 }
 
 // NewHTTPHandler creates a new HTTP ingestion handler
-func NewHTTPHandler(batchProcessor *BatchProcessor, wsHub *websocket.Hub) *HTTPHandler {
+func NewHTTPHandler(batchProcessor *BatchProcessor) *HTTPHandler {
 	return &HTTPHandler{
 		batchProcessor: batchProcessor,
-		wsHub:          wsHub,
 	}
 }
 
@@ -71,9 +68,6 @@ func (h *HTTPHandler) IngestLogs() http.HandlerFunc {
 			if logs[i].Service == "" {
 				logs[i].Service = "unknown"
 			}
-			
-			// Broadcast to WebSocket clients
-			h.wsHub.BroadcastLog(&logs[i])
 		}
 		
 		// Add to batch processor
@@ -135,15 +129,15 @@ func (h *HTTPHandler) BulkIngestLogs() http.HandlerFunc {
 			if request.Logs[i].Service == "" {
 				request.Logs[i].Service = "unknown"
 			}
-			
-			// Optionally broadcast to WebSocket clients (disabled for bulk by default)
-			if !request.Options.SkipBroadcast && i < 100 { // Limit broadcasts for bulk
-				h.wsHub.BroadcastLog(&request.Logs[i])
-			}
 		}
 		
-		// Add to batch processor
-		h.batchProcessor.AddBatch(request.Logs)
+		// Add to batch processor, which publishes the logs to live tails
+		// unless asked not to
+		if request.Options.SkipBroadcast {
+			h.batchProcessor.AddBatchQuietly(request.Logs)
+		} else {
+			h.batchProcessor.AddBatch(request.Logs)
+		}
 		
 		// Return acknowledgment
 		response := map[string]interface{}{
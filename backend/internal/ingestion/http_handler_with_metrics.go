@@ -10,21 +10,18 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/monitoring"
-	"github.com/your-username/click-lite-log-analytics/backend/internal/websocket"
 )
 
 // HTTPHandlerWithMetrics handles HTTP log ingestion with batching and metrics
 type HTTPHandlerWithMetrics struct {
 	batchProcessor *BatchProcessor
-	wsHub          *websocket.Hub
 	metrics        *monitoring.MetricsCollector
 }
 
 // NewHTTPHandlerWithMetrics creates a new HTTP ingestion handler with metrics
-func NewHTTPHandlerWithMetrics(batchProcessor *BatchProcessor, wsHub *websocket.Hub, metrics *monitoring.MetricsCollector) *HTTPHandlerWithMetrics {
+func NewHTTPHandlerWithMetrics(batchProcessor *BatchProcessor, metrics *monitoring.MetricsCollector) *HTTPHandlerWithMetrics {
 	return &HTTPHandlerWithMetrics{
 		batchProcessor: batchProcessor,
-		wsHub:          wsHub,
 		metrics:        metrics,
 	}
 }
@@ -66,16 +63,11 @@ func (h *HTTPHandlerWithMetrics) IngestLogs() http.HandlerFunc {
 			}
 		}
 		
-		// Add logs to batch processor
+		// Add logs to batch processor, which publishes them to live tails
 		for _, log := range logs {
 			h.batchProcessor.Add(log)
 		}
 		
-		// Broadcast logs via WebSocket
-		for i := range logs {
-			h.wsHub.BroadcastLog(&logs[i])
-		}
-		
 		// Record metrics
 		h.metrics.RecordIngestion(len(logs))
 		h.metrics.RecordHistogram("ingestion_request_duration_ms", float64(time.Since(start).Milliseconds()))
@@ -118,27 +110,12 @@ func (h *HTTPHandlerWithMetrics) BulkIngestLogs() http.HandlerFunc {
 			}
 		}
 		
-		// Add logs to batch processor
+		// Add logs to batch processor, which publishes them to live tails;
+		// slow WebSocket clients drop the oldest rather than hold it up
 		for _, log := range logs {
 			h.batchProcessor.Add(log)
 		}
 		
-		// For bulk ingestion, only broadcast a summary to avoid overwhelming WebSocket
-		if len(logs) > 0 {
-			summaryLog := models.Log{
-				ID:        uuid.New().String(),
-				Timestamp: now,
-				Level:     "info",
-				Message:   "Bulk ingestion",
-				Service:   "ingestion",
-				Attributes: map[string]interface{}{
-					"count": len(logs),
-					"type":  "bulk_ingestion",
-				},
-			}
-			h.wsHub.BroadcastLog(&summaryLog)
-		}
-		
 		// Record metrics
 		h.metrics.RecordIngestion(len(logs))
 		h.metrics.RecordHistogram("bulk_ingestion_duration_ms", float64(time.Since(start).Milliseconds()))
@@ -0,0 +1,239 @@
+package ingestion
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/tracing"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxOTLPBodyBytes bounds the size of an export request, once decompressed
+const maxOTLPBodyBytes = 16 << 20
+
+// OTLPHandler accepts the spans of services instrumented with
+// OpenTelemetry, exported over OTLP/HTTP in protobuf or JSON
+type OTLPHandler struct {
+	traceManager *tracing.TraceManager
+}
+
+// NewOTLPHandler creates an OTLP handler adding the spans to traceManager
+func NewOTLPHandler(traceManager *tracing.TraceManager) *OTLPHandler {
+	return &OTLPHandler{
+		traceManager: traceManager,
+	}
+}
+
+// IngestTraces handles POST /api/v1/ingest/otlp/v1/traces, the path OTLP
+// exporters use with /api/v1/ingest/otlp as their endpoint
+func (h *OTLPHandler) IngestTraces() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, "Invalid gzip body", http.StatusBadRequest)
+				return
+			}
+			defer gz.Close()
+			reader = gz
+		}
+		body, err := io.ReadAll(io.LimitReader(reader, maxOTLPBodyBytes+1))
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxOTLPBodyBytes {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		isJSON := contentType == "application/json"
+		request := &collectortrace.ExportTraceServiceRequest{}
+		if isJSON {
+			err = unmarshalOTLPJSON(body, request)
+		} else if contentType == "application/x-protobuf" {
+			err = proto.Unmarshal(body, request)
+		} else {
+			http.Error(w, "Content-Type must be application/x-protobuf or application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to parse OTLP trace request")
+			http.Error(w, "Invalid OTLP trace request", http.StatusBadRequest)
+			return
+		}
+
+		rejected := int64(0)
+		for _, resourceSpans := range request.ResourceSpans {
+			service := "unknown"
+			if name, ok := attributeValue(resourceSpans.GetResource().GetAttributes(), "service.name").(string); ok && name != "" {
+				service = name
+			}
+			for _, scopeSpans := range resourceSpans.ScopeSpans {
+				for _, span := range scopeSpans.Spans {
+					data, ok := spanData(service, span)
+					if !ok {
+						rejected++
+						continue
+					}
+					h.traceManager.ProcessSpan(data)
+				}
+			}
+		}
+
+		response := &collectortrace.ExportTraceServiceResponse{}
+		if rejected > 0 {
+			response.PartialSuccess = &collectortrace.ExportTracePartialSuccess{
+				RejectedSpans: rejected,
+				ErrorMessage:  "spans without a valid trace or span ID were rejected",
+			}
+		}
+		var encoded []byte
+		if isJSON {
+			w.Header().Set("Content-Type", "application/json")
+			encoded, err = protojson.Marshal(response)
+		} else {
+			w.Header().Set("Content-Type", "application/x-protobuf")
+			encoded, err = proto.Marshal(response)
+		}
+		if err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			return
+		}
+		w.Write(encoded)
+	}
+}
+
+// spanData converts an OTLP span, false when its IDs are invalid
+func spanData(service string, span *tracepb.Span) (*tracing.SpanData, bool) {
+	if len(span.TraceId) != 16 || len(span.SpanId) != 8 {
+		return nil, false
+	}
+	data := &tracing.SpanData{
+		TraceID:    hex.EncodeToString(span.TraceId),
+		SpanID:     hex.EncodeToString(span.SpanId),
+		Service:    service,
+		Operation:  span.Name,
+		StartTime:  time.Unix(0, int64(span.StartTimeUnixNano)),
+		EndTime:    time.Unix(0, int64(span.EndTimeUnixNano)),
+		Failed:     span.GetStatus().GetCode() == tracepb.Status_STATUS_CODE_ERROR,
+		Attributes: make(map[string]interface{}, len(span.Attributes)+2),
+	}
+	if len(span.ParentSpanId) == 8 {
+		data.ParentID = hex.EncodeToString(span.ParentSpanId)
+	}
+	for _, attribute := range span.Attributes {
+		data.Attributes[attribute.Key] = anyValue(attribute.Value)
+	}
+	if span.Kind != tracepb.Span_SPAN_KIND_UNSPECIFIED {
+		data.Attributes["span.kind"] = strings.ToLower(strings.TrimPrefix(span.Kind.String(), "SPAN_KIND_"))
+	}
+	if message := span.GetStatus().GetMessage(); message != "" {
+		data.Attributes["status.message"] = message
+	}
+	return data, true
+}
+
+func attributeValue(attributes []*commonpb.KeyValue, key string) interface{} {
+	for _, attribute := range attributes {
+		if attribute.Key == key {
+			return anyValue(attribute.Value)
+		}
+	}
+	return nil
+}
+
+// anyValue converts an attribute value to the types JSON has, bytes
+// becoming base64
+func anyValue(value *commonpb.AnyValue) interface{} {
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return v.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return v.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return v.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]interface{}, 0, len(v.ArrayValue.GetValues()))
+		for _, item := range v.ArrayValue.GetValues() {
+			values = append(values, anyValue(item))
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		values := make(map[string]interface{}, len(v.KvlistValue.GetValues()))
+		for _, item := range v.KvlistValue.GetValues() {
+			values[item.Key] = anyValue(item.Value)
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// unmarshalOTLPJSON decodes an export request in the JSON encoding of
+// OTLP, which writes trace and span IDs in hex where protobuf JSON
+// expects base64
+func unmarshalOTLPJSON(body []byte, request *collectortrace.ExportTraceServiceRequest) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return err
+	}
+	if err := hexIDsToBase64(document); err != nil {
+		return err
+	}
+	converted, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(converted, request)
+}
+
+func hexIDsToBase64(value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			id, ok := field.(string)
+			if ok && (key == "traceId" || key == "spanId" || key == "parentSpanId") {
+				decoded, err := hex.DecodeString(id)
+				if err != nil {
+					return fmt.Errorf("invalid %s %q: %w", key, id, err)
+				}
+				v[key] = base64.StdEncoding.EncodeToString(decoded)
+				continue
+			}
+			if err := hexIDsToBase64(field); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := hexIDsToBase64(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
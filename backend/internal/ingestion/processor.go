@@ -2,7 +2,9 @@ package ingestion
 
 import (
 	"github.com/your-username/click-lite-log-analytics/backend/internal/errors"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/mining"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/parsing"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/tracing"
 )
 
@@ -10,6 +12,8 @@ import (
 type LogProcessor struct {
 	traceManager  *tracing.TraceManager
 	errorDetector *errors.ErrorDetector
+	schema        *parsing.SchemaRegistry
+	miner         *mining.Miner
 }
 
 // NewLogProcessor creates a new log processor
@@ -20,6 +24,16 @@ func NewLogProcessor(traceManager *tracing.TraceManager, errorDetector *errors.E
 	}
 }
 
+// SetSchemaRegistry records the attributes of processed logs in registry
+func (p *LogProcessor) SetSchemaRegistry(registry *parsing.SchemaRegistry) {
+	p.schema = registry
+}
+
+// SetPatternMiner clusters the messages of processed logs into patterns
+func (p *LogProcessor) SetPatternMiner(miner *mining.Miner) {
+	p.miner = miner
+}
+
 // ProcessLog processes a log through all analyzers
 func (p *LogProcessor) ProcessLog(log *models.Log) {
 	// Process for trace correlation
@@ -29,15 +43,27 @@ func (p *LogProcessor) ProcessLog(log *models.Log) {
 
 	// Process for error detection
 	if p.errorDetector != nil {
-		detectedErrors := p.errorDetector.ProcessLog(log)
+		detectedErrors, fingerprint := p.errorDetector.ProcessLog(log)
 		if len(detectedErrors) > 0 {
-			// Add error information to attributes
+			// Add error information to attributes, the fingerprint to
+			// find the logs of an error group
 			if log.Attributes == nil {
 				log.Attributes = make(map[string]interface{})
 			}
 			log.Attributes["detected_errors"] = detectedErrors
+			log.Attributes["error_fingerprint"] = fingerprint
 		}
 	}
+
+	// Record attribute names and types
+	if p.schema != nil {
+		p.schema.Observe(log)
+	}
+
+	// Discover message templates
+	if p.miner != nil {
+		p.miner.ProcessLog(log)
+	}
 }
 
 // ProcessBatch processes multiple logs
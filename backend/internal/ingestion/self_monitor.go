@@ -0,0 +1,160 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// SelfMonitoringField marks the logs that are not ingested by the self
+// monitor when set to false. It is set on the logs the pipeline writes
+// about the logs it ingests, which would otherwise feed themselves back
+// in forever.
+const SelfMonitoringField = "self_monitoring"
+
+const (
+	defaultSelfMonitorService      = "clicklite"
+	defaultSelfMonitorQueueSize    = 1000
+	defaultSelfMonitorMaxPerSecond = 100
+)
+
+// SelfMonitorOptions configure the ingestion of the server's own logs
+type SelfMonitorOptions struct {
+	Service      string        // service of the ingested logs, clicklite when empty
+	MinLevel     zerolog.Level // logs below it are not ingested
+	QueueSize    int           // logs waiting to be ingested, 1000 when 0
+	MaxPerSecond int           // logs ingested per second at most, 100 when 0
+}
+
+// SelfMonitor is a zerolog writer feeding the server's own structured logs
+// into the ingestion pipeline, so that Click-Lite can be debugged with
+// Click-Lite. Add it next to the server's usual output with
+// zerolog.MultiLevelWriter: unlike a hook, a writer sees the fields of
+// the event.
+//
+// Writing never blocks the caller: logs are queued and dropped when the
+// queue is full or they arrive faster than MaxPerSecond. Together with
+// SelfMonitoringField and the monitor never logging itself, this keeps
+// logs about ingesting logs from looping back in.
+type SelfMonitor struct {
+	options SelfMonitorOptions
+	queue   chan models.Log
+	dropped atomic.Uint64
+}
+
+// NewSelfMonitor creates a self monitor. The logs written before it is
+// started are queued, so that startup logs are ingested too.
+func NewSelfMonitor(options SelfMonitorOptions) *SelfMonitor {
+	if options.Service == "" {
+		options.Service = defaultSelfMonitorService
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = defaultSelfMonitorQueueSize
+	}
+	if options.MaxPerSecond <= 0 {
+		options.MaxPerSecond = defaultSelfMonitorMaxPerSecond
+	}
+	return &SelfMonitor{
+		options: options,
+		queue:   make(chan models.Log, options.QueueSize),
+	}
+}
+
+// Write queues a log written without a level
+func (m *SelfMonitor) Write(p []byte) (int, error) {
+	return m.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel queues a JSON log event for ingestion. It always succeeds so
+// that the server's other outputs are unaffected.
+func (m *SelfMonitor) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < m.options.MinLevel && level != zerolog.NoLevel {
+		return len(p), nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(p, &fields); err != nil {
+		// Only JSON objects are ingested
+		return len(p), nil
+	}
+	if ingest, ok := fields[SelfMonitoringField].(bool); ok && !ingest {
+		return len(p), nil
+	}
+
+	entry := m.newLog(level, fields)
+	select {
+	case m.queue <- entry:
+	default:
+		m.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+// Dropped returns how many logs were not ingested because the queue was
+// full or the rate limit was reached
+func (m *SelfMonitor) Dropped() uint64 {
+	return m.dropped.Load()
+}
+
+// Start adds the queued logs to sink until ctx is cancelled
+func (m *SelfMonitor) Start(ctx context.Context, sink *BatchProcessor) {
+	// Ingestion is limited per second, the allowance refilling every second
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	allowance := m.options.MaxPerSecond
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			allowance = m.options.MaxPerSecond
+		case entry := <-m.queue:
+			if allowance == 0 {
+				m.dropped.Add(1)
+				continue
+			}
+			allowance--
+			sink.Add(entry)
+		}
+	}
+}
+
+// newLog converts the fields of a zerolog event to a log of the server's
+// service, keeping fields other than the level, message and time as
+// attributes
+func (m *SelfMonitor) newLog(level zerolog.Level, fields map[string]interface{}) models.Log {
+	entry := models.Log{
+		ID:        uuid.New().String(),
+		Timestamp: time.Now(),
+		Level:     level.String(),
+		Service:   m.options.Service,
+	}
+	if level == zerolog.NoLevel {
+		entry.Level = zerolog.InfoLevel.String()
+	}
+	if message, ok := fields[zerolog.MessageFieldName].(string); ok {
+		entry.Message = message
+	}
+	delete(fields, zerolog.MessageFieldName)
+	delete(fields, zerolog.LevelFieldName)
+	delete(fields, zerolog.TimestampFieldName)
+
+	if traceID, ok := fields["trace_id"].(string); ok {
+		entry.TraceID = traceID
+		delete(fields, "trace_id")
+	}
+	if spanID, ok := fields["span_id"].(string); ok {
+		entry.SpanID = spanID
+		delete(fields, "span_id")
+	}
+	if len(fields) > 0 {
+		entry.Attributes = fields
+	}
+	return entry
+}
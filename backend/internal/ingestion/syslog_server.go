@@ -11,14 +11,12 @@ import (
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
-	"github.com/your-username/click-lite-log-analytics/backend/internal/websocket"
 )
 
 // SyslogServer handles Syslog protocol log ingestion (RFC3164 and RFC5424)
 type SyslogServer struct {
 	addr           string
 	batchProcessor *BatchProcessor
-	wsHub          *websocket.Hub
 	conn           net.PacketConn
 	stopChan       chan struct{}
 	wg             sync.WaitGroup
@@ -65,11 +63,10 @@ var facilityNames = map[int]string{
 }
 
 // NewSyslogServer creates a new Syslog ingestion server
-func NewSyslogServer(addr string, batchProcessor *BatchProcessor, wsHub *websocket.Hub) *SyslogServer {
+func NewSyslogServer(addr string, batchProcessor *BatchProcessor) *SyslogServer {
 	return &SyslogServer{
 		addr:           addr,
 		batchProcessor: batchProcessor,
-		wsHub:          wsHub,
 		stopChan:       make(chan struct{}),
 	}
 }
@@ -138,11 +135,8 @@ func (s *SyslogServer) processSyslogMessage(message, source string) {
 		logEntry.Timestamp = time.Now()
 	}
 	
-	// Add to batch processor
+	// Add to batch processor, which publishes it to live tails
 	s.batchProcessor.Add(*logEntry)
-	
-	// Broadcast to WebSocket clients
-	s.wsHub.BroadcastLog(logEntry)
 }
 
 // parseSyslogMessage parses RFC3164 or RFC5424 syslog messages
@@ -10,25 +10,22 @@ import (
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
-	"github.com/your-username/click-lite-log-analytics/backend/internal/websocket"
 )
 
 // TCPServer handles TCP log ingestion
 type TCPServer struct {
 	addr           string
 	batchProcessor *BatchProcessor
-	wsHub          *websocket.Hub
 	listener       net.Listener
 	stopChan       chan struct{}
 	wg             sync.WaitGroup
 }
 
 // NewTCPServer creates a new TCP ingestion server
-func NewTCPServer(addr string, batchProcessor *BatchProcessor, wsHub *websocket.Hub) *TCPServer {
+func NewTCPServer(addr string, batchProcessor *BatchProcessor) *TCPServer {
 	return &TCPServer{
 		addr:           addr,
 		batchProcessor: batchProcessor,
-		wsHub:          wsHub,
 		stopChan:       make(chan struct{}),
 	}
 }
@@ -140,11 +137,8 @@ func (s *TCPServer) processLog(logEntry *models.Log) {
 		logEntry.Service = "tcp-client"
 	}
 	
-	// Add to batch processor
+	// Add to batch processor, which publishes it to live tails
 	s.batchProcessor.Add(*logEntry)
-	
-	// Broadcast to WebSocket clients
-	s.wsHub.BroadcastLog(logEntry)
 }
 
 // Stop gracefully shuts down the TCP server
@@ -0,0 +1,55 @@
+package ingestion
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const instrumentationName = "github.com/your-username/click-lite-log-analytics/backend/internal/ingestion"
+
+var (
+	tracer = otel.Tracer(instrumentationName)
+	meter  = otel.Meter(instrumentationName)
+
+	flushDuration, _ = meter.Float64Histogram("clicklite.ingestion.flush.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of batch writes to ClickHouse, retries included"),
+		metric.WithExplicitBucketBoundaries(0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10))
+	batchSize, _ = meter.Int64Histogram("clicklite.ingestion.batch.size",
+		metric.WithUnit("{log}"),
+		metric.WithDescription("Logs per batch written to ClickHouse"),
+		metric.WithExplicitBucketBoundaries(1, 10, 50, 100, 250, 500, 1000, 5000))
+	logsWritten, _ = meter.Int64Counter("clicklite.ingestion.logs.written",
+		metric.WithUnit("{log}"),
+		metric.WithDescription("Logs written to ClickHouse"))
+	logsDropped, _ = meter.Int64Counter("clicklite.ingestion.logs.dropped",
+		metric.WithUnit("{log}"),
+		metric.WithDescription("Logs given up on after their batch failed every retry"))
+)
+
+// observeQueueDepth reports the logs waiting in the queue of bp as the
+// clicklite.ingestion.queue.depth gauge, until the returned registration is
+// unregistered
+func observeQueueDepth(bp *BatchProcessor) metric.Registration {
+	depth, err := meter.Int64ObservableGauge("clicklite.ingestion.queue.depth",
+		metric.WithUnit("{log}"),
+		metric.WithDescription("Logs waiting to be written to ClickHouse"))
+	if err != nil {
+		otel.Handle(err)
+		return nil
+	}
+	registration, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		bp.bufferMu.Lock()
+		n := len(bp.buffer)
+		bp.bufferMu.Unlock()
+		o.ObserveInt64(depth, int64(n))
+		return nil
+	}, depth)
+	if err != nil {
+		otel.Handle(err)
+		return nil
+	}
+	return registration
+}
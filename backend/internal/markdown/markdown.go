@@ -0,0 +1,236 @@
+// Package markdown renders the markdown of text widgets to HTML on the
+// server, so dashboards, shared links and reports show the same text.
+//
+// It covers what runbook-style text needs: headings, paragraphs, lists,
+// block quotes, fenced code, horizontal rules, tables, emphasis, inline
+// code and links. All text is HTML-escaped, so the output is safe to
+// insert into a page; links only keep http, https, mailto and relative
+// targets.
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	headingPattern   = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	ruleCharacters   = regexp.MustCompile(`^\s{0,3}([-*_])(\s*[-*_]){2,}\s*$`)
+	unorderedPattern = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedPattern   = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	tableDelimiter   = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+
+	linkPattern   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	strongPattern = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	emPattern     = regexp.MustCompile(`\*([^*\s][^*]*)\*|\b_([^_]+)_\b`)
+
+	placeholderPattern = regexp.MustCompile("\x00[0-9]+\x00")
+)
+
+// ToHTML renders markdown to HTML
+func ToHTML(source string) string {
+	var out strings.Builder
+	// NUL marks links set aside by formatted, so it cannot come from source
+	source = strings.ReplaceAll(strings.ReplaceAll(source, "\x00", ""), "\r\n", "\n")
+	renderBlocks(&out, strings.Split(source, "\n"))
+	return out.String()
+}
+
+// renderBlocks renders lines as a sequence of blocks
+func renderBlocks(out *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence := trimmed[:3]
+			i++
+			var code []string
+			for ; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			i++ // the closing fence, if any
+			out.WriteString("<pre><code>")
+			out.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			out.WriteString("</code></pre>\n")
+
+		case headingPattern.MatchString(trimmed):
+			match := headingPattern.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(match[1])))
+			out.WriteString("<h" + level + ">" + inline(match[2]) + "</h" + level + ">\n")
+			i++
+
+		case ruleCharacters.MatchString(line):
+			out.WriteString("<hr>\n")
+			i++
+
+		case strings.HasPrefix(trimmed, ">"):
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+			}
+			out.WriteString("<blockquote>\n")
+			renderBlocks(out, quoted)
+			out.WriteString("</blockquote>\n")
+
+		case unorderedPattern.MatchString(line):
+			i = renderList(out, lines, i, unorderedPattern, "ul")
+
+		case orderedPattern.MatchString(line):
+			i = renderList(out, lines, i, orderedPattern, "ol")
+
+		case strings.Contains(trimmed, "|") && i+1 < len(lines) && tableDelimiter.MatchString(lines[i+1]):
+			i = renderTable(out, lines, i)
+
+		default:
+			var paragraph []string
+			for ; i < len(lines) && startsParagraphLine(lines, i, len(paragraph) == 0); i++ {
+				paragraph = append(paragraph, strings.TrimSpace(lines[i]))
+			}
+			out.WriteString("<p>" + inline(strings.Join(paragraph, " ")) + "</p>\n")
+		}
+	}
+}
+
+// startsParagraphLine reports whether line i continues a paragraph, which
+// ends at a blank line or the start of another block
+func startsParagraphLine(lines []string, i int, first bool) bool {
+	if first {
+		return true
+	}
+	line := lines[i]
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" &&
+		!strings.HasPrefix(trimmed, "```") && !strings.HasPrefix(trimmed, "~~~") &&
+		!strings.HasPrefix(trimmed, ">") &&
+		!headingPattern.MatchString(trimmed) &&
+		!ruleCharacters.MatchString(line) &&
+		!unorderedPattern.MatchString(line) &&
+		!orderedPattern.MatchString(line)
+}
+
+// renderList renders the items starting at line i, an item's indented
+// lines continuing it, and returns the line after the list
+func renderList(out *strings.Builder, lines []string, i int, item *regexp.Regexp, tag string) int {
+	out.WriteString("<" + tag + ">\n")
+	for i < len(lines) {
+		match := item.FindStringSubmatch(lines[i])
+		if match == nil {
+			break
+		}
+		text := []string{strings.TrimSpace(match[1])}
+		for i++; i < len(lines) && isContinuation(lines[i], item); i++ {
+			text = append(text, strings.TrimSpace(lines[i]))
+		}
+		out.WriteString("<li>" + inline(strings.Join(text, " ")) + "</li>\n")
+	}
+	out.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// isContinuation reports whether line goes on with the list item before
+func isContinuation(line string, item *regexp.Regexp) bool {
+	return strings.TrimSpace(line) != "" &&
+		(strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) &&
+		!item.MatchString(line)
+}
+
+// renderTable renders the header at line i, the delimiter row after it and
+// the rows that follow, and returns the line after the table
+func renderTable(out *strings.Builder, lines []string, i int) int {
+	out.WriteString("<table>\n<thead>\n<tr>")
+	for _, cell := range tableCells(lines[i]) {
+		out.WriteString("<th>" + inline(cell) + "</th>")
+	}
+	out.WriteString("</tr>\n</thead>\n<tbody>\n")
+	for i += 2; i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != ""; i++ {
+		out.WriteString("<tr>")
+		for _, cell := range tableCells(lines[i]) {
+			out.WriteString("<td>" + inline(cell) + "</td>")
+		}
+		out.WriteString("</tr>\n")
+	}
+	out.WriteString("</tbody>\n</table>\n")
+	return i
+}
+
+func tableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// inline escapes text and renders its code spans, links and emphasis
+func inline(text string) string {
+	var out strings.Builder
+	for {
+		start := strings.Index(text, "`")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(text[start+1:], "`")
+		if end < 0 {
+			break
+		}
+		out.WriteString(formatted(text[:start]))
+		out.WriteString("<code>" + html.EscapeString(text[start+1:start+1+end]) + "</code>")
+		text = text[start+end+2:]
+	}
+	out.WriteString(formatted(text))
+	return out.String()
+}
+
+// formatted escapes text without code spans and renders its links and
+// emphasis
+func formatted(text string) string {
+	// Links are set aside while emphasis is rendered, which would otherwise
+	// also match in their targets
+	var links []string
+	text = linkPattern.ReplaceAllStringFunc(text, func(link string) string {
+		match := linkPattern.FindStringSubmatch(link)
+		label := emphasis(html.EscapeString(match[1]))
+		if safeURL(match[2]) {
+			label = `<a href="` + html.EscapeString(match[2]) + `" rel="noopener noreferrer">` + label + `</a>`
+		}
+		links = append(links, label)
+		return "\x00" + strconv.Itoa(len(links)-1) + "\x00"
+	})
+	text = emphasis(html.EscapeString(text))
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		i, _ := strconv.Atoi(strings.Trim(placeholder, "\x00"))
+		return links[i]
+	})
+}
+
+func emphasis(text string) string {
+	text = strongPattern.ReplaceAllString(text, "<strong>$1$2</strong>")
+	return emPattern.ReplaceAllString(text, "<em>$1$2</em>")
+}
+
+// safeURL reports whether a link target is relative or uses http, https
+// or mailto, leaving out javascript: and other schemes
+func safeURL(target string) bool {
+	scheme := target
+	if end := strings.IndexAny(target, "/?#"); end >= 0 {
+		scheme = target[:end]
+	}
+	colon := strings.Index(scheme, ":")
+	if colon < 0 {
+		return true
+	}
+	switch strings.ToLower(scheme[:colon]) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
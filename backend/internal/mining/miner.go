@@ -0,0 +1,368 @@
+// Package mining discovers the templates of log messages, so that the kinds
+// of logs a system sends can be listed without writing a parser for each.
+package mining
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+const (
+	// Wildcard stands for the tokens that vary between the messages of a
+	// pattern
+	Wildcard = "<*>"
+
+	// DefaultMaxPatterns bounds the patterns kept; the least recently seen
+	// one is dropped when full
+	DefaultMaxPatterns = 5000
+	// DefaultSimilarity is the share of its tokens a message must have in
+	// common with a pattern to join it
+	DefaultSimilarity = 0.4
+
+	// prefixDepth is how many leading tokens route a message in the tree,
+	// after its token count
+	prefixDepth = 2
+	// maxChildren bounds the distinct tokens under a node; further tokens
+	// share the wildcard child
+	maxChildren = 100
+	// maxTokens bounds the tokens of a message that are compared
+	maxTokens = 64
+	// maxExamples is how many distinct messages a pattern keeps as examples
+	maxExamples = 3
+)
+
+// Pattern is a template shared by log messages, their varying tokens
+// replaced with the wildcard
+type Pattern struct {
+	ID        string           `json:"id"`
+	Template  string           `json:"template"`
+	Count     int64            `json:"count"`
+	FirstSeen time.Time        `json:"first_seen"`
+	LastSeen  time.Time        `json:"last_seen"`
+	Services  map[string]int64 `json:"services"`
+	Levels    map[string]int64 `json:"levels"`
+	// Examples are the latest distinct messages of the pattern, oldest
+	// first
+	Examples []string `json:"examples"`
+	// Share is the part of the logs mined that matched the pattern, from 0
+	// to 1
+	Share float64 `json:"share"`
+}
+
+// Miner clusters log messages into patterns with the Drain algorithm: a
+// message is routed by its token count and first tokens to a few candidate
+// patterns, joins the most similar one, generalizing it, or starts its own.
+type Miner struct {
+	mu          sync.Mutex
+	root        map[int]*node // by token count
+	patterns    map[string]*pattern
+	nextID      int64
+	total       int64
+	maxPatterns int
+	similarity  float64
+}
+
+type node struct {
+	children map[string]*node
+	patterns []*pattern // on the leaves
+}
+
+type pattern struct {
+	Pattern
+	tokens []string
+	leaf   *node
+}
+
+// NewMiner creates a miner keeping up to maxPatterns patterns, merging a
+// message into a pattern when they share at least the similarity share of
+// their tokens
+func NewMiner(maxPatterns int, similarity float64) *Miner {
+	if maxPatterns <= 0 {
+		maxPatterns = DefaultMaxPatterns
+	}
+	if similarity <= 0 || similarity > 1 {
+		similarity = DefaultSimilarity
+	}
+	return &Miner{
+		root:        make(map[int]*node),
+		patterns:    make(map[string]*pattern),
+		maxPatterns: maxPatterns,
+		similarity:  similarity,
+	}
+}
+
+// ProcessLog adds the message of a log to its pattern and returns the ID of
+// the pattern, "" for empty messages
+func (m *Miner) ProcessLog(log *models.Log) string {
+	tokens := tokenize(log.Message)
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	leaf := m.leaf(tokens)
+	p := m.match(leaf, tokens)
+	if p == nil {
+		if len(m.patterns) >= m.maxPatterns {
+			m.evictOldest()
+		}
+		m.nextID++
+		p = &pattern{
+			Pattern: Pattern{
+				ID:        fmt.Sprintf("p%d", m.nextID),
+				FirstSeen: log.Timestamp,
+				LastSeen:  log.Timestamp,
+				Services:  make(map[string]int64),
+				Levels:    make(map[string]int64),
+			},
+			tokens: tokens,
+			leaf:   leaf,
+		}
+		leaf.patterns = append(leaf.patterns, p)
+		m.patterns[p.ID] = p
+	} else {
+		p.merge(tokens)
+	}
+
+	m.total++
+	p.Count++
+	if log.Timestamp.Before(p.FirstSeen) {
+		p.FirstSeen = log.Timestamp
+	}
+	if log.Timestamp.After(p.LastSeen) {
+		p.LastSeen = log.Timestamp
+	}
+	if log.Service != "" {
+		p.Services[log.Service]++
+	}
+	if log.Level != "" {
+		p.Levels[strings.ToLower(log.Level)]++
+	}
+	p.addExample(log.Message)
+	return p.ID
+}
+
+// ProcessBatch adds the messages of logs to their patterns
+func (m *Miner) ProcessBatch(logs []models.Log) {
+	for i := range logs {
+		m.ProcessLog(&logs[i])
+	}
+}
+
+// tokenize splits the first line of a message into the tokens compared
+func tokenize(message string) []string {
+	if i := strings.IndexByte(message, '\n'); i >= 0 {
+		message = message[:i]
+	}
+	tokens := strings.Fields(message)
+	if len(tokens) > maxTokens {
+		tokens = tokens[:maxTokens]
+	}
+	return tokens
+}
+
+// leaf returns the leaf a message is routed to, creating the nodes on its
+// way. Tokens with digits are routed as wildcards, since they are likely
+// IDs, counts or durations. Callers hold the lock.
+func (m *Miner) leaf(tokens []string) *node {
+	n, ok := m.root[len(tokens)]
+	if !ok {
+		n = &node{children: make(map[string]*node)}
+		m.root[len(tokens)] = n
+	}
+	for i := 0; i < prefixDepth && i < len(tokens); i++ {
+		token := tokens[i]
+		if hasDigit(token) {
+			token = Wildcard
+		}
+		child, ok := n.children[token]
+		if !ok {
+			if len(n.children) >= maxChildren {
+				token = Wildcard
+				child = n.children[token]
+			}
+			if child == nil {
+				child = &node{children: make(map[string]*node)}
+				n.children[token] = child
+			}
+		}
+		n = child
+	}
+	return n
+}
+
+// match returns the pattern of a leaf most similar to the tokens, nil when
+// none is similar enough. Callers hold the lock.
+func (m *Miner) match(leaf *node, tokens []string) *pattern {
+	var best *pattern
+	bestSimilarity, bestWildcards := -1.0, 0
+	for _, p := range leaf.patterns {
+		similarity, wildcards := p.similarity(tokens)
+		if similarity > bestSimilarity || (similarity == bestSimilarity && wildcards > bestWildcards) {
+			best, bestSimilarity, bestWildcards = p, similarity, wildcards
+		}
+	}
+	if best == nil || bestSimilarity < m.similarity {
+		return nil
+	}
+	return best
+}
+
+// similarity returns the share of the tokens equal to those of the pattern
+// and how many wildcards the pattern has
+func (p *pattern) similarity(tokens []string) (float64, int) {
+	same, wildcards := 0, 0
+	for i, token := range p.tokens {
+		if token == Wildcard {
+			wildcards++
+			continue
+		}
+		if token == tokens[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens)), wildcards
+}
+
+// merge replaces the tokens of the pattern that differ from those of a
+// message with wildcards
+func (p *pattern) merge(tokens []string) {
+	for i, token := range p.tokens {
+		if token != Wildcard && token != tokens[i] {
+			p.tokens[i] = Wildcard
+		}
+	}
+}
+
+// addExample remembers a message as the latest example of the pattern
+func (p *pattern) addExample(message string) {
+	for i, example := range p.Examples {
+		if example == message {
+			p.Examples = append(p.Examples[:i], p.Examples[i+1:]...)
+			break
+		}
+	}
+	if len(p.Examples) == maxExamples {
+		p.Examples = p.Examples[1:]
+	}
+	p.Examples = append(p.Examples, message)
+}
+
+// evictOldest drops the pattern seen least recently. Callers hold the lock.
+func (m *Miner) evictOldest() {
+	var oldest *pattern
+	for _, p := range m.patterns {
+		if oldest == nil || p.LastSeen.Before(oldest.LastSeen) {
+			oldest = p
+		}
+	}
+	if oldest == nil {
+		return
+	}
+	delete(m.patterns, oldest.ID)
+	for i, p := range oldest.leaf.patterns {
+		if p == oldest {
+			oldest.leaf.patterns = append(oldest.leaf.patterns[:i], oldest.leaf.patterns[i+1:]...)
+			break
+		}
+	}
+}
+
+// snapshot returns a copy of the pattern. Callers hold the lock.
+func (m *Miner) snapshot(p *pattern) Pattern {
+	c := p.Pattern
+	c.Template = strings.Join(p.tokens, " ")
+	c.Services = make(map[string]int64, len(p.Services))
+	for service, count := range p.Services {
+		c.Services[service] = count
+	}
+	c.Levels = make(map[string]int64, len(p.Levels))
+	for level, count := range p.Levels {
+		c.Levels[level] = count
+	}
+	c.Examples = append([]string(nil), p.Examples...)
+	if m.total > 0 {
+		c.Share = float64(p.Count) / float64(m.total)
+	}
+	return c
+}
+
+// Filter selects patterns
+type Filter struct {
+	Service string // patterns of logs from the service
+	Level   string
+	Search  string // in the template, case insensitive
+	// Sort is count, the default, first_seen for the newest patterns first
+	// or last_seen for the most recent first
+	Sort  string
+	Limit int // unlimited when not positive
+}
+
+// Patterns returns the patterns matching filter, the most frequent first
+// unless sorted otherwise, and the number of logs mined
+func (m *Miner) Patterns(filter Filter) ([]Pattern, int64) {
+	search := strings.ToLower(filter.Search)
+	level := strings.ToLower(filter.Level)
+
+	m.mu.Lock()
+	patterns := make([]Pattern, 0, len(m.patterns))
+	for _, p := range m.patterns {
+		if filter.Service != "" && p.Services[filter.Service] == 0 {
+			continue
+		}
+		if level != "" && p.Levels[level] == 0 {
+			continue
+		}
+		snapshot := m.snapshot(p)
+		if search != "" && !strings.Contains(strings.ToLower(snapshot.Template), search) {
+			continue
+		}
+		patterns = append(patterns, snapshot)
+	}
+	total := m.total
+	m.mu.Unlock()
+
+	sort.Slice(patterns, func(i, j int) bool {
+		switch filter.Sort {
+		case "first_seen":
+			if !patterns[i].FirstSeen.Equal(patterns[j].FirstSeen) {
+				return patterns[i].FirstSeen.After(patterns[j].FirstSeen)
+			}
+		case "last_seen":
+			if !patterns[i].LastSeen.Equal(patterns[j].LastSeen) {
+				return patterns[i].LastSeen.After(patterns[j].LastSeen)
+			}
+		}
+		if patterns[i].Count != patterns[j].Count {
+			return patterns[i].Count > patterns[j].Count
+		}
+		return patterns[i].ID < patterns[j].ID
+	})
+	if filter.Limit > 0 && len(patterns) > filter.Limit {
+		patterns = patterns[:filter.Limit]
+	}
+	return patterns, total
+}
+
+// Pattern returns a pattern by ID
+func (m *Miner) Pattern(id string) (*Pattern, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.patterns[id]
+	if !ok {
+		return nil, false
+	}
+	snapshot := m.snapshot(p)
+	return &snapshot, true
+}
+
+func hasDigit(token string) bool {
+	return strings.ContainsAny(token, "0123456789")
+}
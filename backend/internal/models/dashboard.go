@@ -14,21 +14,51 @@ type Dashboard struct {
 	Settings    DashboardSettings `json:"settings"`
 	SharedWith  []string          `json:"shared_with,omitempty"`
 	IsPublic    bool              `json:"is_public"`
+	FolderID    string            `json:"folder_id,omitempty"` // top level when empty
+	Permissions []DashboardPermission `json:"permissions,omitempty"`
+	AnnotationQueries []AnnotationQuery `json:"annotation_queries,omitempty"`
+	IsTemplate  bool              `json:"is_template"` // cloned with chosen variables rather than used directly
+	Category    string            `json:"category,omitempty"`
 	CreatedAt   time.Time         `json:"created_at"`
 	UpdatedAt   time.Time         `json:"updated_at"`
 	CreatedBy   string            `json:"created_by"`
 }
 
+// DashboardFolder groups dashboards and other folders. Access granted on a
+// folder extends to everything inside it.
+type DashboardFolder struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	ParentID    string    `json:"parent_id,omitempty"` // top level when empty
+	SharedWith  []string  `json:"shared_with,omitempty"`
+	IsPublic    bool                  `json:"is_public"`
+	Permissions []DashboardPermission `json:"permissions,omitempty"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+	CreatedBy   string                `json:"created_by"`
+}
+
+// DashboardPermission grants a role on a dashboard or folder to a user or
+// to every member of a team. Shared and public dashboards and folders can
+// also be viewed by the users listed in SharedWith and by everyone.
+type DashboardPermission struct {
+	User string `json:"user,omitempty"`
+	Team string `json:"team,omitempty"`
+	Role string `json:"role"` // viewer, editor, admin
+}
+
 // DashboardWidget represents a widget on the dashboard
 type DashboardWidget struct {
 	ID         string            `json:"id"`
-	Type       string            `json:"type"` // chart, table, metric, text
+	Type       string            `json:"type"` // chart, table, metric, text, histogram, heatmap, pie, log_stream
 	Title      string            `json:"title"`
 	Position   WidgetPosition    `json:"position"`
 	Size       WidgetSize        `json:"size"`
 	Config     WidgetConfig      `json:"config"`
 	DataSource WidgetDataSource  `json:"data_source"`
 	RefreshRate int              `json:"refresh_rate,omitempty"` // seconds, 0 = no auto-refresh
+	IgnoreDashboardTimeRange bool `json:"ignore_dashboard_time_range,omitempty"` // keep the widget's own time range
 }
 
 // WidgetPosition represents widget position on the dashboard
@@ -56,6 +86,37 @@ type WidgetConfig struct {
 	ValueFormat   string                 `json:"value_format,omitempty"`
 	Threshold     *ThresholdConfig       `json:"threshold,omitempty"`
 	CustomOptions map[string]interface{} `json:"custom_options,omitempty"`
+
+	// Columns the histogram, heatmap and pie widgets read. Values are the
+	// last numeric column, labels the first other column and times the
+	// first column when empty.
+	ValueField string `json:"value_field,omitempty"`
+	LabelField string `json:"label_field,omitempty"`
+	TimeField  string `json:"time_field,omitempty"`
+
+	Bins       int         `json:"bins,omitempty"`        // histogram buckets, 20 when empty
+	MaxSlices  int         `json:"max_slices,omitempty"`  // pie slices before the rest are grouped as other, 10 when empty
+	Donut      bool        `json:"donut,omitempty"`       // draws a pie as a donut
+	LogFilters []LogFilter `json:"log_filters,omitempty"` // live logs a log_stream widget appends
+
+	// AnnotationTags limits the annotations drawn on a widget to those
+	// with one of the tags. All are drawn when empty.
+	AnnotationTags []string `json:"annotation_tags,omitempty"`
+
+	Alert     *WidgetAlert     `json:"alert,omitempty"`      // evaluates metric and chart widgets on the server
+	DrillDown *WidgetDrillDown `json:"drill_down,omitempty"` // links clicked points to the logs behind them
+
+	// Content is the markdown of a text widget. {{name}} is replaced by
+	// the value of the dashboard variable name and {{query:id}} by the
+	// first value of saved query id, or {{query:id.column}} by a column's.
+	Content string `json:"content,omitempty"`
+}
+
+// TextData is the content of a text widget, filled in and rendered
+type TextData struct {
+	Markdown string   `json:"markdown"`
+	HTML     string   `json:"html"`
+	Errors   []string `json:"errors,omitempty"` // queries that failed, shown as n/a
 }
 
 // AxisConfig represents chart axis configuration
@@ -74,6 +135,37 @@ type ThresholdConfig struct {
 	ShowLine  bool    `json:"show_line"`
 }
 
+// WidgetAlert raises an alert when a widget's value crosses its warning or
+// critical threshold, and resolves it once the value is back. The value is
+// Field, or the widget's value column, in the first row of a metric widget
+// and the last row, the latest point, of a chart widget.
+type WidgetAlert struct {
+	Field    string   `json:"field,omitempty"`
+	Operator string   `json:"operator"` // >, >=, <, <=
+	Warning  *float64 `json:"warning,omitempty"`
+	Critical *float64 `json:"critical,omitempty"`
+	Interval int      `json:"interval,omitempty"` // seconds between evaluations, 60 when 0
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+// WidgetDrillDown configures the log search a clicked series or point of
+// a widget links to. The clicked value filters Field, the first group by
+// field of a query builder widget or its label field when empty, and the
+// clicked time narrows the search to its time bucket.
+type WidgetDrillDown struct {
+	Field  string `json:"field,omitempty"`
+	Query  string `json:"query,omitempty"`  // search terms always added, e.g. level:error
+	Bucket int    `json:"bucket,omitempty"` // seconds, the query builder's interval when 0
+}
+
+// DrillDownLink is a log search a widget drills down to
+type DrillDownLink struct {
+	URL       string    `json:"url"`
+	Query     string    `json:"query"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
 // WidgetDataSource represents the data source for a widget
 type WidgetDataSource struct {
 	Type         string                 `json:"type"` // query_builder, saved_query, custom_sql
@@ -83,12 +175,34 @@ type WidgetDataSource struct {
 	Parameters   map[string]interface{} `json:"parameters,omitempty"`
 }
 
-// DashboardLayout represents overall dashboard layout
+// DashboardLayout represents overall dashboard layout. The positions and
+// sizes of the widgets are the layout of the widest screens; Breakpoints
+// hold the placements for narrower ones.
 type DashboardLayout struct {
 	Columns    int    `json:"columns"`
 	RowHeight  int    `json:"row_height"`
 	GridGap    int    `json:"grid_gap"`
 	AutoLayout bool   `json:"auto_layout"`
+	Breakpoints []LayoutBreakpoint `json:"breakpoints,omitempty"`
+}
+
+// LayoutBreakpoint is the grid of screens at most MaxWidth pixels wide,
+// down to the next narrower breakpoint's, with a placement for every
+// widget
+type LayoutBreakpoint struct {
+	Name       string            `json:"name"` // e.g. md, sm, xs
+	MaxWidth   int               `json:"max_width"`
+	Columns    int               `json:"columns"`
+	Placements []WidgetPlacement `json:"placements"`
+}
+
+// WidgetPlacement is where a widget sits on a breakpoint's grid
+type WidgetPlacement struct {
+	WidgetID string `json:"widget_id"`
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
 }
 
 // DashboardSettings represents dashboard-wide settings
@@ -99,7 +213,8 @@ type DashboardSettings struct {
 	Variables       []DashboardVariable `json:"variables,omitempty"`
 }
 
-// DashboardVariable represents a dashboard variable
+// DashboardVariable represents a dashboard variable. Its value is passed
+// to the saved query and SQL widgets as the parameter of the same name.
 type DashboardVariable struct {
 	Name         string   `json:"name"`
 	Type         string   `json:"type"` // text, select, multi_select, time_range
@@ -109,6 +224,81 @@ type DashboardVariable struct {
 	Query        string   `json:"query,omitempty"` // for dynamic options
 }
 
+// WidgetUpdate is the latest data of a widget, pushed to the clients
+// watching its dashboard
+type WidgetUpdate struct {
+	DashboardID string      `json:"dashboard_id"`
+	WidgetID    string      `json:"widget_id"`
+	Type        string      `json:"type"`
+	Data        interface{} `json:"data,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// Annotation marks a moment or a period on the charts of a dashboard, or
+// of every dashboard when DashboardID is empty
+type Annotation struct {
+	ID          string     `json:"id"`
+	DashboardID string     `json:"dashboard_id,omitempty"`
+	Kind        string     `json:"kind"` // event, deploy, query, alert
+	Title       string     `json:"title"`
+	Text        string     `json:"text,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	Time        time.Time  `json:"time"`
+	EndTime     *time.Time `json:"end_time,omitempty"` // a single moment when nil
+	QueryID     string     `json:"query_id,omitempty"` // the annotation query that found it
+	CreatedAt   time.Time  `json:"created_at,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+}
+
+// AnnotationQuery derives annotations of a dashboard from the rows of a
+// query, e.g. the minutes with an error spike, run over the time range the
+// dashboard shows. Fields name the columns read; times are the first
+// column and titles the query's name when empty.
+type AnnotationQuery struct {
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	DataSource WidgetDataSource `json:"data_source"`
+	TimeField  string           `json:"time_field,omitempty"`
+	EndField   string           `json:"end_field,omitempty"`
+	TitleField string           `json:"title_field,omitempty"`
+	TextField  string           `json:"text_field,omitempty"`
+	Tags       []string         `json:"tags,omitempty"`
+	Disabled   bool             `json:"disabled,omitempty"`
+}
+
+// DashboardSnapshot is a dashboard with the data its widgets showed when
+// it was taken, readable through its share link without running queries
+type DashboardSnapshot struct {
+	ID          string           `json:"id"`
+	DashboardID string           `json:"dashboard_id"`
+	Name        string           `json:"name"`
+	Dashboard   Dashboard        `json:"dashboard"`
+	TimeRange   *QueryTimeRange  `json:"time_range,omitempty"` // the absolute dashboard time range the widgets read
+	Widgets     []SnapshotWidget `json:"widgets"`
+	ShareToken  string           `json:"share_token"`
+	ExpiresAt   *time.Time       `json:"expires_at,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	CreatedBy   string           `json:"created_by"`
+}
+
+// SnapshotWidget is the data of a widget in a snapshot, or why it has none
+type SnapshotWidget struct {
+	WidgetID    string           `json:"widget_id"`
+	Type        string           `json:"type"`
+	Data        interface{}      `json:"data,omitempty"`
+	Annotations []*Annotation    `json:"annotations,omitempty"`
+	Cache       *WidgetCacheInfo `json:"cache,omitempty"` // when served from the widget cache
+	Error       string           `json:"error,omitempty"`
+}
+
+// WidgetCacheInfo tells how fresh cached widget data is
+type WidgetCacheInfo struct {
+	CachedAt time.Time `json:"cached_at"`
+	Age      float64   `json:"age"`   // seconds since the data was loaded
+	Stale    bool      `json:"stale"` // being refreshed in the background
+}
+
 // DashboardShare represents sharing configuration
 type DashboardShare struct {
 	ID           string    `json:"id"`
@@ -116,8 +306,76 @@ type DashboardShare struct {
 	ShareToken   string    `json:"share_token"`
 	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
 	Permissions  []string  `json:"permissions"` // view, edit
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
 	CreatedAt    time.Time `json:"created_at"`
 	CreatedBy    string    `json:"created_by"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+}
+
+// ShareAccess is a use of a share link, or an attempt refused because the
+// link expired or was revoked
+type ShareAccess struct {
+	At         time.Time `json:"at"`
+	Resource   string    `json:"resource"` // what was read, e.g. dashboard
+	RemoteAddr string    `json:"remote_addr,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	Denied     string    `json:"denied,omitempty"` // why access was refused
+}
+
+// ShareAccessAudit is the record of how a share link has been used
+type ShareAccessAudit struct {
+	ShareID        string        `json:"share_id"`
+	AccessCount    int64         `json:"access_count"`
+	DeniedCount    int64         `json:"denied_count"`
+	LastAccessedAt *time.Time    `json:"last_accessed_at,omitempty"`
+	Accesses       []ShareAccess `json:"accesses"` // most recent first
+}
+
+// HistogramData is the distribution of a histogram widget's values over
+// equal width buckets
+type HistogramData struct {
+	Buckets []HistogramBucket `json:"buckets"`
+	Min     float64           `json:"min"`
+	Max     float64           `json:"max"`
+	Count   int               `json:"count"`
+}
+
+// HistogramBucket counts the values in [Lower, Upper), the last bucket
+// including Upper
+type HistogramBucket struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int     `json:"count"`
+}
+
+// HeatmapData is a grid of values by time (X) and category (Y).
+// Values[y][x] is the value of category Y[y] at time X[x].
+type HeatmapData struct {
+	X      []string    `json:"x"`
+	Y      []string    `json:"y"`
+	Values [][]float64 `json:"values"`
+	Max    float64     `json:"max"`
+}
+
+// PieData is the share of each label of a pie widget, largest first
+type PieData struct {
+	Slices []PieSlice `json:"slices"`
+	Total  float64    `json:"total"`
+	Donut  bool       `json:"donut,omitempty"`
+}
+
+// PieSlice is one label of a pie widget
+type PieSlice struct {
+	Label   string  `json:"label"`
+	Value   float64 `json:"value"`
+	Percent float64 `json:"percent"`
+}
+
+// LogStreamData is the latest logs of a log_stream widget. Clients
+// watching its dashboard are then sent the new logs matching Filters.
+type LogStreamData struct {
+	Logs    []map[string]interface{} `json:"logs"`
+	Filters []LogFilter              `json:"filters,omitempty"`
 }
 
 // ChartData represents data for chart widgets
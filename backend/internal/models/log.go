@@ -22,6 +22,7 @@ type LogQuery struct {
 	Level     string    `json:"level,omitempty"`
 	TraceID   string    `json:"trace_id,omitempty"`
 	Search    string    `json:"search,omitempty"`
+	Filter    string    `json:"-"` // trusted SQL predicate, e.g. a compiled search
 	Limit     int       `json:"limit"`
 	Offset    int       `json:"offset"`
 }
@@ -33,8 +34,24 @@ type LogFilter struct {
 }
 
 type WebSocketMessage struct {
-	Type    string      `json:"type"`
-	Action  string      `json:"action,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
-	Filters []LogFilter `json:"filters,omitempty"`
+	Version    int            `json:"v,omitempty"`
+	Type       string         `json:"type"`
+	ID         string         `json:"id,omitempty"` // of a request, echoed by its reply
+	Action     string         `json:"action,omitempty"`
+	Seq        uint64         `json:"seq,omitempty"`     // cursor of live logs, to resume from
+	Channel    string         `json:"channel,omitempty"` // of a live log sent to a channel
+	Highlights []LogHighlight `json:"highlights,omitempty"`
+	Data       interface{}    `json:"data,omitempty"`
+	Filters    []LogFilter    `json:"filters,omitempty"`
+}
+
+// LogHighlight is a match of a highlight rule in a field of a live log.
+// Start and End are offsets in UTF-16 code units, as JavaScript indexes
+// strings.
+type LogHighlight struct {
+	Rule  string `json:"rule"`
+	Field string `json:"field"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Color string `json:"color,omitempty"`
 }
\ No newline at end of file
@@ -9,20 +9,29 @@ type QueryBuilder struct {
 	ID          string                 `json:"id"`
 	Name        string                 `json:"name"`
 	Description string                 `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Table       string                 `json:"table,omitempty"` // logs when empty
+	Joins       []QueryJoin           `json:"joins,omitempty"`
 	Fields      []QueryField          `json:"fields"`
 	Filters     []QueryBuilderFilter  `json:"filters"`
 	Aggregations []QueryAggregation   `json:"aggregations"`
+	Computed    []QueryComputedField  `json:"computed,omitempty"`
 	GroupBy     []string              `json:"group_by"`
+	Having      []QueryBuilderFilter  `json:"having,omitempty"` // on aggregation aliases and group by columns
 	OrderBy     []QueryOrderBy        `json:"order_by"`
 	Limit       int                   `json:"limit,omitempty"`
 	TimeRange   *QueryTimeRange       `json:"time_range,omitempty"`
+	TimeBucket  *QueryTimeBucket      `json:"time_bucket,omitempty"` // groups by time before GroupBy
+	TopN        *QueryTopN            `json:"top_n,omitempty"`
+	Freshness   int                   `json:"freshness,omitempty"` // seconds a cached result may be old, shared by identical queries
 	GeneratedSQL string               `json:"generated_sql,omitempty"`
 	CreatedAt   time.Time             `json:"created_at"`
 	UpdatedAt   time.Time             `json:"updated_at"`
 	CreatedBy   string                `json:"created_by"`
 }
 
-// QueryField represents a selected field in the query
+// QueryField represents a selected field in the query. Attribute fields
+// are named attributes.<key> or attributes['key'] and read as their type.
 type QueryField struct {
 	Name     string `json:"name"`
 	Type     string `json:"type"` // string, number, date, boolean
@@ -30,14 +39,17 @@ type QueryField struct {
 	Selected bool   `json:"selected"`
 }
 
-// QueryBuilderFilter represents a filter condition
+// QueryBuilderFilter represents a filter condition, or a group of nested
+// filters when Filters is set. LogicalOp joins it to the filter before it.
 type QueryBuilderFilter struct {
 	ID       string      `json:"id"`
 	Field    string      `json:"field"`
-	Operator string      `json:"operator"` // equals, not_equals, contains, not_contains, greater_than, less_than, between, in, not_in
+	Operator string      `json:"operator"` // equals, not_equals, contains, not_contains, icontains, matches_regex, not_matches_regex, greater_than, less_than, between, in, not_in
 	Value    interface{} `json:"value"`
 	Values   []interface{} `json:"values,omitempty"` // for 'in', 'not_in', 'between'
 	LogicalOp string     `json:"logical_op,omitempty"` // AND, OR
+	Type     string      `json:"type,omitempty"` // attribute fields are cast to it: string, number, date, boolean
+	Filters  []QueryBuilderFilter `json:"filters,omitempty"` // makes this a group; Field and Operator are then unused
 }
 
 // QueryAggregation represents an aggregation function
@@ -46,6 +58,17 @@ type QueryAggregation struct {
 	Function string `json:"function"` // COUNT, SUM, AVG, MIN, MAX, COUNT_DISTINCT
 	Field    string `json:"field,omitempty"`
 	Alias    string `json:"alias,omitempty"`
+	Type     string `json:"type,omitempty"` // attribute fields are cast to it, number for SUM and AVG
+}
+
+// QueryComputedField is a column derived from fields and aggregation
+// aliases, e.g. error_rate = errors / total * 100. Expressions allow
+// arithmetic, comparisons, AND/OR/NOT and a set of functions such as if()
+// and toFloat64OrNull().
+type QueryComputedField struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	Label      string `json:"label,omitempty"`
 }
 
 // QueryOrderBy represents ordering
@@ -58,7 +81,44 @@ type QueryOrderBy struct {
 type QueryTimeRange struct {
 	Start    time.Time `json:"start"`
 	End      time.Time `json:"end"`
-	Relative string    `json:"relative,omitempty"` // last_1h, last_24h, last_7d, last_30d
+	Relative string    `json:"relative,omitempty"` // last_<n><unit> (s, m, h, d, w), today, yesterday, this_week, previous_week, this_month, previous_month
+	Compare  string    `json:"compare,omitempty"`  // previous_period, previous_day, previous_week
+}
+
+// ResolvedTimeRange is the absolute range a query read, and the range it
+// was compared with
+type ResolvedTimeRange struct {
+	Start        time.Time  `json:"start"`
+	End          time.Time  `json:"end"`
+	Relative     string     `json:"relative,omitempty"`
+	Compare      string     `json:"compare,omitempty"`
+	CompareStart *time.Time `json:"compare_start,omitempty"`
+	CompareEnd   *time.Time `json:"compare_end,omitempty"`
+}
+
+// QueryTimeBucket groups rows into fixed intervals of the table's time
+// column, generating toStartOfInterval
+type QueryTimeBucket struct {
+	Interval  string `json:"interval,omitempty"`   // e.g. "5m"; chosen from the time range when empty
+	MaxPoints int    `json:"max_points,omitempty"` // upper bound on buckets when choosing the interval
+	Alias     string `json:"alias,omitempty"`      // "time" when empty
+	FillGaps  bool   `json:"fill_gaps,omitempty"`  // adds empty buckets, over the time range if set
+}
+
+// QueryTopN keeps the Limit groups of a GroupBy field ranking highest by
+// an aggregation and rolls the rest into one other group
+type QueryTopN struct {
+	Field      string `json:"field"`
+	Limit      int    `json:"limit"`
+	By         string `json:"by,omitempty"`          // aggregation alias, the first aggregation when empty
+	Alias      string `json:"alias,omitempty"`       // <field>_group when empty
+	OtherLabel string `json:"other_label,omitempty"` // "other" when empty
+}
+
+// QueryJoin joins a registered lookup table, whose columns can then be
+// used as fields named <lookup>.<column>
+type QueryJoin struct {
+	Lookup string `json:"lookup"`
 }
 
 // QueryBuilderResponse represents the result of executing a query builder
@@ -69,6 +129,8 @@ type QueryBuilderResponse struct {
 	RowCount     int                      `json:"row_count"`
 	ExecutionTime int64                   `json:"execution_time_ms"`
 	Error        string                   `json:"error,omitempty"`
+	TimeRange    *ResolvedTimeRange       `json:"time_range,omitempty"`
+	ComparisonRows []map[string]interface{} `json:"comparison_rows,omitempty"` // the query over TimeRange's comparison period
 }
 
 // QueryResultColumn represents metadata about result columns
@@ -80,5 +142,31 @@ type QueryResultColumn struct {
 
 // AvailableFields represents the schema information for query building
 type AvailableFields struct {
+	Table      string       `json:"table"`
+	Fields     []QueryField `json:"fields"`
+	Tables     []string     `json:"tables,omitempty"`     // every table that can be queried
+	Attributes []QueryField `json:"attributes,omitempty"` // attribute fields seen during ingestion
+	Lookups    []QueryLookup `json:"lookups,omitempty"`   // lookup tables the table can be joined with
+}
+
+// FieldValue is a value of a field and how many recent rows have it
+type FieldValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// FieldValues are suggested values of a field, most frequent first
+type FieldValues struct {
+	Table  string       `json:"table"`
+	Field  string       `json:"field"`
+	Prefix string       `json:"prefix,omitempty"`
+	Values []FieldValue `json:"values"`
+}
+
+// QueryLookup is a lookup table joined on Field = Key, with its fields
+type QueryLookup struct {
+	Table  string       `json:"table"`
+	Key    string       `json:"key"`
+	Field  string       `json:"field"`
 	Fields []QueryField `json:"fields"`
 }
\ No newline at end of file
@@ -0,0 +1,220 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AbsenceSource is the source of the alerts raised when a service stops
+// logging
+const AbsenceSource = "absence"
+
+// maxTrackedServices bounds the services the absence monitor remembers, so
+// that logs with arbitrary service names cannot grow it forever
+const maxTrackedServices = 10000
+
+// ServiceActivity is when a service was last heard from, and whether it is
+// silent for longer than it may be
+type ServiceActivity struct {
+	Service   string    `json:"service"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Logs      uint64    `json:"logs"` // since the server started
+	// AbsenceAfter is how long the service may stay silent, in seconds, 0
+	// when it is not monitored
+	AbsenceAfter int  `json:"absence_after_seconds"`
+	Monitored    bool `json:"monitored"` // whether the service logs long enough to be expected to
+	Absent       bool `json:"absent"`
+}
+
+// serviceActivity is what the monitor tracks about a service
+type serviceActivity struct {
+	firstSeen time.Time
+	lastSeen  time.Time
+	logs      uint64
+	raised    bool // its absence alert is active
+}
+
+// AbsenceMonitor tracks when each service last logged and raises an alert
+// when a service that normally logs goes silent. A service is expected to
+// log once it has logged over a span as long as the silence that would
+// raise its alert, so that one-off senders do not raise alerts; services
+// with a threshold of their own are expected to log from the start.
+type AbsenceMonitor struct {
+	alerts *AlertManager
+	after  time.Duration // 0 to monitor only the services with a threshold
+
+	mu         sync.Mutex
+	services   map[string]*serviceActivity
+	thresholds map[string]time.Duration // by service, 0 to never monitor it
+}
+
+// NewAbsenceMonitor creates an absence monitor raising alerts in alerts
+// when a service logs nothing for after
+func NewAbsenceMonitor(alerts *AlertManager, after time.Duration) *AbsenceMonitor {
+	return &AbsenceMonitor{
+		alerts:     alerts,
+		after:      after,
+		services:   make(map[string]*serviceActivity),
+		thresholds: make(map[string]time.Duration),
+	}
+}
+
+// SetServiceThreshold sets how long service may log nothing before its
+// alert is raised, 0 to never raise it. The service is expected to log
+// from now on even if it has not yet.
+func (a *AbsenceMonitor) SetServiceThreshold(service string, after time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.thresholds[service] = after
+	if _, ok := a.services[service]; !ok && after > 0 {
+		now := time.Now()
+		a.services[service] = &serviceActivity{firstSeen: now, lastSeen: now}
+	}
+}
+
+// ParseAbsenceThreshold parses a service:minutes pair, such as checkout:5
+func ParseAbsenceThreshold(spec string) (string, time.Duration, error) {
+	service, value, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok || service == "" {
+		return "", 0, fmt.Errorf("invalid absence threshold %q: expected service:minutes", spec)
+	}
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes < 0 {
+		return "", 0, fmt.Errorf("invalid absence threshold %q: bad minutes %q", spec, value)
+	}
+	return service, time.Duration(minutes) * time.Minute, nil
+}
+
+// Observe records a log of service arriving
+func (a *AbsenceMonitor) Observe(service string) {
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	activity, ok := a.services[service]
+	if !ok {
+		if len(a.services) >= maxTrackedServices {
+			return
+		}
+		activity = &serviceActivity{firstSeen: now}
+		a.services[service] = activity
+	}
+	activity.lastSeen = now
+	activity.logs++
+}
+
+// Start checks for silent services on the interval until ctx is cancelled
+func (a *AbsenceMonitor) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.Check(time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// absence is a change to the alert of a service found by Check
+type absence struct {
+	service  string
+	lastSeen time.Time
+	after    time.Duration
+	absent   bool
+}
+
+// Check raises the alerts of the services silent for longer than they may
+// be at now, and resolves those of the services heard from again
+func (a *AbsenceMonitor) Check(now time.Time) {
+	a.mu.Lock()
+	var changes []absence
+	for service, activity := range a.services {
+		after := a.threshold(service)
+		absent := a.monitored(service, activity, after) && now.Sub(activity.lastSeen) >= after
+		if absent != activity.raised {
+			activity.raised = absent
+			changes = append(changes, absence{service: service, lastSeen: activity.lastSeen, after: after, absent: absent})
+		}
+	}
+	a.mu.Unlock()
+
+	// The alert manager notifies listeners, which is kept out of the lock
+	for _, change := range changes {
+		name := absenceAlertName(change.service)
+		if !change.absent {
+			a.alerts.ResolveAlert(name)
+			continue
+		}
+		silent := now.Sub(change.lastSeen).Truncate(time.Second)
+		a.alerts.RaiseAlert(name, SeverityWarning, AbsenceSource,
+			fmt.Sprintf("Service %s has not logged for %s (threshold: %s)", change.service, silent, change.after),
+			map[string]interface{}{
+				"service":        change.service,
+				"last_seen":      change.lastSeen,
+				"silent_seconds": int(silent.Seconds()),
+				"value":          silent.Minutes(),
+				"threshold":      change.after.Minutes(),
+			})
+	}
+}
+
+// Services returns the activity of the services heard from, the silent
+// ones first
+func (a *AbsenceMonitor) Services(now time.Time) []ServiceActivity {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	services := make([]ServiceActivity, 0, len(a.services))
+	for service, activity := range a.services {
+		after := a.threshold(service)
+		monitored := a.monitored(service, activity, after)
+		services = append(services, ServiceActivity{
+			Service:      service,
+			FirstSeen:    activity.firstSeen,
+			LastSeen:     activity.lastSeen,
+			Logs:         activity.logs,
+			AbsenceAfter: int(after.Seconds()),
+			Monitored:    monitored,
+			Absent:       monitored && now.Sub(activity.lastSeen) >= after,
+		})
+	}
+	sort.Slice(services, func(i, j int) bool {
+		if !services[i].LastSeen.Equal(services[j].LastSeen) {
+			return services[i].LastSeen.Before(services[j].LastSeen)
+		}
+		return services[i].Service < services[j].Service
+	})
+	return services
+}
+
+// threshold returns how long service may stay silent, 0 when it is not
+// monitored. Callers hold the lock.
+func (a *AbsenceMonitor) threshold(service string) time.Duration {
+	if after, ok := a.thresholds[service]; ok {
+		return after
+	}
+	return a.after
+}
+
+// monitored reports whether service is expected to log. Callers hold the
+// lock.
+func (a *AbsenceMonitor) monitored(service string, activity *serviceActivity, after time.Duration) bool {
+	if after <= 0 {
+		return false
+	}
+	if _, ok := a.thresholds[service]; ok {
+		return true
+	}
+	return activity.lastSeen.Sub(activity.firstSeen) >= after
+}
+
+func absenceAlertName(service string) string {
+	return fmt.Sprintf("log_absence_%s", service)
+}
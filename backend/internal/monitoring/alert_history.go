@@ -0,0 +1,355 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+)
+
+// AlertHistoryTable holds alert state transitions when ClickHouse storage
+// is enabled
+const AlertHistoryTable = "alert_history"
+
+const (
+	// maxAlertHistoryEntries bounds the transitions kept in memory
+	maxAlertHistoryEntries = 1000
+	// maxAlertHistoryLimit bounds the transitions returned at once
+	maxAlertHistoryLimit = 5000
+
+	alertHistoryTimeLayout = "2006-01-02 15:04:05.000"
+)
+
+// SQLExecutor runs statements against ClickHouse
+type SQLExecutor interface {
+	Execute(ctx context.Context, query string) error
+	ExecuteSQL(sql string) ([]map[string]interface{}, error)
+}
+
+// AlertTransition records an alert changing state: pending when a rule's
+// condition starts to hold, active once it has held long enough to fire,
+// resolved when it no longer holds. From is empty for a new alert.
+type AlertTransition struct {
+	AlertID  string        `json:"alert_id"`
+	Name     string        `json:"name"`
+	Severity AlertSeverity `json:"severity"`
+	Source   string        `json:"source"`
+	From     AlertStatus   `json:"from,omitempty"`
+	To       AlertStatus   `json:"to"`
+	Time     time.Time     `json:"time"`
+	Value    *float64      `json:"value,omitempty"` // the value details reported, if any
+	Message  string        `json:"message"`
+	Details  interface{}   `json:"details,omitempty"`
+}
+
+// AlertHistoryFilter selects alert transitions. Zero fields match all.
+type AlertHistoryFilter struct {
+	Name     string
+	Severity AlertSeverity
+	Source   string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+// AlertEpisode is the span of one alert on a timeline, from when it became
+// pending or fired to when it resolved
+type AlertEpisode struct {
+	AlertID    string        `json:"alert_id"`
+	Name       string        `json:"name"`
+	Severity   AlertSeverity `json:"severity"`
+	Source     string        `json:"source"`
+	PendingAt  *time.Time    `json:"pending_at,omitempty"`
+	FiredAt    *time.Time    `json:"fired_at,omitempty"`
+	ResolvedAt *time.Time    `json:"resolved_at,omitempty"`
+	Status     AlertStatus   `json:"status"` // the latest
+	Message    string        `json:"message"`
+	DurationMs int64         `json:"duration_ms"` // until resolved, or until now
+}
+
+// AlertHistory records alert state transitions. They are kept in memory,
+// or in ClickHouse once SetStorage is called.
+type AlertHistory struct {
+	mu      sync.RWMutex
+	entries []AlertTransition // oldest first
+	db      SQLExecutor
+}
+
+// NewAlertHistory creates an alert history kept in memory
+func NewAlertHistory() *AlertHistory {
+	return &AlertHistory{}
+}
+
+// SetStorage stores transitions in ClickHouse, creating the table if needed
+func (h *AlertHistory) SetStorage(db SQLExecutor) error {
+	ddl := fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		alert_id String,
+		name String,
+		severity LowCardinality(String),
+		source LowCardinality(String),
+		from_status LowCardinality(String),
+		to_status LowCardinality(String),
+		changed_at DateTime64(3),
+		value Nullable(Float64),
+		message String,
+		details String
+	) ENGINE = MergeTree()
+	ORDER BY (changed_at, name)
+	TTL toDateTime(changed_at) + INTERVAL 90 DAY
+	`, AlertHistoryTable)
+	if err := db.Execute(context.Background(), ddl); err != nil {
+		return fmt.Errorf("failed to create alert history table: %w", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.db = db
+	return nil
+}
+
+// record keeps a transition and stores it in the background
+func (h *AlertHistory) record(transition AlertTransition) {
+	h.mu.Lock()
+	h.entries = append(h.entries, transition)
+	if len(h.entries) > maxAlertHistoryEntries {
+		h.entries = h.entries[len(h.entries)-maxAlertHistoryEntries:]
+	}
+	db := h.db
+	h.mu.Unlock()
+
+	if db != nil {
+		go func() {
+			if err := h.store(db, transition); err != nil {
+				log.Error().Err(err).Str("alert_id", transition.AlertID).Msg("Failed to store alert transition")
+			}
+		}()
+	}
+}
+
+func (h *AlertHistory) store(db SQLExecutor, t AlertTransition) error {
+	value := "NULL"
+	if t.Value != nil {
+		value = fmt.Sprintf("%g", *t.Value)
+	}
+	details := ""
+	if t.Details != nil {
+		encoded, err := json.Marshal(t.Details)
+		if err != nil {
+			return fmt.Errorf("failed to encode alert details: %w", err)
+		}
+		details = string(encoded)
+	}
+
+	statement := fmt.Sprintf(`INSERT INTO %s (alert_id, name, severity, source, from_status, to_status, changed_at, value, message, details)
+	VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s)`,
+		AlertHistoryTable,
+		chsql.Quote(t.AlertID),
+		chsql.Quote(t.Name),
+		chsql.Quote(string(t.Severity)),
+		chsql.Quote(t.Source),
+		chsql.Quote(string(t.From)),
+		chsql.Quote(string(t.To)),
+		chsql.Quote(t.Time.UTC().Format(alertHistoryTimeLayout)),
+		value,
+		chsql.Quote(t.Message),
+		chsql.Quote(details),
+	)
+	return db.Execute(context.Background(), statement)
+}
+
+// List returns the transitions matching the filter, newest first
+func (h *AlertHistory) List(filter AlertHistoryFilter) ([]AlertTransition, error) {
+	if filter.Limit <= 0 || filter.Limit > maxAlertHistoryLimit {
+		filter.Limit = maxAlertHistoryLimit
+	}
+
+	h.mu.RLock()
+	db := h.db
+	h.mu.RUnlock()
+	if db != nil {
+		return h.load(db, filter)
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	result := make([]AlertTransition, 0)
+	for i := len(h.entries) - 1; i >= 0 && len(result) < filter.Limit; i-- {
+		if filter.matches(&h.entries[i]) {
+			result = append(result, h.entries[i])
+		}
+	}
+	return result, nil
+}
+
+func (f *AlertHistoryFilter) matches(t *AlertTransition) bool {
+	return (f.Name == "" || t.Name == f.Name) &&
+		(f.Severity == "" || t.Severity == f.Severity) &&
+		(f.Source == "" || t.Source == f.Source) &&
+		(f.Since.IsZero() || !t.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || !t.Time.After(f.Until))
+}
+
+func (h *AlertHistory) load(db SQLExecutor, filter AlertHistoryFilter) ([]AlertTransition, error) {
+	conditions := []string{"1 = 1"}
+	if filter.Name != "" {
+		conditions = append(conditions, "name = "+chsql.Quote(filter.Name))
+	}
+	if filter.Severity != "" {
+		conditions = append(conditions, "severity = "+chsql.Quote(string(filter.Severity)))
+	}
+	if filter.Source != "" {
+		conditions = append(conditions, "source = "+chsql.Quote(filter.Source))
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "changed_at >= "+chsql.Quote(filter.Since.UTC().Format(alertHistoryTimeLayout)))
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "changed_at <= "+chsql.Quote(filter.Until.UTC().Format(alertHistoryTimeLayout)))
+	}
+
+	rows, err := db.ExecuteSQL(fmt.Sprintf(`
+		SELECT alert_id, name, severity, source, from_status, to_status, changed_at, value, message, details
+		FROM %s
+		WHERE %s
+		ORDER BY changed_at DESC
+		LIMIT %d
+	`, AlertHistoryTable, strings.Join(conditions, " AND "), filter.Limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load alert history: %w", err)
+	}
+
+	transitions := make([]AlertTransition, 0, len(rows))
+	for _, row := range rows {
+		t := AlertTransition{
+			AlertID:  fmt.Sprint(row["alert_id"]),
+			Name:     fmt.Sprint(row["name"]),
+			Severity: AlertSeverity(fmt.Sprint(row["severity"])),
+			Source:   fmt.Sprint(row["source"]),
+			From:     AlertStatus(fmt.Sprint(row["from_status"])),
+			To:       AlertStatus(fmt.Sprint(row["to_status"])),
+		}
+		t.Message, _ = row["message"].(string)
+		if changedAt, ok := row["changed_at"].(string); ok {
+			t.Time, _ = time.Parse(alertHistoryTimeLayout, changedAt)
+		}
+		switch v := row["value"].(type) {
+		case float64:
+			t.Value = &v
+		case string:
+			var f float64
+			if _, err := fmt.Sscanf(v, "%g", &f); err == nil {
+				t.Value = &f
+			}
+		}
+		if details, ok := row["details"].(string); ok && details != "" {
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(details), &decoded); err == nil {
+				t.Details = decoded
+			}
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, nil
+}
+
+// Timeline returns the episodes of the alerts that changed state in the
+// filter's range, those that started first first
+func (h *AlertHistory) Timeline(filter AlertHistoryFilter) ([]AlertEpisode, error) {
+	transitions, err := h.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := make(map[string]*AlertEpisode)
+	var order []string
+	// Oldest first, so the latest transition sets the status
+	for i := len(transitions) - 1; i >= 0; i-- {
+		t := transitions[i]
+		episode, ok := episodes[t.AlertID]
+		if !ok {
+			episode = &AlertEpisode{
+				AlertID:  t.AlertID,
+				Name:     t.Name,
+				Severity: t.Severity,
+				Source:   t.Source,
+			}
+			episodes[t.AlertID] = episode
+			order = append(order, t.AlertID)
+		}
+		at := t.Time
+		switch t.To {
+		case AlertStatusPending:
+			episode.PendingAt = &at
+		case AlertStatusActive:
+			if episode.FiredAt == nil {
+				episode.FiredAt = &at
+			}
+		case AlertStatusResolved:
+			episode.ResolvedAt = &at
+		}
+		episode.Status = t.To
+		episode.Message = t.Message
+	}
+
+	now := time.Now()
+	result := make([]AlertEpisode, 0, len(order))
+	for _, id := range order {
+		episode := episodes[id]
+		start := episode.FiredAt
+		if episode.PendingAt != nil {
+			start = episode.PendingAt
+		}
+		if start != nil {
+			end := now
+			if episode.ResolvedAt != nil {
+				end = *episode.ResolvedAt
+			}
+			episode.DurationMs = end.Sub(*start).Milliseconds()
+		}
+		result = append(result, *episode)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return episodeStart(&result[i]).Before(episodeStart(&result[j]))
+	})
+	return result, nil
+}
+
+func episodeStart(e *AlertEpisode) time.Time {
+	switch {
+	case e.PendingAt != nil:
+		return *e.PendingAt
+	case e.FiredAt != nil:
+		return *e.FiredAt
+	case e.ResolvedAt != nil:
+		return *e.ResolvedAt
+	}
+	return time.Time{}
+}
+
+// transitionValue reads the value an alert's details report, as the
+// scheduler and widget alerts do
+func transitionValue(details interface{}) *float64 {
+	fields, ok := details.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	switch v := fields["value"].(type) {
+	case float64:
+		return &v
+	case int:
+		f := float64(v)
+		return &f
+	case int64:
+		f := float64(v)
+		return &f
+	}
+	return nil
+}
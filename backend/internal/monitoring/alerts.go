@@ -1,9 +1,12 @@
 package monitoring
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/rs/zerolog/log"
 )
 
 // AlertSeverity represents the severity level of an alert
@@ -19,7 +22,8 @@ const (
 type AlertStatus string
 
 const (
-	AlertStatusActive   AlertStatus = "active"
+	AlertStatusPending  AlertStatus = "pending" // waiting for its rule's For to pass
+	AlertStatusActive   AlertStatus = "active"  // firing
 	AlertStatusResolved AlertStatus = "resolved"
 )
 
@@ -45,6 +49,24 @@ type AlertRule struct {
 	Severity    AlertSeverity
 	Condition   func(metrics []Metric) (bool, string)
 	Cooldown    time.Duration
+	For         time.Duration // how long the condition must hold before the alert fires
+	Source      string        // of the rule's alerts, "system" when empty
+	
+	// Evaluate replaces Condition for rules that report details with their
+	// alerts. An error skips the check, leaving the rule's alert as it is.
+	Evaluate func(metrics []Metric) (triggered bool, message string, details interface{}, err error)
+}
+
+// ErrNoSample is returned by Evaluate to skip a check quietly while a rule
+// has nothing to evaluate yet
+var ErrNoSample = errors.New("no sample")
+
+// ruleResult is the outcome of evaluating a rule
+type ruleResult struct {
+	rule      AlertRule
+	triggered bool
+	message   string
+	details   interface{}
 }
 
 // AlertManager manages system alerts
@@ -55,6 +77,13 @@ type AlertManager struct {
 	lastChecked map[string]time.Time
 	listeners   []AlertListener
 	metrics     *MetricsCollector
+	history     *AlertHistory
+	db          SQLExecutor // runs the queries of anomaly rules
+	anomalies   map[string]*anomalySeries
+	anomalyStorage AnomalyRuleStorage
+	forecaster      CapacityForecaster // evaluates capacity rules
+	capacityRules   map[string]CapacityRule
+	capacityStorage CapacityRuleStorage
 }
 
 // AlertListener interface for alert notifications
@@ -69,6 +98,9 @@ func NewAlertManager(metrics *MetricsCollector) *AlertManager {
 		rules:       make([]AlertRule, 0),
 		lastChecked: make(map[string]time.Time),
 		metrics:     metrics,
+		history:     NewAlertHistory(),
+		anomalies:   make(map[string]*anomalySeries),
+		capacityRules: make(map[string]CapacityRule),
 	}
 	
 	// Register default alert rules
@@ -84,6 +116,11 @@ func (am *AlertManager) AddListener(listener AlertListener) {
 	am.listeners = append(am.listeners, listener)
 }
 
+// History returns the record of alert state transitions
+func (am *AlertManager) History() *AlertHistory {
+	return am.history
+}
+
 // AddRule adds a custom alert rule
 func (am *AlertManager) AddRule(rule AlertRule) {
 	am.mu.Lock()
@@ -91,14 +128,44 @@ func (am *AlertManager) AddRule(rule AlertRule) {
 	am.rules = append(am.rules, rule)
 }
 
-// CheckAlerts evaluates all alert rules
-func (am *AlertManager) CheckAlerts() {
+// HasRule reports whether a rule with the given name is registered
+func (am *AlertManager) HasRule(name string) bool {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	return am.hasRule(name)
+}
+
+// RemoveRule removes a rule, resolving its open alert
+func (am *AlertManager) RemoveRule(name string) {
 	am.mu.Lock()
 	defer am.mu.Unlock()
-	
+	am.removeRule(name)
+	if alert := am.findOpenAlert(name); alert != nil {
+		am.resolve(alert, time.Now())
+	}
+}
+
+func (am *AlertManager) removeRule(name string) {
+	delete(am.anomalies, name)
+	delete(am.capacityRules, name)
+	delete(am.lastChecked, name)
+	for i, rule := range am.rules {
+		if rule.Name == name {
+			am.rules = append(am.rules[:i], am.rules[i+1:]...)
+			break
+		}
+	}
+}
+
+// CheckAlerts evaluates all alert rules
+func (am *AlertManager) CheckAlerts() {
 	metrics := am.metrics.GetMetrics()
 	now := time.Now()
 	
+	// Rules are evaluated without the lock held, as those running queries
+	// may take a while
+	am.mu.RLock()
+	var due []AlertRule
 	for _, rule := range am.rules {
 		// Check cooldown
 		if lastCheck, exists := am.lastChecked[rule.Name]; exists {
@@ -106,48 +173,145 @@ func (am *AlertManager) CheckAlerts() {
 				continue
 			}
 		}
-		
-		// Evaluate condition
-		triggered, message := rule.Condition(metrics)
-		alertID := fmt.Sprintf("%s_%d", rule.Name, now.Unix())
+		due = append(due, rule)
+	}
+	am.mu.RUnlock()
+	
+	results := make([]ruleResult, 0, len(due))
+	for _, rule := range due {
+		result, err := evaluateRule(rule, metrics)
+		if err != nil {
+			if err != ErrNoSample {
+				log.Warn().Err(err).Str("rule", rule.Name).Msg("Failed to evaluate alert rule")
+			}
+			continue
+		}
+		results = append(results, result)
+	}
+	
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	
+	for _, result := range results {
+		rule := result.rule
+		if !am.hasRule(rule.Name) {
+			continue // removed while it was evaluated
+		}
+		triggered, message := result.triggered, result.message
+		alertID := fmt.Sprintf("%s_%d", rule.Name, now.UnixNano())
+		existingAlert := am.findOpenAlert(rule.Name)
 		
 		if triggered {
-			// Check if alert already exists
-			existingAlert := am.findActiveAlert(rule.Name)
 			if existingAlert != nil {
-				// Update existing alert
+				// Update existing alert, firing it once it has been pending long enough
 				existingAlert.Count++
 				existingAlert.LastUpdated = now
 				existingAlert.Message = message
+				existingAlert.Details = result.details
+				if existingAlert.Status == AlertStatusPending && now.Sub(existingAlert.StartTime) >= rule.For {
+					existingAlert.Status = AlertStatusActive
+					am.recordTransition(existingAlert, AlertStatusPending, now)
+					am.notifyListeners(existingAlert)
+				}
 			} else {
-				// Create new alert
-				alert := &Alert{
+				// Create new alert, pending first if the rule waits before firing
+				status := AlertStatusActive
+				if rule.For > 0 {
+					status = AlertStatusPending
+				}
+				source := rule.Source
+				if source == "" {
+					source = "system"
+				}
+				existingAlert = &Alert{
 					ID:          alertID,
 					Name:        rule.Name,
 					Severity:    rule.Severity,
-					Status:      AlertStatusActive,
+					Status:      status,
 					Message:     message,
-					Source:      "system",
+					Source:      source,
 					StartTime:   now,
 					LastUpdated: now,
 					Count:       1,
+					Details:     result.details,
+				}
+				am.alerts[alertID] = existingAlert
+				am.recordTransition(existingAlert, "", now)
+				if status == AlertStatusActive {
+					am.notifyListeners(existingAlert)
 				}
-				am.alerts[alertID] = alert
-				am.notifyListeners(alert)
 			}
-			am.lastChecked[rule.Name] = now
-		} else {
-			// Resolve existing alert if condition is no longer met
-			if existingAlert := am.findActiveAlert(rule.Name); existingAlert != nil {
-				existingAlert.Status = AlertStatusResolved
-				existingAlert.EndTime = &now
-				existingAlert.LastUpdated = now
-				am.notifyListeners(existingAlert)
+			// Pending alerts are checked again at once to fire in time
+			if existingAlert.Status == AlertStatusActive {
+				am.lastChecked[rule.Name] = now
 			}
+		} else if existingAlert != nil {
+			// Resolve existing alert if condition is no longer met
+			am.resolve(existingAlert, now)
 		}
 	}
 }
 
+// evaluateRule runs a rule's Evaluate, or its Condition if it has none
+func evaluateRule(rule AlertRule, metrics []Metric) (ruleResult, error) {
+	result := ruleResult{rule: rule}
+	if rule.Evaluate != nil {
+		var err error
+		result.triggered, result.message, result.details, err = rule.Evaluate(metrics)
+		return result, err
+	}
+	result.triggered, result.message = rule.Condition(metrics)
+	return result, nil
+}
+
+// RaiseAlert records an alert reported by another component rather than
+// produced by a rule. An active alert with the same name is updated.
+func (am *AlertManager) RaiseAlert(name string, severity AlertSeverity, source, message string, details interface{}) *Alert {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	
+	now := time.Now()
+	if existing := am.findActiveAlert(name); existing != nil {
+		existing.Count++
+		existing.LastUpdated = now
+		existing.Message = message
+		existing.Details = details
+		return existing
+	}
+	
+	alert := &Alert{
+		ID:          fmt.Sprintf("%s_%d", name, now.UnixNano()),
+		Name:        name,
+		Severity:    severity,
+		Status:      AlertStatusActive,
+		Message:     message,
+		Source:      source,
+		StartTime:   now,
+		LastUpdated: now,
+		Count:       1,
+		Details:     details,
+	}
+	am.alerts[alert.ID] = alert
+	am.recordTransition(alert, "", now)
+	am.notifyListeners(alert)
+	return alert
+}
+
+// ResolveAlert resolves the active alert with the given name, if any
+func (am *AlertManager) ResolveAlert(name string) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	
+	if existing := am.findActiveAlert(name); existing != nil {
+		now := time.Now()
+		existing.Status = AlertStatusResolved
+		existing.EndTime = &now
+		existing.LastUpdated = now
+		am.recordTransition(existing, AlertStatusActive, now)
+		am.notifyListeners(existing)
+	}
+}
+
 // GetActiveAlerts returns all active alerts
 func (am *AlertManager) GetActiveAlerts() []*Alert {
 	am.mu.RLock()
@@ -176,6 +340,28 @@ func (am *AlertManager) GetAllAlerts() []*Alert {
 	return allAlerts
 }
 
+// hasRule reports whether a rule with the given name is registered
+func (am *AlertManager) hasRule(name string) bool {
+	for _, rule := range am.rules {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve resolves an open alert, notifying listeners if it had fired
+func (am *AlertManager) resolve(alert *Alert, now time.Time) {
+	previous := alert.Status
+	alert.Status = AlertStatusResolved
+	alert.EndTime = &now
+	alert.LastUpdated = now
+	am.recordTransition(alert, previous, now)
+	if previous == AlertStatusActive {
+		am.notifyListeners(alert)
+	}
+}
+
 // findActiveAlert finds an active alert by name
 func (am *AlertManager) findActiveAlert(name string) *Alert {
 	for _, alert := range am.alerts {
@@ -186,10 +372,39 @@ func (am *AlertManager) findActiveAlert(name string) *Alert {
 	return nil
 }
 
-// notifyListeners notifies all listeners of an alert
+// findOpenAlert finds an active or pending alert by name
+func (am *AlertManager) findOpenAlert(name string) *Alert {
+	for _, alert := range am.alerts {
+		if alert.Name == name && (alert.Status == AlertStatusActive || alert.Status == AlertStatusPending) {
+			return alert
+		}
+	}
+	return nil
+}
+
+// recordTransition adds an alert's change from one status to its current
+// one to the history
+func (am *AlertManager) recordTransition(alert *Alert, from AlertStatus, at time.Time) {
+	am.history.record(AlertTransition{
+		AlertID:  alert.ID,
+		Name:     alert.Name,
+		Severity: alert.Severity,
+		Source:   alert.Source,
+		From:     from,
+		To:       alert.Status,
+		Time:     at,
+		Value:    transitionValue(alert.Details),
+		Message:  alert.Message,
+		Details:  alert.Details,
+	})
+}
+
+// notifyListeners notifies all listeners of an alert. They get a copy, as
+// the alert keeps changing while they run.
 func (am *AlertManager) notifyListeners(alert *Alert) {
 	for _, listener := range am.listeners {
-		go listener.OnAlert(alert)
+		copied := *alert
+		go listener.OnAlert(&copied)
 	}
 }
 
@@ -201,6 +416,7 @@ func (am *AlertManager) registerDefaultRules() {
 		Description: "Log ingestion rate is abnormally high",
 		Severity:    SeverityWarning,
 		Cooldown:    5 * time.Minute,
+		For:         time.Minute,
 		Condition: func(metrics []Metric) (bool, string) {
 			for _, m := range metrics {
 				if m.Name == "ingestion_rate_per_second" && m.Value > 10000 {
@@ -217,6 +433,7 @@ func (am *AlertManager) registerDefaultRules() {
 		Description: "Queries are taking too long to execute",
 		Severity:    SeverityWarning,
 		Cooldown:    5 * time.Minute,
+		For:         time.Minute,
 		Condition: func(metrics []Metric) (bool, string) {
 			for _, m := range metrics {
 				if m.Name == "query_duration_ms_p99" && m.Value > 5000 {
@@ -233,6 +450,7 @@ func (am *AlertManager) registerDefaultRules() {
 		Description: "Memory usage is too high",
 		Severity:    SeverityCritical,
 		Cooldown:    5 * time.Minute,
+		For:         time.Minute,
 		Condition: func(metrics []Metric) (bool, string) {
 			var allocMB float64
 			for _, m := range metrics {
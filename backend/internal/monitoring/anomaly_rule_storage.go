@@ -0,0 +1,83 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// AnomalyRuleStorage persists anomaly rules
+type AnomalyRuleStorage interface {
+	Load() ([]AnomalyRule, error)
+	Save(rules []AnomalyRule) error
+}
+
+// AnomalyRuleFileStorage persists anomaly rules as a JSON document on disk
+type AnomalyRuleFileStorage struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewAnomalyRuleFileStorage creates a file-backed anomaly rule storage
+func NewAnomalyRuleFileStorage(path string) *AnomalyRuleFileStorage {
+	return &AnomalyRuleFileStorage{path: path}
+}
+
+// Load reads the rules from the file, none if it does not exist
+func (s *AnomalyRuleFileStorage) Load() ([]AnomalyRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rules []AnomalyRule
+	if err := readRulesFile(s.path, "anomaly", &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Save replaces the rules in the file
+func (s *AnomalyRuleFileStorage) Save(rules []AnomalyRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeRulesFile(s.path, "anomaly", rules)
+}
+
+// readRulesFile decodes the rules of kind in the file at path into rules,
+// leaving them empty if the file does not exist
+func readRulesFile(path, kind string, rules interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s rules file: %w", kind, err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, rules); err != nil {
+		return fmt.Errorf("failed to decode %s rules file: %w", kind, err)
+	}
+	return nil
+}
+
+// writeRulesFile replaces the file at path with rules of kind
+func writeRulesFile(path, kind string, rules interface{}) error {
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s rules: %w", kind, err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s rules directory: %w", kind, err)
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s rules file: %w", kind, err)
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,537 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	detector "github.com/your-username/click-lite-log-analytics/backend/internal/errors"
+)
+
+// AnomalySource is the source of the alerts raised by anomaly rules
+const AnomalySource = "anomaly"
+
+// AnomalyMethod is how an anomaly rule builds the baseline its series is
+// compared with
+type AnomalyMethod string
+
+const (
+	// AnomalyZScore compares a value with the latest values of the series
+	AnomalyZScore AnomalyMethod = "zscore"
+	// AnomalySeasonal compares a value with the values seen at the same
+	// hour of the day or of the week
+	AnomalySeasonal AnomalyMethod = "seasonal"
+)
+
+// Seasons of seasonal anomaly rules, both in hourly buckets
+const (
+	SeasonDaily  = "daily"
+	SeasonWeekly = "weekly"
+)
+
+// Directions of the deviations anomaly rules flag
+const (
+	DirectionAbove = "above"
+	DirectionBelow = "below"
+	DirectionBoth  = "both"
+)
+
+const (
+	defaultAnomalyThreshold = 3.0
+	// defaultAnomalyWindow is an hour of checks every 30 seconds
+	defaultAnomalyWindow = 120
+	// defaultSeasonalWindow is about a week of checks per hour of the day
+	defaultSeasonalWindow = 840
+	minAnomalyWindow      = 10
+	maxAnomalyWindow      = 10000
+)
+
+var (
+	anomalyRuleNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,100}$`)
+	selectPattern          = regexp.MustCompile(`(?is)^\s*(select|with)\s`)
+)
+
+// AnomalyRule flags a series when it deviates from its own baseline,
+// without a hand-tuned threshold. The series is either a metric of the
+// collector, as a per-second rate for counters, or a SQL query returning a
+// single number, such as the count of the logs of the last minute.
+type AnomalyRule struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Severity    AlertSeverity     `json:"severity"`
+	Metric      string            `json:"metric,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"` // the metric series to sum, all when empty
+	Query       string            `json:"query,omitempty"`
+	Method      AnomalyMethod     `json:"method"`
+	Season      string            `json:"season,omitempty"`   // of seasonal rules, daily when empty
+	Threshold   float64           `json:"threshold"`          // standard deviations, 3 when 0
+	Direction   string            `json:"direction"`          // both when empty
+	Window      int               `json:"window"`             // data points kept, per hour of the season for seasonal rules
+	For         int               `json:"for,omitempty"`      // seconds a deviation must last before the alert fires
+	Cooldown    int               `json:"cooldown,omitempty"` // seconds between checks once the alert fires
+	CreatedBy   string            `json:"created_by,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// AnomalyBaseline is what an anomaly rule has learnt of its series. For
+// seasonal rules it is the baseline of the current hour.
+type AnomalyBaseline struct {
+	Mean       float64    `json:"mean"`
+	StdDev     float64    `json:"std_dev"`
+	Samples    int        `json:"samples"`
+	Ready      bool       `json:"ready"` // whether there are enough samples to flag anomalies
+	LastValue  *float64   `json:"last_value,omitempty"`
+	LastZScore *float64   `json:"last_z_score,omitempty"`
+	LastCheck  *time.Time `json:"last_check,omitempty"`
+}
+
+// AnomalyRuleStatus is an anomaly rule and its baseline
+type AnomalyRuleStatus struct {
+	AnomalyRule
+	Baseline AnomalyBaseline `json:"baseline"`
+}
+
+// validate fills in defaults and checks the rule
+func (r *AnomalyRule) validate() error {
+	if !anomalyRuleNamePattern.MatchString(r.Name) {
+		return fmt.Errorf("invalid rule name %q: use up to 100 letters, digits, '_', '.' or '-'", r.Name)
+	}
+	switch {
+	case r.Metric == "" && r.Query == "":
+		return fmt.Errorf("rule %s needs a metric or a query", r.Name)
+	case r.Metric != "" && r.Query != "":
+		return fmt.Errorf("rule %s has both a metric and a query", r.Name)
+	case r.Query != "" && !selectPattern.MatchString(r.Query):
+		return fmt.Errorf("query of rule %s must be a SELECT statement", r.Name)
+	}
+
+	switch r.Severity {
+	case "":
+		r.Severity = SeverityWarning
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return fmt.Errorf("invalid severity %q", r.Severity)
+	}
+
+	window := defaultAnomalyWindow
+	switch r.Method {
+	case "":
+		r.Method = AnomalyZScore
+	case AnomalyZScore:
+	case AnomalySeasonal:
+		window = defaultSeasonalWindow
+		switch r.Season {
+		case "":
+			r.Season = SeasonDaily
+		case SeasonDaily, SeasonWeekly:
+		default:
+			return fmt.Errorf("invalid season %q: use %s or %s", r.Season, SeasonDaily, SeasonWeekly)
+		}
+	default:
+		return fmt.Errorf("invalid method %q: use %s or %s", r.Method, AnomalyZScore, AnomalySeasonal)
+	}
+	if r.Method != AnomalySeasonal {
+		r.Season = ""
+	}
+
+	if r.Window == 0 {
+		r.Window = window
+	}
+	if r.Window < minAnomalyWindow || r.Window > maxAnomalyWindow {
+		return fmt.Errorf("window must be between %d and %d data points", minAnomalyWindow, maxAnomalyWindow)
+	}
+	if r.Threshold == 0 {
+		r.Threshold = defaultAnomalyThreshold
+	}
+	if r.Threshold < 0 || math.IsNaN(r.Threshold) || math.IsInf(r.Threshold, 0) {
+		return fmt.Errorf("threshold must be a positive number of standard deviations")
+	}
+	switch r.Direction {
+	case "":
+		r.Direction = DirectionBoth
+	case DirectionAbove, DirectionBelow, DirectionBoth:
+	default:
+		return fmt.Errorf("invalid direction %q: use %s, %s or %s", r.Direction, DirectionAbove, DirectionBelow, DirectionBoth)
+	}
+	if r.For < 0 || r.Cooldown < 0 {
+		return fmt.Errorf("for and cooldown cannot be negative")
+	}
+	return nil
+}
+
+// series names the series of the rule in alert messages
+func (r *AnomalyRule) series() string {
+	if r.Metric == "" {
+		return "query result"
+	}
+	if len(r.Labels) == 0 {
+		return r.Metric
+	}
+	keys := make([]string, 0, len(r.Labels))
+	for key := range r.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = fmt.Sprintf("%s=%q", key, r.Labels[key])
+	}
+	return r.Metric + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// anomalySeries samples the series of an anomaly rule and keeps its
+// baseline
+type anomalySeries struct {
+	rule AnomalyRule
+	db   SQLExecutor
+
+	mu       sync.Mutex
+	zscore   *detector.AnomalyDetector
+	seasonal *detector.SeasonalDetector
+
+	// The previous reading of a counter, to turn it into a rate
+	counter   float64
+	counterAt time.Time
+
+	lastValue  *float64
+	lastZScore *float64
+	lastCheck  *time.Time
+}
+
+func newAnomalySeries(rule AnomalyRule, db SQLExecutor) *anomalySeries {
+	series := &anomalySeries{rule: rule, db: db}
+	switch {
+	case rule.Method == AnomalySeasonal && rule.Season == SeasonWeekly:
+		series.seasonal = detector.NewSeasonalDetector(7*24*time.Hour, time.Hour, rule.Window)
+	case rule.Method == AnomalySeasonal:
+		series.seasonal = detector.NewSeasonalDetector(24*time.Hour, time.Hour, rule.Window)
+	default:
+		series.zscore = detector.NewAnomalyDetector(rule.Window)
+	}
+	return series
+}
+
+// alertRule returns the alert rule evaluating the series
+func (s *anomalySeries) alertRule() AlertRule {
+	description := s.rule.Description
+	if description == "" {
+		description = fmt.Sprintf("%s deviates from its baseline", s.rule.series())
+	}
+	return AlertRule{
+		Name:        s.rule.Name,
+		Description: description,
+		Severity:    s.rule.Severity,
+		Source:      AnomalySource,
+		Cooldown:    time.Duration(s.rule.Cooldown) * time.Second,
+		For:         time.Duration(s.rule.For) * time.Second,
+		Evaluate:    s.evaluate,
+	}
+}
+
+// evaluate compares the current value of the series with the baseline,
+// then adds it to the baseline
+func (s *anomalySeries) evaluate(metrics []Metric) (bool, string, interface{}, error) {
+	now := time.Now()
+	value, err := s.sample(metrics, now)
+	if err != nil {
+		return false, "", nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var z float64
+	var ready bool
+	var mean, stdDev float64
+	if s.seasonal != nil {
+		z, ready = s.seasonal.ZScore(now, value)
+		mean, stdDev, _ = s.seasonal.Baseline(now)
+		s.seasonal.AddDataPoint(now, value)
+	} else {
+		z, ready = s.zscore.ZScore(value)
+		mean, stdDev, _ = s.zscore.Baseline()
+		s.zscore.AddDataPoint(value)
+	}
+	s.lastValue, s.lastCheck = &value, &now
+	s.lastZScore = nil
+	if !ready {
+		return false, "", nil, nil
+	}
+	s.lastZScore = &z
+
+	triggered := false
+	switch s.rule.Direction {
+	case DirectionAbove:
+		triggered = z > s.rule.Threshold
+	case DirectionBelow:
+		triggered = z < -s.rule.Threshold
+	default:
+		triggered = math.Abs(z) > s.rule.Threshold
+	}
+	if !triggered {
+		return false, "", nil, nil
+	}
+
+	position := "above"
+	if z < 0 {
+		position = "below"
+	}
+	message := fmt.Sprintf("%s is %.4g, %.1f standard deviations %s its baseline of %.4g (threshold: %.1f)",
+		s.rule.series(), value, math.Abs(z), position, mean, s.rule.Threshold)
+	details := map[string]interface{}{
+		"value":     value,
+		"z_score":   z,
+		"mean":      mean,
+		"std_dev":   stdDev,
+		"threshold": s.rule.Threshold,
+		"method":    string(s.rule.Method),
+		"series":    s.rule.series(),
+	}
+	return true, message, details, nil
+}
+
+// sample reads the current value of the series
+func (s *anomalySeries) sample(metrics []Metric, now time.Time) (float64, error) {
+	if s.rule.Query != "" {
+		return s.query()
+	}
+
+	var value float64
+	found, counter := false, false
+	for _, m := range metrics {
+		if m.Name != s.rule.Metric || !hasLabels(m.Labels, s.rule.Labels) {
+			continue
+		}
+		value += m.Value
+		found = true
+		counter = counter || m.Type == string(MetricTypeCounter)
+	}
+	if !found {
+		return 0, ErrNoSample
+	}
+	if !counter {
+		return value, nil
+	}
+
+	// Counters only grow, their rate is what changes
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, previousAt := s.counter, s.counterAt
+	s.counter, s.counterAt = value, now
+	elapsed := now.Sub(previousAt).Seconds()
+	if previousAt.IsZero() || value < previous || elapsed <= 0 {
+		return 0, ErrNoSample // first reading, or the counter was reset
+	}
+	return (value - previous) / elapsed, nil
+}
+
+// query runs the rule's query and reads the number it returns: the value
+// column, or the only column
+func (s *anomalySeries) query() (float64, error) {
+	s.mu.Lock()
+	db := s.db
+	s.mu.Unlock()
+	if db == nil {
+		return 0, fmt.Errorf("no database to run the query")
+	}
+	rows, err := db.ExecuteSQL(s.rule.Query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to run query: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, ErrNoSample
+	}
+	row := rows[0]
+	raw, ok := row["value"]
+	if !ok && len(row) == 1 {
+		for _, v := range row {
+			raw = v
+		}
+		ok = true
+	}
+	if !ok {
+		return 0, fmt.Errorf("query must return a single column or a value column")
+	}
+	value, ok := seriesValue(raw)
+	if !ok {
+		return 0, fmt.Errorf("query returned %v, not a number", raw)
+	}
+	return value, nil
+}
+
+// baseline returns what the series has learnt so far
+func (s *anomalySeries) baseline() AnomalyBaseline {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var baseline AnomalyBaseline
+	if s.seasonal != nil {
+		baseline.Mean, baseline.StdDev, baseline.Samples = s.seasonal.Baseline(time.Now())
+	} else {
+		baseline.Mean, baseline.StdDev, baseline.Samples = s.zscore.Baseline()
+	}
+	baseline.Ready = baseline.Samples >= minAnomalyWindow && baseline.StdDev > 0
+	baseline.LastValue, baseline.LastZScore, baseline.LastCheck = s.lastValue, s.lastZScore, s.lastCheck
+	return baseline
+}
+
+// hasLabels reports whether labels include all of want
+func hasLabels(labels, want map[string]string) bool {
+	for key, value := range want {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// seriesValue reads a number, which ClickHouse quotes in JSON when it is a
+// 64-bit integer
+func seriesValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	}
+	return 0, false
+}
+
+// SetQueryExecutor sets where the queries of anomaly rules run
+func (am *AlertManager) SetQueryExecutor(db SQLExecutor) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.db = db
+	for _, series := range am.anomalies {
+		series.mu.Lock()
+		series.db = db
+		series.mu.Unlock()
+	}
+}
+
+// SetAnomalyRuleStorage persists anomaly rules, adding those it holds
+func (am *AlertManager) SetAnomalyRuleStorage(storage AnomalyRuleStorage) error {
+	rules, err := storage.Load()
+	if err != nil {
+		return err
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.anomalyStorage = storage
+	for i := range rules {
+		if err := am.addAnomalyRule(&rules[i]); err != nil {
+			log.Warn().Err(err).Str("rule", rules[i].Name).Msg("Skipping invalid anomaly rule")
+		}
+	}
+	return nil
+}
+
+// AddAnomalyRule adds an anomaly rule. Its baseline starts empty, so it
+// flags nothing until it has seen enough of its series.
+func (am *AlertManager) AddAnomalyRule(rule *AnomalyRule) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	rule.CreatedAt = time.Now()
+	if err := am.addAnomalyRule(rule); err != nil {
+		return err
+	}
+	if err := am.saveAnomalyRules(); err != nil {
+		am.removeRule(rule.Name)
+		return err
+	}
+	return nil
+}
+
+func (am *AlertManager) addAnomalyRule(rule *AnomalyRule) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+	if am.hasRule(rule.Name) {
+		return fmt.Errorf("alert rule already exists: %s", rule.Name)
+	}
+	series := newAnomalySeries(*rule, am.db)
+	am.anomalies[rule.Name] = series
+	am.rules = append(am.rules, series.alertRule())
+	return nil
+}
+
+// AnomalyRules returns the anomaly rules by name, with their baselines
+func (am *AlertManager) AnomalyRules() []AnomalyRuleStatus {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	rules := make([]AnomalyRuleStatus, 0, len(am.anomalies))
+	for _, series := range am.anomalies {
+		rules = append(rules, AnomalyRuleStatus{AnomalyRule: series.rule, Baseline: series.baseline()})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules
+}
+
+// GetAnomalyRule returns an anomaly rule by name, with its baseline
+func (am *AlertManager) GetAnomalyRule(name string) (*AnomalyRuleStatus, error) {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	series, ok := am.anomalies[name]
+	if !ok {
+		return nil, fmt.Errorf("anomaly rule not found: %s", name)
+	}
+	return &AnomalyRuleStatus{AnomalyRule: series.rule, Baseline: series.baseline()}, nil
+}
+
+// DeleteAnomalyRule removes an anomaly rule, resolving its open alert
+func (am *AlertManager) DeleteAnomalyRule(name string) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	series, ok := am.anomalies[name]
+	if !ok {
+		return fmt.Errorf("anomaly rule not found: %s", name)
+	}
+	am.removeRule(name)
+	if err := am.saveAnomalyRules(); err != nil {
+		am.anomalies[name] = series
+		am.rules = append(am.rules, series.alertRule())
+		return err
+	}
+	if alert := am.findOpenAlert(name); alert != nil {
+		am.resolve(alert, time.Now())
+	}
+	return nil
+}
+
+func (am *AlertManager) saveAnomalyRules() error {
+	if am.anomalyStorage == nil {
+		return nil
+	}
+	rules := make([]AnomalyRule, 0, len(am.anomalies))
+	for _, series := range am.anomalies {
+		rules = append(rules, series.rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	if err := am.anomalyStorage.Save(rules); err != nil {
+		return fmt.Errorf("failed to save anomaly rules: %w", err)
+	}
+	return nil
+}
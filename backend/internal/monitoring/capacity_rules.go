@@ -0,0 +1,240 @@
+package monitoring
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CapacitySource is the source of the alerts raised by capacity rules
+const CapacitySource = "capacity"
+
+// CapacityForecaster projects when the disk fills up
+type CapacityForecaster interface {
+	// DaysUntilFull returns in how many days the disk fills up, or the
+	// table alone fills it when set, false when it does not in the
+	// forecast's horizon or growth is not known yet
+	DaysUntilFull(table string) (float64, bool, error)
+}
+
+// CapacityRule raises an alert when the capacity forecast has the disk
+// filling up within Days
+type CapacityRule struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Severity    AlertSeverity `json:"severity"`
+	Table       string        `json:"table,omitempty"` // counts only this table's growth when set
+	Days        float64       `json:"days"`
+	For         int           `json:"for,omitempty"`      // seconds the forecast must hold before the alert fires
+	Cooldown    int           `json:"cooldown,omitempty"` // seconds between checks once the alert fires
+	CreatedBy   string        `json:"created_by,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// validate fills in defaults and checks the rule
+func (r *CapacityRule) validate() error {
+	if !anomalyRuleNamePattern.MatchString(r.Name) {
+		return fmt.Errorf("invalid rule name %q: use up to 100 letters, digits, '_', '.' or '-'", r.Name)
+	}
+	switch r.Severity {
+	case "":
+		r.Severity = SeverityWarning
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return fmt.Errorf("invalid severity %q", r.Severity)
+	}
+	if r.Days <= 0 || math.IsNaN(r.Days) || math.IsInf(r.Days, 0) {
+		return fmt.Errorf("days must be a positive number")
+	}
+	if r.For < 0 || r.Cooldown < 0 {
+		return fmt.Errorf("for and cooldown cannot be negative")
+	}
+	return nil
+}
+
+// alertRule returns the alert rule evaluating the capacity rule against
+// the forecast of am
+func (r CapacityRule) alertRule(am *AlertManager) AlertRule {
+	description := r.Description
+	if description == "" {
+		description = fmt.Sprintf("The disk is forecast to fill up within %g days", r.Days)
+	}
+	return AlertRule{
+		Name:        r.Name,
+		Description: description,
+		Severity:    r.Severity,
+		Source:      CapacitySource,
+		Cooldown:    time.Duration(r.Cooldown) * time.Second,
+		For:         time.Duration(r.For) * time.Second,
+		Evaluate: func([]Metric) (bool, string, interface{}, error) {
+			am.mu.RLock()
+			forecaster := am.forecaster
+			am.mu.RUnlock()
+			if forecaster == nil {
+				return false, "", nil, ErrNoSample
+			}
+
+			days, ok, err := forecaster.DaysUntilFull(r.Table)
+			if err != nil {
+				return false, "", nil, err
+			}
+			if !ok || days > r.Days {
+				return false, "", nil, nil
+			}
+			subject := "The disk"
+			if r.Table != "" {
+				subject = fmt.Sprintf("The growth of table %s", r.Table)
+			}
+			message := fmt.Sprintf("%s is forecast to fill the disk in %.1f days (threshold: %g days)", subject, days, r.Days)
+			return true, message, map[string]interface{}{
+				"value":     days,
+				"threshold": r.Days,
+				"table":     r.Table,
+			}, nil
+		},
+	}
+}
+
+// CapacityRuleStorage persists capacity rules
+type CapacityRuleStorage interface {
+	Load() ([]CapacityRule, error)
+	Save(rules []CapacityRule) error
+}
+
+// CapacityRuleFileStorage persists capacity rules as a JSON document on
+// disk
+type CapacityRuleFileStorage struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewCapacityRuleFileStorage creates a file-backed capacity rule storage
+func NewCapacityRuleFileStorage(path string) *CapacityRuleFileStorage {
+	return &CapacityRuleFileStorage{path: path}
+}
+
+// Load reads the rules from the file, none if it does not exist
+func (s *CapacityRuleFileStorage) Load() ([]CapacityRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rules []CapacityRule
+	if err := readRulesFile(s.path, "capacity", &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Save replaces the rules in the file
+func (s *CapacityRuleFileStorage) Save(rules []CapacityRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeRulesFile(s.path, "capacity", rules)
+}
+
+// SetCapacityForecaster sets the forecast capacity rules are evaluated
+// against
+func (am *AlertManager) SetCapacityForecaster(forecaster CapacityForecaster) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.forecaster = forecaster
+}
+
+// SetCapacityRuleStorage persists capacity rules, adding those it holds
+func (am *AlertManager) SetCapacityRuleStorage(storage CapacityRuleStorage) error {
+	rules, err := storage.Load()
+	if err != nil {
+		return err
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.capacityStorage = storage
+	for i := range rules {
+		if err := am.addCapacityRule(&rules[i]); err != nil {
+			log.Warn().Err(err).Str("rule", rules[i].Name).Msg("Skipping invalid capacity rule")
+		}
+	}
+	return nil
+}
+
+// AddCapacityRule adds a capacity rule
+func (am *AlertManager) AddCapacityRule(rule *CapacityRule) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	rule.CreatedAt = time.Now()
+	if err := am.addCapacityRule(rule); err != nil {
+		return err
+	}
+	if err := am.saveCapacityRules(); err != nil {
+		am.removeRule(rule.Name)
+		return err
+	}
+	return nil
+}
+
+func (am *AlertManager) addCapacityRule(rule *CapacityRule) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+	if am.hasRule(rule.Name) {
+		return fmt.Errorf("alert rule already exists: %s", rule.Name)
+	}
+	am.capacityRules[rule.Name] = *rule
+	am.rules = append(am.rules, rule.alertRule(am))
+	return nil
+}
+
+// CapacityRules returns the capacity rules by name
+func (am *AlertManager) CapacityRules() []CapacityRule {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	rules := make([]CapacityRule, 0, len(am.capacityRules))
+	for _, rule := range am.capacityRules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules
+}
+
+// DeleteCapacityRule removes a capacity rule, resolving its open alert
+func (am *AlertManager) DeleteCapacityRule(name string) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	rule, ok := am.capacityRules[name]
+	if !ok {
+		return fmt.Errorf("capacity rule not found: %s", name)
+	}
+	am.removeRule(name)
+	if err := am.saveCapacityRules(); err != nil {
+		am.capacityRules[name] = rule
+		am.rules = append(am.rules, rule.alertRule(am))
+		return err
+	}
+	if alert := am.findOpenAlert(name); alert != nil {
+		am.resolve(alert, time.Now())
+	}
+	return nil
+}
+
+func (am *AlertManager) saveCapacityRules() error {
+	if am.capacityStorage == nil {
+		return nil
+	}
+	rules := make([]CapacityRule, 0, len(am.capacityRules))
+	for _, rule := range am.capacityRules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	if err := am.capacityStorage.Save(rules); err != nil {
+		return fmt.Errorf("failed to save capacity rules: %w", err)
+	}
+	return nil
+}
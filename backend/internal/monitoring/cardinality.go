@@ -0,0 +1,223 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxSeriesPerMetric is how many label sets a metric may have
+// unless a limit is set for it
+const DefaultMaxSeriesPerMetric = 1000
+
+// overflowLabels label the series that takes the updates of the label sets
+// over the limit of a metric, so that their values are kept in aggregate
+var overflowLabels = map[string]string{"overflow": "true"}
+
+// seriesState is what the collector tracks about every labeled series to
+// bound their number
+type seriesState struct {
+	updated  atomic.Int64 // unix nanoseconds
+	overflow bool         // the overflow series, which is not counted
+}
+
+func (s *seriesState) touch() {
+	s.updated.Store(time.Now().UnixNano())
+}
+
+// stale reports whether the series was last updated before cutoff
+func (s *seriesState) stale(cutoff time.Time) bool {
+	return s.updated.Load() < cutoff.UnixNano()
+}
+
+// CardinalityStats describe the labeled series of a metric
+type CardinalityStats struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Series int    `json:"series"`
+	Limit  int    `json:"limit"` // 0 when unlimited
+	// OverflowedUpdates counts the updates of label sets over the limit,
+	// which went to the overflow series instead
+	OverflowedUpdates uint64         `json:"overflowed_updates"`
+	Labels            map[string]int `json:"labels"` // distinct values per label
+}
+
+// SetMaxSeriesPerMetric sets how many label sets a metric may have unless
+// a limit is set for it, 0 for no limit
+func (m *MetricsCollector) SetMaxSeriesPerMetric(limit int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxSeries = limit
+}
+
+// SetCardinalityLimit sets how many label sets the metric named name may
+// have, 0 for no limit. Label sets over the limit are counted together in
+// a series labeled overflow="true".
+func (m *MetricsCollector) SetCardinalityLimit(name string, limit int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seriesLimits[name] = limit
+}
+
+// ParseCardinalityLimit parses a metric:limit pair, such as
+// http_requests:500
+func ParseCardinalityLimit(spec string) (string, int, error) {
+	name, value, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok || name == "" {
+		return "", 0, fmt.Errorf("invalid cardinality limit %q: expected metric:limit", spec)
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		return "", 0, fmt.Errorf("invalid cardinality limit %q: bad limit %q", spec, value)
+	}
+	return name, limit, nil
+}
+
+// SetSeriesTTL sets how long a labeled series is kept without updates, 0
+// to keep them forever. Gauges are only evicted at zero, as they may be
+// added to again.
+func (m *MetricsCollector) SetSeriesTTL(ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seriesTTL = ttl
+}
+
+// StartSeriesEviction evicts stale series on the interval until ctx is
+// cancelled
+func (m *MetricsCollector) StartSeriesEviction(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.EvictStaleSeries()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// EvictStaleSeries removes the labeled series not updated within the
+// series TTL and returns how many it removed. Evicted counters start over
+// from zero if updated again.
+func (m *MetricsCollector) EvictStaleSeries() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.seriesTTL <= 0 {
+		return 0
+	}
+
+	cutoff := time.Now().Add(-m.seriesTTL)
+	evicted := 0
+	for key, counter := range m.labeledCounters {
+		if counter.stale(cutoff) {
+			delete(m.labeledCounters, key)
+			m.releaseSeries(counter.name, &counter.seriesState)
+			evicted++
+		}
+	}
+	for key, gauge := range m.labeledGauges {
+		if gauge.value == 0 && gauge.stale(cutoff) {
+			delete(m.labeledGauges, key)
+			m.releaseSeries(gauge.name, &gauge.seriesState)
+			evicted++
+		}
+	}
+	for key, series := range m.labeledHistograms {
+		if series.stale(cutoff) {
+			delete(m.labeledHistograms, key)
+			m.releaseSeries(series.name, &series.seriesState)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// admitSeries returns the key and labels of the series a new label set of
+// name goes to: its own while name is under its limit, the overflow series
+// after. Callers hold the lock.
+func (m *MetricsCollector) admitSeries(name, key string, labels map[string]string) (string, map[string]string, bool) {
+	limit := m.cardinalityLimit(name)
+	if limit > 0 && m.seriesCounts[name] >= limit {
+		m.overflowed[name]++
+		return seriesKey(name, overflowLabels), overflowLabels, true
+	}
+	m.seriesCounts[name]++
+	return key, labels, false
+}
+
+// releaseSeries uncounts an evicted series. Callers hold the lock.
+func (m *MetricsCollector) releaseSeries(name string, state *seriesState) {
+	if state.overflow {
+		return
+	}
+	if m.seriesCounts[name]--; m.seriesCounts[name] <= 0 {
+		delete(m.seriesCounts, name)
+	}
+}
+
+// cardinalityLimit returns how many label sets name may have. Callers hold
+// the lock.
+func (m *MetricsCollector) cardinalityLimit(name string) int {
+	if limit, ok := m.seriesLimits[name]; ok {
+		return limit
+	}
+	return m.maxSeries
+}
+
+// Cardinality returns the labeled metrics with the most series first, at
+// most top of them, all when top is 0
+func (m *MetricsCollector) Cardinality(top int) []CardinalityStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byName := make(map[string]*CardinalityStats)
+	values := make(map[string]map[string]map[string]bool) // distinct values by metric and label
+	add := func(name string, metricType MetricType, labels map[string]string) {
+		stats, ok := byName[name]
+		if !ok {
+			stats = &CardinalityStats{Name: name, Type: string(metricType), Limit: m.cardinalityLimit(name),
+				OverflowedUpdates: m.overflowed[name], Labels: make(map[string]int)}
+			byName[name] = stats
+			values[name] = make(map[string]map[string]bool)
+		}
+		stats.Series++
+		for label, value := range labels {
+			if values[name][label] == nil {
+				values[name][label] = make(map[string]bool)
+			}
+			values[name][label][value] = true
+		}
+	}
+	for _, counter := range m.labeledCounters {
+		add(counter.name, MetricTypeCounter, counter.labels)
+	}
+	for _, gauge := range m.labeledGauges {
+		add(gauge.name, MetricTypeGauge, gauge.labels)
+	}
+	for _, series := range m.labeledHistograms {
+		add(series.name, MetricTypeHistogram, series.labels)
+	}
+
+	stats := make([]CardinalityStats, 0, len(byName))
+	for name, s := range byName {
+		for label, distinct := range values[name] {
+			s.Labels[label] = len(distinct)
+		}
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Series != stats[j].Series {
+			return stats[i].Series > stats[j].Series
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	if top > 0 && len(stats) > top {
+		stats = stats[:top]
+	}
+	return stats
+}
@@ -1,22 +1,30 @@
 package monitoring
 
 import (
-	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"time"
 )
 
-// StorageHealthChecker checks storage health
+// StorageHealthChecker checks the data directory is writable and the disk
+// it is on has space left
 type StorageHealthChecker struct {
-	storagePath string
+	storagePath         string
+	degradedFreePercent float64
+	downFreePercent     float64
 }
 
-// NewStorageHealthChecker creates a new storage health checker
-func NewStorageHealthChecker(storagePath string) *StorageHealthChecker {
+// NewStorageHealthChecker creates a new storage health checker, degraded
+// when less than degradedFreePercent of the disk is free and down below
+// downFreePercent
+func NewStorageHealthChecker(storagePath string, degradedFreePercent, downFreePercent float64) *StorageHealthChecker {
 	return &StorageHealthChecker{
-		storagePath: storagePath,
+		storagePath:         storagePath,
+		degradedFreePercent: degradedFreePercent,
+		downFreePercent:     downFreePercent,
 	}
 }
 
@@ -76,27 +84,45 @@ func (s *StorageHealthChecker) Check() (*ComponentHealth, error) {
 	health.Details["total_size_mb"] = float64(totalSize) / 1024 / 1024
 	health.Details["file_count"] = fileCount
 	health.Details["path"] = s.storagePath
+	health.Details["thresholds"] = map[string]interface{}{
+		"degraded_free_percent": s.degradedFreePercent,
+		"down_free_percent":     s.downFreePercent,
+	}
 	
-	// Check available space (simplified)
-	if totalSize > 10*1024*1024*1024 { // 10GB warning threshold
+	// Check the space left on the disk
+	free, total, ok := diskSpace(s.storagePath)
+	if !ok || total == 0 {
+		return health, nil
+	}
+	freePercent := float64(free) / float64(total) * 100
+	health.Details["disk_free_gb"] = float64(free) / 1024 / 1024 / 1024
+	health.Details["disk_total_gb"] = float64(total) / 1024 / 1024 / 1024
+	health.Details["disk_free_percent"] = freePercent
+	switch {
+	case freePercent < s.downFreePercent:
+		health.Status = HealthStatusDown
+		health.Message = fmt.Sprintf("Only %.1f%% of the disk is free", freePercent)
+	case freePercent < s.degradedFreePercent:
 		health.Status = HealthStatusDegraded
-		health.Message = "Storage usage is high"
+		health.Message = fmt.Sprintf("Only %.1f%% of the disk is free", freePercent)
 	}
 	
 	return health, nil
 }
 
-// APIHealthChecker checks API endpoint health
+// APIHealthChecker checks the API answers over HTTP, as clients reach it
 type APIHealthChecker struct {
 	endpoint string
 	timeout  time.Duration
+	client   *http.Client
 }
 
-// NewAPIHealthChecker creates a new API health checker
+// NewAPIHealthChecker creates a new API health checker requesting endpoint
 func NewAPIHealthChecker(endpoint string, timeout time.Duration) *APIHealthChecker {
 	return &APIHealthChecker{
 		endpoint: endpoint,
 		timeout:  timeout,
+		client:   &http.Client{Timeout: timeout},
 	}
 }
 
@@ -112,27 +138,33 @@ func (a *APIHealthChecker) Check() (*ComponentHealth, error) {
 		Status:  HealthStatusOK,
 		Details: make(map[string]interface{}),
 	}
+	health.Details["endpoint"] = a.endpoint
+	health.Details["thresholds"] = map[string]interface{}{
+		"timeout_ms": a.timeout.Milliseconds(),
+	}
 	
-	// Simple check - just verify the endpoint is reachable
-	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
-	defer cancel()
+	start := time.Now()
+	resp, err := a.client.Get(a.endpoint)
+	if err != nil {
+		health.Status = HealthStatusDown
+		return health, fmt.Errorf("API is not reachable: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
 	
-	// Simulate API check
-	select {
-	case <-ctx.Done():
+	health.Details["response_time_ms"] = time.Since(start).Milliseconds()
+	health.Details["status_code"] = resp.StatusCode
+	if resp.StatusCode != http.StatusOK {
 		health.Status = HealthStatusDown
-		return health, fmt.Errorf("API health check timeout")
-	case <-time.After(10 * time.Millisecond):
-		// API is responsive
-		health.Details["endpoint"] = a.endpoint
-		health.Details["response_time_ms"] = 10
-		return health, nil
+		health.Message = fmt.Sprintf("API answered %s", resp.Status)
 	}
+	return health, nil
 }
 
 // IngestionHealthChecker checks log ingestion health
 type IngestionHealthChecker struct {
 	metrics *MetricsCollector
+	queue   BatchQueue
 }
 
 // NewIngestionHealthChecker creates a new ingestion health checker
@@ -142,6 +174,12 @@ func NewIngestionHealthChecker(metrics *MetricsCollector) *IngestionHealthChecke
 	}
 }
 
+// SetBatchQueue adds the state of the batch queue the ingested logs are
+// written through to the details of the check
+func (i *IngestionHealthChecker) SetBatchQueue(queue BatchQueue) {
+	i.queue = queue
+}
+
 // Name returns the name of the checker
 func (i *IngestionHealthChecker) Name() string {
 	return "ingestion"
@@ -172,6 +210,15 @@ func (i *IngestionHealthChecker) Check() (*ComponentHealth, error) {
 	
 	health.Details["rate_per_second"] = ingestionRate
 	health.Details["total_ingested"] = totalIngested
+	if i.queue != nil {
+		stats := i.queue.QueueStats()
+		health.Details["queue_depth"] = stats.Depth
+		health.Details["in_flight_batches"] = stats.InFlightBatches
+		health.Details["flush_latency"] = stats.FlushLatency
+		if stats.LastError != "" {
+			health.Details["last_error"] = stats.LastError
+		}
+	}
 	
 	// Check if ingestion is working
 	if totalIngested == 0 {
@@ -0,0 +1,242 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DatabasePinger is a database that can be pinged, such as ClickHouse
+type DatabasePinger interface {
+	Health(ctx context.Context) error
+}
+
+// ClickHouseHealthChecker checks ClickHouse answers, and answers quickly
+type ClickHouseHealthChecker struct {
+	db            DatabasePinger
+	timeout       time.Duration
+	slowThreshold time.Duration
+}
+
+// NewClickHouseHealthChecker creates a checker that is down when a ping
+// fails or takes longer than timeout, and degraded when it takes longer
+// than slowThreshold
+func NewClickHouseHealthChecker(db DatabasePinger, timeout, slowThreshold time.Duration) *ClickHouseHealthChecker {
+	return &ClickHouseHealthChecker{db: db, timeout: timeout, slowThreshold: slowThreshold}
+}
+
+// Name returns the name of the checker
+func (c *ClickHouseHealthChecker) Name() string {
+	return "clickhouse"
+}
+
+// Check pings ClickHouse and measures how long it takes
+func (c *ClickHouseHealthChecker) Check() (*ComponentHealth, error) {
+	health := &ComponentHealth{
+		Name:   c.Name(),
+		Status: HealthStatusOK,
+		Details: map[string]interface{}{
+			"thresholds": map[string]interface{}{
+				"slow_ms":    c.slowThreshold.Milliseconds(),
+				"timeout_ms": c.timeout.Milliseconds(),
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	start := time.Now()
+	err := c.db.Health(ctx)
+	latency := time.Since(start)
+	health.Details["latency_ms"] = latency.Milliseconds()
+	if err != nil {
+		health.Status = HealthStatusDown
+		return health, fmt.Errorf("ClickHouse is not reachable: %v", err)
+	}
+	if latency > c.slowThreshold {
+		health.Status = HealthStatusDegraded
+		health.Message = fmt.Sprintf("ClickHouse took %dms to answer", latency.Milliseconds())
+	}
+	return health, nil
+}
+
+// BatchQueueStats describes the logs waiting to be written to ClickHouse
+type BatchQueueStats struct {
+	Depth         int           `json:"depth"` // logs buffered
+	BatchSize     int           `json:"batch_size"`
+	FlushInterval time.Duration `json:"-"`
+	// InFlightBatches are the batches being written, with InFlightLogs
+	// logs, which are no longer counted in Depth
+	InFlightBatches int `json:"in_flight_batches"`
+	InFlightLogs    int `json:"in_flight_logs"`
+	// LastFlush is when the queue was last written out or found empty
+	LastFlush   time.Time  `json:"last_flush"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	// ConsecutiveFailures counts the batches that failed since one was
+	// last written
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	DroppedLogs         uint64       `json:"dropped_logs"`    // logs of the batches given up on
+	FlushedBatches      uint64       `json:"flushed_batches"` // since the server started
+	FlushLatency        FlushLatency `json:"flush_latency"`
+}
+
+// FlushLatency summarizes how long the latest batches took to write,
+// retries included
+type FlushLatency struct {
+	Samples int     `json:"samples"`
+	P50Ms   float64 `json:"p50_ms"`
+	P95Ms   float64 `json:"p95_ms"`
+	P99Ms   float64 `json:"p99_ms"`
+	MaxMs   float64 `json:"max_ms"`
+}
+
+// BatchQueue is a queue of logs written to ClickHouse in batches
+type BatchQueue interface {
+	QueueStats() BatchQueueStats
+}
+
+// BatchQueueHealthChecker checks the ingestion batch queue is drained
+type BatchQueueHealthChecker struct {
+	queue         BatchQueue
+	degradedDepth int
+	downDepth     int
+}
+
+// downBatchFailures is how many batches in a row must fail to write for
+// the queue to be down; one failure degrades it
+const downBatchFailures = 3
+
+// NewBatchQueueHealthChecker creates a checker that is degraded when more
+// than degradedDepth logs are queued, or a batch failed to write, and down
+// when more than downDepth are, or several batches in a row failed
+func NewBatchQueueHealthChecker(queue BatchQueue, degradedDepth, downDepth int) *BatchQueueHealthChecker {
+	return &BatchQueueHealthChecker{queue: queue, degradedDepth: degradedDepth, downDepth: downDepth}
+}
+
+// Name returns the name of the checker
+func (b *BatchQueueHealthChecker) Name() string {
+	return "batch_queue"
+}
+
+// Check reads the depth of the queue and the outcome of the latest writes
+func (b *BatchQueueHealthChecker) Check() (*ComponentHealth, error) {
+	stats := b.queue.QueueStats()
+	health := &ComponentHealth{
+		Name:   b.Name(),
+		Status: HealthStatusOK,
+		Details: map[string]interface{}{
+			"depth":                stats.Depth,
+			"batch_size":           stats.BatchSize,
+			"consecutive_failures": stats.ConsecutiveFailures,
+			"dropped_logs":         stats.DroppedLogs,
+			"thresholds": map[string]interface{}{
+				"degraded_depth":    b.degradedDepth,
+				"down_depth":        b.downDepth,
+				"down_failures":     downBatchFailures,
+				"stale_flush_after": (3 * stats.FlushInterval).String(),
+			},
+		},
+	}
+	if !stats.LastFlush.IsZero() {
+		health.Details["last_flush"] = stats.LastFlush
+	}
+	if stats.LastError != "" {
+		health.Details["last_error"] = stats.LastError
+	}
+
+	// A flush retries for a few seconds, so a queue is stale after missing
+	// a few intervals
+	stale := stats.Depth > 0 && stats.FlushInterval > 0 && time.Since(stats.LastFlush) > 3*stats.FlushInterval
+	switch {
+	case stats.Depth > b.downDepth:
+		health.Status = HealthStatusDown
+		health.Message = fmt.Sprintf("%d logs are waiting to be written", stats.Depth)
+	case stats.ConsecutiveFailures >= downBatchFailures:
+		health.Status = HealthStatusDown
+		health.Message = fmt.Sprintf("The last %d batches failed to write", stats.ConsecutiveFailures)
+	case stats.Depth > b.degradedDepth:
+		health.Status = HealthStatusDegraded
+		health.Message = fmt.Sprintf("%d logs are waiting to be written", stats.Depth)
+	case stats.ConsecutiveFailures > 0:
+		health.Status = HealthStatusDegraded
+		health.Message = "The last batch failed to write"
+	case stale:
+		health.Status = HealthStatusDegraded
+		health.Message = fmt.Sprintf("The queue was last flushed %s ago", time.Since(stats.LastFlush).Round(time.Second))
+	}
+	return health, nil
+}
+
+// WebSocketHubStats describes the hub delivering live logs to clients
+type WebSocketHubStats struct {
+	Clients           int
+	SlowClients       int
+	DroppedMessages   uint64
+	BroadcastBacklog  int    // logs waiting for the hub to deliver them
+	BroadcastCapacity int    // live logs are dropped once the backlog reaches it
+	BroadcastDropped  uint64 // live logs dropped as the backlog was full
+	LastLoop          time.Time
+}
+
+// WebSocketHub is a hub delivering live logs to clients
+type WebSocketHub interface {
+	HealthStats() WebSocketHubStats
+}
+
+// WebSocketHubHealthChecker checks the WebSocket hub keeps up with the
+// logs to deliver
+type WebSocketHubHealthChecker struct {
+	hub           WebSocketHub
+	stallTimeout  time.Duration
+	backlogFactor float64
+}
+
+// NewWebSocketHubHealthChecker creates a checker that is down when the hub
+// loop has not run for stallTimeout or its backlog is full, and degraded
+// when the backlog is over backlogFactor of its capacity
+func NewWebSocketHubHealthChecker(hub WebSocketHub, stallTimeout time.Duration, backlogFactor float64) *WebSocketHubHealthChecker {
+	return &WebSocketHubHealthChecker{hub: hub, stallTimeout: stallTimeout, backlogFactor: backlogFactor}
+}
+
+// Name returns the name of the checker
+func (c *WebSocketHubHealthChecker) Name() string {
+	return "websocket_hub"
+}
+
+// Check reads the backlog of the hub and when its loop last ran
+func (c *WebSocketHubHealthChecker) Check() (*ComponentHealth, error) {
+	stats := c.hub.HealthStats()
+	health := &ComponentHealth{
+		Name:   c.Name(),
+		Status: HealthStatusOK,
+		Details: map[string]interface{}{
+			"clients":            stats.Clients,
+			"slow_clients":       stats.SlowClients,
+			"dropped_messages":   stats.DroppedMessages,
+			"broadcast_backlog":  stats.BroadcastBacklog,
+			"broadcast_capacity": stats.BroadcastCapacity,
+			"broadcast_dropped":  stats.BroadcastDropped,
+			"thresholds": map[string]interface{}{
+				"stall_timeout":  c.stallTimeout.String(),
+				"backlog_factor": c.backlogFactor,
+			},
+		},
+	}
+	if !stats.LastLoop.IsZero() {
+		health.Details["last_loop"] = stats.LastLoop
+	}
+
+	switch {
+	case stats.LastLoop.IsZero() || time.Since(stats.LastLoop) > c.stallTimeout:
+		health.Status = HealthStatusDown
+		health.Message = "The hub loop is not running"
+	case stats.BroadcastCapacity > 0 && stats.BroadcastBacklog >= stats.BroadcastCapacity:
+		health.Status = HealthStatusDown
+		health.Message = "The broadcast backlog is full, live logs are being dropped"
+	case stats.BroadcastCapacity > 0 && float64(stats.BroadcastBacklog) > c.backlogFactor*float64(stats.BroadcastCapacity):
+		health.Status = HealthStatusDegraded
+		health.Message = fmt.Sprintf("%d of %d broadcast slots are in use", stats.BroadcastBacklog, stats.BroadcastCapacity)
+	}
+	return health, nil
+}
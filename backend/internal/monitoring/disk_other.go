@@ -0,0 +1,8 @@
+//go:build !unix
+
+package monitoring
+
+// diskSpace is not available on this platform
+func diskSpace(path string) (free, total uint64, ok bool) {
+	return 0, 0, false
+}
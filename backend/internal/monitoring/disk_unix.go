@@ -0,0 +1,14 @@
+//go:build unix
+
+package monitoring
+
+import "syscall"
+
+// diskSpace returns the free and total bytes of the filesystem of path
+func diskSpace(path string) (free, total uint64, ok bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, false
+	}
+	return stat.Bavail * uint64(stat.Bsize), stat.Blocks * uint64(stat.Bsize), true
+}
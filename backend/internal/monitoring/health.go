@@ -107,11 +107,12 @@ func (h *HealthMonitor) GetHealth() *SystemHealth {
 			start := time.Now()
 			componentHealth, err := c.Check()
 			if err != nil {
-				componentHealth = &ComponentHealth{
-					Name:    n,
-					Status:  HealthStatusDown,
-					Message: err.Error(),
+				// Keep the details of the failed check
+				if componentHealth == nil {
+					componentHealth = &ComponentHealth{Name: n}
 				}
+				componentHealth.Status = HealthStatusDown
+				componentHealth.Message = err.Error()
 			}
 			componentHealth.ResponseTime = time.Since(start)
 			componentHealth.LastChecked = time.Now()
@@ -176,25 +177,24 @@ func (h *HealthMonitor) LivenessHandler() http.HandlerFunc {
 	}
 }
 
-// ReadinessHandler returns a readiness check handler
+// ReadinessHandler returns a readiness check handler. A degraded system is
+// still ready; the components say what is degraded, with the details and
+// thresholds of each check.
 func (h *HealthMonitor) ReadinessHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		health := h.GetHealth()
 		
+		status, statusCode := "ready", http.StatusOK
 		if health.Status == HealthStatusDown {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusServiceUnavailable)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"status": "not_ready",
-				"components": health.Components,
-			})
-			return
+			status, statusCode = "not_ready", http.StatusServiceUnavailable
 		}
 		
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status": "ready",
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":     status,
+			"health":     health.Status,
+			"components": health.Components,
 		})
 	}
 }
@@ -0,0 +1,81 @@
+package monitoring
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// unmatchedRoute labels requests no route matched, so that arbitrary paths
+// do not each get their own series
+const unmatchedRoute = "unmatched"
+
+// RequestMetrics is chi middleware recording, per method and route pattern,
+// the requests served by status class (http_requests), how long they took
+// (http_request_duration_ms) and how many are in flight
+// (http_requests_in_flight). WebSocket upgrades are connections rather than
+// requests and are left out.
+func RequestMetrics(metrics *MetricsCollector) func(http.Handler) http.Handler {
+	metrics.SetDescription("http_requests", "HTTP requests served, by method, route and status class")
+	metrics.SetDescription("http_request_duration_ms", "HTTP request duration in milliseconds, by method, route and status class")
+	metrics.SetDescription("http_requests_in_flight", "HTTP requests being served, by method and route")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isWebSocketUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			labels := map[string]string{"method": r.Method, "route": routePattern(r)}
+			metrics.AddLabeledGauge("http_requests_in_flight", labels, 1)
+			defer metrics.AddLabeledGauge("http_requests_in_flight", labels, -1)
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			next.ServeHTTP(ww, r)
+			duration := time.Since(start)
+
+			status := ww.Status()
+			if status == 0 {
+				// Nothing written, so net/http answers 200
+				status = http.StatusOK
+			}
+			labels = map[string]string{
+				"method": r.Method,
+				"route":  labels["route"],
+				"status": strconv.Itoa(status/100) + "xx",
+			}
+			metrics.IncrementLabeledCounter("http_requests", labels, 1)
+			metrics.RecordLabeledHistogram("http_request_duration_ms", labels, float64(duration.Microseconds())/1000)
+		})
+	}
+}
+
+// routePattern finds the pattern of the route that will serve a request,
+// such as /api/v1/dashboards/{id}
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return unmatchedRoute
+	}
+	path := r.URL.RawPath
+	if path == "" {
+		path = r.URL.Path
+	}
+
+	// Matching fills a context of its own, leaving the request's to the router
+	match := chi.NewRouteContext()
+	if !rctx.Routes.Match(match, r.Method, path) {
+		return unmatchedRoute
+	}
+	return match.RoutePattern()
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
@@ -1,6 +1,11 @@
 package monitoring
 
 import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -23,17 +28,68 @@ type Metric struct {
 	Labels      map[string]string      `json:"labels,omitempty"`
 	Timestamp   time.Time              `json:"timestamp"`
 	Description string                 `json:"description,omitempty"`
+
+	histogram string // the histogram a statistic was derived from
 }
 
 // MetricsCollector collects and manages metrics
 type MetricsCollector struct {
 	mu              sync.RWMutex
 	counters        map[string]*int64
+	labeledCounters map[string]*labeledCounter
 	gauges          map[string]*float64
+	labeledGauges   map[string]*labeledGauge
 	histograms      map[string]*Histogram
+	labeledHistograms map[string]*labeledHistogram
+	histogramBuckets  map[string][]float64
 	descriptions    map[string]string
 	ingestionRate   *RateCounter
 	queryRate       *RateCounter
+	
+	// Bounds on the labeled series, see cardinality.go
+	maxSeries    int
+	seriesLimits map[string]int
+	seriesTTL    time.Duration
+	seriesCounts map[string]int    // label sets by metric, the overflow series aside
+	overflowed   map[string]uint64 // updates sent to the overflow series by metric
+}
+
+// labeledCounter is a counter series identified by a name and label set
+type labeledCounter struct {
+	name   string
+	labels map[string]string
+	value  int64
+	seriesState
+}
+
+// labeledGauge is a gauge series identified by a name and label set
+type labeledGauge struct {
+	name   string
+	labels map[string]string
+	value  float64
+	seriesState
+}
+
+// labeledHistogram is a histogram series identified by a name and label set
+type labeledHistogram struct {
+	name      string
+	labels    map[string]string
+	histogram *Histogram
+	seriesState
+}
+
+// HistogramSnapshot is the state of a histogram series. Counts are
+// cumulative, one per bucket upper bound, and Count includes the values
+// above the last bound.
+type HistogramSnapshot struct {
+	Name    string
+	Labels  map[string]string
+	Buckets []float64
+	Counts  []int64
+	Count   int64
+	Sum     float64
+
+	Description string
 }
 
 // Histogram tracks distribution of values
@@ -61,11 +117,19 @@ type RateCounter struct {
 func NewMetricsCollector() *MetricsCollector {
 	return &MetricsCollector{
 		counters:      make(map[string]*int64),
+		labeledCounters: make(map[string]*labeledCounter),
 		gauges:        make(map[string]*float64),
+		labeledGauges: make(map[string]*labeledGauge),
 		histograms:    make(map[string]*Histogram),
+		labeledHistograms: make(map[string]*labeledHistogram),
+		histogramBuckets:  make(map[string][]float64),
 		descriptions:  make(map[string]string),
 		ingestionRate: NewRateCounter(time.Minute, time.Second),
 		queryRate:     NewRateCounter(time.Minute, time.Second),
+		maxSeries:     DefaultMaxSeriesPerMetric,
+		seriesLimits:  make(map[string]int),
+		seriesCounts:  make(map[string]int),
+		overflowed:    make(map[string]uint64),
 	}
 }
 
@@ -83,6 +147,55 @@ func (m *MetricsCollector) IncrementCounter(name string, delta int64) {
 	atomic.AddInt64(counter, delta)
 }
 
+// IncrementLabeledCounter increments the counter series for name and labels
+func (m *MetricsCollector) IncrementLabeledCounter(name string, labels map[string]string, delta int64) {
+	key := seriesKey(name, labels)
+	
+	m.mu.Lock()
+	counter, exists := m.labeledCounters[key]
+	if !exists {
+		var overflow bool
+		key, labels, overflow = m.admitSeries(name, key, labels)
+		if counter, exists = m.labeledCounters[key]; !exists {
+			counter = &labeledCounter{name: name, labels: copyLabels(labels)}
+			counter.overflow = overflow
+			m.labeledCounters[key] = counter
+		}
+	}
+	m.mu.Unlock()
+	
+	counter.touch()
+	atomic.AddInt64(&counter.value, delta)
+}
+
+// copyLabels copies a label set so callers may reuse theirs
+func copyLabels(labels map[string]string) map[string]string {
+	copied := make(map[string]string, len(labels))
+	for k, v := range labels {
+		copied[k] = v
+	}
+	return copied
+}
+
+// seriesKey builds a stable identifier for a metric name and label set
+func seriesKey(name string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	
+	var sb strings.Builder
+	sb.WriteString(name)
+	for _, k := range keys {
+		sb.WriteString("|")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(labels[k])
+	}
+	return sb.String()
+}
+
 // SetGauge sets a gauge metric value
 func (m *MetricsCollector) SetGauge(name string, value float64) {
 	m.mu.Lock()
@@ -94,12 +207,94 @@ func (m *MetricsCollector) SetGauge(name string, value float64) {
 	*m.gauges[name] = value
 }
 
+// AddLabeledGauge adds delta to the gauge series for name and labels
+func (m *MetricsCollector) AddLabeledGauge(name string, labels map[string]string, delta float64) {
+	key := seriesKey(name, labels)
+	
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	gauge, exists := m.labeledGauges[key]
+	if !exists {
+		var overflow bool
+		key, labels, overflow = m.admitSeries(name, key, labels)
+		if gauge, exists = m.labeledGauges[key]; !exists {
+			gauge = &labeledGauge{name: name, labels: copyLabels(labels)}
+			gauge.overflow = overflow
+			m.labeledGauges[key] = gauge
+		}
+	}
+	gauge.touch()
+	gauge.value += delta
+}
+
+// defaultHistogramBuckets are the bucket upper bounds of the histograms
+// recorded by the server, fine enough for histogram_quantile to estimate
+// their quantiles. Other histograms use genericHistogramBuckets.
+var defaultHistogramBuckets = map[string][]float64{
+	"http_request_duration_ms": {5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000},
+	"query_duration_ms":        {5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000},
+	"batch_write_duration_ms":  {5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000},
+	"ingestion_batch_size":     {1, 10, 50, 100, 250, 500, 1000, 5000},
+	"parse_duration_ms":        {0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 50},
+	"transform_duration_ms":    {0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 50},
+}
+
+var genericHistogramBuckets = []float64{0.1, 0.5, 1, 5, 10, 50, 100, 500, 1000}
+
+// SetHistogramBuckets sets the bucket upper bounds of the histograms named
+// name created from now on
+func (m *MetricsCollector) SetHistogramBuckets(name string, buckets []float64) {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.histogramBuckets[name] = sorted
+}
+
+// ParseHistogramBuckets parses the bucket upper bounds of a histogram, as
+// its name and bounds separated by spaces: query_duration_ms:10 100 1000
+func ParseHistogramBuckets(spec string) (string, []float64, error) {
+	name, bounds, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok || name == "" {
+		return "", nil, fmt.Errorf("invalid histogram buckets %q: expected metric:bound bound...", spec)
+	}
+	var buckets []float64
+	for _, field := range strings.Fields(bounds) {
+		bound, err := strconv.ParseFloat(field, 64)
+		if err != nil || math.IsNaN(bound) || math.IsInf(bound, 0) {
+			return "", nil, fmt.Errorf("invalid histogram buckets %q: bad bound %q", spec, field)
+		}
+		if len(buckets) > 0 && bound <= buckets[len(buckets)-1] {
+			return "", nil, fmt.Errorf("invalid histogram buckets %q: bounds must increase", spec)
+		}
+		buckets = append(buckets, bound)
+	}
+	if len(buckets) == 0 {
+		return "", nil, fmt.Errorf("invalid histogram buckets %q: no bounds", spec)
+	}
+	return name, buckets, nil
+}
+
+// newHistogram creates a histogram with the buckets set for name, if any.
+// Callers hold the lock.
+func (m *MetricsCollector) newHistogram(name string) *Histogram {
+	if buckets, ok := m.histogramBuckets[name]; ok {
+		return NewHistogram(buckets)
+	}
+	if buckets, ok := defaultHistogramBuckets[name]; ok {
+		return NewHistogram(buckets)
+	}
+	return NewHistogram(genericHistogramBuckets)
+}
+
 // RecordHistogram records a value in a histogram
 func (m *MetricsCollector) RecordHistogram(name string, value float64) {
 	m.mu.Lock()
 	hist, exists := m.histograms[name]
 	if !exists {
-		hist = NewHistogram([]float64{0.1, 0.5, 1, 5, 10, 50, 100, 500, 1000})
+		hist = m.newHistogram(name)
 		m.histograms[name] = hist
 	}
 	m.mu.Unlock()
@@ -107,6 +302,47 @@ func (m *MetricsCollector) RecordHistogram(name string, value float64) {
 	hist.Record(value)
 }
 
+// RecordLabeledHistogram records a value in the histogram series for name
+// and labels
+func (m *MetricsCollector) RecordLabeledHistogram(name string, labels map[string]string, value float64) {
+	key := seriesKey(name, labels)
+	
+	m.mu.Lock()
+	series, exists := m.labeledHistograms[key]
+	if !exists {
+		var overflow bool
+		key, labels, overflow = m.admitSeries(name, key, labels)
+		if series, exists = m.labeledHistograms[key]; !exists {
+			series = &labeledHistogram{name: name, labels: copyLabels(labels), histogram: m.newHistogram(name)}
+			series.overflow = overflow
+			m.labeledHistograms[key] = series
+		}
+	}
+	m.mu.Unlock()
+	
+	series.touch()
+	series.histogram.Record(value)
+}
+
+// GetHistograms returns the state of every histogram series
+func (m *MetricsCollector) GetHistograms() []HistogramSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	snapshots := make([]HistogramSnapshot, 0, len(m.histograms)+len(m.labeledHistograms))
+	for name, hist := range m.histograms {
+		snapshot := hist.snapshot(name, nil)
+		snapshot.Description = m.descriptions[name]
+		snapshots = append(snapshots, snapshot)
+	}
+	for _, series := range m.labeledHistograms {
+		snapshot := series.histogram.snapshot(series.name, series.labels)
+		snapshot.Description = m.descriptions[series.name]
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots
+}
+
 // SetDescription sets description for a metric
 func (m *MetricsCollector) SetDescription(name string, description string) {
 	m.mu.Lock()
@@ -134,6 +370,18 @@ func (m *MetricsCollector) GetMetrics() []Metric {
 		})
 	}
 	
+	// Collect labeled counters
+	for _, counter := range m.labeledCounters {
+		metrics = append(metrics, Metric{
+			Name:        counter.name,
+			Type:        string(MetricTypeCounter),
+			Value:       float64(atomic.LoadInt64(&counter.value)),
+			Labels:      counter.labels,
+			Timestamp:   timestamp,
+			Description: m.descriptions[counter.name],
+		})
+	}
+	
 	// Collect gauges
 	for name, gauge := range m.gauges {
 		metrics = append(metrics, Metric{
@@ -145,6 +393,18 @@ func (m *MetricsCollector) GetMetrics() []Metric {
 		})
 	}
 	
+	// Collect labeled gauges
+	for _, gauge := range m.labeledGauges {
+		metrics = append(metrics, Metric{
+			Name:        gauge.name,
+			Type:        string(MetricTypeGauge),
+			Value:       gauge.value,
+			Labels:      gauge.labels,
+			Timestamp:   timestamp,
+			Description: m.descriptions[gauge.name],
+		})
+	}
+	
 	// Collect histograms
 	for name, hist := range m.histograms {
 		stats := hist.GetStats()
@@ -155,6 +415,22 @@ func (m *MetricsCollector) GetMetrics() []Metric {
 				Value: value,
 				Timestamp: timestamp,
 				Description: m.descriptions[name],
+				histogram: name,
+			})
+		}
+	}
+	
+	// Collect labeled histograms
+	for _, series := range m.labeledHistograms {
+		for statName, value := range series.histogram.GetStats() {
+			metrics = append(metrics, Metric{
+				Name:        series.name + "_" + statName,
+				Type:        string(MetricTypeGauge),
+				Value:       value,
+				Labels:      series.labels,
+				Timestamp:   timestamp,
+				Description: m.descriptions[series.name],
+				histogram:   series.name,
 			})
 		}
 	}
@@ -188,7 +464,7 @@ func (m *MetricsCollector) RecordIngestion(count int) {
 // RecordQuery records a query execution
 func (m *MetricsCollector) RecordQuery(duration time.Duration) {
 	m.IncrementCounter("total_queries_executed", 1)
-	m.RecordHistogram("query_duration_ms", float64(duration.Milliseconds()))
+	m.RecordHistogram("query_duration_ms", float64(duration.Microseconds())/1000)
 	m.queryRate.Increment(1)
 }
 
@@ -264,6 +540,27 @@ func (h *Histogram) GetStats() map[string]float64 {
 	}
 }
 
+// snapshot returns the state of the histogram as the series name with labels
+func (h *Histogram) snapshot(name string, labels map[string]string) HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	
+	counts := make([]int64, len(h.buckets))
+	cumulative := int64(0)
+	for i := range h.buckets {
+		cumulative += h.values[i]
+		counts[i] = cumulative
+	}
+	return HistogramSnapshot{
+		Name:    name,
+		Labels:  labels,
+		Buckets: h.buckets,
+		Counts:  counts,
+		Count:   h.count,
+		Sum:     h.sum,
+	}
+}
+
 func (h *Histogram) getPercentile(p float64) float64 {
 	// Simple approximation - return the bucket threshold
 	target := int64(float64(h.count) * p)
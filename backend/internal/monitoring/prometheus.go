@@ -32,6 +32,10 @@ func (p *PrometheusExporter) Export(w io.Writer) error {
 	// Group metrics by name for proper Prometheus formatting
 	metricGroups := make(map[string][]Metric)
 	for _, metric := range metricsData {
+		if metric.histogram != "" {
+			// Histograms are written whole below
+			continue
+		}
 		baseName := getBaseMetricName(metric.Name)
 		metricGroups[baseName] = append(metricGroups[baseName], metric)
 	}
@@ -53,9 +57,16 @@ func (p *PrometheusExporter) Export(w io.Writer) error {
 		// Write metric help and type
 		metric := metrics[0]
 		prometheusName := toPrometheusName(baseName)
+		if metric.Type == "counter" && !strings.HasSuffix(prometheusName, "_total") {
+			// Counter samples are named with _total and so must be their HELP and TYPE
+			prometheusName += "_total"
+		}
 		
 		// Write HELP
 		help := getMetricHelp(baseName)
+		if metric.Description != "" {
+			help = metric.Description
+		}
 		fmt.Fprintf(w, "# HELP %s %s\n", prometheusName, help)
 		
 		// Write TYPE
@@ -69,12 +80,52 @@ func (p *PrometheusExporter) Export(w io.Writer) error {
 		fmt.Fprintln(w)
 	}
 
-	// Add Go runtime metrics
+	writeHistograms(w, p.metrics.GetHistograms())
+
+	// Add Go runtime and process metrics
 	writeGoMetrics(w)
+	writeProcessMetrics(w)
 
 	return nil
 }
 
+// writeHistograms writes histogram series with their cumulative buckets,
+// sum and count
+func writeHistograms(w io.Writer, snapshots []HistogramSnapshot) {
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].Name != snapshots[j].Name {
+			return snapshots[i].Name < snapshots[j].Name
+		}
+		return buildLabels(snapshots[i].Labels) < buildLabels(snapshots[j].Labels)
+	})
+
+	for i, snapshot := range snapshots {
+		name := toPrometheusName(snapshot.Name)
+		if i == 0 || snapshots[i-1].Name != snapshot.Name {
+			help := snapshot.Description
+			if help == "" {
+				help = getMetricHelp(snapshot.Name)
+			}
+			fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+			fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+		}
+
+		labels := buildLabels(snapshot.Labels)
+		if labels != "" {
+			labels += ","
+		}
+		for j, bound := range snapshot.Buckets {
+			fmt.Fprintf(w, "%s_bucket{%sle=\"%g\"} %d\n", name, labels, bound, snapshot.Counts[j])
+		}
+		fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, snapshot.Count)
+		fmt.Fprintf(w, "%s_sum%s %g\n", name, formatLabels(buildLabels(snapshot.Labels)), snapshot.Sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", name, formatLabels(buildLabels(snapshot.Labels)), snapshot.Count)
+		if i == len(snapshots)-1 || snapshots[i+1].Name != snapshot.Name {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
 // getBaseMetricName extracts the base metric name without suffixes
 func getBaseMetricName(name string) string {
 	// Remove common suffixes for grouping
@@ -146,26 +197,7 @@ func writeMetricValue(w io.Writer, name string, metric Metric) {
 	// Build labels
 	labels := buildLabels(metric.Labels)
 	
-	// Handle different metric types
 	switch metric.Type {
-	case "histogram":
-		// For histograms, we need to write buckets, sum, and count
-		if strings.HasSuffix(metric.Name, "_p50") || 
-		   strings.HasSuffix(metric.Name, "_p90") || 
-		   strings.HasSuffix(metric.Name, "_p99") ||
-		   strings.HasSuffix(metric.Name, "_avg") {
-			// These are pre-calculated percentiles, write as gauges
-			percentile := getPercentileFromName(metric.Name)
-			if percentile != "" {
-				fmt.Fprintf(w, "%s{%squantile=\"%s\"} %g\n", name, labels, percentile, metric.Value)
-			} else if strings.HasSuffix(metric.Name, "_avg") {
-				// Average as a separate gauge
-				fmt.Fprintf(w, "%s_avg%s %g\n", name, formatLabels(labels), metric.Value)
-			}
-		} else {
-			// Regular histogram metric
-			fmt.Fprintf(w, "%s%s %g\n", name, formatLabels(labels), metric.Value)
-		}
 	case "counter":
 		// Ensure counter names end with _total
 		if !strings.HasSuffix(name, "_total") {
@@ -178,18 +210,6 @@ func writeMetricValue(w io.Writer, name string, metric Metric) {
 	}
 }
 
-// getPercentileFromName extracts percentile from metric name
-func getPercentileFromName(name string) string {
-	if strings.HasSuffix(name, "_p50") {
-		return "0.5"
-	} else if strings.HasSuffix(name, "_p90") {
-		return "0.9"
-	} else if strings.HasSuffix(name, "_p99") {
-		return "0.99"
-	}
-	return ""
-}
-
 // buildLabels constructs label string from map
 func buildLabels(labels map[string]string) string {
 	if len(labels) == 0 {
@@ -215,97 +235,3 @@ func formatLabels(labels string) string {
 	}
 	return "{" + labels + "}"
 }
-
-// writeGoMetrics writes Go runtime metrics
-func writeGoMetrics(w io.Writer) {
-	// Process-level metrics
-	fmt.Fprintln(w, "# HELP process_cpu_seconds_total Total user and system CPU time spent in seconds.")
-	fmt.Fprintln(w, "# TYPE process_cpu_seconds_total counter")
-	fmt.Fprintln(w, "process_cpu_seconds_total 0")
-	fmt.Fprintln(w)
-	
-	fmt.Fprintln(w, "# HELP process_open_fds Number of open file descriptors.")
-	fmt.Fprintln(w, "# TYPE process_open_fds gauge")
-	fmt.Fprintln(w, "process_open_fds 0")
-	fmt.Fprintln(w)
-	
-	fmt.Fprintln(w, "# HELP process_resident_memory_bytes Resident memory size in bytes.")
-	fmt.Fprintln(w, "# TYPE process_resident_memory_bytes gauge")
-	fmt.Fprintln(w, "process_resident_memory_bytes 0")
-	fmt.Fprintln(w)
-	
-	// Go runtime metrics
-	fmt.Fprintln(w, "# HELP go_memstats_alloc_bytes Number of bytes allocated and still in use.")
-	fmt.Fprintln(w, "# TYPE go_memstats_alloc_bytes gauge")
-	fmt.Fprintln(w, "go_memstats_alloc_bytes 0")
-	fmt.Fprintln(w)
-	
-	fmt.Fprintln(w, "# HELP go_goroutines Number of goroutines that currently exist.")
-	fmt.Fprintln(w, "# TYPE go_goroutines gauge")
-	fmt.Fprintln(w, "go_goroutines 0")
-	fmt.Fprintln(w)
-	
-	fmt.Fprintln(w, "# HELP go_gc_duration_seconds A summary of the pause duration of garbage collection cycles.")
-	fmt.Fprintln(w, "# TYPE go_gc_duration_seconds summary")
-	fmt.Fprintln(w, "go_gc_duration_seconds{quantile=\"0\"} 0")
-	fmt.Fprintln(w, "go_gc_duration_seconds{quantile=\"0.25\"} 0")
-	fmt.Fprintln(w, "go_gc_duration_seconds{quantile=\"0.5\"} 0")
-	fmt.Fprintln(w, "go_gc_duration_seconds{quantile=\"0.75\"} 0")
-	fmt.Fprintln(w, "go_gc_duration_seconds{quantile=\"1\"} 0")
-	fmt.Fprintln(w, "go_gc_duration_seconds_sum 0")
-	fmt.Fprintln(w, "go_gc_duration_seconds_count 0")
-	fmt.Fprintln(w)
-	
-	fmt.Fprintln(w, "# HELP go_info Information about the Go environment.")
-	fmt.Fprintln(w, "# TYPE go_info gauge")
-	fmt.Fprintf(w, "go_info{version=\"%s\"} 1\n", "go1.21")
-}
-
-// ConvertHistogramToPrometheus converts internal histogram to Prometheus format
-func ConvertHistogramToPrometheus(name string, hist *Histogram, labels map[string]string) []Metric {
-	// This would generate the full histogram with buckets
-	// For now, returning percentiles as separate metrics
-	metrics := []Metric{}
-	
-	stats := hist.GetStats()
-	
-	// Add percentiles
-	if p50, ok := stats["p50"]; ok && p50 > 0 {
-		metrics = append(metrics, Metric{
-			Name:   name + "_p50",
-			Type:   "histogram",
-			Value:  p50,
-			Labels: labels,
-		})
-	}
-	
-	if p90, ok := stats["p90"]; ok && p90 > 0 {
-		metrics = append(metrics, Metric{
-			Name:   name + "_p90",
-			Type:   "histogram",
-			Value:  p90,
-			Labels: labels,
-		})
-	}
-	
-	if p99, ok := stats["p99"]; ok && p99 > 0 {
-		metrics = append(metrics, Metric{
-			Name:   name + "_p99",
-			Type:   "histogram",
-			Value:  p99,
-			Labels: labels,
-		})
-	}
-	
-	// Add average
-	if avg, ok := stats["avg"]; ok && avg > 0 {
-		metrics = append(metrics, Metric{
-			Name:   name + "_avg",
-			Type:   "histogram",
-			Value:  avg,
-			Labels: labels,
-		})
-	}
-	
-	return metrics
-}
\ No newline at end of file
@@ -0,0 +1,194 @@
+package monitoring
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userHZ is the rate of the clock ticks /proc reports CPU and start times
+// in. Linux fixes it at 100 for userspace.
+const userHZ = 100
+
+// writeMetric writes the HELP, TYPE and single sample of an unlabelled
+// metric
+func writeMetric(w io.Writer, name, help, metricType string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType)
+	fmt.Fprintf(w, "%s %g\n\n", name, value)
+}
+
+// writeGoMetrics writes Go runtime metrics, named as the official Go client
+// names them so that standard dashboards work
+func writeGoMetrics(w io.Writer) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	writeMetric(w, "go_goroutines", "Number of goroutines that currently exist.", "gauge", float64(runtime.NumGoroutine()))
+	writeMetric(w, "go_threads", "Number of OS threads created.", "gauge", float64(pprof.Lookup("threadcreate").Count()))
+
+	// Pause quantiles over the recent GC cycles the runtime keeps
+	stats := debug.GCStats{PauseQuantiles: make([]time.Duration, 5)}
+	debug.ReadGCStats(&stats)
+	fmt.Fprintln(w, "# HELP go_gc_duration_seconds A summary of the pause duration of garbage collection cycles.")
+	fmt.Fprintln(w, "# TYPE go_gc_duration_seconds summary")
+	for i, quantile := range []string{"0", "0.25", "0.5", "0.75", "1"} {
+		fmt.Fprintf(w, "go_gc_duration_seconds{quantile=%q} %g\n", quantile, stats.PauseQuantiles[i].Seconds())
+	}
+	fmt.Fprintf(w, "go_gc_duration_seconds_sum %g\n", stats.PauseTotal.Seconds())
+	fmt.Fprintf(w, "go_gc_duration_seconds_count %d\n\n", stats.NumGC)
+
+	fmt.Fprintln(w, "# HELP go_info Information about the Go environment.")
+	fmt.Fprintln(w, "# TYPE go_info gauge")
+	fmt.Fprintf(w, "go_info{version=%q} 1\n\n", runtime.Version())
+
+	memstats := []struct {
+		name, help, metricType string
+		value                  uint64
+	}{
+		{"go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", "gauge", m.Alloc},
+		{"go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.", "counter", m.TotalAlloc},
+		{"go_memstats_sys_bytes", "Number of bytes obtained from system.", "gauge", m.Sys},
+		{"go_memstats_lookups_total", "Total number of pointer lookups.", "counter", m.Lookups},
+		{"go_memstats_mallocs_total", "Total number of mallocs.", "counter", m.Mallocs},
+		{"go_memstats_frees_total", "Total number of frees.", "counter", m.Frees},
+		{"go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", "gauge", m.HeapAlloc},
+		{"go_memstats_heap_sys_bytes", "Number of heap bytes obtained from system.", "gauge", m.HeapSys},
+		{"go_memstats_heap_idle_bytes", "Number of heap bytes waiting to be used.", "gauge", m.HeapIdle},
+		{"go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", "gauge", m.HeapInuse},
+		{"go_memstats_heap_released_bytes", "Number of heap bytes released to OS.", "gauge", m.HeapReleased},
+		{"go_memstats_heap_objects", "Number of allocated objects.", "gauge", m.HeapObjects},
+		{"go_memstats_stack_inuse_bytes", "Number of bytes in use by the stack allocator.", "gauge", m.StackInuse},
+		{"go_memstats_stack_sys_bytes", "Number of bytes obtained from system for stack allocator.", "gauge", m.StackSys},
+		{"go_memstats_mspan_inuse_bytes", "Number of bytes in use by mspan structures.", "gauge", m.MSpanInuse},
+		{"go_memstats_mspan_sys_bytes", "Number of bytes used for mspan structures obtained from system.", "gauge", m.MSpanSys},
+		{"go_memstats_mcache_inuse_bytes", "Number of bytes in use by mcache structures.", "gauge", m.MCacheInuse},
+		{"go_memstats_mcache_sys_bytes", "Number of bytes used for mcache structures obtained from system.", "gauge", m.MCacheSys},
+		{"go_memstats_buck_hash_sys_bytes", "Number of bytes used by the profiling bucket hash table.", "gauge", m.BuckHashSys},
+		{"go_memstats_gc_sys_bytes", "Number of bytes used for garbage collection system metadata.", "gauge", m.GCSys},
+		{"go_memstats_other_sys_bytes", "Number of bytes used for other system allocations.", "gauge", m.OtherSys},
+		{"go_memstats_next_gc_bytes", "Number of heap bytes when next garbage collection will take place.", "gauge", m.NextGC},
+	}
+	for _, stat := range memstats {
+		writeMetric(w, stat.name, stat.help, stat.metricType, float64(stat.value))
+	}
+	writeMetric(w, "go_memstats_last_gc_time_seconds", "Number of seconds since 1970 of last garbage collection.", "gauge",
+		float64(m.LastGC)/1e9)
+	writeMetric(w, "go_memstats_gc_cpu_fraction", "The fraction of this program's available CPU time used by the GC since the program started.", "gauge",
+		m.GCCPUFraction)
+}
+
+// processStats are the figures of the process read from /proc
+type processStats struct {
+	cpuSeconds    float64
+	residentBytes float64
+	virtualBytes  float64
+	startTime     float64 // seconds since 1970
+	openFDs       int
+	maxFDs        float64
+}
+
+// writeProcessMetrics writes process metrics. They are read from /proc, so
+// they are left out where it does not exist.
+func writeProcessMetrics(w io.Writer) {
+	stats, err := readProcessStats()
+	if err != nil {
+		return
+	}
+
+	writeMetric(w, "process_cpu_seconds_total", "Total user and system CPU time spent in seconds.", "counter", stats.cpuSeconds)
+	writeMetric(w, "process_open_fds", "Number of open file descriptors.", "gauge", float64(stats.openFDs))
+	if stats.maxFDs > 0 {
+		writeMetric(w, "process_max_fds", "Maximum number of open file descriptors.", "gauge", stats.maxFDs)
+	}
+	writeMetric(w, "process_resident_memory_bytes", "Resident memory size in bytes.", "gauge", stats.residentBytes)
+	writeMetric(w, "process_virtual_memory_bytes", "Virtual memory size in bytes.", "gauge", stats.virtualBytes)
+	if stats.startTime > 0 {
+		writeMetric(w, "process_start_time_seconds", "Start time of the process since unix epoch in seconds.", "gauge", stats.startTime)
+	}
+}
+
+// readProcessStats reads the CPU time, memory, start time and file
+// descriptors of this process from /proc
+func readProcessStats() (processStats, error) {
+	var stats processStats
+
+	data, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		return stats, err
+	}
+	// The command name is parenthesized and may contain spaces, so the
+	// fields are counted from after it. They start at the state, field 3.
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return stats, fmt.Errorf("malformed /proc/self/stat")
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 22 {
+		return stats, fmt.Errorf("malformed /proc/self/stat")
+	}
+	field := func(n int) float64 {
+		value, _ := strconv.ParseFloat(fields[n-3], 64)
+		return value
+	}
+	stats.cpuSeconds = (field(14) + field(15)) / userHZ
+	stats.virtualBytes = field(23)
+	stats.residentBytes = field(24) * float64(os.Getpagesize())
+	if bootTime, err := readBootTime(); err == nil {
+		stats.startTime = bootTime + field(22)/userHZ
+	}
+
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return stats, err
+	}
+	stats.openFDs = len(fds)
+	stats.maxFDs = readMaxFDs()
+
+	return stats, nil
+}
+
+// readBootTime returns when the system booted, in seconds since 1970
+func readBootTime() (float64, error) {
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "btime "); ok {
+			return strconv.ParseFloat(strings.TrimSpace(value), 64)
+		}
+	}
+	return 0, fmt.Errorf("no btime in /proc/stat")
+}
+
+// readMaxFDs returns the soft limit on open file descriptors, zero if
+// unknown or unlimited
+func readMaxFDs() float64 {
+	data, err := os.ReadFile("/proc/self/limits")
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "Max open files"); ok {
+			fields := strings.Fields(value)
+			if len(fields) == 0 {
+				return 0
+			}
+			limit, _ := strconv.ParseFloat(fields[0], 64)
+			return limit
+		}
+	}
+	return 0
+}
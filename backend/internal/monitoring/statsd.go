@@ -0,0 +1,216 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultStatsDFlushInterval = 10 * time.Second
+	// defaultStatsDPacketSize keeps datagrams within an Ethernet MTU
+	defaultStatsDPacketSize = 1432
+)
+
+// StatsDOptions configure a StatsD sink
+type StatsDOptions struct {
+	Address       string        // host:port of the agent, over UDP
+	Prefix        string        // prepended to every metric name
+	Tags          []string      // such as env:prod, added to every metric, DogStatsD only
+	FlushInterval time.Duration // 10s when 0
+	// DogStatsD sends metric labels as tags. Plain StatsD has no tags, so
+	// label values are appended to the metric name instead, in label order.
+	DogStatsD     bool
+	MaxPacketSize int // bytes per datagram, 1432 when 0
+}
+
+// StatsDSink pushes the collector's metrics to a StatsD or DogStatsD agent,
+// such as the Datadog agent or Telegraf, for environments that do not
+// scrape Prometheus. Counters are sent as the increase since the previous
+// flush, gauges as their value, and histograms as their statistics with
+// their count and sum as counters.
+type StatsDSink struct {
+	metrics *MetricsCollector
+	options StatsDOptions
+	conn    net.Conn
+
+	mu       sync.Mutex
+	counters map[string]float64 // the counter values sent last, by series
+}
+
+// NewStatsDSink creates a sink sending metrics to the agent at
+// options.Address
+func NewStatsDSink(metrics *MetricsCollector, options StatsDOptions) (*StatsDSink, error) {
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = defaultStatsDFlushInterval
+	}
+	if options.MaxPacketSize <= 0 {
+		options.MaxPacketSize = defaultStatsDPacketSize
+	}
+	conn, err := net.Dial("udp", options.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to StatsD agent: %w", err)
+	}
+	return &StatsDSink{
+		metrics:  metrics,
+		options:  options,
+		conn:     conn,
+		counters: make(map[string]float64),
+	}, nil
+}
+
+// Start flushes metrics on the flush interval until ctx is cancelled, then
+// flushes once more and closes the connection
+func (s *StatsDSink) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.options.FlushInterval)
+	defer ticker.Stop()
+	defer s.conn.Close()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				log.Warn().Err(err).Str("address", s.options.Address).Msg("Failed to send metrics to StatsD")
+			}
+		case <-ctx.Done():
+			s.Flush()
+			return
+		}
+	}
+}
+
+// Flush sends the current metrics to the agent
+func (s *StatsDSink) Flush() error {
+	s.mu.Lock()
+	lines := s.lines()
+	s.mu.Unlock()
+
+	var packet strings.Builder
+	for _, line := range lines {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > s.options.MaxPacketSize {
+			if err := s.send(packet.String()); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	if packet.Len() > 0 {
+		return s.send(packet.String())
+	}
+	return nil
+}
+
+func (s *StatsDSink) send(packet string) error {
+	_, err := s.conn.Write([]byte(packet))
+	return err
+}
+
+// lines formats the current metrics, one per line
+func (s *StatsDSink) lines() []string {
+	var lines []string
+	seen := make(map[string]bool, len(s.counters))
+	for _, m := range s.metrics.GetMetrics() {
+		// The count and sum of histograms are sent as counters below
+		if m.histogram != "" && (m.Name == m.histogram+"_count" || m.Name == m.histogram+"_sum") {
+			continue
+		}
+		if m.Type == string(MetricTypeCounter) {
+			seen[seriesKey(m.Name, m.Labels)] = true
+			if line, ok := s.counterLine(m.Name, m.Labels, m.Value); ok {
+				lines = append(lines, line)
+			}
+			continue
+		}
+		lines = append(lines, s.line(m.Name, m.Labels, m.Value, "g"))
+	}
+	for _, snapshot := range s.metrics.GetHistograms() {
+		seen[seriesKey(snapshot.Name+"_count", snapshot.Labels)] = true
+		seen[seriesKey(snapshot.Name+"_sum", snapshot.Labels)] = true
+		if line, ok := s.counterLine(snapshot.Name+"_count", snapshot.Labels, float64(snapshot.Count)); ok {
+			lines = append(lines, line)
+		}
+		if line, ok := s.counterLine(snapshot.Name+"_sum", snapshot.Labels, snapshot.Sum); ok {
+			lines = append(lines, line)
+		}
+	}
+	// Forget the counters of evicted series
+	for key := range s.counters {
+		if !seen[key] {
+			delete(s.counters, key)
+		}
+	}
+	return lines
+}
+
+// counterLine formats the increase of a counter since the previous flush,
+// false when it has not changed
+func (s *StatsDSink) counterLine(name string, labels map[string]string, value float64) (string, bool) {
+	key := seriesKey(name, labels)
+	delta := value - s.counters[key]
+	if delta < 0 {
+		delta = value // the counter was reset
+	}
+	s.counters[key] = value
+	if delta == 0 {
+		return "", false
+	}
+	return s.line(name, labels, delta, "c"), true
+}
+
+// line formats a metric as name:value|type, with its tags for DogStatsD
+func (s *StatsDSink) line(name string, labels map[string]string, value float64, metricType string) string {
+	keys := sortedKeys(labels)
+
+	var b strings.Builder
+	b.WriteString(statsDName(s.options.Prefix + name))
+	if !s.options.DogStatsD {
+		for _, key := range keys {
+			b.WriteByte('.')
+			b.WriteString(statsDName(labels[key]))
+		}
+	}
+	b.WriteByte(':')
+	b.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	b.WriteByte('|')
+	b.WriteString(metricType)
+
+	if s.options.DogStatsD {
+		tags := make([]string, 0, len(keys)+len(s.options.Tags))
+		for _, key := range keys {
+			tags = append(tags, statsDTag(key)+":"+statsDTag(labels[key]))
+		}
+		for _, tag := range s.options.Tags {
+			tags = append(tags, statsDTag(tag))
+		}
+		if len(tags) > 0 {
+			b.WriteString("|#")
+			b.WriteString(strings.Join(tags, ","))
+		}
+	}
+	return b.String()
+}
+
+// statsDName replaces the characters StatsD uses as separators in a name
+var statsDName = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", " ", "_", "\n", "_", "/", "_").Replace
+
+// statsDTag replaces the characters DogStatsD uses as separators in a tag
+var statsDTag = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_").Replace
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,300 @@
+package notification
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"path"
+	"regexp"
+	"text/template"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/monitoring"
+)
+
+// ChannelType is the kind of service a channel delivers to
+type ChannelType string
+
+const (
+	ChannelSlack     ChannelType = "slack"
+	ChannelEmail     ChannelType = "email"
+	ChannelPagerDuty ChannelType = "pagerduty"
+	ChannelWebhook   ChannelType = "webhook"
+)
+
+// redacted replaces secrets in channels returned by the API. Sending it
+// back in an update keeps the stored secret.
+const redacted = "********"
+
+// DefaultPagerDutyURL is the PagerDuty Events API v2 endpoint
+const DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+var channelNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Channel is a destination for alert notifications, such as ops-slack.
+// Only the configuration of its type is used.
+type Channel struct {
+	Name        string           `json:"name"`
+	Type        ChannelType      `json:"type"`
+	Description string           `json:"description,omitempty"`
+	Slack       *SlackConfig     `json:"slack,omitempty"`
+	Email       *EmailConfig     `json:"email,omitempty"`
+	PagerDuty   *PagerDutyConfig `json:"pagerduty,omitempty"`
+	Webhook     *WebhookConfig   `json:"webhook,omitempty"`
+	CreatedBy   string           `json:"created_by,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// SlackConfig posts to a Slack incoming webhook
+type SlackConfig struct {
+	WebhookURL string `json:"webhook_url"` // secret
+	Channel    string `json:"channel,omitempty"`
+	Username   string `json:"username,omitempty"`
+}
+
+// EmailConfig sends mail through an SMTP server. Port 465 uses implicit
+// TLS; on other ports STARTTLS is used when the server offers it.
+type EmailConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port,omitempty"` // 587 by default
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"` // secret
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// PagerDutyConfig triggers and resolves PagerDuty incidents through the
+// Events API v2
+type PagerDutyConfig struct {
+	RoutingKey string `json:"routing_key"` // secret
+	URL        string `json:"url,omitempty"`
+}
+
+// WebhookConfig sends notifications to any HTTP endpoint. The body is the
+// notification as JSON unless BodyTemplate, a Go text/template executed
+// with the notification, is set.
+type WebhookConfig struct {
+	URL          string            `json:"url"`
+	Method       string            `json:"method,omitempty"`  // POST by default
+	Headers      map[string]string `json:"headers,omitempty"` // values are secrets
+	BodyTemplate string            `json:"body_template,omitempty"`
+	ContentType  string            `json:"content_type,omitempty"` // application/json by default
+}
+
+// Route sends the alerts it matches to channels. An alert matches when its
+// severity is one of Severities, if any are given, and each label in Match
+// matches its shell pattern, such as dashboard_id=prod-*. Alerts are
+// labelled with alertname, source, severity and the scalar fields of
+// their details.
+type Route struct {
+	Name         string                     `json:"name"`
+	Severities   []monitoring.AlertSeverity `json:"severities,omitempty"`
+	Match        map[string]string          `json:"match,omitempty"`
+	Channels     []string                   `json:"channels"`
+	SendResolved bool                       `json:"send_resolved"`
+	Continue     bool                       `json:"continue"` // to later routes after matching
+}
+
+// validateChannel checks a channel and fills in its defaults
+func validateChannel(channel *Channel) error {
+	if !channelNamePattern.MatchString(channel.Name) {
+		return fmt.Errorf("invalid channel name %q: use lowercase letters, digits, - and _", channel.Name)
+	}
+
+	switch channel.Type {
+	case ChannelSlack:
+		if channel.Slack == nil {
+			return fmt.Errorf("slack channel %s has no slack configuration", channel.Name)
+		}
+		if err := validateURL(channel.Slack.WebhookURL); err != nil {
+			return fmt.Errorf("invalid slack webhook_url: %w", err)
+		}
+	case ChannelEmail:
+		config := channel.Email
+		if config == nil {
+			return fmt.Errorf("email channel %s has no email configuration", channel.Name)
+		}
+		if config.Host == "" {
+			return fmt.Errorf("email channel %s has no SMTP host", channel.Name)
+		}
+		if config.Port == 0 {
+			config.Port = 587
+		}
+		if config.Port < 0 || config.Port > 65535 {
+			return fmt.Errorf("invalid SMTP port %d", config.Port)
+		}
+		if _, err := mail.ParseAddress(config.From); err != nil {
+			return fmt.Errorf("invalid from address %q", config.From)
+		}
+		if len(config.To) == 0 {
+			return fmt.Errorf("email channel %s has no recipients", channel.Name)
+		}
+		for _, to := range config.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				return fmt.Errorf("invalid recipient %q", to)
+			}
+		}
+	case ChannelPagerDuty:
+		config := channel.PagerDuty
+		if config == nil {
+			return fmt.Errorf("pagerduty channel %s has no pagerduty configuration", channel.Name)
+		}
+		if config.RoutingKey == "" {
+			return fmt.Errorf("pagerduty channel %s has no routing_key", channel.Name)
+		}
+		if config.URL == "" {
+			config.URL = DefaultPagerDutyURL
+		}
+		if err := validateURL(config.URL); err != nil {
+			return fmt.Errorf("invalid pagerduty url: %w", err)
+		}
+	case ChannelWebhook:
+		config := channel.Webhook
+		if config == nil {
+			return fmt.Errorf("webhook channel %s has no webhook configuration", channel.Name)
+		}
+		if err := validateURL(config.URL); err != nil {
+			return fmt.Errorf("invalid webhook url: %w", err)
+		}
+		if config.Method == "" {
+			config.Method = "POST"
+		}
+		switch config.Method {
+		case "POST", "PUT", "PATCH":
+		default:
+			return fmt.Errorf("unsupported webhook method %s", config.Method)
+		}
+		if config.BodyTemplate != "" {
+			if _, err := parseBodyTemplate(config.BodyTemplate); err != nil {
+				return fmt.Errorf("invalid body_template: %w", err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown channel type %q: use slack, email, pagerduty or webhook", channel.Type)
+	}
+	return nil
+}
+
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http(s) URL", raw)
+	}
+	return nil
+}
+
+func parseBodyTemplate(text string) (*template.Template, error) {
+	return template.New("body").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// redact returns a copy of a channel with its secrets masked
+func (c *Channel) redact() *Channel {
+	copied := *c
+	if c.Slack != nil {
+		slack := *c.Slack
+		slack.WebhookURL = redacted
+		copied.Slack = &slack
+	}
+	if c.Email != nil {
+		email := *c.Email
+		if email.Password != "" {
+			email.Password = redacted
+		}
+		copied.Email = &email
+	}
+	if c.PagerDuty != nil {
+		pagerDuty := *c.PagerDuty
+		pagerDuty.RoutingKey = redacted
+		copied.PagerDuty = &pagerDuty
+	}
+	if c.Webhook != nil {
+		webhook := *c.Webhook
+		if len(c.Webhook.Headers) > 0 {
+			webhook.Headers = make(map[string]string, len(c.Webhook.Headers))
+			for name := range c.Webhook.Headers {
+				webhook.Headers[name] = redacted
+			}
+		}
+		copied.Webhook = &webhook
+	}
+	return &copied
+}
+
+// keepSecrets restores into an updated channel the secrets of the stored
+// one that the update left masked
+func (c *Channel) keepSecrets(stored *Channel) {
+	if c.Slack != nil && stored.Slack != nil && c.Slack.WebhookURL == redacted {
+		c.Slack.WebhookURL = stored.Slack.WebhookURL
+	}
+	if c.Email != nil && stored.Email != nil && c.Email.Password == redacted {
+		c.Email.Password = stored.Email.Password
+	}
+	if c.PagerDuty != nil && stored.PagerDuty != nil && c.PagerDuty.RoutingKey == redacted {
+		c.PagerDuty.RoutingKey = stored.PagerDuty.RoutingKey
+	}
+	if c.Webhook != nil && stored.Webhook != nil {
+		for name, value := range c.Webhook.Headers {
+			if value == redacted {
+				c.Webhook.Headers[name] = stored.Webhook.Headers[name]
+			}
+		}
+	}
+}
+
+// validateRoute checks a route against the channels it sends to
+func validateRoute(route *Route, channels map[string]*Channel) error {
+	if route.Name == "" {
+		return fmt.Errorf("route has no name")
+	}
+	if len(route.Channels) == 0 {
+		return fmt.Errorf("route %s has no channels", route.Name)
+	}
+	for _, name := range route.Channels {
+		if _, ok := channels[name]; !ok {
+			return fmt.Errorf("route %s: channel not found: %s", route.Name, name)
+		}
+	}
+	for _, severity := range route.Severities {
+		switch severity {
+		case monitoring.SeverityInfo, monitoring.SeverityWarning, monitoring.SeverityCritical:
+		default:
+			return fmt.Errorf("route %s: unknown severity %q", route.Name, severity)
+		}
+	}
+	for label, pattern := range route.Match {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("route %s: invalid pattern for %s: %w", route.Name, label, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether a route applies to an alert with labels
+func (r *Route) matches(alert *monitoring.Alert, labels map[string]string) bool {
+	if len(r.Severities) > 0 {
+		found := false
+		for _, severity := range r.Severities {
+			if severity == alert.Severity {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for label, pattern := range r.Match {
+		value, ok := labels[label]
+		if !ok {
+			return false
+		}
+		if matched, _ := path.Match(pattern, value); !matched {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,447 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/monitoring"
+)
+
+const (
+	// maxDeliveries bounds the delivery history kept
+	maxDeliveries = 200
+	// sendAttempts is how many times a notification is tried per channel
+	sendAttempts = 3
+	// sendTimeout bounds one attempt
+	sendTimeout = 15 * time.Second
+)
+
+// Notification is what channels are sent about an alert. It is the data of
+// webhook body templates.
+type Notification struct {
+	Status string            `json:"status"` // firing or resolved
+	Alert  monitoring.Alert  `json:"alert"`
+	Labels map[string]string `json:"labels"`
+	Test   bool              `json:"test,omitempty"`
+}
+
+// Title summarizes a notification in a line, such as
+// "[CRITICAL] high_memory_usage"
+func (n *Notification) Title() string {
+	status := strings.ToUpper(string(n.Alert.Severity))
+	if n.Status == statusResolved {
+		status = "RESOLVED"
+	}
+	return fmt.Sprintf("[%s] %s", status, n.Alert.Name)
+}
+
+const (
+	statusFiring   = "firing"
+	statusResolved = "resolved"
+)
+
+// Delivery records the outcome of sending a notification to a channel
+type Delivery struct {
+	Channel   string      `json:"channel"`
+	Type      ChannelType `json:"type"`
+	Route     string      `json:"route,omitempty"`
+	AlertID   string      `json:"alert_id"`
+	AlertName string      `json:"alert_name"`
+	Status    string      `json:"status"` // of the alert
+	Attempts  int         `json:"attempts"`
+	Error     string      `json:"error,omitempty"`
+	SentAt    time.Time   `json:"sent_at"`
+}
+
+// Notifier routes alerts to notification channels. It listens to an
+// alert manager, sending an alert when it fires and, for routes that ask
+// for it, when it resolves.
+type Notifier struct {
+	mu       sync.RWMutex
+	channels map[string]*Channel
+	routes   []Route
+	storage  Storage
+
+	deliveriesMu sync.Mutex
+	deliveries   []Delivery
+
+	senders map[ChannelType]sender
+}
+
+// NewNotifier creates a notifier with no channels that keeps its settings
+// in memory until SetStorage is called
+func NewNotifier() *Notifier {
+	return &Notifier{
+		channels: make(map[string]*Channel),
+		senders:  defaultSenders(),
+	}
+}
+
+// SetStorage persists channels and routes in storage and loads those it
+// holds. Invalid channels, and routes to them, are skipped.
+func (n *Notifier) SetStorage(storage Storage) error {
+	settings, err := storage.Load()
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.storage = storage
+	for _, channel := range settings.Channels {
+		if err := validateChannel(channel); err != nil {
+			log.Warn().Err(err).Str("channel", channel.Name).Msg("Skipping invalid notification channel")
+			continue
+		}
+		n.channels[channel.Name] = channel
+	}
+	n.routes = n.routes[:0]
+	for i := range settings.Routes {
+		route := settings.Routes[i]
+		if err := validateRoute(&route, n.channels); err != nil {
+			log.Warn().Err(err).Str("route", route.Name).Msg("Skipping invalid notification route")
+			continue
+		}
+		n.routes = append(n.routes, route)
+	}
+	return nil
+}
+
+// ListChannels returns the channels by name, secrets masked
+func (n *Notifier) ListChannels() []*Channel {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	channels := make([]*Channel, 0, len(n.channels))
+	for _, channel := range n.channels {
+		channels = append(channels, channel.redact())
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].Name < channels[j].Name
+	})
+	return channels
+}
+
+// GetChannel returns a channel, secrets masked
+func (n *Notifier) GetChannel(name string) (*Channel, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	channel, ok := n.channels[name]
+	if !ok {
+		return nil, fmt.Errorf("channel not found: %s", name)
+	}
+	return channel.redact(), nil
+}
+
+// CreateChannel adds a channel
+func (n *Notifier) CreateChannel(channel *Channel) error {
+	if err := validateChannel(channel); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, exists := n.channels[channel.Name]; exists {
+		return fmt.Errorf("channel already exists: %s", channel.Name)
+	}
+	now := time.Now()
+	channel.CreatedAt = now
+	channel.UpdatedAt = now
+
+	// The caller gets the channel back with its secrets masked
+	stored := *channel
+	n.channels[channel.Name] = &stored
+	if err := n.save(); err != nil {
+		delete(n.channels, channel.Name)
+		return err
+	}
+	*channel = *stored.redact()
+	return nil
+}
+
+// UpdateChannel replaces the configuration of a channel. Secrets left
+// masked keep their stored values.
+func (n *Notifier) UpdateChannel(name string, channel *Channel) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	stored, ok := n.channels[name]
+	if !ok {
+		return fmt.Errorf("channel not found: %s", name)
+	}
+	channel.Name = name
+	channel.keepSecrets(stored)
+	if err := validateChannel(channel); err != nil {
+		return err
+	}
+	channel.CreatedBy = stored.CreatedBy
+	channel.CreatedAt = stored.CreatedAt
+	channel.UpdatedAt = time.Now()
+
+	updated := *channel
+	n.channels[name] = &updated
+	if err := n.save(); err != nil {
+		n.channels[name] = stored
+		return err
+	}
+	*channel = *updated.redact()
+	return nil
+}
+
+// DeleteChannel removes a channel no route sends to
+func (n *Notifier) DeleteChannel(name string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	stored, ok := n.channels[name]
+	if !ok {
+		return fmt.Errorf("channel not found: %s", name)
+	}
+	for _, route := range n.routes {
+		for _, channel := range route.Channels {
+			if channel == name {
+				return fmt.Errorf("channel %s is still used by route %s", name, route.Name)
+			}
+		}
+	}
+
+	delete(n.channels, name)
+	if err := n.save(); err != nil {
+		n.channels[name] = stored
+		return err
+	}
+	return nil
+}
+
+// Routes returns the routes in the order alerts are matched against them
+func (n *Notifier) Routes() []Route {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return append([]Route{}, n.routes...)
+}
+
+// SetRoutes replaces the routes
+func (n *Notifier) SetRoutes(routes []Route) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	names := make(map[string]bool, len(routes))
+	for i := range routes {
+		if err := validateRoute(&routes[i], n.channels); err != nil {
+			return err
+		}
+		if names[routes[i].Name] {
+			return fmt.Errorf("route already exists: %s", routes[i].Name)
+		}
+		names[routes[i].Name] = true
+	}
+
+	previous := n.routes
+	n.routes = append([]Route{}, routes...)
+	if err := n.save(); err != nil {
+		n.routes = previous
+		return err
+	}
+	return nil
+}
+
+// save persists the settings, if a storage is set. Callers hold the lock.
+func (n *Notifier) save() error {
+	if n.storage == nil {
+		return nil
+	}
+	settings := &Settings{Routes: n.routes}
+	for _, channel := range n.channels {
+		settings.Channels = append(settings.Channels, channel)
+	}
+	sort.Slice(settings.Channels, func(i, j int) bool {
+		return settings.Channels[i].Name < settings.Channels[j].Name
+	})
+	if err := n.storage.Save(settings); err != nil {
+		return fmt.Errorf("failed to save notification settings: %w", err)
+	}
+	return nil
+}
+
+// Deliveries returns the latest deliveries, newest first
+func (n *Notifier) Deliveries() []Delivery {
+	n.deliveriesMu.Lock()
+	defer n.deliveriesMu.Unlock()
+
+	deliveries := make([]Delivery, len(n.deliveries))
+	for i, delivery := range n.deliveries {
+		deliveries[len(n.deliveries)-1-i] = delivery
+	}
+	return deliveries
+}
+
+// OnAlert sends an alert that fired or resolved to the channels of the
+// routes matching it
+func (n *Notifier) OnAlert(alert *monitoring.Alert) {
+	notification := newNotification(alert)
+
+	type target struct {
+		channel *Channel
+		route   string
+	}
+	var targets []target
+	seen := make(map[string]bool)
+
+	n.mu.RLock()
+	for _, route := range n.routes {
+		if !route.matches(alert, notification.Labels) {
+			continue
+		}
+		if notification.Status == statusFiring || route.SendResolved {
+			for _, name := range route.Channels {
+				if channel, ok := n.channels[name]; ok && !seen[name] {
+					seen[name] = true
+					targets = append(targets, target{channel: channel, route: route.Name})
+				}
+			}
+		}
+		if !route.Continue {
+			break
+		}
+	}
+	n.mu.RUnlock()
+
+	for _, t := range targets {
+		go n.deliver(t.channel, t.route, notification)
+	}
+}
+
+// TestChannel sends a test notification to a stored channel
+func (n *Notifier) TestChannel(ctx context.Context, name string) error {
+	n.mu.RLock()
+	channel, ok := n.channels[name]
+	n.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("channel not found: %s", name)
+	}
+	return n.test(ctx, channel)
+}
+
+// TestConfig sends a test notification to a channel that is not stored,
+// such as one being edited. Masked secrets are taken from the stored
+// channel of the same name.
+func (n *Notifier) TestConfig(ctx context.Context, channel *Channel) error {
+	n.mu.RLock()
+	if stored, ok := n.channels[channel.Name]; ok {
+		channel.keepSecrets(stored)
+	}
+	n.mu.RUnlock()
+
+	if err := validateChannel(channel); err != nil {
+		return err
+	}
+	return n.test(ctx, channel)
+}
+
+func (n *Notifier) test(ctx context.Context, channel *Channel) error {
+	now := time.Now()
+	notification := newNotification(&monitoring.Alert{
+		ID:          fmt.Sprintf("notification_test_%d", now.Unix()),
+		Name:        "notification_test",
+		Severity:    monitoring.SeverityInfo,
+		Status:      monitoring.AlertStatusActive,
+		Message:     fmt.Sprintf("Test notification for channel %s", channel.Name),
+		Source:      "notification",
+		StartTime:   now,
+		LastUpdated: now,
+		Count:       1,
+	})
+	notification.Test = true
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	return n.senders[channel.Type].send(ctx, channel, notification)
+}
+
+// deliver sends a notification to a channel, retrying failures, and
+// records the outcome
+func (n *Notifier) deliver(channel *Channel, route string, notification *Notification) {
+	delivery := Delivery{
+		Channel:   channel.Name,
+		Type:      channel.Type,
+		Route:     route,
+		AlertID:   notification.Alert.ID,
+		AlertName: notification.Alert.Name,
+		Status:    notification.Status,
+	}
+
+	var err error
+	for attempt := 1; attempt <= sendAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(time.Duration(attempt-1) * 2 * time.Second)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		err = n.senders[channel.Type].send(ctx, channel, notification)
+		cancel()
+		delivery.Attempts = attempt
+		if err == nil {
+			break
+		}
+	}
+	delivery.SentAt = time.Now()
+
+	if err != nil {
+		delivery.Error = err.Error()
+		log.Error().Err(err).Str("channel", channel.Name).Str("alert", notification.Alert.Name).
+			Int("attempts", delivery.Attempts).Msg("Failed to send alert notification")
+	} else {
+		log.Info().Str("channel", channel.Name).Str("alert", notification.Alert.Name).
+			Str("status", notification.Status).Msg("Sent alert notification")
+	}
+
+	n.deliveriesMu.Lock()
+	n.deliveries = append(n.deliveries, delivery)
+	if len(n.deliveries) > maxDeliveries {
+		n.deliveries = n.deliveries[len(n.deliveries)-maxDeliveries:]
+	}
+	n.deliveriesMu.Unlock()
+}
+
+// newNotification describes an alert to channels
+func newNotification(alert *monitoring.Alert) *Notification {
+	status := statusFiring
+	if alert.Status == monitoring.AlertStatusResolved {
+		status = statusResolved
+	}
+	return &Notification{
+		Status: status,
+		Alert:  *alert,
+		Labels: alertLabels(alert),
+	}
+}
+
+// alertLabels are the labels routes match alerts on: alertname, source,
+// severity and the strings, numbers and booleans among its details
+func alertLabels(alert *monitoring.Alert) map[string]string {
+	labels := map[string]string{
+		"alertname": alert.Name,
+		"source":    alert.Source,
+		"severity":  string(alert.Severity),
+	}
+	if details, ok := alert.Details.(map[string]interface{}); ok {
+		for key, value := range details {
+			if _, reserved := labels[key]; reserved {
+				continue
+			}
+			switch v := value.(type) {
+			case string:
+				labels[key] = v
+			case bool, int, int64, float64:
+				labels[key] = fmt.Sprint(v)
+			}
+		}
+	}
+	return labels
+}
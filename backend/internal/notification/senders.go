@@ -0,0 +1,308 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/monitoring"
+)
+
+// sender delivers notifications to the channels of one type
+type sender interface {
+	send(ctx context.Context, channel *Channel, notification *Notification) error
+}
+
+// templateFuncs are available in webhook body templates
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+func defaultSenders() map[ChannelType]sender {
+	client := &http.Client{Timeout: sendTimeout}
+	return map[ChannelType]sender{
+		ChannelSlack:     slackSender{client: client},
+		ChannelEmail:     emailSender{},
+		ChannelPagerDuty: pagerDutySender{client: client},
+		ChannelWebhook:   webhookSender{client: client},
+	}
+}
+
+// post sends a request and fails unless the response status is 2xx
+func post(ctx context.Context, client *http.Client, method, url, contentType string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "click-lite-notifier")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to deliver: %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// sortedDetails returns the keys of an alert's details in order, or nil
+// when it has none in a form worth listing
+func sortedDetails(alert *monitoring.Alert) ([]string, map[string]interface{}) {
+	details, ok := alert.Details.(map[string]interface{})
+	if !ok || len(details) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(details))
+	for key := range details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, details
+}
+
+// slackSender posts to Slack incoming webhooks
+type slackSender struct {
+	client *http.Client
+}
+
+func (s slackSender) send(ctx context.Context, channel *Channel, notification *Notification) error {
+	alert := &notification.Alert
+	color := map[monitoring.AlertSeverity]string{
+		monitoring.SeverityInfo:     "#439FE0",
+		monitoring.SeverityWarning:  "warning",
+		monitoring.SeverityCritical: "danger",
+	}[alert.Severity]
+	if notification.Status == statusResolved {
+		color = "good"
+	}
+
+	fields := []map[string]interface{}{
+		{"title": "Severity", "value": string(alert.Severity), "short": true},
+		{"title": "Source", "value": alert.Source, "short": true},
+	}
+	keys, details := sortedDetails(alert)
+	for _, key := range keys {
+		fields = append(fields, map[string]interface{}{"title": key, "value": fmt.Sprint(details[key]), "short": true})
+	}
+
+	payload := map[string]interface{}{
+		"text": notification.Title(),
+		"attachments": []map[string]interface{}{{
+			"color":  color,
+			"title":  alert.Name,
+			"text":   alert.Message,
+			"fields": fields,
+			"ts":     alert.LastUpdated.Unix(),
+		}},
+	}
+	if channel.Slack.Channel != "" {
+		payload["channel"] = channel.Slack.Channel
+	}
+	if channel.Slack.Username != "" {
+		payload["username"] = channel.Slack.Username
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode slack message: %w", err)
+	}
+	return post(ctx, s.client, http.MethodPost, channel.Slack.WebhookURL, "application/json", nil, body)
+}
+
+// pagerDutySender triggers and resolves incidents through the PagerDuty
+// Events API v2. The alert ID is the dedup key, so an alert's resolution
+// closes the incident it opened.
+type pagerDutySender struct {
+	client *http.Client
+}
+
+func (s pagerDutySender) send(ctx context.Context, channel *Channel, notification *Notification) error {
+	alert := &notification.Alert
+	action := "trigger"
+	if notification.Status == statusResolved {
+		action = "resolve"
+	}
+	severity := map[monitoring.AlertSeverity]string{
+		monitoring.SeverityInfo:     "info",
+		monitoring.SeverityWarning:  "warning",
+		monitoring.SeverityCritical: "critical",
+	}[alert.Severity]
+	if severity == "" {
+		severity = "error"
+	}
+
+	event := map[string]interface{}{
+		"routing_key":  channel.PagerDuty.RoutingKey,
+		"event_action": action,
+		"dedup_key":    alert.ID,
+	}
+	if action == "trigger" {
+		event["payload"] = map[string]interface{}{
+			"summary":        fmt.Sprintf("%s: %s", alert.Name, alert.Message),
+			"source":         alert.Source,
+			"severity":       severity,
+			"timestamp":      alert.StartTime.Format(time.RFC3339),
+			"component":      "click-lite",
+			"custom_details": notification.Labels,
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode pagerduty event: %w", err)
+	}
+	return post(ctx, s.client, http.MethodPost, channel.PagerDuty.URL, "application/json", nil, body)
+}
+
+// webhookSender sends notifications to any HTTP endpoint
+type webhookSender struct {
+	client *http.Client
+}
+
+func (s webhookSender) send(ctx context.Context, channel *Channel, notification *Notification) error {
+	config := channel.Webhook
+	contentType := config.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	var body []byte
+	if config.BodyTemplate == "" {
+		data, err := json.Marshal(notification)
+		if err != nil {
+			return fmt.Errorf("failed to encode notification: %w", err)
+		}
+		body = data
+	} else {
+		tmpl, err := parseBodyTemplate(config.BodyTemplate)
+		if err != nil {
+			return fmt.Errorf("failed to parse body template: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, notification); err != nil {
+			return fmt.Errorf("failed to render body template: %w", err)
+		}
+		body = buf.Bytes()
+	}
+	return post(ctx, s.client, config.Method, config.URL, contentType, config.Headers, body)
+}
+
+// emailSender sends mail through SMTP servers
+type emailSender struct{}
+
+func (emailSender) send(ctx context.Context, channel *Channel, notification *Notification) error {
+	config := channel.Email
+	message := emailMessage(config, notification)
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(sendTimeout)
+	}
+	address := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	dialer := &net.Dialer{Deadline: deadline}
+
+	var conn net.Conn
+	var err error
+	if config.Port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: config.Host})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if config.Port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: config.Host}); err != nil {
+				return fmt.Errorf("failed to start TLS: %w", err)
+			}
+		}
+	}
+	if config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", config.Username, config.Password, config.Host)); err != nil {
+			return fmt.Errorf("failed to authenticate to SMTP server: %w", err)
+		}
+	}
+	if err := client.Mail(config.From); err != nil {
+		return fmt.Errorf("failed to send mail from %s: %w", config.From, err)
+	}
+	for _, to := range config.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("failed to send mail to %s: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return client.Quit()
+}
+
+// emailMessage formats a notification as a plain text mail
+func emailMessage(config *EmailConfig, notification *Notification) []byte {
+	alert := &notification.Alert
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", config.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(config.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(notification.Title()))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+
+	fmt.Fprintf(&buf, "%s\r\n\r\n", alert.Message)
+	fmt.Fprintf(&buf, "Alert:    %s\r\n", alert.Name)
+	fmt.Fprintf(&buf, "Status:   %s\r\n", notification.Status)
+	fmt.Fprintf(&buf, "Severity: %s\r\n", alert.Severity)
+	fmt.Fprintf(&buf, "Source:   %s\r\n", alert.Source)
+	fmt.Fprintf(&buf, "Started:  %s\r\n", alert.StartTime.Format(time.RFC1123Z))
+	if alert.EndTime != nil {
+		fmt.Fprintf(&buf, "Resolved: %s\r\n", alert.EndTime.Format(time.RFC1123Z))
+	}
+	keys, details := sortedDetails(alert)
+	if len(keys) > 0 {
+		buf.WriteString("\r\n")
+		for _, key := range keys {
+			fmt.Fprintf(&buf, "%s: %v\r\n", key, details[key])
+		}
+	}
+	return buf.Bytes()
+}
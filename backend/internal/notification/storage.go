@@ -0,0 +1,77 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Settings are the channels and routes of a notifier
+type Settings struct {
+	Channels []*Channel `json:"channels"`
+	Routes   []Route    `json:"routes"`
+}
+
+// Storage persists notification settings
+type Storage interface {
+	Load() (*Settings, error)
+	Save(settings *Settings) error
+}
+
+// FileStorage persists notification settings as a JSON document on disk.
+// The file holds channel secrets and is only readable by its owner.
+type FileStorage struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStorage creates a file-backed notification storage
+func NewFileStorage(path string) *FileStorage {
+	return &FileStorage{path: path}
+}
+
+// Load reads the settings from the file, empty if it does not exist
+func (s *FileStorage) Load() (*Settings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings := &Settings{}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return settings, nil
+		}
+		return nil, fmt.Errorf("failed to read notifications file: %w", err)
+	}
+	if len(data) == 0 {
+		return settings, nil
+	}
+	if err := json.Unmarshal(data, settings); err != nil {
+		return nil, fmt.Errorf("failed to decode notifications file: %w", err)
+	}
+	return settings, nil
+}
+
+// Save replaces the settings in the file
+func (s *FileStorage) Save(settings *Settings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode notification settings: %w", err)
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create notifications directory: %w", err)
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write notifications file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,235 @@
+package optimization
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Explainer runs EXPLAIN statements against ClickHouse
+type Explainer interface {
+	ExecuteQuery(ctx context.Context, query string) ([]map[string]interface{}, error)
+}
+
+// ScanEstimate is what ClickHouse expects to read for a query, taken from
+// EXPLAIN ESTIMATE and EXPLAIN indexes = 1
+type ScanEstimate struct {
+	Parts   int64           `json:"parts"`
+	Rows    int64           `json:"rows"`
+	Marks   int64           `json:"marks"`
+	Tables  []TableEstimate `json:"tables"`
+	Indexes []IndexUsage    `json:"indexes"`
+}
+
+// TableEstimate is the EXPLAIN ESTIMATE row of one table
+type TableEstimate struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	Parts    int64  `json:"parts"`
+	Rows     int64  `json:"rows"`
+	Marks    int64  `json:"marks"`
+}
+
+// IndexUsage describes how one index narrowed a MergeTree read
+type IndexUsage struct {
+	Table            string   `json:"table,omitempty"`
+	Type             string   `json:"type"` // MinMax, Partition, PrimaryKey or Skip
+	Name             string   `json:"name,omitempty"`
+	Keys             []string `json:"keys,omitempty"`
+	Condition        string   `json:"condition,omitempty"`
+	InitialParts     int64    `json:"initial_parts"`
+	SelectedParts    int64    `json:"selected_parts"`
+	InitialGranules  int64    `json:"initial_granules"`
+	SelectedGranules int64    `json:"selected_granules"`
+}
+
+// SetExplainer enables EXPLAIN based cost estimates
+func (o *QueryOptimizer) SetExplainer(explainer Explainer) {
+	o.explainer = explainer
+}
+
+// Explain asks ClickHouse how much data the query would read without running it
+func (o *QueryOptimizer) Explain(ctx context.Context, query string) (*ScanEstimate, error) {
+	if o.explainer == nil {
+		return nil, fmt.Errorf("no explainer configured")
+	}
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+
+	rows, err := o.explainer.ExecuteQuery(ctx, "EXPLAIN ESTIMATE "+query)
+	if err != nil {
+		return nil, fmt.Errorf("EXPLAIN ESTIMATE failed: %w", err)
+	}
+	estimate := &ScanEstimate{Tables: []TableEstimate{}, Indexes: []IndexUsage{}}
+	for _, row := range rows {
+		table := TableEstimate{
+			Database: fmt.Sprintf("%v", row["database"]),
+			Table:    fmt.Sprintf("%v", row["table"]),
+			Parts:    explainInt(row["parts"]),
+			Rows:     explainInt(row["rows"]),
+			Marks:    explainInt(row["marks"]),
+		}
+		estimate.Parts += table.Parts
+		estimate.Rows += table.Rows
+		estimate.Marks += table.Marks
+		estimate.Tables = append(estimate.Tables, table)
+	}
+
+	rows, err = o.explainer.ExecuteQuery(ctx, "EXPLAIN indexes = 1 "+query)
+	if err != nil {
+		return nil, fmt.Errorf("EXPLAIN indexes failed: %w", err)
+	}
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if line, ok := row["explain"].(string); ok {
+			lines = append(lines, line)
+		}
+	}
+	estimate.Indexes = parseIndexExplain(lines)
+	return estimate, nil
+}
+
+// OptimizeAndEstimate optimizes a query and replaces the cost estimate with
+// the rows ClickHouse expects to read. If EXPLAIN fails the plan is still
+// returned, with the reason in EstimateError.
+func (o *QueryOptimizer) OptimizeAndEstimate(ctx context.Context, query string) *QueryPlan {
+	plan := o.Optimize(query)
+	o.Estimate(ctx, plan, plan.OptimizedQuery)
+	return plan
+}
+
+// Estimate fills in the scan estimate of a plan from EXPLAIN on query, which
+// may differ from the plan's optimized query by pagination or limits
+func (o *QueryOptimizer) Estimate(ctx context.Context, plan *QueryPlan, query string) {
+	estimate, err := o.Explain(ctx, query)
+	if err != nil {
+		plan.EstimateError = err.Error()
+		return
+	}
+	plan.Scan = estimate
+	plan.EstimateError = ""
+	plan.EstimatedCost = float64(estimate.Rows)
+
+	// Report the indexes ClickHouse actually used instead of the name based guess
+	plan.IndexesUsed = []string{}
+	for _, index := range estimate.Indexes {
+		if index.SelectedGranules < index.InitialGranules || index.SelectedParts < index.InitialParts {
+			name := index.Name
+			if name == "" {
+				name = index.Type
+			}
+			plan.IndexesUsed = append(plan.IndexesUsed, name)
+		}
+		if index.Type == "Partition" && index.SelectedParts < index.InitialParts {
+			plan.PartitionPruning = true
+		}
+	}
+}
+
+// parseIndexExplain reads the Indexes sections of EXPLAIN indexes = 1:
+//
+//	ReadFromMergeTree (default.logs)
+//	Indexes:
+//	  PrimaryKey
+//	    Keys:
+//	      service
+//	    Condition: (service in ['api', 'api'])
+//	    Parts: 2/10
+//	    Granules: 12/400
+func parseIndexExplain(lines []string) []IndexUsage {
+	indexes := []IndexUsage{}
+	var current *IndexUsage
+	table := ""
+	indexesIndent, typeIndent := -1, -1
+	inKeys := false
+
+	flush := func() {
+		if current != nil {
+			indexes = append(indexes, *current)
+			current = nil
+		}
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		if strings.HasPrefix(trimmed, "ReadFromMergeTree") {
+			flush()
+			indexesIndent, typeIndent = -1, -1
+			if open := strings.Index(trimmed, "("); open >= 0 {
+				table = strings.TrimSuffix(trimmed[open+1:], ")")
+			}
+			continue
+		}
+		if trimmed == "Indexes:" {
+			flush()
+			indexesIndent, typeIndent = indent, -1
+			continue
+		}
+		if indexesIndent < 0 {
+			continue
+		}
+		if indent <= indexesIndent {
+			// Left the Indexes section
+			flush()
+			indexesIndent = -1
+			continue
+		}
+
+		if typeIndent < 0 || indent == typeIndent {
+			flush()
+			typeIndent = indent
+			current = &IndexUsage{Table: table, Type: trimmed}
+			inKeys = false
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(trimmed, ":")
+		value = strings.TrimSpace(value)
+		switch {
+		case trimmed == "Keys:":
+			inKeys = true
+		case hasValue && key == "Name":
+			current.Name, inKeys = value, false
+		case hasValue && key == "Condition":
+			current.Condition, inKeys = value, false
+		case hasValue && key == "Parts":
+			current.SelectedParts, current.InitialParts = explainRatio(value)
+			inKeys = false
+		case hasValue && key == "Granules":
+			current.SelectedGranules, current.InitialGranules = explainRatio(value)
+			inKeys = false
+		case hasValue && value != "":
+			inKeys = false
+		case inKeys:
+			current.Keys = append(current.Keys, trimmed)
+		}
+	}
+	flush()
+	return indexes
+}
+
+// explainRatio parses "selected/initial"
+func explainRatio(value string) (int64, int64) {
+	selected, initial, _ := strings.Cut(value, "/")
+	s, _ := strconv.ParseInt(strings.TrimSpace(selected), 10, 64)
+	i, _ := strconv.ParseInt(strings.TrimSpace(initial), 10, 64)
+	return s, i
+}
+
+// explainInt converts JSONEachRow numbers, which are quoted for 64-bit types
+func explainInt(value interface{}) int64 {
+	switch v := value.(type) {
+	case float64:
+		return int64(v)
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	default:
+		return 0
+	}
+}
@@ -3,6 +3,7 @@ package optimization
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -12,6 +13,7 @@ type QueryOptimizer struct {
 	indexHints    map[string][]string
 	queryPatterns []QueryPattern
 	rewriteRules  []RewriteRule
+	explainer     Explainer
 }
 
 // QueryPattern represents a query pattern for optimization
@@ -34,7 +36,9 @@ type QueryPlan struct {
 	OriginalQuery   string
 	OptimizedQuery  string
 	Optimizations   []string
-	EstimatedCost   float64
+	EstimatedCost   float64 // rows ClickHouse expects to read; 0 until estimated
+	Scan            *ScanEstimate `json:",omitempty"`
+	EstimateError   string        `json:",omitempty"`
 	IndexesUsed     []string
 	PartitionPruning bool
 	Parallelism     int
@@ -219,7 +223,6 @@ func (o *QueryOptimizer) Optimize(query string) *QueryPlan {
 		OriginalQuery:    query,
 		OptimizedQuery:   query,
 		Optimizations:    []string{},
-		EstimatedCost:    100.0,
 		IndexesUsed:      []string{},
 		PartitionPruning: false,
 		Parallelism:      1,
@@ -257,9 +260,6 @@ func (o *QueryOptimizer) Optimize(query string) *QueryPlan {
 	// Estimate parallelism
 	plan.Parallelism = o.estimateParallelism(plan.OptimizedQuery)
 	
-	// Calculate estimated cost
-	plan.EstimatedCost = o.estimateCost(plan)
-	
 	return plan
 }
 
@@ -299,36 +299,6 @@ func (o *QueryOptimizer) estimateParallelism(query string) int {
 	return parallelism
 }
 
-// estimateCost estimates query execution cost
-func (o *QueryOptimizer) estimateCost(plan *QueryPlan) float64 {
-	cost := 100.0
-	
-	// Reduce cost for index usage
-	cost -= float64(len(plan.IndexesUsed)) * 10
-	
-	// Reduce cost for partition pruning
-	if plan.PartitionPruning {
-		cost *= 0.5
-	}
-	
-	// Reduce cost for optimizations
-	cost -= float64(len(plan.Optimizations)) * 5
-	
-	// Increase cost for complex operations
-	if strings.Contains(plan.OptimizedQuery, "JOIN") {
-		cost *= 2
-	}
-	if strings.Contains(plan.OptimizedQuery, "DISTINCT") {
-		cost *= 1.5
-	}
-	
-	if cost < 1 {
-		cost = 1
-	}
-	
-	return cost
-}
-
 // extractWhereConditions extracts individual conditions from WHERE clause
 func extractWhereConditions(query string) []string {
 	whereIdx := strings.Index(query, "WHERE")
@@ -380,33 +350,71 @@ func isSimpleCondition(condition string) bool {
 	return false
 }
 
+// WorkloadPattern is a query pattern with how often it ran and how many
+// rows its executions scanned
+type WorkloadPattern struct {
+	Query       string
+	Count       int64
+	RowsScanned int64
+}
+
 // SuggestIndexes suggests indexes based on query patterns
 func (o *QueryOptimizer) SuggestIndexes(queries []string) []IndexSuggestion {
-	fieldUsage := make(map[string]int)
+	workload := make([]WorkloadPattern, len(queries))
+	for i, query := range queries {
+		workload[i] = WorkloadPattern{Query: query, Count: 1}
+	}
+	return o.SuggestIndexesForWorkload(workload)
+}
+
+// SuggestIndexesForWorkload suggests indexes for fields filtered on by more
+// than 10% of executions. Impact is the share of scanned rows read by
+// queries filtering on the field, or the share of executions when no scan
+// sizes are known.
+func (o *QueryOptimizer) SuggestIndexesForWorkload(workload []WorkloadPattern) []IndexSuggestion {
+	fieldCount := make(map[string]int64)
+	fieldRows := make(map[string]int64)
+	fieldPatterns := make(map[string]int)
+	var totalCount, totalRows int64
 	suggestions := []IndexSuggestion{}
 	
 	// Analyze field usage in WHERE clauses
-	for _, query := range queries {
-		fields := extractFieldsFromWhere(query)
-		for _, field := range fields {
-			fieldUsage[field]++
+	for _, pattern := range workload {
+		totalCount += pattern.Count
+		totalRows += pattern.RowsScanned
+		seen := make(map[string]bool)
+		for _, field := range extractFieldsFromWhere(pattern.Query) {
+			if seen[field] {
+				continue
+			}
+			seen[field] = true
+			fieldCount[field] += pattern.Count
+			fieldRows[field] += pattern.RowsScanned
+			fieldPatterns[field]++
 		}
 	}
 	
 	// Generate suggestions
-	for field, count := range fieldUsage {
-		if count > len(queries)/10 { // Used in more than 10% of queries
+	for field, count := range fieldCount {
+		if count > totalCount/10 { // Used in more than 10% of queries
 			if _, hasIndex := o.indexHints[field]; !hasIndex {
+				impact := float64(count) / float64(totalCount)
+				if totalRows > 0 {
+					impact = float64(fieldRows[field]) / float64(totalRows)
+				}
 				suggestions = append(suggestions, IndexSuggestion{
 					Field:       field,
 					IndexType:   "bloom_filter",
-					Reason:      fmt.Sprintf("Field used in %d queries", count),
-					Impact:      float64(count) / float64(len(queries)),
+					Reason:      fmt.Sprintf("Field used in %d queries across %d patterns, scanning %d rows", count, fieldPatterns[field], fieldRows[field]),
+					Impact:      impact,
 				})
 			}
 		}
 	}
 	
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].Impact > suggestions[j].Impact
+	})
 	return suggestions
 }
 
@@ -0,0 +1,124 @@
+package parsing
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// FlattenConfig controls how nested JSON objects are expanded into attributes
+type FlattenConfig struct {
+	Enabled   bool   `json:"enabled"`
+	MaxDepth  int    `json:"max_depth"`
+	MaxKeys   int    `json:"max_keys"`
+	Separator string `json:"separator"`
+}
+
+// DefaultFlattenConfig returns the flattening settings used by NewJSONParser
+func DefaultFlattenConfig() FlattenConfig {
+	return FlattenConfig{
+		Enabled:   true,
+		MaxDepth:  5,
+		MaxKeys:   200,
+		Separator: ".",
+	}
+}
+
+// Validate checks the limits are not negative; zero means unlimited
+func (c FlattenConfig) Validate() error {
+	if c.MaxDepth < 0 {
+		return fmt.Errorf("flatten max_depth must not be negative")
+	}
+	if c.MaxKeys < 0 {
+		return fmt.Errorf("flatten max_keys must not be negative")
+	}
+	return nil
+}
+
+// FlattenAttributes expands nested maps into dotted keys (e.g. "error.type").
+// Objects deeper than MaxDepth are kept as JSON strings and flattening stops
+// once MaxKeys attributes have been produced; in that case the
+// "_flatten_truncated" attribute is set.
+//
+// A key written with the separator, such as "error.type", collides with
+// the same key flattened from {"error": {"type": ...}}. The value nested
+// the least wins whatever the order of the keys, so a literal "error.type"
+// beats the flattened one, and the "_flatten_collision" attribute is set.
+func FlattenAttributes(attrs map[string]interface{}, cfg FlattenConfig) map[string]interface{} {
+	if !cfg.Enabled || len(attrs) == 0 {
+		return attrs
+	}
+	if cfg.Separator == "" {
+		cfg.Separator = "."
+	}
+
+	f := &flattener{
+		cfg:    cfg,
+		out:    make(map[string]interface{}, len(attrs)),
+		depths: make(map[string]int, len(attrs)),
+	}
+	f.walk("", attrs, 1)
+	if f.truncated {
+		f.out["_flatten_truncated"] = true
+	}
+	if f.collided {
+		f.out["_flatten_collision"] = true
+	}
+	return f.out
+}
+
+type flattener struct {
+	cfg       FlattenConfig
+	out       map[string]interface{}
+	depths    map[string]int // how deeply nested the value of each key was
+	truncated bool
+	collided  bool
+}
+
+func (f *flattener) walk(prefix string, m map[string]interface{}, depth int) {
+	// Iterate in key order so truncation is deterministic
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := k
+		if prefix != "" {
+			key = prefix + f.cfg.Separator + k
+		}
+
+		nested, isMap := m[k].(map[string]interface{})
+		if isMap && len(nested) > 0 && (f.cfg.MaxDepth <= 0 || depth < f.cfg.MaxDepth) {
+			f.walk(key, nested, depth+1)
+			continue
+		}
+
+		if existing, ok := f.depths[key]; ok {
+			f.collided = true
+			if existing <= depth {
+				continue
+			}
+		} else if f.cfg.MaxKeys > 0 && len(f.out) >= f.cfg.MaxKeys {
+			f.truncated = true
+			return
+		}
+		f.out[key] = flattenLeaf(m[k])
+		f.depths[key] = depth
+	}
+}
+
+// flattenLeaf converts values that cannot be expanded further into scalars
+func flattenLeaf(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,85 @@
+package parsing
+
+import (
+	"testing"
+)
+
+func TestFlattenAttributesCollisions(t *testing.T) {
+	cfg := DefaultFlattenConfig()
+
+	// The value nested the least wins: the literal key, or the object
+	// written with the separator over the one nested deeper
+	cases := []struct {
+		attrs map[string]interface{}
+		key   string
+	}{
+		{map[string]interface{}{"a.b": "literal", "a": map[string]interface{}{"b": "nested"}}, "a.b"},
+		{map[string]interface{}{
+			"a":   map[string]interface{}{"b": map[string]interface{}{"c": "nested"}},
+			"a.b": map[string]interface{}{"c": "literal"},
+		}, "a.b.c"},
+	}
+	for _, c := range cases {
+		out := FlattenAttributes(c.attrs, cfg)
+		if out[c.key] != "literal" {
+			t.Errorf("%s = %v, want the value nested the least", c.key, out[c.key])
+		}
+		if out["_flatten_collision"] != true {
+			t.Errorf("_flatten_collision = %v, want true", out["_flatten_collision"])
+		}
+	}
+
+	out := FlattenAttributes(map[string]interface{}{"a": map[string]interface{}{"b": 1}, "c": 2}, cfg)
+	if out["a.b"] != 1 || out["c"] != 2 {
+		t.Errorf("flattened attributes = %v", out)
+	}
+	if _, ok := out["_flatten_collision"]; ok {
+		t.Errorf("_flatten_collision set without a collision")
+	}
+}
+
+func TestFlattenAttributesLimits(t *testing.T) {
+	attrs := map[string]interface{}{
+		"a": map[string]interface{}{"b": map[string]interface{}{"c": 1}},
+		"d": 2,
+		"e": 3,
+	}
+	out := FlattenAttributes(attrs, FlattenConfig{Enabled: true, MaxDepth: 2, MaxKeys: 2, Separator: "_"})
+	if out["a_b"] != `{"c":1}` {
+		t.Errorf("a_b = %v, want the object past MaxDepth as JSON", out["a_b"])
+	}
+	if _, ok := out["e"]; ok || out["_flatten_truncated"] != true {
+		t.Errorf("attributes past MaxKeys = %v, want them truncated", out)
+	}
+}
+
+func TestRuleSetFlattenConfig(t *testing.T) {
+	m := NewManager()
+	m.RegisterParser(NewJSONParser())
+	raw := `{"message": "payment failed", "error": {"type": "timeout", "detail": {"code": 504}}}`
+
+	result := m.ParseWithRules(raw, &RuleSet{})
+	if !result.Success || result.Log.Attributes["error.detail.code"] != 504.0 {
+		t.Fatalf("default flattening = %v (%s)", result.Log, result.Error)
+	}
+
+	rules := &RuleSet{Flatten: &FlattenConfig{Enabled: true, MaxDepth: 2, Separator: "_"}}
+	result = m.ParseWithRules(raw, rules)
+	if !result.Success {
+		t.Fatalf("ParseWithRules error: %s", result.Error)
+	}
+	attrs := result.Log.Attributes
+	if attrs["error_type"] != "timeout" || attrs["error_detail"] != `{"code":504}` {
+		t.Errorf("attributes with the ruleset's flattening = %v", attrs)
+	}
+
+	result = m.ParseWithRules(raw, &RuleSet{Flatten: &FlattenConfig{Enabled: false}})
+	if _, ok := result.Log.Attributes["error"].(map[string]interface{}); !ok {
+		t.Errorf("attributes with flattening disabled = %v", result.Log.Attributes)
+	}
+
+	invalid := &NamedRuleSet{Name: "nested", Rules: &RuleSet{Flatten: &FlattenConfig{Enabled: true, MaxDepth: -1}}}
+	if err := validateNamedRuleSet(invalid); err == nil {
+		t.Error("validateNamedRuleSet accepted a negative max_depth")
+	}
+}
@@ -0,0 +1,117 @@
+package parsing
+
+import (
+	"strconv"
+	"strings"
+)
+
+// KeyValuePair is a single key=value pair in the order it appeared
+type KeyValuePair struct {
+	Key   string
+	Value interface{}
+}
+
+// ParseKeyValuePairs splits logfmt-style text (`a=1 b="two words" c='x\'y'`)
+// into pairs. Quoted values may contain spaces and backslash escapes;
+// unquoted values are type-inferred as integers, floats or booleans.
+// Tokens that are not key=value pairs are skipped.
+func ParseKeyValuePairs(s string) []KeyValuePair {
+	var pairs []KeyValuePair
+	i := 0
+	n := len(s)
+
+	for i < n {
+		// Skip separators
+		for i < n && (s[i] == ' ' || s[i] == '\t' || s[i] == ',') {
+			i++
+		}
+		if i >= n {
+			break
+		}
+
+		// Read key
+		start := i
+		for i < n && isKeyChar(s[i]) {
+			i++
+		}
+		key := s[start:i]
+		if key == "" || i >= n || s[i] != '=' {
+			// Not a pair, skip to the next whitespace
+			for i < n && s[i] != ' ' && s[i] != '\t' {
+				i++
+			}
+			continue
+		}
+		i++ // skip '='
+
+		// Read value
+		if i < n && (s[i] == '"' || s[i] == '\'') {
+			value, consumed := readQuotedValue(s[i:])
+			pairs = append(pairs, KeyValuePair{Key: key, Value: value})
+			i += consumed
+			continue
+		}
+
+		start = i
+		for i < n && s[i] != ' ' && s[i] != '\t' {
+			i++
+		}
+		pairs = append(pairs, KeyValuePair{Key: key, Value: inferValue(strings.TrimRight(s[start:i], ","))})
+	}
+
+	return pairs
+}
+
+// readQuotedValue reads a quoted string, returning the unescaped value and
+// the number of bytes consumed. An unterminated quote consumes the rest.
+func readQuotedValue(s string) (string, int) {
+	quote := s[0]
+	var sb strings.Builder
+
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(s[i])
+			}
+		case c == quote:
+			return sb.String(), i + 1
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return sb.String(), len(s)
+}
+
+// inferValue converts unquoted values to int64, float64 or bool when they look like one
+func inferValue(v string) interface{} {
+	if v == "" {
+		return v
+	}
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil && strings.ContainsAny(v, ".eE") {
+		return f
+	}
+	switch strings.ToLower(v) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return v
+}
+
+func isKeyChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
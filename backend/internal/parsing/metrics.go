@@ -0,0 +1,58 @@
+package parsing
+
+import "time"
+
+// StageMetrics receives the metrics of the parsing stages, so that
+// operators can see where ingest latency and drops come from
+type StageMetrics interface {
+	IncrementLabeledCounter(name string, labels map[string]string, delta int64)
+	RecordLabeledHistogram(name string, labels map[string]string, value float64)
+}
+
+// Outcomes of a parser attempt, as the outcome label of parser_attempts
+const (
+	attemptSucceeded       = "success"
+	attemptFailed          = "failure"          // the parser could not read the log
+	attemptInvalid         = "invalid"          // the ruleset rejected the parsed log
+	attemptTransformFailed = "transform_failed" // a transform rule failed
+)
+
+// noParser labels the logs no parser could handle
+const noParser = "none"
+
+// SetMetrics records per-stage metrics in metrics:
+//   - parser_attempts, by parser and outcome
+//   - parse_duration_ms, the time to parse a log, by the parser that did
+//   - transform_duration_ms, the time the ruleset took to transform a
+//     parsed log, by parser
+//   - format_cache_lookups, by result: hit when the format last seen from
+//     the log's source parsed it, stale when it did not and miss when no
+//     format was known for the source
+func (m *Manager) SetMetrics(metrics StageMetrics) {
+	m.metrics = metrics
+}
+
+func (m *Manager) recordAttempt(parser, outcome string) {
+	if m.metrics == nil {
+		return
+	}
+	m.metrics.IncrementLabeledCounter("parser_attempts", map[string]string{
+		"parser":  parser,
+		"outcome": outcome,
+	}, 1)
+}
+
+func (m *Manager) recordDuration(name, parser string, start time.Time) {
+	if m.metrics == nil {
+		return
+	}
+	m.metrics.RecordLabeledHistogram(name, map[string]string{"parser": parser},
+		float64(time.Since(start).Microseconds())/1000)
+}
+
+func (m *Manager) recordCacheLookup(result string) {
+	if m.metrics == nil {
+		return
+	}
+	m.metrics.IncrementLabeledCounter("format_cache_lookups", map[string]string{"result": result}, 1)
+}
@@ -3,6 +3,7 @@ package parsing
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +18,18 @@ type Parser interface {
 	CanParse(rawLog string) bool
 }
 
+// TimestampAwareParser is implemented by parsers that can read timestamps
+// with a caller supplied configuration, e.g. a per-source timezone
+type TimestampAwareParser interface {
+	ParseWithTimestamps(rawLog string, timestamps *TimestampParser) (*models.Log, error)
+}
+
+// FlattenAwareParser is implemented by parsers that expand nested objects
+// into attributes with a caller supplied configuration, e.g. a ruleset's
+type FlattenAwareParser interface {
+	ParseWithFlatten(rawLog string, timestamps *TimestampParser, flatten FlattenConfig) (*models.Log, error)
+}
+
 // ParsingResult contains the parsed log and any parsing metadata
 type ParsingResult struct {
 	Log        *models.Log `json:"log"`
@@ -32,6 +45,8 @@ type Manager struct {
 	parsers []Parser
 	rules   *RuleSet
 	stats   *ParseStats
+	formats *formatCache
+	metrics StageMetrics
 }
 
 // ParseStats tracks parsing statistics
@@ -41,6 +56,8 @@ type ParseStats struct {
 	FailureCount   int64            `json:"failure_count"`
 	ParserUsage    map[string]int64 `json:"parser_usage"`
 	LastParseTime  time.Time        `json:"last_parse_time"`
+	CacheHits      int64            `json:"format_cache_hits"`
+	CacheMisses    int64            `json:"format_cache_misses"`
 }
 
 // NewManager creates a new parsing manager
@@ -51,6 +68,7 @@ func NewManager() *Manager {
 		stats: &ParseStats{
 			ParserUsage: make(map[string]int64),
 		},
+		formats: newFormatCache(defaultFormatCacheSize),
 	}
 }
 
@@ -63,6 +81,31 @@ func (m *Manager) RegisterParser(parser Parser) {
 
 // Parse attempts to parse a raw log message using available parsers
 func (m *Manager) Parse(rawLog string) *ParsingResult {
+	return m.ParseWithRules(rawLog, m.rules)
+}
+
+// ParseWithRules parses a raw log message and applies the given ruleset
+// instead of the manager's own rules
+func (m *Manager) ParseWithRules(rawLog string, rules *RuleSet) *ParsingResult {
+	return m.ParseFromSource(rawLog, "", rules)
+}
+
+// ParseFromSource parses a raw log from a known source (e.g. a service).
+// The parser that handled the previous log from the same source is tried
+// first; if it no longer matches, all parsers are scanned again.
+func (m *Manager) ParseFromSource(rawLog string, source string, rules *RuleSet) *ParsingResult {
+	if rules == nil {
+		rules = m.rules
+	}
+	
+	// A ruleset may override how timestamps are read for its sources
+	timestamps, err := rules.TimestampParser()
+	if err != nil {
+		log.Warn().Err(err).Msg("Invalid ruleset timestamp configuration, using parser defaults")
+		timestamps = nil
+	}
+	flatten := rules.FlattenConfig()
+	
 	startTime := time.Now()
 	
 	result := &ParsingResult{
@@ -74,52 +117,135 @@ func (m *Manager) Parse(rawLog string) *ParsingResult {
 	m.stats.TotalParsed++
 	m.stats.LastParseTime = startTime
 	
+	// Try the cached format for this source first
+	if source != "" {
+		if hint, ok := m.formats.get(source); ok {
+			if parser := m.parserByName(hint.parser); parser != nil {
+				parsedLog, pattern, err := m.tryParser(parser, rawLog, hint.pattern, timestamps, flatten)
+				if err != nil {
+					m.recordAttempt(parser.Name(), attemptFailed)
+				} else if m.finishParse(result, parser, parsedLog, rules, startTime) {
+					m.stats.CacheHits++
+					m.recordCacheLookup("hit")
+					if pattern != hint.pattern {
+						m.formats.set(source, parser.Name(), pattern)
+					}
+					return result
+				}
+			}
+			m.stats.CacheMisses++
+			m.recordCacheLookup("stale")
+			m.formats.remove(source)
+		} else {
+			m.recordCacheLookup("miss")
+		}
+	}
+	
 	// Try each parser in order of preference
 	for _, parser := range m.parsers {
-		if parser.CanParse(rawLog) {
-			log.Debug().Str("parser", parser.Name()).Msg("Attempting to parse with parser")
-			
-			parsedLog, err := parser.Parse(rawLog)
-			if err != nil {
-				log.Debug().Err(err).Str("parser", parser.Name()).Msg("Parser failed")
-				continue
-			}
-			
-			// Validate parsed log
-			if err := m.rules.Validate(parsedLog); err != nil {
-				log.Debug().Err(err).Str("parser", parser.Name()).Msg("Validation failed")
-				result.Error = fmt.Sprintf("validation failed: %v", err)
-				continue
-			}
-			
-			// Apply transformation rules
-			if err := m.rules.Transform(parsedLog); err != nil {
-				log.Debug().Err(err).Str("parser", parser.Name()).Msg("Transformation failed")
-				result.Error = fmt.Sprintf("transformation failed: %v", err)
-				continue
+		if _, ok := parser.(PatternParser); !ok && !parser.CanParse(rawLog) {
+			continue
+		}
+		log.Debug().Str("parser", parser.Name()).Msg("Attempting to parse with parser")
+		
+		parsedLog, pattern, err := m.tryParser(parser, rawLog, "", timestamps, flatten)
+		if err != nil {
+			log.Debug().Err(err).Str("parser", parser.Name()).Msg("Parser failed")
+			m.recordAttempt(parser.Name(), attemptFailed)
+			continue
+		}
+		
+		if m.finishParse(result, parser, parsedLog, rules, startTime) {
+			if source != "" {
+				m.formats.set(source, parser.Name(), pattern)
 			}
-			
-			// Success
-			result.Log = parsedLog
-			result.Parser = parser.Name()
-			result.Success = true
-			m.stats.SuccessCount++
-			m.stats.ParserUsage[parser.Name()]++
-			
-			log.Debug().Str("parser", parser.Name()).Dur("duration", time.Since(startTime)).Msg("Successfully parsed log")
 			return result
 		}
 	}
 	
 	// No parser could handle the log
 	result.Success = false
-	result.Error = "no suitable parser found"
+	if result.Error == "" {
+		result.Error = "no suitable parser found"
+	}
 	m.stats.FailureCount++
+	m.recordDuration("parse_duration_ms", noParser, startTime)
 	
 	log.Debug().Str("raw_log", rawLog).Msg("Failed to parse log with any parser")
 	return result
 }
 
+// tryParser runs a single parser. Pattern parsers are asked for a specific
+// pattern (or to scan all when pattern is empty), which avoids matching
+// every regex once in CanParse and again in Parse. A ruleset's timestamp
+// and flattening settings, when set, override the parser's own.
+func (m *Manager) tryParser(parser Parser, rawLog, pattern string, timestamps *TimestampParser, flatten *FlattenConfig) (*models.Log, string, error) {
+	if pp, ok := parser.(PatternParser); ok {
+		return pp.ParsePattern(rawLog, pattern, timestamps)
+	}
+	if !parser.CanParse(rawLog) {
+		return nil, "", fmt.Errorf("parser %s cannot parse log", parser.Name())
+	}
+	if fParser, ok := parser.(FlattenAwareParser); ok && flatten != nil {
+		parsedLog, err := fParser.ParseWithFlatten(rawLog, timestamps, *flatten)
+		return parsedLog, "", err
+	}
+	if tsParser, ok := parser.(TimestampAwareParser); ok && timestamps != nil {
+		parsedLog, err := tsParser.ParseWithTimestamps(rawLog, timestamps)
+		return parsedLog, "", err
+	}
+	parsedLog, err := parser.Parse(rawLog)
+	return parsedLog, "", err
+}
+
+// finishParse normalizes, validates and transforms a parsed log and records
+// it in result. It returns false if the ruleset rejected the log.
+func (m *Manager) finishParse(result *ParsingResult, parser Parser, parsedLog *models.Log, rules *RuleSet, startTime time.Time) bool {
+	// Map source specific levels before the level enum is checked
+	rules.NormalizeLevel(parsedLog)
+	
+	// Validate parsed log
+	if err := rules.Validate(parsedLog); err != nil {
+		log.Debug().Err(err).Str("parser", parser.Name()).Msg("Validation failed")
+		result.Error = fmt.Sprintf("validation failed: %v", err)
+		m.recordAttempt(parser.Name(), attemptInvalid)
+		return false
+	}
+	
+	// Apply transformation rules
+	transformStart := time.Now()
+	err := rules.Transform(parsedLog)
+	m.recordDuration("transform_duration_ms", parser.Name(), transformStart)
+	if err != nil {
+		log.Debug().Err(err).Str("parser", parser.Name()).Msg("Transformation failed")
+		result.Error = fmt.Sprintf("transformation failed: %v", err)
+		m.recordAttempt(parser.Name(), attemptTransformFailed)
+		return false
+	}
+	
+	// Success
+	result.Log = parsedLog
+	result.Parser = parser.Name()
+	result.Success = true
+	result.Error = ""
+	m.stats.SuccessCount++
+	m.stats.ParserUsage[parser.Name()]++
+	m.recordAttempt(parser.Name(), attemptSucceeded)
+	m.recordDuration("parse_duration_ms", parser.Name(), startTime)
+	
+	log.Debug().Str("parser", parser.Name()).Dur("duration", time.Since(startTime)).Msg("Successfully parsed log")
+	return true
+}
+
+func (m *Manager) parserByName(name string) Parser {
+	for _, parser := range m.parsers {
+		if parser.Name() == name {
+			return parser
+		}
+	}
+	return nil
+}
+
 // GetStats returns current parsing statistics
 func (m *Manager) GetStats() *ParseStats {
 	return m.stats
@@ -138,13 +264,15 @@ func (m *Manager) GetRules() *RuleSet {
 
 // JSONParser handles structured JSON logs
 type JSONParser struct {
-	name string
+	name    string
+	flatten FlattenConfig
 }
 
 // NewJSONParser creates a new JSON parser
 func NewJSONParser() *JSONParser {
 	return &JSONParser{
-		name: "json",
+		name:    "json",
+		flatten: DefaultFlattenConfig(),
 	}
 }
 
@@ -161,6 +289,21 @@ func (p *JSONParser) CanParse(rawLog string) bool {
 
 // Parse parses a JSON log message
 func (p *JSONParser) Parse(rawLog string) (*models.Log, error) {
+	return p.ParseWithTimestamps(rawLog, nil)
+}
+
+// ParseWithTimestamps parses a JSON log message, reading the timestamp with the given parser
+func (p *JSONParser) ParseWithTimestamps(rawLog string, timestamps *TimestampParser) (*models.Log, error) {
+	return p.ParseWithFlatten(rawLog, timestamps, p.flatten)
+}
+
+// ParseWithFlatten parses a JSON log message, reading the timestamp with
+// the given parser, or the default one when nil, and expanding nested
+// objects as flatten says
+func (p *JSONParser) ParseWithFlatten(rawLog string, timestamps *TimestampParser, flatten FlattenConfig) (*models.Log, error) {
+	if timestamps == nil {
+		timestamps = defaultTimestampParser
+	}
 	var logData map[string]interface{}
 	if err := json.Unmarshal([]byte(rawLog), &logData); err != nil {
 		return nil, fmt.Errorf("invalid JSON: %w", err)
@@ -171,26 +314,28 @@ func (p *JSONParser) Parse(rawLog string) (*models.Log, error) {
 	}
 	
 	// Extract standard fields
-	if timestamp, ok := logData["timestamp"].(string); ok {
-		if t, err := time.Parse(time.RFC3339, timestamp); err == nil {
-			log.Timestamp = t
-		} else if t, err := time.Parse("2006-01-02T15:04:05.000Z", timestamp); err == nil {
-			log.Timestamp = t
-		} else if t, err := time.Parse("2006-01-02 15:04:05", timestamp); err == nil {
+	switch timestamp := logData["timestamp"].(type) {
+	case string:
+		if t, err := timestamps.Parse(timestamp); err == nil {
 			log.Timestamp = t
 		} else {
 			log.Timestamp = time.Now()
 		}
-	} else {
+	case float64:
+		log.Timestamp = parseEpoch(timestamp)
+	default:
 		log.Timestamp = time.Now()
 	}
 	
-	if level, ok := logData["level"].(string); ok {
+	if level, ok := logData["level"].(float64); ok {
+		// Numeric levels are resolved by the ruleset's severity mapping
+		log.Level = strconv.FormatFloat(level, 'f', -1, 64)
+	} else if level, ok := logData["level"].(string); ok {
 		log.Level = strings.ToLower(level)
 	} else if lvl, ok := logData["lvl"].(string); ok {
 		log.Level = strings.ToLower(lvl)
 	} else if severity, ok := logData["severity"].(string); ok {
-		log.Level = mapSeverityToLevel(severity)
+		log.Level = parseLevel(severity)
 	} else {
 		log.Level = "info"
 	}
@@ -242,6 +387,9 @@ func (p *JSONParser) Parse(rawLog string) (*models.Log, error) {
 		}
 	}
 	
+	// Expand nested objects so their fields can be filtered individually
+	log.Attributes = FlattenAttributes(log.Attributes, flatten)
+	
 	return log, nil
 }
 
@@ -249,7 +397,7 @@ func (p *JSONParser) Parse(rawLog string) (*models.Log, error) {
 func mapSeverityToLevel(severity string) string {
 	severity = strings.ToLower(severity)
 	switch severity {
-	case "emergency", "emerg", "panic":
+	case "emergency", "emerg", "panic", "fatal":
 		return "fatal"
 	case "alert", "crit", "critical":
 		return "error"
@@ -261,6 +409,8 @@ func mapSeverityToLevel(severity string) string {
 		return "info"
 	case "debug":
 		return "debug"
+	case "trace":
+		return "trace"
 	default:
 		return "info"
 	}
@@ -0,0 +1,288 @@
+package parsing
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/scripting"
+)
+
+// Plugin is a user supplied script that can mutate or drop parsed logs
+type Plugin struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Source      string           `json:"source"`
+	Services    []string         `json:"services,omitempty"` // empty means all services
+	Order       int              `json:"order"`
+	Enabled     bool             `json:"enabled"`
+	Limits      scripting.Limits `json:"limits"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	Stats       PluginStats      `json:"stats"`
+
+	script *scripting.Script
+	runs   atomic.Int64
+	errors atomic.Int64
+	drops  atomic.Int64
+}
+
+// PluginStats reports how often a plugin ran, failed and dropped logs
+type PluginStats struct {
+	Runs   int64 `json:"runs"`
+	Errors int64 `json:"errors"`
+	Drops  int64 `json:"drops"`
+}
+
+// PluginStage runs registered plugins against each log after parsing
+type PluginStage struct {
+	mu      sync.RWMutex
+	plugins map[string]*Plugin
+	ordered []*Plugin
+}
+
+// NewPluginStage creates an empty plugin stage
+func NewPluginStage() *PluginStage {
+	return &PluginStage{
+		plugins: make(map[string]*Plugin),
+	}
+}
+
+// Register compiles and adds or replaces a plugin
+func (s *PluginStage) Register(p *Plugin) error {
+	if !ruleSetNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("plugin name must contain only alphanumeric characters, underscores, and hyphens")
+	}
+
+	script, err := scripting.Compile(p.Source)
+	if err != nil {
+		return err
+	}
+	p.script = script
+	p.Limits = clampLimits(p.Limits)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := s.plugins[p.Name]; ok {
+		p.CreatedAt = existing.CreatedAt
+	} else {
+		p.CreatedAt = now
+	}
+	p.UpdatedAt = now
+
+	s.plugins[p.Name] = p
+	s.reorder()
+
+	log.Info().Str("plugin", p.Name).Msg("Parsing plugin registered")
+	return nil
+}
+
+// Get returns a copy of a plugin by name
+func (s *PluginStage) Get(name string) (*Plugin, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.plugins[name]
+	if !ok {
+		return nil, fmt.Errorf("plugin not found: %s", name)
+	}
+	return p.view(), nil
+}
+
+// List returns copies of all plugins in execution order
+func (s *PluginStage) List() []*Plugin {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	plugins := make([]*Plugin, len(s.ordered))
+	for i, p := range s.ordered {
+		plugins[i] = p.view()
+	}
+	return plugins
+}
+
+// Delete removes a plugin
+func (s *PluginStage) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.plugins[name]; !ok {
+		return fmt.Errorf("plugin not found: %s", name)
+	}
+	delete(s.plugins, name)
+	s.reorder()
+	return nil
+}
+
+// Apply runs every enabled plugin that matches the log's service. It
+// returns true when a plugin dropped the log. A plugin that fails leaves the
+// log untouched and the remaining plugins still run.
+func (s *PluginStage) Apply(logEntry *models.Log) bool {
+	s.mu.RLock()
+	plugins := s.ordered
+	s.mu.RUnlock()
+
+	for _, p := range plugins {
+		if !p.Enabled || !p.appliesTo(logEntry.Service) {
+			continue
+		}
+
+		dropped, err := RunPlugin(p.script, p.Limits, logEntry)
+		p.runs.Add(1)
+		if err != nil {
+			p.errors.Add(1)
+			log.Debug().Err(err).Str("plugin", p.Name).Msg("Parsing plugin failed")
+			continue
+		}
+		if dropped {
+			p.drops.Add(1)
+			return true
+		}
+	}
+	return false
+}
+
+// RunPlugin executes a script against a copy of the log and only writes the
+// changes back if the script completes within its limits
+func RunPlugin(script *scripting.Script, limits scripting.Limits, logEntry *models.Log) (bool, error) {
+	table := logToTable(logEntry)
+
+	result, err := script.Run(map[string]interface{}{"log": table}, limits)
+	if err != nil {
+		return false, err
+	}
+	if result.Dropped {
+		return true, nil
+	}
+	return false, tableToLog(table, logEntry)
+}
+
+func (p *Plugin) appliesTo(service string) bool {
+	if len(p.Services) == 0 {
+		return true
+	}
+	for _, s := range p.Services {
+		if s == service {
+			return true
+		}
+	}
+	return false
+}
+
+// view copies a plugin with its current stats. Registered plugins are
+// shared with the ingestion goroutines running them, so callers only ever
+// get copies.
+func (p *Plugin) view() *Plugin {
+	return &Plugin{
+		Name:        p.Name,
+		Description: p.Description,
+		Source:      p.Source,
+		Services:    append([]string(nil), p.Services...),
+		Order:       p.Order,
+		Enabled:     p.Enabled,
+		Limits:      p.Limits,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+		Stats:       p.snapshot(),
+		script:      p.script,
+	}
+}
+
+func (p *Plugin) snapshot() PluginStats {
+	return PluginStats{
+		Runs:   p.runs.Load(),
+		Errors: p.errors.Load(),
+		Drops:  p.drops.Load(),
+	}
+}
+
+// reorder rebuilds the execution order. Caller holds the lock.
+func (s *PluginStage) reorder() {
+	ordered := make([]*Plugin, 0, len(s.plugins))
+	for _, p := range s.plugins {
+		ordered = append(ordered, p)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].Order != ordered[j].Order {
+			return ordered[i].Order < ordered[j].Order
+		}
+		return ordered[i].Name < ordered[j].Name
+	})
+	s.ordered = ordered
+}
+
+// clampLimits fills in defaults and caps user supplied limits
+func clampLimits(l scripting.Limits) scripting.Limits {
+	def := scripting.DefaultLimits()
+	if l.MaxSteps <= 0 || l.MaxSteps > def.MaxSteps*10 {
+		l.MaxSteps = def.MaxSteps
+	}
+	if l.MaxMemory <= 0 || l.MaxMemory > def.MaxMemory*10 {
+		l.MaxMemory = def.MaxMemory
+	}
+	if l.Timeout <= 0 || l.Timeout > time.Second {
+		l.Timeout = def.Timeout
+	}
+	return l
+}
+
+func logToTable(l *models.Log) map[string]interface{} {
+	attrs := make(map[string]interface{}, len(l.Attributes))
+	for k, v := range l.Attributes {
+		attrs[k] = v
+	}
+	return map[string]interface{}{
+		"timestamp":  l.Timestamp.Format(time.RFC3339Nano),
+		"level":      l.Level,
+		"message":    l.Message,
+		"service":    l.Service,
+		"trace_id":   l.TraceID,
+		"span_id":    l.SpanID,
+		"attributes": attrs,
+	}
+}
+
+func tableToLog(table map[string]interface{}, l *models.Log) error {
+	updated := *l
+
+	if ts, ok := table["timestamp"].(string); ok {
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return fmt.Errorf("plugin set an invalid timestamp: %w", err)
+		}
+		updated.Timestamp = t
+	}
+
+	fields := map[string]*string{
+		"level":    &updated.Level,
+		"message":  &updated.Message,
+		"service":  &updated.Service,
+		"trace_id": &updated.TraceID,
+		"span_id":  &updated.SpanID,
+	}
+	for name, target := range fields {
+		switch v := table[name].(type) {
+		case nil:
+			*target = ""
+		case string:
+			*target = v
+		default:
+			*target = fmt.Sprintf("%v", v)
+		}
+	}
+
+	attrs, ok := table["attributes"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("plugin replaced attributes with a non-table value")
+	}
+	updated.Attributes = attrs
+
+	*l = updated
+	return nil
+}
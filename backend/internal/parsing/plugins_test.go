@@ -0,0 +1,51 @@
+package parsing
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+func TestPluginStageReturnsCopies(t *testing.T) {
+	stage := NewPluginStage()
+	err := stage.Register(&Plugin{
+		Name:    "lower-level",
+		Source:  `log.level = lower(log.level)`,
+		Enabled: true,
+	})
+	if err != nil {
+		t.Fatalf("Register error: %v", err)
+	}
+
+	// Concurrent listings, while logs go through the plugin, must not race
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			entry := &models.Log{Level: "ERROR", Attributes: map[string]interface{}{}}
+			stage.Apply(entry)
+			stage.Get("lower-level")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		stage.List()
+	}
+	wg.Wait()
+
+	p, err := stage.Get("lower-level")
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if p.Stats.Runs != 100 {
+		t.Errorf("Stats.Runs = %d, want 100", p.Stats.Runs)
+	}
+	p.Enabled = false
+
+	entry := &models.Log{Level: "WARN", Attributes: map[string]interface{}{}}
+	stage.Apply(entry)
+	if entry.Level != "warn" {
+		t.Errorf("level = %q; disabling the copy returned by Get disabled the plugin", entry.Level)
+	}
+}
@@ -0,0 +1,321 @@
+package parsing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// RejectionCounter receives per-rule rejection counts
+type RejectionCounter interface {
+	IncrementLabeledCounter(name string, labels map[string]string, delta int64)
+}
+
+// QuarantinedLog is a log that failed validation, kept with the reason
+type QuarantinedLog struct {
+	ID            string      `json:"id"`
+	QuarantinedAt time.Time   `json:"quarantined_at"`
+	RuleSet       string      `json:"ruleset"`
+	Rule          string      `json:"rule"`
+	Reason        string      `json:"reason"`
+	Service       string      `json:"service"`
+	Log           *models.Log `json:"log"`
+}
+
+// QuarantineFilter narrows a quarantine listing
+type QuarantineFilter struct {
+	Rule    string
+	RuleSet string
+	Service string
+	Since   time.Time
+	Limit   int
+	Offset  int
+}
+
+const (
+	// quarantineBufferSize bounds the rejected logs waiting to be stored;
+	// the oldest are dropped when it is full
+	quarantineBufferSize = 10000
+	// quarantineBatchSize bounds the rows of one INSERT
+	quarantineBatchSize = 1000
+)
+
+// Quarantine counts validation rejections and, when enabled, stores the
+// rejected logs in ClickHouse so they can be inspected later. Rejected logs
+// are buffered and inserted in batches by Start, so that rejecting logs
+// costs ingestion no round trip to ClickHouse.
+type Quarantine struct {
+	db      SQLExecutor
+	counter RejectionCounter
+	enabled bool
+	table   string
+
+	mu      sync.Mutex
+	pending []quarantineRow
+	dropped int64 // rows dropped from a full buffer since the last flush
+}
+
+// quarantineRow is a rejected log waiting to be stored
+type quarantineRow struct {
+	at      time.Time
+	ruleSet string
+	rule    string
+	reason  string
+	service string
+	raw     string
+}
+
+// NewQuarantine creates a quarantine. When enabled, the table is created if needed.
+func NewQuarantine(db SQLExecutor, counter RejectionCounter, enabled bool) (*Quarantine, error) {
+	q := &Quarantine{
+		db:      db,
+		counter: counter,
+		enabled: enabled,
+		table:   "quarantined_logs",
+	}
+	if !enabled {
+		return q, nil
+	}
+
+	ddl := fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		id UUID DEFAULT generateUUIDv4(),
+		quarantined_at DateTime64(3) DEFAULT now64(3),
+		ruleset LowCardinality(String),
+		rule LowCardinality(String),
+		reason String,
+		service LowCardinality(String),
+		raw String
+	) ENGINE = MergeTree()
+	PARTITION BY toYYYYMMDD(quarantined_at)
+	ORDER BY (rule, quarantined_at)
+	TTL toDateTime(quarantined_at) + INTERVAL 7 DAY
+	`, q.table)
+
+	if err := db.Execute(context.Background(), ddl); err != nil {
+		return nil, fmt.Errorf("failed to create quarantine table: %w", err)
+	}
+	return q, nil
+}
+
+// Enabled reports whether rejected logs are stored
+func (q *Quarantine) Enabled() bool {
+	return q.enabled
+}
+
+// Reject records a validation failure for a log
+func (q *Quarantine) Reject(ruleSet string, logEntry *models.Log, err error) {
+	rule := "unknown"
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		rule = validationErr.Rule
+	}
+
+	if q.counter != nil {
+		q.counter.IncrementLabeledCounter("validation_rejections", map[string]string{
+			"ruleset": ruleSet,
+			"rule":    rule,
+		}, 1)
+	}
+
+	if !q.enabled {
+		return
+	}
+
+	raw, marshalErr := json.Marshal(logEntry)
+	if marshalErr != nil {
+		log.Error().Err(marshalErr).Msg("Failed to encode quarantined log")
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) >= quarantineBufferSize {
+		q.pending = q.pending[1:]
+		q.dropped++
+	}
+	q.pending = append(q.pending, quarantineRow{
+		at:      time.Now(),
+		ruleSet: ruleSet,
+		rule:    rule,
+		reason:  err.Error(),
+		service: logEntry.Service,
+		raw:     string(raw),
+	})
+}
+
+// Flush stores the buffered rejected logs. Rows not stored when an insert
+// fails go back to the buffer, so the next flush retries them.
+func (q *Quarantine) Flush() error {
+	q.mu.Lock()
+	rows := q.pending
+	dropped := q.dropped
+	q.pending = nil
+	q.dropped = 0
+	q.mu.Unlock()
+
+	if dropped > 0 {
+		log.Warn().Int64("dropped", dropped).Msg("Quarantine buffer full, dropped the oldest rejected logs")
+	}
+	for len(rows) > 0 {
+		batch := rows
+		if len(batch) > quarantineBatchSize {
+			batch = batch[:quarantineBatchSize]
+		}
+		if err := q.insert(batch); err != nil {
+			q.requeue(rows)
+			return err
+		}
+		rows = rows[len(batch):]
+	}
+	return nil
+}
+
+// insert stores a batch of rejected logs in one INSERT
+func (q *Quarantine) insert(rows []quarantineRow) error {
+	values := make([]string, len(rows))
+	for i, row := range rows {
+		values[i] = fmt.Sprintf("(%s, %s, %s, %s, %s, %s)",
+			chsql.Quote(row.at.UTC().Format("2006-01-02 15:04:05.000")),
+			chsql.Quote(row.ruleSet),
+			chsql.Quote(row.rule),
+			chsql.Quote(row.reason),
+			chsql.Quote(row.service),
+			chsql.Quote(row.raw),
+		)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (quarantined_at, ruleset, rule, reason, service, raw) VALUES %s",
+		q.table, strings.Join(values, ", "))
+	if err := q.db.Execute(context.Background(), query); err != nil {
+		return fmt.Errorf("failed to quarantine %d logs: %w", len(rows), err)
+	}
+	return nil
+}
+
+// requeue puts rows that could not be stored back in front of the logs
+// rejected since, dropping the oldest beyond the buffer size
+func (q *Quarantine) requeue(rows []quarantineRow) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pending := append(append([]quarantineRow(nil), rows...), q.pending...)
+	if excess := len(pending) - quarantineBufferSize; excess > 0 {
+		pending = pending[excess:]
+		q.dropped += int64(excess)
+	}
+	q.pending = pending
+}
+
+// Start flushes the buffered rejected logs periodically until the context
+// is cancelled
+func (q *Quarantine) Start(ctx context.Context, interval time.Duration) {
+	if !q.enabled {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := q.Flush(); err != nil {
+				log.Error().Err(err).Msg("Failed to store quarantined logs")
+			}
+		case <-ctx.Done():
+			if err := q.Flush(); err != nil {
+				log.Error().Err(err).Msg("Failed to store quarantined logs")
+			}
+			return
+		}
+	}
+}
+
+// List returns quarantined logs, newest first
+func (q *Quarantine) List(filter QuarantineFilter) ([]*QuarantinedLog, error) {
+	if !q.enabled {
+		return nil, fmt.Errorf("quarantine storage is disabled")
+	}
+
+	where := q.buildWhere(filter)
+	limit := filter.Limit
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	rows, err := q.db.ExecuteSQL(fmt.Sprintf(`
+		SELECT toString(id) AS id, quarantined_at, ruleset, rule, reason, service, raw
+		FROM %s
+		%s
+		ORDER BY quarantined_at DESC
+		LIMIT %d OFFSET %d
+	`, q.table, where, limit, filter.Offset))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quarantined logs: %w", err)
+	}
+
+	entries := make([]*QuarantinedLog, 0, len(rows))
+	for _, row := range rows {
+		entry := &QuarantinedLog{}
+		entry.ID, _ = row["id"].(string)
+		entry.RuleSet, _ = row["ruleset"].(string)
+		entry.Rule, _ = row["rule"].(string)
+		entry.Reason, _ = row["reason"].(string)
+		entry.Service, _ = row["service"].(string)
+		if ts, ok := row["quarantined_at"].(string); ok {
+			entry.QuarantinedAt, _ = time.Parse("2006-01-02 15:04:05.000", ts)
+		}
+		if raw, ok := row["raw"].(string); ok {
+			var l models.Log
+			if err := json.Unmarshal([]byte(raw), &l); err == nil {
+				entry.Log = &l
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Stats returns rejection counts per ruleset and rule
+func (q *Quarantine) Stats(filter QuarantineFilter) ([]map[string]interface{}, error) {
+	if !q.enabled {
+		return nil, fmt.Errorf("quarantine storage is disabled")
+	}
+
+	rows, err := q.db.ExecuteSQL(fmt.Sprintf(`
+		SELECT ruleset, rule, count() AS count, max(quarantined_at) AS last_seen
+		FROM %s
+		%s
+		GROUP BY ruleset, rule
+		ORDER BY count DESC
+	`, q.table, q.buildWhere(filter)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load quarantine stats: %w", err)
+	}
+	return rows, nil
+}
+
+func (q *Quarantine) buildWhere(filter QuarantineFilter) string {
+	var conditions []string
+	if filter.Rule != "" {
+		conditions = append(conditions, "rule = "+chsql.Quote(filter.Rule))
+	}
+	if filter.RuleSet != "" {
+		conditions = append(conditions, "ruleset = "+chsql.Quote(filter.RuleSet))
+	}
+	if filter.Service != "" {
+		conditions = append(conditions, "service = "+chsql.Quote(filter.Service))
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("quarantined_at >= %s", chsql.Quote(filter.Since.UTC().Format("2006-01-02 15:04:05"))))
+	}
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
@@ -0,0 +1,103 @@
+package parsing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// recordingExecutor records the statements executed, failing while fail is set
+type recordingExecutor struct {
+	mu      sync.Mutex
+	queries []string
+	fail    bool
+}
+
+func (e *recordingExecutor) Execute(ctx context.Context, query string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.fail {
+		return fmt.Errorf("clickhouse unavailable")
+	}
+	e.queries = append(e.queries, query)
+	return nil
+}
+
+func (e *recordingExecutor) ExecuteSQL(sql string) ([]map[string]interface{}, error) {
+	return nil, nil
+}
+
+func (e *recordingExecutor) inserts() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var inserts []string
+	for _, q := range e.queries {
+		if strings.HasPrefix(q, "INSERT") {
+			inserts = append(inserts, q)
+		}
+	}
+	return inserts
+}
+
+func TestQuarantineBuffersRejectedLogs(t *testing.T) {
+	db := &recordingExecutor{}
+	q, err := NewQuarantine(db, nil, true)
+	if err != nil {
+		t.Fatalf("new quarantine: %v", err)
+	}
+
+	for i := 0; i < quarantineBatchSize+5; i++ {
+		q.Reject("default", &models.Log{Service: "api", Message: fmt.Sprintf("log %d", i)}, &ValidationError{Rule: "level_enum", Field: "level", Err: fmt.Errorf("invalid level")})
+	}
+	if inserts := db.inserts(); len(inserts) != 0 {
+		t.Fatalf("Reject ran %d inserts, want none before a flush", len(inserts))
+	}
+
+	if err := q.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	inserts := db.inserts()
+	if len(inserts) != 2 {
+		t.Fatalf("flush ran %d inserts, want 2 batches", len(inserts))
+	}
+	if got := strings.Count(inserts[0], "'level_enum'"); got != quarantineBatchSize {
+		t.Fatalf("first batch holds %d rows, want %d", got, quarantineBatchSize)
+	}
+}
+
+func TestQuarantineRetriesAndDropsOldest(t *testing.T) {
+	db := &recordingExecutor{}
+	q, err := NewQuarantine(db, nil, true)
+	if err != nil {
+		t.Fatalf("new quarantine: %v", err)
+	}
+
+	db.fail = true
+	for i := 0; i < quarantineBufferSize+3; i++ {
+		q.Reject("default", &models.Log{Service: fmt.Sprintf("svc-%d", i)}, &ValidationError{Rule: "required", Field: "message", Err: fmt.Errorf("missing")})
+	}
+	if err := q.Flush(); err == nil {
+		t.Fatal("want the flush to fail while ClickHouse is down")
+	}
+	if len(q.pending) != quarantineBufferSize {
+		t.Fatalf("%d rows pending, want the %d newest kept for a retry", len(q.pending), quarantineBufferSize)
+	}
+	if first := q.pending[0].service; first != "svc-3" {
+		t.Fatalf("oldest pending row is %s, want svc-3", first)
+	}
+
+	db.fail = false
+	if err := q.Flush(); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if len(q.pending) != 0 {
+		t.Fatalf("%d rows pending after the retry, want none", len(q.pending))
+	}
+	if got := len(db.inserts()); got != quarantineBufferSize/quarantineBatchSize {
+		t.Fatalf("retry ran %d inserts, want %d", got, quarantineBufferSize/quarantineBatchSize)
+	}
+}
@@ -3,7 +3,6 @@ package parsing
 import (
 	"fmt"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
@@ -55,18 +54,40 @@ func (p *RegexParser) CanParse(rawLog string) bool {
 
 // Parse parses a log using the first matching regex pattern
 func (p *RegexParser) Parse(rawLog string) (*models.Log, error) {
-	// Try patterns in priority order
+	return p.ParseWithTimestamps(rawLog, nil)
+}
+
+// ParseWithTimestamps parses a log, reading timestamps with the given parser
+func (p *RegexParser) ParseWithTimestamps(rawLog string, timestamps *TimestampParser) (*models.Log, error) {
+	log, _, err := p.ParsePattern(rawLog, "", timestamps)
+	return log, err
+}
+
+// ParsePattern parses a log with the named pattern only, or with the first
+// matching pattern in priority order when name is empty. Timestamps are
+// read with the default parser when timestamps is nil.
+func (p *RegexParser) ParsePattern(rawLog string, name string, timestamps *TimestampParser) (*models.Log, string, error) {
+	if timestamps == nil {
+		timestamps = defaultTimestampParser
+	}
 	for _, pattern := range p.patterns {
+		if name != "" && pattern.Name != name {
+			continue
+		}
 		if matches := pattern.Pattern.FindStringSubmatch(rawLog); matches != nil {
-			return p.parseWithPattern(rawLog, pattern, matches)
+			log, err := p.parseWithPattern(rawLog, pattern, matches, timestamps)
+			return log, pattern.Name, err
+		}
+		if name != "" {
+			break
 		}
 	}
 	
-	return nil, fmt.Errorf("no regex pattern matched the log")
+	return nil, "", fmt.Errorf("no regex pattern matched the log")
 }
 
 // parseWithPattern parses a log using a specific pattern and its matches
-func (p *RegexParser) parseWithPattern(rawLog string, pattern *RegexPattern, matches []string) (*models.Log, error) {
+func (p *RegexParser) parseWithPattern(rawLog string, pattern *RegexPattern, matches []string, timestamps *TimestampParser) (*models.Log, error) {
 	log := &models.Log{
 		Attributes: make(map[string]interface{}),
 	}
@@ -93,11 +114,11 @@ func (p *RegexParser) parseWithPattern(rawLog string, pattern *RegexPattern, mat
 		// Map to standard fields or attributes
 		switch fieldName {
 		case "timestamp", "time", "date":
-			if t, err := parseTimestamp(match); err == nil {
+			if t, err := timestamps.Parse(match); err == nil {
 				log.Timestamp = t
 			}
 		case "level", "severity", "priority":
-			log.Level = mapSeverityToLevel(match)
+			log.Level = parseLevel(match)
 		case "message", "msg", "text":
 			log.Message = strings.TrimSpace(match)
 		case "service", "app", "component", "logger":
@@ -106,6 +127,8 @@ func (p *RegexParser) parseWithPattern(rawLog string, pattern *RegexPattern, mat
 			log.TraceID = strings.TrimSpace(match)
 		case "span_id", "spanid", "span":
 			log.SpanID = strings.TrimSpace(match)
+		case "kvpairs":
+			applyKeyValuePairs(log, ParseKeyValuePairs(match), timestamps)
 		default:
 			// Add as attribute
 			log.Attributes[fieldName] = strings.TrimSpace(match)
@@ -118,7 +141,7 @@ func (p *RegexParser) parseWithPattern(rawLog string, pattern *RegexPattern, mat
 			if value, exists := log.Attributes[captured]; exists {
 				switch target {
 				case "level":
-					log.Level = mapSeverityToLevel(fmt.Sprintf("%v", value))
+					log.Level = parseLevel(fmt.Sprintf("%v", value))
 				case "service":
 					log.Service = fmt.Sprintf("%v", value)
 				case "trace_id":
@@ -245,7 +268,7 @@ func (p *RegexParser) AddDefaultPatterns() {
 		// Key-value pairs
 		{
 			Name:        "key_value",
-			PatternStr:  `^(?P<message>.*?)(?:\s+(?P<kvpairs>(?:\w+=\S+\s*)+))$`,
+			PatternStr:  `^(?P<message>.*?)(?:\s+(?P<kvpairs>(?:[\w.-]+=(?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|\S*)\s*)+))$`,
 			Priority:    40,
 			Description: "Message with Key-Value Pairs",
 		},
@@ -271,38 +294,33 @@ func (p *RegexParser) GetPatterns() []*RegexPattern {
 	return p.patterns
 }
 
-// parseTimestamp attempts to parse various timestamp formats
-func parseTimestamp(timeStr string) (time.Time, error) {
-	// Common timestamp formats
-	formats := []string{
-		time.RFC3339,
-		time.RFC3339Nano,
-		"2006-01-02T15:04:05.000Z",
-		"2006-01-02T15:04:05.000000Z",
-		"2006-01-02 15:04:05.000",
-		"2006-01-02 15:04:05",
-		"2006/01/02 15:04:05",
-		"Jan 02 15:04:05",
-		"Jan _2 15:04:05",
-		"02/Jan/2006:15:04:05 -0700",
-		"2006-01-02T15:04:05-07:00",
-		"2006-01-02T15:04:05.000-07:00",
-	}
-	
-	for _, format := range formats {
-		if t, err := time.Parse(format, timeStr); err == nil {
-			return t, nil
-		}
-	}
-	
-	// Try Unix timestamp
-	if timestamp, err := strconv.ParseInt(timeStr, 10, 64); err == nil {
-		if timestamp > 1000000000000 { // Milliseconds
-			return time.Unix(0, timestamp*int64(time.Millisecond)), nil
-		} else { // Seconds
-			return time.Unix(timestamp, 0), nil
+// applyKeyValuePairs stores parsed pairs on the log. Well-known keys such as
+// level or trace_id populate the standard fields, everything else becomes an attribute.
+func applyKeyValuePairs(log *models.Log, pairs []KeyValuePair, timestamps *TimestampParser) {
+	for _, pair := range pairs {
+		value := fmt.Sprintf("%v", pair.Value)
+		switch strings.ToLower(pair.Key) {
+		case "level", "lvl", "severity":
+			log.Level = parseLevel(value)
+		case "service", "app":
+			log.Service = value
+		case "trace_id", "traceid":
+			log.TraceID = value
+		case "span_id", "spanid":
+			log.SpanID = value
+		case "ts", "time", "timestamp":
+			if t, err := timestamps.Parse(value); err == nil {
+				log.Timestamp = t
+			} else {
+				log.Attributes[pair.Key] = pair.Value
+			}
+		default:
+			log.Attributes[pair.Key] = pair.Value
 		}
 	}
-	
-	return time.Time{}, fmt.Errorf("unable to parse timestamp: %s", timeStr)
-}
\ No newline at end of file
+}
+
+// parseTimestamp attempts to parse various timestamp formats as UTC
+func parseTimestamp(timeStr string) (time.Time, error) {
+	return defaultTimestampParser.Parse(timeStr)
+}
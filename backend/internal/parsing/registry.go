@@ -0,0 +1,618 @@
+package parsing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultRuleSetName is the ruleset used when no binding matches
+const DefaultRuleSetName = "default"
+
+// redacted masks the API keys of rulesets returned by the API. Sending a
+// masked key back in an update keeps the stored key.
+const redacted = "********"
+
+// NamedRuleSet is a ruleset that can be bound to services or API keys
+type NamedRuleSet struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Services    []string  `json:"services,omitempty"`
+	APIKeys     []string  `json:"api_keys,omitempty"`
+	Rules       *RuleSet  `json:"rules"`
+	Version     int       `json:"version"`
+	Comment     string    `json:"comment,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// RuleSetStorage persists named rulesets
+type RuleSetStorage interface {
+	Save(ruleSet *NamedRuleSet) error
+	LoadAll() ([]*NamedRuleSet, error)
+	Delete(name string) error
+}
+
+// InMemoryRuleSetStorage keeps rulesets and their revisions in memory only
+type InMemoryRuleSetStorage struct {
+	data    map[string]*NamedRuleSet
+	history map[string][]*RuleSetVersion
+	mu      sync.RWMutex
+}
+
+// NewInMemoryRuleSetStorage creates a new in-memory ruleset storage
+func NewInMemoryRuleSetStorage() *InMemoryRuleSetStorage {
+	return &InMemoryRuleSetStorage{
+		data:    make(map[string]*NamedRuleSet),
+		history: make(map[string][]*RuleSetVersion),
+	}
+}
+
+// Save stores a ruleset in memory
+func (s *InMemoryRuleSetStorage) Save(ruleSet *NamedRuleSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[ruleSet.Name] = ruleSet
+
+	// Keep a copy so later mutations do not rewrite history
+	snapshot, err := copyNamedRuleSet(ruleSet)
+	if err != nil {
+		return err
+	}
+	s.history[ruleSet.Name] = append(s.history[ruleSet.Name], &RuleSetVersion{
+		Name:      ruleSet.Name,
+		Version:   ruleSet.Version,
+		Comment:   ruleSet.Comment,
+		CreatedAt: time.Now(),
+		RuleSet:   snapshot,
+	})
+	return nil
+}
+
+// History returns every revision of a ruleset, newest first
+func (s *InMemoryRuleSetStorage) History(name string) ([]*RuleSetVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	revisions := s.history[name]
+	versions := make([]*RuleSetVersion, 0, len(revisions))
+	for i := len(revisions) - 1; i >= 0; i-- {
+		versions = append(versions, revisions[i])
+	}
+	return versions, nil
+}
+
+// LoadVersion returns a specific revision of a ruleset
+func (s *InMemoryRuleSetStorage) LoadVersion(name string, version int) (*NamedRuleSet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, v := range s.history[name] {
+		if v.Version == version && !v.Deleted {
+			return copyNamedRuleSet(v.RuleSet)
+		}
+	}
+	return nil, fmt.Errorf("ruleset version not found: %s@%d", name, version)
+}
+
+// LoadAll returns all stored rulesets
+func (s *InMemoryRuleSetStorage) LoadAll() ([]*NamedRuleSet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ruleSets := make([]*NamedRuleSet, 0, len(s.data))
+	for _, rs := range s.data {
+		ruleSets = append(ruleSets, rs)
+	}
+	return ruleSets, nil
+}
+
+// Delete removes a ruleset from memory and records a tombstone revision
+func (s *InMemoryRuleSetStorage) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	version := 1
+	if revisions := s.history[name]; len(revisions) > 0 {
+		version = revisions[len(revisions)-1].Version + 1
+	}
+	s.history[name] = append(s.history[name], &RuleSetVersion{
+		Name:      name,
+		Version:   version,
+		Deleted:   true,
+		Comment:   "deleted",
+		CreatedAt: time.Now(),
+	})
+	delete(s.data, name)
+	return nil
+}
+
+// FileRuleSetStorage persists rulesets as a single JSON document on disk
+type FileRuleSetStorage struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileRuleSetStorage creates a file-backed ruleset storage
+func NewFileRuleSetStorage(path string) *FileRuleSetStorage {
+	return &FileRuleSetStorage{path: path}
+}
+
+// Save writes or replaces a ruleset in the file
+func (s *FileRuleSetStorage) Save(ruleSet *NamedRuleSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.read()
+	if err != nil {
+		return err
+	}
+	all[ruleSet.Name] = ruleSet
+	return s.write(all)
+}
+
+// LoadAll reads every ruleset from the file
+func (s *FileRuleSetStorage) LoadAll() ([]*NamedRuleSet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	ruleSets := make([]*NamedRuleSet, 0, len(all))
+	for _, rs := range all {
+		ruleSets = append(ruleSets, rs)
+	}
+	return ruleSets, nil
+}
+
+// Delete removes a ruleset from the file
+func (s *FileRuleSetStorage) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.read()
+	if err != nil {
+		return err
+	}
+	delete(all, name)
+	return s.write(all)
+}
+
+func (s *FileRuleSetStorage) read() (map[string]*NamedRuleSet, error) {
+	all := make(map[string]*NamedRuleSet)
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return all, nil
+		}
+		return nil, fmt.Errorf("failed to read rulesets file: %w", err)
+	}
+	if len(data) == 0 {
+		return all, nil
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to decode rulesets file: %w", err)
+	}
+	return all, nil
+}
+
+func (s *FileRuleSetStorage) write(all map[string]*NamedRuleSet) error {
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode rulesets: %w", err)
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create rulesets directory: %w", err)
+		}
+	}
+
+	// Write to a temp file first so a crash never leaves a truncated file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write rulesets file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// RuleSetRegistry holds named rulesets and resolves which one applies to a log
+type RuleSetRegistry struct {
+	mu        sync.RWMutex
+	ruleSets  map[string]*NamedRuleSet
+	byService map[string]string
+	byAPIKey  map[string]string
+	storage   RuleSetStorage
+}
+
+// NewRuleSetRegistry creates a registry containing only the default ruleset
+func NewRuleSetRegistry() *RuleSetRegistry {
+	now := time.Now()
+	r := &RuleSetRegistry{
+		ruleSets:  make(map[string]*NamedRuleSet),
+		byService: make(map[string]string),
+		byAPIKey:  make(map[string]string),
+		storage:   NewInMemoryRuleSetStorage(),
+	}
+	r.ruleSets[DefaultRuleSetName] = &NamedRuleSet{
+		Name:        DefaultRuleSetName,
+		Description: "Built-in ruleset applied when no binding matches",
+		Rules:       NewDefaultRuleSet(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	return r
+}
+
+// SetStorage sets the storage backend and loads any rulesets it contains
+func (r *RuleSetRegistry) SetStorage(storage RuleSetStorage) error {
+	stored, err := storage.LoadAll()
+	if err != nil {
+		return fmt.Errorf("failed to load rulesets: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.storage = storage
+	for _, rs := range stored {
+		if rs.Rules == nil {
+			continue
+		}
+		r.ruleSets[rs.Name] = rs
+	}
+	r.rebuildBindings()
+
+	log.Info().Int("count", len(stored)).Msg("Loaded parsing rulesets")
+	return nil
+}
+
+// Get returns a ruleset by name, API keys masked
+func (r *RuleSetRegistry) Get(name string) (*NamedRuleSet, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rs, exists := r.ruleSets[name]
+	if !exists {
+		return nil, fmt.Errorf("ruleset not found: %s", name)
+	}
+	return rs.redact(), nil
+}
+
+// List returns all rulesets sorted by name, API keys masked
+func (r *RuleSetRegistry) List() []*NamedRuleSet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ruleSets := make([]*NamedRuleSet, 0, len(r.ruleSets))
+	for _, rs := range r.ruleSets {
+		ruleSets = append(ruleSets, rs.redact())
+	}
+	sort.Slice(ruleSets, func(i, j int) bool {
+		return ruleSets[i].Name < ruleSets[j].Name
+	})
+	return ruleSets
+}
+
+// Create adds a new ruleset. The caller gets it back with its API keys
+// masked.
+func (r *RuleSetRegistry) Create(rs *NamedRuleSet) error {
+	if err := validateNamedRuleSet(rs); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.ruleSets[rs.Name]; exists {
+		return fmt.Errorf("ruleset already exists: %s", rs.Name)
+	}
+	if err := r.checkBindingConflicts(rs); err != nil {
+		return err
+	}
+
+	rs.CreatedAt = time.Now()
+	rs.UpdatedAt = rs.CreatedAt
+	stored := *rs
+	if err := r.store(&stored); err != nil {
+		return err
+	}
+	*rs = *stored.redact()
+	return nil
+}
+
+// Update replaces an existing ruleset. API keys left masked keep their
+// stored values, and the caller gets the ruleset back with them masked.
+func (r *RuleSetRegistry) Update(name string, rs *NamedRuleSet) error {
+	rs.Name = name
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.ruleSets[name]
+	if !exists {
+		return fmt.Errorf("ruleset not found: %s", name)
+	}
+	if err := rs.keepSecrets(existing); err != nil {
+		return err
+	}
+	if err := validateNamedRuleSet(rs); err != nil {
+		return err
+	}
+	if err := r.checkBindingConflicts(rs); err != nil {
+		return err
+	}
+
+	rs.CreatedAt = existing.CreatedAt
+	rs.UpdatedAt = time.Now()
+	stored := *rs
+	if err := r.store(&stored); err != nil {
+		return err
+	}
+	*rs = *stored.redact()
+	return nil
+}
+
+// Delete removes a ruleset; the default ruleset cannot be deleted
+func (r *RuleSetRegistry) Delete(name string) error {
+	if name == DefaultRuleSetName {
+		return fmt.Errorf("the default ruleset cannot be deleted")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.ruleSets[name]; !exists {
+		return fmt.Errorf("ruleset not found: %s", name)
+	}
+	if err := r.storage.Delete(name); err != nil {
+		return fmt.Errorf("failed to delete ruleset: %w", err)
+	}
+
+	delete(r.ruleSets, name)
+	r.rebuildBindings()
+	return nil
+}
+
+// Resolve picks the ruleset for a log. API key bindings take precedence
+// over service bindings; the default ruleset is used otherwise.
+func (r *RuleSetRegistry) Resolve(service, apiKey string) *NamedRuleSet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if apiKey != "" {
+		if name, ok := r.byAPIKey[apiKey]; ok {
+			return r.ruleSets[name]
+		}
+	}
+	if service != "" {
+		if name, ok := r.byService[service]; ok {
+			return r.ruleSets[name]
+		}
+	}
+	return r.ruleSets[DefaultRuleSetName]
+}
+
+// store persists a ruleset as a new revision and updates the in-memory
+// indexes. Caller holds the lock.
+func (r *RuleSetRegistry) store(rs *NamedRuleSet) error {
+	rs.Version = r.nextVersion(rs.Name)
+	if err := r.storage.Save(rs); err != nil {
+		return fmt.Errorf("failed to save ruleset: %w", err)
+	}
+	r.ruleSets[rs.Name] = rs
+	r.rebuildBindings()
+	return nil
+}
+
+// nextVersion returns the revision number for the next save of a ruleset
+func (r *RuleSetRegistry) nextVersion(name string) int {
+	latest := 0
+	if existing, ok := r.ruleSets[name]; ok {
+		latest = existing.Version
+	}
+	if versioned, ok := r.storage.(VersionedRuleSetStorage); ok {
+		if history, err := versioned.History(name); err == nil && len(history) > 0 && history[0].Version > latest {
+			latest = history[0].Version
+		}
+	}
+	return latest + 1
+}
+
+// History returns the revisions of a ruleset when the storage keeps them,
+// API keys masked
+func (r *RuleSetRegistry) History(name string) ([]*RuleSetVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versioned, ok := r.storage.(VersionedRuleSetStorage)
+	if !ok {
+		return nil, fmt.Errorf("ruleset storage does not keep version history")
+	}
+	history, err := versioned.History(name)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]*RuleSetVersion, len(history))
+	for i, v := range history {
+		copied := *v
+		if v.RuleSet != nil {
+			copied.RuleSet = v.RuleSet.redact()
+		}
+		versions[i] = &copied
+	}
+	return versions, nil
+}
+
+// Rollback restores an earlier revision of a ruleset by saving it as a new
+// revision. Deleted rulesets can be restored the same way. The restored
+// ruleset is returned with its API keys masked.
+func (r *RuleSetRegistry) Rollback(name string, version int) (*NamedRuleSet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versioned, ok := r.storage.(VersionedRuleSetStorage)
+	if !ok {
+		return nil, fmt.Errorf("ruleset storage does not keep version history")
+	}
+
+	previous, err := versioned.LoadVersion(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateNamedRuleSet(previous); err != nil {
+		return nil, err
+	}
+	if err := r.checkBindingConflicts(previous); err != nil {
+		return nil, err
+	}
+
+	previous.Comment = fmt.Sprintf("rollback to version %d", version)
+	previous.UpdatedAt = time.Now()
+	if err := r.store(previous); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("ruleset", name).Int("version", version).Msg("Ruleset rolled back")
+	return previous.redact(), nil
+}
+
+// checkBindingConflicts ensures a service or API key is bound to at most one ruleset
+func (r *RuleSetRegistry) checkBindingConflicts(rs *NamedRuleSet) error {
+	for _, service := range rs.Services {
+		if owner, ok := r.byService[service]; ok && owner != rs.Name {
+			return fmt.Errorf("service %s is already bound to ruleset %s", service, owner)
+		}
+	}
+	for _, key := range rs.APIKeys {
+		if owner, ok := r.byAPIKey[key]; ok && owner != rs.Name {
+			return fmt.Errorf("API key is already bound to ruleset %s", owner)
+		}
+	}
+	return nil
+}
+
+func (r *RuleSetRegistry) rebuildBindings() {
+	r.byService = make(map[string]string)
+	r.byAPIKey = make(map[string]string)
+	for name, rs := range r.ruleSets {
+		for _, service := range rs.Services {
+			r.byService[service] = name
+		}
+		for _, key := range rs.APIKeys {
+			r.byAPIKey[key] = name
+		}
+	}
+}
+
+// maskAPIKey masks an API key, keeping the last characters of long keys so
+// that the keys of a ruleset can be told apart
+func maskAPIKey(key string) string {
+	if len(key) < 16 {
+		return redacted
+	}
+	return redacted + key[len(key)-4:]
+}
+
+// redact returns a copy of a ruleset with its API keys masked
+func (rs *NamedRuleSet) redact() *NamedRuleSet {
+	copied := *rs
+	if len(rs.APIKeys) > 0 {
+		copied.APIKeys = make([]string, len(rs.APIKeys))
+		for i, key := range rs.APIKeys {
+			copied.APIKeys[i] = maskAPIKey(key)
+		}
+	}
+	return &copied
+}
+
+// keepSecrets restores into an updated ruleset the API keys of the stored
+// one that the update left masked. Each stored key is restored at most
+// once, in order, so keys masked alike are all kept.
+func (rs *NamedRuleSet) keepSecrets(stored *NamedRuleSet) error {
+	used := make([]bool, len(stored.APIKeys))
+	for i, key := range rs.APIKeys {
+		if !strings.HasPrefix(key, redacted) {
+			continue
+		}
+		restored := false
+		for j, storedKey := range stored.APIKeys {
+			if !used[j] && maskAPIKey(storedKey) == key {
+				rs.APIKeys[i] = storedKey
+				used[j] = true
+				restored = true
+				break
+			}
+		}
+		if !restored {
+			return fmt.Errorf("masked API key %s matches no API key of ruleset %s", key, stored.Name)
+		}
+	}
+	return nil
+}
+
+// copyNamedRuleSet deep-copies a ruleset through JSON
+func copyNamedRuleSet(rs *NamedRuleSet) (*NamedRuleSet, error) {
+	data, err := json.Marshal(rs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy ruleset: %w", err)
+	}
+	var copied NamedRuleSet
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, fmt.Errorf("failed to copy ruleset: %w", err)
+	}
+	return &copied, nil
+}
+
+var ruleSetNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// validateNamedRuleSet checks the name and that every regex in the ruleset compiles
+func validateNamedRuleSet(rs *NamedRuleSet) error {
+	if !ruleSetNamePattern.MatchString(rs.Name) {
+		return fmt.Errorf("ruleset name must contain only alphanumeric characters, underscores, and hyphens")
+	}
+	if rs.Rules == nil {
+		return fmt.Errorf("ruleset rules are required")
+	}
+	for _, key := range rs.APIKeys {
+		if strings.HasPrefix(key, redacted) {
+			return fmt.Errorf("API keys cannot start with %s", redacted)
+		}
+	}
+	if _, err := rs.Rules.TimestampParser(); err != nil {
+		return err
+	}
+	if fc := rs.Rules.Flatten; fc != nil {
+		if err := fc.Validate(); err != nil {
+			return err
+		}
+	}
+	if sm := rs.Rules.SeverityMapping; sm != nil {
+		if err := sm.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, rule := range rs.Rules.ValidationRules {
+		if rule.Pattern == "" {
+			continue
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("validation rule %s has invalid pattern: %w", rule.Name, err)
+		}
+	}
+	for _, rule := range rs.Rules.TransformRules {
+		if rule.Pattern == "" {
+			continue
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("transform rule %s has invalid pattern: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package parsing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+)
+
+// RuleSetVersion is a historical revision of a named ruleset
+type RuleSetVersion struct {
+	Name      string        `json:"name"`
+	Version   int           `json:"version"`
+	Deleted   bool          `json:"deleted"`
+	Comment   string        `json:"comment,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	RuleSet   *NamedRuleSet `json:"ruleset,omitempty"`
+}
+
+// VersionedRuleSetStorage is implemented by storages that keep every revision
+type VersionedRuleSetStorage interface {
+	RuleSetStorage
+	History(name string) ([]*RuleSetVersion, error)
+	LoadVersion(name string, version int) (*NamedRuleSet, error)
+}
+
+// SQLExecutor is the subset of the database used by parsing components
+// that persist state in ClickHouse
+type SQLExecutor interface {
+	Execute(ctx context.Context, query string) error
+	ExecuteSQL(sql string) ([]map[string]interface{}, error)
+}
+
+// ClickHouseRuleSetStorage stores every ruleset revision as a row in ClickHouse.
+// Deletions are recorded as tombstone revisions so history is never lost.
+type ClickHouseRuleSetStorage struct {
+	db    SQLExecutor
+	table string
+}
+
+// NewClickHouseRuleSetStorage creates the rulesets table if needed
+func NewClickHouseRuleSetStorage(db SQLExecutor) (*ClickHouseRuleSetStorage, error) {
+	s := &ClickHouseRuleSetStorage{
+		db:    db,
+		table: "parsing_rulesets",
+	}
+
+	ddl := fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		name String,
+		version UInt32,
+		definition String,
+		deleted UInt8 DEFAULT 0,
+		comment String DEFAULT '',
+		created_at DateTime64(3) DEFAULT now64(3)
+	) ENGINE = MergeTree()
+	ORDER BY (name, version)
+	`, s.table)
+
+	if err := db.Execute(context.Background(), ddl); err != nil {
+		return nil, fmt.Errorf("failed to create rulesets table: %w", err)
+	}
+	return s, nil
+}
+
+// Save appends a new revision of the ruleset
+func (s *ClickHouseRuleSetStorage) Save(ruleSet *NamedRuleSet) error {
+	definition, err := json.Marshal(ruleSet)
+	if err != nil {
+		return fmt.Errorf("failed to encode ruleset: %w", err)
+	}
+	return s.insert(ruleSet.Name, ruleSet.Version, string(definition), false, ruleSet.Comment)
+}
+
+// LoadAll returns the latest non-deleted revision of every ruleset
+func (s *ClickHouseRuleSetStorage) LoadAll() ([]*NamedRuleSet, error) {
+	rows, err := s.db.ExecuteSQL(fmt.Sprintf(`
+		SELECT name, argMax(definition, version) AS definition, argMax(deleted, version) AS deleted
+		FROM %s
+		GROUP BY name
+	`, s.table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rulesets: %w", err)
+	}
+
+	ruleSets := make([]*NamedRuleSet, 0, len(rows))
+	for _, row := range rows {
+		if toInt(row["deleted"]) != 0 {
+			continue
+		}
+		rs, err := decodeRuleSet(row["definition"])
+		if err != nil {
+			return nil, err
+		}
+		ruleSets = append(ruleSets, rs)
+	}
+	return ruleSets, nil
+}
+
+// Delete records a tombstone revision
+func (s *ClickHouseRuleSetStorage) Delete(name string) error {
+	latest, err := s.latestVersion(name)
+	if err != nil {
+		return err
+	}
+	return s.insert(name, latest+1, "", true, "deleted")
+}
+
+// History returns every revision of a ruleset, newest first
+func (s *ClickHouseRuleSetStorage) History(name string) ([]*RuleSetVersion, error) {
+	rows, err := s.db.ExecuteSQL(fmt.Sprintf(`
+		SELECT version, definition, deleted, comment, created_at
+		FROM %s
+		WHERE name = %s
+		ORDER BY version DESC
+	`, s.table, chsql.Quote(name)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ruleset history: %w", err)
+	}
+
+	versions := make([]*RuleSetVersion, 0, len(rows))
+	for _, row := range rows {
+		v := &RuleSetVersion{
+			Name:    name,
+			Version: toInt(row["version"]),
+			Deleted: toInt(row["deleted"]) != 0,
+		}
+		if comment, ok := row["comment"].(string); ok {
+			v.Comment = comment
+		}
+		if ts, ok := row["created_at"].(string); ok {
+			if t, err := time.Parse("2006-01-02 15:04:05.000", ts); err == nil {
+				v.CreatedAt = t
+			}
+		}
+		if !v.Deleted {
+			if rs, err := decodeRuleSet(row["definition"]); err == nil {
+				v.RuleSet = rs
+			}
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// LoadVersion returns a specific revision of a ruleset
+func (s *ClickHouseRuleSetStorage) LoadVersion(name string, version int) (*NamedRuleSet, error) {
+	rows, err := s.db.ExecuteSQL(fmt.Sprintf(`
+		SELECT definition, deleted
+		FROM %s
+		WHERE name = %s AND version = %d
+		LIMIT 1
+	`, s.table, chsql.Quote(name), version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ruleset version: %w", err)
+	}
+	if len(rows) == 0 || toInt(rows[0]["deleted"]) != 0 {
+		return nil, fmt.Errorf("ruleset version not found: %s@%d", name, version)
+	}
+	return decodeRuleSet(rows[0]["definition"])
+}
+
+func (s *ClickHouseRuleSetStorage) latestVersion(name string) (int, error) {
+	rows, err := s.db.ExecuteSQL(fmt.Sprintf(
+		"SELECT max(version) AS version FROM %s WHERE name = %s",
+		s.table, chsql.Quote(name)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to load ruleset version: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return toInt(rows[0]["version"]), nil
+}
+
+func (s *ClickHouseRuleSetStorage) insert(name string, version int, definition string, deleted bool, comment string) error {
+	deletedFlag := 0
+	if deleted {
+		deletedFlag = 1
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s (name, version, definition, deleted, comment) VALUES (%s, %d, %s, %d, %s)",
+		s.table,
+		chsql.Quote(name),
+		version,
+		chsql.Quote(definition),
+		deletedFlag,
+		chsql.Quote(comment),
+	)
+
+	if err := s.db.Execute(context.Background(), query); err != nil {
+		return fmt.Errorf("failed to save ruleset: %w", err)
+	}
+	return nil
+}
+
+func decodeRuleSet(value interface{}) (*NamedRuleSet, error) {
+	definition, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid ruleset definition")
+	}
+	var rs NamedRuleSet
+	if err := json.Unmarshal([]byte(definition), &rs); err != nil {
+		return nil, fmt.Errorf("failed to decode ruleset: %w", err)
+	}
+	return &rs, nil
+}
+
+// toInt converts numeric JSONEachRow values, which ClickHouse may emit
+// as numbers or quoted strings depending on the type
+func toInt(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		var n int
+		fmt.Sscanf(v, "%d", &n)
+		return n
+	default:
+		return 0
+	}
+}
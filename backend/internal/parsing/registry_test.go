@@ -0,0 +1,89 @@
+package parsing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuleSetRegistryMasksAPIKeys(t *testing.T) {
+	const longKey = "ingest-key-0123456789abcd"
+	const shortKey = "short-key"
+
+	r := NewRuleSetRegistry()
+	created := &NamedRuleSet{
+		Name:    "payments",
+		APIKeys: []string{longKey, shortKey},
+		Rules:   NewDefaultRuleSet(),
+	}
+	if err := r.Create(created); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	wantMasked := []string{redacted + "abcd", redacted}
+	for _, got := range [][]string{created.APIKeys, mustGet(t, r, "payments").APIKeys, r.List()[1].APIKeys} {
+		if strings.Join(got, ",") != strings.Join(wantMasked, ",") {
+			t.Fatalf("API keys = %v, want %v", got, wantMasked)
+		}
+	}
+	if got := r.Resolve("", longKey).Name; got != "payments" {
+		t.Fatalf("resolved ruleset = %s, want payments", got)
+	}
+
+	// Sending the masks back keeps the stored keys
+	update := mustGet(t, r, "payments")
+	update.Description = "card payments"
+	update.APIKeys = append(update.APIKeys, "new-key")
+	if err := r.Update("payments", update); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	for _, key := range []string{longKey, shortKey, "new-key"} {
+		if got := r.Resolve("", key).Name; got != "payments" {
+			t.Fatalf("resolved ruleset of %s = %s, want payments", key, got)
+		}
+	}
+
+	history, err := r.History("payments")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	for _, v := range history {
+		for _, key := range v.RuleSet.APIKeys {
+			if !strings.HasPrefix(key, redacted) {
+				t.Fatalf("version %d exposes API key %s", v.Version, key)
+			}
+		}
+	}
+
+	rolledBack, err := r.Rollback("payments", 1)
+	if err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if strings.Join(rolledBack.APIKeys, ",") != strings.Join(wantMasked, ",") {
+		t.Fatalf("rolled back API keys = %v, want %v", rolledBack.APIKeys, wantMasked)
+	}
+	if got := r.Resolve("", longKey).Name; got != "payments" {
+		t.Fatalf("resolved ruleset after rollback = %s, want payments", got)
+	}
+}
+
+func TestRuleSetRegistryRejectsUnknownMasks(t *testing.T) {
+	r := NewRuleSetRegistry()
+	if err := r.Create(&NamedRuleSet{Name: "payments", APIKeys: []string{redacted}, Rules: NewDefaultRuleSet()}); err == nil {
+		t.Fatal("want a masked key rejected on create")
+	}
+	if err := r.Create(&NamedRuleSet{Name: "payments", APIKeys: []string{"ingest-key-0123456789abcd"}, Rules: NewDefaultRuleSet()}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	update := &NamedRuleSet{APIKeys: []string{redacted + "zzzz"}, Rules: NewDefaultRuleSet()}
+	if err := r.Update("payments", update); err == nil {
+		t.Fatal("want a mask matching no stored key rejected")
+	}
+}
+
+func mustGet(t *testing.T, r *RuleSetRegistry, name string) *NamedRuleSet {
+	t.Helper()
+	rs, err := r.Get(name)
+	if err != nil {
+		t.Fatalf("get %s: %v", name, err)
+	}
+	return rs
+}
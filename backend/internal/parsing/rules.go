@@ -17,6 +17,27 @@ type RuleSet struct {
 	RequiredFields    []string           `json:"required_fields"`
 	DefaultValues     map[string]string  `json:"default_values"`
 	FieldConstraints  map[string]FieldConstraint `json:"field_constraints"`
+	Timestamp         *TimestampConfig   `json:"timestamp,omitempty"`
+	SeverityMapping   *SeverityMapping   `json:"severity_mapping,omitempty"`
+	Flatten           *FlattenConfig     `json:"flatten,omitempty"`
+}
+
+// TimestampParser returns the ruleset's timestamp parser, or nil when the
+// ruleset leaves timestamp handling to the parsers
+func (rs *RuleSet) TimestampParser() (*TimestampParser, error) {
+	if rs == nil || rs.Timestamp == nil {
+		return nil, nil
+	}
+	return NewTimestampParser(*rs.Timestamp)
+}
+
+// FlattenConfig returns how the ruleset's sources have nested JSON objects
+// flattened, or nil when the ruleset leaves it to the parsers
+func (rs *RuleSet) FlattenConfig() *FlattenConfig {
+	if rs == nil {
+		return nil
+	}
+	return rs.Flatten
 }
 
 // ValidationRule defines a validation rule for parsed logs
@@ -36,7 +57,7 @@ type ValidationRule struct {
 // TransformRule defines a transformation rule for parsed logs
 type TransformRule struct {
 	Name        string            `json:"name"`
-	Type        string            `json:"type"` // "normalize", "extract", "enrich", "filter"
+	Type        string            `json:"type"` // "normalize", "extract", "enrich", "filter", "template"
 	Field       string            `json:"field"`
 	Target      string            `json:"target,omitempty"`
 	Pattern     string            `json:"pattern,omitempty"`
@@ -113,6 +134,13 @@ func NewDefaultRuleSet() *RuleSet {
 				Pattern:     `user_id[=:]\s*([a-zA-Z0-9_-]+)`,
 				Description: "Extract user_id from message",
 			},
+			{
+				Name:        "message_template",
+				Type:        "template",
+				Field:       "message",
+				Target:      "message_template",
+				Description: "Strip IDs, numbers and addresses from the message to build a groupable template",
+			},
 			{
 				Name:        "extract_request_id",
 				Type:        "extract",
@@ -171,27 +199,42 @@ func (rs *RuleSet) Validate(log *models.Log) error {
 	// Check required fields
 	for _, field := range rs.RequiredFields {
 		if err := rs.validateRequiredField(log, field); err != nil {
-			return err
+			return &ValidationError{Rule: "required_" + field, Field: field, Err: err}
 		}
 	}
 	
 	// Apply field constraints
 	for field, constraint := range rs.FieldConstraints {
 		if err := rs.validateFieldConstraint(log, field, constraint); err != nil {
-			return err
+			return &ValidationError{Rule: "constraint_" + field, Field: field, Err: err}
 		}
 	}
 	
 	// Apply validation rules
 	for _, rule := range rs.ValidationRules {
 		if err := rs.validateRule(log, rule); err != nil {
-			return err
+			return &ValidationError{Rule: rule.Name, Field: rule.Field, Err: err}
 		}
 	}
 	
 	return nil
 }
 
+// ValidationError identifies the rule that rejected a log
+type ValidationError struct {
+	Rule  string
+	Field string
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
 // Transform applies transformation rules to a parsed log
 func (rs *RuleSet) Transform(log *models.Log) error {
 	// Apply field mappings
@@ -468,6 +511,8 @@ func (rs *RuleSet) applyTransformRule(log *models.Log, rule TransformRule) error
 		return rs.applyEnrichment(log, rule)
 	case "filter":
 		return rs.applyFilter(log, rule)
+	case "template":
+		return rs.applyTemplate(log, rule)
 	default:
 		return fmt.Errorf("unknown transform rule type: %s", rule.Type)
 	}
@@ -596,6 +641,35 @@ func (rs *RuleSet) applyEnrichment(log *models.Log, rule TransformRule) error {
 	return nil
 }
 
+// applyTemplate stores the normalized form of a message and its hash so
+// logs from the same statement can be grouped
+func (rs *RuleSet) applyTemplate(log *models.Log, rule TransformRule) error {
+	source := log.Message
+	if rule.Field != "" && rule.Field != "message" {
+		attr, ok := log.Attributes[rule.Field]
+		if !ok {
+			return nil
+		}
+		source = fmt.Sprintf("%v", attr)
+	}
+	if source == "" {
+		return nil
+	}
+	
+	target := rule.Target
+	if target == "" {
+		target = "message_template"
+	}
+	
+	template := MessageTemplate(source)
+	if log.Attributes == nil {
+		log.Attributes = make(map[string]interface{})
+	}
+	log.Attributes[target] = template
+	log.Attributes[target+"_hash"] = TemplateHash(template)
+	return nil
+}
+
 // applyFilter applies filtering logic (placeholder)
 func (rs *RuleSet) applyFilter(log *models.Log, rule TransformRule) error {
 	// Filtering could be implemented here
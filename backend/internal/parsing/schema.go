@@ -0,0 +1,351 @@
+package parsing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// DefaultSchemaMaxAttributes bounds how many distinct attribute names are tracked
+const DefaultSchemaMaxAttributes = 1000
+
+// AttributeSchema describes an attribute name seen during ingestion
+type AttributeSchema struct {
+	Name      string           `json:"name"`
+	Type      string           `json:"type"`  // most frequently observed type
+	Types     map[string]int64 `json:"types"` // observations per type
+	Count     int64            `json:"count"`
+	FirstSeen time.Time        `json:"first_seen"`
+	LastSeen  time.Time        `json:"last_seen"`
+}
+
+// SchemaStorage persists observed attribute schemas
+type SchemaStorage interface {
+	Save(attributes []*AttributeSchema) error
+	LoadAll() ([]*AttributeSchema, error)
+}
+
+// SchemaRegistry tracks attribute names and their inferred types across
+// ingested logs. Once maxAttributes names are known, new names are counted
+// as overflow instead of being tracked, so high cardinality keys (ids used
+// as keys, for example) cannot grow the registry without bound.
+type SchemaRegistry struct {
+	mu            sync.RWMutex
+	attributes    map[string]*AttributeSchema
+	dirty         map[string]bool
+	maxAttributes int
+	overflow      int64
+	storage       SchemaStorage
+}
+
+// NewSchemaRegistry creates an in-memory schema registry
+func NewSchemaRegistry(maxAttributes int) *SchemaRegistry {
+	if maxAttributes <= 0 {
+		maxAttributes = DefaultSchemaMaxAttributes
+	}
+	return &SchemaRegistry{
+		attributes:    make(map[string]*AttributeSchema),
+		dirty:         make(map[string]bool),
+		maxAttributes: maxAttributes,
+	}
+}
+
+// SetStorage sets the persistence backend and loads previously observed attributes
+func (r *SchemaRegistry) SetStorage(storage SchemaStorage) error {
+	stored, err := storage.LoadAll()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.storage = storage
+	for _, attr := range stored {
+		if existing, ok := r.attributes[attr.Name]; ok {
+			mergeAttributeSchema(existing, attr)
+			continue
+		}
+		if len(r.attributes) >= r.maxAttributes {
+			break
+		}
+		if attr.Types == nil {
+			attr.Types = make(map[string]int64)
+		}
+		r.attributes[attr.Name] = attr
+	}
+	return nil
+}
+
+// Observe records the attributes of a log
+func (r *SchemaRegistry) Observe(logEntry *models.Log) {
+	if r == nil || len(logEntry.Attributes) == 0 {
+		return
+	}
+
+	now := time.Now().UTC()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, value := range logEntry.Attributes {
+		attr, ok := r.attributes[name]
+		if !ok {
+			if len(r.attributes) >= r.maxAttributes {
+				r.overflow++
+				continue
+			}
+			attr = &AttributeSchema{
+				Name:      name,
+				Types:     make(map[string]int64),
+				FirstSeen: now,
+			}
+			r.attributes[name] = attr
+		}
+		attr.Types[inferAttributeType(value)]++
+		attr.Type = dominantType(attr.Types)
+		attr.Count++
+		attr.LastSeen = now
+		r.dirty[name] = true
+	}
+}
+
+// List returns known attributes, most frequent first. An empty prefix matches all.
+func (r *SchemaRegistry) List(prefix string) []*AttributeSchema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	attributes := make([]*AttributeSchema, 0, len(r.attributes))
+	for name, attr := range r.attributes {
+		if prefix != "" && !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		copied := *attr
+		copied.Types = make(map[string]int64, len(attr.Types))
+		for t, n := range attr.Types {
+			copied.Types[t] = n
+		}
+		attributes = append(attributes, &copied)
+	}
+	sort.Slice(attributes, func(i, j int) bool {
+		if attributes[i].Count != attributes[j].Count {
+			return attributes[i].Count > attributes[j].Count
+		}
+		return attributes[i].Name < attributes[j].Name
+	})
+	return attributes
+}
+
+// Stats returns registry size and how many new names were dropped by the limit
+func (r *SchemaRegistry) Stats() map[string]interface{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return map[string]interface{}{
+		"attributes":     len(r.attributes),
+		"max_attributes": r.maxAttributes,
+		"overflow":       r.overflow,
+	}
+}
+
+// Flush writes attributes changed since the last flush to storage
+func (r *SchemaRegistry) Flush() error {
+	r.mu.Lock()
+	if r.storage == nil || len(r.dirty) == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	changed := make([]*AttributeSchema, 0, len(r.dirty))
+	for name := range r.dirty {
+		if attr, ok := r.attributes[name]; ok {
+			copied := *attr
+			copied.Types = make(map[string]int64, len(attr.Types))
+			for t, n := range attr.Types {
+				copied.Types[t] = n
+			}
+			changed = append(changed, &copied)
+		}
+	}
+	r.dirty = make(map[string]bool)
+	storage := r.storage
+	r.mu.Unlock()
+
+	if err := storage.Save(changed); err != nil {
+		// Mark them dirty again so the next flush retries
+		r.mu.Lock()
+		for _, attr := range changed {
+			r.dirty[attr.Name] = true
+		}
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Start flushes the registry periodically until the context is cancelled
+func (r *SchemaRegistry) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				log.Error().Err(err).Msg("Failed to persist attribute schema")
+			}
+		case <-ctx.Done():
+			if err := r.Flush(); err != nil {
+				log.Error().Err(err).Msg("Failed to persist attribute schema")
+			}
+			return
+		}
+	}
+}
+
+// inferAttributeType maps a decoded attribute value to a schema type
+func inferAttributeType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case int, int32, int64, uint, uint32, uint64, float32, float64, json.Number:
+		return "number"
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return "timestamp"
+		}
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "string"
+	}
+}
+
+func dominantType(types map[string]int64) string {
+	best, bestCount := "", int64(-1)
+	for t, n := range types {
+		if n > bestCount || (n == bestCount && t < best) {
+			best, bestCount = t, n
+		}
+	}
+	return best
+}
+
+func mergeAttributeSchema(dst, src *AttributeSchema) {
+	for t, n := range src.Types {
+		dst.Types[t] += n
+	}
+	dst.Type = dominantType(dst.Types)
+	dst.Count += src.Count
+	if !src.FirstSeen.IsZero() && (dst.FirstSeen.IsZero() || src.FirstSeen.Before(dst.FirstSeen)) {
+		dst.FirstSeen = src.FirstSeen
+	}
+	if src.LastSeen.After(dst.LastSeen) {
+		dst.LastSeen = src.LastSeen
+	}
+}
+
+// ClickHouseSchemaStorage keeps one row per attribute. Rows are replaced on
+// every flush and collapsed by ReplacingMergeTree, keeping the latest totals.
+type ClickHouseSchemaStorage struct {
+	db    SQLExecutor
+	table string
+}
+
+// NewClickHouseSchemaStorage creates the attribute schema table if needed
+func NewClickHouseSchemaStorage(db SQLExecutor) (*ClickHouseSchemaStorage, error) {
+	s := &ClickHouseSchemaStorage{
+		db:    db,
+		table: "attribute_schema",
+	}
+
+	ddl := fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		name String,
+		type LowCardinality(String),
+		types String,
+		count UInt64,
+		first_seen DateTime64(3),
+		last_seen DateTime64(3)
+	) ENGINE = ReplacingMergeTree(last_seen)
+	ORDER BY name
+	`, s.table)
+
+	if err := db.Execute(context.Background(), ddl); err != nil {
+		return nil, fmt.Errorf("failed to create attribute schema table: %w", err)
+	}
+	return s, nil
+}
+
+// Save writes the current totals of the given attributes
+func (s *ClickHouseSchemaStorage) Save(attributes []*AttributeSchema) error {
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(attributes))
+	for _, attr := range attributes {
+		types, err := json.Marshal(attr.Types)
+		if err != nil {
+			return fmt.Errorf("failed to encode attribute types: %w", err)
+		}
+		values = append(values, fmt.Sprintf("(%s, %s, %s, %d, %s, %s)",
+			chsql.Quote(attr.Name),
+			chsql.Quote(attr.Type),
+			chsql.Quote(string(types)),
+			attr.Count,
+			chsql.Quote(attr.FirstSeen.UTC().Format("2006-01-02 15:04:05.000")),
+			chsql.Quote(attr.LastSeen.UTC().Format("2006-01-02 15:04:05.000")),
+		))
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (name, type, types, count, first_seen, last_seen) VALUES %s",
+		s.table, strings.Join(values, ", "))
+	if err := s.db.Execute(context.Background(), query); err != nil {
+		return fmt.Errorf("failed to save attribute schema: %w", err)
+	}
+	return nil
+}
+
+// LoadAll returns the latest row of every attribute
+func (s *ClickHouseSchemaStorage) LoadAll() ([]*AttributeSchema, error) {
+	rows, err := s.db.ExecuteSQL(fmt.Sprintf(`
+		SELECT name,
+			argMax(type, last_seen) AS type,
+			argMax(types, last_seen) AS types,
+			argMax(count, last_seen) AS count,
+			min(first_seen) AS first_seen,
+			max(last_seen) AS last_seen
+		FROM %s
+		GROUP BY name
+	`, s.table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load attribute schema: %w", err)
+	}
+
+	attributes := make([]*AttributeSchema, 0, len(rows))
+	for _, row := range rows {
+		attr := &AttributeSchema{Types: make(map[string]int64)}
+		attr.Name, _ = row["name"].(string)
+		attr.Type, _ = row["type"].(string)
+		attr.Count = int64(toInt(row["count"]))
+		if types, ok := row["types"].(string); ok {
+			json.Unmarshal([]byte(types), &attr.Types)
+		}
+		if ts, ok := row["first_seen"].(string); ok {
+			attr.FirstSeen, _ = time.Parse("2006-01-02 15:04:05.000", ts)
+		}
+		if ts, ok := row["last_seen"].(string); ok {
+			attr.LastSeen, _ = time.Parse("2006-01-02 15:04:05.000", ts)
+		}
+		attributes = append(attributes, attr)
+	}
+	return attributes, nil
+}
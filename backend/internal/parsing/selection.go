@@ -0,0 +1,88 @@
+package parsing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// defaultFormatCacheSize bounds how many sources remember their last format
+const defaultFormatCacheSize = 10000
+
+// PatternParser is implemented by parsers that choose between several
+// patterns. ParsePattern tries only the named pattern, or scans all of them
+// when pattern is empty, and reports which pattern matched.
+type PatternParser interface {
+	ParsePattern(rawLog string, pattern string, timestamps *TimestampParser) (*models.Log, string, error)
+}
+
+// formatHint is the parser (and pattern) that last parsed a source
+type formatHint struct {
+	parser   string
+	pattern  string
+	lastUsed time.Time
+}
+
+// formatCache remembers which parser handled each source so that steady
+// state parsing tries one format instead of running every CanParse check
+type formatCache struct {
+	mu      sync.Mutex
+	entries map[string]*formatHint
+	maxSize int
+}
+
+func newFormatCache(maxSize int) *formatCache {
+	return &formatCache{
+		entries: make(map[string]*formatHint),
+		maxSize: maxSize,
+	}
+}
+
+func (c *formatCache) get(source string) (formatHint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hint, ok := c.entries[source]
+	if !ok {
+		return formatHint{}, false
+	}
+	hint.lastUsed = time.Now()
+	return *hint, true
+}
+
+func (c *formatCache) set(source, parser, pattern string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hint, ok := c.entries[source]; ok {
+		hint.parser, hint.pattern, hint.lastUsed = parser, pattern, time.Now()
+		return
+	}
+	if len(c.entries) >= c.maxSize {
+		c.evictOldest()
+	}
+	c.entries[source] = &formatHint{parser: parser, pattern: pattern, lastUsed: time.Now()}
+}
+
+func (c *formatCache) remove(source string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, source)
+}
+
+func (c *formatCache) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// evictOldest drops the least recently used source. Callers hold the lock.
+func (c *formatCache) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, hint := range c.entries {
+		if oldestKey == "" || hint.lastUsed.Before(oldest) {
+			oldestKey, oldest = key, hint.lastUsed
+		}
+	}
+	delete(c.entries, oldestKey)
+}
@@ -0,0 +1,167 @@
+package parsing
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// SeverityMapping converts source specific levels to the canonical level
+// enum (trace, debug, info, warn, error, fatal). Numeric levels mean different
+// things per source (syslog 3 is an error, pino 30 is info), so nothing maps
+// them by default: each source sending numeric levels needs a ruleset with a
+// mapping, otherwise its logs fail the level_enum validation.
+type SeverityMapping struct {
+	Preset     string              `json:"preset,omitempty"`     // "syslog", "python", "pino" or "bunyan"
+	Levels     map[string]string   `json:"levels,omitempty"`     // exact overrides, e.g. "notice": "info"
+	Thresholds []SeverityThreshold `json:"thresholds,omitempty"` // numeric ranges, checked before the preset
+}
+
+// SeverityThreshold maps every numeric level >= Min (up to the next threshold) to Level
+type SeverityThreshold struct {
+	Min   float64 `json:"min"`
+	Level string  `json:"level"`
+}
+
+// canonicalLevels are the levels a mapping may produce
+var canonicalLevels = map[string]bool{
+	"trace": true, "debug": true, "info": true, "warn": true, "error": true, "fatal": true,
+}
+
+var severityPresets = map[string]SeverityMapping{
+	// RFC 5424 severities
+	"syslog": {
+		Levels: map[string]string{
+			"0": "fatal", "1": "fatal", "2": "fatal", "3": "error",
+			"4": "warn", "5": "info", "6": "info", "7": "debug",
+		},
+	},
+	// Python logging: DEBUG=10 INFO=20 WARNING=30 ERROR=40 CRITICAL=50
+	"python": {
+		Thresholds: []SeverityThreshold{
+			{Min: 0, Level: "trace"},
+			{Min: 10, Level: "debug"},
+			{Min: 20, Level: "info"},
+			{Min: 30, Level: "warn"},
+			{Min: 40, Level: "error"},
+			{Min: 50, Level: "fatal"},
+		},
+	},
+	// pino and bunyan: trace=10 debug=20 info=30 warn=40 error=50 fatal=60
+	"pino": {
+		Thresholds: []SeverityThreshold{
+			{Min: 0, Level: "trace"},
+			{Min: 20, Level: "debug"},
+			{Min: 30, Level: "info"},
+			{Min: 40, Level: "warn"},
+			{Min: 50, Level: "error"},
+			{Min: 60, Level: "fatal"},
+		},
+	},
+}
+
+func init() {
+	severityPresets["bunyan"] = severityPresets["pino"]
+}
+
+// IsSeverityPreset reports whether name is a known preset
+func IsSeverityPreset(name string) bool {
+	_, ok := severityPresets[name]
+	return ok
+}
+
+// Validate checks the preset is known and every level maps to a canonical one
+func (m *SeverityMapping) Validate() error {
+	if m.Preset != "" && !IsSeverityPreset(m.Preset) {
+		return fmt.Errorf("unknown severity preset: %s", m.Preset)
+	}
+	for raw, level := range m.Levels {
+		if !canonicalLevels[level] {
+			return fmt.Errorf("severity level %s maps to %q, want one of trace, debug, info, warn, error, fatal", raw, level)
+		}
+	}
+	for _, t := range m.Thresholds {
+		if !canonicalLevels[t.Level] {
+			return fmt.Errorf("severity threshold %v maps to %q, want one of trace, debug, info, warn, error, fatal", t.Min, t.Level)
+		}
+	}
+	return nil
+}
+
+// Map converts a raw level to a canonical one. Custom levels win over
+// thresholds, which win over the preset; names fall back to the common aliases.
+func (m *SeverityMapping) Map(raw string) string {
+	key := strings.ToLower(strings.TrimSpace(raw))
+
+	if level, ok := m.Levels[key]; ok {
+		return level
+	}
+
+	preset, hasPreset := severityPresets[m.Preset]
+	if hasPreset {
+		if level, ok := preset.Levels[key]; ok {
+			return level
+		}
+	}
+
+	if value, err := strconv.ParseFloat(key, 64); err == nil {
+		if level, ok := matchThreshold(m.Thresholds, value); ok {
+			return level
+		}
+		if hasPreset {
+			if level, ok := matchThreshold(preset.Thresholds, value); ok {
+				return level
+			}
+		}
+		return raw
+	}
+
+	return mapSeverityToLevel(key)
+}
+
+// matchThreshold returns the level of the highest threshold not above value
+func matchThreshold(thresholds []SeverityThreshold, value float64) (string, bool) {
+	if len(thresholds) == 0 {
+		return "", false
+	}
+	sorted := make([]SeverityThreshold, len(thresholds))
+	copy(sorted, thresholds)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Min < sorted[j].Min })
+
+	level, found := "", false
+	for _, t := range sorted {
+		if value >= t.Min {
+			level, found = t.Level, true
+		}
+	}
+	return level, found
+}
+
+// parseLevel canonicalises level names but keeps numeric levels as-is so
+// the ruleset's severity mapping can decide what they mean
+func parseLevel(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if _, err := strconv.ParseFloat(raw, 64); err == nil {
+		return raw
+	}
+	return mapSeverityToLevel(raw)
+}
+
+// NormalizeLevel rewrites the log level using the ruleset's severity mapping.
+// Without a mapping, only well-known level names are canonicalised and
+// numeric levels are left for the level_enum validation to reject.
+func (rs *RuleSet) NormalizeLevel(log *models.Log) {
+	if rs == nil || log.Level == "" {
+		return
+	}
+	if rs.SeverityMapping != nil {
+		log.Level = rs.SeverityMapping.Map(log.Level)
+		return
+	}
+	if _, err := strconv.ParseFloat(log.Level, 64); err != nil {
+		log.Level = mapSeverityToLevel(log.Level)
+	}
+}
@@ -0,0 +1,51 @@
+package parsing
+
+import (
+	"testing"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+func TestSeverityMappingValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping SeverityMapping
+		wantErr bool
+	}{
+		{name: "preset", mapping: SeverityMapping{Preset: "bunyan"}},
+		{name: "unknown preset", mapping: SeverityMapping{Preset: "log4j"}, wantErr: true},
+		{name: "levels", mapping: SeverityMapping{Levels: map[string]string{"notice": "info"}}},
+		{name: "level outside the enum", mapping: SeverityMapping{Levels: map[string]string{"notice": "notice"}}, wantErr: true},
+		{name: "threshold outside the enum", mapping: SeverityMapping{Thresholds: []SeverityThreshold{{Min: 40, Level: "critical"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.mapping.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuleSetRegistryRejectsUnknownSeverityTargets(t *testing.T) {
+	rules := NewDefaultRuleSet()
+	rules.SeverityMapping = &SeverityMapping{Thresholds: []SeverityThreshold{{Min: 30, Level: "warning"}}}
+	if err := NewRuleSetRegistry().Create(&NamedRuleSet{Name: "legacy", Rules: rules}); err == nil {
+		t.Fatal("want a threshold mapping to warning rejected")
+	}
+}
+
+func TestNormalizeLevelNeedsMappingForNumericLevels(t *testing.T) {
+	rules := NewDefaultRuleSet()
+	log := &models.Log{Level: "30"}
+	rules.NormalizeLevel(log)
+	if log.Level != "30" {
+		t.Fatalf("level = %s, want 30 left for the level_enum validation", log.Level)
+	}
+
+	rules.SeverityMapping = &SeverityMapping{Preset: "pino"}
+	rules.NormalizeLevel(log)
+	if log.Level != "info" {
+		t.Fatalf("level = %s, want info", log.Level)
+	}
+}
@@ -0,0 +1,103 @@
+package parsing
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// templateReplacement replaces one class of variable token with a placeholder
+type templateReplacement struct {
+	pattern     *regexp.Regexp
+	placeholder string
+}
+
+// Order matters: specific shapes (UUIDs, addresses, timestamps) are replaced
+// before the generic number and identifier rules would split them up.
+var templateReplacements = []templateReplacement{
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<uuid>"},
+	{regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`), "<ts>"},
+	{regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b`), "<hex>"},
+	{regexp.MustCompile(`[\w.+-]+@[\w-]+\.[\w.-]+`), "<email>"},
+	{regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{12,}\b`), "<hex>"},
+	{regexp.MustCompile(`"[^"]*"`), `"<str>"`},
+	{regexp.MustCompile(`'[^']*'`), `'<str>'`},
+	// file.go:123, Foo.java:45, app.py:10 (line and optional column)
+	{regexp.MustCompile(`(\.\w+):\d+(?::\d+)?\b`), "$1:<n>"},
+	{regexp.MustCompile(`(?i)\bline \d+\b`), "line <n>"},
+	{regexp.MustCompile(`\b\d+\.\d+`), "<n>"},
+}
+
+// Any token containing a digit; classified by templateToken
+var templateDigitToken = regexp.MustCompile(`\b[A-Za-z0-9_-]*\d[A-Za-z0-9_-]*\b`)
+
+var templateWhitespace = regexp.MustCompile(`\s+`)
+
+// pythonTracebackHeader starts Python tracebacks, whose exception and its
+// message come last, after the frames
+const pythonTracebackHeader = "Traceback (most recent call last):"
+
+// MessageTemplate strips variable parts from a message so that messages
+// produced by the same log statement collapse into one template. For stack
+// traces only the line naming the exception and its message is used, since
+// frame lists vary with call paths and line numbers.
+func MessageTemplate(message string) string {
+	template := summaryLine(message)
+	for _, r := range templateReplacements {
+		template = r.pattern.ReplaceAllString(template, r.placeholder)
+	}
+	template = templateDigitToken.ReplaceAllStringFunc(template, templateToken)
+
+	return strings.TrimSpace(templateWhitespace.ReplaceAllString(template, " "))
+}
+
+// summaryLine returns the line of a message that is templated: the first
+// one that is not blank, or for a Python traceback its last line that is
+// not indented, which names the exception, such as "KeyError: 'user_id'".
+// Java exceptions and Go panics lead with theirs.
+func summaryLine(message string) string {
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	if strings.TrimSpace(lines[0]) != pythonTracebackHeader {
+		return lines[0]
+	}
+	for i := len(lines) - 1; i > 0; i-- {
+		line := lines[i]
+		if strings.TrimSpace(line) != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			return line
+		}
+	}
+	return lines[0]
+}
+
+// templateToken replaces plain numbers with <n> and identifier-like tokens
+// (ord_8f3k29x, req-20240101-77) with <id>. Short tokens such as http2 or
+// sha256 are kept because they are usually part of the message text.
+func templateToken(token string) string {
+	digits := 0
+	for _, c := range token {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+	if digits == len(token) {
+		return "<n>"
+	}
+	if len(token) >= 8 && digits >= 2 {
+		return "<id>"
+	}
+	return token
+}
+
+// TemplateHash returns a short stable identifier for a message template
+func TemplateHash(template string) string {
+	sum := sha1.Sum([]byte(template))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,68 @@
+package parsing
+
+import "testing"
+
+func TestMessageTemplateStackTraces(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name: "python traceback",
+			message: "Traceback (most recent call last):\n" +
+				"  File \"/app/handlers.py\", line 42, in handle\n" +
+				"    user = users[request.user_id]\n" +
+				"KeyError: 'user_id'\n",
+			want: "KeyError: '<str>'",
+		},
+		{
+			name: "chained python traceback",
+			message: "Traceback (most recent call last):\n" +
+				"  File \"/app/db.py\", line 10, in connect\n" +
+				"    sock.connect(addr)\n" +
+				"ConnectionRefusedError: [Errno 111] Connection refused\n" +
+				"\n" +
+				"During handling of the above exception, another exception occurred:\n" +
+				"\n" +
+				"Traceback (most recent call last):\n" +
+				"  File \"/app/db.py\", line 14, in connect\n" +
+				"    raise DatabaseError(f\"cannot reach {addr}\")\n" +
+				"app.errors.DatabaseError: cannot reach 10.0.0.12:5432",
+			want: "app.errors.DatabaseError: cannot reach <ip>",
+		},
+		{
+			name: "java exception",
+			message: "java.lang.IllegalStateException: order 4821 is already closed\n" +
+				"\tat com.shop.Orders.close(Orders.java:118)\n" +
+				"\tat com.shop.Api.handle(Api.java:57)",
+			want: "java.lang.IllegalStateException: order <n> is already closed",
+		},
+		{
+			name: "go panic",
+			message: "panic: runtime error: index out of range [5] with length 3\n" +
+				"\n" +
+				"goroutine 1 [running]:\n" +
+				"main.main()\n" +
+				"\t/app/main.go:8 +0x1d",
+			want: "panic: runtime error: index out of range [<n>] with length <n>",
+		},
+		{
+			name:    "leading blank lines",
+			message: "\n\r\n  \nconnection reset by peer after 30 retries",
+			want:    "connection reset by peer after <n> retries",
+		},
+		{
+			name:    "traceback header without exception",
+			message: "Traceback (most recent call last):\n  File \"/app/main.py\", line 3, in <module>",
+			want:    "Traceback (most recent call last):",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MessageTemplate(tt.message); got != tt.want {
+				t.Errorf("MessageTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package parsing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimestampConfig controls how timestamps without an explicit offset are
+// interpreted and which extra layouts are tried. It is set per ruleset;
+// logs of sources without one are read with the defaults.
+type TimestampConfig struct {
+	Timezone string   `json:"timezone,omitempty"` // IANA name, e.g. "Asia/Shanghai"; defaults to UTC
+	Layouts  []string `json:"layouts,omitempty"`  // Go layouts tried before the built-in ones
+	DayFirst bool     `json:"day_first"`          // read 03/04/2024 as 3 April instead of March 4
+}
+
+// TimestampParser parses timestamps according to a TimestampConfig
+type TimestampParser struct {
+	location *time.Location
+	layouts  []string
+	dayFirst bool
+}
+
+// Built-in layouts. Layouts without an offset are read in the configured zone.
+var defaultTimestampLayouts = []string{
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.000Z",
+	"2006-01-02T15:04:05.000000Z",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05.000",
+	"2006-01-02 15:04:05.000",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05,000",
+	"2006/01/02 15:04:05",
+	"02/Jan/2006:15:04:05 -0700",
+	"02/Jan/2006:15:04:05",
+	"2006-01-02T15:04:05-07:00",
+	"2006-01-02T15:04:05.000-07:00",
+	"Mon Jan _2 15:04:05 2006",
+	"Jan 02 15:04:05",
+	"Jan _2 15:04:05",
+}
+
+// Numeric dates whose day/month order cannot be told apart
+var (
+	monthFirstLayouts = []string{"01/02/2006 15:04:05", "01/02/2006 15:04:05.000", "01-02-2006 15:04:05"}
+	dayFirstLayouts   = []string{"02/01/2006 15:04:05", "02/01/2006 15:04:05.000", "02-01-2006 15:04:05", "02.01.2006 15:04:05"}
+)
+
+var (
+	locationCache   = make(map[string]*time.Location)
+	locationCacheMu sync.Mutex
+)
+
+var defaultTimestampParser = &TimestampParser{location: time.UTC}
+
+// NewTimestampParser creates a parser for the given configuration
+func NewTimestampParser(cfg TimestampConfig) (*TimestampParser, error) {
+	loc, err := loadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	return &TimestampParser{
+		location: loc,
+		layouts:  cfg.Layouts,
+		dayFirst: cfg.DayFirst,
+	}, nil
+}
+
+// Parse parses a timestamp string. Custom layouts are tried first, then the
+// built-in layouts, then ambiguous numeric dates, then Unix epochs.
+func (tp *TimestampParser) Parse(timeStr string) (time.Time, error) {
+	timeStr = strings.TrimSpace(timeStr)
+
+	for _, layout := range tp.layouts {
+		if t, err := time.ParseInLocation(layout, timeStr, tp.location); err == nil {
+			return tp.fillYear(t), nil
+		}
+	}
+
+	for _, layout := range defaultTimestampLayouts {
+		if t, err := time.ParseInLocation(layout, timeStr, tp.location); err == nil {
+			return tp.fillYear(t), nil
+		}
+	}
+
+	ambiguous := monthFirstLayouts
+	if tp.dayFirst {
+		ambiguous = dayFirstLayouts
+	}
+	for _, layout := range ambiguous {
+		if t, err := time.ParseInLocation(layout, timeStr, tp.location); err == nil {
+			return t, nil
+		}
+	}
+
+	if timestamp, err := strconv.ParseInt(timeStr, 10, 64); err == nil {
+		return parseEpoch(float64(timestamp)), nil
+	}
+	if timestamp, err := strconv.ParseFloat(timeStr, 64); err == nil {
+		return parseEpoch(timestamp), nil
+	}
+
+	return time.Time{}, fmt.Errorf("unable to parse timestamp: %s", timeStr)
+}
+
+// fillYear handles syslog-style stamps that omit the year. The current year
+// is assumed unless that would put the log more than a day in the future,
+// which happens around New Year.
+func (tp *TimestampParser) fillYear(t time.Time) time.Time {
+	if t.Year() != 0 {
+		return t
+	}
+	now := time.Now().In(tp.location)
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
+
+// parseEpoch converts seconds, milliseconds, microseconds or nanoseconds
+// since the epoch, picking the unit from the magnitude
+func parseEpoch(value float64) time.Time {
+	switch {
+	case value > 1e17:
+		return time.Unix(0, int64(value))
+	case value > 1e14:
+		return time.Unix(0, int64(value*float64(time.Microsecond)))
+	case value > 1e11:
+		return time.Unix(0, int64(value*float64(time.Millisecond)))
+	default:
+		sec := int64(value)
+		return time.Unix(sec, int64((value-float64(sec))*float64(time.Second)))
+	}
+}
+
+// loadLocation resolves and caches an IANA time zone
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" || strings.EqualFold(name, "UTC") {
+		return time.UTC, nil
+	}
+
+	locationCacheMu.Lock()
+	defer locationCacheMu.Unlock()
+
+	if loc, ok := locationCache[name]; ok {
+		return loc, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q: %w", name, err)
+	}
+	locationCache[name] = loc
+	return loc, nil
+}
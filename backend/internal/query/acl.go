@@ -0,0 +1,186 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+)
+
+// Saved query visibility levels
+const (
+	VisibilityPrivate = "private" // owner only
+	VisibilityTeam    = "team"    // owner and members of Team
+	VisibilityPublic  = "public"  // everyone
+)
+
+// ErrQueryAccessDenied is returned when a user may see a query but not change it
+var ErrQueryAccessDenied = fmt.Errorf("access denied")
+
+// QueryShare is a link that lets anyone holding the token view and run a query
+type QueryShare struct {
+	Token     string     `json:"token"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	CreatedBy string     `json:"created_by"`
+}
+
+// Expired reports whether the share link can no longer be used
+func (s QueryShare) Expired() bool {
+	return s.ExpiresAt != nil && time.Now().After(*s.ExpiresAt)
+}
+
+// ValidateVisibility checks a visibility level and its team
+func ValidateVisibility(visibility, team string) error {
+	switch visibility {
+	case VisibilityPrivate, VisibilityPublic:
+		return nil
+	case VisibilityTeam:
+		if team == "" {
+			return fmt.Errorf("team visibility requires a team")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid visibility: %s", visibility)
+	}
+}
+
+// CanView reports whether the user may read and execute the query. Queries
+// saved before visibility existed have none and stay visible to everyone.
+func (q *SavedQuery) CanView(user auth.User) bool {
+	if user.IsAdmin() || q.CreatedBy == user.ID {
+		return true
+	}
+	switch q.Visibility {
+	case VisibilityPublic, "":
+		return true
+	case VisibilityTeam:
+		return user.InTeam(q.Team)
+	default:
+		return false
+	}
+}
+
+// CanEdit reports whether the user may change, delete or share the query
+func (q *SavedQuery) CanEdit(user auth.User) bool {
+	return user.IsAdmin() || q.CreatedBy == user.ID
+}
+
+// ViewFor returns the query as the user may see it. Share tokens are only
+// shown to users who can edit the query.
+func (q *SavedQuery) ViewFor(user auth.User) *SavedQuery {
+	if q.CanEdit(user) || len(q.Shares) == 0 {
+		return q
+	}
+	copied := *q
+	copied.Shares = nil
+	return &copied
+}
+
+// GetFor returns a query the user in ctx may view
+func (qs *QueryStore) GetFor(ctx context.Context, id string) (*SavedQuery, error) {
+	query, err := qs.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	user := auth.UserFromContext(ctx)
+	if !query.CanView(user) {
+		// Do not reveal that the query exists
+		return nil, fmt.Errorf("query not found: %s", id)
+	}
+	return query.ViewFor(user), nil
+}
+
+// ListFor returns the queries the user in ctx may view
+func (qs *QueryStore) ListFor(ctx context.Context, filters ...QueryFilter) ([]*SavedQuery, error) {
+	queries, err := qs.List(filters...)
+	if err != nil {
+		return nil, err
+	}
+	user := auth.UserFromContext(ctx)
+	visible := make([]*SavedQuery, 0, len(queries))
+	for _, q := range queries {
+		if q.CanView(user) {
+			visible = append(visible, q.ViewFor(user))
+		}
+	}
+	return visible, nil
+}
+
+// getForEdit returns a query the user in ctx may change
+func (qs *QueryStore) getForEdit(ctx context.Context, id string) (*SavedQuery, error) {
+	query, err := qs.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	user := auth.UserFromContext(ctx)
+	if !query.CanView(user) {
+		return nil, fmt.Errorf("query not found: %s", id)
+	}
+	if !query.CanEdit(user) {
+		return nil, ErrQueryAccessDenied
+	}
+	return query, nil
+}
+
+// UpdateFor applies updates on behalf of the user in ctx
+func (qs *QueryStore) UpdateFor(ctx context.Context, id string, updates map[string]interface{}) error {
+	if _, err := qs.getForEdit(ctx, id); err != nil {
+		return err
+	}
+	return qs.Update(id, updates)
+}
+
+// DeleteFor deletes a query on behalf of the user in ctx
+func (qs *QueryStore) DeleteFor(ctx context.Context, id string) error {
+	if _, err := qs.getForEdit(ctx, id); err != nil {
+		return err
+	}
+	return qs.Delete(id)
+}
+
+// Share creates a share link for a query the user in ctx owns
+func (qs *QueryStore) Share(ctx context.Context, id string, expiresAt *time.Time) (*QueryShare, error) {
+	query, err := qs.getForEdit(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	share := QueryShare{
+		Token:     uuid.New().String(),
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+		CreatedBy: auth.UserFromContext(ctx).ID,
+	}
+
+	// Save a copy so a failed write leaves the cached query untouched
+	updated := *query
+	updated.Shares = append(append([]QueryShare{}, query.Shares...), share)
+	if err := qs.Save(&updated); err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// GetByShareToken returns the query a share link points to
+func (qs *QueryStore) GetByShareToken(token string) (*SavedQuery, error) {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+	for _, query := range qs.queries {
+		for _, share := range query.Shares {
+			if share.Token != token {
+				continue
+			}
+			if share.Expired() {
+				return nil, fmt.Errorf("share link has expired")
+			}
+			copied := *query
+			copied.Shares = nil
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid share token")
+}
@@ -0,0 +1,139 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AdmissionError is returned when a user has too many queries running or queued
+type AdmissionError struct {
+	UserID     string
+	Running    int
+	Queued     int
+	Limit      int
+	RetryAfter time.Duration
+	Reason     string
+}
+
+func (e *AdmissionError) Error() string {
+	return fmt.Sprintf("query rejected for user %s: %s (%d running, %d queued, limit %d)",
+		e.UserID, e.Reason, e.Running, e.Queued, e.Limit)
+}
+
+// AdmissionStats describes one user's current load
+type AdmissionStats struct {
+	Running int `json:"running"`
+	Queued  int `json:"queued"`
+}
+
+// userSlots holds the concurrency slots of one user
+type userSlots struct {
+	sem     chan struct{}
+	waiting int
+}
+
+// AdmissionController limits how many queries each user runs at once.
+// Queries beyond the limit wait in a bounded per-user queue for a slot, so
+// one user's burst cannot take every ClickHouse connection.
+type AdmissionController struct {
+	mu            sync.Mutex
+	users         map[string]*userSlots
+	maxConcurrent int
+	maxQueued     int
+	queueTimeout  time.Duration
+}
+
+// NewAdmissionController creates an admission controller
+func NewAdmissionController(maxConcurrent, maxQueued int, queueTimeout time.Duration) *AdmissionController {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	if maxQueued < 0 {
+		maxQueued = 0
+	}
+	return &AdmissionController{
+		users:         make(map[string]*userSlots),
+		maxConcurrent: maxConcurrent,
+		maxQueued:     maxQueued,
+		queueTimeout:  queueTimeout,
+	}
+}
+
+// Acquire waits for a query slot for the user. The returned function must
+// be called when the query finishes.
+func (ac *AdmissionController) Acquire(ctx context.Context, userID string) (func(), error) {
+	ac.mu.Lock()
+	slots, ok := ac.users[userID]
+	if !ok {
+		slots = &userSlots{sem: make(chan struct{}, ac.maxConcurrent)}
+		ac.users[userID] = slots
+	}
+
+	release := func() { <-slots.sem }
+
+	select {
+	case slots.sem <- struct{}{}:
+		ac.mu.Unlock()
+		return release, nil
+	default:
+	}
+
+	if slots.waiting >= ac.maxQueued {
+		err := ac.rejection(userID, slots, "too many concurrent queries")
+		ac.mu.Unlock()
+		return nil, err
+	}
+	slots.waiting++
+	ac.mu.Unlock()
+
+	timer := time.NewTimer(ac.queueTimeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case slots.sem <- struct{}{}:
+	case <-timer.C:
+		err = fmt.Errorf("timed out waiting for a query slot")
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	slots.waiting--
+	if err != nil {
+		if err != ctx.Err() {
+			return nil, ac.rejection(userID, slots, err.Error())
+		}
+		return nil, err
+	}
+	return release, nil
+}
+
+// rejection builds an AdmissionError. Callers hold the lock.
+func (ac *AdmissionController) rejection(userID string, slots *userSlots, reason string) *AdmissionError {
+	return &AdmissionError{
+		UserID:     userID,
+		Running:    len(slots.sem),
+		Queued:     slots.waiting,
+		Limit:      ac.maxConcurrent,
+		RetryAfter: time.Second,
+		Reason:     reason,
+	}
+}
+
+// Stats returns running and queued queries per user
+func (ac *AdmissionController) Stats() map[string]AdmissionStats {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	stats := make(map[string]AdmissionStats)
+	for userID, slots := range ac.users {
+		if len(slots.sem) == 0 && slots.waiting == 0 {
+			continue
+		}
+		stats[userID] = AdmissionStats{Running: len(slots.sem), Queued: slots.waiting}
+	}
+	return stats
+}
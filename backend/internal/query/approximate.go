@@ -0,0 +1,260 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/tables"
+)
+
+// DefaultSampleRatio is the share of rows approximate queries read from
+// tables with a sampling key
+const DefaultSampleRatio = 0.1
+
+// Exact aggregates and their approximate counterparts
+var approximateFunctions = map[string]string{
+	"UNIQEXACT":      "uniq",
+	"QUANTILEEXACT":  "quantile",
+	"QUANTILESEXACT": "quantiles",
+	"MEDIANEXACT":    "median",
+}
+
+// Aggregates that give the same answer, give or take, on a sample. count()
+// is scaled by the sample factor; sums and distinct counts are not safe.
+var sampleSafeAggregates = map[string]bool{
+	"COUNT": true, "AVG": true, "MIN": true, "MAX": true, "ANY": true, "ANYLAST": true,
+	"ARGMIN": true, "ARGMAX": true, "QUANTILE": true, "QUANTILES": true, "MEDIAN": true,
+}
+
+var aggregateFunctions = map[string]bool{
+	"COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true, "ANY": true,
+	"ANYLAST": true, "ARGMIN": true, "ARGMAX": true, "UNIQ": true, "UNIQEXACT": true,
+	"UNIQCOMBINED": true, "UNIQHLL12": true, "QUANTILE": true, "QUANTILES": true,
+	"QUANTILEEXACT": true, "QUANTILESEXACT": true, "MEDIAN": true, "MEDIANEXACT": true,
+	"GROUPARRAY": true, "GROUPUNIQARRAY": true, "TOPK": true, "COUNTIF": true,
+	"SUMIF": true, "AVGIF": true, "MINIF": true, "MAXIF": true, "UNIQIF": true,
+}
+
+// Functions rounding time to the hour or coarser, which the hourly rollup
+// can answer
+var hourlyGrainFunctions = map[string]bool{
+	"TOSTARTOFHOUR": true, "TOSTARTOFDAY": true, "TODATE": true, "TOSTARTOFWEEK": true,
+	"TOMONDAY": true, "TOSTARTOFMONTH": true, "TOSTARTOFQUARTER": true, "TOSTARTOFYEAR": true,
+}
+
+// Columns of logs the hourly rollup keeps
+var hourlyRollupColumns = map[string]bool{"TIMESTAMP": true, "SERVICE": true, "LEVEL": true}
+
+// Words that end a table reference rather than alias it
+var tableClauseKeywords = map[string]bool{
+	"WHERE": true, "PREWHERE": true, "GROUP": true, "ORDER": true, "LIMIT": true,
+	"HAVING": true, "JOIN": true, "LEFT": true, "RIGHT": true, "INNER": true, "FULL": true,
+	"CROSS": true, "ARRAY": true, "FINAL": true, "SAMPLE": true, "SETTINGS": true,
+	"FORMAT": true, "UNION": true, "WITH": true,
+}
+
+// sqlEdit replaces query[start:end] with text
+type sqlEdit struct {
+	start, end int
+	text       string
+}
+
+// approximate rewrites a query to answer faster at the cost of exactness:
+// exact aggregates become their approximate versions, hourly counts over
+// logs read the hourly rollup, and otherwise aggregations over tables with
+// a sampling key read a sample. It returns the query and what was changed;
+// nothing changed means the query could not be approximated.
+func (e *Engine) approximate(query string, sampleRatio float64) (string, []string) {
+	tokens := tokenizeSQL(query)
+	edits := []sqlEdit{}
+	notes := []string{}
+
+	for i, tok := range tokens {
+		if i+1 >= len(tokens) || tokens[i+1].text != "(" {
+			continue
+		}
+		if approx, ok := approximateFunctions[tok.text]; ok {
+			edits = append(edits, sqlEdit{tok.start, tok.end, approx})
+			notes = append(notes, fmt.Sprintf("%s replaced by %s", query[tok.start:tok.end], approx))
+		}
+		if tok.text == "COUNT" && i+2 < len(tokens) && tokens[i+2].text == "DISTINCT" {
+			edits = append(edits, sqlEdit{tok.start, tokens[i+2].end, "uniq("})
+			notes = append(notes, "COUNT(DISTINCT) replaced by uniq")
+		}
+	}
+
+	from, ok := singleTableRead(tokens)
+	if ok && tokens[from+1].text == strings.ToUpper(tables.DefaultTable) && e.tables.Has(hourlyView) {
+		if rollup, ok := hourlyRollupEdits(tokens); ok {
+			edits = append(edits, rollup...)
+			notes = append(notes, "counted from the hourly rollup "+hourlyView+", which only holds logs since it was created")
+			return applyEdits(query, edits), notes
+		}
+	}
+	if ok && sampleRatio > 0 && sampleRatio < 1 {
+		if table, exists := e.tables.Get(strings.ToLower(tokens[from+1].text)); exists && table.SamplingKey != "" {
+			if sample, ok := sampleEdits(tokens, from, sampleRatio); ok {
+				edits = append(edits, sample...)
+				notes = append(notes, fmt.Sprintf("read a %s%% sample of %s; counts are scaled up", strconv.FormatFloat(sampleRatio*100, 'f', -1, 64), table.Name))
+			}
+		}
+	}
+
+	return applyEdits(query, edits), notes
+}
+
+// singleTableRead returns the index of the FROM of a query reading one
+// table, without joins or subqueries
+func singleTableRead(tokens []sqlToken) (int, bool) {
+	from := -1
+	for i, tok := range tokens {
+		switch tok.text {
+		case "FROM":
+			if from >= 0 || tok.depth != 0 {
+				return 0, false
+			}
+			from = i
+		case "JOIN":
+			return 0, false
+		case "SELECT":
+			if i > 0 {
+				return 0, false
+			}
+		}
+	}
+	if from < 0 || from+1 >= len(tokens) {
+		return 0, false
+	}
+	next := tokens[from+1]
+	if next.text == "(" || next.text == "'" || strings.HasPrefix(next.text, `"`) {
+		return 0, false
+	}
+	if from+2 < len(tokens) && (tokens[from+2].text == "(" || tokens[from+2].text == ".") {
+		return 0, false
+	}
+	return from, true
+}
+
+// hourlyRollupEdits rewrites a count over logs to the hourly rollup. Only
+// service, level and timestamp may be used, timestamp only rounded to the
+// hour or coarser or in comparisons, and count() as the only aggregate.
+func hourlyRollupEdits(tokens []sqlToken) ([]sqlEdit, bool) {
+	edits := []sqlEdit{}
+	counts := 0
+	for i, tok := range tokens {
+		isCall := i+1 < len(tokens) && tokens[i+1].text == "("
+		switch {
+		case tok.text == "AS" && i+1 < len(tokens) && (tokens[i+1].text == "COUNT" || tokens[i+1].text == "HOUR"):
+			return nil, false // e.g. sum(count) AS count would be a cyclic alias
+		case tok.text == "COUNT" && isCall:
+			end, ok := emptyCountArgs(tokens, i+1)
+			if !ok {
+				return nil, false
+			}
+			edits = append(edits, sqlEdit{tok.start, tokens[end].end, "sum(count)"})
+			counts++
+		case isCall && aggregateFunctions[tok.text]:
+			return nil, false
+		case tok.text == strings.ToUpper(tables.DefaultTable):
+			edits = append(edits, sqlEdit{tok.start, tok.end, hourlyView})
+		case tok.text == "TIMESTAMP":
+			if !hourlyTimestampUse(tokens, i) {
+				return nil, false
+			}
+			edits = append(edits, sqlEdit{tok.start, tok.end, "hour"})
+		case logsColumns[tok.text] && !hourlyRollupColumns[tok.text]:
+			return nil, false
+		}
+	}
+	return edits, counts > 0
+}
+
+// hourlyTimestampUse reports whether the timestamp at i is rounded to the
+// hour or coarser, or compared, where hour granularity is close enough
+func hourlyTimestampUse(tokens []sqlToken, i int) bool {
+	if i >= 2 && tokens[i-1].text == "(" && hourlyGrainFunctions[tokens[i-2].text] {
+		return true
+	}
+	comparison := func(text string) bool {
+		return text == "<" || text == ">" || text == "=" || text == "!" || text == "BETWEEN"
+	}
+	return (i+1 < len(tokens) && comparison(tokens[i+1].text)) || (i > 0 && comparison(tokens[i-1].text))
+}
+
+// Columns of the logs table, as upper-cased tokens
+var logsColumns = map[string]bool{
+	"ID": true, "TIMESTAMP": true, "LEVEL": true, "MESSAGE": true, "SERVICE": true,
+	"TRACE_ID": true, "SPAN_ID": true, "ATTRIBUTES": true,
+}
+
+// sampleEdits adds a SAMPLE clause after the table read at from and scales
+// count() by the sample factor. Aggregations whose result depends on the
+// number of rows read, other than count(), are left exact.
+func sampleEdits(tokens []sqlToken, from int, ratio float64) ([]sqlEdit, bool) {
+	edits := []sqlEdit{}
+	aggregates := 0
+	for i, tok := range tokens {
+		if tok.text == "SAMPLE" || tok.text == "FINAL" {
+			return nil, false
+		}
+		if i+1 >= len(tokens) || tokens[i+1].text != "(" || !aggregateFunctions[tok.text] {
+			continue
+		}
+		if !sampleSafeAggregates[tok.text] {
+			return nil, false
+		}
+		aggregates++
+		if tok.text == "COUNT" {
+			end, ok := emptyCountArgs(tokens, i+1)
+			if !ok {
+				return nil, false
+			}
+			edits = append(edits, sqlEdit{tok.start, tokens[end].end, "toUInt64(sum(_sample_factor))"})
+		}
+	}
+	if aggregates == 0 {
+		return nil, false // sampling a plain row listing gains nothing
+	}
+
+	// The clause goes after the table's alias, if any
+	at := from + 1
+	if at+1 < len(tokens) {
+		next := tokens[at+1]
+		switch {
+		case next.text == "AS" && at+2 < len(tokens):
+			at += 2
+		case isWord(next.text) && !tableClauseKeywords[next.text]:
+			at++
+		}
+	}
+	pos := tokens[at].end
+	edits = append(edits, sqlEdit{pos, pos, " SAMPLE " + strconv.FormatFloat(ratio, 'f', -1, 64)})
+	return edits, true
+}
+
+// emptyCountArgs checks that the parenthesis at open starts count() or
+// count(*) and returns the index of the closing parenthesis
+func emptyCountArgs(tokens []sqlToken, open int) (int, bool) {
+	if open+1 < len(tokens) && tokens[open+1].text == ")" {
+		return open + 1, true
+	}
+	if open+2 < len(tokens) && tokens[open+1].text == "*" && tokens[open+2].text == ")" {
+		return open + 2, true
+	}
+	return 0, false
+}
+
+func isWord(text string) bool {
+	return text != "" && isIdentChar(text[0]) && !isDigit(text[0])
+}
+
+// applyEdits applies non-overlapping edits to query
+func applyEdits(query string, edits []sqlEdit) string {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	for _, edit := range edits {
+		query = query[:edit.start] + edit.text + query[edit.end:]
+	}
+	return query
+}
@@ -2,15 +2,20 @@ package query
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"regexp"
+	"io"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/cache"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/monitoring"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/optimization"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/pagination"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/tables"
 )
 
 // Engine manages SQL query execution and optimization
@@ -19,8 +24,15 @@ type Engine struct {
 	validator  *Validator
 	optimizer  *optimization.QueryOptimizer
 	queryStore *QueryStore
-	cache      *cache.QueryCache
+	cache      *ResultCache
 	paginator  *pagination.Paginator
+	jobs       *JobManager
+	admission  *AdmissionController
+	slowLog    *SlowQueryLog
+	stats      *QueryStatsCollector
+	views      *viewCoverage
+	tables     *tables.Registry
+	metrics    *monitoring.MetricsCollector
 }
 
 // QueryExecutor interface for database operations
@@ -28,14 +40,30 @@ type QueryExecutor interface {
 	ExecuteQuery(ctx context.Context, query string) ([]map[string]interface{}, error)
 }
 
+// StreamingExecutor is implemented by executors that can hand rows over
+// as they arrive instead of buffering the whole result
+type StreamingExecutor interface {
+	StreamQuery(ctx context.Context, query string, fn func(row map[string]interface{}) error) error
+}
+
+// RawStreamingExecutor is implemented by executors that can have the
+// database encode results in a given format and copy them through as-is
+type RawStreamingExecutor interface {
+	StreamRaw(ctx context.Context, query, format string, w io.Writer) error
+}
+
 // QueryRequest represents a SQL query request
 type QueryRequest struct {
 	Query      string                 `json:"query"`
 	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	ParameterDefs []QueryParameter    `json:"parameter_defs,omitempty"` // declared types for Parameters
 	Timeout    int                    `json:"timeout,omitempty"` // seconds
 	MaxRows    int                    `json:"max_rows,omitempty"`
 	Format     string                 `json:"format,omitempty"` // json, csv, tsv
 	UseCache   bool                   `json:"use_cache,omitempty"`
+	MaxAge     int                    `json:"max_age,omitempty"` // seconds a cached result over recent data may be old; the cache default when 0
+	Approximate bool                  `json:"approximate,omitempty"`  // trade exactness for speed, see approximate
+	SampleRatio float64               `json:"sample_ratio,omitempty"` // share of rows sampled in approximate mode
 	
 	// Pagination parameters
 	PageSize  int    `json:"page_size,omitempty"`
@@ -54,7 +82,10 @@ type QueryResponse struct {
 	Error         string                   `json:"error,omitempty"`
 	
 	// Performance optimization info
+	Fingerprint   string                     `json:"fingerprint,omitempty"`
 	CacheHit      bool                       `json:"cache_hit,omitempty"`
+	Approximate   bool                       `json:"approximate,omitempty"`    // the result is an estimate
+	Approximations []string                  `json:"approximations,omitempty"` // how the query was approximated
 	Optimizations []string                   `json:"optimizations,omitempty"`
 	QueryPlan     *optimization.QueryPlan    `json:"query_plan,omitempty"`
 	
@@ -73,20 +104,47 @@ type ColumnInfo struct {
 func NewEngine(db QueryExecutor) *Engine {
 	// Initialize caching system
 	memCache := cache.NewMemoryCache(1000) // 1000 items max
-	queryCache := cache.NewQueryCache(memCache, 10*time.Minute) // 10 min TTL
+	queryCache := NewResultCache(memCache, DefaultResultCacheOptions())
 	
-	return &Engine{
+	engine := &Engine{
 		db:         db,
 		validator:  NewValidator(),
 		optimizer:  optimization.NewQueryOptimizer(),
 		queryStore: NewQueryStore(),
 		cache:      queryCache,
 		paginator:  pagination.NewPaginator(100, 1000), // default 100, max 1000
+		admission:  NewAdmissionController(4, 8, 10*time.Second),
 	}
+	engine.jobs = NewJobManager(engine, time.Hour, 30*time.Minute)
+	engine.optimizer.SetExplainer(db)
+	engine.slowLog = NewSlowQueryLog(engine.optimizer, DefaultSlowQueryThreshold, 500)
+	// Slow queries of all users are stored there; they are read through the API
+	engine.validator.RestrictTable(SlowQueryTable)
+	engine.stats = NewQueryStatsCollector(1000, 256)
+	engine.views = &viewCoverage{}
+	engine.tables = tables.NewRegistry()
+	engine.validator.SetTables(engine.tables)
+	
+	return engine
 }
 
 // Execute executes a SQL query with validation and optimization
 func (e *Engine) Execute(ctx context.Context, req *QueryRequest) (*QueryResponse, error) {
+	start := time.Now()
+	ctx, span := startQuerySpan(ctx, "execute", req)
+	response, err := e.execute(ctx, req)
+	rows, cacheHit := 0, false
+	if response != nil {
+		rows, cacheHit = response.RowCount, response.CacheHit
+		if response.Fingerprint != "" {
+			span.SetAttributes(attribute.String("clicklite.query.fingerprint", response.Fingerprint))
+		}
+	}
+	e.endQuery(ctx, span, "execute", start, rows, cacheHit, err)
+	return response, err
+}
+
+func (e *Engine) execute(ctx context.Context, req *QueryRequest) (*QueryResponse, error) {
 	start := time.Now()
 	response := &QueryResponse{
 		Query: req.Query,
@@ -101,28 +159,27 @@ func (e *Engine) Execute(ctx context.Context, req *QueryRequest) (*QueryResponse
 	ctx, cancel := context.WithTimeout(ctx, time.Duration(req.Timeout)*time.Second)
 	defer cancel()
 
-	// Check cache first if enabled
-	if req.UseCache {
-		if cached, found := e.cache.GetQueryResult(req.Query, req.Parameters); found {
-			if cachedResponse, ok := cached.(*QueryResponse); ok {
-				cachedResponse.CacheHit = true
-				cachedResponse.ExecutionTime = time.Since(start).Milliseconds()
-				return cachedResponse, nil
-			}
-		}
+	// Bind typed parameters
+	query, err := BindParameters(req.Query, req.ParameterDefs, req.Parameters)
+	if err != nil {
+		response.Error = fmt.Sprintf("parameter error: %v", err)
+		return response, err
 	}
 
-	// Validate query
-	if err := e.validator.Validate(req.Query); err != nil {
+	// Validate the query that runs; only admins may read system tables
+	if err := e.validator.ValidateFor(query, auth.UserFromContext(ctx).IsAdmin()); err != nil {
 		response.Error = fmt.Sprintf("validation error: %v", err)
 		return response, err
 	}
 
-	// Parameter substitution
-	query, err := e.substituteParameters(req.Query, req.Parameters)
-	if err != nil {
-		response.Error = fmt.Sprintf("parameter error: %v", err)
-		return response, err
+	// Approximate when asked to, before planning the rewritten query
+	if req.Approximate {
+		ratio := req.SampleRatio
+		if ratio == 0 {
+			ratio = DefaultSampleRatio
+		}
+		query, response.Approximations = e.approximate(query, ratio)
+		response.Approximate = len(response.Approximations) > 0
 	}
 
 	// Optimize query
@@ -156,8 +213,38 @@ func (e *Engine) Execute(ctx context.Context, req *QueryRequest) (*QueryResponse
 		query = fmt.Sprintf("%s LIMIT %d", query, req.MaxRows)
 	}
 
+	// Cap the number of rows any query can return
+	query, err = e.validator.EnforceLimit(query)
+	if err != nil {
+		response.Error = fmt.Sprintf("validation error: %v", err)
+		return response, err
+	}
+
+	// Check the cache once the final SQL is known, so the key covers bound
+	// parameters, pagination and the row cap
+	if req.UseCache {
+		if cached, found := e.cache.Get(query, start, time.Duration(req.MaxAge)*time.Second); found {
+			cached.CacheHit = true
+			cached.ExecutionTime = time.Since(start).Milliseconds()
+			return cached, nil
+		}
+	}
+
+	// Wait for one of the user's query slots
+	release, err := e.admit(ctx)
+	if err != nil {
+		response.Error = err.Error()
+		return response, err
+	}
+	defer release()
+
 	// Execute query
-	rows, err := e.db.ExecuteQuery(ctx, query)
+	execCtx, summary := WithExecutionSummary(ctx)
+	execStart := time.Now()
+	rows, err := e.db.ExecuteQuery(execCtx, query)
+	duration := time.Since(execStart)
+	response.Fingerprint = e.stats.Record(req.Query, duration, summary.ReadRows, err)
+	e.slowLog.Capture(ctx, req.Query, queryPlan, query, duration, len(rows), err)
 	if err != nil {
 		response.Error = fmt.Sprintf("execution error: %v", err)
 		return response, err
@@ -205,70 +292,13 @@ func (e *Engine) Execute(ctx context.Context, req *QueryRequest) (*QueryResponse
 	
 	// Cache the response if caching is enabled
 	if req.UseCache && response.Error == "" {
-		e.cache.SetQueryResult(req.Query, req.Parameters, response)
+		e.cache.Set(query, start, time.Duration(req.MaxAge)*time.Second, response)
 	}
 
 	return response, nil
 }
 
 
-// substituteParameters replaces named parameters in the query
-func (e *Engine) substituteParameters(query string, params map[string]interface{}) (string, error) {
-	if params == nil || len(params) == 0 {
-		return query, nil
-	}
-
-	// Find all parameter placeholders like :param_name or ${param_name}
-	re := regexp.MustCompile(`:(\w+)|\$\{(\w+)\}`)
-	
-	result := re.ReplaceAllStringFunc(query, func(match string) string {
-		// Extract parameter name
-		paramName := ""
-		if strings.HasPrefix(match, ":") {
-			paramName = match[1:]
-		} else {
-			paramName = match[2 : len(match)-1]
-		}
-		
-		// Get parameter value
-		if value, exists := params[paramName]; exists {
-			return e.formatParameterValue(value)
-		}
-		
-		// Return original if parameter not found
-		return match
-	})
-	
-	return result, nil
-}
-
-// formatParameterValue formats a parameter value for SQL
-func (e *Engine) formatParameterValue(value interface{}) string {
-	switch v := value.(type) {
-	case string:
-		// Escape single quotes and wrap in quotes
-		escaped := strings.ReplaceAll(v, "'", "''")
-		return fmt.Sprintf("'%s'", escaped)
-	case int, int32, int64, float32, float64:
-		return fmt.Sprintf("%v", v)
-	case bool:
-		if v {
-			return "1"
-		}
-		return "0"
-	case time.Time:
-		return fmt.Sprintf("'%s'", v.Format("2006-01-02 15:04:05"))
-	case nil:
-		return "NULL"
-	default:
-		// Try JSON encoding for complex types
-		if data, err := json.Marshal(v); err == nil {
-			return fmt.Sprintf("'%s'", strings.ReplaceAll(string(data), "'", "''"))
-		}
-		return fmt.Sprintf("%v", v)
-	}
-}
-
 // convertValue converts database values to appropriate Go types
 func convertValue(v interface{}) interface{} {
 	switch val := v.(type) {
@@ -283,6 +313,287 @@ func convertValue(v interface{}) interface{} {
 	}
 }
 
+// Stream validates and executes a query, calling fn for every row as it is
+// read. Caching and pagination do not apply to streamed queries.
+func (e *Engine) Stream(ctx context.Context, req *QueryRequest, fn func(row map[string]interface{}) error) (int, error) {
+	start := time.Now()
+	ctx, span := startQuerySpan(ctx, "stream", req)
+	count, err := e.stream(ctx, req, fn)
+	e.endQuery(ctx, span, "stream", start, count, false, err)
+	return count, err
+}
+
+func (e *Engine) stream(ctx context.Context, req *QueryRequest, fn func(row map[string]interface{}) error) (int, error) {
+	if req.Timeout <= 0 {
+		req.Timeout = 30
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(req.Timeout)*time.Second)
+	defer cancel()
+
+	query, plan, err := e.prepareStream(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+
+	release, err := e.admit(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	count := 0
+	emit := func(row map[string]interface{}) error {
+		count++
+		return fn(row)
+	}
+
+	execStart := time.Now()
+	streamer, ok := e.db.(StreamingExecutor)
+	if !ok {
+		execCtx, summary := WithExecutionSummary(ctx)
+		rows, err := e.db.ExecuteQuery(execCtx, query)
+		duration := time.Since(execStart)
+		e.stats.Record(req.Query, duration, summary.ReadRows, err)
+		e.slowLog.Capture(ctx, req.Query, plan, query, duration, len(rows), err)
+		if err != nil {
+			return 0, fmt.Errorf("execution error: %w", err)
+		}
+		for _, row := range rows {
+			if err := emit(row); err != nil {
+				return count, err
+			}
+		}
+		return count, nil
+	}
+
+	// Streamed results start before ClickHouse knows how much it read, so
+	// no scan size is recorded for them
+	err = streamer.StreamQuery(ctx, query, emit)
+	duration := time.Since(execStart)
+	e.stats.Record(req.Query, duration, 0, err)
+	e.slowLog.Capture(ctx, req.Query, plan, query, duration, count, err)
+	if err != nil {
+		return count, fmt.Errorf("execution error: %w", err)
+	}
+	return count, nil
+}
+
+// StreamFormat runs a query like Stream but has ClickHouse encode the
+// result in format, e.g. CSVWithNames or Parquet, writing it to w as it
+// arrives. The query must not set a FORMAT itself.
+func (e *Engine) StreamFormat(ctx context.Context, req *QueryRequest, format string, w io.Writer) error {
+	start := time.Now()
+	ctx, span := startQuerySpan(ctx, "download", req)
+	span.SetAttributes(attribute.String("clicklite.query.format", format))
+	err := e.streamFormat(ctx, req, format, w)
+	e.endQuery(ctx, span, "download", start, 0, false, err)
+	return err
+}
+
+func (e *Engine) streamFormat(ctx context.Context, req *QueryRequest, format string, w io.Writer) error {
+	streamer, ok := e.db.(RawStreamingExecutor)
+	if !ok {
+		return fmt.Errorf("downloads in %s are not supported by this database", format)
+	}
+	if req.Timeout <= 0 {
+		req.Timeout = 30
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(req.Timeout)*time.Second)
+	defer cancel()
+
+	query, plan, err := e.prepareStream(ctx, req)
+	if err != nil {
+		return err
+	}
+	if hasFormatClause(query) {
+		return fmt.Errorf("validation error: remove the FORMAT clause to download results")
+	}
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+
+	release, err := e.admit(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Row counts are unknown as the result is never decoded
+	execStart := time.Now()
+	err = streamer.StreamRaw(ctx, query, format, w)
+	duration := time.Since(execStart)
+	e.stats.Record(req.Query, duration, 0, err)
+	e.slowLog.Capture(ctx, req.Query, plan, query, duration, 0, err)
+	if err != nil {
+		return fmt.Errorf("execution error: %w", err)
+	}
+	return nil
+}
+
+// prepareStream binds, validates, optimizes and limits a query to stream
+func (e *Engine) prepareStream(ctx context.Context, req *QueryRequest) (string, *optimization.QueryPlan, error) {
+	query, err := BindParameters(req.Query, req.ParameterDefs, req.Parameters)
+	if err != nil {
+		return "", nil, fmt.Errorf("parameter error: %w", err)
+	}
+	if err := e.validator.ValidateFor(query, auth.UserFromContext(ctx).IsAdmin()); err != nil {
+		return "", nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	plan := e.optimizer.Optimize(query)
+	query = plan.OptimizedQuery
+	if req.MaxRows > 0 && !strings.Contains(strings.ToUpper(query), "LIMIT") {
+		query = fmt.Sprintf("%s LIMIT %d", query, req.MaxRows)
+	}
+	query, err = e.validator.EnforceLimit(query)
+	if err != nil {
+		return "", nil, fmt.Errorf("validation error: %w", err)
+	}
+	return query, plan, nil
+}
+
+// hasFormatClause reports whether a query sets its output format
+func hasFormatClause(query string) bool {
+	for _, tok := range tokenizeSQL(query) {
+		if tok.text == "FORMAT" && tok.depth == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// admit acquires a concurrency slot for the user in ctx. Queries issued by
+// the server itself are not limited.
+func (e *Engine) admit(ctx context.Context) (func(), error) {
+	user := auth.UserFromContext(ctx)
+	if user.ID == auth.SystemUser.ID {
+		return func() {}, nil
+	}
+	start := time.Now()
+	release, err := e.admission.Acquire(ctx, user.ID)
+	if err == nil {
+		// Tells waiting for a query slot apart from running the query
+		trace.SpanFromContext(ctx).AddEvent("admitted", trace.WithAttributes(
+			attribute.Int64("clicklite.query.queued_ms", time.Since(start).Milliseconds())))
+	}
+	return release, err
+}
+
+// SetAdmissionLimits sets how many queries each user may run and queue
+func (e *Engine) SetAdmissionLimits(maxConcurrent, maxQueued int, queueTimeout time.Duration) {
+	e.admission = NewAdmissionController(maxConcurrent, maxQueued, queueTimeout)
+}
+
+// GetAdmissionStats returns running and queued queries per user
+func (e *Engine) GetAdmissionStats() map[string]AdmissionStats {
+	return e.admission.Stats()
+}
+
+// SetMaxLimit sets the maximum LIMIT a query may use
+func (e *Engine) SetMaxLimit(limit int) {
+	e.validator.SetMaxLimit(limit)
+}
+
+// Explain binds and validates a query like Execute, then returns its
+// optimized plan with the parts, rows and marks ClickHouse expects to read,
+// without running it
+func (e *Engine) Explain(ctx context.Context, req *QueryRequest) (*optimization.QueryPlan, error) {
+	query, err := BindParameters(req.Query, req.ParameterDefs, req.Parameters)
+	if err != nil {
+		return nil, fmt.Errorf("parameter error: %w", err)
+	}
+	if err := e.validator.ValidateFor(query, auth.UserFromContext(ctx).IsAdmin()); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	plan := e.optimizer.OptimizeAndEstimate(ctx, query)
+	if plan.Scan == nil {
+		return plan, fmt.Errorf("explain error: %s", plan.EstimateError)
+	}
+	return plan, nil
+}
+
+// SetCacheOptions configures the result cache used by requests with UseCache
+func (e *Engine) SetCacheOptions(options ResultCacheOptions) {
+	e.cache.SetOptions(options)
+}
+
+// GetCacheStats returns result cache statistics
+func (e *Engine) GetCacheStats() ResultCacheStats {
+	return e.cache.Stats()
+}
+
+// SetMetrics records the duration of every query in metrics, as the
+// query_duration_ms histogram
+func (e *Engine) SetMetrics(metrics *monitoring.MetricsCollector) {
+	e.metrics = metrics
+}
+
+// SetSlowQueryThreshold sets the duration above which queries are captured
+// in the slow query log; zero or less disables capture
+func (e *Engine) SetSlowQueryThreshold(threshold time.Duration) {
+	e.slowLog.SetThreshold(threshold)
+}
+
+// GetSlowQueryLog returns the slow query log
+func (e *Engine) GetSlowQueryLog() *SlowQueryLog {
+	return e.slowLog
+}
+
+// GetQueryStats returns execution statistics per query fingerprint
+func (e *Engine) GetQueryStats() *QueryStatsCollector {
+	return e.stats
+}
+
+// SuggestIndexes suggests indexes from the fingerprinted workload seen so far
+func (e *Engine) SuggestIndexes() []optimization.IndexSuggestion {
+	return e.optimizer.SuggestIndexesForWorkload(e.stats.Workload())
+}
+
+// maxParameterOptions caps the values an options query may offer
+const maxParameterOptions = 1000
+
+// ResolveParameters returns copies of defs with the options of dynamic
+// parameters loaded by running their options query as the caller, and the
+// widget a form should render filled in
+func (e *Engine) ResolveParameters(ctx context.Context, defs []QueryParameter) ([]QueryParameter, error) {
+	resolved := make([]QueryParameter, len(defs))
+	for i, def := range defs {
+		def.Widget = DefaultWidget(def)
+		if def.OptionsQuery != "" {
+			response, err := e.Execute(ctx, &QueryRequest{Query: def.OptionsQuery, MaxRows: maxParameterOptions, UseCache: true})
+			if err != nil {
+				return nil, fmt.Errorf("failed to load options of parameter %s: %w", def.Name, err)
+			}
+			def.Options = []string{}
+			for _, row := range response.Rows {
+				value, ok := row["value"]
+				if !ok && len(row) == 1 {
+					for _, v := range row {
+						value, ok = v, true
+					}
+				}
+				if !ok {
+					return nil, fmt.Errorf("options query of parameter %s must return one column or a value column", def.Name)
+				}
+				def.Options = append(def.Options, fmt.Sprintf("%v", value))
+			}
+		}
+		resolved[i] = def
+	}
+	return resolved, nil
+}
+
+// GetTables returns the registry of tables users may query
+func (e *Engine) GetTables() *tables.Registry {
+	return e.tables
+}
+
+// GetJobManager returns the asynchronous query job manager
+func (e *Engine) GetJobManager() *JobManager {
+	return e.jobs
+}
+
 // GetQueryStore returns the query store
 func (e *Engine) GetQueryStore() *QueryStore {
 	return e.queryStore
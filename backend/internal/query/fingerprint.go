@@ -0,0 +1,64 @@
+package query
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Fingerprint reduces a query to its pattern by replacing string and number
+// literals with ? and collapsing IN lists, so queries differing only in
+// their values share a pattern. It returns a short ID for the pattern and
+// the pattern itself.
+func Fingerprint(sql string) (string, string) {
+	tokens := tokenizeSQL(sql)
+	parts := make([]string, 0, len(tokens))
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok.text == ";" && i == len(tokens)-1:
+			continue
+		case tok.text == "'" || isDigit(tok.text[0]):
+			parts = append(parts, "?")
+		case tok.text == "(" && isLiteralList(tokens[i+1:]):
+			// IN ('a', 'b', 'c') and IN ('a') are the same pattern
+			for i < len(tokens) && tokens[i].text != ")" {
+				i++
+			}
+			parts = append(parts, "(?+)")
+		case strings.HasPrefix(tok.text, `"`):
+			end := tok.end + 1
+			if end > len(sql) {
+				end = len(sql)
+			}
+			parts = append(parts, sql[tok.start:end])
+		case normalizedKeywords[tok.text]:
+			parts = append(parts, tok.text)
+		default:
+			parts = append(parts, sql[tok.start:tok.end])
+		}
+	}
+
+	pattern := strings.Join(parts, " ")
+	hash := sha256.Sum256([]byte(pattern))
+	return hex.EncodeToString(hash[:8]), pattern
+}
+
+// isLiteralList reports whether tokens start with literals separated by
+// commas up to a closing parenthesis
+func isLiteralList(tokens []sqlToken) bool {
+	literals := 0
+	for i, tok := range tokens {
+		switch {
+		case tok.text == ")":
+			return literals > 0
+		case i%2 == 0 && (tok.text == "'" || isDigit(tok.text[0])):
+			literals++
+		case i%2 == 1 && tok.text == ",":
+		default:
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package query
+
+// FunctionSignature describes a ClickHouse function for SQL autocomplete
+type FunctionSignature struct {
+	Name        string `json:"name"`
+	Signature   string `json:"signature"`
+	ReturnType  string `json:"return_type"`
+	Category    string `json:"category"` // aggregate, date, string, json, map, array, conversion, conditional, math
+	Description string `json:"description"`
+}
+
+// SQLKeywords are the keywords the SQL editor completes
+var SQLKeywords = []string{
+	"SELECT", "DISTINCT", "FROM", "WHERE", "PREWHERE", "GROUP BY", "HAVING", "ORDER BY",
+	"ASC", "DESC", "LIMIT", "OFFSET", "WITH", "AS", "AND", "OR", "NOT", "IN", "NOT IN",
+	"LIKE", "ILIKE", "NOT LIKE", "BETWEEN", "IS NULL", "IS NOT NULL", "CASE", "WHEN",
+	"THEN", "ELSE", "END", "JOIN", "LEFT JOIN", "INNER JOIN", "ARRAY JOIN", "ON", "USING",
+	"UNION ALL", "INTERVAL", "SECOND", "MINUTE", "HOUR", "DAY", "WEEK", "MONTH",
+}
+
+// Functions returns the functions offered by SQL autocomplete. Table
+// functions are left out since the validator rejects them.
+func Functions() []FunctionSignature {
+	return []FunctionSignature{
+		// Aggregates
+		{"count", "count([expr])", "UInt64", "aggregate", "Number of rows, or of non-NULL values of expr"},
+		{"countIf", "countIf(cond)", "UInt64", "aggregate", "Number of rows matching cond"},
+		{"sum", "sum(expr)", "Number", "aggregate", "Sum of values"},
+		{"sumIf", "sumIf(expr, cond)", "Number", "aggregate", "Sum of values of rows matching cond"},
+		{"avg", "avg(expr)", "Float64", "aggregate", "Arithmetic mean"},
+		{"avgIf", "avgIf(expr, cond)", "Float64", "aggregate", "Mean of values of rows matching cond"},
+		{"min", "min(expr)", "Any", "aggregate", "Smallest value"},
+		{"max", "max(expr)", "Any", "aggregate", "Largest value"},
+		{"any", "any(expr)", "Any", "aggregate", "First value encountered"},
+		{"argMax", "argMax(arg, val)", "Any", "aggregate", "Value of arg for the largest val"},
+		{"argMin", "argMin(arg, val)", "Any", "aggregate", "Value of arg for the smallest val"},
+		{"uniq", "uniq(expr)", "UInt64", "aggregate", "Approximate number of distinct values"},
+		{"uniqExact", "uniqExact(expr)", "UInt64", "aggregate", "Exact number of distinct values"},
+		{"quantile", "quantile(level)(expr)", "Float64", "aggregate", "Approximate quantile, e.g. quantile(0.95)(duration)"},
+		{"quantiles", "quantiles(level1, level2, ...)(expr)", "Array(Float64)", "aggregate", "Several approximate quantiles at once"},
+		{"median", "median(expr)", "Float64", "aggregate", "Approximate median"},
+		{"topK", "topK(N)(expr)", "Array", "aggregate", "Approximately most frequent values"},
+		{"groupArray", "groupArray([max_size])(expr)", "Array", "aggregate", "Values collected into an array"},
+		{"groupUniqArray", "groupUniqArray(expr)", "Array", "aggregate", "Distinct values collected into an array"},
+
+		// Dates and times
+		{"now", "now()", "DateTime", "date", "Current time"},
+		{"today", "today()", "Date", "date", "Current date"},
+		{"toStartOfMinute", "toStartOfMinute(time)", "DateTime", "date", "Rounds down to the minute"},
+		{"toStartOfFiveMinutes", "toStartOfFiveMinutes(time)", "DateTime", "date", "Rounds down to five minutes"},
+		{"toStartOfFifteenMinutes", "toStartOfFifteenMinutes(time)", "DateTime", "date", "Rounds down to fifteen minutes"},
+		{"toStartOfHour", "toStartOfHour(time)", "DateTime", "date", "Rounds down to the hour"},
+		{"toStartOfDay", "toStartOfDay(time)", "DateTime", "date", "Rounds down to the day"},
+		{"toStartOfWeek", "toStartOfWeek(time[, mode])", "Date", "date", "Rounds down to the week"},
+		{"toStartOfInterval", "toStartOfInterval(time, INTERVAL n unit)", "DateTime", "date", "Rounds down to a multiple of the interval"},
+		{"toDate", "toDate(expr)", "Date", "date", "Converts to a date"},
+		{"toDateTime", "toDateTime(expr[, timezone])", "DateTime", "date", "Converts to a date and time"},
+		{"toUnixTimestamp", "toUnixTimestamp(time)", "UInt32", "date", "Seconds since the epoch"},
+		{"formatDateTime", "formatDateTime(time, format)", "String", "date", "Formats a time, e.g. '%Y-%m-%d %H:%M'"},
+		{"dateDiff", "dateDiff(unit, start, end)", "Int64", "date", "Difference between two times in unit"},
+		{"parseDateTime64BestEffortOrNull", "parseDateTime64BestEffortOrNull(str)", "Nullable(DateTime64)", "date", "Parses a time string in any common format"},
+
+		// Strings
+		{"lower", "lower(str)", "String", "string", "Converts to lower case"},
+		{"upper", "upper(str)", "String", "string", "Converts to upper case"},
+		{"length", "length(str)", "UInt64", "string", "Length in bytes, or of an array"},
+		{"substring", "substring(str, offset[, length])", "String", "string", "Part of a string, offset starting at 1"},
+		{"concat", "concat(str1, str2, ...)", "String", "string", "Joins strings"},
+		{"trim", "trim(str)", "String", "string", "Removes surrounding whitespace"},
+		{"position", "position(haystack, needle)", "UInt64", "string", "Position of needle, 0 when absent"},
+		{"positionCaseInsensitive", "positionCaseInsensitive(haystack, needle)", "UInt64", "string", "Case insensitive position"},
+		{"startsWith", "startsWith(str, prefix)", "UInt8", "string", "Whether str starts with prefix"},
+		{"endsWith", "endsWith(str, suffix)", "UInt8", "string", "Whether str ends with suffix"},
+		{"hasToken", "hasToken(haystack, token)", "UInt8", "string", "Whether a whole token occurs, using the token index"},
+		{"multiSearchAny", "multiSearchAny(haystack, [needle1, ...])", "UInt8", "string", "Whether any needle occurs"},
+		{"match", "match(str, pattern)", "UInt8", "string", "Whether str matches a regular expression"},
+		{"extract", "extract(str, pattern)", "String", "string", "First match of a regular expression"},
+		{"replaceRegexpAll", "replaceRegexpAll(str, pattern, replacement)", "String", "string", "Replaces every match of a regular expression"},
+		{"splitByChar", "splitByChar(separator, str)", "Array(String)", "string", "Splits str on a character"},
+
+		// JSON in messages
+		{"JSONExtractString", "JSONExtractString(json, key)", "String", "json", "String value of a key"},
+		{"JSONExtractInt", "JSONExtractInt(json, key)", "Int64", "json", "Integer value of a key"},
+		{"JSONExtractFloat", "JSONExtractFloat(json, key)", "Float64", "json", "Number value of a key"},
+		{"JSONHas", "JSONHas(json, key)", "UInt8", "json", "Whether a key exists"},
+
+		// Maps, for attributes
+		{"mapKeys", "mapKeys(map)", "Array(String)", "map", "Keys of a map, e.g. mapKeys(attributes)"},
+		{"mapValues", "mapValues(map)", "Array(String)", "map", "Values of a map"},
+		{"mapContains", "mapContains(map, key)", "UInt8", "map", "Whether a map has a key"},
+
+		// Arrays
+		{"has", "has(array, value)", "UInt8", "array", "Whether an array contains value"},
+		{"hasAny", "hasAny(array1, array2)", "UInt8", "array", "Whether the arrays share an element"},
+		{"arrayJoin", "arrayJoin(array)", "Any", "array", "One row per element"},
+		{"arrayFilter", "arrayFilter(x -> cond, array)", "Array", "array", "Elements matching cond"},
+
+		// Type conversion
+		{"toString", "toString(expr)", "String", "conversion", "Converts to a string"},
+		{"toInt64", "toInt64(expr)", "Int64", "conversion", "Converts to an integer"},
+		{"toInt64OrZero", "toInt64OrZero(str)", "Int64", "conversion", "Parses an integer, 0 on failure"},
+		{"toFloat64", "toFloat64(expr)", "Float64", "conversion", "Converts to a float"},
+		{"toFloat64OrZero", "toFloat64OrZero(str)", "Float64", "conversion", "Parses a float, 0 on failure"},
+
+		// Conditionals
+		{"if", "if(cond, then, else)", "Any", "conditional", "then when cond holds, else otherwise"},
+		{"multiIf", "multiIf(cond1, then1, cond2, then2, ..., else)", "Any", "conditional", "First matching branch"},
+		{"coalesce", "coalesce(expr1, expr2, ...)", "Any", "conditional", "First non-NULL argument"},
+		{"ifNull", "ifNull(expr, default)", "Any", "conditional", "default when expr is NULL"},
+
+		// Math
+		{"round", "round(x[, digits])", "Number", "math", "Rounds to digits decimals"},
+		{"floor", "floor(x[, digits])", "Number", "math", "Rounds down"},
+		{"abs", "abs(x)", "Number", "math", "Absolute value"},
+	}
+}
@@ -0,0 +1,322 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+)
+
+// Job statuses
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is a query executed in the background. Results are kept until ExpiresAt.
+type Job struct {
+	ID         string                 `json:"id"`
+	Query      string                 `json:"query"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	UserID     string                 `json:"user_id"`
+	Status     string                 `json:"status"`
+	Progress   float64                `json:"progress"` // 0..1, estimated from rows read
+	RowsRead   int64                  `json:"rows_read"`
+	TotalRows  int64                  `json:"total_rows_approx"`
+	RowCount   int                    `json:"row_count"`
+	Error      string                 `json:"error,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	StartedAt  *time.Time             `json:"started_at,omitempty"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time             `json:"expires_at,omitempty"`
+
+	result *QueryResponse
+	cancel context.CancelFunc
+}
+
+// JobManager runs queries asynchronously so long aggregations are not
+// bound by the HTTP request timeout
+type JobManager struct {
+	engine     *Engine
+	jobs       map[string]*Job
+	mu         sync.RWMutex
+	timeout    time.Duration
+	resultTTL  time.Duration
+	maxPerUser int
+}
+
+type queryIDKey struct{}
+
+// WithQueryID tags a context so the database sends the query under this ID,
+// which lets running queries be inspected and killed
+func WithQueryID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, queryIDKey{}, id)
+}
+
+// QueryIDFromContext returns the query ID set by WithQueryID
+func QueryIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(queryIDKey{}).(string)
+	return id
+}
+
+// NewJobManager creates a job manager. Jobs may run for up to timeout and
+// their results are dropped resultTTL after they finish.
+func NewJobManager(engine *Engine, timeout, resultTTL time.Duration) *JobManager {
+	jm := &JobManager{
+		engine:     engine,
+		jobs:       make(map[string]*Job),
+		timeout:    timeout,
+		resultTTL:  resultTTL,
+		maxPerUser: 5,
+	}
+
+	// Start cleanup routine
+	go jm.cleanupExpiredJobs()
+
+	return jm
+}
+
+// Submit starts a query in the background on behalf of the user in ctx
+func (jm *JobManager) Submit(ctx context.Context, req *QueryRequest) (*Job, error) {
+	user := auth.UserFromContext(ctx)
+
+	// Reject obviously invalid queries up front instead of failing the job later
+	query, err := BindParameters(req.Query, req.ParameterDefs, req.Parameters)
+	if err != nil {
+		return nil, fmt.Errorf("parameter error: %w", err)
+	}
+	if err := jm.engine.validator.ValidateFor(query, user.IsAdmin()); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	jm.mu.Lock()
+	active := 0
+	for _, job := range jm.jobs {
+		if job.UserID == user.ID && (job.Status == JobPending || job.Status == JobRunning) {
+			active++
+		}
+	}
+	if active >= jm.maxPerUser {
+		jm.mu.Unlock()
+		return nil, fmt.Errorf("too many running jobs (max %d)", jm.maxPerUser)
+	}
+
+	job := &Job{
+		ID:         uuid.New().String(),
+		Query:      req.Query,
+		Parameters: req.Parameters,
+		UserID:     user.ID,
+		Status:     JobPending,
+		CreatedAt:  time.Now(),
+	}
+	jobCtx, cancel := context.WithTimeout(auth.WithUser(context.Background(), user), jm.timeout)
+	job.cancel = cancel
+	jm.jobs[job.ID] = job
+	snapshot := jm.snapshot(job)
+	jm.mu.Unlock()
+
+	jobReq := *req
+	jobReq.Timeout = int(jm.timeout.Seconds())
+	jobReq.UseCache = false
+	jobReq.PageSize = 0
+
+	go jm.run(WithQueryID(jobCtx, job.ID), job, &jobReq)
+
+	return snapshot, nil
+}
+
+func (jm *JobManager) run(ctx context.Context, job *Job, req *QueryRequest) {
+	defer job.cancel()
+
+	jm.mu.Lock()
+	if job.Status != JobPending {
+		jm.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	job.Status = JobRunning
+	job.StartedAt = &now
+	jm.mu.Unlock()
+
+	done := make(chan struct{})
+	go jm.trackProgress(job, done)
+
+	response, err := jm.engine.Execute(ctx, req)
+	close(done)
+
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	if job.Status == JobCancelled {
+		// Cancel already recorded the outcome
+		return
+	}
+	finished := time.Now()
+	expires := finished.Add(jm.resultTTL)
+	job.FinishedAt = &finished
+	job.ExpiresAt = &expires
+
+	switch {
+	case err != nil:
+		job.Status = JobFailed
+		job.Error = err.Error()
+		if ctx.Err() == context.DeadlineExceeded {
+			job.Error = fmt.Sprintf("query exceeded job timeout of %s", jm.timeout)
+		}
+	default:
+		job.Status = JobCompleted
+		job.Progress = 1
+		job.RowCount = response.RowCount
+		job.result = response
+	}
+}
+
+// trackProgress polls system.processes for rows read until done is closed
+func (jm *JobManager) trackProgress(job *Job, done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	sql := fmt.Sprintf(
+		"SELECT read_rows, total_rows_approx FROM system.processes WHERE query_id = %s",
+		chsql.Quote(job.ID))
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			rows, err := jm.engine.db.ExecuteQuery(ctx, sql)
+			cancel()
+			if err != nil || len(rows) == 0 {
+				continue
+			}
+			read, total := chsql.ToInt64(rows[0]["read_rows"]), chsql.ToInt64(rows[0]["total_rows_approx"])
+			jm.mu.Lock()
+			job.RowsRead, job.TotalRows = read, total
+			if total > 0 {
+				job.Progress = float64(read) / float64(total)
+				if job.Progress > 0.99 {
+					job.Progress = 0.99
+				}
+			}
+			jm.mu.Unlock()
+		}
+	}
+}
+
+// Get returns a job visible to the user in ctx
+func (jm *JobManager) Get(ctx context.Context, id string) (*Job, error) {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	job, err := jm.lookup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return jm.snapshot(job), nil
+}
+
+// Result returns the results of a completed job
+func (jm *JobManager) Result(ctx context.Context, id string) (*QueryResponse, error) {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	job, err := jm.lookup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != JobCompleted {
+		return nil, fmt.Errorf("job is %s", job.Status)
+	}
+	return job.result, nil
+}
+
+// List returns the jobs of the user in ctx, newest first
+func (jm *JobManager) List(ctx context.Context) []*Job {
+	user := auth.UserFromContext(ctx)
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+
+	jobs := make([]*Job, 0)
+	for _, job := range jm.jobs {
+		if job.UserID == user.ID || user.IsAdmin() {
+			jobs = append(jobs, jm.snapshot(job))
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// Cancel stops a pending or running job and kills its query in ClickHouse
+func (jm *JobManager) Cancel(ctx context.Context, id string) (*Job, error) {
+	jm.mu.Lock()
+	job, err := jm.lookup(ctx, id)
+	if err != nil {
+		jm.mu.Unlock()
+		return nil, err
+	}
+	if job.Status != JobPending && job.Status != JobRunning {
+		jm.mu.Unlock()
+		return nil, fmt.Errorf("job is already %s", job.Status)
+	}
+	wasRunning := job.Status == JobRunning
+	now := time.Now()
+	expires := now.Add(jm.resultTTL)
+	job.Status = JobCancelled
+	job.FinishedAt = &now
+	job.ExpiresAt = &expires
+	job.cancel()
+	snapshot := jm.snapshot(job)
+	jm.mu.Unlock()
+
+	// Closing the connection does not always stop the server side query
+	if wasRunning {
+		killCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := jm.engine.db.ExecuteQuery(killCtx, "KILL QUERY WHERE query_id = "+chsql.Quote(id)+" ASYNC"); err != nil {
+			log.Warn().Err(err).Str("job_id", id).Msg("Failed to kill cancelled query")
+		}
+	}
+	return snapshot, nil
+}
+
+// lookup finds a job the user may access. Callers hold the lock.
+func (jm *JobManager) lookup(ctx context.Context, id string) (*Job, error) {
+	job, ok := jm.jobs[id]
+	user := auth.UserFromContext(ctx)
+	if !ok || (job.UserID != user.ID && !user.IsAdmin()) {
+		return nil, fmt.Errorf("job not found: %s", id)
+	}
+	return job, nil
+}
+
+// snapshot copies the exported fields of a job. Callers hold the lock.
+func (jm *JobManager) snapshot(job *Job) *Job {
+	copied := *job
+	copied.result = nil
+	copied.cancel = nil
+	return &copied
+}
+
+func (jm *JobManager) cleanupExpiredJobs() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		jm.mu.Lock()
+		now := time.Now()
+		for id, job := range jm.jobs {
+			if job.ExpiresAt != nil && now.After(*job.ExpiresAt) {
+				delete(jm.jobs, id)
+			}
+		}
+		jm.mu.Unlock()
+	}
+}
@@ -0,0 +1,477 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Date layouts accepted for "date" parameters, most specific first
+var parameterDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.000",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// placeholder is a :name or ${name} reference found outside literals and comments
+type placeholder struct {
+	name       string
+	start, end int
+}
+
+// BindParameters replaces :name and ${name} placeholders with typed SQL
+// literals. Placeholders inside string literals, quoted identifiers and
+// comments are left alone, as are ClickHouse :: casts. When a definition
+// exists for a parameter its declared type decides how the value is
+// converted; otherwise the type is inferred from the JSON value. Every
+// placeholder must resolve to a value or a default.
+func BindParameters(query string, defs []QueryParameter, values map[string]interface{}) (string, error) {
+	placeholders := findPlaceholders(query)
+	if len(placeholders) == 0 {
+		return query, nil
+	}
+
+	byName := make(map[string]QueryParameter, len(defs))
+	for _, def := range defs {
+		byName[def.Name] = def
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, ph := range placeholders {
+		def, hasDef := byName[ph.name]
+		value, hasValue := values[ph.name]
+		// Query string values arrive as strings, so "" means unset for typed parameters
+		missing := !hasValue || value == nil || (hasDef && def.Type != "string" && value == "")
+		if missing {
+			switch {
+			case hasDef && def.DefaultValue != nil:
+				value = def.DefaultValue
+			case hasDef && !def.Required:
+				value = nil
+			default:
+				return "", fmt.Errorf("missing value for parameter %s", ph.name)
+			}
+		}
+
+		paramType := ""
+		if hasDef {
+			paramType = def.Type
+			if err := checkParameterValue(def, value); err != nil {
+				return "", err
+			}
+		}
+
+		literal, err := formatParameter(paramType, value)
+		if err != nil {
+			return "", fmt.Errorf("parameter %s: %w", ph.name, err)
+		}
+
+		b.WriteString(query[last:ph.start])
+		b.WriteString(literal)
+		last = ph.end
+	}
+	b.WriteString(query[last:])
+	return b.String(), nil
+}
+
+// findPlaceholders scans the query once, skipping literals and comments
+func findPlaceholders(query string) []placeholder {
+	var found []placeholder
+	n := len(query)
+	for i := 0; i < n; i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(query, i)
+		case c == '-' && i+1 < n && query[i+1] == '-':
+			for i < n && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < n && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return found
+			}
+			i += end + 3
+		case c == ':':
+			if i+1 < n && query[i+1] == ':' {
+				i++ // ClickHouse cast, e.g. x::String
+				continue
+			}
+			if i > 0 && isIdentChar(query[i-1]) {
+				continue
+			}
+			j := i + 1
+			for j < n && isIdentChar(query[j]) {
+				j++
+			}
+			if j > i+1 && !isDigit(query[i+1]) {
+				found = append(found, placeholder{name: query[i+1 : j], start: i, end: j})
+				i = j - 1
+			}
+		case c == '$' && i+1 < n && query[i+1] == '{':
+			end := strings.IndexByte(query[i+2:], '}')
+			if end < 0 {
+				continue
+			}
+			name := query[i+2 : i+2+end]
+			if name != "" && identifierPattern.MatchString(name) && !strings.Contains(name, ".") {
+				found = append(found, placeholder{name: name, start: i, end: i + 3 + end})
+				i += 2 + end
+			}
+		}
+	}
+	return found
+}
+
+// skipQuoted returns the index of the closing quote of the literal starting at i
+func skipQuoted(query string, i int) int {
+	quote := query[i]
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			j++
+		case quote:
+			// Doubled quotes escape themselves
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j
+		}
+	}
+	return len(query)
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func checkParameterOption(def QueryParameter, value interface{}) error {
+	if len(def.Options) == 0 || value == nil {
+		return nil
+	}
+	s := fmt.Sprintf("%v", value)
+	for _, option := range def.Options {
+		if option == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("parameter %s must be one of %s", def.Name, strings.Join(def.Options, ", "))
+}
+
+// checkParameterValue enforces the options and constraints of a definition.
+// Every item of a multi-value parameter is checked on its own.
+func checkParameterValue(def QueryParameter, value interface{}) error {
+	if items, ok := value.([]interface{}); ok {
+		if def.Widget != WidgetMultiSelect {
+			return fmt.Errorf("parameter %s takes a single value", def.Name)
+		}
+		for _, item := range items {
+			if err := checkParameterValue(def, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if value == nil {
+		return nil
+	}
+	if err := checkParameterOption(def, value); err != nil {
+		return err
+	}
+
+	switch def.Type {
+	case "number":
+		n, err := formatNumber(value)
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", def.Name, err)
+		}
+		f, _ := strconv.ParseFloat(n, 64)
+		if def.Min != nil && f < *def.Min {
+			return fmt.Errorf("parameter %s must be at least %v", def.Name, *def.Min)
+		}
+		if def.Max != nil && f > *def.Max {
+			return fmt.Errorf("parameter %s must be at most %v", def.Name, *def.Max)
+		}
+	case "string":
+		s := fmt.Sprintf("%v", value)
+		if def.MaxLength > 0 && len(s) > def.MaxLength {
+			return fmt.Errorf("parameter %s must be at most %d characters", def.Name, def.MaxLength)
+		}
+		if def.Pattern != "" {
+			re, err := regexp.Compile(def.Pattern)
+			if err != nil {
+				return fmt.Errorf("parameter %s has an invalid pattern: %w", def.Name, err)
+			}
+			if !re.MatchString(s) {
+				return fmt.Errorf("parameter %s must match %s", def.Name, def.Pattern)
+			}
+		}
+	case "date":
+		t, err := toTime(value)
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", def.Name, err)
+		}
+		if def.MaxAge != "" {
+			maxAge, err := time.ParseDuration(def.MaxAge)
+			if err == nil && t.Before(time.Now().Add(-maxAge)) {
+				return fmt.Errorf("parameter %s must be within the last %s", def.Name, def.MaxAge)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateParameters checks supplied values against their definitions
+// before a query runs, so forms get an error naming the parameter at fault
+func ValidateParameters(defs []QueryParameter, values map[string]interface{}) error {
+	for _, def := range defs {
+		value, hasValue := values[def.Name]
+		if !hasValue || value == nil || (def.Type != "string" && value == "") {
+			if def.Required && def.DefaultValue == nil {
+				return fmt.Errorf("missing value for parameter %s", def.Name)
+			}
+			continue
+		}
+		if err := checkParameterValue(def, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateParameterDef checks that the UI hints of a definition fit its type
+func ValidateParameterDef(def QueryParameter) error {
+	if def.Widget != "" && !validWidgets[def.Widget] {
+		return fmt.Errorf("invalid widget for parameter %s: %s", def.Name, def.Widget)
+	}
+	if (def.Min != nil || def.Max != nil) && def.Type != "number" {
+		return fmt.Errorf("parameter %s: min and max apply to numbers", def.Name)
+	}
+	if def.Min != nil && def.Max != nil && *def.Min > *def.Max {
+		return fmt.Errorf("parameter %s: min is greater than max", def.Name)
+	}
+	if (def.Pattern != "" || def.MaxLength > 0) && def.Type != "string" {
+		return fmt.Errorf("parameter %s: pattern and max_length apply to strings", def.Name)
+	}
+	if def.Pattern != "" {
+		if _, err := regexp.Compile(def.Pattern); err != nil {
+			return fmt.Errorf("parameter %s has an invalid pattern: %w", def.Name, err)
+		}
+	}
+	if def.MaxAge != "" {
+		if def.Type != "date" {
+			return fmt.Errorf("parameter %s: max_age applies to dates", def.Name)
+		}
+		if _, err := time.ParseDuration(def.MaxAge); err != nil {
+			return fmt.Errorf("parameter %s has an invalid max_age: %w", def.Name, err)
+		}
+	}
+	if def.OptionsQuery != "" && len(def.Options) > 0 {
+		return fmt.Errorf("parameter %s: use either options or options_query", def.Name)
+	}
+	if (def.Widget == WidgetSelect || def.Widget == WidgetMultiSelect) && def.OptionsQuery == "" && len(def.Options) == 0 {
+		return fmt.Errorf("parameter %s: %s needs options or options_query", def.Name, def.Widget)
+	}
+	return nil
+}
+
+// DefaultWidget returns the widget a form renders for a parameter without one
+func DefaultWidget(def QueryParameter) string {
+	switch {
+	case def.Widget != "":
+		return def.Widget
+	case len(def.Options) > 0 || def.OptionsQuery != "":
+		return WidgetSelect
+	case def.Type == "number" && def.Min != nil && def.Max != nil:
+		return WidgetSlider
+	case def.Type == "number":
+		return WidgetNumber
+	case def.Type == "date":
+		return WidgetDate
+	case def.Type == "boolean":
+		return WidgetCheckbox
+	default:
+		return WidgetText
+	}
+}
+
+// formatParameter converts a value into a SQL literal of the given type.
+// An empty type infers the literal from the value itself.
+func formatParameter(paramType string, value interface{}) (string, error) {
+	if value == nil {
+		return "NULL", nil
+	}
+	if items, ok := value.([]interface{}); ok && paramType != "" {
+		// Multi-select values become a tuple for IN
+		literals := make([]string, 0, len(items))
+		for _, item := range items {
+			literal, err := formatParameter(paramType, item)
+			if err != nil {
+				return "", err
+			}
+			literals = append(literals, literal)
+		}
+		return "(" + strings.Join(literals, ", ") + ")", nil
+	}
+
+	switch paramType {
+	case "number":
+		return formatNumber(value)
+	case "boolean":
+		b, err := toBool(value)
+		if err != nil {
+			return "", err
+		}
+		if b {
+			return "1", nil
+		}
+		return "0", nil
+	case "date":
+		t, err := toTime(value)
+		if err != nil {
+			return "", err
+		}
+		return chsql.Quote(t.UTC().Format("2006-01-02 15:04:05.000")), nil
+	case "identifier":
+		s, ok := value.(string)
+		if !ok || !identifierPattern.MatchString(s) {
+			return "", fmt.Errorf("invalid identifier %v", value)
+		}
+		parts := strings.Split(s, ".")
+		for i, part := range parts {
+			parts[i] = "`" + part + "`"
+		}
+		return strings.Join(parts, "."), nil
+	case "string":
+		return chsql.Quote(fmt.Sprintf("%v", value)), nil
+	case "":
+		return inferLiteral(value)
+	default:
+		return "", fmt.Errorf("unsupported parameter type %s", paramType)
+	}
+}
+
+func inferLiteral(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return chsql.Quote(v), nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case int, int32, int64, float32, float64, json.Number:
+		return formatNumber(v)
+	case time.Time:
+		return chsql.Quote(v.UTC().Format("2006-01-02 15:04:05.000")), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			literal, err := inferLiteral(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, literal)
+		}
+		return "(" + strings.Join(items, ", ") + ")", nil
+	default:
+		return "", fmt.Errorf("unsupported parameter value %T", value)
+	}
+}
+
+func formatNumber(value interface{}) (string, error) {
+	var f float64
+	switch v := value.(type) {
+	case int:
+		return numberLiteral(strconv.Itoa(v)), nil
+	case int32:
+		return numberLiteral(strconv.FormatInt(int64(v), 10)), nil
+	case int64:
+		return numberLiteral(strconv.FormatInt(v, 10)), nil
+	case float32:
+		f = float64(v)
+	case float64:
+		f = v
+	case json.Number:
+		parsed, err := v.Float64()
+		if err != nil {
+			return "", fmt.Errorf("invalid number %s", v)
+		}
+		f = parsed
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid number %q", v)
+		}
+		f = parsed
+	default:
+		return "", fmt.Errorf("invalid number %v", value)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("invalid number %v", value)
+	}
+	return numberLiteral(strconv.FormatFloat(f, 'f', -1, 64)), nil
+}
+
+// numberLiteral wraps negative numbers in parentheses, so that a minus
+// sign in front of the parameter cannot turn into a -- comment
+func numberLiteral(n string) string {
+	if strings.HasPrefix(n, "-") {
+		return "(" + n + ")"
+	}
+	return n
+}
+
+func toBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case float64:
+		return v != 0, nil
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return false, fmt.Errorf("invalid boolean %q", v)
+		}
+		return b, nil
+	default:
+		return false, fmt.Errorf("invalid boolean %v", value)
+	}
+}
+
+func toTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case float64:
+		// Unix seconds
+		return time.Unix(int64(v), 0), nil
+	case string:
+		s := strings.TrimSpace(v)
+		for _, layout := range parameterDateLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid date %q", v)
+	default:
+		return time.Time{}, fmt.Errorf("invalid date %v", value)
+	}
+}
@@ -0,0 +1,47 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/tables"
+)
+
+func TestBindParametersNegativeNumbers(t *testing.T) {
+	defs := []QueryParameter{{Name: "n", Type: "number"}}
+	cases := map[string]string{
+		"SELECT 1-:n":   "SELECT 1-(-1)",
+		"SELECT 1 - :n": "SELECT 1 - (-1)",
+		"SELECT :n":     "SELECT (-1)",
+	}
+	for query, want := range cases {
+		got, err := BindParameters(query, defs, map[string]interface{}{"n": -1})
+		if err != nil {
+			t.Fatalf("BindParameters(%q) error: %v", query, err)
+		}
+		if got != want {
+			t.Errorf("BindParameters(%q) = %q, want %q", query, got, want)
+		}
+	}
+
+	got, err := BindParameters("SELECT :n", nil, map[string]interface{}{"n": -2.5})
+	if err != nil {
+		t.Fatalf("BindParameters with an inferred float error: %v", err)
+	}
+	if got != "SELECT (-2.5)" {
+		t.Errorf("BindParameters with an inferred float = %q, want %q", got, "SELECT (-2.5)")
+	}
+}
+
+func TestBoundQueryIsValidated(t *testing.T) {
+	v := NewValidator()
+	v.SetTables(tables.NewRegistry())
+
+	// Unparenthesized, -:n with n = -1 would comment out the rest of the line
+	query, err := BindParameters("SELECT count() FROM logs WHERE 1-:n > 0", []QueryParameter{{Name: "n", Type: "number"}}, map[string]interface{}{"n": -1})
+	if err != nil {
+		t.Fatalf("BindParameters error: %v", err)
+	}
+	if err := v.Validate(query); err != nil {
+		t.Errorf("Validate(%q) = %v", query, err)
+	}
+}
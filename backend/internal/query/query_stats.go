@@ -0,0 +1,227 @@
+package query
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/optimization"
+)
+
+// ExecutionSummary is what ClickHouse reports having read for a query
+type ExecutionSummary struct {
+	ReadRows  int64
+	ReadBytes int64
+}
+
+type executionSummaryKey struct{}
+
+// WithExecutionSummary asks the executor to fill in the returned summary
+// once the query has finished
+func WithExecutionSummary(ctx context.Context) (context.Context, *ExecutionSummary) {
+	summary := &ExecutionSummary{}
+	return context.WithValue(ctx, executionSummaryKey{}, summary), summary
+}
+
+// ExecutionSummaryFromContext returns the summary set by WithExecutionSummary
+func ExecutionSummaryFromContext(ctx context.Context) *ExecutionSummary {
+	summary, _ := ctx.Value(executionSummaryKey{}).(*ExecutionSummary)
+	return summary
+}
+
+// PatternStats aggregates the executions of one query fingerprint
+type PatternStats struct {
+	Fingerprint    string    `json:"fingerprint"`
+	Pattern        string    `json:"pattern"`
+	Count          int64     `json:"count"`
+	Errors         int64     `json:"errors"`
+	AvgMs          float64   `json:"avg_ms"`
+	P50Ms          int64     `json:"p50_ms"`
+	P99Ms          int64     `json:"p99_ms"`
+	MaxMs          int64     `json:"max_ms"`
+	RowsScanned    int64     `json:"rows_scanned"`
+	AvgRowsScanned float64   `json:"avg_rows_scanned"`
+	FirstSeen      time.Time `json:"first_seen"`
+	LastSeen       time.Time `json:"last_seen"`
+}
+
+// patternStats keeps the totals of a pattern and its most recent durations,
+// from which the percentiles are computed
+type patternStats struct {
+	PatternStats
+	totalMs   int64
+	durations []int64 // ring buffer of the last maxSamples durations
+	next      int
+}
+
+// QueryStatsCollector aggregates execution statistics per query fingerprint
+type QueryStatsCollector struct {
+	patterns    map[string]*patternStats
+	maxPatterns int
+	maxSamples  int
+	mu          sync.RWMutex
+}
+
+// NewQueryStatsCollector creates a collector tracking up to maxPatterns
+// fingerprints; the least recently seen pattern is dropped when full
+func NewQueryStatsCollector(maxPatterns, maxSamples int) *QueryStatsCollector {
+	return &QueryStatsCollector{
+		patterns:    make(map[string]*patternStats),
+		maxPatterns: maxPatterns,
+		maxSamples:  maxSamples,
+	}
+}
+
+// Record adds an execution of sql to its pattern and returns the fingerprint
+func (c *QueryStatsCollector) Record(sql string, duration time.Duration, rowsScanned int64, execErr error) string {
+	fingerprint, pattern := Fingerprint(sql)
+	ms := duration.Milliseconds()
+	now := time.Now().UTC()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats, exists := c.patterns[fingerprint]
+	if !exists {
+		if len(c.patterns) >= c.maxPatterns {
+			c.evictOldest()
+		}
+		stats = &patternStats{
+			PatternStats: PatternStats{
+				Fingerprint: fingerprint,
+				Pattern:     pattern,
+				FirstSeen:   now,
+			},
+			durations: make([]int64, 0, c.maxSamples),
+		}
+		c.patterns[fingerprint] = stats
+	}
+
+	stats.Count++
+	stats.LastSeen = now
+	if execErr != nil {
+		stats.Errors++
+	}
+	stats.totalMs += ms
+	if ms > stats.MaxMs {
+		stats.MaxMs = ms
+	}
+	stats.RowsScanned += rowsScanned
+
+	if len(stats.durations) < c.maxSamples {
+		stats.durations = append(stats.durations, ms)
+	} else {
+		stats.durations[stats.next] = ms
+		stats.next = (stats.next + 1) % c.maxSamples
+	}
+
+	return fingerprint
+}
+
+func (c *QueryStatsCollector) evictOldest() {
+	oldest := ""
+	for fingerprint, stats := range c.patterns {
+		if oldest == "" || stats.LastSeen.Before(c.patterns[oldest].LastSeen) {
+			oldest = fingerprint
+		}
+	}
+	delete(c.patterns, oldest)
+}
+
+// List returns pattern statistics sorted by sortBy (count, p99, avg, rows
+// or errors), highest first
+func (c *QueryStatsCollector) List(sortBy string, limit int) []PatternStats {
+	c.mu.RLock()
+	result := make([]PatternStats, 0, len(c.patterns))
+	for _, stats := range c.patterns {
+		result = append(result, stats.snapshot())
+	}
+	c.mu.RUnlock()
+
+	key := func(s PatternStats) float64 {
+		switch sortBy {
+		case "p99":
+			return float64(s.P99Ms)
+		case "avg":
+			return s.AvgMs
+		case "rows":
+			return float64(s.RowsScanned)
+		case "errors":
+			return float64(s.Errors)
+		default:
+			return float64(s.Count)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return key(result[i]) > key(result[j])
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// Get returns the statistics of one fingerprint
+func (c *QueryStatsCollector) Get(fingerprint string) (PatternStats, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	stats, exists := c.patterns[fingerprint]
+	if !exists {
+		return PatternStats{}, false
+	}
+	return stats.snapshot(), true
+}
+
+// Reset drops all collected statistics
+func (c *QueryStatsCollector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.patterns = make(map[string]*patternStats)
+}
+
+// Workload returns every pattern weighted by its executions and rows
+// scanned, for index suggestions
+func (c *QueryStatsCollector) Workload() []optimization.WorkloadPattern {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	workload := make([]optimization.WorkloadPattern, 0, len(c.patterns))
+	for _, stats := range c.patterns {
+		workload = append(workload, optimization.WorkloadPattern{
+			Query:       stats.Pattern,
+			Count:       stats.Count,
+			RowsScanned: stats.RowsScanned,
+		})
+	}
+	return workload
+}
+
+// snapshot computes the derived fields; the caller holds the lock
+func (s *patternStats) snapshot() PatternStats {
+	result := s.PatternStats
+	if s.Count > 0 {
+		result.AvgMs = float64(s.totalMs) / float64(s.Count)
+		result.AvgRowsScanned = float64(s.RowsScanned) / float64(s.Count)
+	}
+	if len(s.durations) > 0 {
+		sorted := append([]int64(nil), s.durations...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		result.P50Ms = percentile(sorted, 0.50)
+		result.P99Ms = percentile(sorted, 0.99)
+	}
+	return result
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
@@ -0,0 +1,254 @@
+package query
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/cache"
+)
+
+// ResultCacheOptions configures the query result cache
+type ResultCacheOptions struct {
+	TTL          time.Duration // lifetime of results over historical data
+	Bucket       time.Duration // granularity time literals and now() are rounded to in cache keys
+	RecentWindow time.Duration // results covering data newer than this are "recent"
+	RecentTTL    time.Duration // lifetime of recent results
+}
+
+// DefaultResultCacheOptions returns the options used by NewEngine
+func DefaultResultCacheOptions() ResultCacheOptions {
+	return ResultCacheOptions{
+		TTL:          10 * time.Minute,
+		Bucket:       time.Minute,
+		RecentWindow: 5 * time.Minute,
+		RecentTTL:    15 * time.Second,
+	}
+}
+
+// ResultCacheStats reports cache effectiveness
+type ResultCacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	Size    int     `json:"size"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// Time literals as produced by parameter binding and the query builder
+var timeLiteralPattern = regexp.MustCompile(`'(\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}(?::\d{2})?(?:\.\d+)?)'`)
+
+// Functions whose value depends on when the query runs
+var relativeTimeFunctions = map[string]bool{
+	"NOW": true, "NOW64": true, "TODAY": true, "YESTERDAY": true,
+	"CURRENT_TIMESTAMP": true, "CURRENT_DATE": true,
+}
+
+// Keywords upper-cased by NormalizeSQL. Other words keep their case because
+// ClickHouse identifiers and function names are case sensitive.
+var normalizedKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "AND": true, "OR": true, "NOT": true,
+	"GROUP": true, "BY": true, "ORDER": true, "HAVING": true, "LIMIT": true, "OFFSET": true,
+	"AS": true, "ASC": true, "DESC": true, "IN": true, "LIKE": true, "ILIKE": true, "IS": true,
+	"NULL": true, "BETWEEN": true, "CASE": true, "WHEN": true, "THEN": true, "ELSE": true,
+	"END": true, "WITH": true, "DISTINCT": true, "JOIN": true, "LEFT": true, "RIGHT": true,
+	"INNER": true, "OUTER": true, "ON": true, "USING": true, "SETTINGS": true, "FORMAT": true,
+	"INTERVAL": true, "PREWHERE": true, "FINAL": true, "SAMPLE": true,
+}
+
+// cachedResponse is a cache entry with when it was stored
+type cachedResponse struct {
+	response *QueryResponse
+	storedAt time.Time
+}
+
+// ResultCache caches query responses by normalized SQL. Keys round time
+// literals and now() to a bucket so dashboards refreshing a relative range
+// hit the same entry, and results that include recent data expire quickly
+// because new logs keep arriving for that range.
+type ResultCache struct {
+	cache   cache.Cache
+	options ResultCacheOptions
+	hits    int64
+	misses  int64
+}
+
+// NewResultCache creates a result cache on top of a cache backend
+func NewResultCache(backend cache.Cache, options ResultCacheOptions) *ResultCache {
+	return &ResultCache{
+		cache:   backend,
+		options: options,
+	}
+}
+
+// SetOptions replaces the cache options; zero values keep the current setting
+func (c *ResultCache) SetOptions(options ResultCacheOptions) {
+	if options.TTL > 0 {
+		c.options.TTL = options.TTL
+	}
+	if options.Bucket > 0 {
+		c.options.Bucket = options.Bucket
+	}
+	if options.RecentWindow > 0 {
+		c.options.RecentWindow = options.RecentWindow
+	}
+	if options.RecentTTL > 0 {
+		c.options.RecentTTL = options.RecentTTL
+	}
+}
+
+// Get returns a copy of the cached response for a bound query. maxAge is
+// how old a result covering recent data may be, the recent TTL when zero.
+func (c *ResultCache) Get(sql string, now time.Time, maxAge time.Duration) (*QueryResponse, bool) {
+	key, recent := c.key(sql, now, maxAge)
+	if cached, found := c.cache.Get(key); found {
+		entry, ok := cached.(*cachedResponse)
+		if ok && (!recent || now.Sub(entry.storedAt) <= c.recentTTL(maxAge)) {
+			atomic.AddInt64(&c.hits, 1)
+			copied := *entry.response
+			return &copied, true
+		}
+	}
+	atomic.AddInt64(&c.misses, 1)
+	return nil, false
+}
+
+// Set caches a response. Results reaching into the recent window get the
+// short recent TTL, or maxAge when the caller tolerates staler results.
+func (c *ResultCache) Set(sql string, now time.Time, maxAge time.Duration, response *QueryResponse) {
+	key, recent := c.key(sql, now, maxAge)
+	ttl := c.options.TTL
+	if recentTTL := c.recentTTL(maxAge); recent && recentTTL < ttl {
+		ttl = recentTTL
+	}
+	copied := *response
+	c.cache.Set(key, &cachedResponse{response: &copied, storedAt: now}, ttl)
+}
+
+// recentTTL returns how long results covering recent data are served
+func (c *ResultCache) recentTTL(maxAge time.Duration) time.Duration {
+	if maxAge > 0 {
+		return maxAge
+	}
+	return c.options.RecentTTL
+}
+
+// Clear drops every cached result
+func (c *ResultCache) Clear() {
+	c.cache.Clear()
+}
+
+// Stats returns hit and miss counts
+func (c *ResultCache) Stats() ResultCacheStats {
+	stats := ResultCacheStats{
+		Hits:   atomic.LoadInt64(&c.hits),
+		Misses: atomic.LoadInt64(&c.misses),
+		Size:   c.cache.Size(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// key hashes the normalized query. It also reports whether the query may
+// cover recent data, which is assumed unless it is bounded by at least two
+// time literals that all lie before the recent window: a query without a
+// closed time range reads whatever was ingested last. Only recent queries
+// have their time literals rounded down to the bucket, so historical ranges
+// that differ by seconds never share an entry. A maxAge longer than the
+// bucket widens it, so a relative range keeps its key as long as its
+// result may be served.
+func (c *ResultCache) key(sql string, now time.Time, maxAge time.Duration) (string, bool) {
+	exact := NormalizeSQL(sql)
+	bucket := c.options.Bucket
+	if maxAge > bucket {
+		bucket = maxAge
+	}
+	recentSince := now.Add(-c.options.RecentWindow)
+	recent := false
+	literals := 0
+
+	normalized := timeLiteralPattern.ReplaceAllStringFunc(exact, func(literal string) string {
+		t, ok := parseTimeLiteral(strings.Trim(literal, "'"))
+		if !ok {
+			return literal
+		}
+		literals++
+		if t.After(recentSince) {
+			recent = true
+		}
+		return "'" + t.Truncate(bucket).Format("2006-01-02 15:04:05") + "'"
+	})
+
+	if literals < 2 {
+		recent = true
+	}
+	if !recent {
+		normalized = exact
+	}
+	if usesRelativeTime(sql) {
+		// The result depends on the current time: scope it to the bucket
+		recent = true
+		normalized += fmt.Sprintf(" /* now=%d */", now.Truncate(bucket).Unix())
+	}
+
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:]), recent
+}
+
+// NormalizeSQL rewrites a query so formatting differences do not change
+// the cache key: comments are dropped, whitespace is collapsed, keywords are
+// upper-cased and a trailing semicolon is removed. Literals are kept as-is.
+func NormalizeSQL(sql string) string {
+	tokens := tokenizeSQL(sql)
+	var b strings.Builder
+	for i, tok := range tokens {
+		if tok.text == ";" && i == len(tokens)-1 {
+			break
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		end := tok.end
+		if tok.text == "'" || strings.HasPrefix(tok.text, `"`) {
+			end++ // include the closing quote
+		}
+		if end > len(sql) {
+			end = len(sql)
+		}
+		text := sql[tok.start:end]
+		if normalizedKeywords[tok.text] {
+			text = tok.text
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+func usesRelativeTime(sql string) bool {
+	tokens := tokenizeSQL(sql)
+	for i, tok := range tokens {
+		if !relativeTimeFunctions[tok.text] {
+			continue
+		}
+		if tok.text == "CURRENT_TIMESTAMP" || tok.text == "CURRENT_DATE" ||
+			(i+1 < len(tokens) && tokens[i+1].text == "(") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTimeLiteral(s string) (time.Time, bool) {
+	s = strings.Replace(s, "T", " ", 1)
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999", "2006-01-02 15:04:05", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
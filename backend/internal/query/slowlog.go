@@ -0,0 +1,307 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/optimization"
+)
+
+// SlowQueryTable holds captured slow queries when ClickHouse storage is enabled
+const SlowQueryTable = "slow_queries"
+
+// DefaultSlowQueryThreshold is the duration above which queries are captured
+const DefaultSlowQueryThreshold = time.Second
+
+// SlowQuery is a query that ran for at least the slow query threshold
+type SlowQuery struct {
+	ID          string                  `json:"id"`
+	Query       string                  `json:"query"`        // as submitted
+	ExecutedSQL string                  `json:"executed_sql"` // after binding, optimization and limits
+	UserID      string                  `json:"user_id"`
+	DurationMs  int64                   `json:"duration_ms"`
+	RowCount    int                     `json:"row_count"`
+	Error       string                  `json:"error,omitempty"`
+	CapturedAt  time.Time               `json:"captured_at"`
+	Plan        *optimization.QueryPlan `json:"plan,omitempty"`
+}
+
+// SlowQueryFilter selects entries of the slow query log
+type SlowQueryFilter struct {
+	UserID string // empty for all users
+	Since  time.Time
+	Limit  int
+}
+
+// SlowQueryLog captures queries exceeding a duration threshold together
+// with their plan and the index usage EXPLAIN reports for them. Entries are
+// kept in memory, or in ClickHouse once SetStorage is called.
+type SlowQueryLog struct {
+	optimizer  *optimization.QueryOptimizer
+	threshold  time.Duration
+	db         SQLExecutor
+	entries    []*SlowQuery // oldest first
+	maxEntries int
+	mu         sync.RWMutex
+}
+
+// NewSlowQueryLog creates a slow query log keeping up to maxEntries in memory
+func NewSlowQueryLog(optimizer *optimization.QueryOptimizer, threshold time.Duration, maxEntries int) *SlowQueryLog {
+	return &SlowQueryLog{
+		optimizer:  optimizer,
+		threshold:  threshold,
+		entries:    make([]*SlowQuery, 0),
+		maxEntries: maxEntries,
+	}
+}
+
+// SetThreshold sets the capture threshold; zero or less disables capture
+func (l *SlowQueryLog) SetThreshold(threshold time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.threshold = threshold
+}
+
+// Threshold returns the capture threshold
+func (l *SlowQueryLog) Threshold() time.Duration {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.threshold
+}
+
+// SetStorage stores captured queries in ClickHouse, creating the table if needed
+func (l *SlowQueryLog) SetStorage(db SQLExecutor) error {
+	ddl := fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		id String,
+		captured_at DateTime64(3),
+		user_id String,
+		query String,
+		executed_sql String,
+		duration_ms UInt64,
+		row_count UInt64,
+		error String,
+		read_rows UInt64,
+		indexes_used Array(String),
+		plan String
+	) ENGINE = MergeTree()
+	ORDER BY captured_at
+	TTL toDateTime(captured_at) + INTERVAL 30 DAY
+	`, SlowQueryTable)
+	if err := db.Execute(context.Background(), ddl); err != nil {
+		return fmt.Errorf("failed to create slow query table: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.db = db
+	return nil
+}
+
+// Capture records a finished query if it ran for at least the threshold.
+// EXPLAIN runs in the background so the caller is not delayed further.
+func (l *SlowQueryLog) Capture(ctx context.Context, original string, plan *optimization.QueryPlan, executed string, duration time.Duration, rowCount int, execErr error) {
+	threshold := l.Threshold()
+	if threshold <= 0 || duration < threshold {
+		return
+	}
+
+	entry := &SlowQuery{
+		ID:          uuid.New().String(),
+		Query:       original,
+		ExecutedSQL: executed,
+		UserID:      auth.UserFromContext(ctx).ID,
+		DurationMs:  duration.Milliseconds(),
+		RowCount:    rowCount,
+		CapturedAt:  time.Now().UTC(),
+	}
+	if execErr != nil {
+		entry.Error = execErr.Error()
+	}
+	if plan != nil {
+		copied := *plan
+		entry.Plan = &copied
+	}
+
+	go l.record(entry)
+}
+
+func (l *SlowQueryLog) record(entry *SlowQuery) {
+	if entry.Plan != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		l.optimizer.Estimate(ctx, entry.Plan, entry.ExecutedSQL)
+		cancel()
+	}
+
+	log.Warn().
+		Str("id", entry.ID).
+		Str("user_id", entry.UserID).
+		Int64("duration_ms", entry.DurationMs).
+		Str("query", entry.Query).
+		Msg("Slow query captured")
+
+	l.mu.Lock()
+	l.entries = append(l.entries, entry)
+	if len(l.entries) > l.maxEntries {
+		l.entries = l.entries[len(l.entries)-l.maxEntries:]
+	}
+	db := l.db
+	l.mu.Unlock()
+
+	if db != nil {
+		if err := l.store(db, entry); err != nil {
+			log.Error().Err(err).Str("id", entry.ID).Msg("Failed to store slow query")
+		}
+	}
+}
+
+func (l *SlowQueryLog) store(db SQLExecutor, entry *SlowQuery) error {
+	planJSON := ""
+	var readRows int64
+	indexes := []string{}
+	if entry.Plan != nil {
+		encoded, err := json.Marshal(entry.Plan)
+		if err != nil {
+			return fmt.Errorf("failed to encode plan: %w", err)
+		}
+		planJSON = string(encoded)
+		if entry.Plan.Scan != nil {
+			readRows = entry.Plan.Scan.Rows
+		}
+		indexes = entry.Plan.IndexesUsed
+	}
+
+	quoted := make([]string, len(indexes))
+	for i, index := range indexes {
+		quoted[i] = chsql.Quote(index)
+	}
+
+	statement := fmt.Sprintf(`INSERT INTO %s (id, captured_at, user_id, query, executed_sql, duration_ms, row_count, error, read_rows, indexes_used, plan)
+	VALUES (%s, %s, %s, %s, %s, %d, %d, %s, %d, [%s], %s)`,
+		SlowQueryTable,
+		chsql.Quote(entry.ID),
+		chsql.Quote(entry.CapturedAt.Format("2006-01-02 15:04:05.000")),
+		chsql.Quote(entry.UserID),
+		chsql.Quote(entry.Query),
+		chsql.Quote(entry.ExecutedSQL),
+		entry.DurationMs,
+		entry.RowCount,
+		chsql.Quote(entry.Error),
+		readRows,
+		strings.Join(quoted, ", "),
+		chsql.Quote(planJSON),
+	)
+	return db.Execute(context.Background(), statement)
+}
+
+// List returns captured queries matching the filter, slowest first
+func (l *SlowQueryLog) List(filter SlowQueryFilter) ([]*SlowQuery, error) {
+	if filter.Limit <= 0 || filter.Limit > l.maxEntries {
+		filter.Limit = l.maxEntries
+	}
+
+	l.mu.RLock()
+	db := l.db
+	l.mu.RUnlock()
+	if db != nil {
+		conditions := []string{"1 = 1"}
+		if filter.UserID != "" {
+			conditions = append(conditions, "user_id = "+chsql.Quote(filter.UserID))
+		}
+		if !filter.Since.IsZero() {
+			conditions = append(conditions, "captured_at >= "+chsql.Quote(filter.Since.UTC().Format("2006-01-02 15:04:05.000")))
+		}
+		return l.load(db, strings.Join(conditions, " AND "), filter.Limit)
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	result := make([]*SlowQuery, 0)
+	for _, entry := range l.entries {
+		if filter.UserID != "" && entry.UserID != filter.UserID {
+			continue
+		}
+		if !filter.Since.IsZero() && entry.CapturedAt.Before(filter.Since) {
+			continue
+		}
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DurationMs > result[j].DurationMs
+	})
+	if len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result, nil
+}
+
+// Get returns a captured query by ID
+func (l *SlowQueryLog) Get(id string) (*SlowQuery, error) {
+	l.mu.RLock()
+	db := l.db
+	for _, entry := range l.entries {
+		if entry.ID == id {
+			l.mu.RUnlock()
+			return entry, nil
+		}
+	}
+	l.mu.RUnlock()
+
+	if db != nil {
+		entries, err := l.load(db, "id = "+chsql.Quote(id), 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			return entries[0], nil
+		}
+	}
+	return nil, fmt.Errorf("slow query not found: %s", id)
+}
+
+func (l *SlowQueryLog) load(db SQLExecutor, where string, limit int) ([]*SlowQuery, error) {
+	rows, err := db.ExecuteSQL(fmt.Sprintf(`
+		SELECT id, captured_at, user_id, query, executed_sql, duration_ms, row_count, error, plan
+		FROM %s
+		WHERE %s
+		ORDER BY duration_ms DESC
+		LIMIT %d
+	`, SlowQueryTable, where, limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load slow queries: %w", err)
+	}
+
+	entries := make([]*SlowQuery, 0, len(rows))
+	for _, row := range rows {
+		entry := &SlowQuery{
+			ID:         fmt.Sprintf("%v", row["id"]),
+			UserID:     fmt.Sprintf("%v", row["user_id"]),
+			DurationMs: chsql.ToInt64(row["duration_ms"]),
+			RowCount:   int(chsql.ToInt64(row["row_count"])),
+		}
+		entry.Query, _ = row["query"].(string)
+		entry.ExecutedSQL, _ = row["executed_sql"].(string)
+		entry.Error, _ = row["error"].(string)
+		if capturedAt, ok := row["captured_at"].(string); ok {
+			entry.CapturedAt, _ = time.Parse("2006-01-02 15:04:05.000", capturedAt)
+		}
+		if plan, ok := row["plan"].(string); ok && plan != "" {
+			var decoded optimization.QueryPlan
+			if err := json.Unmarshal([]byte(plan), &decoded); err == nil {
+				entry.Plan = &decoded
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,198 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+)
+
+// SQLExecutor is the subset of the database used to persist saved queries
+type SQLExecutor interface {
+	Execute(ctx context.Context, query string) error
+	ExecuteSQL(sql string) ([]map[string]interface{}, error)
+}
+
+// migration is a schema change applied once, in version order
+type migration struct {
+	version     int
+	description string
+	statements  []string
+}
+
+// Saved query schema history. Append new migrations; never edit applied ones.
+var savedQueryMigrations = []migration{
+	{
+		version:     1,
+		description: "create saved_queries",
+		statements: []string{`
+		CREATE TABLE IF NOT EXISTS saved_queries (
+			id String,
+			name String,
+			created_by String,
+			is_template UInt8,
+			definition String,
+			deleted UInt8 DEFAULT 0,
+			updated_at DateTime64(3),
+			version UInt64
+		) ENGINE = ReplacingMergeTree(version)
+		ORDER BY id
+		`},
+	},
+}
+
+// ClickHouseStorage keeps saved queries in ClickHouse. Every save inserts a
+// new row for the query and ReplacingMergeTree keeps the latest; deletions
+// are tombstone rows so a stale replica cannot resurrect a deleted query.
+type ClickHouseStorage struct {
+	db    SQLExecutor
+	table string
+}
+
+// NewClickHouseStorage applies pending migrations and returns the storage
+func NewClickHouseStorage(db SQLExecutor) (*ClickHouseStorage, error) {
+	s := &ClickHouseStorage{
+		db:    db,
+		table: "saved_queries",
+	}
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate applies migrations newer than the recorded schema version
+func (s *ClickHouseStorage) migrate() error {
+	ctx := context.Background()
+	if err := s.db.Execute(ctx, `
+	CREATE TABLE IF NOT EXISTS saved_queries_migrations (
+		version UInt32,
+		description String,
+		applied_at DateTime64(3) DEFAULT now64(3)
+	) ENGINE = MergeTree()
+	ORDER BY version
+	`); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	rows, err := s.db.ExecuteSQL("SELECT max(version) AS version FROM saved_queries_migrations")
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	current := 0
+	if len(rows) > 0 {
+		current = int(chsql.ToInt64(rows[0]["version"]))
+	}
+
+	for _, m := range savedQueryMigrations {
+		if m.version <= current {
+			continue
+		}
+		for _, statement := range m.statements {
+			if err := s.db.Execute(ctx, statement); err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+			}
+		}
+		record := fmt.Sprintf("INSERT INTO saved_queries_migrations (version, description) VALUES (%d, %s)",
+			m.version, chsql.Quote(m.description))
+		if err := s.db.Execute(ctx, record); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+		}
+		log.Info().Int("version", m.version).Str("migration", m.description).Msg("Applied saved query migration")
+	}
+	return nil
+}
+
+// Save writes the current definition of a query
+func (s *ClickHouseStorage) Save(query *SavedQuery) error {
+	definition, err := json.Marshal(query)
+	if err != nil {
+		return fmt.Errorf("failed to encode query: %w", err)
+	}
+	return s.insert(query, string(definition), false)
+}
+
+// Load returns a query unless it was deleted
+func (s *ClickHouseStorage) Load(id string) (*SavedQuery, error) {
+	queries, err := s.load("WHERE id = " + chsql.Quote(id))
+	if err != nil {
+		return nil, err
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("query not found: %s", id)
+	}
+	return queries[0], nil
+}
+
+// LoadAll returns every query that has not been deleted
+func (s *ClickHouseStorage) LoadAll() ([]*SavedQuery, error) {
+	return s.load("")
+}
+
+// Delete records a tombstone for the query
+func (s *ClickHouseStorage) Delete(id string) error {
+	return s.insert(&SavedQuery{ID: id}, "", true)
+}
+
+func (s *ClickHouseStorage) insert(query *SavedQuery, definition string, deleted bool) error {
+	now := time.Now().UTC()
+	deletedFlag, templateFlag := 0, 0
+	if deleted {
+		deletedFlag = 1
+	}
+	if query.IsTemplate {
+		templateFlag = 1
+	}
+
+	statement := fmt.Sprintf(
+		"INSERT INTO %s (id, name, created_by, is_template, definition, deleted, updated_at, version) VALUES (%s, %s, %s, %d, %s, %d, %s, %d)",
+		s.table,
+		chsql.Quote(query.ID),
+		chsql.Quote(query.Name),
+		chsql.Quote(query.CreatedBy),
+		templateFlag,
+		chsql.Quote(definition),
+		deletedFlag,
+		chsql.Quote(now.Format("2006-01-02 15:04:05.000")),
+		now.UnixNano(),
+	)
+	if err := s.db.Execute(context.Background(), statement); err != nil {
+		return fmt.Errorf("failed to store query: %w", err)
+	}
+	return nil
+}
+
+// load reads the latest row per query, resolving versions with argMax so the
+// result does not depend on background merges having run
+func (s *ClickHouseStorage) load(where string) ([]*SavedQuery, error) {
+	rows, err := s.db.ExecuteSQL(fmt.Sprintf(`
+		SELECT id,
+			argMax(definition, version) AS definition,
+			argMax(deleted, version) AS deleted
+		FROM %s
+		%s
+		GROUP BY id
+	`, s.table, where))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load queries: %w", err)
+	}
+
+	queries := make([]*SavedQuery, 0, len(rows))
+	for _, row := range rows {
+		if chsql.ToInt64(row["deleted"]) != 0 {
+			continue
+		}
+		definition, _ := row["definition"].(string)
+		var query SavedQuery
+		if err := json.Unmarshal([]byte(definition), &query); err != nil {
+			log.Warn().Err(err).Interface("id", row["id"]).Msg("Skipping unreadable saved query")
+			continue
+		}
+		queries = append(queries, &query)
+	}
+	return queries, nil
+}
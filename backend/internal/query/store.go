@@ -23,16 +23,48 @@ type SavedQuery struct {
 	IsTemplate  bool                   `json:"is_template"`
 	Category    string                 `json:"category,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Visibility  string                 `json:"visibility,omitempty"` // private, team or public
+	Team        string                 `json:"team,omitempty"`
+	Shares      []QueryShare           `json:"shares,omitempty"`
 }
 
 // QueryParameter defines a parameter for a saved query
 type QueryParameter struct {
 	Name         string      `json:"name"`
-	Type         string      `json:"type"` // string, number, date, boolean
+	Type         string      `json:"type"` // string, number, date, boolean, identifier
 	Description  string      `json:"description,omitempty"`
 	DefaultValue interface{} `json:"default_value,omitempty"`
 	Required     bool        `json:"required"`
 	Options      []string    `json:"options,omitempty"` // For enum-like parameters
+
+	// UI hints for template forms, also enforced when the query runs
+	Widget       string   `json:"widget,omitempty"` // see the Widget constants; DefaultWidget when empty
+	Label        string   `json:"label,omitempty"`
+	Placeholder  string   `json:"placeholder,omitempty"`
+	Min          *float64 `json:"min,omitempty"`           // number
+	Max          *float64 `json:"max,omitempty"`           // number
+	Step         float64  `json:"step,omitempty"`          // number, for sliders
+	MaxLength    int      `json:"max_length,omitempty"`    // string
+	Pattern      string   `json:"pattern,omitempty"`       // string, regular expression values must match
+	MaxAge       string   `json:"max_age,omitempty"`       // date, oldest value allowed relative to now, e.g. "720h"
+	OptionsQuery string   `json:"options_query,omitempty"` // SQL listing the allowed values in its only or value column
+}
+
+// Parameter widgets
+const (
+	WidgetText        = "text"
+	WidgetNumber      = "number"
+	WidgetSlider      = "slider"
+	WidgetSelect      = "select"
+	WidgetMultiSelect = "multi_select"
+	WidgetDate        = "date"
+	WidgetDateTime    = "datetime"
+	WidgetCheckbox    = "checkbox"
+)
+
+var validWidgets = map[string]bool{
+	WidgetText: true, WidgetNumber: true, WidgetSlider: true, WidgetSelect: true,
+	WidgetMultiSelect: true, WidgetDate: true, WidgetDateTime: true, WidgetCheckbox: true,
 }
 
 // QueryStore manages saved queries
@@ -114,9 +146,22 @@ func NewQueryStore() *QueryStore {
 	return store
 }
 
-// SetStorage sets the storage backend
-func (qs *QueryStore) SetStorage(storage StorageBackend) {
+// SetStorage sets the storage backend and loads the queries it holds.
+// Built-in templates stay in memory and are not written to the new backend.
+func (qs *QueryStore) SetStorage(storage StorageBackend) error {
+	stored, err := storage.LoadAll()
+	if err != nil {
+		return fmt.Errorf("failed to load saved queries: %w", err)
+	}
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
 	qs.storage = storage
+	for _, query := range stored {
+		qs.queries[query.ID] = query
+	}
+	log.Info().Int("count", len(stored)).Msg("Loaded saved queries")
+	return nil
 }
 
 // Save saves a query
@@ -182,6 +227,8 @@ func (qs *QueryStore) List(filters ...QueryFilter) ([]*SavedQuery, error) {
 			allQueries = append(allQueries, q)
 		}
 		qs.mu.RUnlock()
+	} else {
+		allQueries = qs.withBuiltInTemplates(allQueries)
 	}
 	
 	// Apply filters
@@ -193,14 +240,33 @@ func (qs *QueryStore) List(filters ...QueryFilter) ([]*SavedQuery, error) {
 	return filtered, nil
 }
 
+// withBuiltInTemplates adds built-in templates that the storage backend does not hold
+func (qs *QueryStore) withBuiltInTemplates(queries []*SavedQuery) []*SavedQuery {
+	stored := make(map[string]bool, len(queries))
+	for _, q := range queries {
+		stored[q.ID] = true
+	}
+
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+	for id, q := range qs.queries {
+		if q.IsTemplate && q.CreatedBy == "system" && !stored[id] {
+			queries = append(queries, q)
+		}
+	}
+	return queries
+}
+
 // Update updates an existing query
 func (qs *QueryStore) Update(id string, updates map[string]interface{}) error {
-	query, err := qs.Get(id)
+	current, err := qs.Get(id)
 	if err != nil {
 		return err
 	}
 	
-	// Apply updates
+	// Apply updates to a copy so a rejected update leaves the cache untouched
+	updated := *current
+	query := &updated
 	if name, ok := updates["name"].(string); ok {
 		query.Name = name
 	}
@@ -222,6 +288,12 @@ func (qs *QueryStore) Update(id string, updates map[string]interface{}) error {
 	if metadata, ok := updates["metadata"].(map[string]interface{}); ok {
 		query.Metadata = metadata
 	}
+	if visibility, ok := updates["visibility"].(string); ok {
+		query.Visibility = visibility
+	}
+	if team, ok := updates["team"].(string); ok {
+		query.Team = team
+	}
 	
 	query.UpdatedAt = time.Now()
 	
@@ -264,6 +336,12 @@ func (qs *QueryStore) validateQuery(query *SavedQuery) error {
 		return fmt.Errorf("query text is required")
 	}
 	
+	if query.Visibility != "" {
+		if err := ValidateVisibility(query.Visibility, query.Team); err != nil {
+			return err
+		}
+	}
+	
 	// Validate parameter names
 	paramNames := make(map[string]bool)
 	for _, param := range query.Parameters {
@@ -277,7 +355,7 @@ func (qs *QueryStore) validateQuery(query *SavedQuery) error {
 		paramNames[param.Name] = true
 		
 		// Validate parameter type
-		validTypes := []string{"string", "number", "date", "boolean"}
+		validTypes := []string{"string", "number", "date", "boolean", "identifier"}
 		validType := false
 		for _, t := range validTypes {
 			if param.Type == t {
@@ -288,6 +366,10 @@ func (qs *QueryStore) validateQuery(query *SavedQuery) error {
 		if !validType {
 			return fmt.Errorf("invalid parameter type: %s", param.Type)
 		}
+
+		if err := ValidateParameterDef(param); err != nil {
+			return err
+		}
 	}
 	
 	return nil
@@ -447,6 +529,7 @@ ORDER BY timestamp ASC`,
 	
 	// Save templates
 	for _, template := range templates {
+		template.Visibility = VisibilityPublic
 		qs.queries[template.ID] = template
 		// Also save to storage
 		if err := qs.storage.Save(template); err != nil {
@@ -0,0 +1,71 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/telemetry"
+)
+
+const instrumentationName = "github.com/your-username/click-lite-log-analytics/backend/internal/query"
+
+var (
+	tracer = otel.Tracer(instrumentationName)
+
+	queryDuration, _ = otel.Meter(instrumentationName).Float64Histogram("clicklite.query.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of queries run by the query engine, from validation to the last row"),
+		metric.WithExplicitBucketBoundaries(0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60))
+	queryRows, _ = otel.Meter(instrumentationName).Int64Counter("clicklite.query.rows",
+		metric.WithUnit("{row}"),
+		metric.WithDescription("Rows returned by the query engine"))
+)
+
+// startQuerySpan starts the span of a query run by operation: execute,
+// stream or download. The SQL is left out of spans, as it often holds the
+// values searched for.
+func startQuerySpan(ctx context.Context, operation string, req *QueryRequest) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "query."+operation, trace.WithAttributes(
+		attribute.String("db.system", "clickhouse"),
+		attribute.Bool("clicklite.query.use_cache", req.UseCache),
+		attribute.Bool("clicklite.query.approximate", req.Approximate),
+	))
+}
+
+// endQuery ends the span of a query and records its duration and rows,
+// by operation and outcome: ok, cache_hit or error, and in the server's
+// metrics when set
+func (e *Engine) endQuery(ctx context.Context, span trace.Span, operation string, start time.Time, rows int, cacheHit bool, err error) {
+	outcome := "ok"
+	switch {
+	case err != nil:
+		outcome = "error"
+	case cacheHit:
+		outcome = "cache_hit"
+	}
+	span.SetAttributes(
+		attribute.Int("clicklite.query.rows", rows),
+		attribute.Bool("clicklite.query.cache_hit", cacheHit),
+	)
+	telemetry.SpanError(span, err)
+	span.End()
+
+	attributes := metric.WithAttributes(
+		attribute.String("operation", operation),
+		attribute.String("outcome", outcome),
+	)
+	duration := time.Since(start)
+	queryDuration.Record(ctx, duration.Seconds(), attributes)
+	if rows > 0 {
+		queryRows.Add(ctx, int64(rows), attributes)
+	}
+
+	if e.metrics != nil {
+		e.metrics.RecordQuery(duration)
+	}
+}
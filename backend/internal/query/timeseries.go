@@ -0,0 +1,313 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/querybuilder"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/tables"
+)
+
+const (
+	defaultTimeSeriesSplits = 10
+
+	// hourlyView is the materialized view created by the storage optimizer
+	hourlyView = "logs_by_service_hourly"
+)
+
+// Fields stored in the hourly view, which can be filtered and split on there
+var hourlyViewFields = map[string]bool{"service": true, "level": true}
+
+// TimeSeriesRequest describes a series of one aggregation over time
+type TimeSeriesRequest struct {
+	Table       string                      `json:"table,omitempty"` // logs when empty
+	Filters     []models.QueryBuilderFilter `json:"filters,omitempty"`
+	Aggregation models.QueryAggregation     `json:"aggregation"`           // COUNT when empty
+	SplitBy     string                      `json:"split_by,omitempty"`    // field producing one series per value
+	SplitLimit  int                         `json:"split_limit,omitempty"` // series kept when splitting, largest first
+	TimeRange   models.QueryTimeRange       `json:"time_range"`
+	Interval    string                      `json:"interval,omitempty"`   // e.g. "5m"; chosen from the range when empty
+	MaxPoints   int                         `json:"max_points,omitempty"` // upper bound on buckets when choosing the interval
+}
+
+// TimeSeriesPoint is one bucket. Value is null for buckets without data
+// when the aggregation has no natural zero (AVG, MIN, MAX).
+type TimeSeriesPoint struct {
+	Time  time.Time `json:"time"`
+	Value *float64  `json:"value"`
+}
+
+// TimeSeries is the points of one series
+type TimeSeries struct {
+	Name   string            `json:"name"`
+	Points []TimeSeriesPoint `json:"points"`
+}
+
+// TimeSeriesResponse is a dense series covering the whole range
+type TimeSeriesResponse struct {
+	Start           time.Time    `json:"start"`
+	End             time.Time    `json:"end"`
+	Interval        string       `json:"interval"`
+	IntervalSeconds int64        `json:"interval_seconds"`
+	Source          string       `json:"source"` // table or materialized view read
+	Series          []TimeSeries `json:"series"`
+	SQL             string       `json:"sql"`
+	CacheHit        bool         `json:"cache_hit,omitempty"`
+	ExecutionTime   int64        `json:"execution_time_ms"`
+}
+
+// viewCoverage remembers since when a materialized view holds data. Views
+// are created without POPULATE, so earlier ranges must read the table.
+type viewCoverage struct {
+	since     time.Time
+	available bool
+	checkedAt time.Time
+	mu        sync.Mutex
+}
+
+// TimeSeries runs an aggregation bucketed over time. The interval is chosen
+// so the range fits in MaxPoints buckets, the hourly materialized view is
+// read instead of logs when it can answer the request, and missing buckets
+// are filled in so every series has a point per bucket.
+func (e *Engine) TimeSeries(ctx context.Context, req *TimeSeriesRequest) (*TimeSeriesResponse, error) {
+	builder := querybuilder.NewService(e.tables)
+	if req.Table == "" {
+		req.Table = tables.DefaultTable
+	}
+	timeColumn, err := builder.TimeColumn(req.Table)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := builder.ResolveTimeRange(&req.TimeRange)
+	if err != nil {
+		return nil, err
+	}
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if start.IsZero() || !start.Before(end) {
+		return nil, fmt.Errorf("time range needs a start before its end")
+	}
+
+	interval, err := querybuilder.ChooseInterval(req.Interval, end.Sub(start), req.MaxPoints)
+	if err != nil {
+		return nil, err
+	}
+	// Align the range to whole buckets so the first and last are complete
+	start = querybuilder.AlignToInterval(start, interval)
+	if aligned := querybuilder.AlignToInterval(end, interval); aligned.Before(end) {
+		end = aligned.Add(interval)
+	}
+
+	if req.Aggregation.Function == "" {
+		req.Aggregation.Function = "COUNT"
+	}
+	if req.SplitBy != "" && !builder.IsAvailableField(req.Table, req.SplitBy) {
+		return nil, fmt.Errorf("unknown split field: %s", req.SplitBy)
+	}
+	if req.SplitLimit <= 0 {
+		req.SplitLimit = defaultTimeSeriesSplits
+	}
+	rows := int(end.Sub(start) / interval)
+	if req.SplitBy != "" {
+		rows *= req.SplitLimit
+	}
+	if rows > e.validator.MaxLimit() {
+		return nil, fmt.Errorf("time series would return %d rows, more than the limit of %d", rows, e.validator.MaxLimit())
+	}
+
+	source := req.Table
+	if source == tables.DefaultTable && e.canUseHourlyView(ctx, req, start, interval) {
+		source, timeColumn = hourlyView, "hour"
+	}
+	sql, err := buildTimeSeriesSQL(builder, req, source, timeColumn, start, end, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := e.Execute(ctx, &QueryRequest{Query: sql, UseCache: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TimeSeriesResponse{
+		Start:           start,
+		End:             end,
+		Interval:        querybuilder.FormatInterval(interval),
+		IntervalSeconds: int64(interval / time.Second),
+		Source:          source,
+		Series:          fillTimeSeries(result.Rows, req, start, end, interval),
+		SQL:             sql,
+		CacheHit:        result.CacheHit,
+		ExecutionTime:   result.ExecutionTime,
+	}, nil
+}
+
+// canUseHourlyView reports whether the hourly view can answer the request:
+// a plain count in whole-hour buckets, filtered and split only on columns
+// of the view, over a range the view has data for
+func (e *Engine) canUseHourlyView(ctx context.Context, req *TimeSeriesRequest, start time.Time, interval time.Duration) bool {
+	if interval%time.Hour != 0 || req.Aggregation.Function != "COUNT" || req.Aggregation.Field != "" {
+		return false
+	}
+	if req.SplitBy != "" && !hourlyViewFields[req.SplitBy] {
+		return false
+	}
+	for _, filter := range req.Filters {
+		if !hourlyViewFields[filter.Field] {
+			return false
+		}
+		switch filter.Operator {
+		case "equals", "not_equals", "in", "not_in":
+		default:
+			return false
+		}
+	}
+
+	// Users can only read the view when it is a registered table
+	if !e.tables.Has(hourlyView) {
+		return false
+	}
+	since, ok := e.hourlyViewSince(ctx)
+	// The view's first hour may only be partly covered
+	return ok && !start.Before(since.Add(time.Hour))
+}
+
+// hourlyViewSince returns the first hour held by the hourly view, checking
+// at most every five minutes
+func (e *Engine) hourlyViewSince(ctx context.Context) (time.Time, bool) {
+	v := e.views
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if time.Since(v.checkedAt) < 5*time.Minute {
+		return v.since, v.available
+	}
+
+	v.checkedAt = time.Now()
+	v.available = false
+	rows, err := e.db.ExecuteQuery(auth.WithUser(ctx, auth.SystemUser),
+		fmt.Sprintf("SELECT toUnixTimestamp(min(hour)) AS since, count() AS buckets FROM %s", hourlyView))
+	if err != nil || len(rows) == 0 || chsql.ToInt64(rows[0]["buckets"]) == 0 {
+		return v.since, v.available
+	}
+	v.since = time.Unix(chsql.ToInt64(rows[0]["since"]), 0).UTC()
+	v.available = true
+	return v.since, v.available
+}
+
+func buildTimeSeriesSQL(builder *querybuilder.Service, req *TimeSeriesRequest, source, timeColumn string, start, end time.Time, interval time.Duration) (string, error) {
+	value := ""
+	if source == hourlyView {
+		value = "toFloat64(sum(count))"
+	} else {
+		agg := req.Aggregation
+		field := ""
+		if agg.Field != "" {
+			var err error
+			if field, err = builder.FieldExpression(req.Table, agg.Field, agg.Type); err != nil {
+				return "", fmt.Errorf("unknown field in aggregation: %s", agg.Field)
+			}
+		}
+		switch agg.Function {
+		case "COUNT":
+			value = "toFloat64(count())"
+			if agg.Field != "" {
+				value = fmt.Sprintf("toFloat64(count(%s))", field)
+			}
+		case "COUNT_DISTINCT":
+			if agg.Field == "" {
+				return "", fmt.Errorf("COUNT_DISTINCT requires a field")
+			}
+			value = fmt.Sprintf("toFloat64(uniqExact(%s))", field)
+		case "SUM", "AVG", "MIN", "MAX":
+			if agg.Field == "" {
+				return "", fmt.Errorf("%s requires a field", agg.Function)
+			}
+			value = fmt.Sprintf("toFloat64(%s(toFloat64OrZero(toString(%s))))", strings.ToLower(agg.Function), field)
+		default:
+			return "", fmt.Errorf("unsupported aggregation function: %s", agg.Function)
+		}
+	}
+
+	where := fmt.Sprintf("%s >= toDateTime('%s', 'UTC') AND %s < toDateTime('%s', 'UTC')",
+		timeColumn, start.Format("2006-01-02 15:04:05"), timeColumn, end.Format("2006-01-02 15:04:05"))
+	if len(req.Filters) > 0 {
+		filters, err := builder.BuildFilters(req.Table, req.Filters)
+		if err != nil {
+			return "", err
+		}
+		where += " AND (" + filters + ")"
+	}
+
+	columns := fmt.Sprintf("toUnixTimestamp(toStartOfInterval(%s, INTERVAL %d SECOND)) AS bucket", timeColumn, interval/time.Second)
+	splitBy := req.SplitBy
+	if splitBy != "" {
+		var err error
+		if splitBy, err = builder.FieldExpression(req.Table, req.SplitBy, ""); err != nil {
+			return "", err
+		}
+	}
+	if req.SplitBy == "" {
+		return fmt.Sprintf("SELECT %s, %s AS value FROM %s WHERE %s GROUP BY bucket ORDER BY bucket",
+			columns, value, source, where), nil
+	}
+
+	// Keep the largest series only. The series are looked up in a WITH
+	// clause rather than an IN subquery, which the optimizer would rewrite.
+	return fmt.Sprintf("WITH (SELECT groupArray(series) FROM (SELECT toString(%s) AS series FROM %s WHERE %s GROUP BY series ORDER BY %s DESC LIMIT %d)) AS top_series "+
+		"SELECT %s, toString(%s) AS series, %s AS value FROM %s WHERE %s AND has(top_series, toString(%s)) GROUP BY bucket, series ORDER BY bucket",
+		splitBy, source, where, value, req.SplitLimit,
+		columns, splitBy, value, source, where, splitBy), nil
+}
+
+// fillTimeSeries turns rows into one series per split value with a point
+// for every bucket between start and end
+func fillTimeSeries(rows []map[string]interface{}, req *TimeSeriesRequest, start, end time.Time, interval time.Duration) []TimeSeries {
+	values := make(map[string]map[int64]float64)
+	names := []string{}
+	for _, row := range rows {
+		name := req.Aggregation.Function
+		if req.SplitBy != "" {
+			name = fmt.Sprintf("%v", row["series"])
+		}
+		if _, exists := values[name]; !exists {
+			values[name] = make(map[int64]float64)
+			names = append(names, name)
+		}
+		value, _ := row["value"].(float64)
+		values[name][chsql.ToInt64(row["bucket"])] = value
+	}
+	if len(names) == 0 && req.SplitBy == "" {
+		names = append(names, req.Aggregation.Function)
+	}
+
+	// Counts and sums are zero where nothing matched; averages and extremes are undefined
+	zeroFill := false
+	switch req.Aggregation.Function {
+	case "COUNT", "COUNT_DISTINCT", "SUM":
+		zeroFill = true
+	}
+
+	series := make([]TimeSeries, 0, len(names))
+	for _, name := range names {
+		points := make([]TimeSeriesPoint, 0, int(end.Sub(start)/interval))
+		for t := start; t.Before(end); t = t.Add(interval) {
+			point := TimeSeriesPoint{Time: t}
+			if value, found := values[name][t.Unix()]; found {
+				point.Value = &value
+			} else if zeroFill {
+				zero := 0.0
+				point.Value = &zero
+			}
+			points = append(points, point)
+		}
+		series = append(series, TimeSeries{Name: name, Points: points})
+	}
+	return series
+}
@@ -3,138 +3,273 @@ package query
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
-	
+
 	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/tables"
 )
 
+// DefaultMaxLimit caps the number of rows a user query may request
+const DefaultMaxLimit = 10000
+
 // Validator validates SQL queries for safety and correctness
 type Validator struct {
 	allowedStatements []string
 	deniedStatements  []string
 	maxQueryLength    int
-	patterns          map[string]*regexp.Regexp
+	maxLimit          int
+	restrictedTables  map[string]bool
+	tables            *tables.Registry
+}
+
+// Table functions queries may read from. They generate rows; the others
+// read files, URLs or other servers, and new ones come with every ClickHouse
+// release, so they are not listed.
+var allowedTableFunctions = map[string]bool{
+	"NUMBERS": true, "NUMBERS_MT": true, "ZEROS": true, "ZEROS_MT": true,
+	"GENERATE_SERIES": true, "GENERATESERIES": true, "VALUES": true,
+}
+
+// Functions that read files wherever they are called, not only after FROM
+var fileFunctions = map[string]bool{"FILE": true}
+
+// Clauses that end the table list of a FROM clause
+var fromClauseEnd = map[string]bool{
+	"SELECT": true, "WITH": true, "PREWHERE": true, "WHERE": true, "GROUP": true, "HAVING": true,
+	"ORDER": true, "LIMIT": true, "SETTINGS": true, "FORMAT": true, "ARRAY": true, "ON": true, "USING": true,
+}
+
+// sqlToken is a word or symbol outside string literals and comments
+type sqlToken struct {
+	text  string // upper-cased for words
+	depth int    // parenthesis nesting level
+	start int
+	end   int
 }
 
 // NewValidator creates a new query validator
 func NewValidator() *Validator {
 	v := &Validator{
-		allowedStatements: []string{"SELECT", "WITH", "SHOW", "DESCRIBE", "DESC", "EXPLAIN"},
-		deniedStatements:  []string{"INSERT", "UPDATE", "DELETE", "DROP", "CREATE", "ALTER", "TRUNCATE", "GRANT", "REVOKE"},
+		allowedStatements: []string{"SELECT", "WITH"},
+		deniedStatements: []string{
+			"INSERT", "UPDATE", "DELETE", "DROP", "CREATE", "ALTER", "TRUNCATE", "GRANT", "REVOKE",
+			"RENAME", "ATTACH", "DETACH", "OPTIMIZE", "KILL", "EXCHANGE",
+		},
 		maxQueryLength:    50000, // 50KB max query size
-		patterns:          make(map[string]*regexp.Regexp),
-	}
-	
-	// Compile regex patterns
-	v.patterns["comments"] = regexp.MustCompile(`--.*$|/\*[\s\S]*?\*/`)
-	v.patterns["semicolon"] = regexp.MustCompile(`;`)
-	v.patterns["system_tables"] = regexp.MustCompile(`(?i)\b(system|information_schema)\b`)
-	v.patterns["dangerous_functions"] = regexp.MustCompile(`(?i)\b(file|url|jdbc|odbc|mysql|postgresql)\s*\(`)
-	
+		maxLimit:          DefaultMaxLimit,
+		restrictedTables:  make(map[string]bool),
+	}
+
 	return v
 }
 
-// Validate checks if a query is safe to execute
+// SetMaxLimit sets the LIMIT cap enforced by EnforceLimit
+func (v *Validator) SetMaxLimit(limit int) {
+	if limit > 0 {
+		v.maxLimit = limit
+	}
+}
+
+// RestrictTable makes a table readable by admins only, like system tables.
+// Used for tables holding data of all users.
+func (v *Validator) RestrictTable(table string) {
+	v.restrictedTables[strings.ToUpper(table)] = true
+}
+
+// SetTables limits the tables regular users may read to those in the registry
+func (v *Validator) SetTables(registry *tables.Registry) {
+	v.tables = registry
+}
+
+// MaxLimit returns the LIMIT cap
+func (v *Validator) MaxLimit() int {
+	return v.maxLimit
+}
+
+// Validate checks if a query is safe to execute for a regular user
 func (v *Validator) Validate(query string) error {
+	return v.ValidateFor(query, false)
+}
+
+// ValidateFor checks if a query is safe to execute. Only read-only
+// SELECT/WITH statements are accepted; admins may also read system tables.
+func (v *Validator) ValidateFor(query string, admin bool) error {
 	if query == "" {
 		return fmt.Errorf("empty query")
 	}
-	
+
 	// Check query length
 	if len(query) > v.maxQueryLength {
 		return fmt.Errorf("query too long: %d bytes (max %d)", len(query), v.maxQueryLength)
 	}
-	
-	// Remove comments for validation
-	cleanQuery := v.removeComments(query)
-	
+
+	tokens := tokenizeSQL(query)
+	if len(tokens) == 0 {
+		return fmt.Errorf("empty query")
+	}
+
 	// Check for multiple statements (SQL injection prevention)
-	if v.hasMultipleStatements(cleanQuery) {
+	if v.hasMultipleStatements(tokens) {
 		return fmt.Errorf("multiple statements not allowed")
 	}
-	
+
 	// Get the statement type
-	statementType := v.getStatementType(cleanQuery)
+	statementType := v.getStatementType(tokens)
 	if statementType == "" {
 		return fmt.Errorf("unable to determine query type")
 	}
-	
+
 	// Check if statement is allowed
 	if !v.isStatementAllowed(statementType) {
 		return fmt.Errorf("statement type '%s' not allowed", statementType)
 	}
-	
+
 	// Check for dangerous patterns
-	if err := v.checkDangerousPatterns(cleanQuery); err != nil {
+	if err := v.checkDangerousPatterns(tokens, admin); err != nil {
 		return err
 	}
-	
-	// Validate specific to logs table
-	if err := v.validateLogsQuery(cleanQuery); err != nil {
+
+	// Check that only registered tables are read
+	if err := v.checkTables(tokens, admin); err != nil {
 		return err
 	}
-	
+
+	// Validate specific to logs table
+	v.validateLogsQuery(tokens)
+
 	return nil
 }
 
-// removeComments removes SQL comments from the query
-func (v *Validator) removeComments(query string) string {
-	return v.patterns["comments"].ReplaceAllString(query, "")
-}
-
-// hasMultipleStatements checks if query contains multiple statements
-func (v *Validator) hasMultipleStatements(query string) bool {
-	// Count semicolons not within quotes
-	inQuote := false
-	quoteChar := rune(0)
-	semicolonCount := 0
-	
-	for i, char := range query {
-		if !inQuote {
-			if char == '\'' || char == '"' {
-				inQuote = true
-				quoteChar = char
-			} else if char == ';' {
-				// Check if this is the last character (trailing semicolon is OK)
-				if i < len(query)-1 && strings.TrimSpace(query[i+1:]) != "" {
-					semicolonCount++
-				}
+// EnforceLimit appends the LIMIT cap to queries without a top-level LIMIT
+// and rejects queries that ask for more rows than the cap
+func (v *Validator) EnforceLimit(query string) (string, error) {
+	tokens := tokenizeSQL(query)
+
+	insertAt := -1
+	for i, tok := range tokens {
+		if tok.depth != 0 {
+			continue
+		}
+		switch tok.text {
+		case "LIMIT":
+			// LIMIT n BY columns limits rows per group, not the result
+			if i+2 < len(tokens) && tokens[i+2].text == "BY" {
+				continue
+			}
+			limit, err := topLevelLimit(query, tokens[i+1:])
+			if err != nil {
+				return "", err
 			}
-		} else {
-			if char == quoteChar && (i == 0 || query[i-1] != '\\') {
-				inQuote = false
+			if limit > v.maxLimit {
+				return "", fmt.Errorf("LIMIT %d exceeds maximum of %d", limit, v.maxLimit)
+			}
+			return query, nil
+		case "SETTINGS", "FORMAT":
+			if insertAt < 0 {
+				insertAt = tok.start
 			}
 		}
 	}
-	
-	return semicolonCount > 0
-}
-
-// getStatementType extracts the SQL statement type
-func (v *Validator) getStatementType(query string) string {
-	query = strings.TrimSpace(strings.ToUpper(query))
-	
-	// Handle WITH clause (CTE)
-	if strings.HasPrefix(query, "WITH") {
-		// Find the main statement after WITH
-		parts := strings.Split(query, ")")
-		for _, part := range parts {
-			part = strings.TrimSpace(part)
-			for _, allowed := range v.allowedStatements {
-				if strings.Contains(part, allowed) {
-					return allowed
-				}
+
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	if insertAt >= 0 && insertAt < len(query) {
+		return fmt.Sprintf("%s LIMIT %d %s", strings.TrimSpace(query[:insertAt]), v.maxLimit, query[insertAt:]), nil
+	}
+	return fmt.Sprintf("%s LIMIT %d", query, v.maxLimit), nil
+}
+
+// topLevelLimit reads the row count of "LIMIT n", "LIMIT offset, n" or
+// "LIMIT n OFFSET m". Tokens start right after the LIMIT keyword.
+func topLevelLimit(query string, tokens []sqlToken) (int, error) {
+	if len(tokens) == 0 {
+		return 0, fmt.Errorf("LIMIT requires a row count")
+	}
+	countTok := tokens[0]
+	if len(tokens) >= 3 && tokens[1].text == "," {
+		countTok = tokens[2]
+	}
+	limit, err := strconv.Atoi(query[countTok.start:countTok.end])
+	if err != nil {
+		return 0, fmt.Errorf("LIMIT must be a constant number")
+	}
+	return limit, nil
+}
+
+// tokenizeSQL splits a query into words and symbols, skipping comments and
+// the contents of string literals and quoted identifiers
+func tokenizeSQL(query string) []sqlToken {
+	var tokens []sqlToken
+	depth := 0
+	n := len(query)
+	for i := 0; i < n; i++ {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			continue
+		case c == '-' && i+1 < n && query[i+1] == '-':
+			for i < n && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < n && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 3
+		case c == '\'':
+			end := skipQuoted(query, i)
+			tokens = append(tokens, sqlToken{text: "'", depth: depth, start: i, end: end})
+			i = end
+		case c == '"' || c == '`':
+			// Quoted identifiers keep their name so table checks still apply
+			end := skipQuoted(query, i)
+			name := ""
+			if end > i+1 {
+				name = strings.ToUpper(query[i+1 : min(end, n)])
 			}
+			tokens = append(tokens, sqlToken{text: `"` + name, depth: depth, start: i, end: end})
+			i = end
+		case isIdentChar(c):
+			j := i
+			for j < n && (isIdentChar(query[j]) || (isDigit(c) && query[j] == '.')) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{text: strings.ToUpper(query[i:j]), depth: depth, start: i, end: j})
+			i = j - 1
+		case c == '(':
+			tokens = append(tokens, sqlToken{text: "(", depth: depth, start: i, end: i + 1})
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+			tokens = append(tokens, sqlToken{text: ")", depth: depth, start: i, end: i + 1})
+		default:
+			tokens = append(tokens, sqlToken{text: string(c), depth: depth, start: i, end: i + 1})
 		}
-		return "WITH"
 	}
-	
-	// Get first word
-	fields := strings.Fields(query)
-	if len(fields) > 0 {
-		return fields[0]
+	return tokens
+}
+
+// hasMultipleStatements checks for semicolons other than a trailing one
+func (v *Validator) hasMultipleStatements(tokens []sqlToken) bool {
+	for i, tok := range tokens {
+		if tok.text == ";" && i < len(tokens)-1 {
+			return true
+		}
+	}
+	return false
+}
+
+// getStatementType returns the leading keyword, looking through opening parentheses
+func (v *Validator) getStatementType(tokens []sqlToken) string {
+	for _, tok := range tokens {
+		if tok.text != "(" {
+			return tok.text
+		}
 	}
-	
 	return ""
 }
 
@@ -149,91 +284,193 @@ func (v *Validator) isStatementAllowed(statementType string) bool {
 }
 
 // checkDangerousPatterns checks for potentially dangerous SQL patterns
-func (v *Validator) checkDangerousPatterns(query string) error {
-	upperQuery := strings.ToUpper(query)
-	
-	// Check for denied statements anywhere in the query
-	for _, denied := range v.deniedStatements {
-		if strings.Contains(upperQuery, denied) {
-			return fmt.Errorf("statement contains denied operation: %s", denied)
-		}
-	}
-	
-	// Check for system table access
-	if v.patterns["system_tables"].MatchString(query) {
-		// Allow SHOW and DESCRIBE on system tables
-		if !strings.Contains(upperQuery, "SHOW") && !strings.Contains(upperQuery, "DESCRIBE") {
+func (v *Validator) checkDangerousPatterns(tokens []sqlToken, admin bool) error {
+	for i, tok := range tokens {
+		// Check for denied statements anywhere in the query
+		for _, denied := range v.deniedStatements {
+			if tok.text == denied {
+				return fmt.Errorf("statement contains denied operation: %s", denied)
+			}
+		}
+
+		// Check for functions that read files
+		if fileFunctions[identifier(tok.text)] && i+1 < len(tokens) && tokens[i+1].text == "(" {
+			return fmt.Errorf("potentially dangerous functions not allowed")
+		}
+
+		// Check for UNION (can be used for SQL injection)
+		if tok.text == "UNION" {
+			return fmt.Errorf("UNION queries not allowed")
+		}
+
+		// Check for system table access, e.g. system.tables or `system`.`parts`
+		name := strings.TrimPrefix(tok.text, `"`)
+		if !admin && (name == "SYSTEM" || name == "INFORMATION_SCHEMA") &&
+			i+1 < len(tokens) && tokens[i+1].text == "." {
 			return fmt.Errorf("access to system tables not allowed")
 		}
+		if !admin && v.restrictedTables[name] {
+			return fmt.Errorf("access to table %s not allowed", strings.ToLower(name))
+		}
 	}
-	
-	// Check for dangerous functions
-	if v.patterns["dangerous_functions"].MatchString(query) {
-		return fmt.Errorf("potentially dangerous functions not allowed")
-	}
-	
-	// Check for UNION (can be used for SQL injection)
-	if strings.Contains(upperQuery, "UNION") {
-		return fmt.Errorf("UNION queries not allowed")
+
+	// Check for table functions that reach outside the database, quoted or
+	// qualified with a database
+	for _, p := range tablePositions(tokens) {
+		if tokens[p].text == "(" {
+			continue // subquery
+		}
+		name := identifier(tokens[p].text)
+		call := p+1 < len(tokens) && tokens[p+1].text == "("
+		if p+3 < len(tokens) && tokens[p+1].text == "." && tokens[p+3].text == "(" {
+			name, call = identifier(tokens[p+2].text), true
+		}
+		if call && !allowedTableFunctions[name] {
+			return fmt.Errorf("table function %s not allowed", strings.ToLower(name))
+		}
 	}
-	
+
 	return nil
 }
 
-// validateLogsQuery performs specific validation for queries on logs table
-func (v *Validator) validateLogsQuery(query string) error {
-	upperQuery := strings.ToUpper(query)
-	
-	// If query references logs table, ensure it has reasonable limits
-	if strings.Contains(upperQuery, "FROM LOGS") || strings.Contains(upperQuery, "FROM `LOGS`") {
-		// Check for LIMIT clause
-		if !strings.Contains(upperQuery, "LIMIT") {
-			// Check if it's an aggregation query (which doesn't need LIMIT)
-			if !v.isAggregationQuery(upperQuery) {
-				return fmt.Errorf("queries on logs table must include a LIMIT clause")
-			}
+// checkTables rejects tables that are not in the registry. Names after
+// FROM, JOIN and the commas of a FROM clause are checked; subqueries, table
+// functions and names defined in a WITH clause are not tables.
+func (v *Validator) checkTables(tokens []sqlToken, admin bool) error {
+	if admin || v.tables == nil {
+		return nil
+	}
+
+	defined := make(map[string]bool)
+	for i := 0; i+2 < len(tokens); i++ {
+		if tokens[i+1].text == "AS" && tokens[i+2].text == "(" {
+			defined[identifier(tokens[i].text)] = true
 		}
-		
-		// Check for time range in WHERE clause (recommended)
-		if !strings.Contains(upperQuery, "WHERE") || !strings.Contains(upperQuery, "TIMESTAMP") {
-			log.Warn().Msg("Query on logs table without timestamp filter may be slow")
+	}
+
+	for _, p := range tablePositions(tokens) {
+		next := tokens[p]
+		if next.text == "(" || (p+1 < len(tokens) && tokens[p+1].text == "(") {
+			continue
+		}
+		if p+1 < len(tokens) && tokens[p+1].text == "." {
+			return fmt.Errorf("tables of other databases cannot be queried")
+		}
+		name := identifier(next.text)
+		if defined[name] {
+			continue
+		}
+		if !v.tables.Has(name) {
+			return fmt.Errorf("unknown table: %s", strings.ToLower(name))
 		}
 	}
-	
 	return nil
 }
 
-// isAggregationQuery checks if query is an aggregation
-func (v *Validator) isAggregationQuery(query string) bool {
-	aggregateFunctions := []string{
-		"COUNT(", "SUM(", "AVG(", "MIN(", "MAX(",
-		"GROUP BY", "HAVING",
+// tablePositions returns the indexes of the tokens naming what a SELECT
+// reads: the ones after FROM, after JOIN and after the commas separating
+// the tables of a FROM clause
+func tablePositions(tokens []sqlToken) []int {
+	var positions []int
+	for i, tok := range tokens {
+		if i+1 >= len(tokens) {
+			break
+		}
+		switch tok.text {
+		case "FROM":
+			if !inSelect(tokens, i) {
+				continue // e.g. EXTRACT(YEAR FROM timestamp)
+			}
+		case "JOIN":
+			if i > 0 && tokens[i-1].text == "ARRAY" {
+				continue // ARRAY JOIN takes a column
+			}
+		case ",":
+			if !inFromClause(tokens, i) {
+				continue
+			}
+		default:
+			continue
+		}
+		positions = append(positions, i+1)
+	}
+	return positions
+}
+
+// inFromClause reports whether the comma at i separates the tables of a
+// FROM clause
+func inFromClause(tokens []sqlToken, i int) bool {
+	depth := tokens[i].depth
+	for j := i - 1; j >= 0; j-- {
+		if tokens[j].depth < depth {
+			return false
+		}
+		if tokens[j].depth > depth {
+			continue
+		}
+		if tokens[j].text == "FROM" {
+			return inSelect(tokens, j)
+		}
+		if fromClauseEnd[tokens[j].text] {
+			return false
+		}
 	}
-	
-	for _, fn := range aggregateFunctions {
-		if strings.Contains(query, fn) {
+	return false
+}
+
+// identifier returns the name of a word or quoted identifier token
+func identifier(text string) string {
+	return strings.TrimPrefix(text, `"`)
+}
+
+// inSelect reports whether the token at i belongs to a SELECT at its own
+// parenthesis level rather than to a function call
+func inSelect(tokens []sqlToken, i int) bool {
+	depth := tokens[i].depth
+	for j := i - 1; j >= 0; j-- {
+		if tokens[j].depth < depth {
+			return false
+		}
+		if tokens[j].depth == depth && tokens[j].text == "SELECT" {
 			return true
 		}
 	}
-	
 	return false
 }
 
+// validateLogsQuery performs specific validation for queries on logs table
+func (v *Validator) validateLogsQuery(tokens []sqlToken) {
+	readsLogs, hasTimeFilter := false, false
+	for i, tok := range tokens {
+		if tok.text == "FROM" && i+1 < len(tokens) && strings.TrimPrefix(tokens[i+1].text, `"`) == "LOGS" {
+			readsLogs = true
+		}
+		if tok.text == "TIMESTAMP" {
+			hasTimeFilter = true
+		}
+	}
+
+	// Check for time range in WHERE clause (recommended)
+	if readsLogs && !hasTimeFilter {
+		log.Warn().Msg("Query on logs table without timestamp filter may be slow")
+	}
+}
+
 // ValidateParameterName validates a parameter name
 func (v *Validator) ValidateParameterName(name string) error {
 	if name == "" {
 		return fmt.Errorf("parameter name cannot be empty")
 	}
-	
+
 	// Only allow alphanumeric and underscore
 	if !regexp.MustCompile(`^[a-zA-Z0-9_]+$`).MatchString(name) {
 		return fmt.Errorf("parameter name can only contain letters, numbers, and underscores")
 	}
-	
+
 	// Check length
 	if len(name) > 64 {
 		return fmt.Errorf("parameter name too long (max 64 characters)")
 	}
-	
+
 	return nil
-}
\ No newline at end of file
+}
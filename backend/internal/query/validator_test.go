@@ -0,0 +1,62 @@
+package query
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/tables"
+)
+
+func TestValidatorTableFunctions(t *testing.T) {
+	v := NewValidator()
+	v.SetTables(tables.NewRegistry())
+
+	rejected := []string{
+		"SELECT * FROM url('http://attacker/x', CSV, 'a String')",
+		"SELECT * FROM `url`('http://attacker/x', CSV, 'a String')",
+		`SELECT * FROM "url"('http://attacker/x', CSV, 'a String')`,
+		"SELECT * FROM s3Cluster('default', 'https://bucket/x.csv')",
+		"SELECT * FROM urlCluster('default', 'http://attacker/x')",
+		"SELECT * FROM `hdfsCluster`('default', 'hdfs://x')",
+		"SELECT * FROM fileCluster('default', 'x.csv')",
+		"SELECT * FROM azureBlobStorage('x')",
+		"SELECT * FROM gcs('https://storage.googleapis.com/x')",
+		"SELECT * FROM mongodb('host', 'db', 'c', 'u', 'p', 'a String')",
+		"SELECT * FROM logs, url('http://attacker/x', CSV, 'a String')",
+		"SELECT * FROM logs JOIN sqlite('x.db', 't') AS s ON logs.id = s.id",
+		"SELECT * FROM (SELECT * FROM redis('host', 'k', 'k String'))",
+		"SELECT * FROM default.url('http://attacker/x')",
+		"SELECT file('/etc/passwd')",
+	}
+	for _, query := range rejected {
+		if err := v.Validate(query); err == nil {
+			t.Errorf("Validate(%q) accepted a query reading outside the database", query)
+		}
+		if err := v.ValidateFor(query, true); err == nil {
+			t.Errorf("ValidateFor(%q, admin) accepted a query reading outside the database", query)
+		}
+	}
+
+	accepted := []string{
+		"SELECT number FROM numbers(10)",
+		"SELECT * FROM `numbers`(10)",
+		"SELECT level, count() FROM logs GROUP BY level ORDER BY level, count() LIMIT 10, 20",
+		"SELECT * FROM logs ARRAY JOIN mapKeys(attributes) AS key, mapValues(attributes) AS value",
+		"SELECT toYear(timestamp), EXTRACT(YEAR FROM timestamp) FROM logs",
+	}
+	for _, query := range accepted {
+		if err := v.Validate(query); err != nil {
+			t.Errorf("Validate(%q) = %v", query, err)
+		}
+	}
+}
+
+func TestValidatorCommaJoinedTables(t *testing.T) {
+	v := NewValidator()
+	v.SetTables(tables.NewRegistry())
+
+	err := v.Validate("SELECT * FROM logs, secrets")
+	if err == nil || !strings.Contains(err.Error(), "unknown table") {
+		t.Errorf("Validate of a comma-joined unknown table = %v, want unknown table", err)
+	}
+}
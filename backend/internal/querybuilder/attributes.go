@@ -0,0 +1,172 @@
+package querybuilder
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/parsing"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/tables"
+)
+
+// attributesColumn is the Map(String, String) column holding log attributes
+const attributesColumn = "attributes"
+
+// maxAttributeSuggestions caps the attribute fields offered, most frequent first
+const maxAttributeSuggestions = 200
+
+// attributeFieldPattern matches attributes['key']
+var attributeFieldPattern = regexp.MustCompile(`^attributes\['([^'\\]*)'\]$`)
+
+// aliasPattern matches what cannot appear in a generated alias
+var aliasPattern = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// SetSchema sets the registry of attributes seen during ingestion, which
+// attribute fields are suggested from
+func (s *Service) SetSchema(schema *parsing.SchemaRegistry) {
+	s.schema = schema
+}
+
+// GetAttributeFields returns the attributes of a table that can be used as
+// fields, named attributes.<key> and typed as most often observed. Tables
+// without an attributes map have none.
+func (s *Service) GetAttributeFields(table string) []models.QueryField {
+	fields := []models.QueryField{}
+	if s.schema == nil || !s.hasAttributes(table) {
+		return fields
+	}
+	for _, attr := range s.schema.List("") {
+		if len(fields) >= maxAttributeSuggestions {
+			break
+		}
+		if validAttributeKey(attr.Name) != nil {
+			continue
+		}
+		fields = append(fields, models.QueryField{
+			Name:  attributeFieldName(attr.Name),
+			Type:  attributeFieldType(attr.Type),
+			Label: attr.Name,
+		})
+	}
+	return fields
+}
+
+// hasAttributes reports whether a table has the attributes map
+func (s *Service) hasAttributes(table string) bool {
+	table = tableName(table)
+	if table == tables.DefaultTable {
+		return true
+	}
+	if s.tables == nil {
+		return false
+	}
+	t, exists := s.tables.Get(table)
+	if !exists {
+		return false
+	}
+	for _, column := range t.Columns {
+		if column.Name == attributesColumn {
+			return strings.HasPrefix(column.Type, "Map(")
+		}
+	}
+	return false
+}
+
+// parseAttributeField returns the key of an attribute field, written as
+// attributes.<key> or attributes['key']
+func parseAttributeField(name string) (string, bool, error) {
+	var key string
+	if m := attributeFieldPattern.FindStringSubmatch(name); m != nil {
+		key = m[1]
+	} else if strings.HasPrefix(name, attributesColumn+".") {
+		key = strings.TrimPrefix(name, attributesColumn+".")
+	} else {
+		return "", false, nil
+	}
+	if err := validAttributeKey(key); err != nil {
+		return "", true, err
+	}
+	return key, true, nil
+}
+
+// validAttributeKey rejects keys that cannot be written as a plain SQL
+// string literal and backquoted alias
+func validAttributeKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("attribute field needs a key")
+	}
+	if len(key) > 256 {
+		return fmt.Errorf("attribute key too long: %s", key[:32]+"...")
+	}
+	for _, c := range key {
+		if c == '\'' || c == '\\' || c == '`' || c < ' ' {
+			return fmt.Errorf("invalid character in attribute key: %q", key)
+		}
+	}
+	return nil
+}
+
+// attributeFieldName returns the canonical name of an attribute field,
+// which is also its column name in results
+func attributeFieldName(key string) string {
+	return attributesColumn + "." + key
+}
+
+// attributeFieldType maps a schema registry type to a field type
+func attributeFieldType(attrType string) string {
+	switch attrType {
+	case "number", "boolean":
+		return attrType
+	case "timestamp":
+		return "date"
+	default:
+		return "string"
+	}
+}
+
+// attributeExpression reads an attribute, stored as a string, as fieldType.
+// Values that do not parse are NULL, so aggregates skip them.
+func attributeExpression(key, fieldType string) (string, error) {
+	value := fmt.Sprintf("%s['%s']", attributesColumn, key)
+	switch fieldType {
+	case "", "string":
+		return value, nil
+	case "number":
+		return "toFloat64OrNull(" + value + ")", nil
+	case "boolean":
+		return "(" + value + " = 'true')", nil
+	case "date":
+		return "parseDateTime64BestEffortOrNull(" + value + ")", nil
+	default:
+		return "", fmt.Errorf("unsupported attribute type: %s", fieldType)
+	}
+}
+
+// FieldExpression returns the SQL reading a field of table, casting
+// attribute fields to fieldType. Columns are returned as they are.
+func (s *Service) FieldExpression(table, name, fieldType string) (string, error) {
+	key, isAttribute, err := parseAttributeField(name)
+	if err != nil {
+		return "", err
+	}
+	if !isAttribute {
+		fields, err := s.fieldMap(table)
+		if err != nil {
+			return "", err
+		}
+		if !fields[name] {
+			return "", fmt.Errorf("unknown field: %s", name)
+		}
+		return name, nil
+	}
+	if !s.hasAttributes(table) {
+		return "", fmt.Errorf("table %s has no attributes", tableName(table))
+	}
+	return attributeExpression(key, fieldType)
+}
+
+// quoteAlias backquotes a result column name
+func quoteAlias(name string) string {
+	return "`" + name + "`"
+}
@@ -1,22 +1,38 @@
 package querybuilder
 
 import (
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/parsing"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/tables"
 )
 
+// maxFilterDepth bounds how deep filter groups nest
+const maxFilterDepth = 5
+
+// maxFreshness bounds how stale a configuration may accept results to be
+const maxFreshness = 3600
+
 // Service handles query builder operations
 type Service struct {
 	availableFields []models.QueryField
+	tables          *tables.Registry
+	schema          *parsing.SchemaRegistry
 }
 
-// NewService creates a new query builder service
-func NewService() *Service {
+// NewService creates a new query builder service. Tables other than logs
+// are looked up in registry, which may be nil.
+func NewService(registry *tables.Registry) *Service {
 	return &Service{
 		availableFields: getAvailableFields(),
+		tables:          registry,
 	}
 }
 
@@ -25,10 +41,94 @@ func (s *Service) GetAvailableFields() []models.QueryField {
 	return s.availableFields
 }
 
+// GetTableFields returns the fields of a table; the logs table, which is
+// also used when table is empty, has a curated list with labels
+func (s *Service) GetTableFields(table string) ([]models.QueryField, error) {
+	if table == "" || table == tables.DefaultTable {
+		return s.availableFields, nil
+	}
+	if s.tables == nil {
+		return nil, fmt.Errorf("unknown table: %s", table)
+	}
+	t, exists := s.tables.Get(table)
+	if !exists {
+		return nil, fmt.Errorf("unknown table: %s", table)
+	}
+
+	fields := make([]models.QueryField, 0, len(t.Columns))
+	for _, column := range t.Columns {
+		fields = append(fields, models.QueryField{
+			Name: column.Name,
+			Type: fieldType(column.Type),
+		})
+	}
+	return fields, nil
+}
+
+// GetTables returns the tables that can be queried
+func (s *Service) GetTables() []string {
+	if s.tables == nil {
+		return []string{tables.DefaultTable}
+	}
+	names := []string{}
+	for _, table := range s.tables.List() {
+		names = append(names, table.Name)
+	}
+	return names
+}
+
+// tableName returns the table a configuration reads, logs by default
+func tableName(table string) string {
+	if table == "" {
+		return tables.DefaultTable
+	}
+	return table
+}
+
+// timeColumn returns the column time ranges of a table filter on
+func (s *Service) timeColumn(table string) (string, error) {
+	table = tableName(table)
+	if s.tables != nil {
+		if t, exists := s.tables.Get(table); exists {
+			if t.TimeColumn == "" {
+				return "", fmt.Errorf("table %s has no time column", table)
+			}
+			return t.TimeColumn, nil
+		}
+	}
+	return "timestamp", nil
+}
+
+// fieldType maps a ClickHouse column type to a query builder field type
+func fieldType(columnType string) string {
+	base := strings.TrimPrefix(columnType, "LowCardinality(")
+	base = strings.TrimPrefix(base, "Nullable(")
+	switch {
+	case strings.HasPrefix(base, "Date"):
+		return "date"
+	case strings.HasPrefix(base, "Int"), strings.HasPrefix(base, "UInt"),
+		strings.HasPrefix(base, "Float"), strings.HasPrefix(base, "Decimal"):
+		return "number"
+	case strings.HasPrefix(base, "Bool"):
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
 // GenerateSQL converts a QueryBuilder configuration to SQL
 func (s *Service) GenerateSQL(qb *models.QueryBuilder) (string, error) {
 	var parts []string
 
+	// WITH clause ranking the groups of a top-N
+	if qb.TopN != nil {
+		withClause, err := s.buildTopNWith(qb)
+		if err != nil {
+			return "", fmt.Errorf("failed to build top-N: %w", err)
+		}
+		parts = append(parts, withClause)
+	}
+
 	// SELECT clause
 	selectClause, err := s.buildSelectClause(qb)
 	if err != nil {
@@ -37,7 +137,18 @@ func (s *Service) GenerateSQL(qb *models.QueryBuilder) (string, error) {
 	parts = append(parts, selectClause)
 
 	// FROM clause
-	parts = append(parts, "FROM logs")
+	table, err := quoteIdentifier(tableName(qb.Table))
+	if err != nil {
+		return "", err
+	}
+	parts = append(parts, "FROM "+table)
+
+	// JOIN clauses for lookups
+	joinClauses, err := s.buildJoinClauses(qb)
+	if err != nil {
+		return "", fmt.Errorf("failed to build JOIN clause: %w", err)
+	}
+	parts = append(parts, joinClauses...)
 
 	// WHERE clause
 	if len(qb.Filters) > 0 || qb.TimeRange != nil {
@@ -51,14 +162,29 @@ func (s *Service) GenerateSQL(qb *models.QueryBuilder) (string, error) {
 	}
 
 	// GROUP BY clause
-	if len(qb.GroupBy) > 0 {
-		groupByClause := s.buildGroupByClause(qb.GroupBy)
+	if len(qb.GroupBy) > 0 || qb.TimeBucket != nil {
+		groupByClause, err := s.buildGroupByClause(qb)
+		if err != nil {
+			return "", fmt.Errorf("failed to build GROUP BY clause: %w", err)
+		}
 		parts = append(parts, "GROUP BY "+groupByClause)
 	}
 
+	// HAVING clause
+	if len(qb.Having) > 0 {
+		havingClause, err := s.buildFilterGroup(qb.Having)
+		if err != nil {
+			return "", fmt.Errorf("failed to build HAVING clause: %w", err)
+		}
+		parts = append(parts, "HAVING "+havingClause)
+	}
+
 	// ORDER BY clause
-	if len(qb.OrderBy) > 0 {
-		orderByClause := s.buildOrderByClause(qb.OrderBy)
+	if len(qb.OrderBy) > 0 || qb.TimeBucket != nil {
+		orderByClause, err := s.buildOrderByClause(qb)
+		if err != nil {
+			return "", fmt.Errorf("failed to build ORDER BY clause: %w", err)
+		}
 		parts = append(parts, "ORDER BY "+orderByClause)
 	}
 
@@ -77,50 +203,357 @@ func (s *Service) ValidateQueryBuilder(qb *models.QueryBuilder) error {
 		return fmt.Errorf("query name is required")
 	}
 
-	// Validate fields
-	availableFieldMap := make(map[string]bool)
-	for _, field := range s.availableFields {
-		availableFieldMap[field.Name] = true
+	// Validate fields against the table's schema
+	availableFieldMap, err := s.fieldMap(qb.Table)
+	if err != nil {
+		return err
+	}
+
+	if err := s.validateJoins(qb, availableFieldMap); err != nil {
+		return err
 	}
 
 	for _, field := range qb.Fields {
-		if field.Selected && !availableFieldMap[field.Name] {
+		if field.Selected && !s.isField(qb.Table, availableFieldMap, field.Name, field.Type) {
 			return fmt.Errorf("unknown field: %s", field.Name)
 		}
 	}
 
 	// Validate filters
-	for _, filter := range qb.Filters {
-		if !availableFieldMap[filter.Field] {
-			return fmt.Errorf("unknown field in filter: %s", filter.Field)
+	if err := s.validateFilters(qb.Filters, 1, s.filterFieldCheck(qb.Table, availableFieldMap)); err != nil {
+		return err
+	}
+
+	if err := s.validateHaving(qb); err != nil {
+		return err
+	}
+
+	if err := s.validateTimeRange(qb.TimeRange); err != nil {
+		return err
+	}
+
+	if err := s.validateTimeBucket(qb); err != nil {
+		return err
+	}
+
+	if err := s.validateTopN(qb); err != nil {
+		return err
+	}
+
+	if err := s.validateComputed(qb, availableFieldMap); err != nil {
+		return err
+	}
+
+	// Validate aliases and ordering, which are written into the query
+	for _, agg := range qb.Aggregations {
+		if alias := aggregationAlias(agg); !identifierPattern.MatchString(alias) {
+			return fmt.Errorf("invalid aggregation alias: %s", alias)
 		}
-		if err := s.validateFilterOperator(filter.Operator); err != nil {
+	}
+	for _, order := range qb.OrderBy {
+		if _, err := orderDirection(order.Direction); err != nil {
 			return err
 		}
 	}
 
+	if qb.Freshness < 0 || qb.Freshness > maxFreshness {
+		return fmt.Errorf("freshness must be between 0 and %d seconds", maxFreshness)
+	}
+
 	// Validate aggregations
 	for _, agg := range qb.Aggregations {
 		if err := s.validateAggregationFunction(agg.Function); err != nil {
 			return err
 		}
-		if agg.Field != "" && !availableFieldMap[agg.Field] {
+		if agg.Field != "" && !s.isField(qb.Table, availableFieldMap, agg.Field, agg.Type) {
 			return fmt.Errorf("unknown field in aggregation: %s", agg.Field)
 		}
 	}
 
+	// Attribute and lookup fields may be grouped on without being selected
+	for _, name := range qb.GroupBy {
+		_, isAttribute, _ := parseAttributeField(name)
+		_, _, isLookup := parseLookupField(name)
+		if (isAttribute || isLookup) && !s.isField(qb.Table, availableFieldMap, name, "") {
+			return fmt.Errorf("unknown field in group by: %s", name)
+		}
+	}
+
 	return nil
 }
 
+// BuildFilters validates filters against a table and joins their conditions, with AND when a
+// filter does not specify its logical operator
+func (s *Service) BuildFilters(table string, filters []models.QueryBuilderFilter) (string, error) {
+	availableFieldMap, err := s.fieldMap(table)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.validateFilters(filters, 1, s.filterFieldCheck(table, availableFieldMap)); err != nil {
+		return "", err
+	}
+	return s.buildFilterGroup(filters)
+}
+
+// validateFilters checks the fields, with checkField, and operators of
+// filters and of the groups nested in them, at most maxFilterDepth deep
+func (s *Service) validateFilters(filters []models.QueryBuilderFilter, depth int, checkField func(name, fieldType string) error) error {
+	if depth > maxFilterDepth {
+		return fmt.Errorf("filter groups nest more than %d deep", maxFilterDepth)
+	}
+	for _, filter := range filters {
+		if op := strings.ToUpper(filter.LogicalOp); op != "" && op != "AND" && op != "OR" {
+			return fmt.Errorf("invalid logical operator: %s", filter.LogicalOp)
+		}
+		if isFilterGroup(filter) {
+			if filter.Field != "" {
+				return fmt.Errorf("filter group cannot also filter on %s", filter.Field)
+			}
+			if err := s.validateFilters(filter.Filters, depth+1, checkField); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := checkField(filter.Field, filter.Type); err != nil {
+			return err
+		}
+		if err := s.validateFilterOperator(filter.Operator); err != nil {
+			return err
+		}
+		if filter.Operator == "matches_regex" || filter.Operator == "not_matches_regex" {
+			if _, err := regexValue(filter.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// regexValue returns the pattern of a regex filter. ClickHouse uses RE2,
+// the syntax of Go's regexp, so a pattern compiling here is one it accepts.
+func regexValue(value interface{}) (string, error) {
+	pattern, ok := value.(string)
+	if !ok || pattern == "" {
+		return "", fmt.Errorf("regex filter needs a pattern")
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return "", fmt.Errorf("invalid regex %q: %w", pattern, err)
+	}
+	return pattern, nil
+}
+
+// filterFieldCheck accepts the fields of table in WHERE filters
+func (s *Service) filterFieldCheck(table string, fields map[string]bool) func(name, fieldType string) error {
+	return func(name, fieldType string) error {
+		if !s.isField(table, fields, name, fieldType) {
+			return fmt.Errorf("unknown field in filter: %s", name)
+		}
+		return nil
+	}
+}
+
+// validateHaving checks that HAVING filters only refer to aggregation
+// aliases and plain GROUP BY columns, the names that exist after grouping
+func (s *Service) validateHaving(qb *models.QueryBuilder) error {
+	if len(qb.Having) == 0 {
+		return nil
+	}
+	if len(qb.Aggregations) == 0 && len(qb.GroupBy) == 0 && qb.TimeBucket == nil {
+		return fmt.Errorf("having requires aggregations or group by")
+	}
+
+	aliases := make(map[string]bool, len(qb.Aggregations)+len(qb.GroupBy))
+	for _, agg := range qb.Aggregations {
+		aliases[aggregationAlias(agg)] = true
+	}
+	for _, name := range qb.GroupBy {
+		if _, isAttribute, _ := parseAttributeField(name); !isAttribute && !isTopNField(qb, name) {
+			aliases[name] = true
+		}
+	}
+	if qb.TimeBucket != nil {
+		aliases[timeBucketAlias(qb.TimeBucket)] = true
+	}
+	if qb.TopN != nil {
+		aliases[topNAlias(qb.TopN)] = true
+	}
+	for _, computed := range qb.Computed {
+		aliases[computed.Name] = true
+	}
+
+	return s.validateFilters(qb.Having, 1, func(name, fieldType string) error {
+		if !aliases[name] {
+			return fmt.Errorf("unknown alias in having: %s", name)
+		}
+		if fieldType != "" {
+			return fmt.Errorf("having on %s cannot set a type", name)
+		}
+		return nil
+	})
+}
+
+// buildFilterGroup joins the conditions of filters, each with the logical
+// operator of its filter and AND when it has none. Nested groups are
+// parenthesized, so (a AND b) OR (c AND d) keeps its meaning.
+func (s *Service) buildFilterGroup(filters []models.QueryBuilderFilter) (string, error) {
+	var parts []string
+	for _, filter := range filters {
+		var condition string
+		var err error
+		if isFilterGroup(filter) {
+			condition, err = s.buildFilterGroup(filter.Filters)
+			if len(filter.Filters) > 1 {
+				condition = "(" + condition + ")"
+			}
+		} else {
+			condition, err = s.buildFilterCondition(filter)
+		}
+		if err != nil {
+			return "", err
+		}
+		if condition == "" {
+			continue
+		}
+		if len(parts) > 0 {
+			op := strings.ToUpper(filter.LogicalOp)
+			if op != "OR" {
+				op = "AND"
+			}
+			parts = append(parts, op)
+		}
+		parts = append(parts, condition)
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+// isFilterGroup reports whether a filter is a group of nested filters
+// rather than a condition
+func isFilterGroup(filter models.QueryBuilderFilter) bool {
+	return len(filter.Filters) > 0
+}
+
+// TimeColumn returns the column time ranges of a table filter on
+func (s *Service) TimeColumn(table string) (string, error) {
+	return s.timeColumn(table)
+}
+
+// IsAvailableField reports whether a field of table may be used in
+// queries, either a column or an attribute of a table with attributes
+func (s *Service) IsAvailableField(table, name string) bool {
+	_, err := s.FieldExpression(table, name, "")
+	return err == nil
+}
+
+// isField reports whether name is a column in fields or an attribute of
+// table readable as fieldType
+func (s *Service) isField(table string, fields map[string]bool, name, fieldType string) bool {
+	if _, isAttribute, _ := parseAttributeField(name); !isAttribute {
+		return fields[name]
+	}
+	_, err := s.FieldExpression(table, name, fieldType)
+	return err == nil
+}
+
+// fieldSQL returns the SQL reading a field, casting attribute fields to
+// fieldType and qualifying lookup fields. Other names are read as columns.
+func fieldSQL(name, fieldType string) (string, error) {
+	key, isAttribute, err := parseAttributeField(name)
+	if err != nil {
+		return "", err
+	}
+	if lookup, column, isLookup := parseLookupField(name); isLookup {
+		return lookup + "." + column, nil
+	}
+	if !isAttribute {
+		return quoteIdentifier(name)
+	}
+	return attributeExpression(key, fieldType)
+}
+
+// columnSQL returns the SQL of a field in GROUP BY and ORDER BY: the top-N
+// field and selected attribute fields by their alias, other attributes as
+// strings and lookup fields qualified
+func columnSQL(qb *models.QueryBuilder, name string) (string, error) {
+	if isTopNField(qb, name) {
+		return topNAlias(qb.TopN), nil
+	}
+	key, isAttribute, err := parseAttributeField(name)
+	if err != nil {
+		return "", err
+	}
+	if !isAttribute {
+		return fieldSQL(name, "")
+	}
+	for _, field := range qb.Fields {
+		if fieldKey, ok, _ := parseAttributeField(field.Name); field.Selected && ok && fieldKey == key {
+			return quoteAlias(attributeFieldName(key)), nil
+		}
+	}
+	return attributeExpression(key, "")
+}
+
+func (s *Service) fieldMap(table string) (map[string]bool, error) {
+	fields, err := s.GetTableFields(table)
+	if err != nil {
+		return nil, err
+	}
+	fieldMap := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		fieldMap[field.Name] = true
+	}
+	return fieldMap, nil
+}
+
 // buildSelectClause builds the SELECT part of the SQL query
 func (s *Service) buildSelectClause(qb *models.QueryBuilder) (string, error) {
 	var columns []string
 
+	// Add the time bucket first, as charts expect
+	if qb.TimeBucket != nil {
+		column, err := s.buildTimeBucketColumn(qb)
+		if err != nil {
+			return "", err
+		}
+		columns = append(columns, column)
+	}
+
+	// The top-N field is replaced by its groups, selected or not
+	if qb.TopN != nil {
+		column, err := s.buildTopNColumn(qb)
+		if err != nil {
+			return "", err
+		}
+		columns = append(columns, column)
+	}
+
 	// Add selected fields
 	for _, field := range qb.Fields {
-		if field.Selected {
-			columns = append(columns, field.Name)
+		if !field.Selected || isTopNField(qb, field.Name) {
+			continue
+		}
+		key, isAttribute, err := parseAttributeField(field.Name)
+		if err != nil {
+			return "", err
 		}
+		if lookup, column, isLookup := parseLookupField(field.Name); isLookup {
+			columns = append(columns, lookup+"."+column+" AS "+quoteAlias(field.Name))
+			continue
+		}
+		if !isAttribute {
+			column, err := quoteIdentifier(field.Name)
+			if err != nil {
+				return "", err
+			}
+			columns = append(columns, column)
+			continue
+		}
+		expr, err := attributeExpression(key, field.Type)
+		if err != nil {
+			return "", err
+		}
+		columns = append(columns, expr+" AS "+quoteAlias(attributeFieldName(key)))
 	}
 
 	// Add aggregations
@@ -132,6 +565,13 @@ func (s *Service) buildSelectClause(qb *models.QueryBuilder) (string, error) {
 		columns = append(columns, aggSQL)
 	}
 
+	// Add computed fields, which may use the aliases above
+	computed, err := s.buildComputedColumns(qb)
+	if err != nil {
+		return "", err
+	}
+	columns = append(columns, computed...)
+
 	if len(columns) == 0 {
 		columns = append(columns, "*")
 	}
@@ -145,7 +585,11 @@ func (s *Service) buildWhereClause(qb *models.QueryBuilder) (string, error) {
 
 	// Add time range filter
 	if qb.TimeRange != nil {
-		timeCondition, err := s.buildTimeRangeCondition(qb.TimeRange)
+		column, err := s.timeColumn(qb.Table)
+		if err != nil {
+			return "", err
+		}
+		timeCondition, err := s.buildTimeRangeCondition(column, qb.TimeRange)
 		if err != nil {
 			return "", err
 		}
@@ -154,25 +598,30 @@ func (s *Service) buildWhereClause(qb *models.QueryBuilder) (string, error) {
 		}
 	}
 
-	// Add custom filters
-	for i, filter := range qb.Filters {
-		condition, err := s.buildFilterCondition(filter)
+	// Add custom filters, parenthesized so an OR among them does not
+	// escape the time range
+	if len(qb.Filters) > 0 {
+		filters, err := s.buildFilterGroup(qb.Filters)
 		if err != nil {
 			return "", err
 		}
-
-		if i > 0 && filter.LogicalOp != "" {
-			condition = filter.LogicalOp + " " + condition
+		if filters != "" && len(conditions) > 0 {
+			filters = "(" + filters + ")"
+		}
+		if filters != "" {
+			conditions = append(conditions, filters)
 		}
-		conditions = append(conditions, condition)
 	}
 
-	return strings.Join(conditions, " "), nil
+	return strings.Join(conditions, " AND "), nil
 }
 
 // buildFilterCondition builds a single filter condition
 func (s *Service) buildFilterCondition(filter models.QueryBuilderFilter) (string, error) {
-	field := filter.Field
+	field, err := fieldSQL(filter.Field, filter.Type)
+	if err != nil {
+		return "", err
+	}
 	operator := filter.Operator
 	value := filter.Value
 
@@ -181,10 +630,23 @@ func (s *Service) buildFilterCondition(filter models.QueryBuilderFilter) (string
 		return fmt.Sprintf("%s = %s", field, s.formatValue(value)), nil
 	case "not_equals":
 		return fmt.Sprintf("%s != %s", field, s.formatValue(value)), nil
+	// Substrings are found with position(), where LIKE would read % and _
+	// in the value as wildcards
 	case "contains":
-		return fmt.Sprintf("%s LIKE %s", field, s.formatValue("%"+fmt.Sprintf("%v", value)+"%")), nil
+		return fmt.Sprintf("position(%s, %s) > 0", field, s.formatValue(fmt.Sprintf("%v", value))), nil
 	case "not_contains":
-		return fmt.Sprintf("%s NOT LIKE %s", field, s.formatValue("%"+fmt.Sprintf("%v", value)+"%")), nil
+		return fmt.Sprintf("position(%s, %s) = 0", field, s.formatValue(fmt.Sprintf("%v", value))), nil
+	case "icontains":
+		return fmt.Sprintf("positionCaseInsensitive(%s, %s) > 0", field, s.formatValue(fmt.Sprintf("%v", value))), nil
+	case "matches_regex", "not_matches_regex":
+		pattern, err := regexValue(value)
+		if err != nil {
+			return "", err
+		}
+		if operator == "not_matches_regex" {
+			return fmt.Sprintf("NOT match(%s, %s)", field, s.formatValue(pattern)), nil
+		}
+		return fmt.Sprintf("match(%s, %s)", field, s.formatValue(pattern)), nil
 	case "greater_than":
 		return fmt.Sprintf("%s > %s", field, s.formatValue(value)), nil
 	case "less_than":
@@ -227,18 +689,10 @@ func (s *Service) buildFilterCondition(filter models.QueryBuilderFilter) (string
 }
 
 // buildTimeRangeCondition builds time range filter condition
-func (s *Service) buildTimeRangeCondition(timeRange *models.QueryTimeRange) (string, error) {
-	var start, end time.Time
-
-	if timeRange.Relative != "" {
-		var err error
-		start, end, err = s.parseRelativeTimeRange(timeRange.Relative)
-		if err != nil {
-			return "", err
-		}
-	} else {
-		start = timeRange.Start
-		end = timeRange.End
+func (s *Service) buildTimeRangeCondition(column string, timeRange *models.QueryTimeRange) (string, error) {
+	start, end, err := s.ResolveTimeRange(timeRange)
+	if err != nil {
+		return "", err
 	}
 
 	if start.IsZero() && end.IsZero() {
@@ -247,109 +701,194 @@ func (s *Service) buildTimeRangeCondition(timeRange *models.QueryTimeRange) (str
 
 	var conditions []string
 	if !start.IsZero() {
-		conditions = append(conditions, fmt.Sprintf("timestamp >= '%s'", start.Format("2006-01-02 15:04:05")))
+		conditions = append(conditions, fmt.Sprintf("%s >= '%s'", column, start.Format("2006-01-02 15:04:05")))
 	}
 	if !end.IsZero() {
-		conditions = append(conditions, fmt.Sprintf("timestamp <= '%s'", end.Format("2006-01-02 15:04:05")))
+		conditions = append(conditions, fmt.Sprintf("%s <= '%s'", column, end.Format("2006-01-02 15:04:05")))
 	}
 
 	return strings.Join(conditions, " AND "), nil
 }
 
-// parseRelativeTimeRange converts relative time range to absolute times
-func (s *Service) parseRelativeTimeRange(relative string) (time.Time, time.Time, error) {
-	now := time.Now()
-	var start time.Time
-
-	switch relative {
-	case "last_1h":
-		start = now.Add(-1 * time.Hour)
-	case "last_24h":
-		start = now.Add(-24 * time.Hour)
-	case "last_7d":
-		start = now.Add(-7 * 24 * time.Hour)
-	case "last_30d":
-		start = now.Add(-30 * 24 * time.Hour)
-	case "last_1m":
-		start = now.Add(-1 * time.Minute)
-	case "last_5m":
-		start = now.Add(-5 * time.Minute)
-	case "last_15m":
-		start = now.Add(-15 * time.Minute)
-	default:
-		return time.Time{}, time.Time{}, fmt.Errorf("unsupported relative time range: %s", relative)
+// buildAggregationSQL builds SQL for aggregation functions
+func (s *Service) buildAggregationSQL(agg models.QueryAggregation) (string, error) {
+	expr, err := s.aggregationExpression(agg)
+	if err != nil {
+		return "", err
 	}
-
-	return start, now, nil
+	alias := aggregationAlias(agg)
+	if !identifierPattern.MatchString(alias) {
+		return "", fmt.Errorf("invalid aggregation alias: %s", alias)
+	}
+	return expr + " AS " + alias, nil
 }
 
-// buildAggregationSQL builds SQL for aggregation functions
-func (s *Service) buildAggregationSQL(agg models.QueryAggregation) (string, error) {
-	alias := agg.Alias
-	if alias == "" {
-		alias = fmt.Sprintf("%s_%s", strings.ToLower(agg.Function), agg.Field)
+// aggregationExpression builds the SQL computing an aggregation
+func (s *Service) aggregationExpression(agg models.QueryAggregation) (string, error) {
+	// Attributes are strings, so arithmetic on them needs a number cast
+	fieldType := agg.Type
+	if fieldType == "" && (agg.Function == "SUM" || agg.Function == "AVG") {
+		fieldType = "number"
+	}
+	var field string
+	if agg.Field != "" {
+		var err error
+		field, err = fieldSQL(agg.Field, fieldType)
+		if err != nil {
+			return "", err
+		}
 	}
 
 	switch agg.Function {
 	case "COUNT":
 		if agg.Field == "" {
-			return fmt.Sprintf("COUNT(*) AS %s", alias), nil
+			return "COUNT(*)", nil
 		}
-		return fmt.Sprintf("COUNT(%s) AS %s", agg.Field, alias), nil
+		return fmt.Sprintf("COUNT(%s)", field), nil
 	case "COUNT_DISTINCT":
 		if agg.Field == "" {
 			return "", fmt.Errorf("COUNT_DISTINCT requires a field")
 		}
-		return fmt.Sprintf("COUNT(DISTINCT %s) AS %s", agg.Field, alias), nil
+		return fmt.Sprintf("COUNT(DISTINCT %s)", field), nil
 	case "SUM", "AVG", "MIN", "MAX":
 		if agg.Field == "" {
 			return "", fmt.Errorf("%s requires a field", agg.Function)
 		}
-		return fmt.Sprintf("%s(%s) AS %s", agg.Function, agg.Field, alias), nil
+		return fmt.Sprintf("%s(%s)", agg.Function, field), nil
 	default:
 		return "", fmt.Errorf("unsupported aggregation function: %s", agg.Function)
 	}
 }
 
+// aggregationAlias returns the result column name of an aggregation
+func aggregationAlias(agg models.QueryAggregation) string {
+	if agg.Alias != "" {
+		return agg.Alias
+	}
+	return aliasPattern.ReplaceAllString(fmt.Sprintf("%s_%s", strings.ToLower(agg.Function), agg.Field), "_")
+}
+
 // buildGroupByClause builds GROUP BY clause
-func (s *Service) buildGroupByClause(groupBy []string) string {
-	return strings.Join(groupBy, ", ")
+func (s *Service) buildGroupByClause(qb *models.QueryBuilder) (string, error) {
+	var parts []string
+	if qb.TimeBucket != nil {
+		parts = append(parts, timeBucketAlias(qb.TimeBucket))
+	}
+	for _, name := range qb.GroupBy {
+		column, err := columnSQL(qb, name)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, column)
+	}
+	return strings.Join(parts, ", "), nil
 }
 
 // buildOrderByClause builds ORDER BY clause
-func (s *Service) buildOrderByClause(orderBy []models.QueryOrderBy) string {
+func (s *Service) buildOrderByClause(qb *models.QueryBuilder) (string, error) {
 	var parts []string
-	for _, order := range orderBy {
-		parts = append(parts, fmt.Sprintf("%s %s", order.Field, order.Direction))
+
+	// Buckets sort first, unless ordered explicitly without gap filling,
+	// which needs them first and ascending
+	if qb.TimeBucket != nil {
+		alias := timeBucketAlias(qb.TimeBucket)
+		ordered := false
+		for _, order := range qb.OrderBy {
+			ordered = ordered || order.Field == alias
+		}
+		if !ordered || qb.TimeBucket.FillGaps {
+			order, err := s.buildTimeBucketOrder(qb)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, order)
+		}
+	}
+
+	for _, order := range qb.OrderBy {
+		if qb.TimeBucket != nil && qb.TimeBucket.FillGaps && order.Field == timeBucketAlias(qb.TimeBucket) {
+			continue
+		}
+		column, err := columnSQL(qb, order.Field)
+		if err != nil {
+			return "", err
+		}
+		direction, err := orderDirection(order.Direction)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", column, direction))
 	}
-	return strings.Join(parts, ", ")
+	return strings.Join(parts, ", "), nil
 }
 
-// formatValue formats a value for SQL
+// formatValue formats a value as a SQL literal. Anything but numbers,
+// booleans and NULL becomes an escaped string, so no value can end the
+// literal early.
 func (s *Service) formatValue(value interface{}) string {
 	switch v := value.(type) {
+	case nil:
+		return "NULL"
 	case string:
-		// Escape single quotes
-		escaped := strings.ReplaceAll(v, "'", "''")
-		return fmt.Sprintf("'%s'", escaped)
-	case int, int32, int64, float32, float64:
-		return fmt.Sprintf("%v", v)
+		return chsql.Quote(v)
+	case int, int32, int64, uint, uint32, uint64:
+		return fmt.Sprintf("%d", v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		if _, err := v.Float64(); err == nil {
+			return v.String()
+		}
+		return chsql.Quote(v.String())
 	case bool:
 		if v {
 			return "1"
 		}
 		return "0"
 	case time.Time:
-		return fmt.Sprintf("'%s'", v.Format("2006-01-02 15:04:05"))
+		return chsql.Quote(v.Format("2006-01-02 15:04:05"))
+	default:
+		return chsql.Quote(fmt.Sprintf("%v", v))
+	}
+}
+
+// quoteIdentifier returns a table or column name for SQL: plain
+// identifiers as they are, other names backquoted. Names that cannot be
+// quoted safely are rejected.
+func quoteIdentifier(name string) (string, error) {
+	if identifierPattern.MatchString(name) {
+		return name, nil
+	}
+	if name == "" {
+		return "", fmt.Errorf("empty identifier")
+	}
+	for _, c := range name {
+		if c == '`' || c == '\\' || c < ' ' {
+			return "", fmt.Errorf("invalid identifier: %q", name)
+		}
+	}
+	return "`" + name + "`", nil
+}
+
+// orderDirection normalizes an ORDER BY direction, ASC when empty
+func orderDirection(direction string) (string, error) {
+	switch strings.ToUpper(direction) {
+	case "", "ASC":
+		return "ASC", nil
+	case "DESC":
+		return "DESC", nil
 	default:
-		return fmt.Sprintf("'%v'", v)
+		return "", fmt.Errorf("invalid order direction: %s", direction)
 	}
 }
 
 // validateFilterOperator validates filter operators
 func (s *Service) validateFilterOperator(operator string) error {
 	validOperators := []string{
-		"equals", "not_equals", "contains", "not_contains",
+		"equals", "not_equals", "contains", "not_contains", "icontains",
+		"matches_regex", "not_matches_regex",
 		"greater_than", "less_than", "greater_equal", "less_equal",
 		"between", "in", "not_in", "is_null", "is_not_null",
 	}
@@ -0,0 +1,214 @@
+package querybuilder
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// ConfigStorage persists saved query builder configurations
+type ConfigStorage interface {
+	Save(config *models.QueryBuilder) error
+	LoadAll() ([]*models.QueryBuilder, error)
+	Delete(id string) error
+}
+
+// ConfigStore keeps saved query builder configurations, owned by the user
+// who created them. They are kept apart from SQL saved queries so they can
+// be edited in the builder again; converting one to a saved query freezes
+// its generated SQL.
+type ConfigStore struct {
+	service *Service
+	storage ConfigStorage // nil keeps configurations in memory only
+
+	mu      sync.RWMutex
+	configs map[string]*models.QueryBuilder
+}
+
+// NewConfigStore creates an in-memory store validating configurations
+// with service
+func NewConfigStore(service *Service) *ConfigStore {
+	return &ConfigStore{
+		service: service,
+		configs: make(map[string]*models.QueryBuilder),
+	}
+}
+
+// SetStorage sets the persistence backend and loads the stored configurations
+func (cs *ConfigStore) SetStorage(storage ConfigStorage) error {
+	stored, err := storage.LoadAll()
+	if err != nil {
+		return err
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.storage = storage
+	for _, config := range stored {
+		cs.configs[config.ID] = config
+	}
+	log.Info().Int("count", len(stored)).Msg("Loaded query builder configurations")
+	return nil
+}
+
+// Create validates and saves a configuration owned by the user in ctx
+func (cs *ConfigStore) Create(ctx context.Context, config *models.QueryBuilder) (*models.QueryBuilder, error) {
+	config.ID = uuid.New().String()
+	config.CreatedBy = auth.UserFromContext(ctx).ID
+	config.CreatedAt = time.Now().UTC()
+	config.UpdatedAt = config.CreatedAt
+	config.Tags = normalizeTags(config.Tags)
+
+	if err := cs.save(config); err != nil {
+		return nil, err
+	}
+	snapshot := *config
+	return &snapshot, nil
+}
+
+// Update replaces a configuration, keeping its owner and creation time
+func (cs *ConfigStore) Update(ctx context.Context, id string, changes *models.QueryBuilder) (*models.QueryBuilder, error) {
+	cs.mu.RLock()
+	current, err := cs.lookup(ctx, id)
+	cs.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *changes
+	updated.ID = current.ID
+	updated.CreatedBy = current.CreatedBy
+	updated.CreatedAt = current.CreatedAt
+	updated.UpdatedAt = time.Now().UTC()
+	updated.Tags = normalizeTags(updated.Tags)
+
+	if err := cs.save(&updated); err != nil {
+		return nil, err
+	}
+	snapshot := updated
+	return &snapshot, nil
+}
+
+// Delete removes a configuration
+func (cs *ConfigStore) Delete(ctx context.Context, id string) error {
+	cs.mu.RLock()
+	_, err := cs.lookup(ctx, id)
+	storage := cs.storage
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if storage != nil {
+		if err := storage.Delete(id); err != nil {
+			return fmt.Errorf("failed to delete configuration: %w", err)
+		}
+	}
+
+	cs.mu.Lock()
+	delete(cs.configs, id)
+	cs.mu.Unlock()
+	return nil
+}
+
+// Get returns a configuration visible to the user in ctx
+func (cs *ConfigStore) Get(ctx context.Context, id string) (*models.QueryBuilder, error) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	config, err := cs.lookup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := *config
+	return &snapshot, nil
+}
+
+// List returns the configurations of the user in ctx, all of them for
+// admins, most recently updated first. A non-empty tag keeps those tagged
+// with it.
+func (cs *ConfigStore) List(ctx context.Context, tag string) []*models.QueryBuilder {
+	user := auth.UserFromContext(ctx)
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	configs := make([]*models.QueryBuilder, 0)
+	for _, config := range cs.configs {
+		if config.CreatedBy != user.ID && !user.IsAdmin() {
+			continue
+		}
+		if tag != "" && !hasTag(config.Tags, tag) {
+			continue
+		}
+		snapshot := *config
+		configs = append(configs, &snapshot)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].UpdatedAt.After(configs[j].UpdatedAt) })
+	return configs
+}
+
+// save validates a configuration, stores its generated SQL and persists it
+func (cs *ConfigStore) save(config *models.QueryBuilder) error {
+	if err := cs.service.ValidateQueryBuilder(config); err != nil {
+		return err
+	}
+	sql, err := cs.service.GenerateSQL(config)
+	if err != nil {
+		return err
+	}
+	config.GeneratedSQL = sql
+
+	cs.mu.RLock()
+	storage := cs.storage
+	cs.mu.RUnlock()
+	if storage != nil {
+		if err := storage.Save(config); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+	}
+
+	cs.mu.Lock()
+	cs.configs[config.ID] = config
+	cs.mu.Unlock()
+	return nil
+}
+
+// lookup finds a configuration the user in ctx may access. Callers hold the lock.
+func (cs *ConfigStore) lookup(ctx context.Context, id string) (*models.QueryBuilder, error) {
+	config, ok := cs.configs[id]
+	user := auth.UserFromContext(ctx)
+	if !ok || (config.CreatedBy != user.ID && !user.IsAdmin()) {
+		return nil, fmt.Errorf("configuration not found: %s", id)
+	}
+	return config, nil
+}
+
+// normalizeTags drops empty and repeated tags
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,410 @@
+package querybuilder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+const (
+	maxExpressionLength = 1000
+	maxExpressionDepth  = 32
+)
+
+// Functions computed fields may call, by lower-cased name, with the name
+// written to SQL. Aggregates are left out; they belong in aggregations,
+// whose aliases expressions can refer to.
+var expressionFunctions = map[string]string{
+	"if": "if", "multiif": "multiIf", "coalesce": "coalesce", "ifnull": "ifNull", "nullif": "nullIf",
+	"tofloat64": "toFloat64", "tofloat64ornull": "toFloat64OrNull", "tofloat64orzero": "toFloat64OrZero",
+	"toint64": "toInt64", "toint64ornull": "toInt64OrNull", "toint64orzero": "toInt64OrZero",
+	"tostring": "toString", "todate": "toDate", "todatetime": "toDateTime",
+	"round": "round", "floor": "floor", "ceil": "ceil", "abs": "abs", "greatest": "greatest", "least": "least",
+	"length": "length", "lower": "lower", "upper": "upper", "concat": "concat",
+}
+
+var comparisonOperators = map[string]bool{"=": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true}
+
+// expressionToken is a token of a computed field expression
+type expressionToken struct {
+	kind string // number, string, name, op, (, ), ","
+	text string
+}
+
+// expressionParser compiles the restricted grammar of computed fields to
+// SQL. The SQL is written from the parsed tokens rather than copied from
+// the input, so nothing outside the grammar reaches the query.
+//
+//	expr    = and { OR and }
+//	and     = not { AND not }
+//	not     = NOT not | compare
+//	compare = sum [ ( = | != | < | > | <= | >= ) sum ]
+//	sum     = product { ( + | - ) product }
+//	product = unary { ( * | / | % ) unary }
+//	unary   = - unary | primary
+//	primary = number | 'string' | name | function ( [ expr { , expr } ] ) | ( expr )
+type expressionParser struct {
+	tokens []expressionToken
+	pos    int
+	depth  int
+	names  func(name string) (string, error)
+}
+
+// compileExpression validates a computed field expression and returns its
+// SQL. names resolves the fields and aliases it refers to.
+func compileExpression(expression string, names func(name string) (string, error)) (string, error) {
+	if strings.TrimSpace(expression) == "" {
+		return "", fmt.Errorf("expression is empty")
+	}
+	if len(expression) > maxExpressionLength {
+		return "", fmt.Errorf("expression longer than %d characters", maxExpressionLength)
+	}
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return "", err
+	}
+	p := &expressionParser{tokens: tokens, names: names}
+	sql, err := p.parseOr()
+	if err != nil {
+		return "", err
+	}
+	if p.pos < len(p.tokens) {
+		return "", fmt.Errorf("unexpected %q in expression", p.tokens[p.pos].text)
+	}
+	return sql, nil
+}
+
+func tokenizeExpression(expression string) ([]expressionToken, error) {
+	var tokens []expressionToken
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(expression) && expression[i+1] >= '0' && expression[i+1] <= '9':
+			j := i
+			for j < len(expression) && (expression[j] >= '0' && expression[j] <= '9' || expression[j] == '.') {
+				j++
+			}
+			if _, err := strconv.ParseFloat(expression[i:j], 64); err != nil {
+				return nil, fmt.Errorf("invalid number %q in expression", expression[i:j])
+			}
+			tokens = append(tokens, expressionToken{"number", expression[i:j]})
+			i = j
+		case c == '\'':
+			// '' is a quote inside a string
+			var value strings.Builder
+			j := i + 1
+			for ; j < len(expression); j++ {
+				if expression[j] == '\'' {
+					if j+1 < len(expression) && expression[j+1] == '\'' {
+						j++
+					} else {
+						break
+					}
+				}
+				value.WriteByte(expression[j])
+			}
+			if j >= len(expression) {
+				return nil, fmt.Errorf("unterminated string in expression")
+			}
+			tokens = append(tokens, expressionToken{"string", value.String()})
+			i = j + 1
+		case isNameChar(c) && !(c >= '0' && c <= '9'):
+			j := i
+			for j < len(expression) && (isNameChar(expression[j]) || expression[j] == '.') {
+				j++
+			}
+			// attributes['key'] is one name
+			if expression[i:j] == attributesColumn && strings.HasPrefix(expression[j:], "['") {
+				end := strings.Index(expression[j:], "']")
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated attribute in expression")
+				}
+				j += end + 2
+			}
+			tokens = append(tokens, expressionToken{"name", expression[i:j]})
+			i = j
+		case strings.HasPrefix(expression[i:], "!=") || strings.HasPrefix(expression[i:], "<>") ||
+			strings.HasPrefix(expression[i:], "<=") || strings.HasPrefix(expression[i:], ">="):
+			op := expression[i : i+2]
+			if op == "<>" {
+				op = "!="
+			}
+			tokens = append(tokens, expressionToken{"op", op})
+			i += 2
+		case strings.ContainsRune("+-*/%=<>", rune(c)):
+			tokens = append(tokens, expressionToken{"op", string(c)})
+			i++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, expressionToken{string(c), string(c)})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected %q in expression", c)
+		}
+	}
+	return tokens, nil
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (p *expressionParser) peek() expressionToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return expressionToken{}
+}
+
+// keyword reports whether the next token is the keyword, in any case
+func (p *expressionParser) keyword(word string) bool {
+	tok := p.peek()
+	return tok.kind == "name" && strings.EqualFold(tok.text, word)
+}
+
+func (p *expressionParser) expect(kind string) error {
+	if p.peek().kind != kind {
+		if p.pos >= len(p.tokens) {
+			return fmt.Errorf("expression ends early, expected %s", kind)
+		}
+		return fmt.Errorf("unexpected %q in expression, expected %s", p.peek().text, kind)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *expressionParser) parseOr() (string, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxExpressionDepth {
+		return "", fmt.Errorf("expression nests more than %d deep", maxExpressionDepth)
+	}
+
+	sql, err := p.parseAnd()
+	for err == nil && p.keyword("OR") {
+		p.pos++
+		var right string
+		if right, err = p.parseAnd(); err == nil {
+			sql += " OR " + right
+		}
+	}
+	return sql, err
+}
+
+func (p *expressionParser) parseAnd() (string, error) {
+	sql, err := p.parseNot()
+	for err == nil && p.keyword("AND") {
+		p.pos++
+		var right string
+		if right, err = p.parseNot(); err == nil {
+			sql += " AND " + right
+		}
+	}
+	return sql, err
+}
+
+func (p *expressionParser) parseNot() (string, error) {
+	if p.keyword("NOT") {
+		p.pos++
+		operand, err := p.parseNot()
+		return "NOT " + operand, err
+	}
+	return p.parseCompare()
+}
+
+func (p *expressionParser) parseCompare() (string, error) {
+	sql, err := p.parseSum()
+	if err != nil {
+		return "", err
+	}
+	if tok := p.peek(); tok.kind == "op" && comparisonOperators[tok.text] {
+		p.pos++
+		right, err := p.parseSum()
+		return sql + " " + tok.text + " " + right, err
+	}
+	return sql, nil
+}
+
+func (p *expressionParser) parseSum() (string, error) {
+	sql, err := p.parseProduct()
+	for err == nil && p.peek().kind == "op" && (p.peek().text == "+" || p.peek().text == "-") {
+		op := p.peek().text
+		p.pos++
+		var right string
+		if right, err = p.parseProduct(); err == nil {
+			sql += " " + op + " " + right
+		}
+	}
+	return sql, err
+}
+
+func (p *expressionParser) parseProduct() (string, error) {
+	sql, err := p.parseUnary()
+	for err == nil && p.peek().kind == "op" && strings.Contains("*/%", p.peek().text) {
+		op := p.peek().text
+		p.pos++
+		var right string
+		if right, err = p.parseUnary(); err == nil {
+			sql += " " + op + " " + right
+		}
+	}
+	return sql, err
+}
+
+func (p *expressionParser) parseUnary() (string, error) {
+	if tok := p.peek(); tok.kind == "op" && tok.text == "-" {
+		p.pos++
+		operand, err := p.parseUnary()
+		if strings.HasPrefix(operand, "-") {
+			operand = "(" + operand + ")" // -- would start a comment
+		}
+		return "-" + operand, err
+	}
+	return p.parsePrimary()
+}
+
+func (p *expressionParser) parsePrimary() (string, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case "number":
+		p.pos++
+		return tok.text, nil
+	case "string":
+		p.pos++
+		return chsql.Quote(tok.text), nil
+	case "(":
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect(")"); err != nil {
+			return "", err
+		}
+		return "(" + inner + ")", nil
+	case "name":
+		p.pos++
+		if p.peek().kind == "(" {
+			return p.parseCall(tok.text)
+		}
+		return p.names(tok.text)
+	case "":
+		return "", fmt.Errorf("expression ends early")
+	default:
+		return "", fmt.Errorf("unexpected %q in expression", tok.text)
+	}
+}
+
+func (p *expressionParser) parseCall(name string) (string, error) {
+	function, ok := expressionFunctions[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf("function %s is not allowed in expressions", name)
+	}
+	p.pos++ // (
+
+	var args []string
+	if p.peek().kind != ")" {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return "", err
+			}
+			args = append(args, arg)
+			if p.peek().kind != "," {
+				break
+			}
+			p.pos++
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return "", err
+	}
+	return function + "(" + strings.Join(args, ", ") + ")", nil
+}
+
+// expressionNames resolves the names a computed field of qb may use: the
+// columns of the table, attribute fields as strings, and the aliases of
+// aggregations, the time bucket, the top-N and the computed fields before
+// it
+func (s *Service) expressionNames(qb *models.QueryBuilder, fields map[string]bool, before int) func(name string) (string, error) {
+	aliases := map[string]bool{}
+	for _, agg := range qb.Aggregations {
+		aliases[aggregationAlias(agg)] = true
+	}
+	if qb.TimeBucket != nil {
+		aliases[timeBucketAlias(qb.TimeBucket)] = true
+	}
+	if qb.TopN != nil {
+		aliases[topNAlias(qb.TopN)] = true
+	}
+	for _, computed := range qb.Computed[:before] {
+		aliases[computed.Name] = true
+	}
+
+	return func(name string) (string, error) {
+		key, isAttribute, err := parseAttributeField(name)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case isAttribute:
+			if !s.hasAttributes(qb.Table) {
+				return "", fmt.Errorf("table %s has no attributes", tableName(qb.Table))
+			}
+			return attributeExpression(key, "")
+		case aliases[name], fields[name] && identifierPattern.MatchString(name):
+			return name, nil
+		default:
+			return "", fmt.Errorf("unknown name in expression: %s", name)
+		}
+	}
+}
+
+// validateComputed checks that computed fields have unique names and
+// expressions within the grammar
+func (s *Service) validateComputed(qb *models.QueryBuilder, fields map[string]bool) error {
+	taken := map[string]bool{}
+	for _, agg := range qb.Aggregations {
+		taken[aggregationAlias(agg)] = true
+	}
+	for i, computed := range qb.Computed {
+		if !identifierPattern.MatchString(computed.Name) {
+			return fmt.Errorf("invalid computed field name: %s", computed.Name)
+		}
+		if taken[computed.Name] || fields[computed.Name] {
+			return fmt.Errorf("computed field %s is already a field or alias", computed.Name)
+		}
+		taken[computed.Name] = true
+		if _, err := compileExpression(computed.Expression, s.expressionNames(qb, fields, i)); err != nil {
+			return fmt.Errorf("computed field %s: %w", computed.Name, err)
+		}
+	}
+	return nil
+}
+
+// buildComputedColumns returns the SELECT columns of the computed fields
+func (s *Service) buildComputedColumns(qb *models.QueryBuilder) ([]string, error) {
+	if len(qb.Computed) == 0 {
+		return nil, nil
+	}
+	fields, err := s.fieldMap(qb.Table)
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, 0, len(qb.Computed))
+	for i, computed := range qb.Computed {
+		if !identifierPattern.MatchString(computed.Name) {
+			return nil, fmt.Errorf("invalid computed field name: %s", computed.Name)
+		}
+		sql, err := compileExpression(computed.Expression, s.expressionNames(qb, fields, i))
+		if err != nil {
+			return nil, fmt.Errorf("computed field %s: %w", computed.Name, err)
+		}
+		columns = append(columns, sql+" AS "+computed.Name)
+	}
+	return columns, nil
+}
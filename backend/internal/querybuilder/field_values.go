@@ -0,0 +1,70 @@
+package querybuilder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+const (
+	// DefaultFieldValues is how many values are suggested unless asked otherwise
+	DefaultFieldValues = 20
+	// MaxFieldValues caps the values suggested
+	MaxFieldValues = 200
+
+	// fieldValueCandidates is how many of the most frequent values of a
+	// field are read; prefixes are matched among them, so one cached query
+	// serves every keystroke
+	fieldValueCandidates = 1000
+
+	// fieldValueWindow is how far back values are counted
+	fieldValueWindow = 24 * time.Hour
+)
+
+// FieldValuesSQL returns the query counting the most frequent values of a
+// field over the last day. The time bound is relative to now(), which the
+// result cache rounds, so repeated lookups share one execution.
+func (s *Service) FieldValuesSQL(table, field string) (string, error) {
+	if _, err := s.FieldExpression(table, field, ""); err != nil {
+		return "", err
+	}
+	expr, err := fieldSQL(field, "")
+	if err != nil {
+		return "", err
+	}
+	column, err := s.timeColumn(table)
+	if err != nil {
+		return "", err
+	}
+	from, err := quoteIdentifier(tableName(table))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("SELECT toString(%s) AS value, count() AS count FROM %s WHERE %s >= now() - INTERVAL %d SECOND GROUP BY value ORDER BY count DESC LIMIT %d",
+		expr, from, column, int(fieldValueWindow.Seconds()), fieldValueCandidates), nil
+}
+
+// FilterFieldValues returns up to limit values of a FieldValuesSQL result
+// starting with prefix, ignoring case, most frequent first. Empty values
+// are left out.
+func FilterFieldValues(rows []map[string]interface{}, prefix string, limit int) []models.FieldValue {
+	if limit <= 0 || limit > MaxFieldValues {
+		limit = DefaultFieldValues
+	}
+	prefix = strings.ToLower(prefix)
+	values := []models.FieldValue{}
+	for _, row := range rows {
+		if len(values) >= limit {
+			break
+		}
+		value := fmt.Sprintf("%v", row["value"])
+		if value == "" || !strings.HasPrefix(strings.ToLower(value), prefix) {
+			continue
+		}
+		values = append(values, models.FieldValue{Value: value, Count: chsql.ToInt64(row["count"])})
+	}
+	return values
+}
@@ -0,0 +1,130 @@
+package querybuilder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/tables"
+)
+
+// maxJoins bounds the lookups one query joins
+const maxJoins = 5
+
+// GetLookups returns the lookup tables a table can be joined with, those
+// whose join field is one of its columns, with their fields named
+// <lookup>.<column>
+func (s *Service) GetLookups(table string) []models.QueryLookup {
+	lookups := []models.QueryLookup{}
+	if s.tables == nil {
+		return lookups
+	}
+	fields, err := s.fieldMap(table)
+	if err != nil {
+		return lookups
+	}
+	for _, lookup := range s.tables.Lookups() {
+		_, t, exists := s.tables.GetLookup(lookup.Table)
+		if !exists || !fields[lookup.Field] {
+			continue
+		}
+		queryLookup := models.QueryLookup{
+			Table:  lookup.Table,
+			Key:    lookup.Key,
+			Field:  lookup.Field,
+			Fields: make([]models.QueryField, 0, len(t.Columns)),
+		}
+		for _, column := range t.Columns {
+			queryLookup.Fields = append(queryLookup.Fields, models.QueryField{
+				Name:  lookupFieldName(lookup.Table, column.Name),
+				Type:  fieldType(column.Type),
+				Label: column.Name,
+			})
+		}
+		lookups = append(lookups, queryLookup)
+	}
+	return lookups
+}
+
+// lookupFieldName returns the name a column of a joined lookup is used by
+func lookupFieldName(lookup, column string) string {
+	return lookup + "." + column
+}
+
+// parseLookupField splits a field written as <lookup>.<column>. Attribute
+// fields, also dotted, are not lookup fields.
+func parseLookupField(name string) (string, string, bool) {
+	if _, isAttribute, _ := parseAttributeField(name); isAttribute {
+		return "", "", false
+	}
+	lookup, column, ok := strings.Cut(name, ".")
+	if !ok || !identifierPattern.MatchString(lookup) || !identifierPattern.MatchString(column) {
+		return "", "", false
+	}
+	return lookup, column, true
+}
+
+// validateJoins checks each join names a registered lookup, once, whose
+// join field is a column of the queried table. The lookup's columns are
+// added to fields, so they validate like the table's own.
+func (s *Service) validateJoins(qb *models.QueryBuilder, fields map[string]bool) error {
+	if len(qb.Joins) == 0 {
+		return nil
+	}
+	if len(qb.Joins) > maxJoins {
+		return fmt.Errorf("at most %d lookups can be joined", maxJoins)
+	}
+	joined := make(map[string]bool, len(qb.Joins))
+	for _, join := range qb.Joins {
+		lookup, table, err := s.lookup(join.Lookup)
+		if err != nil {
+			return err
+		}
+		if joined[lookup.Table] {
+			return fmt.Errorf("lookup %s is joined twice", lookup.Table)
+		}
+		joined[lookup.Table] = true
+		if !fields[lookup.Field] {
+			return fmt.Errorf("lookup %s joins on %s, which %s does not have", lookup.Table, lookup.Field, tableName(qb.Table))
+		}
+		if _, exists := table.Column(lookup.Key); !exists {
+			return fmt.Errorf("lookup %s has no key column %s", lookup.Table, lookup.Key)
+		}
+		for _, column := range table.Columns {
+			fields[lookupFieldName(lookup.Table, column.Name)] = true
+		}
+	}
+	return nil
+}
+
+// lookup returns a registered lookup and its table
+func (s *Service) lookup(name string) (tables.Lookup, *tables.Table, error) {
+	if s.tables == nil {
+		return tables.Lookup{}, nil, fmt.Errorf("unknown lookup: %s", name)
+	}
+	lookup, table, exists := s.tables.GetLookup(name)
+	if !exists {
+		return tables.Lookup{}, nil, fmt.Errorf("unknown lookup: %s", name)
+	}
+	return lookup, table, nil
+}
+
+// buildJoinClauses returns an ANY LEFT JOIN per lookup: logs without a
+// match keep their row with default lookup values, and a key repeated in
+// the lookup does not multiply rows
+func (s *Service) buildJoinClauses(qb *models.QueryBuilder) ([]string, error) {
+	var clauses []string
+	table, err := quoteIdentifier(tableName(qb.Table))
+	if err != nil {
+		return nil, err
+	}
+	for _, join := range qb.Joins {
+		lookup, _, err := s.lookup(join.Lookup)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, fmt.Sprintf("ANY LEFT JOIN %s ON %s.%s = %s.%s",
+			lookup.Table, table, lookup.Field, lookup.Table, lookup.Key))
+	}
+	return clauses, nil
+}
@@ -0,0 +1,106 @@
+package querybuilder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// SQLExecutor is the subset of the database used to persist configurations
+type SQLExecutor interface {
+	Execute(ctx context.Context, query string) error
+	ExecuteSQL(sql string) ([]map[string]interface{}, error)
+}
+
+// ClickHouseStorage keeps query builder configurations in ClickHouse, one
+// row per save with the latest row winning; deletions are tombstone rows.
+type ClickHouseStorage struct {
+	db    SQLExecutor
+	table string
+}
+
+// NewClickHouseStorage creates the configurations table if needed
+func NewClickHouseStorage(db SQLExecutor) (*ClickHouseStorage, error) {
+	s := &ClickHouseStorage{
+		db:    db,
+		table: "query_builder_configs",
+	}
+
+	ddl := fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		id String,
+		created_by String,
+		definition String,
+		deleted UInt8 DEFAULT 0,
+		version UInt64
+	) ENGINE = ReplacingMergeTree(version)
+	ORDER BY id
+	`, s.table)
+
+	if err := db.Execute(context.Background(), ddl); err != nil {
+		return nil, fmt.Errorf("failed to create query builder configs table: %w", err)
+	}
+	return s, nil
+}
+
+// Save writes the current definition of a configuration
+func (s *ClickHouseStorage) Save(config *models.QueryBuilder) error {
+	definition, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	return s.insert(config.ID, config.CreatedBy, string(definition), false)
+}
+
+// LoadAll returns every configuration that has not been deleted
+func (s *ClickHouseStorage) LoadAll() ([]*models.QueryBuilder, error) {
+	rows, err := s.db.ExecuteSQL(fmt.Sprintf(`
+		SELECT id,
+			argMax(definition, version) AS definition,
+			argMax(deleted, version) AS deleted
+		FROM %s
+		GROUP BY id
+	`, s.table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configurations: %w", err)
+	}
+
+	configs := make([]*models.QueryBuilder, 0, len(rows))
+	for _, row := range rows {
+		if chsql.ToInt64(row["deleted"]) != 0 {
+			continue
+		}
+		definition, _ := row["definition"].(string)
+		var config models.QueryBuilder
+		if err := json.Unmarshal([]byte(definition), &config); err != nil {
+			log.Warn().Err(err).Interface("id", row["id"]).Msg("Skipping unreadable query builder configuration")
+			continue
+		}
+		configs = append(configs, &config)
+	}
+	return configs, nil
+}
+
+// Delete records a tombstone for the configuration
+func (s *ClickHouseStorage) Delete(id string) error {
+	return s.insert(id, "", "", true)
+}
+
+func (s *ClickHouseStorage) insert(id, createdBy, definition string, deleted bool) error {
+	deletedFlag := 0
+	if deleted {
+		deletedFlag = 1
+	}
+	statement := fmt.Sprintf("INSERT INTO %s (id, created_by, definition, deleted, version) VALUES (%s, %s, %s, %d, %d)",
+		s.table, chsql.Quote(id), chsql.Quote(createdBy), chsql.Quote(definition), deletedFlag, time.Now().UnixNano())
+	if err := s.db.Execute(context.Background(), statement); err != nil {
+		return fmt.Errorf("failed to store configuration: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package querybuilder
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// BucketIntervals are the intervals chosen from when none is requested,
+// smallest first
+var BucketIntervals = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour,
+	24 * time.Hour, 7 * 24 * time.Hour,
+}
+
+const (
+	// DefaultBucketPoints is the number of buckets an interval is chosen for
+	DefaultBucketPoints = 300
+	// MaxBucketPoints bounds the buckets of a range, whichever the interval
+	MaxBucketPoints = 2000
+
+	defaultTimeBucketAlias = "time"
+)
+
+// identifierPattern matches aliases usable without quoting
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ChooseInterval parses the requested interval, or picks the smallest one
+// that keeps the range within maxPoints buckets
+func ChooseInterval(requested string, span time.Duration, maxPoints int) (time.Duration, error) {
+	if maxPoints <= 0 {
+		maxPoints = DefaultBucketPoints
+	}
+	if maxPoints > MaxBucketPoints {
+		maxPoints = MaxBucketPoints
+	}
+
+	if requested != "" {
+		interval, err := ParseInterval(requested)
+		if err != nil {
+			return 0, err
+		}
+		if span/interval > MaxBucketPoints {
+			return 0, fmt.Errorf("interval %s gives more than %d points for this range", requested, MaxBucketPoints)
+		}
+		return interval, nil
+	}
+
+	for _, interval := range BucketIntervals {
+		if int((span+interval-1)/interval) <= maxPoints {
+			return interval, nil
+		}
+	}
+	return BucketIntervals[len(BucketIntervals)-1], nil
+}
+
+// ParseInterval accepts Go durations and a d suffix for days, e.g. 30s, 5m, 1d
+func ParseInterval(s string) (time.Duration, error) {
+	var interval time.Duration
+	var err error
+	if days := strings.TrimSuffix(s, "d"); days != s {
+		var n int
+		if _, err = fmt.Sscanf(days, "%d", &n); err == nil {
+			interval = time.Duration(n) * 24 * time.Hour
+		}
+	} else {
+		interval, err = time.ParseDuration(s)
+	}
+	if err != nil || interval < time.Second || interval%time.Second != 0 {
+		return 0, fmt.Errorf("invalid interval: %s", s)
+	}
+	return interval, nil
+}
+
+// AlignToInterval rounds down to a multiple of interval since the Unix
+// epoch, as toStartOfInterval does for second based intervals
+func AlignToInterval(t time.Time, interval time.Duration) time.Time {
+	seconds := int64(interval / time.Second)
+	unix := t.Unix()
+	return time.Unix(unix-unix%seconds, 0).UTC()
+}
+
+// FormatInterval writes an interval the way ParseInterval reads it
+func FormatInterval(interval time.Duration) string {
+	switch {
+	case interval%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", interval/(24*time.Hour))
+	case interval%time.Hour == 0:
+		return fmt.Sprintf("%dh", interval/time.Hour)
+	case interval%time.Minute == 0:
+		return fmt.Sprintf("%dm", interval/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", interval/time.Second)
+	}
+}
+
+// intervalSQL writes an interval in the largest unit that keeps buckets
+// aligned to the Unix epoch, so they match AlignToInterval
+func intervalSQL(interval time.Duration) string {
+	switch {
+	case interval%time.Hour == 0 && (24*time.Hour)%interval == 0:
+		return fmt.Sprintf("INTERVAL %d HOUR", interval/time.Hour)
+	case interval%time.Minute == 0 && time.Hour%interval == 0:
+		return fmt.Sprintf("INTERVAL %d MINUTE", interval/time.Minute)
+	default:
+		return fmt.Sprintf("INTERVAL %d SECOND", interval/time.Second)
+	}
+}
+
+// timeBucketAlias returns the result column name of a time bucket
+func timeBucketAlias(bucket *models.QueryTimeBucket) string {
+	if bucket.Alias == "" {
+		return defaultTimeBucketAlias
+	}
+	return bucket.Alias
+}
+
+// timeBucketInterval returns the interval of a time bucket, chosen from
+// the time range when none is set
+func (s *Service) timeBucketInterval(qb *models.QueryBuilder) (time.Duration, error) {
+	bucket := qb.TimeBucket
+	if bucket.Interval == "" && qb.TimeRange == nil {
+		return 0, fmt.Errorf("time bucket needs an interval or a time range")
+	}
+	var span time.Duration
+	if qb.TimeRange != nil {
+		start, end, err := s.ResolveTimeRange(qb.TimeRange)
+		if err != nil {
+			return 0, err
+		}
+		if end.IsZero() {
+			end = time.Now()
+		}
+		if !start.IsZero() {
+			span = end.Sub(start)
+		}
+	}
+	if bucket.Interval == "" && span <= 0 {
+		return 0, fmt.Errorf("time bucket needs an interval or a time range with a start")
+	}
+	return ChooseInterval(bucket.Interval, span, bucket.MaxPoints)
+}
+
+// validateTimeBucket checks a time bucket can be generated
+func (s *Service) validateTimeBucket(qb *models.QueryBuilder) error {
+	if qb.TimeBucket == nil {
+		return nil
+	}
+	if len(qb.Aggregations) == 0 {
+		return fmt.Errorf("time bucket requires an aggregation")
+	}
+	alias := timeBucketAlias(qb.TimeBucket)
+	if !identifierPattern.MatchString(alias) {
+		return fmt.Errorf("invalid time bucket alias: %s", alias)
+	}
+	for _, agg := range qb.Aggregations {
+		if aggregationAlias(agg) == alias {
+			return fmt.Errorf("time bucket alias %s is also an aggregation alias", alias)
+		}
+	}
+	if _, err := s.timeColumn(qb.Table); err != nil {
+		return err
+	}
+	_, err := s.timeBucketInterval(qb)
+	return err
+}
+
+// buildTimeBucketColumn returns the SELECT column rounding the time column
+// down to the bucket interval
+func (s *Service) buildTimeBucketColumn(qb *models.QueryBuilder) (string, error) {
+	column, err := s.timeColumn(qb.Table)
+	if err != nil {
+		return "", err
+	}
+	interval, err := s.timeBucketInterval(qb)
+	if err != nil {
+		return "", err
+	}
+	if alias := timeBucketAlias(qb.TimeBucket); !identifierPattern.MatchString(alias) {
+		return "", fmt.Errorf("invalid time bucket alias: %s", alias)
+	}
+	// toDateTime keeps the bucket a DateTime, whatever the precision of the
+	// column, so it compares with the fill bounds
+	return fmt.Sprintf("toStartOfInterval(toDateTime(%s), %s) AS %s",
+		column, intervalSQL(interval), timeBucketAlias(qb.TimeBucket)), nil
+}
+
+// buildTimeBucketOrder returns the ORDER BY element sorting buckets
+// oldest first. With gap filling, buckets without rows are added, over the
+// whole time range when there is one.
+func (s *Service) buildTimeBucketOrder(qb *models.QueryBuilder) (string, error) {
+	order := timeBucketAlias(qb.TimeBucket) + " ASC"
+	if !qb.TimeBucket.FillGaps {
+		return order, nil
+	}
+
+	interval, err := s.timeBucketInterval(qb)
+	if err != nil {
+		return "", err
+	}
+	order += " WITH FILL"
+	if qb.TimeRange != nil {
+		start, end, err := s.ResolveTimeRange(qb.TimeRange)
+		if err != nil {
+			return "", err
+		}
+		if end.IsZero() {
+			end = time.Now()
+		}
+		if !start.IsZero() {
+			order += fmt.Sprintf(" FROM toDateTime('%s', 'UTC')", AlignToInterval(start, interval).Format("2006-01-02 15:04:05"))
+		}
+		order += fmt.Sprintf(" TO toDateTime('%s', 'UTC')", AlignToInterval(end, interval).Add(interval).Format("2006-01-02 15:04:05"))
+	}
+	return order + fmt.Sprintf(" STEP %d", interval/time.Second), nil
+}
@@ -0,0 +1,149 @@
+package querybuilder
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// Comparison periods of a time range
+const (
+	// ComparePreviousPeriod compares with the range of the same length
+	// just before
+	ComparePreviousPeriod = "previous_period"
+	ComparePreviousDay    = "previous_day"
+	ComparePreviousWeek   = "previous_week"
+)
+
+// relativeRangePattern matches last_<n><unit>, e.g. last_90m or last_2w
+var relativeRangePattern = regexp.MustCompile(`^last_(\d{1,6})(s|m|h|d|w)$`)
+
+var relativeRangeUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// ResolveTimeRange returns the absolute bounds of a time range, resolving
+// relative ranges against the current time
+func (s *Service) ResolveTimeRange(timeRange *models.QueryTimeRange) (time.Time, time.Time, error) {
+	if timeRange.Relative != "" {
+		return s.parseRelativeTimeRange(timeRange.Relative, time.Now())
+	}
+	return timeRange.Start, timeRange.End, nil
+}
+
+// parseRelativeTimeRange converts a relative time range to absolute times:
+// last_<n><unit> with unit s, m, h, d or w ending now, or a calendar range
+// (today, yesterday, this_week, previous_week, this_month, previous_month)
+// in the server's time zone, weeks starting on Monday
+func (s *Service) parseRelativeTimeRange(relative string, now time.Time) (time.Time, time.Time, error) {
+	if m := relativeRangePattern.FindStringSubmatch(relative); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if n == 0 {
+			return time.Time{}, time.Time{}, fmt.Errorf("unsupported relative time range: %s", relative)
+		}
+		return now.Add(-time.Duration(n) * relativeRangeUnits[m[2]]), now, nil
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	switch relative {
+	case "today":
+		return today, now, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, nil
+	case "this_week":
+		return monday, now, nil
+	case "previous_week":
+		return monday.AddDate(0, 0, -7), monday, nil
+	case "this_month":
+		return month, now, nil
+	case "previous_month":
+		return month.AddDate(0, -1, 0), month, nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("unsupported relative time range: %s", relative)
+	}
+}
+
+// ResolveComparison returns the bounds of the period a time range is
+// compared with, and false when it sets no comparison
+func (s *Service) ResolveComparison(timeRange *models.QueryTimeRange) (time.Time, time.Time, bool, error) {
+	if timeRange == nil || timeRange.Compare == "" {
+		return time.Time{}, time.Time{}, false, nil
+	}
+	start, end, err := s.ResolveTimeRange(timeRange)
+	if err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+	if start.IsZero() || end.IsZero() {
+		return time.Time{}, time.Time{}, false, fmt.Errorf("comparison needs a time range with a start and an end")
+	}
+
+	switch timeRange.Compare {
+	case ComparePreviousPeriod:
+		return start.Add(-end.Sub(start)), start, true, nil
+	case ComparePreviousDay:
+		return start.AddDate(0, 0, -1), end.AddDate(0, 0, -1), true, nil
+	case ComparePreviousWeek:
+		return start.AddDate(0, 0, -7), end.AddDate(0, 0, -7), true, nil
+	default:
+		return time.Time{}, time.Time{}, false, fmt.Errorf("unsupported comparison: %s", timeRange.Compare)
+	}
+}
+
+// ResolvedTimeRange returns the bounds a query builder configuration
+// reads and, when it compares, those of the comparison, for responses to
+// report what was queried. It returns nil without a time range.
+func (s *Service) ResolvedTimeRange(qb *models.QueryBuilder) (*models.ResolvedTimeRange, error) {
+	if qb.TimeRange == nil {
+		return nil, nil
+	}
+	start, end, err := s.ResolveTimeRange(qb.TimeRange)
+	if err != nil {
+		return nil, err
+	}
+	resolved := &models.ResolvedTimeRange{Start: start, End: end, Relative: qb.TimeRange.Relative}
+
+	compareStart, compareEnd, ok, err := s.ResolveComparison(qb.TimeRange)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		resolved.Compare = qb.TimeRange.Compare
+		resolved.CompareStart = &compareStart
+		resolved.CompareEnd = &compareEnd
+	}
+	return resolved, nil
+}
+
+// GenerateComparisonSQL returns the SQL of a configuration over its
+// comparison period, or "" when it sets none
+func (s *Service) GenerateComparisonSQL(qb *models.QueryBuilder) (string, error) {
+	start, end, ok, err := s.ResolveComparison(qb.TimeRange)
+	if err != nil || !ok {
+		return "", err
+	}
+	shifted := *qb
+	shifted.TimeRange = &models.QueryTimeRange{Start: start, End: end}
+	return s.GenerateSQL(&shifted)
+}
+
+// validateTimeRange checks a time range and its comparison resolve
+func (s *Service) validateTimeRange(timeRange *models.QueryTimeRange) error {
+	if timeRange == nil {
+		return nil
+	}
+	if _, _, err := s.ResolveTimeRange(timeRange); err != nil {
+		return err
+	}
+	_, _, _, err := s.ResolveComparison(timeRange)
+	return err
+}
@@ -0,0 +1,145 @@
+package querybuilder
+
+import (
+	"fmt"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+const (
+	// maxTopNLimit bounds the groups a top-N keeps
+	maxTopNLimit = 100
+
+	defaultTopNOtherLabel = "other"
+
+	// topNGroups names the array of kept groups in the WITH clause
+	topNGroups = "top_groups"
+)
+
+// topNAlias returns the result column name of the top-N field. It cannot
+// be the field itself, which ClickHouse would read as a cyclic alias.
+func topNAlias(topN *models.QueryTopN) string {
+	if topN.Alias != "" {
+		return topN.Alias
+	}
+	return aliasPattern.ReplaceAllString(topN.Field, "_") + "_group"
+}
+
+// isTopNField reports whether name is the field a top-N ranks, in any of
+// the ways an attribute field can be written
+func isTopNField(qb *models.QueryBuilder, name string) bool {
+	if qb.TopN == nil {
+		return false
+	}
+	key, isAttribute, _ := parseAttributeField(name)
+	topKey, topIsAttribute, _ := parseAttributeField(qb.TopN.Field)
+	if isAttribute || topIsAttribute {
+		return isAttribute && topIsAttribute && key == topKey
+	}
+	return name == qb.TopN.Field
+}
+
+// topNMetric returns the aggregation groups are ranked by, the first one
+// unless By names another by its alias
+func topNMetric(qb *models.QueryBuilder) (models.QueryAggregation, bool) {
+	for _, agg := range qb.Aggregations {
+		if qb.TopN.By == "" || aggregationAlias(agg) == qb.TopN.By {
+			return agg, true
+		}
+	}
+	return models.QueryAggregation{}, false
+}
+
+// validateTopN checks a top-N ranks a grouped field by one of the
+// aggregations
+func (s *Service) validateTopN(qb *models.QueryBuilder) error {
+	topN := qb.TopN
+	if topN == nil {
+		return nil
+	}
+	if topN.Limit <= 0 || topN.Limit > maxTopNLimit {
+		return fmt.Errorf("top-N limit must be between 1 and %d", maxTopNLimit)
+	}
+	grouped := false
+	for _, name := range qb.GroupBy {
+		grouped = grouped || isTopNField(qb, name)
+	}
+	if !grouped {
+		return fmt.Errorf("top-N field %s must be grouped by", topN.Field)
+	}
+	if _, ok := topNMetric(qb); !ok {
+		if topN.By == "" {
+			return fmt.Errorf("top-N requires an aggregation to rank by")
+		}
+		return fmt.Errorf("unknown aggregation alias in top-N: %s", topN.By)
+	}
+	alias := topNAlias(topN)
+	if !identifierPattern.MatchString(alias) {
+		return fmt.Errorf("invalid top-N alias: %s", alias)
+	}
+	for _, agg := range qb.Aggregations {
+		if aggregationAlias(agg) == alias {
+			return fmt.Errorf("top-N alias %s is also an aggregation alias", alias)
+		}
+	}
+	return nil
+}
+
+// buildTopNWith returns the WITH clause ranking the groups of the top-N
+// field by its metric, with the same filters as the query. The ranking is
+// exact, unlike topK, and looked up with has() rather than an IN subquery,
+// which the optimizer would rewrite.
+func (s *Service) buildTopNWith(qb *models.QueryBuilder) (string, error) {
+	field, err := fieldSQL(qb.TopN.Field, "")
+	if err != nil {
+		return "", err
+	}
+	metric, _ := topNMetric(qb)
+	expr, err := s.aggregationExpression(metric)
+	if err != nil {
+		return "", err
+	}
+
+	table, err := quoteIdentifier(tableName(qb.Table))
+	if err != nil {
+		return "", err
+	}
+	ranking := fmt.Sprintf("SELECT toString(%s) AS top_group FROM %s", field, table)
+	joinClauses, err := s.buildJoinClauses(qb)
+	if err != nil {
+		return "", err
+	}
+	for _, join := range joinClauses {
+		ranking += " " + join
+	}
+	if len(qb.Filters) > 0 || qb.TimeRange != nil {
+		where, err := s.buildWhereClause(qb)
+		if err != nil {
+			return "", err
+		}
+		if where != "" {
+			ranking += " WHERE " + where
+		}
+	}
+	ranking += fmt.Sprintf(" GROUP BY top_group ORDER BY %s DESC LIMIT %d", expr, qb.TopN.Limit)
+
+	return fmt.Sprintf("WITH (SELECT groupArray(top_group) FROM (%s)) AS %s", ranking, topNGroups), nil
+}
+
+// buildTopNColumn returns the SELECT column keeping the ranked groups and
+// labelling the rest as other
+func (s *Service) buildTopNColumn(qb *models.QueryBuilder) (string, error) {
+	field, err := fieldSQL(qb.TopN.Field, "")
+	if err != nil {
+		return "", err
+	}
+	if alias := topNAlias(qb.TopN); !identifierPattern.MatchString(alias) {
+		return "", fmt.Errorf("invalid top-N alias: %s", alias)
+	}
+	other := qb.TopN.OtherLabel
+	if other == "" {
+		other = defaultTopNOtherLabel
+	}
+	return fmt.Sprintf("if(has(%s, toString(%s)), toString(%s), %s) AS %s",
+		topNGroups, field, field, s.formatValue(other), topNAlias(qb.TopN)), nil
+}
@@ -0,0 +1,718 @@
+package report
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+const (
+	reportWidth      = 1200.0
+	reportMargin     = 24.0
+	headerHeight     = 84.0
+	widgetGap        = 12.0
+	widgetPadding    = 12.0
+	widgetTitleSize  = 16.0
+	bodyTextSize     = 13.0
+	axisTextSize     = 11.0
+	defaultColumns   = 12
+	defaultRowHeight = 60
+	minRowHeight     = 40
+)
+
+var (
+	pageColor   = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	panelColor  = color.RGBA{0xf7, 0xf8, 0xfa, 0xff}
+	borderColor = color.RGBA{0xdd, 0xe1, 0xe6, 0xff}
+	gridColor   = color.RGBA{0xe4, 0xe7, 0xeb, 0xff}
+	textColor   = color.RGBA{0x1f, 0x23, 0x28, 0xff}
+	mutedColor  = color.RGBA{0x6a, 0x73, 0x7d, 0xff}
+	errorColor  = color.RGBA{0xd3, 0x2f, 0x2f, 0xff}
+	warnColor   = color.RGBA{0xed, 0x6c, 0x02, 0xff}
+	goodColor   = color.RGBA{0x2e, 0x7d, 0x32, 0xff}
+
+	// palette colors series without a color of their own
+	palette = []color.RGBA{
+		{0x36, 0xa2, 0xeb, 0xff}, {0xff, 0x63, 0x84, 0xff}, {0x4b, 0xc0, 0xc0, 0xff},
+		{0xff, 0x9f, 0x40, 0xff}, {0x99, 0x66, 0xff, 0xff}, {0xff, 0xcd, 0x56, 0xff},
+		{0x2e, 0x7d, 0x32, 0xff}, {0x8d, 0x6e, 0x63, 0xff}, {0x78, 0x90, 0x9c, 0xff},
+		{0xc2, 0x18, 0x5b, 0xff},
+	}
+)
+
+// rect is an area of the report
+type rect struct{ x, y, w, h float64 }
+
+// layout places the widgets of a report on the page
+type layout struct {
+	report    *Report
+	width     float64
+	height    float64
+	columns   int
+	columnW   float64
+	rowHeight float64
+}
+
+func newLayout(report *Report) *layout {
+	columns := report.Columns
+	if columns <= 0 {
+		columns = defaultColumns
+	}
+	rowHeight := report.RowHeight
+	if rowHeight <= 0 {
+		rowHeight = defaultRowHeight
+	}
+	if rowHeight < minRowHeight {
+		rowHeight = minRowHeight
+	}
+
+	l := &layout{
+		report:    report,
+		width:     reportWidth,
+		columns:   columns,
+		columnW:   (reportWidth - 2*reportMargin) / float64(columns),
+		rowHeight: float64(rowHeight),
+	}
+	rows := 0
+	for _, widget := range report.Widgets {
+		_, _, _, height := l.cells(widget)
+		if bottom := widget.Y + height; bottom > rows {
+			rows = bottom
+		}
+	}
+	l.height = reportMargin + headerHeight + float64(rows)*l.rowHeight + reportMargin
+	return l
+}
+
+// cells returns the grid cells of a widget, kept within the columns
+func (l *layout) cells(widget Widget) (x, y, width, height int) {
+	x, y, width, height = widget.X, widget.Y, widget.Width, widget.Height
+	if width <= 0 {
+		width = l.columns / 2
+	}
+	if height <= 0 {
+		height = 4
+	}
+	if x < 0 {
+		x = 0
+	}
+	if y < 0 {
+		y = 0
+	}
+	if width > l.columns {
+		width = l.columns
+	}
+	if x+width > l.columns {
+		x = l.columns - width
+	}
+	return x, y, width, height
+}
+
+func (l *layout) draw(c canvas) {
+	c.fillRect(0, 0, l.width, l.height, pageColor)
+
+	r := l.report
+	y := reportMargin
+	c.text(reportMargin, y, 28, truncate(c, 28, r.Title, l.width-2*reportMargin), textColor, true)
+	y += 36
+	if r.Description != "" {
+		c.text(reportMargin, y, 15, truncate(c, 15, r.Description, l.width-2*reportMargin), mutedColor, false)
+	}
+	y += 22
+	var period string
+	if r.Start != nil && r.End != nil {
+		period = fmt.Sprintf("%s to %s UTC  |  ", r.Start.UTC().Format("2006-01-02 15:04"), r.End.UTC().Format("2006-01-02 15:04"))
+	}
+	c.text(reportMargin, y, 12, period+"Generated "+r.GeneratedAt.UTC().Format("2006-01-02 15:04 UTC"), mutedColor, false)
+
+	top := reportMargin + headerHeight
+	for _, widget := range r.Widgets {
+		x, y, width, height := l.cells(widget)
+		area := rect{
+			x: reportMargin + float64(x)*l.columnW,
+			y: top + float64(y)*l.rowHeight,
+			w: float64(width)*l.columnW - widgetGap,
+			h: float64(height)*l.rowHeight - widgetGap,
+		}
+		drawWidget(c, area, widget)
+	}
+}
+
+// drawWidget draws a widget's panel, title and content
+func drawWidget(c canvas, area rect, widget Widget) {
+	c.fillRect(area.x, area.y, area.w, area.h, panelColor)
+	strokeRect(c, area, borderColor)
+	c.text(area.x+widgetPadding, area.y+widgetPadding, widgetTitleSize,
+		truncate(c, widgetTitleSize, widget.Title, area.w-2*widgetPadding), textColor, true)
+
+	body := rect{
+		x: area.x + widgetPadding,
+		y: area.y + widgetPadding + widgetTitleSize + 10,
+		w: area.w - 2*widgetPadding,
+		h: area.h - 2*widgetPadding - widgetTitleSize - 10,
+	}
+	if body.w <= 0 || body.h <= 0 {
+		return
+	}
+	if widget.Error != "" {
+		drawLines(c, body, wrap(c, bodyTextSize, "Error: "+widget.Error, body.w), errorColor)
+		return
+	}
+
+	switch data := widget.Data.(type) {
+	case nil:
+		if widget.Type != "text" {
+			drawMessage(c, body, "No data")
+		}
+	case *models.ChartData:
+		drawChart(c, body, data, widget.ChartType)
+	case *models.MetricData:
+		drawMetric(c, body, data)
+	case *models.HistogramData:
+		drawHistogram(c, body, data)
+	case *models.HeatmapData:
+		drawHeatmap(c, body, data)
+	case *models.PieData:
+		drawPie(c, body, data)
+	case *models.TextData:
+		drawText(c, body, data.Markdown)
+	case *models.LogStreamData:
+		drawTable(c, body, data.Logs, []string{"timestamp", "level", "service", "message"})
+	case []map[string]interface{}:
+		drawTable(c, body, data, nil)
+	default:
+		drawMessage(c, body, "This widget cannot be shown in reports")
+	}
+}
+
+// drawChart draws the datasets of a chart as lines, filled areas or
+// grouped bars, with a value axis and a legend when there are several.
+// NaN and infinite values are left out, as if missing.
+func drawChart(c canvas, area rect, data *models.ChartData, chartType string) {
+	points := len(data.Labels)
+	var values []float64
+	for _, dataset := range data.Datasets {
+		if len(dataset.Data) > points {
+			points = len(dataset.Data)
+		}
+		for _, v := range dataset.Data {
+			if isFinite(v) {
+				values = append(values, v)
+			}
+		}
+	}
+	if points == 0 || len(values) == 0 {
+		drawMessage(c, area, "No data")
+		return
+	}
+
+	if len(data.Datasets) > 1 {
+		labels := make([]string, len(data.Datasets))
+		colors := make([]color.RGBA, len(data.Datasets))
+		for i, dataset := range data.Datasets {
+			labels[i], colors[i] = dataset.Label, datasetColor(dataset, i)
+		}
+		area = drawLegend(c, area, labels, colors)
+	}
+	plot, scale := drawValueAxis(c, area, values)
+	drawCategoryLabels(c, plot, data.Labels, points, chartType == "bar")
+
+	if chartType == "bar" {
+		slot := plot.w / float64(points)
+		barW := slot * 0.8 / float64(len(data.Datasets))
+		for d, dataset := range data.Datasets {
+			col := datasetColor(dataset, d)
+			for i, v := range dataset.Data {
+				if !isFinite(v) {
+					continue
+				}
+				x := plot.x + float64(i)*slot + slot*0.1 + float64(d)*barW
+				y0, y1 := scale(0), scale(v)
+				c.fillRect(x, math.Min(y0, y1), math.Max(barW-1, 1), math.Abs(y1-y0), col)
+			}
+		}
+		return
+	}
+
+	for d, dataset := range data.Datasets {
+		col := datasetColor(dataset, d)
+		line := make([]point, 0, len(dataset.Data))
+		for i, v := range dataset.Data {
+			if isFinite(v) {
+				line = append(line, point{categoryX(plot, i, points), scale(v)})
+			}
+		}
+		if len(line) == 0 {
+			continue
+		}
+		if len(line) == 1 {
+			c.fillRect(line[0].x-2, line[0].y-2, 4, 4, col)
+			continue
+		}
+		if chartType == "area" || dataset.Fill {
+			fill := append([]point{{line[0].x, scale(0)}}, line...)
+			fill = append(fill, point{line[len(line)-1].x, scale(0)})
+			c.fillPolygon(fill, translucent(col, 0x40))
+		}
+		c.polyline(line, 2, col)
+	}
+}
+
+// drawMetric draws a metric's value large, colored by its status, with
+// its change below
+func drawMetric(c canvas, area rect, data *models.MetricData) {
+	value := formatNumber(data.Value)
+	if data.Unit != "" {
+		value += " " + data.Unit
+	}
+	col := textColor
+	switch data.Status {
+	case "warning":
+		col = warnColor
+	case "critical":
+		col = errorColor
+	}
+
+	size := math.Min(56, area.h*0.45)
+	for size > 12 && c.textWidth(size, value) > area.w {
+		size -= 4
+	}
+	y := area.y + (area.h-size-40)/2
+	c.text(area.x+(area.w-c.textWidth(size, value))/2, y, size, value, col, true)
+	y += size + 8
+
+	if data.Change != nil {
+		change := fmt.Sprintf("%+.1f%%", *data.Change)
+		if data.ChangeLabel != "" {
+			change += " " + data.ChangeLabel
+		}
+		changeColor := goodColor
+		if *data.Change < 0 {
+			changeColor = errorColor
+		}
+		change = truncate(c, bodyTextSize, change, area.w)
+		c.text(area.x+(area.w-c.textWidth(bodyTextSize, change))/2, y, bodyTextSize, change, changeColor, false)
+	}
+}
+
+// drawHistogram draws a bar per bucket, labelled by the range's bounds
+func drawHistogram(c canvas, area rect, data *models.HistogramData) {
+	if len(data.Buckets) == 0 {
+		drawMessage(c, area, "No data")
+		return
+	}
+	counts := make([]float64, len(data.Buckets))
+	for i, bucket := range data.Buckets {
+		counts[i] = float64(bucket.Count)
+	}
+	plot, scale := drawValueAxis(c, area, counts)
+	drawCategoryLabels(c, plot, []string{formatNumber(data.Min), formatNumber(data.Max)}, 2, false)
+
+	slot := plot.w / float64(len(counts))
+	for i, count := range counts {
+		y := scale(count)
+		c.fillRect(plot.x+float64(i)*slot+0.5, y, math.Max(slot-1, 1), scale(0)-y, palette[0])
+	}
+}
+
+// drawHeatmap shades each cell by its value, category labels on the left
+// and the first and last times below
+func drawHeatmap(c canvas, area rect, data *models.HeatmapData) {
+	if len(data.X) == 0 || len(data.Y) == 0 {
+		drawMessage(c, area, "No data")
+		return
+	}
+	labelW := 0.0
+	for _, y := range data.Y {
+		labelW = math.Max(labelW, c.textWidth(axisTextSize, y))
+	}
+	labelW = math.Min(labelW+8, area.w*0.3)
+	grid := rect{x: area.x + labelW, y: area.y, w: area.w - labelW, h: area.h - axisTextSize - 6}
+	cellW := grid.w / float64(len(data.X))
+	cellH := grid.h / float64(len(data.Y))
+
+	for row, values := range data.Values {
+		y := grid.y + float64(row)*cellH
+		if cellH >= axisTextSize {
+			c.text(area.x, y+(cellH-axisTextSize)/2, axisTextSize, truncate(c, axisTextSize, data.Y[row], labelW-8), mutedColor, false)
+		}
+		for col, value := range values {
+			if value <= 0 || data.Max <= 0 {
+				continue
+			}
+			alpha := uint8(0x20 + 0xdf*math.Min(value/data.Max, 1))
+			c.fillRect(grid.x+float64(col)*cellW, y, cellW, cellH, translucent(palette[0], alpha))
+		}
+	}
+	drawCategoryLabels(c, rect{x: grid.x, y: grid.y, w: grid.w, h: grid.h}, data.X, len(data.X), true)
+}
+
+// drawPie draws the slices of a pie, or a donut, with a legend of their
+// labels and shares beside it
+func drawPie(c canvas, area rect, data *models.PieData) {
+	if len(data.Slices) == 0 || data.Total <= 0 {
+		drawMessage(c, area, "No data")
+		return
+	}
+	radius := math.Min(area.h, area.w/2) / 2
+	center := point{area.x + radius, area.y + area.h/2}
+
+	angle := -math.Pi / 2
+	for i, slice := range data.Slices {
+		sweep := slice.Value / data.Total * 2 * math.Pi
+		wedge := []point{center}
+		steps := int(math.Ceil(sweep / (math.Pi / 60)))
+		for s := 0; s <= steps; s++ {
+			a := angle + sweep*float64(s)/float64(math.Max(float64(steps), 1))
+			wedge = append(wedge, point{center.x + radius*math.Cos(a), center.y + radius*math.Sin(a)})
+		}
+		c.fillPolygon(wedge, palette[i%len(palette)])
+		angle += sweep
+	}
+	if data.Donut {
+		var hole []point
+		for s := 0; s < 120; s++ {
+			a := float64(s) / 120 * 2 * math.Pi
+			hole = append(hole, point{center.x + radius*0.55*math.Cos(a), center.y + radius*0.55*math.Sin(a)})
+		}
+		c.fillPolygon(hole, panelColor)
+	}
+
+	legend := rect{x: area.x + 2*radius + 16, y: area.y, w: area.w - 2*radius - 16, h: area.h}
+	lineH := bodyTextSize + 6
+	for i, slice := range data.Slices {
+		y := legend.y + float64(i)*lineH
+		if y+lineH > legend.y+legend.h {
+			break
+		}
+		c.fillRect(legend.x, y+2, 10, 10, palette[i%len(palette)])
+		share := fmt.Sprintf("%.1f%%", slice.Percent)
+		c.text(legend.x+legend.w-c.textWidth(bodyTextSize, share), y, bodyTextSize, share, mutedColor, false)
+		label := truncate(c, bodyTextSize, slice.Label, legend.w-24-c.textWidth(bodyTextSize, share)-8)
+		c.text(legend.x+16, y, bodyTextSize, label, textColor, false)
+	}
+}
+
+// drawTable draws rows under a header, as many as fit. Columns are those
+// of preferred the rows have, followed by the others in name order.
+func drawTable(c canvas, area rect, rows []map[string]interface{}, preferred []string) {
+	if len(rows) == 0 {
+		drawMessage(c, area, "No rows")
+		return
+	}
+	present := make(map[string]bool)
+	for _, row := range rows {
+		for column := range row {
+			present[column] = true
+		}
+	}
+	var columns, rest []string
+	for _, column := range preferred {
+		if present[column] {
+			columns = append(columns, column)
+			delete(present, column)
+		}
+	}
+	for column := range present {
+		rest = append(rest, column)
+	}
+	sort.Strings(rest)
+	columns = append(columns, rest...)
+
+	lineH := bodyTextSize + 7
+	maxColumns := int(math.Max(1, area.w/80))
+	if len(columns) > maxColumns {
+		columns = columns[:maxColumns]
+	}
+	columnW := area.w / float64(len(columns))
+	for i, column := range columns {
+		c.text(area.x+float64(i)*columnW, area.y, bodyTextSize, truncate(c, bodyTextSize, column, columnW-8), textColor, true)
+	}
+	c.fillRect(area.x, area.y+lineH-3, area.w, 1, borderColor)
+
+	fit := int((area.h - lineH) / lineH)
+	if fit < len(rows) {
+		fit--
+	}
+	for r := 0; r < fit && r < len(rows); r++ {
+		y := area.y + float64(r+1)*lineH
+		for i, column := range columns {
+			value := cellText(rows[r][column])
+			c.text(area.x+float64(i)*columnW, y, bodyTextSize, truncate(c, bodyTextSize, value, columnW-8), textColor, false)
+		}
+	}
+	if fit >= 0 && fit < len(rows) {
+		c.text(area.x, area.y+float64(fit+1)*lineH, bodyTextSize, fmt.Sprintf("%d more rows", len(rows)-fit), mutedColor, false)
+	}
+}
+
+// drawValueAxis draws the gridlines and labels of a value axis covering
+// values and zero in round steps, returning the plot area right of it and
+// the y of a value
+func drawValueAxis(c canvas, area rect, values []float64) (rect, func(float64) float64) {
+	lo, hi := 0.0, 0.0
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+	step := niceStep((hi - lo) / 4)
+	lo, hi = math.Floor(lo/step)*step, math.Ceil(hi/step)*step
+	ticks := int(math.Round((hi - lo) / step))
+	labelW := 0.0
+	for i := 0; i <= ticks; i++ {
+		labelW = math.Max(labelW, c.textWidth(axisTextSize, formatNumber(lo+float64(i)*step)))
+	}
+
+	plot := rect{x: area.x + labelW + 8, y: area.y + axisTextSize/2, w: area.w - labelW - 8, h: area.h - axisTextSize*2 - 8}
+	scale := func(v float64) float64 { return plot.y + plot.h - (v-lo)/(hi-lo)*plot.h }
+	for i := 0; i <= ticks; i++ {
+		v := lo + float64(i)*step
+		y := scale(v)
+		c.fillRect(plot.x, y, plot.w, 1, gridColor)
+		label := formatNumber(v)
+		c.text(plot.x-8-c.textWidth(axisTextSize, label), y-axisTextSize/2, axisTextSize, label, mutedColor, false)
+	}
+	return plot, scale
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// niceStep rounds a step up to 1, 2 or 5 times a power of ten
+func niceStep(step float64) float64 {
+	magnitude := math.Pow(10, math.Floor(math.Log10(step)))
+	for _, m := range []float64{1, 2, 5} {
+		if step <= m*magnitude {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// drawCategoryLabels labels the x axis below a plot with as many of the
+// labels as fit, evenly spaced. Labels are centered on bars or on points.
+func drawCategoryLabels(c canvas, plot rect, labels []string, points int, bars bool) {
+	if len(labels) == 0 || points == 0 {
+		return
+	}
+	widest := 0.0
+	for _, label := range labels {
+		widest = math.Max(widest, c.textWidth(axisTextSize, label))
+	}
+	fit := int(math.Max(1, plot.w/(math.Min(widest, 160)+16)))
+	step := int(math.Ceil(float64(len(labels)) / float64(fit)))
+
+	y := plot.y + plot.h + 6
+	for i := 0; i < len(labels); i += step {
+		label := truncate(c, axisTextSize, labels[i], 160)
+		x := categoryX(plot, i, points)
+		if bars {
+			x = plot.x + (float64(i)+0.5)*plot.w/float64(points)
+		}
+		x -= c.textWidth(axisTextSize, label) / 2
+		x = math.Max(plot.x, math.Min(x, plot.x+plot.w-c.textWidth(axisTextSize, label)))
+		c.text(x, y, axisTextSize, label, mutedColor, false)
+	}
+}
+
+// drawLegend draws a row of series labels above area and returns the rest
+func drawLegend(c canvas, area rect, labels []string, colors []color.RGBA) rect {
+	x := area.x
+	for i, label := range labels {
+		label = truncate(c, axisTextSize, label, 200)
+		width := c.textWidth(axisTextSize, label) + 22
+		if x+width > area.x+area.w {
+			break
+		}
+		c.fillRect(x, area.y+2, 10, 10, colors[i])
+		c.text(x+14, area.y, axisTextSize, label, textColor, false)
+		x += width
+	}
+	return rect{x: area.x, y: area.y + axisTextSize + 8, w: area.w, h: area.h - axisTextSize - 8}
+}
+
+func drawMessage(c canvas, area rect, message string) {
+	c.text(area.x+(area.w-c.textWidth(bodyTextSize, message))/2, area.y+(area.h-bodyTextSize)/2, bodyTextSize, message, mutedColor, false)
+}
+
+// markdownMarks removes the emphasis and code marks of markdown text
+var markdownMarks = strings.NewReplacer("**", "", "__", "", "`", "")
+
+// drawText draws the markdown of a text widget as plain text, a line per
+// paragraph or list item, wrapped to the widget
+func drawText(c canvas, area rect, source string) {
+	var lines []string
+	for _, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			continue
+		}
+		line = markdownMarks.Replace(strings.TrimLeft(line, "#> "))
+		if line == "" {
+			continue
+		}
+		lines = append(lines, wrap(c, bodyTextSize, line, area.w)...)
+	}
+	drawLines(c, area, lines, textColor)
+}
+
+func drawLines(c canvas, area rect, lines []string, col color.RGBA) {
+	lineH := bodyTextSize + 5
+	for i, line := range lines {
+		if float64(i+1)*lineH > area.h {
+			break
+		}
+		c.text(area.x, area.y+float64(i)*lineH, bodyTextSize, line, col, false)
+	}
+}
+
+func strokeRect(c canvas, r rect, col color.RGBA) {
+	c.polyline([]point{{r.x, r.y}, {r.x + r.w, r.y}, {r.x + r.w, r.y + r.h}, {r.x, r.y + r.h}, {r.x, r.y}}, 1, col)
+}
+
+// categoryX is the x of the i-th of n points spread across a plot
+func categoryX(plot rect, i, n int) float64 {
+	if n <= 1 {
+		return plot.x + plot.w/2
+	}
+	return plot.x + float64(i)*plot.w/float64(n-1)
+}
+
+// truncate shortens s with an ellipsis to fit width
+func truncate(c canvas, size float64, s string, width float64) string {
+	if c.textWidth(size, s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && c.textWidth(size, string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	if len(runes) == 0 {
+		return ""
+	}
+	return string(runes) + "..."
+}
+
+// wrap breaks s into lines of words that fit width
+func wrap(c canvas, size float64, s string, width float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if line != "" && c.textWidth(size, candidate) > width {
+			lines = append(lines, truncate(c, size, line, width))
+			candidate = word
+		}
+		line = candidate
+	}
+	if line != "" {
+		lines = append(lines, truncate(c, size, line, width))
+	}
+	return lines
+}
+
+// formatNumber formats a value compactly, e.g. 950, 12.5k or 3.1M
+func formatNumber(v float64) string {
+	abs := math.Abs(v)
+	switch {
+	case abs >= 1e9:
+		return trimZeros(strconv.FormatFloat(v/1e9, 'f', 1, 64)) + "B"
+	case abs >= 1e6:
+		return trimZeros(strconv.FormatFloat(v/1e6, 'f', 1, 64)) + "M"
+	case abs >= 1e4:
+		return trimZeros(strconv.FormatFloat(v/1e3, 'f', 1, 64)) + "k"
+	case abs >= 100 || v == math.Trunc(v):
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	default:
+		return trimZeros(strconv.FormatFloat(v, 'f', 2, 64))
+	}
+}
+
+func trimZeros(s string) string {
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+func cellText(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(fmt.Sprintf("%v", value)), " ")
+}
+
+// datasetColor is a dataset's border or background color, or else one
+// from the palette
+func datasetColor(dataset models.ChartDataset, i int) color.RGBA {
+	for _, s := range []string{dataset.BorderColor, dataset.BackgroundColor} {
+		if col, ok := parseColor(s); ok {
+			col.A = 0xff
+			return col
+		}
+	}
+	return palette[i%len(palette)]
+}
+
+// parseColor reads the #rgb, #rrggbb, rgb() and rgba() colors charts use
+func parseColor(s string) (color.RGBA, bool) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if strings.HasPrefix(s, "#") {
+		hex := s[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) != 6 {
+			return color.RGBA{}, false
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return color.RGBA{}, false
+		}
+		return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}, true
+	}
+
+	open, end := strings.Index(s, "("), strings.LastIndex(s, ")")
+	if (!strings.HasPrefix(s, "rgb(") && !strings.HasPrefix(s, "rgba(")) || end < open {
+		return color.RGBA{}, false
+	}
+	parts := strings.Split(s[open+1:end], ",")
+	if len(parts) < 3 {
+		return color.RGBA{}, false
+	}
+	var channels [3]uint8
+	for i := range channels {
+		v, err := strconv.ParseFloat(strings.TrimSpace(parts[i]), 64)
+		if err != nil {
+			return color.RGBA{}, false
+		}
+		channels[i] = uint8(math.Max(0, math.Min(255, v)))
+	}
+	return color.RGBA{channels[0], channels[1], channels[2], 0xff}, true
+}
+
+func translucent(col color.RGBA, alpha uint8) color.RGBA {
+	col.A = alpha
+	return col
+}
+
+// onWhite mixes a translucent color onto white
+func onWhite(col color.RGBA) color.RGBA {
+	if col.A == 0xff {
+		return col
+	}
+	a := uint32(col.A)
+	mix := func(v uint8) uint8 { return uint8((uint32(v)*a + 0xff*(0xff-a)) / 0xff) }
+	return color.RGBA{mix(col.R), mix(col.G), mix(col.B), 0xff}
+}
@@ -0,0 +1,175 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+)
+
+const (
+	// pdfScale is the size of a report unit in points, fitting the
+	// report's width on a landscape page
+	pdfScale = 0.7
+	// pdfEm is the font size of a line of text, relative to its height
+	pdfEm = 0.9
+)
+
+// helveticaWidths are the advance widths of Helvetica for printable ASCII,
+// in thousandths of the font size
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// pdfCanvas draws a report as the content of a single page PDF, with the
+// report's units scaled to points and its y axis pointing down
+type pdfCanvas struct {
+	width, height float64
+	content       bytes.Buffer
+}
+
+func newPDFCanvas(width, height float64) *pdfCanvas {
+	c := &pdfCanvas{width: width * pdfScale, height: height * pdfScale}
+	fmt.Fprintf(&c.content, "%s 0 0 %s 0 %s cm\n", num(pdfScale), num(-pdfScale), num(c.height))
+	return c
+}
+
+func (c *pdfCanvas) fillRect(x, y, w, h float64, col color.RGBA) {
+	fmt.Fprintf(&c.content, "%s rg %s %s %s %s re f\n", pdfColor(col), num(x), num(y), num(w), num(h))
+}
+
+func (c *pdfCanvas) polyline(points []point, width float64, col color.RGBA) {
+	if len(points) < 2 {
+		return
+	}
+	fmt.Fprintf(&c.content, "%s RG %s w 1 J 1 j ", pdfColor(col), num(width))
+	c.path(points)
+	c.content.WriteString("S\n")
+}
+
+func (c *pdfCanvas) fillPolygon(points []point, col color.RGBA) {
+	if len(points) < 3 {
+		return
+	}
+	fmt.Fprintf(&c.content, "%s rg ", pdfColor(col))
+	c.path(points)
+	c.content.WriteString("h f\n")
+}
+
+func (c *pdfCanvas) path(points []point) {
+	for i, p := range points {
+		op := "l"
+		if i == 0 {
+			op = "m"
+		}
+		fmt.Fprintf(&c.content, "%s %s %s ", num(p.x), num(p.y), op)
+	}
+}
+
+// text places the baseline most of the way down the line, flipping the
+// text matrix back upright against the page's inverted y axis
+func (c *pdfCanvas) text(x, y, size float64, s string, col color.RGBA, bold bool) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&c.content, "BT %s rg /%s %s Tf 1 0 0 -1 %s %s Tm (%s) Tj ET\n",
+		pdfColor(col), font, num(size*pdfEm), num(x), num(y+size*0.78), pdfString(s))
+}
+
+func (c *pdfCanvas) textWidth(size float64, s string) float64 {
+	var width int
+	for _, r := range s {
+		if r < ' ' || r > '~' {
+			r = '?'
+		}
+		width += helveticaWidths[r-' ']
+	}
+	return float64(width) / 1000 * size * pdfEm
+}
+
+// writeTo writes the PDF: catalog, page tree, page, content and the two
+// standard fonts, followed by the cross-reference table
+func (c *pdfCanvas) writeTo(w io.Writer) error {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Contents 4 0 R /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>",
+			num(c.width), num(c.height)),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", c.content.Len(), c.content.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	if _, err := w.Write(out.Bytes()); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// pdfColor is an RGB color operand, translucent colors mixed onto white
+func pdfColor(col color.RGBA) string {
+	col = onWhite(col)
+	return fmt.Sprintf("%s %s %s", num(float64(col.R)/255), num(float64(col.G)/255), num(float64(col.B)/255))
+}
+
+// pdfString escapes a string literal in WinAnsiEncoding, which matches
+// Latin-1 for the characters it shares with it
+func pdfString(s string) string {
+	var b bytes.Buffer
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= ' ' && r <= '~':
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		case r == '\t' || r == '\n':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// maxPDFNumber is the largest real number PDF readers must support
+const maxPDFNumber = 32767
+
+// num formats a number operand to a hundredth of a point. NaN and
+// infinities would corrupt the content stream, so NaN is written as 0 and
+// values beyond what readers support are clamped.
+func num(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		f = 0
+	case f > maxPDFNumber:
+		f = maxPDFNumber
+	case f < -maxPDFNumber:
+		f = -maxPDFNumber
+	}
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,59 @@
+package report
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+func TestNumNonFinite(t *testing.T) {
+	cases := map[float64]string{
+		math.NaN():   "0",
+		math.Inf(1):  "32767",
+		math.Inf(-1): "-32767",
+		1e300:        "32767",
+		12.345:       "12.35",
+		-0.5:         "-0.5",
+	}
+	for f, want := range cases {
+		if got := num(f); got != want {
+			t.Errorf("num(%v) = %s, want %s", f, got, want)
+		}
+	}
+}
+
+func TestRenderNonFiniteChart(t *testing.T) {
+	report := &Report{
+		Title:       "Errors",
+		GeneratedAt: time.Now(),
+		Widgets: []Widget{
+			{Title: "Rate", Type: "chart", ChartType: "line", Width: 6, Height: 4, Data: &models.ChartData{
+				Labels:   []string{"a", "b", "c", "d"},
+				Datasets: []models.ChartDataset{{Label: "rate", Data: []float64{1, math.NaN(), math.Inf(1), 3}, Fill: true}},
+			}},
+			{Title: "Count", Type: "chart", ChartType: "bar", X: 6, Width: 6, Height: 4, Data: &models.ChartData{
+				Labels:   []string{"a", "b"},
+				Datasets: []models.ChartDataset{{Label: "count", Data: []float64{math.Inf(-1), 2}}},
+			}},
+		},
+	}
+
+	var pdf bytes.Buffer
+	if err := Render(&pdf, report, FormatPDF); err != nil {
+		t.Fatalf("Render PDF error: %v", err)
+	}
+	for _, invalid := range []string{"NaN", "Inf"} {
+		if strings.Contains(pdf.String(), invalid) {
+			t.Errorf("PDF content contains %s", invalid)
+		}
+	}
+
+	var png bytes.Buffer
+	if err := Render(&png, report, FormatPNG); err != nil {
+		t.Fatalf("Render PNG error: %v", err)
+	}
+}
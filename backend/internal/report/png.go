@@ -0,0 +1,148 @@
+package report
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+)
+
+// pngCanvas draws a report on an image, one pixel per report unit
+type pngCanvas struct {
+	img *image.RGBA
+}
+
+func newPNGCanvas(width, height float64) *pngCanvas {
+	return &pngCanvas{img: image.NewRGBA(image.Rect(0, 0, int(math.Ceil(width)), int(math.Ceil(height))))}
+}
+
+func (c *pngCanvas) writeTo(w io.Writer) error {
+	if err := png.Encode(w, c.img); err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	return nil
+}
+
+// blend paints a pixel, mixing in colors that are not opaque
+func (c *pngCanvas) blend(x, y int, col color.RGBA) {
+	if !(image.Point{x, y}.In(c.img.Rect)) {
+		return
+	}
+	if col.A == 0xff {
+		c.img.SetRGBA(x, y, col)
+		return
+	}
+	under := c.img.RGBAAt(x, y)
+	a := uint32(col.A)
+	mix := func(top, bottom uint8) uint8 {
+		return uint8((uint32(top)*a + uint32(bottom)*(0xff-a)) / 0xff)
+	}
+	c.img.SetRGBA(x, y, color.RGBA{mix(col.R, under.R), mix(col.G, under.G), mix(col.B, under.B), 0xff})
+}
+
+func (c *pngCanvas) fillRect(x, y, w, h float64, col color.RGBA) {
+	x0, y0 := int(math.Round(x)), int(math.Round(y))
+	x1, y1 := int(math.Round(x+w)), int(math.Round(y+h))
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			c.blend(px, py, col)
+		}
+	}
+}
+
+// polyline stamps a square of the line width along each segment
+func (c *pngCanvas) polyline(points []point, width float64, col color.RGBA) {
+	half := int(math.Max(0, math.Round(width/2-0.5)))
+	stamped := make(map[image.Point]bool)
+	stamp := func(x, y int) {
+		for dy := -half; dy <= half; dy++ {
+			for dx := -half; dx <= half; dx++ {
+				p := image.Point{x + dx, y + dy}
+				if !stamped[p] {
+					stamped[p] = true
+					c.blend(p.X, p.Y, col)
+				}
+			}
+		}
+	}
+	for i := range points {
+		if i == 0 {
+			stamp(int(math.Round(points[0].x)), int(math.Round(points[0].y)))
+			continue
+		}
+		from, to := points[i-1], points[i]
+		steps := int(math.Max(math.Abs(to.x-from.x), math.Abs(to.y-from.y)))
+		for s := 1; s <= steps; s++ {
+			t := float64(s) / float64(steps)
+			stamp(int(math.Round(from.x+(to.x-from.x)*t)), int(math.Round(from.y+(to.y-from.y)*t)))
+		}
+	}
+}
+
+// fillPolygon fills the pixels whose centers are inside the polygon
+func (c *pngCanvas) fillPolygon(points []point, col color.RGBA) {
+	if len(points) < 3 {
+		return
+	}
+	minY, maxY := points[0].y, points[0].y
+	for _, p := range points {
+		minY, maxY = math.Min(minY, p.y), math.Max(maxY, p.y)
+	}
+	var xs []float64
+	for py := int(math.Floor(minY)); py <= int(math.Ceil(maxY)); py++ {
+		y := float64(py) + 0.5
+		xs = xs[:0]
+		for i := range points {
+			a, b := points[i], points[(i+1)%len(points)]
+			if (a.y <= y) != (b.y <= y) {
+				xs = append(xs, a.x+(y-a.y)/(b.y-a.y)*(b.x-a.x))
+			}
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			for px := int(math.Ceil(xs[i] - 0.5)); float64(px)+0.5 <= xs[i+1]; px++ {
+				c.blend(px, py, col)
+			}
+		}
+	}
+}
+
+// pngGlyphScale is the pixel size of a font dot for a line size high.
+// A glyph cell is 6x8 dots including spacing.
+func pngGlyphScale(size float64) int {
+	return int(math.Max(1, math.Round(size/8)))
+}
+
+func (c *pngCanvas) text(x, y, size float64, s string, col color.RGBA, bold bool) {
+	scale := pngGlyphScale(size)
+	top := int(math.Round(y + (size-float64(8*scale))/2))
+	left := int(math.Round(x))
+	for _, r := range s {
+		g := glyph(r)
+		for gx, column := range g {
+			for gy := 0; gy < 7; gy++ {
+				if column&(1<<gy) == 0 {
+					continue
+				}
+				px, py := left+gx*scale, top+gy*scale
+				width := scale
+				if bold {
+					width++
+				}
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < width; dx++ {
+						c.blend(px+dx, py+dy, col)
+					}
+				}
+			}
+		}
+		left += 6 * scale
+	}
+}
+
+func (c *pngCanvas) textWidth(size float64, s string) float64 {
+	return float64(6 * pngGlyphScale(size) * len([]rune(s)))
+}
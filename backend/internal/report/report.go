@@ -0,0 +1,98 @@
+// Package report renders dashboards to PDF and PNG on the server, so they
+// can be downloaded or sent on a schedule without a browser.
+//
+// Widgets are drawn from the data the dashboard service generates for
+// them, on the dashboard's grid, with charts, metrics, tables and the
+// other widget types laid out as the dashboard shows them. PDFs use the
+// standard Helvetica font and vector drawing; PNGs a built-in bitmap font.
+//
+// Neither embeds a font, so text is limited to what those cover: Latin-1
+// in PDFs and printable ASCII in PNGs. Other characters, such as accented
+// letters in PNGs or CJK and emoji in both, are drawn as '?'.
+package report
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"time"
+)
+
+// Format is a report file format
+type Format string
+
+const (
+	FormatPDF Format = "pdf"
+	FormatPNG Format = "png"
+)
+
+// ParseFormat returns the format named by s, PDF when empty
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", FormatPDF:
+		return FormatPDF, nil
+	case FormatPNG:
+		return FormatPNG, nil
+	}
+	return "", fmt.Errorf("invalid report format: %s (must be pdf or png)", s)
+}
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	if f == FormatPNG {
+		return "image/png"
+	}
+	return "application/pdf"
+}
+
+// Report is a dashboard and the data of its widgets
+type Report struct {
+	Title       string
+	Description string
+	Start       *time.Time // the time range the widgets read, if any
+	End         *time.Time
+	GeneratedAt time.Time
+	Columns     int // grid columns, 12 when 0
+	RowHeight   int // grid row height, 60 when 0
+	Widgets     []Widget
+}
+
+// Widget is a widget of a report, placed in grid units
+type Widget struct {
+	Title     string
+	Type      string
+	ChartType string
+	X, Y      int
+	Width     int
+	Height    int
+	Data      interface{} // as generated for the widget type
+	Error     string
+}
+
+// canvas is what a report is drawn on. Coordinates are in report units
+// from the top left; text is placed by the top of its line, size high.
+type canvas interface {
+	fillRect(x, y, w, h float64, c color.RGBA)
+	polyline(points []point, width float64, c color.RGBA)
+	fillPolygon(points []point, c color.RGBA)
+	text(x, y, size float64, s string, c color.RGBA, bold bool)
+	textWidth(size float64, s string) float64
+}
+
+type point struct{ x, y float64 }
+
+// Render writes a report in the given format
+func Render(w io.Writer, report *Report, format Format) error {
+	layout := newLayout(report)
+	switch format {
+	case FormatPDF:
+		pdf := newPDFCanvas(layout.width, layout.height)
+		layout.draw(pdf)
+		return pdf.writeTo(w)
+	case FormatPNG:
+		png := newPNGCanvas(layout.width, layout.height)
+		layout.draw(png)
+		return png.writeTo(w)
+	}
+	return fmt.Errorf("invalid report format: %s (must be pdf or png)", format)
+}
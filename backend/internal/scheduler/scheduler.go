@@ -0,0 +1,831 @@
+// Package scheduler runs saved queries on cron schedules, stores their
+// results and optionally raises alerts or writes export files from them.
+// Schedules can also render a dashboard report on each run, with or
+// without a query.
+package scheduler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/monitoring"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/query"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/report"
+)
+
+// Destination types
+const (
+	DestinationResults = "results" // rows kept in scheduled_query_results
+	DestinationTable   = "table"   // rows appended to an existing table
+)
+
+// Run statuses
+const (
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+const (
+	maxRunHistory = 50
+	runTimeout    = 5 * time.Minute
+	resultsTable  = "scheduled_query_results"
+)
+
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Destination says where the rows of each run are stored
+type Destination struct {
+	Type  string `json:"type"`
+	Table string `json:"table,omitempty"`
+}
+
+// AlertCondition raises an alert when a run's result crosses a threshold.
+// Without a column the row count is compared; otherwise the column's value
+// in the first row.
+type AlertCondition struct {
+	Column    string  `json:"column,omitempty"`
+	Operator  string  `json:"operator"` // >, >=, <, <=, ==, !=
+	Threshold float64 `json:"threshold"`
+	Severity  string  `json:"severity,omitempty"`
+}
+
+// ExportTarget writes each run's rows to a file in the export directory
+type ExportTarget struct {
+	Format string `json:"format"` // csv or json
+}
+
+// ReportTarget renders a dashboard, over its own time range, to a file in
+// the export directory
+type ReportTarget struct {
+	DashboardID string `json:"dashboard_id"`
+	Format      string `json:"format,omitempty"` // pdf or png, pdf when empty
+}
+
+// ReportRenderer renders dashboard reports, as the dashboard service does
+type ReportRenderer interface {
+	RenderReport(ctx context.Context, dashboardID string, timeRange *models.QueryTimeRange, format report.Format, w io.Writer) error
+}
+
+// ScheduledQuery runs a saved query on a cron schedule as its owner. A
+// schedule with a report may have no query.
+type ScheduledQuery struct {
+	ID           string                 `json:"id"`
+	Name         string                 `json:"name"`
+	QueryID      string                 `json:"query_id,omitempty"`
+	Schedule     string                 `json:"schedule"`
+	Parameters   map[string]interface{} `json:"parameters,omitempty"`
+	Destination  Destination            `json:"destination"`
+	Alert        *AlertCondition        `json:"alert,omitempty"`
+	Export       *ExportTarget          `json:"export,omitempty"`
+	Report       *ReportTarget          `json:"report,omitempty"`
+	Enabled      bool                   `json:"enabled"`
+	Owner        auth.User              `json:"owner"`
+	CreatedAt    time.Time              `json:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+	LastRun      *time.Time             `json:"last_run,omitempty"`
+	NextRun      *time.Time             `json:"next_run,omitempty"`
+	LastStatus   string                 `json:"last_status,omitempty"`
+	LastError    string                 `json:"last_error,omitempty"`
+	LastRowCount int                    `json:"last_row_count"`
+}
+
+// Run is one execution of a scheduled query
+type Run struct {
+	ID         string    `json:"id"`
+	ScheduleID string    `json:"schedule_id"`
+	Manual     bool      `json:"manual,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Status     string    `json:"status"`
+	RowCount   int       `json:"row_count"`
+	Error      string    `json:"error,omitempty"`
+	Alerted    bool      `json:"alerted,omitempty"`
+	ExportFile string    `json:"export_file,omitempty"`
+	ReportFile string    `json:"report_file,omitempty"`
+}
+
+// Storage persists schedule definitions
+type Storage interface {
+	Save(schedule *ScheduledQuery) error
+	LoadAll() ([]*ScheduledQuery, error)
+	Delete(id string) error
+}
+
+// Scheduler owns the schedules and runs them when they are due
+type Scheduler struct {
+	engine    *query.Engine
+	db        query.SQLExecutor
+	alerts    *monitoring.AlertManager
+	storage   Storage
+	reports   ReportRenderer
+	exportDir string
+
+	mu        sync.RWMutex
+	schedules map[string]*ScheduledQuery
+	parsed    map[string]*Schedule
+	running   map[string]bool
+	runs      map[string][]*Run
+}
+
+// NewScheduler creates a scheduler and the table holding run results.
+// alerts may be nil, in which case alert conditions are only recorded on runs.
+func NewScheduler(engine *query.Engine, db query.SQLExecutor, alerts *monitoring.AlertManager, exportDir string) (*Scheduler, error) {
+	ddl := fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		schedule_id String,
+		run_id String,
+		run_time DateTime64(3),
+		row_number UInt32,
+		row String
+	) ENGINE = MergeTree()
+	ORDER BY (schedule_id, run_time, row_number)
+	TTL toDateTime(run_time) + INTERVAL 30 DAY
+	`, resultsTable)
+	if err := db.Execute(context.Background(), ddl); err != nil {
+		return nil, fmt.Errorf("failed to create scheduled query results table: %w", err)
+	}
+
+	return &Scheduler{
+		engine:    engine,
+		db:        db,
+		alerts:    alerts,
+		exportDir: exportDir,
+		schedules: make(map[string]*ScheduledQuery),
+		parsed:    make(map[string]*Schedule),
+		running:   make(map[string]bool),
+		runs:      make(map[string][]*Run),
+	}, nil
+}
+
+// SetStorage sets the persistence backend and loads the stored schedules
+func (s *Scheduler) SetStorage(storage Storage) error {
+	stored, err := storage.LoadAll()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.storage = storage
+	now := time.Now().UTC()
+	for _, sq := range stored {
+		parsed, err := ParseSchedule(sq.Schedule)
+		if err != nil {
+			log.Warn().Err(err).Str("schedule_id", sq.ID).Msg("Skipping scheduled query with invalid schedule")
+			continue
+		}
+		next := parsed.Next(now)
+		sq.NextRun = &next
+		s.schedules[sq.ID] = sq
+		s.parsed[sq.ID] = parsed
+	}
+	return nil
+}
+
+// SetReportRenderer sets what renders the reports of schedules, without
+// which schedules cannot have one
+func (s *Scheduler) SetReportRenderer(reports ReportRenderer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports = reports
+}
+
+// Create validates and registers a schedule owned by the user in ctx
+func (s *Scheduler) Create(ctx context.Context, sq *ScheduledQuery) (*ScheduledQuery, error) {
+	user := auth.UserFromContext(ctx)
+	sq.ID = uuid.New().String()
+	sq.Owner = user
+	sq.CreatedAt = time.Now().UTC()
+	sq.UpdatedAt = sq.CreatedAt
+	sq.LastRun, sq.LastStatus, sq.LastError, sq.LastRowCount = nil, "", "", 0
+
+	parsed, err := s.validate(ctx, sq)
+	if err != nil {
+		return nil, err
+	}
+	next := parsed.Next(time.Now().UTC())
+	sq.NextRun = &next
+
+	if err := s.persist(sq); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.schedules[sq.ID] = sq
+	s.parsed[sq.ID] = parsed
+	snapshot := *sq
+	s.mu.Unlock()
+
+	log.Info().Str("schedule_id", sq.ID).Str("query_id", sq.QueryID).Str("schedule", sq.Schedule).Msg("Scheduled query created")
+	return &snapshot, nil
+}
+
+// Update replaces the definition of a schedule, keeping its owner and run state
+func (s *Scheduler) Update(ctx context.Context, id string, changes *ScheduledQuery) (*ScheduledQuery, error) {
+	s.mu.RLock()
+	current, err := s.lookup(ctx, id)
+	var updated ScheduledQuery
+	if err == nil {
+		updated = *current
+	}
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	updated.Name = changes.Name
+	updated.QueryID = changes.QueryID
+	updated.Schedule = changes.Schedule
+	updated.Parameters = changes.Parameters
+	updated.Destination = changes.Destination
+	updated.Alert = changes.Alert
+	updated.Export = changes.Export
+	updated.Report = changes.Report
+	updated.Enabled = changes.Enabled
+	updated.UpdatedAt = time.Now().UTC()
+
+	// Validate as the owner, who is the user the query will run as
+	parsed, err := s.validate(auth.WithUser(ctx, updated.Owner), &updated)
+	if err != nil {
+		return nil, err
+	}
+	next := parsed.Next(time.Now().UTC())
+	updated.NextRun = &next
+
+	if err := s.persist(&updated); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.schedules[id] = &updated
+	s.parsed[id] = parsed
+	snapshot := updated
+	s.mu.Unlock()
+	return &snapshot, nil
+}
+
+// Delete removes a schedule. Stored results expire with the results table TTL.
+func (s *Scheduler) Delete(ctx context.Context, id string) error {
+	s.mu.RLock()
+	_, err := s.lookup(ctx, id)
+	storage := s.storage
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if storage != nil {
+		if err := storage.Delete(id); err != nil {
+			return fmt.Errorf("failed to delete schedule: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	delete(s.schedules, id)
+	delete(s.parsed, id)
+	delete(s.runs, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// Get returns a schedule visible to the user in ctx
+func (s *Scheduler) Get(ctx context.Context, id string) (*ScheduledQuery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sq, err := s.lookup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := *sq
+	return &snapshot, nil
+}
+
+// List returns the schedules of the user in ctx, or all of them for admins
+func (s *Scheduler) List(ctx context.Context) []*ScheduledQuery {
+	user := auth.UserFromContext(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedules := make([]*ScheduledQuery, 0)
+	for _, sq := range s.schedules {
+		if sq.Owner.ID == user.ID || user.IsAdmin() {
+			snapshot := *sq
+			schedules = append(schedules, &snapshot)
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].CreatedAt.Before(schedules[j].CreatedAt) })
+	return schedules
+}
+
+// Runs returns the recent runs of a schedule, newest first
+func (s *Scheduler) Runs(ctx context.Context, id string) ([]*Run, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, err := s.lookup(ctx, id); err != nil {
+		return nil, err
+	}
+	history := s.runs[id]
+	runs := make([]*Run, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		copied := *history[i]
+		runs = append(runs, &copied)
+	}
+	return runs, nil
+}
+
+// Results returns the rows stored for a run. An empty runID selects the
+// latest run that stored results.
+func (s *Scheduler) Results(ctx context.Context, id, runID string) (string, []map[string]interface{}, error) {
+	s.mu.RLock()
+	sq, err := s.lookup(ctx, id)
+	var destination Destination
+	if err == nil {
+		destination = sq.Destination
+	}
+	s.mu.RUnlock()
+	if err != nil {
+		return "", nil, err
+	}
+	if destination.Type == DestinationTable {
+		return "", nil, fmt.Errorf("results of this schedule are written to %s", destination.Table)
+	}
+
+	if runID == "" {
+		rows, err := s.db.ExecuteSQL(fmt.Sprintf(
+			"SELECT run_id FROM %s WHERE schedule_id = %s ORDER BY run_time DESC LIMIT 1",
+			resultsTable, chsql.Quote(id)))
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to find latest run: %w", err)
+		}
+		if len(rows) == 0 {
+			return "", []map[string]interface{}{}, nil
+		}
+		runID, _ = rows[0]["run_id"].(string)
+	}
+
+	rows, err := s.db.ExecuteSQL(fmt.Sprintf(
+		"SELECT row FROM %s WHERE schedule_id = %s AND run_id = %s ORDER BY row_number",
+		resultsTable, chsql.Quote(id), chsql.Quote(runID)))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load results: %w", err)
+	}
+	results := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		var decoded map[string]interface{}
+		if text, ok := row["row"].(string); ok && json.Unmarshal([]byte(text), &decoded) == nil {
+			results = append(results, decoded)
+		}
+	}
+	return runID, results, nil
+}
+
+// RunNow executes a schedule immediately and waits for it to finish
+func (s *Scheduler) RunNow(ctx context.Context, id string) (*Run, error) {
+	s.mu.Lock()
+	sq, err := s.lookup(ctx, id)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	if s.running[id] {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("schedule is already running")
+	}
+	s.running[id] = true
+	snapshot := *sq
+	s.mu.Unlock()
+
+	return s.execute(&snapshot, true), nil
+}
+
+// Start runs due schedules until the context is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.runDue(time.Now().UTC())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Scheduler) runDue(now time.Time) {
+	s.mu.Lock()
+	var due []ScheduledQuery
+	for id, sq := range s.schedules {
+		if !sq.Enabled || sq.NextRun == nil || sq.NextRun.After(now) || s.running[id] {
+			continue
+		}
+		s.running[id] = true
+		due = append(due, *sq)
+	}
+	s.mu.Unlock()
+
+	for i := range due {
+		go s.execute(&due[i], false)
+	}
+}
+
+// execute runs a schedule as its owner and records the outcome. The caller
+// has marked the schedule as running.
+func (s *Scheduler) execute(sq *ScheduledQuery, manual bool) *Run {
+	run := &Run{
+		ID:         uuid.New().String(),
+		ScheduleID: sq.ID,
+		Manual:     manual,
+		StartedAt:  time.Now().UTC(),
+	}
+
+	var rows []map[string]interface{}
+	var err error
+	if sq.QueryID != "" {
+		rows, err = s.runQuery(sq)
+	}
+	if err == nil {
+		run.RowCount = len(rows)
+		err = s.store(sq, run, rows)
+	}
+	if err == nil && sq.Export != nil {
+		run.ExportFile, err = s.export(sq, run, rows)
+	}
+	if err == nil && sq.Alert != nil {
+		run.Alerted = s.checkAlert(sq, run, rows)
+	}
+	if err == nil && sq.Report != nil {
+		run.ReportFile, err = s.renderReport(sq, run)
+	}
+
+	run.FinishedAt = time.Now().UTC()
+	run.Status = RunSucceeded
+	if err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+		log.Error().Err(err).Str("schedule_id", sq.ID).Msg("Scheduled query failed")
+	}
+
+	s.record(sq.ID, run)
+	copied := *run
+	return &copied
+}
+
+func (s *Scheduler) runQuery(sq *ScheduledQuery) ([]map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(auth.WithUser(context.Background(), sq.Owner), runTimeout)
+	defer cancel()
+
+	// Resolve the saved query on every run so edits and revoked access apply
+	saved, err := s.engine.GetQueryStore().GetFor(ctx, sq.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.engine.Execute(ctx, &query.QueryRequest{
+		Query:         saved.Query,
+		Parameters:    sq.Parameters,
+		ParameterDefs: saved.Parameters,
+		Timeout:       int(runTimeout.Seconds()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.Rows, nil
+}
+
+// store writes the rows of a run to the schedule's destination
+func (s *Scheduler) store(sq *ScheduledQuery, run *Run, rows []map[string]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	switch sq.Destination.Type {
+	case DestinationTable:
+		fmt.Fprintf(&b, "INSERT INTO %s SETTINGS input_format_skip_unknown_fields = 1 FORMAT JSONEachRow\n", sq.Destination.Table)
+		for _, row := range rows {
+			line, err := json.Marshal(row)
+			if err != nil {
+				return fmt.Errorf("failed to encode row: %w", err)
+			}
+			b.Write(line)
+			b.WriteByte('\n')
+		}
+	default:
+		fmt.Fprintf(&b, "INSERT INTO %s (schedule_id, run_id, run_time, row_number, row) VALUES ", resultsTable)
+		runTime := chsql.Quote(run.StartedAt.Format("2006-01-02 15:04:05.000"))
+		for i, row := range rows {
+			line, err := json.Marshal(row)
+			if err != nil {
+				return fmt.Errorf("failed to encode row: %w", err)
+			}
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "(%s, %s, %s, %d, %s)", chsql.Quote(sq.ID), chsql.Quote(run.ID), runTime, i, chsql.Quote(string(line)))
+		}
+	}
+
+	if err := s.db.Execute(context.Background(), b.String()); err != nil {
+		return fmt.Errorf("failed to store results: %w", err)
+	}
+	return nil
+}
+
+// export writes the rows to <exportDir>/<schedule>_<time>.<format>
+func (s *Scheduler) export(sq *ScheduledQuery, run *Run, rows []map[string]interface{}) (string, error) {
+	if err := os.MkdirAll(s.exportDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create export directory: %w", err)
+	}
+	path := filepath.Join(s.exportDir,
+		fmt.Sprintf("%s_%s.%s", sq.ID, run.StartedAt.Format("20060102_150405"), sq.Export.Format))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+
+	if sq.Export.Format == "json" {
+		if err := json.NewEncoder(file).Encode(rows); err != nil {
+			return "", fmt.Errorf("failed to write export: %w", err)
+		}
+		return path, nil
+	}
+
+	columns := columnNames(rows)
+	writer := csv.NewWriter(file)
+	writer.Write(columns)
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			if value, ok := row[column]; ok && value != nil {
+				record[i] = fmt.Sprintf("%v", value)
+			}
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", fmt.Errorf("failed to write export: %w", err)
+	}
+	return path, nil
+}
+
+// renderReport renders the schedule's dashboard as its owner to
+// <exportDir>/<schedule>_<time>.<format>
+func (s *Scheduler) renderReport(sq *ScheduledQuery, run *Run) (string, error) {
+	s.mu.RLock()
+	reports := s.reports
+	s.mu.RUnlock()
+	if reports == nil {
+		return "", fmt.Errorf("reports are not available")
+	}
+	format, err := report.ParseFormat(sq.Report.Format)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(s.exportDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create export directory: %w", err)
+	}
+	path := filepath.Join(s.exportDir,
+		fmt.Sprintf("%s_%s.%s", sq.ID, run.StartedAt.Format("20060102_150405"), format))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer file.Close()
+
+	ctx, cancel := context.WithTimeout(auth.WithUser(context.Background(), sq.Owner), runTimeout)
+	defer cancel()
+	if err := reports.RenderReport(ctx, sq.Report.DashboardID, nil, format, file); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to render report: %w", err)
+	}
+	return path, nil
+}
+
+// checkAlert evaluates the alert condition and raises or resolves the
+// schedule's alert. It reports whether the condition matched.
+func (s *Scheduler) checkAlert(sq *ScheduledQuery, run *Run, rows []map[string]interface{}) bool {
+	cond := sq.Alert
+	value := float64(len(rows))
+	subject := "row count"
+	if cond.Column != "" {
+		subject = cond.Column
+		value = 0
+		if len(rows) > 0 {
+			value = toFloat(rows[0][cond.Column])
+		}
+	}
+
+	matched := compare(value, cond.Operator, cond.Threshold)
+	if s.alerts == nil {
+		return matched
+	}
+
+	name := "scheduled_query_" + sq.ID
+	if matched {
+		severity := monitoring.AlertSeverity(cond.Severity)
+		if severity == "" {
+			severity = monitoring.SeverityWarning
+		}
+		s.alerts.RaiseAlert(name, severity, "scheduler",
+			fmt.Sprintf("%s: %s is %g (%s %g)", sq.Name, subject, value, cond.Operator, cond.Threshold),
+			map[string]interface{}{"schedule_id": sq.ID, "run_id": run.ID, "value": value})
+	} else {
+		s.alerts.ResolveAlert(name)
+	}
+	return matched
+}
+
+// record stores the run and advances the schedule
+func (s *Scheduler) record(id string, run *Run) {
+	s.mu.Lock()
+	delete(s.running, id)
+	sq, ok := s.schedules[id]
+	if !ok {
+		// Deleted while running
+		s.mu.Unlock()
+		return
+	}
+	history := append(s.runs[id], run)
+	if len(history) > maxRunHistory {
+		history = history[len(history)-maxRunHistory:]
+	}
+	s.runs[id] = history
+
+	updated := *sq
+	updated.LastRun = &run.StartedAt
+	updated.LastStatus = run.Status
+	updated.LastError = run.Error
+	updated.LastRowCount = run.RowCount
+	if !run.Manual {
+		next := s.parsed[id].Next(run.StartedAt)
+		updated.NextRun = &next
+	}
+	s.schedules[id] = &updated
+	s.mu.Unlock()
+
+	if err := s.persist(&updated); err != nil {
+		log.Error().Err(err).Str("schedule_id", id).Msg("Failed to persist schedule state")
+	}
+}
+
+func (s *Scheduler) validate(ctx context.Context, sq *ScheduledQuery) (*Schedule, error) {
+	if sq.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	parsed, err := ParseSchedule(sq.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+	if parsed.Next(time.Now().UTC()).IsZero() {
+		return nil, fmt.Errorf("schedule never runs")
+	}
+	if sq.QueryID == "" {
+		if sq.Report == nil {
+			return nil, fmt.Errorf("query_id or report is required")
+		}
+		if sq.Alert != nil || sq.Export != nil {
+			return nil, fmt.Errorf("alerts and exports need a query")
+		}
+	} else if _, err := s.engine.GetQueryStore().GetFor(ctx, sq.QueryID); err != nil {
+		return nil, err
+	}
+
+	switch sq.Destination.Type {
+	case "":
+		sq.Destination.Type = DestinationResults
+	case DestinationResults:
+	case DestinationTable:
+		// Writing into arbitrary tables is an admin operation
+		if !auth.UserFromContext(ctx).IsAdmin() {
+			return nil, fmt.Errorf("only admins can store results in a table")
+		}
+		if !tableNamePattern.MatchString(sq.Destination.Table) {
+			return nil, fmt.Errorf("invalid destination table %q", sq.Destination.Table)
+		}
+	default:
+		return nil, fmt.Errorf("invalid destination type: %s", sq.Destination.Type)
+	}
+
+	if sq.Alert != nil {
+		switch sq.Alert.Operator {
+		case ">", ">=", "<", "<=", "==", "!=":
+		default:
+			return nil, fmt.Errorf("invalid alert operator: %s", sq.Alert.Operator)
+		}
+		switch monitoring.AlertSeverity(sq.Alert.Severity) {
+		case "", monitoring.SeverityInfo, monitoring.SeverityWarning, monitoring.SeverityCritical:
+		default:
+			return nil, fmt.Errorf("invalid alert severity: %s", sq.Alert.Severity)
+		}
+	}
+	if sq.Export != nil && sq.Export.Format != "csv" && sq.Export.Format != "json" {
+		return nil, fmt.Errorf("invalid export format: %s", sq.Export.Format)
+	}
+	if sq.Report != nil {
+		s.mu.RLock()
+		reports := s.reports
+		s.mu.RUnlock()
+		if reports == nil {
+			return nil, fmt.Errorf("reports are not available")
+		}
+		if sq.Report.DashboardID == "" {
+			return nil, fmt.Errorf("report dashboard_id is required")
+		}
+		if _, err := report.ParseFormat(sq.Report.Format); err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+func (s *Scheduler) persist(sq *ScheduledQuery) error {
+	s.mu.RLock()
+	storage := s.storage
+	s.mu.RUnlock()
+	if storage == nil {
+		return nil
+	}
+	if err := storage.Save(sq); err != nil {
+		return fmt.Errorf("failed to save schedule: %w", err)
+	}
+	return nil
+}
+
+// lookup finds a schedule the user in ctx may access. Callers hold the lock.
+func (s *Scheduler) lookup(ctx context.Context, id string) (*ScheduledQuery, error) {
+	sq, ok := s.schedules[id]
+	user := auth.UserFromContext(ctx)
+	if !ok || (sq.Owner.ID != user.ID && !user.IsAdmin()) {
+		return nil, fmt.Errorf("schedule not found: %s", id)
+	}
+	return sq, nil
+}
+
+func columnNames(rows []map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var columns []string
+	for _, row := range rows {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+func compare(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	}
+	return false
+}
+
+// toFloat converts JSONEachRow values, where 64-bit numbers arrive as strings
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		var f float64
+		fmt.Sscanf(v, "%g", &f)
+		return f
+	case bool:
+		if v {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/query"
+)
+
+// ClickHouseStorage keeps schedule definitions in ClickHouse, one row per
+// save with the latest row winning; deletions are tombstone rows.
+type ClickHouseStorage struct {
+	db    query.SQLExecutor
+	table string
+}
+
+// NewClickHouseStorage creates the schedules table if needed
+func NewClickHouseStorage(db query.SQLExecutor) (*ClickHouseStorage, error) {
+	s := &ClickHouseStorage{
+		db:    db,
+		table: "scheduled_queries",
+	}
+
+	ddl := fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		id String,
+		definition String,
+		deleted UInt8 DEFAULT 0,
+		version UInt64
+	) ENGINE = ReplacingMergeTree(version)
+	ORDER BY id
+	`, s.table)
+
+	if err := db.Execute(context.Background(), ddl); err != nil {
+		return nil, fmt.Errorf("failed to create scheduled queries table: %w", err)
+	}
+	return s, nil
+}
+
+// Save writes the current definition and state of a schedule
+func (s *ClickHouseStorage) Save(schedule *ScheduledQuery) error {
+	definition, err := json.Marshal(schedule)
+	if err != nil {
+		return fmt.Errorf("failed to encode schedule: %w", err)
+	}
+	return s.insert(schedule.ID, string(definition), false)
+}
+
+// LoadAll returns every schedule that has not been deleted
+func (s *ClickHouseStorage) LoadAll() ([]*ScheduledQuery, error) {
+	rows, err := s.db.ExecuteSQL(fmt.Sprintf(`
+		SELECT id,
+			argMax(definition, version) AS definition,
+			argMax(deleted, version) AS deleted
+		FROM %s
+		GROUP BY id
+	`, s.table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schedules: %w", err)
+	}
+
+	schedules := make([]*ScheduledQuery, 0, len(rows))
+	for _, row := range rows {
+		if toFloat(row["deleted"]) != 0 {
+			continue
+		}
+		definition, _ := row["definition"].(string)
+		var schedule ScheduledQuery
+		if err := json.Unmarshal([]byte(definition), &schedule); err != nil {
+			log.Warn().Err(err).Interface("id", row["id"]).Msg("Skipping unreadable schedule")
+			continue
+		}
+		schedules = append(schedules, &schedule)
+	}
+	return schedules, nil
+}
+
+// Delete records a tombstone for the schedule
+func (s *ClickHouseStorage) Delete(id string) error {
+	return s.insert(id, "", true)
+}
+
+func (s *ClickHouseStorage) insert(id, definition string, deleted bool) error {
+	deletedFlag := 0
+	if deleted {
+		deletedFlag = 1
+	}
+	statement := fmt.Sprintf("INSERT INTO %s (id, definition, deleted, version) VALUES (%s, %s, %d, %d)",
+		s.table, chsql.Quote(id), chsql.Quote(definition), deletedFlag, time.Now().UnixNano())
+	if err := s.db.Execute(context.Background(), statement); err != nil {
+		return fmt.Errorf("failed to store schedule: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package scripting
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// baseFunctions are the functions of Lua's base library scripts may call.
+// The others load code, print, reach the globals and metatables, or catch
+// the errors that stop a run over its limits.
+var baseFunctions = []string{"assert", "error", "ipairs", "next", "pairs", "select", "type", "unpack"}
+
+// functions are the builtins of this package. Every one returning a new
+// string charges its length to the budget of the run.
+var functions = map[string]lua.LGFunction{
+	concatBuiltin: builtinConcat,
+	"drop":        builtinDrop,
+	"lower":       stringFunction(strings.ToLower),
+	"upper":       stringFunction(strings.ToUpper),
+	"trim":        stringFunction(strings.TrimSpace),
+	"len":         builtinLen,
+	"tostring":    builtinToString,
+	"tonumber":    builtinToNumber,
+	"contains":    predicate(strings.Contains),
+	"startswith":  predicate(strings.HasPrefix),
+	"endswith":    predicate(strings.HasSuffix),
+	"sub":         builtinSub,
+	"match":       builtinMatch,
+	"gsub":        builtinGsub,
+}
+
+// maxPatternLength bounds the regular expressions of match and gsub
+const maxPatternLength = 1024
+
+// newBuiltins returns the metatable giving the globals of a run access to
+// the builtins
+func newBuiltins(L *lua.LState) *lua.LTable {
+	lua.OpenBase(L)
+	L.Pop(1)
+	base := L.G.Global
+
+	builtins := L.NewTable()
+	for _, name := range baseFunctions {
+		builtins.RawSetString(name, base.RawGetString(name))
+	}
+	for name, fn := range functions {
+		builtins.RawSetString(name, L.NewFunction(fn))
+	}
+	meta := L.NewTable()
+	meta.RawSetString("__index", builtins)
+	return meta
+}
+
+// charge adds the size of a new string to the budget of the run, raising
+// an error once the memory limit is exceeded
+func charge(L *lua.LState, s string) lua.LString {
+	if err := budgetOf(L).alloc(len(s)); err != nil {
+		L.RaiseError("%s", err.Error())
+	}
+	return lua.LString(s)
+}
+
+// budgetOf returns the budget of the run calling a builtin
+func budgetOf(L *lua.LState) *budget {
+	return L.Context().(*budget)
+}
+
+// builtinConcat implements the .. operator, on strings and numbers as Lua
+// does
+func builtinConcat(L *lua.LState) int {
+	left, right := L.Get(1), L.Get(2)
+	for _, v := range []lua.LValue{left, right} {
+		if v.Type() != lua.LTString && v.Type() != lua.LTNumber {
+			L.RaiseError("attempt to concatenate a %s value", v.Type().String())
+		}
+	}
+	L.Push(charge(L, left.String()+right.String()))
+	return 1
+}
+
+// builtinDrop drops the log and ends the run
+func builtinDrop(L *lua.LState) int {
+	b := budgetOf(L)
+	b.dropped = true
+	if b.err == nil {
+		b.err = errDropped
+	}
+	return 0
+}
+
+func stringFunction(fn func(string) string) lua.LGFunction {
+	return func(L *lua.LState) int {
+		L.Push(charge(L, fn(toString(L.Get(1)))))
+		return 1
+	}
+}
+
+func predicate(fn func(string, string) bool) lua.LGFunction {
+	return func(L *lua.LState) int {
+		L.Push(lua.LBool(fn(toString(L.Get(1)), toString(L.Get(2)))))
+		return 1
+	}
+}
+
+func builtinLen(L *lua.LState) int {
+	switch v := L.Get(1).(type) {
+	case lua.LString:
+		L.Push(lua.LNumber(len(v)))
+	case *lua.LTable:
+		n := 0
+		v.ForEach(func(lua.LValue, lua.LValue) { n++ })
+		L.Push(lua.LNumber(n))
+	default:
+		L.RaiseError("cannot get length of a %s value", v.Type().String())
+	}
+	return 1
+}
+
+func builtinToString(L *lua.LState) int {
+	L.Push(charge(L, toString(L.Get(1))))
+	return 1
+}
+
+// builtinToNumber converts numbers and numeric strings, nil otherwise
+func builtinToNumber(L *lua.LState) int {
+	switch v := L.Get(1).(type) {
+	case lua.LNumber:
+		L.Push(v)
+	case lua.LString:
+		n, err := strconv.ParseFloat(strings.TrimSpace(string(v)), 64)
+		if err != nil {
+			L.Push(lua.LNil)
+		} else {
+			L.Push(lua.LNumber(n))
+		}
+	default:
+		L.Push(lua.LNil)
+	}
+	return 1
+}
+
+// builtinSub implements Lua's 1-based, inclusive sub(s, i, j)
+func builtinSub(L *lua.LState) int {
+	s := toString(L.Get(1))
+	n := len(s)
+	from := L.OptInt(2, 1)
+	to := L.OptInt(3, n)
+	if from < 0 {
+		from = n + from + 1
+	}
+	if to < 0 {
+		to = n + to + 1
+	}
+	if from < 1 {
+		from = 1
+	}
+	if to > n {
+		to = n
+	}
+	if from > to {
+		L.Push(lua.LString(""))
+		return 1
+	}
+	L.Push(charge(L, s[from-1:to]))
+	return 1
+}
+
+// builtinMatch returns the first capture of a regular expression, or the
+// whole match when it has none
+func builtinMatch(L *lua.LState) int {
+	re := regex(L, toString(L.Get(2)))
+	m := re.FindStringSubmatch(toString(L.Get(1)))
+	switch {
+	case m == nil:
+		L.Push(lua.LNil)
+	case len(m) > 1:
+		L.Push(charge(L, m[1]))
+	default:
+		L.Push(charge(L, m[0]))
+	}
+	return 1
+}
+
+// builtinGsub replaces the matches of a regular expression, expanding $1
+// and ${name} in the replacement. The memory limit is checked as the
+// result grows, since a short replacement of many matches builds a string
+// much longer than its input.
+func builtinGsub(L *lua.LState) int {
+	s := toString(L.Get(1))
+	re := regex(L, toString(L.Get(2)))
+	template := toString(L.Get(3))
+	b := budgetOf(L)
+
+	var out []byte
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+		out = append(out, s[last:m[0]]...)
+		out = re.ExpandString(out, template, s, m)
+		last = m[1]
+		if b.limits.MaxMemory > 0 && b.memory+len(out) > b.limits.MaxMemory {
+			charge(L, string(out))
+		}
+	}
+	out = append(out, s[last:]...)
+	L.Push(charge(L, string(out)))
+	return 1
+}
+
+// regex compiles and caches a pattern of match or gsub. These take Go
+// regular expressions rather than Lua patterns: RE2 runs in linear time, so
+// user patterns cannot cause catastrophic backtracking.
+func regex(L *lua.LState, pattern string) *regexp.Regexp {
+	b := budgetOf(L)
+	if re, ok := b.regexes[pattern]; ok {
+		return re
+	}
+	if len(pattern) > maxPatternLength {
+		L.RaiseError("pattern too long")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		L.RaiseError("invalid pattern: %s", err.Error())
+	}
+	b.regexes[pattern] = re
+	return re
+}
+
+// toString converts a value to a string as Lua's tostring does
+func toString(v lua.LValue) string {
+	if s, ok := v.(lua.LString); ok {
+		return string(s)
+	}
+	return v.String()
+}
@@ -0,0 +1,104 @@
+package scripting
+
+import (
+	"github.com/yuin/gopher-lua/ast"
+)
+
+// concatBuiltin is the builtin the .. operator is compiled to, so that the
+// strings it builds are charged. The name is not a valid identifier, which
+// keeps scripts from calling or replacing it.
+const concatBuiltin = "concat!"
+
+// rewriteStmts replaces the .. operators of a parsed chunk with calls to
+// concatBuiltin
+func rewriteStmts(stmts []ast.Stmt) {
+	for _, s := range stmts {
+		switch s := s.(type) {
+		case *ast.AssignStmt:
+			rewriteExprs(s.Lhs)
+			rewriteExprs(s.Rhs)
+		case *ast.LocalAssignStmt:
+			rewriteExprs(s.Exprs)
+		case *ast.FuncCallStmt:
+			s.Expr = rewriteExpr(s.Expr)
+		case *ast.DoBlockStmt:
+			rewriteStmts(s.Stmts)
+		case *ast.WhileStmt:
+			s.Condition = rewriteExpr(s.Condition)
+			rewriteStmts(s.Stmts)
+		case *ast.RepeatStmt:
+			s.Condition = rewriteExpr(s.Condition)
+			rewriteStmts(s.Stmts)
+		case *ast.IfStmt:
+			s.Condition = rewriteExpr(s.Condition)
+			rewriteStmts(s.Then)
+			rewriteStmts(s.Else)
+		case *ast.NumberForStmt:
+			s.Init = rewriteExpr(s.Init)
+			s.Limit = rewriteExpr(s.Limit)
+			s.Step = rewriteExpr(s.Step)
+			rewriteStmts(s.Stmts)
+		case *ast.GenericForStmt:
+			rewriteExprs(s.Exprs)
+			rewriteStmts(s.Stmts)
+		case *ast.FuncDefStmt:
+			s.Name.Func = rewriteExpr(s.Name.Func)
+			s.Name.Receiver = rewriteExpr(s.Name.Receiver)
+			rewriteStmts(s.Func.Stmts)
+		case *ast.ReturnStmt:
+			rewriteExprs(s.Exprs)
+		}
+	}
+}
+
+func rewriteExprs(exprs []ast.Expr) {
+	for i, e := range exprs {
+		exprs[i] = rewriteExpr(e)
+	}
+}
+
+func rewriteExpr(e ast.Expr) ast.Expr {
+	switch e := e.(type) {
+	case *ast.StringConcatOpExpr:
+		call := &ast.FuncCallExpr{
+			Func:      &ast.IdentExpr{Value: concatBuiltin},
+			Args:      []ast.Expr{rewriteExpr(e.Lhs), rewriteExpr(e.Rhs)},
+			AdjustRet: true,
+		}
+		call.SetLine(e.Line())
+		call.SetLastLine(e.LastLine())
+		call.Func.SetLine(e.Line())
+		call.Func.SetLastLine(e.LastLine())
+		return call
+	case *ast.AttrGetExpr:
+		e.Object = rewriteExpr(e.Object)
+		e.Key = rewriteExpr(e.Key)
+	case *ast.TableExpr:
+		for _, field := range e.Fields {
+			field.Key = rewriteExpr(field.Key)
+			field.Value = rewriteExpr(field.Value)
+		}
+	case *ast.FuncCallExpr:
+		e.Func = rewriteExpr(e.Func)
+		e.Receiver = rewriteExpr(e.Receiver)
+		rewriteExprs(e.Args)
+	case *ast.LogicalOpExpr:
+		e.Lhs = rewriteExpr(e.Lhs)
+		e.Rhs = rewriteExpr(e.Rhs)
+	case *ast.RelationalOpExpr:
+		e.Lhs = rewriteExpr(e.Lhs)
+		e.Rhs = rewriteExpr(e.Rhs)
+	case *ast.ArithmeticOpExpr:
+		e.Lhs = rewriteExpr(e.Lhs)
+		e.Rhs = rewriteExpr(e.Rhs)
+	case *ast.UnaryMinusOpExpr:
+		e.Expr = rewriteExpr(e.Expr)
+	case *ast.UnaryNotOpExpr:
+		e.Expr = rewriteExpr(e.Expr)
+	case *ast.UnaryLenOpExpr:
+		e.Expr = rewriteExpr(e.Expr)
+	case *ast.FunctionExpr:
+		rewriteStmts(e.Stmts)
+	}
+	return e
+}
@@ -0,0 +1,229 @@
+// Package scripting runs user supplied log transforms written in Lua 5.1,
+// embedded with gopher-lua. Scripts have no I/O and no access to the host:
+// only a small part of the base library is available, next to the builtins
+// of this package. Every run is bounded by step, memory and time limits.
+package scripting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Limits bounds the resources a single script run may use. A step is one
+// instruction of the Lua VM, so MaxSteps also bounds how many tables and
+// table entries a script creates; MaxMemory bounds the bytes of the
+// strings it builds.
+type Limits struct {
+	MaxSteps  int           `json:"max_steps"`
+	MaxMemory int           `json:"max_memory_bytes"`
+	Timeout   time.Duration `json:"timeout"`
+}
+
+// DefaultLimits returns conservative limits suitable for per-log execution
+func DefaultLimits() Limits {
+	return Limits{
+		MaxSteps:  10000,
+		MaxMemory: 1 << 20,
+		Timeout:   50 * time.Millisecond,
+	}
+}
+
+// Script is a compiled script ready to run
+type Script struct {
+	source string
+	proto  *lua.FunctionProto
+}
+
+// Result describes the outcome of a script run
+type Result struct {
+	Dropped bool `json:"dropped"`
+	Steps   int  `json:"steps"`
+}
+
+// chunkName prefixes the positions in the errors of scripts
+const chunkName = "script"
+
+// callStackSize bounds the depth of function calls, recursion included
+const callStackSize = 200
+
+// Compile parses a script
+func Compile(source string) (*Script, error) {
+	chunk, err := parse.Parse(strings.NewReader(source), chunkName)
+	if err != nil {
+		return nil, fmt.Errorf("compile error: %w", err)
+	}
+	rewriteStmts(chunk)
+	proto, err := lua.Compile(chunk, chunkName)
+	if err != nil {
+		return nil, fmt.Errorf("compile error: %w", err)
+	}
+	return &Script{source: source, proto: proto}, nil
+}
+
+// Source returns the original script text
+func (s *Script) Source() string {
+	return s.source
+}
+
+// Run executes the script against env, whose values become the globals of
+// the script. Tables in env are map[string]interface{} values; they are
+// updated in place with what the script left in them, unless the run fails.
+func (s *Script) Run(env map[string]interface{}, limits Limits) (*Result, error) {
+	vm := states.Get().(*state)
+	defer states.Put(vm)
+	L := vm.L
+
+	run := newBudget(limits)
+	globals := L.NewTable()
+	L.SetMetatable(globals, vm.builtins)
+	for name, value := range env {
+		v, err := toLua(L, value, 0)
+		if err != nil {
+			return &Result{}, fmt.Errorf("global %q: %w", name, err)
+		}
+		globals.RawSetString(name, v)
+	}
+
+	fn := L.NewFunctionFromProto(s.proto)
+	fn.Env = globals
+	L.SetContext(run)
+	L.Push(fn)
+	err := L.PCall(0, 0, nil)
+	L.RemoveContext()
+	L.SetTop(0)
+
+	result := &Result{Dropped: run.dropped, Steps: run.steps}
+	if run.dropped {
+		return result, nil
+	}
+	if err != nil {
+		var apiErr *lua.ApiError
+		if errors.As(err, &apiErr) {
+			return result, errors.New(apiErr.Object.String())
+		}
+		return result, err
+	}
+
+	// Write the tables back only once the script has completed
+	updates := make(map[string]map[string]interface{})
+	for name, value := range env {
+		if _, ok := value.(map[string]interface{}); !ok {
+			continue
+		}
+		table, ok := globals.RawGetString(name).(*lua.LTable)
+		if !ok {
+			return result, fmt.Errorf("cannot replace global %q", name)
+		}
+		converted, err := fromLua(table, 0)
+		if err != nil {
+			return result, fmt.Errorf("global %q: %w", name, err)
+		}
+		m, ok := converted.(map[string]interface{})
+		if !ok {
+			return result, fmt.Errorf("cannot replace global %q with a list", name)
+		}
+		updates[name] = m
+	}
+	for name, m := range updates {
+		table := env[name].(map[string]interface{})
+		for k := range table {
+			delete(table, k)
+		}
+		for k, v := range m {
+			table[k] = v
+		}
+	}
+	return result, nil
+}
+
+// state is a Lua state kept between runs. The globals of each run are a new
+// table falling back to the builtins, so that runs share nothing.
+type state struct {
+	L        *lua.LState
+	builtins *lua.LTable // metatable of the globals of a run
+}
+
+var states = sync.Pool{
+	New: func() interface{} {
+		L := lua.NewState(lua.Options{
+			CallStackSize:       callStackSize,
+			SkipOpenLibs:        true,
+			MinimizeStackMemory: true,
+		})
+		return &state{L: L, builtins: newBuiltins(L)}
+	},
+}
+
+// budget is the context of a run. The VM checks the context before every
+// instruction, which makes Done the place to count steps and to enforce
+// the limits; builtins charge their allocations to the same budget.
+type budget struct {
+	context.Context
+	limits   Limits
+	deadline time.Time
+	steps    int
+	memory   int
+	dropped  bool
+	err      error // set once the run must stop
+	regexes  map[string]*regexp.Regexp
+}
+
+// stopped is the Done channel of budgets whose run must stop
+var stopped = make(chan struct{})
+
+func init() {
+	close(stopped)
+}
+
+// errDropped stops the run of a script that called drop
+var errDropped = errors.New("log dropped")
+
+func newBudget(limits Limits) *budget {
+	b := &budget{
+		Context: context.Background(),
+		limits:  limits,
+		regexes: make(map[string]*regexp.Regexp),
+	}
+	if limits.Timeout > 0 {
+		b.deadline = time.Now().Add(limits.Timeout)
+	}
+	return b
+}
+
+// Done charges one step and enforces the CPU limits
+func (b *budget) Done() <-chan struct{} {
+	if b.err == nil {
+		b.steps++
+		if b.limits.MaxSteps > 0 && b.steps > b.limits.MaxSteps {
+			b.err = fmt.Errorf("step limit of %d exceeded", b.limits.MaxSteps)
+		} else if !b.deadline.IsZero() && b.steps%64 == 0 && time.Now().After(b.deadline) {
+			b.err = fmt.Errorf("time limit of %s exceeded", b.limits.Timeout)
+		}
+	}
+	if b.err != nil {
+		return stopped
+	}
+	return nil
+}
+
+// Err returns why the run stopped
+func (b *budget) Err() error {
+	return b.err
+}
+
+// alloc charges memory for newly created values
+func (b *budget) alloc(n int) error {
+	b.memory += n
+	if b.limits.MaxMemory > 0 && b.memory > b.limits.MaxMemory && b.err == nil {
+		b.err = fmt.Errorf("memory limit of %d bytes exceeded", b.limits.MaxMemory)
+	}
+	return b.err
+}
@@ -0,0 +1,106 @@
+package scripting
+
+import (
+	"strings"
+	"testing"
+)
+
+func run(t *testing.T, source string, env map[string]interface{}) (*Result, error) {
+	t.Helper()
+	script, err := Compile(source)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	return script.Run(env, DefaultLimits())
+}
+
+func TestRunUpdatesTablesInPlace(t *testing.T) {
+	log := map[string]interface{}{
+		"level":      "ERROR",
+		"message":    "user=42 action=login",
+		"attributes": map[string]interface{}{"tags": []interface{}{"a", "b"}},
+	}
+	source := `
+		log.level = lower(log.level)
+		local n = 0
+		for _, tag in ipairs(log.attributes.tags) do
+			n = n + 1
+		end
+		log.attributes.tag_count = n
+		log.attributes.user = match(log.message, "user=(\\d+)")
+		log.attributes.summary = log.level .. ":" .. n
+	`
+	if _, err := run(t, source, map[string]interface{}{"log": log}); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	attrs := log["attributes"].(map[string]interface{})
+	if log["level"] != "error" || attrs["tag_count"] != 2.0 || attrs["user"] != "42" || attrs["summary"] != "error:2" {
+		t.Errorf("log after the run = %v", log)
+	}
+	if tags, ok := attrs["tags"].([]interface{}); !ok || len(tags) != 2 {
+		t.Errorf("tags = %#v, want the list back", attrs["tags"])
+	}
+}
+
+func TestRunDrop(t *testing.T) {
+	log := map[string]interface{}{"level": "debug"}
+	result, err := run(t, `if log.level == "debug" then drop() end log.level = "kept"`, map[string]interface{}{"log": log})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if !result.Dropped || log["level"] != "debug" {
+		t.Errorf("Dropped = %v, level = %v; want a drop ending the run", result.Dropped, log["level"])
+	}
+}
+
+func TestRunLimits(t *testing.T) {
+	cases := map[string]struct {
+		source string
+		want   string
+	}{
+		"endless loop":         {`while true do end`, "step limit"},
+		"recursion":            {`local function f() return f() + 1 end f()`, "stack overflow"},
+		"concatenation":        {`local s = "xxxxxxxx" for i = 1, 40 do s = s .. s end`, "memory limit"},
+		"lower in a loop":      {`local s = "XXXXXXXX" for i = 1, 13 do s = s .. s end local t = {} for i = 1, 100 do t[i] = lower(s) end`, "memory limit"},
+		"upper in a loop":      {`local s = "xxxxxxxx" for i = 1, 13 do s = s .. s end local t = {} for i = 1, 100 do t[i] = upper(s) end`, "memory limit"},
+		"sub of a long string": {`local s = "0123456789" for i = 1, 12 do s = s .. s end local t = {} for i = 1, 100 do t[i] = sub(s, 2) end`, "memory limit"},
+		"gsub expansion":       {`local s = "aaaaaaaaaaaaaaaa" for i = 1, 12 do s = s .. s end gsub(s, "a", "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")`, "memory limit"},
+	}
+	for name, c := range cases {
+		_, err := run(t, c.source, nil)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: Run error = %v, want %q", name, err, c.want)
+		}
+	}
+}
+
+func TestRunSandbox(t *testing.T) {
+	for _, name := range []string{"load", "loadstring", "dofile", "require", "pcall", "getfenv", "setmetatable", "_G", "string", "os", "io"} {
+		result, err := run(t, `if `+name+` ~= nil then drop() end`, nil)
+		if err != nil {
+			t.Fatalf("%s: Run error: %v", name, err)
+		}
+		if result.Dropped {
+			t.Errorf("%s is available to scripts", name)
+		}
+	}
+
+	// Runs share no globals
+	if _, err := run(t, `leaked = "yes"`, nil); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	result, err := run(t, `if leaked ~= nil then drop() end`, nil)
+	if err != nil || result.Dropped {
+		t.Errorf("a global of a previous run is visible: dropped = %v, err = %v", result.Dropped, err)
+	}
+}
+
+func TestRunFailureLeavesTables(t *testing.T) {
+	log := map[string]interface{}{"level": "info"}
+	if _, err := run(t, `log.level = "changed" error("boom")`, map[string]interface{}{"log": log}); err == nil {
+		t.Fatal("Run of a failing script succeeded")
+	}
+	if log["level"] != "info" {
+		t.Errorf("level = %v after a failed run, want it untouched", log["level"])
+	}
+}
@@ -0,0 +1,113 @@
+package scripting
+
+import (
+	"fmt"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// maxDepth bounds how deeply tables nest, which also stops the conversion
+// of tables that contain themselves
+const maxDepth = 32
+
+// toLua converts a Go value of env to a Lua value. Maps become tables and
+// slices tables indexed from 1; values of other types become strings.
+func toLua(L *lua.LState, v interface{}, depth int) (lua.LValue, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("tables nested more than %d deep", maxDepth)
+	}
+	switch v := v.(type) {
+	case nil:
+		return lua.LNil, nil
+	case string:
+		return lua.LString(v), nil
+	case bool:
+		return lua.LBool(v), nil
+	case float64:
+		return lua.LNumber(v), nil
+	case float32:
+		return lua.LNumber(v), nil
+	case int:
+		return lua.LNumber(v), nil
+	case int32:
+		return lua.LNumber(v), nil
+	case int64:
+		return lua.LNumber(v), nil
+	case uint64:
+		return lua.LNumber(v), nil
+	case map[string]interface{}:
+		table := L.CreateTable(0, len(v))
+		for key, item := range v {
+			value, err := toLua(L, item, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			table.RawSetString(key, value)
+		}
+		return table, nil
+	case []interface{}:
+		table := L.CreateTable(len(v), 0)
+		for _, item := range v {
+			value, err := toLua(L, item, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			table.Append(value)
+		}
+		return table, nil
+	default:
+		return lua.LString(fmt.Sprintf("%v", v)), nil
+	}
+}
+
+// fromLua converts a Lua value back. Tables whose keys are 1 to n become
+// slices, other tables maps keyed by the string form of their keys.
+func fromLua(v lua.LValue, depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("tables nested more than %d deep", maxDepth)
+	}
+	switch v := v.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LString:
+		return string(v), nil
+	case lua.LBool:
+		return bool(v), nil
+	case lua.LNumber:
+		return float64(v), nil
+	case *lua.LTable:
+		return tableFromLua(v, depth)
+	default:
+		return nil, fmt.Errorf("cannot store a %s value", v.Type().String())
+	}
+}
+
+func tableFromLua(table *lua.LTable, depth int) (interface{}, error) {
+	keys := 0
+	table.ForEach(func(lua.LValue, lua.LValue) { keys++ })
+
+	if n := table.MaxN(); n > 0 && n == keys {
+		list := make([]interface{}, 0, n)
+		for i := 1; i <= n; i++ {
+			item, err := fromLua(table.RawGetInt(i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, nil
+	}
+
+	m := make(map[string]interface{}, keys)
+	var err error
+	table.ForEach(func(key, value lua.LValue) {
+		if err != nil {
+			return
+		}
+		var item interface{}
+		if item, err = fromLua(value, depth+1); err == nil {
+			m[toString(key)] = item
+		}
+	})
+	return m, err
+}
@@ -0,0 +1,114 @@
+package search
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Matcher evaluates a query against logs in memory, the way its SQL
+// predicate does in ClickHouse, for logs that never go through a query
+// such as those streamed to live tails
+type Matcher struct {
+	groups [][]termMatcher
+}
+
+type termMatcher struct {
+	Term
+	pattern *regexp.Regexp // of values with * wildcards
+	number  float64        // of numeric comparisons
+}
+
+// Matcher compiles the query for in-memory matching
+func (q *Query) Matcher() *Matcher {
+	m := &Matcher{groups: make([][]termMatcher, len(q.Groups))}
+	for i, group := range q.Groups {
+		for _, term := range group {
+			tm := termMatcher{Term: term}
+			if term.Operator != ":" {
+				tm.number, _ = strconv.ParseFloat(term.Value, 64)
+			} else if strings.Contains(term.Value, "*") {
+				tm.pattern = wildcardPattern(term.Value)
+			}
+			m.groups[i] = append(m.groups[i], tm)
+		}
+	}
+	return m
+}
+
+// Match reports whether a log matches the query. value returns the value
+// of a column or attribute of the log, by field name, and whether the log
+// has it; the message is the "message" field. An empty query matches
+// every log.
+func (m *Matcher) Match(value func(field string) (string, bool)) bool {
+	for _, group := range m.groups {
+		matched := false
+		for _, term := range group {
+			if term.match(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (t termMatcher) match(value func(field string) (string, bool)) bool {
+	if t.Operator != ":" {
+		// A value that is missing or not a number compares as NULL, which
+		// fails the term whether it is negated or not
+		v, _ := value(t.Field)
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return false
+		}
+		return t.compare(number) != t.Negated
+	}
+
+	var matched bool
+	_, isColumn := columnFields[t.Field]
+	v, exists := value(t.Field)
+	switch {
+	case t.Field == "" || t.Field == "message":
+		message, _ := value("message")
+		if t.pattern != nil {
+			matched = t.pattern.MatchString(message)
+		} else {
+			matched = strings.Contains(message, t.Value)
+		}
+	case t.pattern != nil:
+		matched = t.pattern.MatchString(v)
+	case !isColumn && t.Value == "":
+		matched = exists
+	default:
+		matched = v == t.Value
+	}
+	return matched != t.Negated
+}
+
+func (t termMatcher) compare(number float64) bool {
+	switch t.Operator {
+	case ">":
+		return number > t.number
+	case ">=":
+		return number >= t.number
+	case "<":
+		return number < t.number
+	case "<=":
+		return number <= t.number
+	}
+	return false
+}
+
+// wildcardPattern converts * wildcards to a regular expression matching
+// the whole value, like the LIKE pattern of wildcardToLike
+func wildcardPattern(value string) *regexp.Regexp {
+	parts := strings.Split(value, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile(`(?s)^` + strings.Join(parts, ".*") + `$`)
+}
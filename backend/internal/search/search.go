@@ -0,0 +1,281 @@
+// Package search translates the log search syntax into ClickHouse predicates.
+//
+// The syntax is a list of terms that must all match:
+//
+//	service:auth level:error "connection refused" -health
+//
+// Bare words and quoted phrases match the message, field:value matches a
+// column or attribute, a leading - negates a term and OR between two terms
+// matches either. Values may use * as a wildcard and numeric comparisons
+// such as status:>=500 or duration_ms:<100.
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+)
+
+// Columns that field:value terms map to directly; everything else is an attribute
+var columnFields = map[string]string{
+	"service":  "service",
+	"level":    "level",
+	"trace_id": "trace_id",
+	"span_id":  "span_id",
+	"message":  "message",
+	"id":       "toString(id)",
+}
+
+var (
+	fieldPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*$`)
+	tokenPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	splitPattern = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+)
+
+// Term is a single search condition
+type Term struct {
+	Field    string `json:"field,omitempty"` // empty for message text
+	Operator string `json:"operator"`        // ":", ">", ">=", "<", "<="
+	Value    string `json:"value"`
+	Phrase   bool   `json:"phrase,omitempty"`
+	Negated  bool   `json:"negated,omitempty"`
+}
+
+// Query is a parsed search: every group must match, and a group matches
+// when any of its terms does
+type Query struct {
+	Groups [][]Term `json:"groups"`
+}
+
+// Parse parses a search string
+func Parse(input string) (*Query, error) {
+	lexemes, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Query{}
+	joinNext := false
+	for _, lexeme := range lexemes {
+		if lexeme.text == "OR" && !lexeme.quoted {
+			if len(q.Groups) == 0 || joinNext {
+				return nil, fmt.Errorf("OR must be between two terms")
+			}
+			joinNext = true
+			continue
+		}
+
+		term, err := parseTerm(lexeme)
+		if err != nil {
+			return nil, err
+		}
+		if joinNext {
+			last := len(q.Groups) - 1
+			q.Groups[last] = append(q.Groups[last], term)
+			joinNext = false
+		} else {
+			q.Groups = append(q.Groups, []Term{term})
+		}
+	}
+	if joinNext {
+		return nil, fmt.Errorf("OR must be between two terms")
+	}
+	return q, nil
+}
+
+// SQL returns the ClickHouse predicate for the query, or "" if it is empty
+func (q *Query) SQL() string {
+	var groups []string
+	for _, group := range q.Groups {
+		var terms []string
+		for _, term := range group {
+			terms = append(terms, term.SQL())
+		}
+		if len(terms) == 1 {
+			groups = append(groups, terms[0])
+		} else {
+			groups = append(groups, "("+strings.Join(terms, " OR ")+")")
+		}
+	}
+	return strings.Join(groups, " AND ")
+}
+
+// SQL returns the ClickHouse predicate for a single term
+func (t Term) SQL() string {
+	var predicate string
+	if t.Field == "" {
+		predicate = messagePredicate(t.Value)
+	} else {
+		predicate = fieldPredicate(t)
+	}
+	if t.Negated {
+		return "NOT (" + predicate + ")"
+	}
+	return predicate
+}
+
+// messagePredicate matches text in the message. Whole tokens are checked
+// with hasToken so the tokenbf_v1 index on message can skip granules; the
+// position check then confirms the exact text, including phrases and
+// punctuation that the token index cannot see.
+func messagePredicate(text string) string {
+	if strings.Contains(text, "*") {
+		return "message LIKE " + chsql.Quote(wildcardToLike(text))
+	}
+
+	var conditions []string
+	for _, token := range splitPattern.Split(text, -1) {
+		if token != "" && tokenPattern.MatchString(token) {
+			conditions = append(conditions, fmt.Sprintf("hasToken(message, %s)", chsql.Quote(token)))
+		}
+	}
+	if len(conditions) == 1 && tokenPattern.MatchString(text) {
+		return conditions[0]
+	}
+	conditions = append(conditions, fmt.Sprintf("position(message, %s) > 0", chsql.Quote(text)))
+	return strings.Join(conditions, " AND ")
+}
+
+func fieldPredicate(t Term) string {
+	column, isColumn := columnFields[t.Field]
+	if !isColumn {
+		column = "attributes[" + chsql.Quote(t.Field) + "]"
+	}
+
+	if t.Operator != ":" {
+		// Value was checked to be numeric when parsing
+		if isColumn {
+			return fmt.Sprintf("toFloat64OrNull(toString(%s)) %s %s", column, t.Operator, t.Value)
+		}
+		return fmt.Sprintf("toFloat64OrNull(%s) %s %s", column, t.Operator, t.Value)
+	}
+
+	if t.Field == "message" {
+		return messagePredicate(t.Value)
+	}
+	if strings.Contains(t.Value, "*") {
+		return column + " LIKE " + chsql.Quote(wildcardToLike(t.Value))
+	}
+	if !isColumn && t.Value == "" {
+		return fmt.Sprintf("mapContains(attributes, %s)", chsql.Quote(t.Field))
+	}
+	return column + " = " + chsql.Quote(t.Value)
+}
+
+func parseTerm(l lexeme) (Term, error) {
+	term := Term{Operator: ":"}
+	text := l.text
+	if l.negated {
+		term.Negated = true
+	}
+	if l.quoted {
+		term.Value = text
+		term.Phrase = true
+		term.Field = l.field
+		if term.Field != "" {
+			if err := checkField(term.Field); err != nil {
+				return term, err
+			}
+		}
+		return term, nil
+	}
+
+	idx := strings.Index(text, ":")
+	if idx <= 0 {
+		term.Value = text
+		return term, nil
+	}
+
+	term.Field = strings.ToLower(text[:idx])
+	if err := checkField(term.Field); err != nil {
+		return term, err
+	}
+	value := text[idx+1:]
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		if strings.HasPrefix(value, op) {
+			number := strings.TrimPrefix(value, op)
+			if _, err := strconv.ParseFloat(number, 64); err != nil {
+				return term, fmt.Errorf("%s%s needs a number, got %q", term.Field, op, number)
+			}
+			term.Operator = op
+			value = number
+			break
+		}
+	}
+	term.Value = value
+	return term, nil
+}
+
+func checkField(field string) error {
+	if !fieldPattern.MatchString(field) {
+		return fmt.Errorf("invalid field name %q", field)
+	}
+	return nil
+}
+
+// lexeme is a whitespace separated word or a quoted phrase, with an
+// optional field prefix (field:"some value") and negation
+type lexeme struct {
+	text    string
+	field   string
+	quoted  bool
+	negated bool
+}
+
+func lex(input string) ([]lexeme, error) {
+	var lexemes []lexeme
+	i := 0
+	for i < len(input) {
+		if input[i] == ' ' || input[i] == '\t' || input[i] == '\n' {
+			i++
+			continue
+		}
+
+		l := lexeme{}
+		if input[i] == '-' && i+1 < len(input) && input[i+1] != ' ' {
+			l.negated = true
+			i++
+		}
+
+		start := i
+		for i < len(input) && input[i] != ' ' && input[i] != '\t' && input[i] != '\n' && input[i] != '"' {
+			i++
+		}
+		word := input[start:i]
+
+		if i < len(input) && input[i] == '"' {
+			// Phrase, optionally preceded by field:
+			if word != "" && !strings.HasSuffix(word, ":") {
+				return nil, fmt.Errorf("unexpected quote after %q", word)
+			}
+			end := strings.IndexByte(input[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote")
+			}
+			l.field = strings.ToLower(strings.TrimSuffix(word, ":"))
+			l.text = input[i+1 : i+1+end]
+			l.quoted = true
+			i += end + 2
+		} else {
+			l.text = word
+		}
+
+		if l.text == "" && !l.quoted {
+			continue
+		}
+		lexemes = append(lexemes, l)
+	}
+	return lexemes, nil
+}
+
+// wildcardToLike converts * wildcards to a LIKE pattern, escaping LIKE's own
+// special characters in the rest of the value
+func wildcardToLike(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "%", `\%`)
+	value = strings.ReplaceAll(value, "_", `\_`)
+	return strings.ReplaceAll(value, "*", "%")
+}
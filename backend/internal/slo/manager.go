@@ -0,0 +1,308 @@
+package slo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/monitoring"
+)
+
+// AlertSource is the source of the alerts raised by SLO burn rates
+const AlertSource = "slo"
+
+// Manager keeps the SLOs, refreshes their status from log counts and
+// registers their burn-rate alert rules
+type Manager struct {
+	mu       sync.RWMutex
+	slos     map[string]*SLO
+	statuses map[string]*Status
+	db       monitoring.SQLExecutor
+	alerts   *monitoring.AlertManager
+	storage  Storage
+}
+
+// NewManager creates an SLO manager counting logs in db and raising burn
+// rate alerts in alerts
+func NewManager(db monitoring.SQLExecutor, alerts *monitoring.AlertManager) *Manager {
+	return &Manager{
+		slos:     make(map[string]*SLO),
+		statuses: make(map[string]*Status),
+		db:       db,
+		alerts:   alerts,
+	}
+}
+
+// SetStorage persists SLOs, adding those it holds
+func (m *Manager) SetStorage(storage Storage) error {
+	slos, err := storage.Load()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.storage = storage
+	for _, slo := range slos {
+		if err := slo.validate(); err != nil {
+			log.Warn().Err(err).Str("slo", slo.Name).Msg("Skipping invalid SLO")
+			continue
+		}
+		m.slos[slo.Name] = slo
+		m.addAlertRules(slo)
+	}
+	return nil
+}
+
+// List returns the SLOs by name
+func (m *Manager) List() []SLO {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	slos := make([]SLO, 0, len(m.slos))
+	for _, slo := range m.slos {
+		slos = append(slos, *slo)
+	}
+	sort.Slice(slos, func(i, j int) bool { return slos[i].Name < slos[j].Name })
+	return slos
+}
+
+// Get returns an SLO by name
+func (m *Manager) Get(name string) (*SLO, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	slo, ok := m.slos[name]
+	if !ok {
+		return nil, fmt.Errorf("SLO not found: %s", name)
+	}
+	copied := *slo
+	return &copied, nil
+}
+
+// Create adds an SLO and its burn-rate alert rules
+func (m *Manager) Create(slo *SLO) error {
+	if err := slo.validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.slos[slo.Name]; exists {
+		return fmt.Errorf("SLO already exists: %s", slo.Name)
+	}
+	for _, name := range alertRuleNames(slo.Name) {
+		if m.alerts.HasRule(name) {
+			return fmt.Errorf("alert rule already exists: %s", name)
+		}
+	}
+	now := time.Now()
+	slo.CreatedAt = now
+	slo.UpdatedAt = now
+
+	stored := *slo
+	m.slos[slo.Name] = &stored
+	if err := m.save(); err != nil {
+		delete(m.slos, slo.Name)
+		return err
+	}
+	m.addAlertRules(&stored)
+	return nil
+}
+
+// Update replaces the definition of an SLO. Its status is recomputed on
+// the next refresh.
+func (m *Manager) Update(name string, slo *SLO) error {
+	slo.Name = name
+	if err := slo.validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	previous, ok := m.slos[name]
+	if !ok {
+		return fmt.Errorf("SLO not found: %s", name)
+	}
+	slo.CreatedBy = previous.CreatedBy
+	slo.CreatedAt = previous.CreatedAt
+	slo.UpdatedAt = time.Now()
+
+	stored := *slo
+	m.slos[name] = &stored
+	if err := m.save(); err != nil {
+		m.slos[name] = previous
+		return err
+	}
+	delete(m.statuses, name)
+	return nil
+}
+
+// Delete removes an SLO, its status and its alert rules
+func (m *Manager) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	slo, ok := m.slos[name]
+	if !ok {
+		return fmt.Errorf("SLO not found: %s", name)
+	}
+	delete(m.slos, name)
+	if err := m.save(); err != nil {
+		m.slos[name] = slo
+		return err
+	}
+	delete(m.statuses, name)
+	for _, rule := range alertRuleNames(name) {
+		m.alerts.RemoveRule(rule)
+	}
+	return nil
+}
+
+// Status returns the latest status of an SLO, computing it if it has none
+// yet
+func (m *Manager) Status(name string) (*Status, error) {
+	m.mu.RLock()
+	slo, ok := m.slos[name]
+	status := m.statuses[name]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("SLO not found: %s", name)
+	}
+	if status != nil {
+		return status, nil
+	}
+	return m.refresh(slo), nil
+}
+
+// Statuses returns the latest status of every SLO, by name
+func (m *Manager) Statuses() []*Status {
+	statuses := make([]*Status, 0)
+	for _, slo := range m.List() {
+		if status, err := m.Status(slo.Name); err == nil {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// Start refreshes the status of every SLO on the interval until ctx is
+// cancelled
+func (m *Manager) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.Refresh()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Refresh recomputes the status of every SLO
+func (m *Manager) Refresh() {
+	for _, slo := range m.List() {
+		slo := slo
+		m.refresh(&slo)
+	}
+}
+
+// refresh computes and keeps the status of an SLO. A status that could
+// not be computed says why.
+func (m *Manager) refresh(slo *SLO) *Status {
+	now := time.Now()
+	var status *Status
+	rows, err := m.db.ExecuteSQL(slo.statusQuery())
+	switch {
+	case err != nil:
+		log.Error().Err(err).Str("slo", slo.Name).Msg("Failed to compute SLO status")
+		status = &Status{SLO: slo.Name, Service: slo.Service, Objective: slo.Objective, WindowDays: slo.WindowDays,
+			UpdatedAt: now, Error: fmt.Sprintf("failed to count logs: %v", err)}
+	case len(rows) == 0:
+		status = slo.newStatus(map[string]interface{}{}, now)
+	default:
+		status = slo.newStatus(rows[0], now)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// The SLO may have changed or gone while its logs were counted
+	if current, ok := m.slos[slo.Name]; ok && current.UpdatedAt.Equal(slo.UpdatedAt) {
+		m.statuses[slo.Name] = status
+	}
+	return status
+}
+
+// alertRuleNames returns the names of the burn-rate alert rules of an SLO:
+// the fast burn one, then the slow burn one
+func alertRuleNames(name string) []string {
+	return []string{"slo_" + name + "_fast_burn", "slo_" + name + "_slow_burn"}
+}
+
+// addAlertRules registers the burn-rate alert rules of an SLO. Fast burns
+// are critical, slow burns warnings.
+func (m *Manager) addAlertRules(slo *SLO) {
+	names := alertRuleNames(slo.Name)
+	m.alerts.AddRule(m.burnRateRule(slo.Name, names[0], "fast", monitoring.SeverityCritical, fastBurnAlerts))
+	m.alerts.AddRule(m.burnRateRule(slo.Name, names[1], "slow", monitoring.SeverityWarning, slowBurnAlerts))
+}
+
+// burnRateRule returns an alert rule holding while the latest status of
+// an SLO burns its error budget as fast as one of alerts
+func (m *Manager) burnRateRule(sloName, ruleName, speed string, severity monitoring.AlertSeverity, alerts []burnRateAlert) monitoring.AlertRule {
+	return monitoring.AlertRule{
+		Name:        ruleName,
+		Description: fmt.Sprintf("SLO %s is burning its error budget %s", sloName, speed),
+		Severity:    severity,
+		Source:      AlertSource,
+		Evaluate: func(_ []monitoring.Metric) (bool, string, interface{}, error) {
+			m.mu.RLock()
+			status := m.statuses[sloName]
+			m.mu.RUnlock()
+			if status == nil || status.Error != "" {
+				return false, "", nil, monitoring.ErrNoSample
+			}
+
+			alert, burning := status.burning(alerts)
+			if !burning {
+				return false, "", nil, nil
+			}
+			long, short := status.window(alert.long), status.window(alert.short)
+			message := fmt.Sprintf("SLO %s is burning its error budget %.1fx over the last %s and %.1fx over the last %s (threshold: %gx)",
+				sloName, long.BurnRate, long.Window, short.BurnRate, short.Window, alert.factor)
+			details := map[string]interface{}{
+				"slo":                    sloName,
+				"service":                status.Service,
+				"objective":              status.Objective,
+				"value":                  long.BurnRate,
+				"short_window_burn_rate": short.BurnRate,
+				"long_window":            long.Window,
+				"short_window":           short.Window,
+				"threshold":              alert.factor,
+				"error_budget_remaining": status.ErrorBudgetRemaining,
+			}
+			return true, message, details, nil
+		},
+	}
+}
+
+func (m *Manager) save() error {
+	if m.storage == nil {
+		return nil
+	}
+	slos := make([]*SLO, 0, len(m.slos))
+	for _, slo := range m.slos {
+		slos = append(slos, slo)
+	}
+	sort.Slice(slos, func(i, j int) bool { return slos[i].Name < slos[j].Name })
+	if err := m.storage.Save(slos); err != nil {
+		return fmt.Errorf("failed to save SLOs: %w", err)
+	}
+	return nil
+}
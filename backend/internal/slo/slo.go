@@ -0,0 +1,256 @@
+package slo
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+)
+
+const (
+	defaultWindowDays = 30
+	maxWindowDays     = 90
+	defaultTable      = "logs"
+)
+
+var (
+	namePattern       = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,100}$`)
+	identifierPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+	// defaultBadLevels are the levels of the logs counted against an SLO
+	defaultBadLevels = []string{"error", "fatal", "critical"}
+)
+
+// SLO is a service level objective computed from log counts: the share of
+// a service's logs over a rolling window that must not be at a bad level,
+// such as 99.9% of the logs of checkout without an error
+type SLO struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Service     string    `json:"service,omitempty"`    // all services when empty
+	Table       string    `json:"table,omitempty"`      // logs when empty
+	Objective   float64   `json:"objective"`            // percent of good logs, e.g. 99.9
+	WindowDays  int       `json:"window_days"`          // 30 when 0
+	BadLevels   []string  `json:"bad_levels,omitempty"` // error, fatal and critical when empty
+	CreatedBy   string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WindowStatus is the error rate of an SLO over a recent window and how
+// many times faster than allowed it burns the error budget
+type WindowStatus struct {
+	Window    string  `json:"window"`
+	Total     int64   `json:"total"`
+	Bad       int64   `json:"bad"`
+	ErrorRate float64 `json:"error_rate"`
+	BurnRate  float64 `json:"burn_rate"` // 1 spends exactly the budget over the SLO window
+}
+
+// Status is where an SLO stands over its window and the burn-rate windows
+type Status struct {
+	SLO        string  `json:"slo"`
+	Service    string  `json:"service,omitempty"`
+	Objective  float64 `json:"objective"`
+	WindowDays int     `json:"window_days"`
+	Total      int64   `json:"total"`
+	Bad        int64   `json:"bad"`
+	// Compliance is the percent of good logs over the SLO window, 100 when
+	// there are none
+	Compliance float64 `json:"compliance"`
+	// ErrorBudgetRemaining is the fraction of the error budget left,
+	// negative once the objective is missed
+	ErrorBudgetRemaining float64        `json:"error_budget_remaining"`
+	Windows              []WindowStatus `json:"windows"`
+	FastBurn             bool           `json:"fast_burn"` // whether the page-worthy burn-rate alert holds
+	SlowBurn             bool           `json:"slow_burn"` // whether the ticket-worthy burn-rate alert holds
+	UpdatedAt            time.Time      `json:"updated_at"`
+	Error                string         `json:"error,omitempty"` // why the status could not be computed
+}
+
+// burnRateAlert fires when the error budget burns faster than factor times
+// the allowed rate over both a long window and a short one, the short one
+// making the alert resolve soon after the burn stops
+type burnRateAlert struct {
+	long   time.Duration
+	short  time.Duration
+	factor float64
+}
+
+// The multi-window burn-rate alerts of a 30-day SLO: fast burns spend 2%
+// of the budget in an hour or 5% in six hours, slow burns 10% in a day or
+// 10% in three days
+var (
+	fastBurnAlerts = []burnRateAlert{
+		{long: time.Hour, short: 5 * time.Minute, factor: 14.4},
+		{long: 6 * time.Hour, short: 30 * time.Minute, factor: 6},
+	}
+	slowBurnAlerts = []burnRateAlert{
+		{long: 24 * time.Hour, short: 2 * time.Hour, factor: 3},
+		{long: 72 * time.Hour, short: 6 * time.Hour, factor: 1},
+	}
+)
+
+// burnWindows are the windows a status reports, shortest first
+var burnWindows = []time.Duration{
+	5 * time.Minute, 30 * time.Minute, time.Hour, 2 * time.Hour, 6 * time.Hour, 24 * time.Hour, 72 * time.Hour,
+}
+
+// validate fills in defaults and checks the SLO
+func (s *SLO) validate() error {
+	if !namePattern.MatchString(s.Name) {
+		return fmt.Errorf("invalid SLO name %q: use up to 100 letters, digits, '_', '.' or '-'", s.Name)
+	}
+	if s.Table == "" {
+		s.Table = defaultTable
+	}
+	if !identifierPattern.MatchString(s.Table) {
+		return fmt.Errorf("invalid table name %q", s.Table)
+	}
+	if s.Objective <= 0 || s.Objective >= 100 {
+		return fmt.Errorf("objective must be a percent between 0 and 100, e.g. 99.9")
+	}
+	if s.WindowDays == 0 {
+		s.WindowDays = defaultWindowDays
+	}
+	if s.WindowDays < 1 || s.WindowDays > maxWindowDays {
+		return fmt.Errorf("window must be between 1 and %d days", maxWindowDays)
+	}
+	if len(s.BadLevels) == 0 {
+		s.BadLevels = append([]string(nil), defaultBadLevels...)
+	}
+	for i, level := range s.BadLevels {
+		level = strings.ToLower(strings.TrimSpace(level))
+		if level == "" {
+			return fmt.Errorf("bad levels cannot be empty")
+		}
+		s.BadLevels[i] = level
+	}
+	return nil
+}
+
+// budget is the share of logs that may be bad
+func (s *SLO) budget() float64 {
+	return 1 - s.Objective/100
+}
+
+// statusQuery counts the logs and the bad logs of the SLO over its window
+// and each burn-rate window in one scan
+func (s *SLO) statusQuery() string {
+	levels := make([]string, len(s.BadLevels))
+	for i, level := range s.BadLevels {
+		levels[i] = chsql.Quote(level)
+	}
+	bad := fmt.Sprintf("lower(level) IN (%s)", strings.Join(levels, ", "))
+
+	span := time.Duration(s.WindowDays) * 24 * time.Hour
+	if longest := burnWindows[len(burnWindows)-1]; span < longest {
+		span = longest
+	}
+	columns := []string{
+		fmt.Sprintf("countIf(timestamp >= now() - INTERVAL %d DAY) AS total", s.WindowDays),
+		fmt.Sprintf("countIf(timestamp >= now() - INTERVAL %d DAY AND %s) AS bad", s.WindowDays, bad),
+	}
+	for _, window := range burnWindows {
+		seconds := int64(window.Seconds())
+		columns = append(columns,
+			fmt.Sprintf("countIf(timestamp >= now() - INTERVAL %d SECOND) AS total_%d", seconds, seconds),
+			fmt.Sprintf("countIf(timestamp >= now() - INTERVAL %d SECOND AND %s) AS bad_%d", seconds, bad, seconds),
+		)
+	}
+
+	conditions := []string{fmt.Sprintf("timestamp >= now() - INTERVAL %d SECOND", int64(span.Seconds()))}
+	if s.Service != "" {
+		conditions = append(conditions, "service = "+chsql.Quote(s.Service))
+	}
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s",
+		strings.Join(columns, ", "), s.Table, strings.Join(conditions, " AND "))
+}
+
+// newStatus computes the status of the SLO from the row of its status query
+func (s *SLO) newStatus(row map[string]interface{}, now time.Time) *Status {
+	status := &Status{
+		SLO:        s.Name,
+		Service:    s.Service,
+		Objective:  s.Objective,
+		WindowDays: s.WindowDays,
+		Total:      count(row["total"]),
+		Bad:        count(row["bad"]),
+		Compliance: 100,
+		UpdatedAt:  now,
+	}
+	budget := s.budget()
+	status.ErrorBudgetRemaining = 1
+	if status.Total > 0 {
+		errorRate := float64(status.Bad) / float64(status.Total)
+		status.Compliance = 100 * (1 - errorRate)
+		status.ErrorBudgetRemaining = 1 - errorRate/budget
+	}
+
+	for _, window := range burnWindows {
+		seconds := int64(window.Seconds())
+		ws := WindowStatus{
+			Window: formatWindow(window),
+			Total:  count(row[fmt.Sprintf("total_%d", seconds)]),
+			Bad:    count(row[fmt.Sprintf("bad_%d", seconds)]),
+		}
+		if ws.Total > 0 {
+			ws.ErrorRate = float64(ws.Bad) / float64(ws.Total)
+			ws.BurnRate = ws.ErrorRate / budget
+		}
+		status.Windows = append(status.Windows, ws)
+	}
+	_, status.FastBurn = status.burning(fastBurnAlerts)
+	_, status.SlowBurn = status.burning(slowBurnAlerts)
+	return status
+}
+
+// window returns the status of a burn-rate window
+func (st *Status) window(window time.Duration) WindowStatus {
+	name := formatWindow(window)
+	for _, ws := range st.Windows {
+		if ws.Window == name {
+			return ws
+		}
+	}
+	return WindowStatus{Window: name}
+}
+
+// burning returns the first of alerts whose windows both burn faster than
+// its factor
+func (st *Status) burning(alerts []burnRateAlert) (burnRateAlert, bool) {
+	for _, alert := range alerts {
+		if st.window(alert.long).BurnRate > alert.factor && st.window(alert.short).BurnRate > alert.factor {
+			return alert, true
+		}
+	}
+	return burnRateAlert{}, false
+}
+
+// formatWindow formats a window as 5m, 1h or 3d
+func formatWindow(window time.Duration) string {
+	switch {
+	case window%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", window/(24*time.Hour))
+	case window%time.Hour == 0:
+		return fmt.Sprintf("%dh", window/time.Hour)
+	}
+	return fmt.Sprintf("%dm", window/time.Minute)
+}
+
+// count reads a count, which ClickHouse quotes in JSON as a 64-bit integer
+func count(value interface{}) int64 {
+	switch v := value.(type) {
+	case float64:
+		return int64(v)
+	case int64:
+		return v
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	}
+	return 0
+}
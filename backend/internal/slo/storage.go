@@ -0,0 +1,70 @@
+package slo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Storage persists SLOs
+type Storage interface {
+	Load() ([]*SLO, error)
+	Save(slos []*SLO) error
+}
+
+// FileStorage persists SLOs as a JSON document on disk
+type FileStorage struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStorage creates a file-backed SLO storage
+func NewFileStorage(path string) *FileStorage {
+	return &FileStorage{path: path}
+}
+
+// Load reads the SLOs from the file, none if it does not exist
+func (s *FileStorage) Load() ([]*SLO, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read SLOs file: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var slos []*SLO
+	if err := json.Unmarshal(data, &slos); err != nil {
+		return nil, fmt.Errorf("failed to decode SLOs file: %w", err)
+	}
+	return slos, nil
+}
+
+// Save replaces the SLOs in the file
+func (s *FileStorage) Save(slos []*SLO) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(slos, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode SLOs: %w", err)
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create SLOs directory: %w", err)
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write SLOs file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
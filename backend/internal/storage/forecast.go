@@ -0,0 +1,480 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+)
+
+const (
+	// usageHistoryTable keeps the samples of table sizes forecasts are
+	// made from
+	usageHistoryTable = "storage_usage_history"
+	// minForecastHistory is the history needed to estimate growth
+	minForecastHistory = time.Hour
+	// forecastCacheTTL bounds how often forecasts query ClickHouse, as
+	// alert rules ask for one on every check
+	forecastCacheTTL = time.Minute
+	// MaxForecastDays bounds the projection of a forecast
+	MaxForecastDays = 365
+)
+
+var (
+	// ttlIntervalPattern finds the intervals of TTL expressions, as
+	// ClickHouse normalizes them in engine_full: toIntervalDay(30)
+	ttlIntervalPattern = regexp.MustCompile(`toInterval(Second|Minute|Hour|Day|Week|Month|Quarter|Year)\((\d+)\)`)
+	// ttlColumnPattern finds what a TTL expression adds its interval to
+	ttlColumnPattern = regexp.MustCompile(`TTL\s+(.+?)\s*\+\s*toInterval`)
+)
+
+var ttlUnits = map[string]float64{
+	"Second":  1.0 / 86400,
+	"Minute":  1.0 / 1440,
+	"Hour":    1.0 / 24,
+	"Day":     1,
+	"Week":    7,
+	"Month":   30,
+	"Quarter": 91,
+	"Year":    365,
+}
+
+// RetentionForecast projects the disk usage of a table, which its TTL
+// keeps to a retention period
+type RetentionForecast struct {
+	Table         string  `json:"table"`
+	RetentionDays float64 `json:"retention_days"` // 0 when data is kept forever
+	Bytes         int64   `json:"bytes"`
+	Rows          int64   `json:"rows"`
+	OldestDays    float64 `json:"oldest_days"` // age of the oldest data, 0 when unknown
+	// Growth is estimated from the history of the table's size, net of
+	// what its TTL removed
+	GrowthBytesPerDay float64 `json:"growth_bytes_per_day"`
+	GrowthRowsPerDay  float64 `json:"growth_rows_per_day"`
+	// A table younger than its retention grows until its oldest data
+	// expires, then stays at its steady state size
+	DaysUntilSteady  *float64 `json:"days_until_steady,omitempty"`
+	SteadyStateBytes *int64   `json:"steady_state_bytes,omitempty"`
+	ProjectedBytes   int64    `json:"projected_bytes"` // at the end of the forecast
+	// DaysUntilFull is when this table alone would fill the disk, the
+	// others staying as they are
+	DaysUntilFull *float64 `json:"days_until_full,omitempty"`
+
+	ttlColumn string  // what the TTL counts from, to find the oldest data
+	fillDays  float64 // days of growth left, +Inf when unbounded
+}
+
+// ProjectedUsage is the disk usage projected for a day
+type ProjectedUsage struct {
+	Day       int       `json:"day"`
+	Time      time.Time `json:"time"`
+	UsedBytes int64     `json:"used_bytes"`
+}
+
+// CapacityForecast projects disk usage from the growth of each table and
+// its retention
+type CapacityForecast struct {
+	GeneratedAt   time.Time           `json:"generated_at"`
+	Days          int                 `json:"days"`
+	DiskTotal     int64               `json:"disk_total_bytes"`
+	DiskFree      int64               `json:"disk_free_bytes"`
+	HistoryHours  float64             `json:"history_hours"` // of size samples growth was estimated from
+	GrowthKnown   bool                `json:"growth_known"`  // false until there is enough history
+	DaysUntilFull *float64            `json:"days_until_full,omitempty"`
+	FullAt        *time.Time          `json:"full_at,omitempty"`
+	Policies      []RetentionForecast `json:"policies"`
+	Projection    []ProjectedUsage    `json:"projection"`
+}
+
+// CapacityForecaster samples the size of the tables of a database into a
+// history table and projects from it when the disk fills up
+type CapacityForecaster struct {
+	db          DatabaseInterface
+	database    string
+	historyDays int
+
+	mu       sync.Mutex
+	cached   *CapacityForecast // of MaxForecastDays
+	cachedAt time.Time
+}
+
+// NewCapacityForecaster creates a forecaster estimating growth from
+// historyDays of samples, creating the history table if needed
+func NewCapacityForecaster(db DatabaseInterface, database string, historyDays int) (*CapacityForecaster, error) {
+	f := &CapacityForecaster{db: db, database: database, historyDays: historyDays}
+	ddl := fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		sampled_at DateTime,
+		table LowCardinality(String),
+		bytes UInt64,
+		rows UInt64
+	) ENGINE = MergeTree()
+	ORDER BY (table, sampled_at)
+	TTL sampled_at + INTERVAL %d DAY`, f.historyTable(), historyDays+1)
+	if err := db.Exec(ddl); err != nil {
+		return nil, fmt.Errorf("failed to create storage usage history table: %w", err)
+	}
+	return f, nil
+}
+
+// Start samples table sizes now and on the interval until ctx is cancelled
+func (f *CapacityForecaster) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := f.Sample(); err != nil {
+			log.Warn().Err(err).Msg("Failed to sample storage usage")
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Sample records the size of every table of the database
+func (f *CapacityForecaster) Sample() error {
+	return f.db.Exec(fmt.Sprintf(`
+	INSERT INTO %s (sampled_at, table, bytes, rows)
+	SELECT now(), table, sum(bytes_on_disk), sum(rows)
+	FROM system.parts
+	WHERE database = %s AND active AND table != %s
+	GROUP BY table`, f.historyTable(), chsql.Quote(f.database), chsql.Quote(usageHistoryTable)))
+}
+
+// Forecast projects disk usage over the next days, at most
+// MaxForecastDays
+func (f *CapacityForecaster) Forecast(days int) (*CapacityForecast, error) {
+	if days <= 0 || days > MaxForecastDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", MaxForecastDays)
+	}
+	full, err := f.forecast()
+	if err != nil {
+		return nil, err
+	}
+
+	forecast := *full
+	forecast.Days = days
+	forecast.Projection = full.Projection[:days+1]
+	forecast.Policies = make([]RetentionForecast, len(full.Policies))
+	for i, policy := range full.Policies {
+		policy.ProjectedBytes = policy.Bytes + int64(growthAt(policy, float64(days)))
+		forecast.Policies[i] = policy
+	}
+	return &forecast, nil
+}
+
+// DaysUntilFull returns in how many days the disk fills up, or table alone
+// does when set. It is false when it does not within MaxForecastDays or
+// growth is not known yet.
+func (f *CapacityForecaster) DaysUntilFull(table string) (float64, bool, error) {
+	forecast, err := f.forecast()
+	if err != nil {
+		return 0, false, err
+	}
+	days := forecast.DaysUntilFull
+	if table != "" {
+		days = nil
+		found := false
+		for _, policy := range forecast.Policies {
+			if policy.Table == table {
+				days, found = policy.DaysUntilFull, true
+			}
+		}
+		if !found {
+			return 0, false, fmt.Errorf("table not found: %s", table)
+		}
+	}
+	if days == nil {
+		return 0, false, nil
+	}
+	return *days, true, nil
+}
+
+// forecast returns the forecast over MaxForecastDays, from the cache when
+// recent
+func (f *CapacityForecaster) forecast() (*CapacityForecast, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cached != nil && time.Since(f.cachedAt) < forecastCacheTTL {
+		return f.cached, nil
+	}
+
+	forecast, err := f.build(time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	f.cached, f.cachedAt = forecast, time.Now()
+	return forecast, nil
+}
+
+// build reads the disk, the tables and their history and projects them
+func (f *CapacityForecaster) build(now time.Time) (*CapacityForecast, error) {
+	forecast := &CapacityForecast{GeneratedAt: now, Days: MaxForecastDays}
+
+	disks, err := f.db.Query(`SELECT sum(free_space) AS free, sum(total_space) AS total FROM system.disks`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read disks: %w", err)
+	}
+	if len(disks) > 0 {
+		forecast.DiskFree = int64(number(disks[0]["free"]))
+		forecast.DiskTotal = int64(number(disks[0]["total"]))
+	}
+
+	policies, err := f.policies()
+	if err != nil {
+		return nil, err
+	}
+	if err := f.estimateGrowth(policies, forecast); err != nil {
+		return nil, err
+	}
+
+	for i := range policies {
+		policy := &policies[i]
+		f.readOldest(policy, now)
+		policy.fillDays = math.Inf(1)
+		if policy.RetentionDays > 0 && policy.OldestDays > 0 && policy.OldestDays < policy.RetentionDays {
+			fill := policy.RetentionDays - policy.OldestDays
+			policy.fillDays = fill
+			steady := policy.Bytes + int64(growthAt(*policy, fill))
+			policy.DaysUntilSteady, policy.SteadyStateBytes = &fill, &steady
+		}
+		policy.ProjectedBytes = policy.Bytes + int64(growthAt(*policy, MaxForecastDays))
+		if forecast.GrowthKnown {
+			policy.DaysUntilFull = daysUntil(policies[i:i+1], float64(forecast.DiskFree))
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Bytes > policies[j].Bytes })
+	forecast.Policies = policies
+
+	if forecast.GrowthKnown {
+		forecast.DaysUntilFull = daysUntil(policies, float64(forecast.DiskFree))
+		if forecast.DaysUntilFull != nil {
+			fullAt := now.Add(time.Duration(*forecast.DaysUntilFull * float64(24*time.Hour)))
+			forecast.FullAt = &fullAt
+		}
+	}
+
+	used := forecast.DiskTotal - forecast.DiskFree
+	forecast.Projection = make([]ProjectedUsage, MaxForecastDays+1)
+	for day := range forecast.Projection {
+		growth := 0.0
+		for _, policy := range policies {
+			growth += growthAt(policy, float64(day))
+		}
+		forecast.Projection[day] = ProjectedUsage{
+			Day:       day,
+			Time:      now.AddDate(0, 0, day),
+			UsedBytes: used + int64(growth),
+		}
+	}
+	return forecast, nil
+}
+
+// policies returns the tables of the database with their size and
+// retention
+func (f *CapacityForecaster) policies() ([]RetentionForecast, error) {
+	rows, err := f.db.Query(fmt.Sprintf(`
+	SELECT t.name AS table, t.engine_full AS engine, sum(p.bytes_on_disk) AS bytes, sum(p.rows) AS rows
+	FROM system.tables AS t
+	INNER JOIN system.parts AS p ON p.database = t.database AND p.table = t.name
+	WHERE t.database = %s AND p.active AND t.name != %s
+	GROUP BY t.name, t.engine_full`, chsql.Quote(f.database), chsql.Quote(usageHistoryTable)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tables: %w", err)
+	}
+
+	policies := make([]RetentionForecast, 0, len(rows))
+	for _, row := range rows {
+		table, _ := row["table"].(string)
+		engine, _ := row["engine"].(string)
+		policies = append(policies, RetentionForecast{
+			Table:         table,
+			RetentionDays: retentionDays(engine),
+			Bytes:         int64(number(row["bytes"])),
+			Rows:          int64(number(row["rows"])),
+			ttlColumn:     ttlColumn(engine),
+		})
+	}
+	return policies, nil
+}
+
+// estimateGrowth fits a line to the size history of each table
+func (f *CapacityForecaster) estimateGrowth(policies []RetentionForecast, forecast *CapacityForecast) error {
+	rows, err := f.db.Query(fmt.Sprintf(`
+	SELECT table, toUnixTimestamp(sampled_at) AS at, bytes, rows
+	FROM %s
+	WHERE sampled_at >= now() - INTERVAL %d DAY
+	ORDER BY table, sampled_at`, f.historyTable(), f.historyDays))
+	if err != nil {
+		return fmt.Errorf("failed to read storage usage history: %w", err)
+	}
+
+	type series struct{ at, bytes, rows []float64 }
+	history := make(map[string]*series)
+	first, last := math.Inf(1), math.Inf(-1)
+	for _, row := range rows {
+		table, _ := row["table"].(string)
+		s, ok := history[table]
+		if !ok {
+			s = &series{}
+			history[table] = s
+		}
+		at := number(row["at"])
+		s.at = append(s.at, at)
+		s.bytes = append(s.bytes, number(row["bytes"]))
+		s.rows = append(s.rows, number(row["rows"]))
+		first, last = math.Min(first, at), math.Max(last, at)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	span := time.Duration(last-first) * time.Second
+	forecast.HistoryHours = span.Hours()
+	forecast.GrowthKnown = span >= minForecastHistory
+	for i := range policies {
+		s, ok := history[policies[i].Table]
+		if !ok || len(s.at) < 2 {
+			continue
+		}
+		policies[i].GrowthBytesPerDay = slope(s.at, s.bytes) * 86400
+		policies[i].GrowthRowsPerDay = slope(s.at, s.rows) * 86400
+	}
+	return nil
+}
+
+// readOldest reads the age of the oldest data of a table with a TTL
+func (f *CapacityForecaster) readOldest(policy *RetentionForecast, now time.Time) {
+	if policy.ttlColumn == "" || policy.Rows == 0 {
+		return
+	}
+	rows, err := f.db.Query(fmt.Sprintf(`SELECT toUnixTimestamp(min(%s)) AS oldest FROM %s.%s`,
+		policy.ttlColumn, quoteIdentifier(f.database), quoteIdentifier(policy.Table)))
+	if err != nil || len(rows) == 0 {
+		log.Debug().Err(err).Str("table", policy.Table).Msg("Failed to read the oldest data of table")
+		return
+	}
+	if oldest := number(rows[0]["oldest"]); oldest > 0 {
+		policy.OldestDays = now.Sub(time.Unix(int64(oldest), 0)).Hours() / 24
+	}
+}
+
+func (f *CapacityForecaster) historyTable() string {
+	return quoteIdentifier(f.database) + "." + usageHistoryTable
+}
+
+// growthAt is how much a table grows over days. Only growth is projected,
+// a shrinking table being taken to stay as it is.
+func growthAt(policy RetentionForecast, days float64) float64 {
+	if policy.GrowthBytesPerDay <= 0 {
+		return 0
+	}
+	return policy.GrowthBytesPerDay * math.Min(days, policy.fillDays)
+}
+
+// daysUntil returns when the growth of policies reaches free bytes, nil
+// when it does not within MaxForecastDays
+func daysUntil(policies []RetentionForecast, free float64) *float64 {
+	if free <= 0 {
+		zero := 0.0
+		return &zero
+	}
+	// Growth is linear between the days tables reach their steady state
+	bounds := []float64{0, MaxForecastDays}
+	for _, policy := range policies {
+		if policy.fillDays < MaxForecastDays {
+			bounds = append(bounds, policy.fillDays)
+		}
+	}
+	sort.Float64s(bounds)
+
+	total := func(days float64) float64 {
+		growth := 0.0
+		for _, policy := range policies {
+			growth += growthAt(policy, days)
+		}
+		return growth
+	}
+	for i := 1; i < len(bounds); i++ {
+		start, end := bounds[i-1], bounds[i]
+		from, to := total(start), total(end)
+		if to < free || to == from {
+			continue
+		}
+		days := start + (free-from)/(to-from)*(end-start)
+		return &days
+	}
+	return nil
+}
+
+// retentionDays reads the longest TTL of a table's engine, which is when
+// its data is deleted, 0 when it has none
+func retentionDays(engine string) float64 {
+	i := strings.Index(engine, "TTL ")
+	if i < 0 {
+		return 0
+	}
+	days := 0.0
+	for _, match := range ttlIntervalPattern.FindAllStringSubmatch(engine[i:], -1) {
+		n, err := strconv.ParseFloat(match[2], 64)
+		if err == nil {
+			days = math.Max(days, n*ttlUnits[match[1]])
+		}
+	}
+	return days
+}
+
+// ttlColumn returns the expression the TTL of a table's engine counts
+// from, such as timestamp, empty when it has none
+func ttlColumn(engine string) string {
+	match := ttlColumnPattern.FindStringSubmatch(engine)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// slope fits y = a + b*x by least squares and returns b
+func slope(x, y []float64) float64 {
+	n := float64(len(x))
+	var sumX, sumY, sumXY, sumXX float64
+	for i := range x {
+		sumX += x[i]
+		sumY += y[i]
+		sumXY += x[i] * y[i]
+		sumXX += x[i] * x[i]
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
+
+// number reads a JSONEachRow number, which ClickHouse quotes when it is a
+// 64-bit integer
+func number(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
+
+func quoteIdentifier(s string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(s) + "`"
+}
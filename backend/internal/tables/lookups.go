@@ -0,0 +1,92 @@
+package tables
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SourceLookup marks dimension tables registered as lookups
+const SourceLookup = "lookup"
+
+var lookupNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Lookup is a dimension table queries may join to enrich logs, e.g.
+// service_owners keyed by service mapping services to their team
+type Lookup struct {
+	Table string `json:"table"`
+	Key   string `json:"key"`   // column of the lookup table rows are matched on
+	Field string `json:"field"` // column of the joined table matched against Key
+}
+
+// ParseLookup reads a lookup written as table:key or table:key=field,
+// where field defaults to key
+func ParseLookup(spec string) (Lookup, error) {
+	table, key, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok {
+		return Lookup{}, fmt.Errorf("invalid lookup %q: expected table:key", spec)
+	}
+	key, field, ok := strings.Cut(key, "=")
+	if !ok {
+		field = key
+	}
+	lookup := Lookup{Table: table, Key: key, Field: field}
+	for _, name := range []string{lookup.Table, lookup.Key, lookup.Field} {
+		if !lookupNamePattern.MatchString(name) {
+			return Lookup{}, fmt.Errorf("invalid lookup %q: bad name %q", spec, name)
+		}
+	}
+	return lookup, nil
+}
+
+// SetLookups registers the lookup tables. Their columns are loaded with the
+// discovered tables, so discovery must be set for them to become queryable.
+func (r *Registry) SetLookups(lookups []Lookup) error {
+	byTable := make(map[string]Lookup, len(lookups))
+	for _, lookup := range lookups {
+		if lookup.Table == DefaultTable {
+			return fmt.Errorf("%s cannot be a lookup", DefaultTable)
+		}
+		if _, exists := byTable[lookup.Table]; exists {
+			return fmt.Errorf("duplicate lookup: %s", lookup.Table)
+		}
+		byTable[lookup.Table] = lookup
+	}
+
+	r.mu.Lock()
+	r.lookups = byTable
+	r.mu.Unlock()
+	return nil
+}
+
+// GetLookup returns the lookup on a table, if the table was loaded
+func (r *Registry) GetLookup(name string) (Lookup, *Table, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	lookup, exists := r.lookups[name]
+	if !exists {
+		return Lookup{}, nil, false
+	}
+	table, exists := r.tables[name]
+	if !exists || table.Source != SourceLookup {
+		return Lookup{}, nil, false
+	}
+	return lookup, table, true
+}
+
+// Lookups returns the loaded lookups sorted by table
+func (r *Registry) Lookups() []Lookup {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	lookups := make([]Lookup, 0, len(r.lookups))
+	for name, lookup := range r.lookups {
+		if table, exists := r.tables[name]; exists && table.Source == SourceLookup {
+			lookups = append(lookups, lookup)
+		}
+	}
+	sort.Slice(lookups, func(i, j int) bool {
+		return lookups[i].Table < lookups[j].Table
+	})
+	return lookups
+}
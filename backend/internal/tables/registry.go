@@ -0,0 +1,261 @@
+package tables
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Table sources
+const (
+	SourceBuiltIn    = "builtin"
+	SourceDiscovered = "discovered"
+)
+
+// DefaultTable is the table queries and the query builder use by default
+const DefaultTable = "logs"
+
+// DefaultPatterns match the tables ingestion routes logs to
+var DefaultPatterns = []string{"logs", "logs_*", "*_logs", "app_logs_*"}
+
+// SQLExecutor runs raw SQL against ClickHouse
+type SQLExecutor interface {
+	ExecuteSQL(sql string) ([]map[string]interface{}, error)
+}
+
+// Column is a column of a table with its ClickHouse type
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Table describes a queryable table
+type Table struct {
+	Name        string   `json:"name"`
+	Columns     []Column `json:"columns"`
+	TimeColumn  string   `json:"time_column,omitempty"`  // column time ranges filter on
+	SamplingKey string   `json:"sampling_key,omitempty"` // set when the table supports SAMPLE
+	Source      string   `json:"source"`
+}
+
+// Column returns the column with the given name
+func (t *Table) Column(name string) (Column, bool) {
+	for _, column := range t.Columns {
+		if column.Name == name {
+			return column, true
+		}
+	}
+	return Column{}, false
+}
+
+// Registry knows which tables users may query and their schemas. The logs
+// table is always present; other tables are discovered in ClickHouse by
+// name pattern once discovery is configured.
+type Registry struct {
+	tables   map[string]*Table
+	db       SQLExecutor
+	patterns []string
+	lookups  map[string]Lookup
+	mu       sync.RWMutex
+}
+
+// NewRegistry creates a registry holding the built-in logs table
+func NewRegistry() *Registry {
+	logs := logsTable()
+	return &Registry{
+		tables: map[string]*Table{logs.Name: logs},
+	}
+}
+
+// SetDiscovery enables discovering tables whose names match one of the
+// patterns (path.Match syntax, e.g. app_logs_*) and loads them
+func (r *Registry) SetDiscovery(db SQLExecutor, patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid table pattern %q: %w", pattern, err)
+		}
+	}
+
+	r.mu.Lock()
+	r.db = db
+	r.patterns = patterns
+	r.mu.Unlock()
+
+	return r.Refresh()
+}
+
+// Refresh reloads the discovered tables and their columns
+func (r *Registry) Refresh() error {
+	r.mu.RLock()
+	db, patterns, lookups := r.db, r.patterns, r.lookups
+	r.mu.RUnlock()
+	if db == nil {
+		return nil
+	}
+
+	rows, err := db.ExecuteSQL(`
+		SELECT table, name, type
+		FROM system.columns
+		WHERE database = currentDatabase()
+		ORDER BY table, position
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to discover tables: %w", err)
+	}
+
+	discovered := make(map[string]*Table)
+	for _, row := range rows {
+		name := fmt.Sprintf("%v", row["table"])
+		_, isLookup := lookups[name]
+		if !isLookup && !matchesAny(name, patterns) {
+			continue
+		}
+		table, exists := discovered[name]
+		if !exists {
+			table = &Table{Name: name, Columns: []Column{}, Source: SourceDiscovered}
+			if isLookup {
+				table.Source = SourceLookup
+			}
+			discovered[name] = table
+		}
+		table.Columns = append(table.Columns, Column{
+			Name: fmt.Sprintf("%v", row["name"]),
+			Type: fmt.Sprintf("%v", row["type"]),
+		})
+	}
+
+	samplingKeys, err := db.ExecuteSQL(`
+		SELECT name, sampling_key
+		FROM system.tables
+		WHERE database = currentDatabase() AND sampling_key != ''
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to discover sampling keys: %w", err)
+	}
+	for _, row := range samplingKeys {
+		if table, exists := discovered[fmt.Sprintf("%v", row["name"])]; exists {
+			table.SamplingKey = fmt.Sprintf("%v", row["sampling_key"])
+		}
+	}
+
+	logs := logsTable()
+	tables := map[string]*Table{logs.Name: logs}
+	for name, table := range discovered {
+		if name == logs.Name {
+			// The live schema wins over the built-in one
+			table.Source = SourceBuiltIn
+		}
+		table.TimeColumn = timeColumn(table.Columns)
+		tables[name] = table
+	}
+
+	r.mu.Lock()
+	r.tables = tables
+	r.mu.Unlock()
+	return nil
+}
+
+// Start refreshes the discovered tables periodically until ctx is cancelled
+func (r *Registry) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Refresh(); err != nil {
+				log.Error().Err(err).Msg("Failed to refresh table registry")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Get returns a table by name
+func (r *Registry) Get(name string) (*Table, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	table, exists := r.tables[name]
+	return table, exists
+}
+
+// Has reports whether a table may be queried. Names are compared case
+// insensitively because the SQL validator sees upper-cased tokens.
+func (r *Registry) Has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if _, exists := r.tables[name]; exists {
+		return true
+	}
+	for tableName := range r.tables {
+		if strings.EqualFold(tableName, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns all tables sorted by name
+func (r *Registry) List() []*Table {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tables := make([]*Table, 0, len(r.tables))
+	for _, table := range r.tables {
+		tables = append(tables, table)
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+	return tables
+}
+
+// logsTable is the schema created by the database package
+func logsTable() *Table {
+	return &Table{
+		Name: DefaultTable,
+		Columns: []Column{
+			{Name: "id", Type: "UUID"},
+			{Name: "timestamp", Type: "DateTime64(3)"},
+			{Name: "level", Type: "String"},
+			{Name: "message", Type: "String"},
+			{Name: "service", Type: "String"},
+			{Name: "trace_id", Type: "String"},
+			{Name: "span_id", Type: "String"},
+			{Name: "attributes", Type: "Map(String, String)"},
+		},
+		TimeColumn: "timestamp",
+		Source:     SourceBuiltIn,
+	}
+}
+
+// timeColumn picks timestamp if present, else the first date-time column
+func timeColumn(columns []Column) string {
+	first := ""
+	for _, column := range columns {
+		if !strings.HasPrefix(strings.TrimPrefix(column.Type, "Nullable("), "DateTime") {
+			continue
+		}
+		if column.Name == "timestamp" {
+			return column.Name
+		}
+		if first == "" {
+			first = column.Name
+		}
+	}
+	return first
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package telemetry
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/your-username/click-lite-log-analytics/backend/internal/telemetry"
+
+// HTTPMiddleware is chi middleware tracing every request in a server span
+// named after its route pattern, continuing the trace of the caller, and
+// recording the http.server.request.duration and
+// http.server.active_requests metrics. WebSocket upgrades are connections
+// rather than requests and are left out.
+func HTTPMiddleware() func(http.Handler) http.Handler {
+	tracer := otel.Tracer(instrumentationName)
+	meter := otel.Meter(instrumentationName)
+	duration, err := meter.Float64Histogram("http.server.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of HTTP server requests"),
+		metric.WithExplicitBucketBoundaries(0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10))
+	if err != nil {
+		otel.Handle(err)
+	}
+	active, err := meter.Int64UpDownCounter("http.server.active_requests",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Number of HTTP server requests in flight"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+					semconv.ClientAddress(r.RemoteAddr),
+					semconv.UserAgentOriginal(r.UserAgent()),
+				))
+			defer span.End()
+
+			activeAttributes := metric.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method))
+			active.Add(ctx, 1, activeAttributes)
+			defer active.Add(ctx, -1, activeAttributes)
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			next.ServeHTTP(ww, r.WithContext(ctx))
+			elapsed := time.Since(start)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			// The router fills in the pattern of the route while serving
+			route := unmatchedRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+
+			duration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.HTTPResponseStatusCode(status),
+			))
+		})
+	}
+}
+
+// unmatchedRoute is the route of requests no route matched, so that
+// arbitrary paths do not each get their own span name
+const unmatchedRoute = "unmatched"
@@ -0,0 +1,106 @@
+// Package telemetry exports OpenTelemetry traces and metrics about
+// Click-Lite itself, over OTLP, to an operator's observability stack.
+// Instrumented packages use the global tracer and meter providers, which
+// discard everything until Setup installs exporting ones.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultMetricInterval = time.Minute
+
+// Options configure the export of traces and metrics
+type Options struct {
+	// Endpoint is the base URL of the OTLP/HTTP collector, such as
+	// http://otel-collector:4318. Traces go to /v1/traces and metrics to
+	// /v1/metrics under it.
+	Endpoint       string
+	ServiceName    string
+	ServiceVersion string
+	// SampleRatio is the share of traces started here that are kept, 1 to
+	// keep them all. Requests carrying a sampled parent are always kept.
+	SampleRatio    float64
+	MetricInterval time.Duration // how often metrics are exported, a minute when 0
+}
+
+// Setup installs global tracer and meter providers exporting to the OTLP
+// collector at options.Endpoint, and the W3C trace context propagator.
+// The returned function flushes what is left and stops exporting.
+func Setup(ctx context.Context, options Options) (func(context.Context) error, error) {
+	endpoint := strings.TrimSuffix(options.Endpoint, "/")
+	if endpoint == "" {
+		return nil, fmt.Errorf("OTLP endpoint is required")
+	}
+	if options.MetricInterval <= 0 {
+		options.MetricInterval = defaultMetricInterval
+	}
+	if options.SampleRatio <= 0 || options.SampleRatio > 1 {
+		options.SampleRatio = 1
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(
+			semconv.ServiceName(options.ServiceName),
+			semconv.ServiceVersion(options.ServiceVersion),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the telemetry resource: %w", err)
+	}
+
+	// Headers, such as an API key, are read from OTEL_EXPORTER_OTLP_HEADERS
+	traceExporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint+"/v1/traces"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+	metricExporter, err := otlpmetrichttp.New(ctx, otlpmetrichttp.WithEndpointURL(endpoint+"/v1/metrics"))
+	if err != nil {
+		traceExporter.Shutdown(ctx)
+		return nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithBatcher(traceExporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SampleRatio))),
+	)
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter, sdkmetric.WithInterval(options.MetricInterval))),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		return errors.Join(tracerProvider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+	}, nil
+}
+
+// SpanError marks span as failed with err, when there is one
+func SpanError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
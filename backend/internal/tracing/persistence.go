@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SetStorage persists the traces to storage from now on, so that they can
+// still be searched and read once they leave the cache
+func (tm *TraceManager) SetStorage(storage TraceStorage) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.storage = storage
+	tm.dirty = make(map[string]bool, len(tm.traceCache))
+	for traceID := range tm.traceCache {
+		tm.dirty[traceID] = true
+	}
+}
+
+// Flush writes the traces changed since the last flush to storage
+func (tm *TraceManager) Flush() error {
+	tm.mu.Lock()
+	if tm.storage == nil || len(tm.dirty) == 0 {
+		tm.mu.Unlock()
+		return nil
+	}
+	changed := make([]*TraceTree, 0, len(tm.dirty))
+	for traceID := range tm.dirty {
+		// Expired traces were flushed after their last change already
+		if trace, ok := tm.traceCache[traceID]; ok {
+			changed = append(changed, trace.tree(false))
+		}
+	}
+	tm.dirty = make(map[string]bool)
+	storage := tm.storage
+	tm.mu.Unlock()
+
+	err := storage.Save(changed)
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	for _, tree := range changed {
+		if err != nil {
+			// Mark them dirty again so the next flush retries
+			tm.dirty[tree.TraceID] = true
+		} else if trace, ok := tm.traceCache[tree.TraceID]; ok {
+			trace.persisted = true
+		}
+	}
+	return err
+}
+
+// Start flushes the traces periodically until the context is cancelled,
+// and a last time then
+func (tm *TraceManager) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := tm.Flush(); err != nil {
+				log.Error().Err(err).Msg("Failed to persist traces")
+			}
+		case <-ctx.Done():
+			if err := tm.Flush(); err != nil {
+				log.Error().Err(err).Msg("Failed to persist traces")
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// SpanData is a span as reported by an instrumented service, with exact
+// timings, as opposed to the spans derived from logs
+type SpanData struct {
+	TraceID    string
+	SpanID     string
+	ParentID   string
+	Service    string
+	Operation  string
+	StartTime  time.Time
+	EndTime    time.Time
+	Failed     bool
+	Attributes map[string]interface{}
+}
+
+// ProcessSpan adds a reported span to its trace. A span already derived
+// from logs takes the timings, parent and operation reported, and keeps
+// its logs; the logs of the span that follow no longer move its timings.
+func (tm *TraceManager) ProcessSpan(data *SpanData) {
+	if data.TraceID == "" || data.SpanID == "" {
+		return
+	}
+	if data.EndTime.Before(data.StartTime) {
+		data.EndTime = data.StartTime
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	trace, exists := tm.traceCache[data.TraceID]
+	if !exists {
+		trace = &Trace{
+			TraceID:   data.TraceID,
+			StartTime: data.StartTime,
+			EndTime:   data.EndTime,
+			Services:  make(map[string]bool),
+			Spans:     make([]*Span, 0),
+		}
+		tm.traceCache[data.TraceID] = trace
+	}
+	if tm.storage != nil {
+		tm.dirty[data.TraceID] = true
+	}
+
+	trace.LastUpdated = time.Now()
+	if data.StartTime.Before(trace.StartTime) {
+		trace.StartTime = data.StartTime
+	}
+	if data.EndTime.After(trace.EndTime) {
+		trace.EndTime = data.EndTime
+	}
+	trace.Duration = trace.EndTime.Sub(trace.StartTime)
+	if data.Service != "" {
+		trace.Services[data.Service] = true
+		trace.ServiceCount = len(trace.Services)
+	}
+
+	var span *Span
+	for _, s := range trace.Spans {
+		if s.SpanID == data.SpanID {
+			span = s
+			break
+		}
+	}
+	if span == nil {
+		span = &Span{
+			SpanID:  data.SpanID,
+			TraceID: data.TraceID,
+			Status:  "ok",
+			Logs:    make([]models.Log, 0),
+		}
+		trace.Spans = append(trace.Spans, span)
+		trace.SpanCount = len(trace.Spans)
+	}
+
+	span.Reported = true
+	span.ParentID = data.ParentID
+	span.StartTime = data.StartTime
+	span.EndTime = data.EndTime
+	span.Duration = data.EndTime.Sub(data.StartTime)
+	if data.Service != "" {
+		span.Service = data.Service
+	}
+	if data.Operation != "" {
+		span.Operation = data.Operation
+	}
+	if data.Failed && span.Status != "error" {
+		// A failed span counts as an error of the trace, as error logs do
+		span.Status = "error"
+		trace.ErrorCount++
+	}
+
+	// The attributes of the span may be those of its first log, shared
+	attributes := make(map[string]interface{}, len(span.Attributes)+len(data.Attributes))
+	for key, value := range span.Attributes {
+		attributes[key] = value
+	}
+	for key, value := range data.Attributes {
+		attributes[key] = value
+	}
+	span.Attributes = attributes
+
+	if span.ParentID == "" && (trace.RootSpan == nil || !trace.RootSpan.Reported) {
+		trace.RootSpan = span
+	}
+}
@@ -0,0 +1,198 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// ErrTraceNotFound is returned for traces neither cached nor stored
+var ErrTraceNotFound = errors.New("trace not found")
+
+// TraceFilter selects traces by their summary
+type TraceFilter struct {
+	Service     string // traces going through the service
+	MinDuration time.Duration
+	MaxDuration time.Duration // no maximum when 0
+	MinErrors   int
+	// Start and End bound when the traces started; either may be zero
+	Start time.Time
+	End   time.Time
+	Limit int // unlimited when not positive
+}
+
+// SpanNode is a span of a trace with the spans it called
+type SpanNode struct {
+	SpanID     string                 `json:"span_id"`
+	ParentID   string                 `json:"parent_id,omitempty"`
+	Service    string                 `json:"service"`
+	Operation  string                 `json:"operation"`
+	StartTime  time.Time              `json:"start_time"`
+	EndTime    time.Time              `json:"end_time"`
+	DurationMs float64                `json:"duration_ms"`
+	Status     string                 `json:"status"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Logs       []models.Log           `json:"logs"`
+	Children   []*SpanNode            `json:"children,omitempty"`
+	// Reported spans were timed by the service rather than from logs
+	Reported bool `json:"reported,omitempty"`
+}
+
+// TraceTree is a trace with its spans nested under the ones that called
+// them
+type TraceTree struct {
+	TraceSummary
+	// Spans are the root spans, and the spans whose parent logged nothing,
+	// in the order they started
+	Spans []*SpanNode `json:"spans"`
+}
+
+// SearchTraces returns the summaries of the traces matching filter, the
+// latest started first, and how many matched before the limit. Traces that
+// left the cache are searched in storage; the cached ones, more recent,
+// take precedence over what was stored of them.
+func (tm *TraceManager) SearchTraces(filter TraceFilter) ([]*TraceSummary, int, error) {
+	tm.mu.RLock()
+	summaries := make([]*TraceSummary, 0)
+	unsaved := 0
+	for _, trace := range tm.traceCache {
+		if !filter.matches(trace) {
+			continue
+		}
+		summaries = append(summaries, trace.summary())
+		if !trace.persisted {
+			unsaved++
+		}
+	}
+	storage := tm.storage
+	tm.mu.RUnlock()
+
+	total := len(summaries)
+	if storage != nil {
+		stored, storedTotal, err := storage.Search(filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		tm.mu.RLock()
+		for _, summary := range stored {
+			if _, cached := tm.traceCache[summary.TraceID]; !cached {
+				summaries = append(summaries, summary)
+			}
+		}
+		tm.mu.RUnlock()
+		total = storedTotal + unsaved
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if !summaries[i].StartTime.Equal(summaries[j].StartTime) {
+			return summaries[i].StartTime.After(summaries[j].StartTime)
+		}
+		return summaries[i].TraceID < summaries[j].TraceID
+	})
+	if filter.Limit > 0 && len(summaries) > filter.Limit {
+		summaries = summaries[:filter.Limit]
+	}
+	return summaries, total, nil
+}
+
+// matches tells whether a cached trace passes the filter. Callers hold the
+// lock of the manager.
+func (filter TraceFilter) matches(trace *Trace) bool {
+	if filter.Service != "" && !trace.Services[filter.Service] {
+		return false
+	}
+	if trace.Duration < filter.MinDuration || (filter.MaxDuration > 0 && trace.Duration > filter.MaxDuration) {
+		return false
+	}
+	if trace.ErrorCount < filter.MinErrors {
+		return false
+	}
+	if (!filter.Start.IsZero() && trace.StartTime.Before(filter.Start)) ||
+		(!filter.End.IsZero() && trace.StartTime.After(filter.End)) {
+		return false
+	}
+	return true
+}
+
+// GetTraceTree returns a copy of a trace with its spans as a tree, from
+// the cache or else from storage
+func (tm *TraceManager) GetTraceTree(traceID string) (*TraceTree, error) {
+	tm.mu.RLock()
+	trace, ok := tm.traceCache[traceID]
+	if ok {
+		tree := trace.tree(true)
+		tm.mu.RUnlock()
+		return tree, nil
+	}
+	storage := tm.storage
+	tm.mu.RUnlock()
+
+	if storage != nil {
+		tree, err := storage.Get(traceID)
+		if err != nil {
+			return nil, err
+		}
+		if tree != nil {
+			return tree, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTraceNotFound, traceID)
+}
+
+// tree copies the trace with its spans as a tree, with or without their
+// logs. Callers hold the lock of the manager.
+func (t *Trace) tree(withLogs bool) *TraceTree {
+	nodes := make([]*SpanNode, 0, len(t.Spans))
+	for _, span := range t.Spans {
+		node := &SpanNode{
+			SpanID:     span.SpanID,
+			ParentID:   span.ParentID,
+			Service:    span.Service,
+			Operation:  span.Operation,
+			StartTime:  span.StartTime,
+			EndTime:    span.EndTime,
+			DurationMs: float64(span.Duration.Microseconds()) / 1000,
+			Status:     span.Status,
+			Attributes: span.Attributes,
+			Logs:       []models.Log{},
+			Reported:   span.Reported,
+		}
+		if withLogs {
+			node.Logs = append(node.Logs, span.Logs...)
+		}
+		nodes = append(nodes, node)
+	}
+	return &TraceTree{TraceSummary: *t.summary(), Spans: nestSpans(nodes)}
+}
+
+// nestSpans puts spans under their parents and returns the roots, spans
+// ordered by start time at every level
+func nestSpans(spans []*SpanNode) []*SpanNode {
+	byID := make(map[string]*SpanNode, len(spans))
+	for _, span := range spans {
+		byID[span.SpanID] = span
+	}
+
+	roots := []*SpanNode{}
+	for _, span := range spans {
+		if parent, ok := byID[span.ParentID]; ok && span.ParentID != span.SpanID {
+			parent.Children = append(parent.Children, span)
+		} else {
+			roots = append(roots, span)
+		}
+	}
+	for _, span := range spans {
+		sortSpans(span.Children)
+	}
+	sortSpans(roots)
+	return roots
+}
+
+func sortSpans(spans []*SpanNode) {
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].StartTime.Before(spans[j].StartTime)
+	})
+}
@@ -0,0 +1,416 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/database/chsql"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// SQLExecutor is the subset of the database used to persist traces
+type SQLExecutor interface {
+	Execute(ctx context.Context, query string) error
+	ExecuteSQL(sql string) ([]map[string]interface{}, error)
+}
+
+// TraceStorage keeps the summaries and spans of traces beyond the cache
+type TraceStorage interface {
+	// Save writes the latest state of traces, their spans without logs
+	Save(traces []*TraceTree) error
+	Search(filter TraceFilter) ([]*TraceSummary, int, error)
+	Summaries(traceIDs []string) ([]*TraceSummary, error)
+	// Get returns a trace with the logs of its spans, nil when unknown
+	Get(traceID string) (*TraceTree, error)
+}
+
+const (
+	// clickHouseTimeFormat is how DateTime64(3) values are written and read
+	clickHouseTimeFormat = "2006-01-02 15:04:05.000"
+	// maxStoredTraceLogs bounds the logs read back for a stored trace
+	maxStoredTraceLogs = 5000
+)
+
+// ClickHouseTraceStorage keeps a row per trace and one per span, replaced
+// whenever the trace changes. Logs are not copied: they are read back from
+// the logs table by trace ID.
+type ClickHouseTraceStorage struct {
+	db          SQLExecutor
+	tracesTable string
+	spansTable  string
+	logsTable   string
+}
+
+// NewClickHouseTraceStorage creates the trace tables if needed, keeping
+// traces for retentionDays after they started
+func NewClickHouseTraceStorage(db SQLExecutor, retentionDays int) (*ClickHouseTraceStorage, error) {
+	s := &ClickHouseTraceStorage{
+		db:          db,
+		tracesTable: "trace_summaries",
+		spansTable:  "trace_spans",
+		logsTable:   "logs",
+	}
+
+	ctx := context.Background()
+	if err := db.Execute(ctx, fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		trace_id String,
+		start_time DateTime64(3),
+		end_time DateTime64(3),
+		duration_ms Float64,
+		services Array(LowCardinality(String)),
+		failed_services Array(LowCardinality(String)),
+		span_count UInt32,
+		error_count UInt32,
+		root_service LowCardinality(String),
+		root_operation String,
+		updated_at DateTime64(3)
+	) ENGINE = ReplacingMergeTree(updated_at)
+	PARTITION BY toYYYYMMDD(start_time)
+	ORDER BY trace_id
+	TTL toDateTime(start_time) + INTERVAL %d DAY
+	`, s.tracesTable, retentionDays)); err != nil {
+		return nil, fmt.Errorf("failed to create trace summaries table: %w", err)
+	}
+	if err := db.Execute(ctx, fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		trace_id String,
+		span_id String,
+		parent_id String,
+		service LowCardinality(String),
+		operation String,
+		start_time DateTime64(3),
+		end_time DateTime64(3),
+		duration_ms Float64,
+		status LowCardinality(String),
+		attributes String,
+		updated_at DateTime64(3),
+		INDEX idx_trace_id trace_id TYPE bloom_filter GRANULARITY 1
+	) ENGINE = ReplacingMergeTree(updated_at)
+	PARTITION BY toYYYYMMDD(start_time)
+	ORDER BY (trace_id, span_id)
+	TTL toDateTime(start_time) + INTERVAL %d DAY
+	`, s.spansTable, retentionDays)); err != nil {
+		return nil, fmt.Errorf("failed to create trace spans table: %w", err)
+	}
+	if err := db.Execute(ctx, fmt.Sprintf(
+		"ALTER TABLE %s ADD COLUMN IF NOT EXISTS reported UInt8 AFTER attributes", s.spansTable)); err != nil {
+		return nil, fmt.Errorf("failed to add reported column to trace spans table: %w", err)
+	}
+	return s, nil
+}
+
+// Save writes the current summaries and spans of the given traces
+func (s *ClickHouseTraceStorage) Save(traces []*TraceTree) error {
+	if len(traces) == 0 {
+		return nil
+	}
+	now := quoteTime(time.Now())
+
+	summaries := make([]string, 0, len(traces))
+	var spans []string
+	for _, trace := range traces {
+		summaries = append(summaries, fmt.Sprintf("(%s, %s, %s, %g, %s, %s, %d, %d, %s, %s, %s)",
+			chsql.Quote(trace.TraceID),
+			quoteTime(trace.StartTime),
+			quoteTime(trace.EndTime),
+			trace.DurationMs,
+			quoteArray(trace.Services),
+			quoteArray(trace.FailedServices),
+			trace.SpanCount,
+			trace.ErrorCount,
+			chsql.Quote(trace.RootService),
+			chsql.Quote(trace.RootOperation),
+			now,
+		))
+
+		var addSpans func(nodes []*SpanNode) error
+		addSpans = func(nodes []*SpanNode) error {
+			for _, span := range nodes {
+				attributes, err := json.Marshal(span.Attributes)
+				if err != nil {
+					return fmt.Errorf("failed to encode span attributes: %w", err)
+				}
+				reported := 0
+				if span.Reported {
+					reported = 1
+				}
+				spans = append(spans, fmt.Sprintf("(%s, %s, %s, %s, %s, %s, %s, %g, %s, %s, %d, %s)",
+					chsql.Quote(trace.TraceID),
+					chsql.Quote(span.SpanID),
+					chsql.Quote(span.ParentID),
+					chsql.Quote(span.Service),
+					chsql.Quote(span.Operation),
+					quoteTime(span.StartTime),
+					quoteTime(span.EndTime),
+					span.DurationMs,
+					chsql.Quote(span.Status),
+					chsql.Quote(string(attributes)),
+					reported,
+					now,
+				))
+				if err := addSpans(span.Children); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if err := addSpans(trace.Spans); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	if len(spans) > 0 {
+		query := fmt.Sprintf("INSERT INTO %s (trace_id, span_id, parent_id, service, operation, start_time, end_time, duration_ms, status, attributes, reported, updated_at) VALUES %s",
+			s.spansTable, strings.Join(spans, ", "))
+		if err := s.db.Execute(ctx, query); err != nil {
+			return fmt.Errorf("failed to save trace spans: %w", err)
+		}
+	}
+	// Summaries go last, so a trace found by a search has its spans
+	query := fmt.Sprintf("INSERT INTO %s (trace_id, start_time, end_time, duration_ms, services, failed_services, span_count, error_count, root_service, root_operation, updated_at) VALUES %s",
+		s.tracesTable, strings.Join(summaries, ", "))
+	if err := s.db.Execute(ctx, query); err != nil {
+		return fmt.Errorf("failed to save trace summaries: %w", err)
+	}
+	return nil
+}
+
+// latestSummaries selects the latest saved state of every trace. Its
+// aliases differ from the column names, which ClickHouse would otherwise
+// substitute into the WHERE clause.
+const latestSummaries = `
+	SELECT trace_id,
+		argMax(start_time, updated_at) AS trace_start,
+		argMax(end_time, updated_at) AS trace_end,
+		argMax(duration_ms, updated_at) AS trace_duration_ms,
+		argMax(services, updated_at) AS trace_services,
+		argMax(failed_services, updated_at) AS trace_failed_services,
+		argMax(span_count, updated_at) AS trace_span_count,
+		argMax(error_count, updated_at) AS trace_error_count,
+		argMax(root_service, updated_at) AS trace_root_service,
+		argMax(root_operation, updated_at) AS trace_root_operation
+	FROM %s
+	WHERE %s
+	GROUP BY trace_id`
+
+// Search returns the stored traces matching filter, the latest started
+// first, and how many matched before the limit
+func (s *ClickHouseTraceStorage) Search(filter TraceFilter) ([]*TraceSummary, int, error) {
+	// The start of a trace may move back while it is cached, so the rows
+	// are only narrowed down loosely before the latest ones are filtered
+	where := []string{"1"}
+	if !filter.Start.IsZero() {
+		where = append(where, fmt.Sprintf("start_time >= %s", quoteTime(filter.Start.Add(-24*time.Hour))))
+	}
+	if !filter.End.IsZero() {
+		where = append(where, fmt.Sprintf("start_time <= %s", quoteTime(filter.End.Add(24*time.Hour))))
+	}
+
+	var having []string
+	if filter.Service != "" {
+		having = append(having, fmt.Sprintf("has(trace_services, %s)", chsql.Quote(filter.Service)))
+	}
+	if filter.MinDuration > 0 {
+		having = append(having, fmt.Sprintf("trace_duration_ms >= %g", durationMs(filter.MinDuration)))
+	}
+	if filter.MaxDuration > 0 {
+		having = append(having, fmt.Sprintf("trace_duration_ms <= %g", durationMs(filter.MaxDuration)))
+	}
+	if filter.MinErrors > 0 {
+		having = append(having, fmt.Sprintf("trace_error_count >= %d", filter.MinErrors))
+	}
+	if !filter.Start.IsZero() {
+		having = append(having, fmt.Sprintf("trace_start >= %s", quoteTime(filter.Start)))
+	}
+	if !filter.End.IsZero() {
+		having = append(having, fmt.Sprintf("trace_start <= %s", quoteTime(filter.End)))
+	}
+
+	matching := fmt.Sprintf(latestSummaries, s.tracesTable, strings.Join(where, " AND "))
+	if len(having) > 0 {
+		matching += "\n\tHAVING " + strings.Join(having, " AND ")
+	}
+
+	query := matching + "\n\tORDER BY trace_start DESC, trace_id"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf("\n\tLIMIT %d", filter.Limit)
+	}
+	rows, err := s.db.ExecuteSQL(query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search traces: %w", err)
+	}
+	summaries := readSummaries(rows)
+
+	total := len(summaries)
+	if filter.Limit > 0 && total == filter.Limit {
+		rows, err := s.db.ExecuteSQL(fmt.Sprintf("SELECT count() AS total FROM (%s)", matching))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to count traces: %w", err)
+		}
+		if len(rows) > 0 {
+			total = int(chsql.ToInt64(rows[0]["total"]))
+		}
+	}
+	return summaries, total, nil
+}
+
+// Summaries returns the stored summaries of the given traces
+func (s *ClickHouseTraceStorage) Summaries(traceIDs []string) ([]*TraceSummary, error) {
+	if len(traceIDs) == 0 {
+		return nil, nil
+	}
+	quoted := make([]string, len(traceIDs))
+	for i, traceID := range traceIDs {
+		quoted[i] = chsql.Quote(traceID)
+	}
+	rows, err := s.db.ExecuteSQL(fmt.Sprintf(latestSummaries, s.tracesTable,
+		fmt.Sprintf("trace_id IN (%s)", strings.Join(quoted, ", "))))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trace summaries: %w", err)
+	}
+	return readSummaries(rows), nil
+}
+
+// Get returns a stored trace, its spans with the logs of the logs table
+func (s *ClickHouseTraceStorage) Get(traceID string) (*TraceTree, error) {
+	summaries, err := s.Summaries([]string{traceID})
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return nil, nil
+	}
+	tree := &TraceTree{TraceSummary: *summaries[0]}
+
+	rows, err := s.db.ExecuteSQL(fmt.Sprintf(`
+		SELECT span_id,
+			argMax(parent_id, updated_at) AS span_parent_id,
+			argMax(service, updated_at) AS span_service,
+			argMax(operation, updated_at) AS span_operation,
+			argMax(start_time, updated_at) AS span_start,
+			argMax(end_time, updated_at) AS span_end,
+			argMax(duration_ms, updated_at) AS span_duration_ms,
+			argMax(status, updated_at) AS span_status,
+			argMax(attributes, updated_at) AS span_attributes,
+			argMax(reported, updated_at) AS span_reported
+		FROM %s
+		WHERE trace_id = %s
+		GROUP BY span_id
+	`, s.spansTable, chsql.Quote(traceID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trace spans: %w", err)
+	}
+	nodes := make([]*SpanNode, 0, len(rows))
+	byID := make(map[string]*SpanNode, len(rows))
+	for _, row := range rows {
+		span := &SpanNode{Logs: []models.Log{}}
+		span.SpanID, _ = row["span_id"].(string)
+		span.ParentID, _ = row["span_parent_id"].(string)
+		span.Service, _ = row["span_service"].(string)
+		span.Operation, _ = row["span_operation"].(string)
+		span.StartTime = parseTime(row["span_start"])
+		span.EndTime = parseTime(row["span_end"])
+		span.DurationMs, _ = row["span_duration_ms"].(float64)
+		span.Status, _ = row["span_status"].(string)
+		span.Reported = chsql.ToInt64(row["span_reported"]) == 1
+		if attributes, ok := row["span_attributes"].(string); ok && attributes != "" && attributes != "null" {
+			json.Unmarshal([]byte(attributes), &span.Attributes)
+		}
+		nodes = append(nodes, span)
+		byID[span.SpanID] = span
+	}
+
+	// The logs table is partitioned by day and sorted by service, so the
+	// time range of the trace keeps the scan short
+	rows, err = s.db.ExecuteSQL(fmt.Sprintf(`
+		SELECT timestamp, level, message, service, trace_id, span_id, attributes
+		FROM %s
+		WHERE trace_id = %s AND timestamp >= %s AND timestamp <= %s
+		ORDER BY timestamp
+		LIMIT %d
+	`, s.logsTable, chsql.Quote(traceID),
+		quoteTime(tree.StartTime.Add(-time.Minute)), quoteTime(tree.EndTime.Add(time.Minute)),
+		maxStoredTraceLogs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trace logs: %w", err)
+	}
+	for _, row := range rows {
+		entry := models.Log{Timestamp: parseTime(row["timestamp"])}
+		entry.Level, _ = row["level"].(string)
+		entry.Message, _ = row["message"].(string)
+		entry.Service, _ = row["service"].(string)
+		entry.TraceID, _ = row["trace_id"].(string)
+		entry.SpanID, _ = row["span_id"].(string)
+		if attributes, ok := row["attributes"].(map[string]interface{}); ok && len(attributes) > 0 {
+			entry.Attributes = attributes
+		}
+		if span, ok := byID[entry.SpanID]; ok {
+			span.Logs = append(span.Logs, entry)
+		}
+	}
+
+	tree.Spans = nestSpans(nodes)
+	return tree, nil
+}
+
+// readSummaries reads the rows selected by latestSummaries
+func readSummaries(rows []map[string]interface{}) []*TraceSummary {
+	summaries := make([]*TraceSummary, 0, len(rows))
+	for _, row := range rows {
+		summary := &TraceSummary{
+			StartTime:      parseTime(row["trace_start"]),
+			EndTime:        parseTime(row["trace_end"]),
+			Services:       toStrings(row["trace_services"]),
+			FailedServices: toStrings(row["trace_failed_services"]),
+			SpanCount:      int(chsql.ToInt64(row["trace_span_count"])),
+			ErrorCount:     int(chsql.ToInt64(row["trace_error_count"])),
+		}
+		summary.TraceID, _ = row["trace_id"].(string)
+		summary.DurationMs, _ = row["trace_duration_ms"].(float64)
+		summary.RootService, _ = row["trace_root_service"].(string)
+		summary.RootOperation, _ = row["trace_root_operation"].(string)
+		if len(summary.FailedServices) == 0 {
+			summary.FailedServices = nil
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func parseTime(value interface{}) time.Time {
+	s, _ := value.(string)
+	t, _ := time.Parse(clickHouseTimeFormat, s)
+	return t
+}
+
+func toStrings(value interface{}) []string {
+	values, _ := value.([]interface{})
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+func quoteTime(t time.Time) string {
+	return chsql.Quote(t.UTC().Format(clickHouseTimeFormat))
+}
+
+func quoteArray(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = chsql.Quote(v)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// TraceSummary outlines a trace without its spans and their logs: how long
+// it took and which services it went through
+type TraceSummary struct {
+	TraceID   string    `json:"trace_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	// DurationMs spans from the first to the last log or reported span of
+	// the trace
+	DurationMs float64  `json:"duration_ms"`
+	Services   []string `json:"services"`
+	// FailedServices are the services with a span in error
+	FailedServices []string `json:"failed_services,omitempty"`
+	SpanCount      int      `json:"span_count"`
+	ErrorCount     int      `json:"error_count"`
+	RootService    string   `json:"root_service,omitempty"`
+	RootOperation  string   `json:"root_operation,omitempty"`
+}
+
+// GetTraceSummary returns the summary of a trace, from the cache or else
+// from storage
+func (tm *TraceManager) GetTraceSummary(traceID string) (*TraceSummary, bool) {
+	tm.mu.RLock()
+	trace, ok := tm.traceCache[traceID]
+	if ok {
+		summary := trace.summary()
+		tm.mu.RUnlock()
+		return summary, true
+	}
+	storage := tm.storage
+	tm.mu.RUnlock()
+
+	if storage == nil {
+		return nil, false
+	}
+	summaries, err := storage.Summaries([]string{traceID})
+	if err != nil {
+		log.Error().Err(err).Str("trace_id", traceID).Msg("Failed to load trace summary")
+		return nil, false
+	}
+	if len(summaries) == 0 {
+		return nil, false
+	}
+	return summaries[0], true
+}
+
+// summary outlines the trace. Callers hold the lock of the manager.
+func (t *Trace) summary() *TraceSummary {
+	s := &TraceSummary{
+		TraceID:    t.TraceID,
+		StartTime:  t.StartTime,
+		EndTime:    t.EndTime,
+		DurationMs: float64(t.Duration.Microseconds()) / 1000,
+		Services:   make([]string, 0, len(t.Services)),
+		SpanCount:  t.SpanCount,
+		ErrorCount: t.ErrorCount,
+	}
+	for service := range t.Services {
+		s.Services = append(s.Services, service)
+	}
+	sort.Strings(s.Services)
+
+	failed := make(map[string]bool)
+	for _, span := range t.Spans {
+		if span.Status == "error" && span.Service != "" && !failed[span.Service] {
+			failed[span.Service] = true
+			s.FailedServices = append(s.FailedServices, span.Service)
+		}
+	}
+	sort.Strings(s.FailedServices)
+
+	if t.RootSpan != nil {
+		s.RootService = t.RootSpan.Service
+		s.RootOperation = t.RootSpan.Operation
+	}
+	return s
+}
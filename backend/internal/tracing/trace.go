@@ -16,6 +16,8 @@ type TraceManager struct {
 	tracePatterns   []TracePattern
 	traceCache      map[string]*Trace
 	cacheExpiration time.Duration
+	storage         TraceStorage
+	dirty           map[string]bool // traces changed since the last flush
 }
 
 // TracePattern defines patterns for extracting trace IDs from logs
@@ -40,6 +42,7 @@ type Trace struct {
 	Spans        []*Span           `json:"spans"`
 	RootSpan     *Span             `json:"root_span"`
 	LastUpdated  time.Time         `json:"last_updated"`
+	persisted    bool              // flushed to storage at least once
 }
 
 // Span represents a span within a trace
@@ -56,6 +59,7 @@ type Span struct {
 	Logs        []models.Log        `json:"logs"`
 	Children    []*Span             `json:"children,omitempty"`
 	Attributes  map[string]interface{} `json:"attributes,omitempty"`
+	Reported    bool                `json:"reported,omitempty"` // timed by the service, not from logs
 }
 
 // NewTraceManager creates a new trace manager
@@ -181,6 +185,10 @@ func (tm *TraceManager) ProcessLog(log *models.Log) {
 		tm.traceCache[traceID] = trace
 	}
 
+	if tm.storage != nil {
+		tm.dirty[traceID] = true
+	}
+
 	// Update trace metadata
 	trace.LastUpdated = time.Now()
 	if log.Timestamp.Before(trace.StartTime) {
@@ -257,13 +265,16 @@ func (tm *TraceManager) findOrCreateSpan(trace *Trace, spanID, parentID string,
 
 // updateSpan updates span information
 func (tm *TraceManager) updateSpan(span *Span, log *models.Log) {
-	if log.Timestamp.Before(span.StartTime) {
-		span.StartTime = log.Timestamp
-	}
-	if log.Timestamp.After(span.EndTime) {
-		span.EndTime = log.Timestamp
+	// Reported spans keep the timings of the service
+	if !span.Reported {
+		if log.Timestamp.Before(span.StartTime) {
+			span.StartTime = log.Timestamp
+		}
+		if log.Timestamp.After(span.EndTime) {
+			span.EndTime = log.Timestamp
+		}
+		span.Duration = span.EndTime.Sub(span.StartTime)
 	}
-	span.Duration = span.EndTime.Sub(span.StartTime)
 
 	// Update status if error
 	if strings.ToLower(log.Level) == "error" || strings.ToLower(log.Level) == "fatal" {
@@ -315,8 +326,8 @@ func (tm *TraceManager) getSpanStatus(level string) string {
 
 // GetTrace retrieves a trace by ID
 func (tm *TraceManager) GetTrace(traceID string) (*Trace, error) {
-	tm.mu.RLock()
-	defer tm.mu.RUnlock()
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 
 	trace, exists := tm.traceCache[traceID]
 	if !exists {
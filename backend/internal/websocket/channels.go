@@ -0,0 +1,479 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+const (
+	// maxChannelHighlights bounds the highlight rules of a channel
+	maxChannelHighlights = 20
+	// maxHighlightsPerRule bounds the matches of one rule in one log
+	maxHighlightsPerRule = 50
+)
+
+var channelNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Channel is a named tail defined on the server, such as payments-errors:
+// the filter of the logs its subscribers receive and the patterns
+// highlighted in them
+type Channel struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Filter      TailRequest     `json:"filter"` // since is not used
+	Highlights  []HighlightRule `json:"highlights,omitempty"`
+	CreatedBy   string          `json:"created_by,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// HighlightRule marks the matches of a regular expression in a field of
+// the logs of a channel, the message by default
+type HighlightRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Field   string `json:"field,omitempty"`
+	Color   string `json:"color,omitempty"` // a hint for clients
+}
+
+// JoinChannelRequest is the data of a join_channel message. With Since,
+// the buffered logs of the channel after that cursor are sent first.
+type JoinChannelRequest struct {
+	Channel string  `json:"channel"`
+	Since   *uint64 `json:"since,omitempty"`
+}
+
+// ChannelStorage persists channels
+type ChannelStorage interface {
+	Save(channel *Channel) error
+	LoadAll() ([]*Channel, error)
+	Delete(name string) error
+}
+
+// FileChannelStorage persists channels as a single JSON document on disk
+type FileChannelStorage struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileChannelStorage creates a file-backed channel storage
+func NewFileChannelStorage(path string) *FileChannelStorage {
+	return &FileChannelStorage{path: path}
+}
+
+// Save writes or replaces a channel in the file
+func (s *FileChannelStorage) Save(channel *Channel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.read()
+	if err != nil {
+		return err
+	}
+	all[channel.Name] = channel
+	return s.write(all)
+}
+
+// LoadAll reads every channel from the file
+func (s *FileChannelStorage) LoadAll() ([]*Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]*Channel, 0, len(all))
+	for _, channel := range all {
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
+
+// Delete removes a channel from the file
+func (s *FileChannelStorage) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.read()
+	if err != nil {
+		return err
+	}
+	delete(all, name)
+	return s.write(all)
+}
+
+func (s *FileChannelStorage) read() (map[string]*Channel, error) {
+	all := make(map[string]*Channel)
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return all, nil
+		}
+		return nil, fmt.Errorf("failed to read channels file: %w", err)
+	}
+	if len(data) == 0 {
+		return all, nil
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to decode channels file: %w", err)
+	}
+	return all, nil
+}
+
+func (s *FileChannelStorage) write(all map[string]*Channel) error {
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode channels: %w", err)
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create channels directory: %w", err)
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write channels file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// compiledChannel is a channel ready to filter and highlight logs
+type compiledChannel struct {
+	*Channel
+	tail       *tail
+	highlights []compiledHighlight
+
+	// The log message of the latest log, shared by the channel's clients
+	mu      sync.Mutex
+	lastSeq uint64
+	last    []byte
+}
+
+type compiledHighlight struct {
+	HighlightRule
+	pattern *regexp.Regexp
+}
+
+// ChannelRegistry holds the channels clients can join
+type ChannelRegistry struct {
+	mu       sync.RWMutex
+	channels map[string]*compiledChannel
+	storage  ChannelStorage
+}
+
+// NewChannelRegistry creates an empty registry that keeps channels in
+// memory until SetStorage is called
+func NewChannelRegistry() *ChannelRegistry {
+	return &ChannelRegistry{channels: make(map[string]*compiledChannel)}
+}
+
+// SetStorage persists channels in storage and loads those it holds.
+// Channels that no longer compile are skipped.
+func (r *ChannelRegistry) SetStorage(storage ChannelStorage) error {
+	channels, err := storage.LoadAll()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.storage = storage
+	for _, channel := range channels {
+		compiled, err := compileChannel(channel)
+		if err != nil {
+			log.Warn().Err(err).Str("channel", channel.Name).Msg("Skipping invalid tail channel")
+			continue
+		}
+		r.channels[channel.Name] = compiled
+	}
+	return nil
+}
+
+// List returns the channels by name
+func (r *ChannelRegistry) List() []*Channel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	channels := make([]*Channel, 0, len(r.channels))
+	for _, compiled := range r.channels {
+		channels = append(channels, compiled.Channel)
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].Name < channels[j].Name
+	})
+	return channels
+}
+
+// Get returns a channel by name
+func (r *ChannelRegistry) Get(name string) (*Channel, error) {
+	if compiled := r.lookup(name); compiled != nil {
+		return compiled.Channel, nil
+	}
+	return nil, fmt.Errorf("channel not found: %s", name)
+}
+
+// Create adds a new channel
+func (r *ChannelRegistry) Create(channel *Channel) error {
+	compiled, err := compileChannel(channel)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.channels[channel.Name]; exists {
+		return fmt.Errorf("channel already exists: %s", channel.Name)
+	}
+	channel.CreatedAt = time.Now()
+	channel.UpdatedAt = channel.CreatedAt
+	return r.store(compiled)
+}
+
+// Update replaces an existing channel. Its clients get the new filter and
+// highlights with the next log.
+func (r *ChannelRegistry) Update(name string, channel *Channel) error {
+	channel.Name = name
+	compiled, err := compileChannel(channel)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, exists := r.channels[name]
+	if !exists {
+		return fmt.Errorf("channel not found: %s", name)
+	}
+	channel.CreatedBy = existing.CreatedBy
+	channel.CreatedAt = existing.CreatedAt
+	channel.UpdatedAt = time.Now()
+	return r.store(compiled)
+}
+
+// Delete removes a channel. Its clients stop receiving logs.
+func (r *ChannelRegistry) Delete(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.channels[name]; !exists {
+		return fmt.Errorf("channel not found: %s", name)
+	}
+	if r.storage != nil {
+		if err := r.storage.Delete(name); err != nil {
+			return fmt.Errorf("failed to delete channel: %w", err)
+		}
+	}
+	delete(r.channels, name)
+	return nil
+}
+
+// store saves a channel and makes it current. Callers hold the lock.
+func (r *ChannelRegistry) store(compiled *compiledChannel) error {
+	if r.storage != nil {
+		if err := r.storage.Save(compiled.Channel); err != nil {
+			return fmt.Errorf("failed to save channel: %w", err)
+		}
+	}
+	r.channels[compiled.Name] = compiled
+	return nil
+}
+
+func (r *ChannelRegistry) lookup(name string) *compiledChannel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.channels[name]
+}
+
+func compileChannel(channel *Channel) (*compiledChannel, error) {
+	if !channelNamePattern.MatchString(channel.Name) {
+		return nil, fmt.Errorf("invalid channel name %q: use lowercase letters, digits, - and _", channel.Name)
+	}
+	channel.Filter.Since = nil
+	t, err := newTail(channel.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid channel filter: %w", err)
+	}
+	if len(channel.Highlights) > maxChannelHighlights {
+		return nil, fmt.Errorf("at most %d highlight rules per channel", maxChannelHighlights)
+	}
+
+	compiled := &compiledChannel{Channel: channel, tail: t}
+	for _, rule := range channel.Highlights {
+		if rule.Name == "" || rule.Pattern == "" {
+			return nil, fmt.Errorf("highlight rules need a name and a pattern")
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of highlight %s: %w", rule.Name, err)
+		}
+		if rule.Field == "" {
+			rule.Field = "message"
+		}
+		compiled.highlights = append(compiled.highlights, compiledHighlight{HighlightRule: rule, pattern: pattern})
+	}
+	return compiled, nil
+}
+
+// message returns the log message of a log of the channel, with its
+// highlights
+func (ch *compiledChannel) message(entry replayEntry) ([]byte, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.last != nil && ch.lastSeq == entry.seq {
+		return ch.last, nil
+	}
+
+	message, err := encodeMessage(models.WebSocketMessage{
+		Type:       TypeLog,
+		Seq:        entry.seq,
+		Channel:    ch.Name,
+		Highlights: ch.highlight(entry.log),
+		Data:       entry.log,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ch.lastSeq, ch.last = entry.seq, message
+	return message, nil
+}
+
+// highlight finds the matches of the channel's rules in a log, ordered by
+// field and offset
+func (ch *compiledChannel) highlight(logEntry *models.Log) []models.LogHighlight {
+	var highlights []models.LogHighlight
+	for _, rule := range ch.highlights {
+		value, ok := fieldValue(logEntry, rule.Field)
+		if !ok || value == "" {
+			continue
+		}
+		offsets := newUTF16Offsets(value)
+		for _, match := range rule.pattern.FindAllStringIndex(value, maxHighlightsPerRule) {
+			if match[0] == match[1] {
+				continue
+			}
+			highlights = append(highlights, models.LogHighlight{
+				Rule:  rule.Name,
+				Field: rule.Field,
+				Start: offsets.at(match[0]),
+				End:   offsets.at(match[1]),
+				Color: rule.Color,
+			})
+		}
+	}
+	sort.SliceStable(highlights, func(i, j int) bool {
+		if highlights[i].Field != highlights[j].Field {
+			return highlights[i].Field < highlights[j].Field
+		}
+		return highlights[i].Start < highlights[j].Start
+	})
+	return highlights
+}
+
+// utf16Offsets converts byte offsets of a string, which increase from one
+// call to the next, to offsets in UTF-16 code units, as JavaScript indexes
+// strings
+type utf16Offsets struct {
+	s     string
+	bytes int
+	units int
+}
+
+func newUTF16Offsets(s string) *utf16Offsets {
+	return &utf16Offsets{s: s}
+}
+
+func (o *utf16Offsets) at(offset int) int {
+	if offset < o.bytes {
+		o.bytes, o.units = 0, 0
+	}
+	for o.bytes < offset {
+		r, size := utf8.DecodeRuneInString(o.s[o.bytes:])
+		o.bytes += size
+		o.units++
+		if r >= 0x10000 {
+			o.units++
+		}
+	}
+	return o.units
+}
+
+// Channels returns the registry of the channels clients can join
+func (h *Hub) Channels() *ChannelRegistry {
+	return h.channels
+}
+
+// handleJoinChannel makes the client receive the logs of a channel,
+// replacing its tail
+func (c *Client) handleJoinChannel(msg models.WebSocketMessage) {
+	var req JoinChannelRequest
+	if err := decodeMessageData(msg.Data, &req); err != nil || req.Channel == "" {
+		c.sendError("", "Joining a channel needs its name")
+		return
+	}
+	if c.hub.channels.lookup(req.Channel) == nil {
+		c.sendError(req.Channel, "Unknown channel")
+		return
+	}
+
+	c.subsMu.Lock()
+	c.channel = req.Channel
+	c.tail = nil
+	c.subsMu.Unlock()
+
+	c.sendStatus("joined", req.Channel)
+	log.Debug().Str("client_id", c.id).Str("channel", req.Channel).Msg("Client joined tail channel")
+
+	if req.Since != nil {
+		c.replay(*req.Since)
+	}
+}
+
+// handleLeaveChannel stops the logs of the client's channel, so it
+// receives every log again
+func (c *Client) handleLeaveChannel() {
+	c.subsMu.Lock()
+	channel := c.channel
+	c.channel = ""
+	c.subsMu.Unlock()
+	if channel == "" {
+		c.sendError("", "Not in a channel")
+		return
+	}
+	c.sendStatus("left", channel)
+}
+
+// joinedChannel returns the channel the client joined, if it still exists
+func (c *Client) joinedChannel() (*compiledChannel, bool) {
+	c.subsMu.Lock()
+	name := c.channel
+	c.subsMu.Unlock()
+	if name == "" {
+		return nil, false
+	}
+	return c.hub.channels.lookup(name), true
+}
+
+// logMessage returns the log message of a live log for the client, with
+// the highlights of its channel if it joined one
+func (c *Client) logMessage(entry replayEntry) []byte {
+	ch, _ := c.joinedChannel()
+	if ch == nil {
+		return entry.message
+	}
+	message, err := ch.message(entry)
+	if err != nil {
+		return entry.message
+	}
+	return message
+}
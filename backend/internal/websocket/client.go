@@ -1,15 +1,20 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/rs/zerolog/log"
 
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
 )
 
@@ -38,30 +43,124 @@ var upgrader = websocket.Upgrader{
 }
 
 type Client struct {
-	id       string
-	hub      *Hub
-	conn     *websocket.Conn
-	send     chan []byte
-	filters  []models.LogFilter
-	isPaused bool
+	id    string
+	hub   *Hub
+	conn  *websocket.Conn
+	queue *sendQueue
+	// Sent when the hub closes the connection, if not a plain close
+	closeMessage atomic.Pointer[[]byte]
+
+	// When and where from the client connected, and what was written to it
+	connectedAt  time.Time
+	remoteAddr   string
+	messagesSent atomic.Uint64
+	bytesSent    atomic.Uint64
+	filters      []models.LogFilter
+	isPaused     bool
+
+	// Live query subscriptions, run as user until ctx is cancelled
+	user   auth.User
+	ctx    context.Context
+	cancel context.CancelFunc
+	subs   map[string]*subscription
+	subsMu sync.Mutex // also guards watches
+	subsWG sync.WaitGroup
+
+	// Unwatch functions of watched dashboards, by dashboard ID, and the
+	// log_stream widgets of those dashboards live logs are appended to
+	watches map[string]func()
+	streams map[string]logStream
+
+	// The tail live logs are filtered by, nil for every log, or the name
+	// of the channel the client joined instead. Guarded by subsMu.
+	tail    *tail
+	channel string
+
+	// How often live stats are pushed, 0 when not subscribed, and when
+	// next. Guarded by subsMu.
+	liveStatsInterval time.Duration
+	liveStatsNext     time.Time
+
+	// The cursor the client resumes from when it connects, if any, and the
+	// time of the last log it received, to catch up from the database when
+	// the replay buffer no longer has the logs after it. Then the latest
+	// log its last replay covered.
+	since      *uint64
+	sinceTime  *time.Time
+	replayMu   sync.Mutex // also guards recording and missedLogs
+	replayed   uint64
+	missedLogs []*models.Log
+
+	// The recording the live logs sent are captured in, if any
+	recording *recording
+
+	// The protocol version the client speaks, and the request being
+	// handled, only used on the readPump goroutine
+	protocol atomic.Int32
+	request  models.WebSocketMessage
 }
 
 // HandleWebSocket handles WebSocket connections
 func HandleWebSocket(hub *Hub) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		conn, err := upgrader.Upgrade(w, r, nil)
+		conn, err := hub.upgrade(w, r)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to upgrade connection")
 			return
 		}
 
+		// A reconnecting client passes the seq of the last log it received
+		var since *uint64
+		if value := r.URL.Query().Get("since"); value != "" {
+			cursor, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				conn.Close()
+				return
+			}
+			since = &cursor
+		}
+		var sinceTime *time.Time
+		if value := r.URL.Query().Get("since_time"); value != "" {
+			at, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				conn.Close()
+				return
+			}
+			sinceTime = &at
+		}
+
+		// Clients may also pick the versioned protocol with their first request
+		protocol := 0
+		if r.URL.Query().Get("protocol") != "" {
+			if protocol, err = strconv.Atoi(r.URL.Query().Get("protocol")); err != nil || protocol < 0 || protocol > ProtocolVersion {
+				conn.Close()
+				return
+			}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
 		client := &Client{
-			id:       uuid.New().String(),
-			hub:      hub,
-			conn:     conn,
-			send:     make(chan []byte, 256),
-			filters:  []models.LogFilter{},
-			isPaused: false,
+			id:          uuid.New().String(),
+			hub:         hub,
+			conn:        conn,
+			queue:       newSendQueue(hub.delivery.SendQueueSize),
+			filters:     []models.LogFilter{},
+			isPaused:    false,
+			user:        auth.UserFromContext(r.Context()),
+			ctx:         ctx,
+			cancel:      cancel,
+			subs:        make(map[string]*subscription),
+			watches:     make(map[string]func()),
+			streams:     make(map[string]logStream),
+			since:       since,
+			sinceTime:   sinceTime,
+			connectedAt: time.Now(),
+			remoteAddr:  r.RemoteAddr,
+		}
+		client.protocol.Store(int32(protocol))
+		if since != nil {
+			// Read before the hub sends it anything, without holding it up
+			client.missedLogs = client.catchUp(*since)
 		}
 
 		client.hub.register <- client
@@ -75,6 +174,9 @@ func HandleWebSocket(hub *Hub) http.HandlerFunc {
 // readPump handles incoming messages from the WebSocket connection
 func (c *Client) readPump() {
 	defer func() {
+		c.closeSubscriptions()
+		c.closeWatches()
+		c.closeRecording()
 		c.hub.unregister <- c
 		c.conn.Close()
 	}()
@@ -99,23 +201,57 @@ func (c *Client) readPump() {
 		var msg models.WebSocketMessage
 		if err := json.Unmarshal(message, &msg); err != nil {
 			log.Error().Err(err).Msg("Failed to parse WebSocket message")
+			c.request = models.WebSocketMessage{}
+			if c.versioned() {
+				c.sendErrorCode(ErrorInvalidMessage, "", "Invalid message: "+err.Error())
+			}
+			continue
+		}
+		c.request = msg
+		if !c.accept(msg) {
 			continue
 		}
 
 		// Handle different message types
 		switch msg.Type {
-		case "filter":
+		case TypeFilter:
 			c.handleFilterMessage(msg)
-		case "pause":
+		case TypePause:
 			c.isPaused = true
 			c.sendStatus("paused", "Stream paused")
-		case "resume":
+		case TypeResume:
 			c.isPaused = false
 			c.sendStatus("resumed", "Stream resumed")
-		case "ping":
+		case TypePing:
 			c.sendStatus("pong", "")
+		case TypeStats:
+			c.handleStats()
+		case TypeSubscribe:
+			c.handleSubscribe(msg)
+		case TypeUnsubscribe:
+			c.handleUnsubscribe(msg)
+		case TypeWatchDashboard:
+			c.handleWatchDashboard(msg)
+		case TypeUnwatchDashboard:
+			c.handleUnwatchDashboard(msg)
+		case TypeTail:
+			c.handleTail(msg)
+		case TypeUntail:
+			c.handleUntail()
+		case TypeJoinChannel:
+			c.handleJoinChannel(msg)
+		case TypeLeaveChannel:
+			c.handleLeaveChannel()
+		case TypeRecord:
+			c.handleRecord(msg)
+		case TypeStopRecording:
+			c.handleStopRecording()
+		case TypeSubscribeLiveStats:
+			c.handleSubscribeLiveStats(msg)
+		case TypeUnsubscribeLiveStats:
+			c.handleUnsubscribeLiveStats()
 		default:
-			log.Warn().Str("type", msg.Type).Msg("Unknown message type")
+			c.handleUnknown(msg)
 		}
 	}
 }
@@ -130,28 +266,19 @@ func (c *Client) writePump() {
 
 	for {
 		select {
-		case message, ok := <-c.send:
+		case <-c.queue.ready:
+			batch, open := c.collectBatch()
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// The hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+			if err := c.writeBatch(batch); err != nil {
 				return
 			}
-
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			w.Write(message)
-
-			// Add queued messages to the current WebSocket message
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
+			if !open {
+				// The hub closed the queue
+				closeMessage := []byte{}
+				if message := c.closeMessage.Load(); message != nil {
+					closeMessage = *message
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, closeMessage)
 				return
 			}
 
@@ -175,13 +302,18 @@ func (c *Client) handleFilterMessage(msg models.WebSocketMessage) {
 
 // MatchesFilters checks if a log entry matches the client's filters
 func (c *Client) MatchesFilters(log *models.Log) bool {
+	return c.matchesAll(log, c.filters)
+}
+
+// matchesAll checks if a log entry matches every filter
+func (c *Client) matchesAll(log *models.Log, filters []models.LogFilter) bool {
 	// If no filters, all logs match
-	if len(c.filters) == 0 {
+	if len(filters) == 0 {
 		return true
 	}
 
 	// Check each filter
-	for _, filter := range c.filters {
+	for _, filter := range filters {
 		if !c.matchFilter(log, filter) {
 			return false
 		}
@@ -192,25 +324,12 @@ func (c *Client) MatchesFilters(log *models.Log) bool {
 
 // matchFilter checks if a log matches a single filter
 func (c *Client) matchFilter(log *models.Log, filter models.LogFilter) bool {
-	var fieldValue string
-
-	// Get field value
-	switch filter.Field {
-	case "level":
-		fieldValue = log.Level
-	case "service":
-		fieldValue = log.Service
-	case "message":
-		fieldValue = log.Message
-	case "trace_id":
-		fieldValue = log.TraceID
-	default:
-		// Check attributes
-		if val, ok := log.Attributes[filter.Field]; ok {
-			fieldValue = strings.ToLower(val.(string))
-		}
-	}
+	value, _ := fieldValue(log, filter.Field)
+	return matchValue(value, filter)
+}
 
+// matchValue checks a field value against a filter, ignoring case
+func matchValue(fieldValue string, filter models.LogFilter) bool {
 	fieldValue = strings.ToLower(fieldValue)
 	filterValue := strings.ToLower(filter.Value)
 
@@ -232,22 +351,3 @@ func (c *Client) matchFilter(log *models.Log, filter models.LogFilter) bool {
 		return false
 	}
 }
-
-// sendStatus sends a status message to the client
-func (c *Client) sendStatus(status, message string) {
-	msg := models.WebSocketMessage{
-		Type: "status",
-		Data: map[string]string{
-			"status":  status,
-			"message": message,
-		},
-	}
-
-	if msgBytes, err := json.Marshal(msg); err == nil {
-		select {
-		case c.send <- msgBytes:
-		default:
-			// Send buffer full
-		}
-	}
-}
\ No newline at end of file
@@ -0,0 +1,125 @@
+package websocket
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+)
+
+// ClientInfo describes a connected client
+type ClientInfo struct {
+	ClientID          string    `json:"client_id"`
+	UserID            string    `json:"user_id,omitempty"`
+	RemoteAddr        string    `json:"remote_addr,omitempty"`
+	ConnectedAt       time.Time `json:"connected_at"`
+	Protocol          int       `json:"protocol"`
+	Paused            bool      `json:"paused"`
+	Tailing           bool      `json:"tailing"`
+	Channel           string    `json:"channel,omitempty"` // the client joined
+	Subscriptions     int       `json:"subscriptions"`
+	WatchedDashboards int       `json:"watched_dashboards"`
+	LiveStats         bool      `json:"live_stats"`
+	Recording         string    `json:"recording,omitempty"` // ID of the recording in progress
+	SendQueue         int       `json:"send_queue"`          // messages waiting to be written
+	Slow              bool      `json:"slow"`
+	Dropped           uint64    `json:"dropped"` // oldest messages dropped from the full send queue
+	MessagesSent      uint64    `json:"messages_sent"`
+	BytesSent         uint64    `json:"bytes_sent"` // before compression
+}
+
+// ConnectionRates are the rates of the messages written to all clients
+// over the last minute, per second
+type ConnectionRates struct {
+	Messages float64 `json:"messages_per_second"`
+	Bytes    float64 `json:"bytes_per_second"`
+	Dropped  float64 `json:"dropped_per_second"`
+}
+
+// info describes the client
+func (c *Client) info() ClientInfo {
+	info := ClientInfo{
+		ClientID:     c.id,
+		UserID:       c.user.ID,
+		RemoteAddr:   c.remoteAddr,
+		ConnectedAt:  c.connectedAt,
+		Protocol:     int(c.protocol.Load()),
+		Dropped:      c.queue.dropped.Load(),
+		MessagesSent: c.messagesSent.Load(),
+		BytesSent:    c.bytesSent.Load(),
+	}
+
+	c.subsMu.Lock()
+	info.Paused = c.isPaused
+	info.Tailing = c.tail != nil
+	info.Channel = c.channel
+	info.Subscriptions = len(c.subs)
+	info.WatchedDashboards = len(c.watches)
+	info.LiveStats = c.liveStatsInterval > 0
+	c.subsMu.Unlock()
+
+	c.replayMu.Lock()
+	if c.recording != nil {
+		info.Recording = c.recording.info.ID
+	}
+	c.replayMu.Unlock()
+
+	var slowSince time.Time
+	info.SendQueue, slowSince = c.queue.state()
+	info.Slow = !slowSince.IsZero()
+	return info
+}
+
+// ListClients describes the clients of user, or every client for admins,
+// oldest connection first
+func (h *Hub) ListClients(user auth.User) []ClientInfo {
+	h.mu.RLock()
+	clients := make([]ClientInfo, 0, len(h.clients))
+	for client := range h.clients {
+		if user.IsAdmin() || client.user.ID == user.ID {
+			clients = append(clients, client.info())
+		}
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].ConnectedAt.Before(clients[j].ConnectedAt)
+	})
+	return clients
+}
+
+// GetConnectionRates returns the rates of the messages written to clients
+func (h *Hub) GetConnectionRates() ConnectionRates {
+	return ConnectionRates{
+		Messages: h.messageRate.GetRate(),
+		Bytes:    h.byteRate.GetRate(),
+		Dropped:  h.dropRate.GetRate(),
+	}
+}
+
+// DisconnectClient closes the connection of a client
+func (h *Hub) DisconnectClient(id string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		if client.id == id {
+			h.disconnect(client, websocket.ClosePolicyViolation, "disconnected by an admin")
+			log.Info().Str("client_id", id).Msg("Client disconnected by an admin")
+			return nil
+		}
+	}
+	return fmt.Errorf("client not found: %s", id)
+}
+
+// disconnect unregisters a client and has its writer close the connection
+// with code and reason. Callers hold the hub lock.
+func (h *Hub) disconnect(client *Client, code int, reason string) {
+	delete(h.clients, client)
+	message := websocket.FormatCloseMessage(code, reason)
+	client.closeMessage.Store(&message)
+	client.queue.close()
+}
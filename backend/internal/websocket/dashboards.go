@@ -0,0 +1,199 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+const maxDashboardWatchesPerClient = 5
+
+// DashboardWatcher pushes the widget data of dashboards, refreshed on the
+// server, until the returned function is called
+type DashboardWatcher interface {
+	Watch(ctx context.Context, dashboardID string, timeRange *models.QueryTimeRange, send func(models.WidgetUpdate)) (func(), error)
+}
+
+// logStream is a log_stream widget of a watched dashboard. The logs
+// broadcast to clients that match its filters are appended to it.
+type logStream struct {
+	dashboardID string
+	widgetID    string
+	filters     []models.LogFilter
+}
+
+// WatchRequest is the data of a watch_dashboard or unwatch_dashboard
+// message. TimeRange overrides the dashboard's time range; watching the
+// dashboard again with another one replaces it.
+type WatchRequest struct {
+	DashboardID string                 `json:"dashboard_id"`
+	TimeRange   *models.QueryTimeRange `json:"time_range,omitempty"`
+}
+
+// SetDashboardWatcher enables watching dashboards
+func (h *Hub) SetDashboardWatcher(watcher DashboardWatcher) {
+	h.dashboards = watcher
+}
+
+// GetDashboardWatchCount returns the number of dashboards watched by all clients
+func (h *Hub) GetDashboardWatchCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	count := 0
+	for client := range h.clients {
+		client.subsMu.Lock()
+		count += len(client.watches)
+		client.subsMu.Unlock()
+	}
+	return count
+}
+
+// handleWatchDashboard starts receiving widget_update messages for the
+// widgets of a dashboard, replacing any watch of the same dashboard
+func (c *Client) handleWatchDashboard(msg models.WebSocketMessage) {
+	var req WatchRequest
+	if err := decodeMessageData(msg.Data, &req); err != nil || req.DashboardID == "" {
+		c.sendError("", "Watching a dashboard needs its dashboard_id")
+		return
+	}
+	if c.hub.dashboards == nil {
+		c.sendError(req.DashboardID, "Dashboard refresh is not available")
+		return
+	}
+
+	c.subsMu.Lock()
+	previous, exists := c.watches[req.DashboardID]
+	if !exists && len(c.watches) >= maxDashboardWatchesPerClient {
+		c.subsMu.Unlock()
+		c.sendError(req.DashboardID, fmt.Sprintf("At most %d watched dashboards per connection", maxDashboardWatchesPerClient))
+		return
+	}
+	// Registered first so the updates sent while the watch starts are kept
+	c.watches[req.DashboardID] = func() {}
+	c.subsMu.Unlock()
+	if previous != nil {
+		previous()
+	}
+
+	unwatch, err := c.hub.dashboards.Watch(auth.WithUser(c.ctx, c.user), req.DashboardID, req.TimeRange, func(update models.WidgetUpdate) {
+		if stream, ok := update.Data.(*models.LogStreamData); ok {
+			c.setStream(logStream{dashboardID: update.DashboardID, widgetID: update.WidgetID, filters: stream.Filters})
+		}
+		if c.isPaused {
+			return
+		}
+		c.sendMessage(models.WebSocketMessage{Type: TypeWidgetUpdate, Data: update})
+	})
+	c.subsMu.Lock()
+	if err != nil {
+		c.forget(req.DashboardID)
+		c.subsMu.Unlock()
+		c.sendError(req.DashboardID, err.Error())
+		return
+	}
+	c.watches[req.DashboardID] = unwatch
+	c.subsMu.Unlock()
+
+	c.sendStatus("watching", req.DashboardID)
+	log.Debug().Str("client_id", c.id).Str("dashboard_id", req.DashboardID).Msg("Dashboard watched")
+}
+
+// handleUnwatchDashboard stops the widget updates of a dashboard
+func (c *Client) handleUnwatchDashboard(msg models.WebSocketMessage) {
+	var req WatchRequest
+	if err := decodeMessageData(msg.Data, &req); err != nil || req.DashboardID == "" {
+		c.sendError("", "Unwatching a dashboard needs its dashboard_id")
+		return
+	}
+
+	c.subsMu.Lock()
+	unwatch, exists := c.watches[req.DashboardID]
+	c.forget(req.DashboardID)
+	c.subsMu.Unlock()
+
+	if !exists {
+		c.sendError(req.DashboardID, "Dashboard is not watched")
+		return
+	}
+	unwatch()
+	c.sendStatus("unwatched", req.DashboardID)
+}
+
+// closeWatches stops the widget updates of every watched dashboard
+func (c *Client) closeWatches() {
+	c.subsMu.Lock()
+	watches := c.watches
+	c.watches = make(map[string]func())
+	c.streams = make(map[string]logStream)
+	c.subsMu.Unlock()
+
+	for _, unwatch := range watches {
+		unwatch()
+	}
+}
+
+// forget removes a watched dashboard and its log streams. Callers hold subsMu.
+func (c *Client) forget(dashboardID string) {
+	delete(c.watches, dashboardID)
+	for key, stream := range c.streams {
+		if stream.dashboardID == dashboardID {
+			delete(c.streams, key)
+		}
+	}
+}
+
+// setStream starts appending live logs to a log_stream widget of a
+// watched dashboard
+func (c *Client) setStream(stream logStream) {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	if _, watched := c.watches[stream.dashboardID]; watched {
+		c.streams[stream.dashboardID+"/"+stream.widgetID] = stream
+	}
+}
+
+// matchingStreams returns the log_stream widgets a log is appended to
+func (c *Client) matchingStreams(logEntry *models.Log) []logStream {
+	if c.isPaused {
+		return nil
+	}
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	var matching []logStream
+	for _, stream := range c.streams {
+		if c.matchesAll(logEntry, stream.filters) {
+			matching = append(matching, stream)
+		}
+	}
+	return matching
+}
+
+// update is the widget_update message appending a log to the widget
+func (stream logStream) update(row map[string]interface{}) models.WebSocketMessage {
+	return models.WebSocketMessage{
+		Type:   TypeWidgetUpdate,
+		Action: "append",
+		Data: models.WidgetUpdate{
+			DashboardID: stream.dashboardID,
+			WidgetID:    stream.widgetID,
+			Type:        "log_stream",
+			Data:        models.LogStreamData{Logs: []map[string]interface{}{row}},
+			UpdatedAt:   time.Now().UTC(),
+		},
+	}
+}
+
+// logRow converts a log to the row shape of a log_stream widget's logs
+func logRow(logEntry *models.Log) map[string]interface{} {
+	row := make(map[string]interface{})
+	if encoded, err := json.Marshal(logEntry); err == nil {
+		json.Unmarshal(encoded, &row)
+	}
+	return row
+}
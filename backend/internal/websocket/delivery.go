@@ -0,0 +1,212 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// maxBatchBytes bounds the messages of one batched frame, before
+// compression
+const maxBatchBytes = 1 << 20
+
+// DeliveryOptions tune how messages are written to clients. Under load,
+// messages queued for a client of the versioned protocol share a frame,
+// waiting at most BatchMaxLatency for more; a message that finds the queue
+// empty is sent at once. Unversioned clients parse every frame as one
+// message, so they always get one message per frame. A client whose queue keeps overflowing for SlowClientTimeout is
+// disconnected.
+type DeliveryOptions struct {
+	Compression       bool // permessage-deflate, for clients that offer it
+	CompressionLevel  int  // 1 (fastest) to 9 (best)
+	BatchMaxLatency   time.Duration
+	BatchMaxMessages  int // 1 disables batching
+	SendQueueSize     int
+	SlowClientTimeout time.Duration
+}
+
+// DefaultDeliveryOptions compress frames cheaply, batch up to 256
+// messages for 50ms and evict clients that stay 30s behind a queue of 256
+func DefaultDeliveryOptions() DeliveryOptions {
+	return DeliveryOptions{
+		Compression:       true,
+		CompressionLevel:  flate.BestSpeed,
+		BatchMaxLatency:   50 * time.Millisecond,
+		BatchMaxMessages:  256,
+		SendQueueSize:     256,
+		SlowClientTimeout: 30 * time.Second,
+	}
+}
+
+// DeliveryStats counts what was written to clients. Bytes are counted
+// before compression.
+type DeliveryStats struct {
+	Compression   bool   `json:"compression"`
+	Frames        uint64 `json:"frames"`
+	BatchedFrames uint64 `json:"batched_frames"` // frames of more than one message
+	Messages      uint64 `json:"messages"`
+	Bytes         uint64 `json:"bytes"`
+}
+
+type deliveryCounters struct {
+	frames        atomic.Uint64
+	batchedFrames atomic.Uint64
+	messages      atomic.Uint64
+	bytes         atomic.Uint64
+}
+
+// SetDeliveryOptions changes how messages are written to the clients that
+// connect next
+func (h *Hub) SetDeliveryOptions(options DeliveryOptions) {
+	if options.CompressionLevel < flate.BestSpeed || options.CompressionLevel > flate.BestCompression {
+		options.CompressionLevel = flate.BestSpeed
+	}
+	if options.BatchMaxMessages < 1 {
+		options.BatchMaxMessages = 1
+	}
+	defaults := DefaultDeliveryOptions()
+	if options.SendQueueSize < 1 {
+		options.SendQueueSize = defaults.SendQueueSize
+	}
+	if options.SlowClientTimeout <= 0 {
+		options.SlowClientTimeout = defaults.SlowClientTimeout
+	}
+	h.delivery = options
+}
+
+// GetDeliveryStats returns how many frames and messages were written to
+// clients
+func (h *Hub) GetDeliveryStats() DeliveryStats {
+	return DeliveryStats{
+		Compression:   h.delivery.Compression,
+		Frames:        h.deliveryCounters.frames.Load(),
+		BatchedFrames: h.deliveryCounters.batchedFrames.Load(),
+		Messages:      h.deliveryCounters.messages.Load(),
+		Bytes:         h.deliveryCounters.bytes.Load(),
+	}
+}
+
+// upgrade switches a request to the WebSocket protocol, negotiating
+// compression if enabled
+func (h *Hub) upgrade(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+	u := upgrader
+	u.EnableCompression = h.delivery.Compression
+	conn, err := u.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	if h.delivery.Compression {
+		conn.SetCompressionLevel(h.delivery.CompressionLevel)
+	}
+	return conn, nil
+}
+
+// collectBatch takes the messages to send in the next frame. Messages
+// already queued join the first, and while more keep coming it waits for
+// them until BatchMaxLatency after it. It reports false when the queue was
+// closed.
+func (c *Client) collectBatch() ([][]byte, bool) {
+	options := c.hub.delivery
+	if !c.versioned() {
+		options.BatchMaxMessages = 1
+	}
+	var batch [][]byte
+	size := 0
+
+	var deadline time.Time
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for len(batch) < options.BatchMaxMessages && size < maxBatchBytes {
+		message, ok, closed := c.queue.pop()
+		if closed {
+			return batch, false
+		}
+		if ok {
+			if batch == nil {
+				deadline = time.Now().Add(options.BatchMaxLatency)
+			}
+			batch = append(batch, message)
+			size += len(message)
+			continue
+		}
+
+		// A lone message is not worth delaying
+		if len(batch) <= 1 {
+			return batch, true
+		}
+		if timer == nil {
+			timer = time.NewTimer(time.Until(deadline))
+		}
+		select {
+		case <-c.queue.ready:
+		case <-timer.C:
+			return batch, true
+		}
+	}
+
+	// Messages left behind get the next frame
+	c.queue.signal()
+	return batch, true
+}
+
+// writeBatch writes messages in one frame, as a batch message of them.
+// Unversioned clients cannot read batch messages and get a frame per
+// message instead.
+func (c *Client) writeBatch(batch [][]byte) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	if len(batch) > 1 && !c.versioned() {
+		for _, message := range batch {
+			if err := c.writeBatch([][]byte{message}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	w, err := c.conn.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+
+	var n int
+	if len(batch) == 1 {
+		n, _ = w.Write(batch[0])
+	} else {
+		var buf bytes.Buffer
+		buf.WriteString(`{"v":1,"type":"` + TypeBatch + `","data":[`)
+		for i, message := range batch {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(message)
+		}
+		buf.WriteString("]}")
+		n, _ = w.Write(buf.Bytes())
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	counters := &c.hub.deliveryCounters
+	counters.frames.Add(1)
+	if len(batch) > 1 {
+		counters.batchedFrames.Add(1)
+	}
+	counters.messages.Add(uint64(len(batch)))
+	counters.bytes.Add(uint64(n))
+	c.messagesSent.Add(uint64(len(batch)))
+	c.bytesSent.Add(uint64(n))
+	c.hub.messageRate.Increment(len(batch))
+	c.hub.byteRate.Increment(n)
+	return nil
+}
@@ -0,0 +1,80 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// framesWritten writes batch to a client speaking protocol and returns the
+// frames it receives
+func framesWritten(t *testing.T, protocol int32, batch [][]byte) []string {
+	t.Helper()
+	h := NewHub()
+	written := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := h.upgrade(w, r)
+		if err != nil {
+			written <- err
+			return
+		}
+		c := &Client{hub: h, conn: conn}
+		c.protocol.Store(protocol)
+		written <- c.writeBatch(batch)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+	defer conn.Close()
+	if err := <-written; err != nil {
+		t.Fatalf("writeBatch error: %v", err)
+	}
+
+	var frames []string
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	for {
+		_, frame, err := conn.ReadMessage()
+		if err != nil {
+			return frames
+		}
+		frames = append(frames, string(frame))
+	}
+}
+
+func TestWriteBatchFrames(t *testing.T) {
+	batch := [][]byte{[]byte(`{"type":"log","data":1}`), []byte(`{"type":"log","data":2}`), []byte(`{"type":"log","data":3}`)}
+
+	// Unversioned clients parse each frame as one message
+	frames := framesWritten(t, 0, batch)
+	if len(frames) != len(batch) {
+		t.Fatalf("unversioned client got %d frames, want %d", len(frames), len(batch))
+	}
+	for i, frame := range frames {
+		if frame != string(batch[i]) {
+			t.Errorf("frame %d = %s, want %s", i, frame, batch[i])
+		}
+	}
+
+	frames = framesWritten(t, ProtocolVersion, batch)
+	if len(frames) != 1 {
+		t.Fatalf("versioned client got %d frames, want one batch", len(frames))
+	}
+	var message struct {
+		Type string            `json:"type"`
+		Data []json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(frames[0]), &message); err != nil {
+		t.Fatalf("batch frame is not JSON: %v", err)
+	}
+	if message.Type != TypeBatch || len(message.Data) != len(batch) {
+		t.Errorf("batch frame = %s", frames[0])
+	}
+}
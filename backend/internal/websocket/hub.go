@@ -1,20 +1,54 @@
 package websocket
 
 import (
-	"encoding/json"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/monitoring"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/query"
 )
 
 type Hub struct {
 	// Registered clients
 	clients map[*Client]bool
 
-	// Inbound messages from clients
-	broadcast chan []byte
+	// Live logs to send to the clients that want them
+	broadcast chan *models.Log
+
+	// The latest live logs, for clients resuming after a disconnect
+	replay *replayBuffer
+
+	// Rates of the logs broadcast over the last minute
+	liveStats liveStats
+
+	// How messages are written to clients, and what was written
+	delivery         DeliveryOptions
+	deliveryCounters deliveryCounters
+
+	// Messages dropped from full send queues and slow clients evicted,
+	// also recorded in metrics if set
+	sendQueueDropped atomic.Uint64
+	slowEvicted      atomic.Uint64
+	metrics          MetricsRecorder
+
+	// Live logs dropped because the broadcast backlog was full
+	broadcastDropped atomic.Uint64
+
+	// Messages, bytes and drops per second over the last minute
+	messageRate *monitoring.RateCounter
+	byteRate    *monitoring.RateCounter
+	dropRate    *monitoring.RateCounter
+
+	// The channels clients can join
+	channels *ChannelRegistry
+
+	// Recordings of live tails, by ID
+	recordings   map[string]*recording
+	recordingsMu sync.Mutex
 
 	// Register requests from clients
 	register chan *Client
@@ -22,22 +56,49 @@ type Hub struct {
 	// Unregister requests from clients
 	unregister chan *Client
 
+	// Runs the live queries clients subscribe to
+	queryEngine *query.Engine
+
+	// Refreshes the widgets of the dashboards clients watch
+	dashboards DashboardWatcher
+
+	// Where resuming clients catch up on logs that left the replay buffer
+	catchUp CatchUpSource
+
+	// When the Run loop last went round, in Unix nanoseconds
+	lastLoop atomic.Int64
+
 	// Mutex for thread-safe operations
 	mu sync.RWMutex
 }
 
 func NewHub() *Hub {
 	return &Hub{
-		broadcast:  make(chan []byte, 256),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		clients:    make(map[*Client]bool),
+		broadcast:   make(chan *models.Log, 256),
+		register:    make(chan *Client),
+		unregister:  make(chan *Client),
+		clients:     make(map[*Client]bool),
+		replay:      newReplayBuffer(replayBufferSize),
+		delivery:    DefaultDeliveryOptions(),
+		messageRate: monitoring.NewRateCounter(time.Minute, time.Second),
+		byteRate:    monitoring.NewRateCounter(time.Minute, time.Second),
+		dropRate:    monitoring.NewRateCounter(time.Minute, time.Second),
+		channels:    NewChannelRegistry(),
+		recordings:  make(map[string]*recording),
 	}
 }
 
 func (h *Hub) Run() {
+	go h.pushLiveStats()
+	go h.evictSlowClients()
+	heartbeat := time.NewTicker(hubHeartbeatInterval)
+	defer heartbeat.Stop()
+	h.lastLoop.Store(time.Now().UnixNano())
 	for {
 		select {
+		case now := <-heartbeat.C:
+			h.lastLoop.Store(now.UnixNano())
+
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
@@ -46,36 +107,55 @@ func (h *Hub) Run() {
 
 			// Send welcome message
 			welcome := models.WebSocketMessage{
-				Type: "connection",
-				Data: map[string]string{
-					"status":  "connected",
-					"message": "Connected to log stream",
+				Type: TypeConnection,
+				Data: map[string]interface{}{
+					"status":    "connected",
+					"message":   "Connected to log stream",
+					"client_id": client.id,
+					"protocol":  ProtocolVersion,
 				},
 			}
-			if msg, err := json.Marshal(welcome); err == nil {
-				client.send <- msg
+			if msg, err := encodeMessage(welcome); err == nil {
+				client.enqueue(msg)
+			}
+			if client.since != nil {
+				client.replay(*client.since)
 			}
 
 		case client := <-h.unregister:
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				close(client.send)
+				client.queue.close()
 				h.mu.Unlock()
 				log.Info().Str("client_id", client.id).Msg("Client disconnected")
 			} else {
 				h.mu.Unlock()
 			}
 
-		case message := <-h.broadcast:
+		case logEntry := <-h.broadcast:
+			h.liveStats.observe(logEntry, time.Now())
+			entry, err := h.replay.add(logEntry)
+			if err != nil {
+				continue
+			}
+			// Slow clients drop their oldest messages; evictSlowClients
+			// disconnects those that stay behind
+			var row map[string]interface{}
 			h.mu.RLock()
 			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					// Client's send channel is full, close it
-					close(client.send)
-					delete(h.clients, client)
+				if client.wantsLog(logEntry) {
+					client.sendLog(entry)
+				}
+
+				// Append it to the log_stream widgets it matches
+				for _, stream := range client.matchingStreams(logEntry) {
+					if row == nil {
+						row = logRow(logEntry)
+					}
+					if update, err := encodeMessage(stream.update(row)); err == nil {
+						client.enqueue(update)
+					}
 				}
 			}
 			h.mu.RUnlock()
@@ -83,49 +163,62 @@ func (h *Hub) Run() {
 	}
 }
 
-// BroadcastLog sends a log entry to all connected clients
+// BroadcastLog sends a log entry to the connected clients whose filters
+// and tail it matches, and to the log_stream widgets of watched dashboards.
+// The ingestion pipeline calls it for every log it accepts, so it never
+// blocks: when the hub falls behind and the backlog is full, the log is
+// dropped from the live stream and counted.
 func (h *Hub) BroadcastLog(log *models.Log) {
-	message := models.WebSocketMessage{
-		Type: "log",
-		Data: log,
-	}
-
-	if msg, err := json.Marshal(message); err == nil {
-		h.broadcast <- msg
+	select {
+	case h.broadcast <- log:
+	default:
+		h.broadcastDropped.Add(1)
+		if h.metrics != nil {
+			h.metrics.IncrementCounter("websocket_broadcast_dropped_logs", 1)
+		}
 	}
 }
 
-// BroadcastToClients sends a message to specific clients based on their filters
-func (h *Hub) BroadcastToClients(logEntry *models.Log) {
-	message := models.WebSocketMessage{
-		Type: "log",
-		Data: logEntry,
+// hubHeartbeatInterval is how often an idle Run loop records it is alive
+const hubHeartbeatInterval = 5 * time.Second
+
+// HealthStats returns the backlog of logs to deliver and when the Run
+// loop last went round, for health checks
+func (h *Hub) HealthStats() monitoring.WebSocketHubStats {
+	queues := h.GetSendQueueStats()
+	stats := monitoring.WebSocketHubStats{
+		Clients:           h.GetConnectedClients(),
+		SlowClients:       queues.SlowClients,
+		DroppedMessages:   queues.Dropped,
+		BroadcastBacklog:  len(h.broadcast),
+		BroadcastCapacity: cap(h.broadcast),
+		BroadcastDropped:  h.broadcastDropped.Load(),
 	}
-
-	msgBytes, err := json.Marshal(message)
-	if err != nil {
-		return
+	if last := h.lastLoop.Load(); last != 0 {
+		stats.LastLoop = time.Unix(0, last)
 	}
+	return stats
+}
 
+// GetConnectedClients returns the number of connected clients
+func (h *Hub) GetConnectedClients() int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
+	return len(h.clients)
+}
 
-	for client := range h.clients {
-		// Check if log matches client's filters
-		if client.MatchesFilters(logEntry) && !client.isPaused {
-			select {
-			case client.send <- msgBytes:
-			default:
-				// Client's send channel is full
-				log.Warn().Str("client_id", client.id).Msg("Client send buffer full")
-			}
-		}
-	}
+// SetQueryEngine enables live query subscriptions
+func (h *Hub) SetQueryEngine(engine *query.Engine) {
+	h.queryEngine = engine
 }
 
-// GetConnectedClients returns the number of connected clients
-func (h *Hub) GetConnectedClients() int {
+// GetSubscriptionCount returns the number of live queries of all clients
+func (h *Hub) GetSubscriptionCount() int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	return len(h.clients)
-}
\ No newline at end of file
+	count := 0
+	for client := range h.clients {
+		count += client.subscriptionCount()
+	}
+	return count
+}
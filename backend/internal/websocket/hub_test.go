@@ -0,0 +1,32 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+func TestBroadcastLogDoesNotBlock(t *testing.T) {
+	// The hub is not running, so nothing drains the backlog
+	h := NewHub()
+	capacity := cap(h.broadcast)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < capacity+10; i++ {
+			h.BroadcastLog(&models.Log{Message: "live"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("BroadcastLog blocked on a full backlog")
+	}
+
+	stats := h.HealthStats()
+	if stats.BroadcastBacklog != capacity || stats.BroadcastDropped != 10 {
+		t.Errorf("backlog = %d, dropped = %d; want %d and 10", stats.BroadcastBacklog, stats.BroadcastDropped, capacity)
+	}
+}
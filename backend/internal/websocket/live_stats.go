@@ -0,0 +1,240 @@
+package websocket
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+const (
+	// liveStatsWindow is the period live stats are computed over, in
+	// one-second buckets
+	liveStatsWindow = 60
+	// defaultLiveStatsInterval and minLiveStatsInterval bound how often
+	// live stats are pushed
+	defaultLiveStatsInterval = 5 * time.Second
+	minLiveStatsInterval     = time.Second
+	// maxLiveStatsServices bounds the services of a live_stats message,
+	// busiest first
+	maxLiveStatsServices = 50
+)
+
+// LiveStatsRequest is the data of a subscribe_live_stats message
+type LiveStatsRequest struct {
+	Interval int `json:"interval,omitempty"` // seconds between pushes
+}
+
+// LiveStats is the data of a live_stats message: the rates of the logs
+// ingested over the last WindowSeconds
+type LiveStats struct {
+	WindowSeconds int                `json:"window_seconds"`
+	Total         int64              `json:"total"`
+	LogsPerSecond float64            `json:"logs_per_second"`
+	ByLevel       map[string]float64 `json:"by_level"` // logs per second
+	Services      []ServiceLiveStats `json:"services"`
+	ErrorRate     float64            `json:"error_rate"` // share of error and fatal logs
+	At            time.Time          `json:"at"`
+}
+
+// ServiceLiveStats are the live stats of one service
+type ServiceLiveStats struct {
+	Service       string  `json:"service"`
+	Logs          int64   `json:"logs"`
+	Errors        int64   `json:"errors"`
+	LogsPerSecond float64 `json:"logs_per_second"`
+	ErrorRate     float64 `json:"error_rate"`
+}
+
+// statsBucket counts the logs of one second
+type statsBucket struct {
+	second   int64
+	levels   map[string]int64
+	services map[string]*serviceCount
+}
+
+type serviceCount struct {
+	logs   int64
+	errors int64
+}
+
+// liveStats counts ingested logs in a ring of one-second buckets
+type liveStats struct {
+	mu      sync.Mutex
+	buckets [liveStatsWindow]statsBucket
+}
+
+// observe counts a log in the bucket of the second it was ingested
+func (s *liveStats) observe(logEntry *models.Log, now time.Time) {
+	second := now.Unix()
+	level := strings.ToLower(logEntry.Level)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bucket := &s.buckets[second%liveStatsWindow]
+	if bucket.second != second {
+		*bucket = statsBucket{
+			second:   second,
+			levels:   make(map[string]int64),
+			services: make(map[string]*serviceCount),
+		}
+	}
+	bucket.levels[level]++
+	count, ok := bucket.services[logEntry.Service]
+	if !ok {
+		count = &serviceCount{}
+		bucket.services[logEntry.Service] = count
+	}
+	count.logs++
+	if isErrorLevel(level) {
+		count.errors++
+	}
+}
+
+// snapshot sums the buckets of the window ending at now
+func (s *liveStats) snapshot(now time.Time) LiveStats {
+	stats := LiveStats{
+		WindowSeconds: liveStatsWindow,
+		ByLevel:       make(map[string]float64),
+		Services:      []ServiceLiveStats{},
+		At:            now.UTC(),
+	}
+	levels := make(map[string]int64)
+	services := make(map[string]*serviceCount)
+	var errors int64
+
+	oldest := now.Unix() - liveStatsWindow
+	s.mu.Lock()
+	for i := range s.buckets {
+		bucket := &s.buckets[i]
+		if bucket.second <= oldest || bucket.second > now.Unix() {
+			continue
+		}
+		for level, n := range bucket.levels {
+			levels[level] += n
+			stats.Total += n
+		}
+		for service, count := range bucket.services {
+			total, ok := services[service]
+			if !ok {
+				total = &serviceCount{}
+				services[service] = total
+			}
+			total.logs += count.logs
+			total.errors += count.errors
+			errors += count.errors
+		}
+	}
+	s.mu.Unlock()
+
+	stats.LogsPerSecond = float64(stats.Total) / liveStatsWindow
+	for level, n := range levels {
+		stats.ByLevel[level] = float64(n) / liveStatsWindow
+	}
+	if stats.Total > 0 {
+		stats.ErrorRate = float64(errors) / float64(stats.Total)
+	}
+	for service, count := range services {
+		stats.Services = append(stats.Services, ServiceLiveStats{
+			Service:       service,
+			Logs:          count.logs,
+			Errors:        count.errors,
+			LogsPerSecond: float64(count.logs) / liveStatsWindow,
+			ErrorRate:     float64(count.errors) / float64(count.logs),
+		})
+	}
+	sort.Slice(stats.Services, func(i, j int) bool {
+		if stats.Services[i].Logs != stats.Services[j].Logs {
+			return stats.Services[i].Logs > stats.Services[j].Logs
+		}
+		return stats.Services[i].Service < stats.Services[j].Service
+	})
+	if len(stats.Services) > maxLiveStatsServices {
+		stats.Services = stats.Services[:maxLiveStatsServices]
+	}
+	return stats
+}
+
+func isErrorLevel(level string) bool {
+	return level == "error" || level == "fatal"
+}
+
+// GetLiveStats returns the rates of the logs ingested over the last minute
+func (h *Hub) GetLiveStats() LiveStats {
+	return h.liveStats.snapshot(time.Now())
+}
+
+// pushLiveStats sends live stats to the clients subscribed to them, each
+// at its interval, until the hub stops
+func (h *Hub) pushLiveStats() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		var due []*Client
+		h.mu.RLock()
+		for client := range h.clients {
+			if client.liveStatsDue(now) {
+				due = append(due, client)
+			}
+		}
+		h.mu.RUnlock()
+		if len(due) == 0 {
+			continue
+		}
+
+		stats := h.liveStats.snapshot(now)
+		for _, client := range due {
+			client.sendMessage(models.WebSocketMessage{Type: TypeLiveStats, Data: stats})
+		}
+	}
+}
+
+// liveStatsDue reports whether the client is subscribed to live stats and
+// their interval has passed, moving the next push on if so
+func (c *Client) liveStatsDue(now time.Time) bool {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	if c.liveStatsInterval == 0 || now.Before(c.liveStatsNext) {
+		return false
+	}
+	c.liveStatsNext = now.Add(c.liveStatsInterval)
+	return true
+}
+
+// handleSubscribeLiveStats starts pushing live stats to the client, the
+// first right away, replacing the interval of any previous subscription
+func (c *Client) handleSubscribeLiveStats(msg models.WebSocketMessage) {
+	var req LiveStatsRequest
+	if err := decodeMessageData(msg.Data, &req); err != nil {
+		c.sendError("", "Invalid live stats subscription: "+err.Error())
+		return
+	}
+	interval := defaultLiveStatsInterval
+	if req.Interval > 0 {
+		interval = time.Duration(req.Interval) * time.Second
+	}
+	if interval < minLiveStatsInterval {
+		interval = minLiveStatsInterval
+	}
+
+	c.subsMu.Lock()
+	c.liveStatsInterval = interval
+	c.liveStatsNext = time.Time{}
+	c.subsMu.Unlock()
+
+	c.sendStatus("live_stats_subscribed", interval.String())
+	log.Debug().Str("client_id", c.id).Dur("interval", interval).Msg("Live stats subscribed")
+}
+
+// handleUnsubscribeLiveStats stops pushing live stats to the client
+func (c *Client) handleUnsubscribeLiveStats() {
+	c.subsMu.Lock()
+	c.liveStatsInterval = 0
+	c.subsMu.Unlock()
+
+	c.sendStatus("live_stats_unsubscribed", "")
+}
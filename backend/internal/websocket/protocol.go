@@ -0,0 +1,198 @@
+package websocket
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// ProtocolVersion is the version of the message envelope. Every message
+// the server sends carries it as v. Clients that send it, or connect with
+// ?protocol=1, get an ack or an error for each request, correlated by the
+// request's id; others get the status and query_error messages of the
+// unversioned protocol.
+const ProtocolVersion = 1
+
+// Messages clients send
+const (
+	TypeSubscribe        = "subscribe"
+	TypeUnsubscribe      = "unsubscribe"
+	TypeTail             = "tail"
+	TypeUntail           = "untail"
+	TypeJoinChannel      = "join_channel"
+	TypeLeaveChannel     = "leave_channel"
+	TypeFilter           = "filter"
+	TypePause            = "pause"
+	TypeResume           = "resume"
+	TypePing             = "ping"
+	TypeStats            = "stats" // also the reply
+	TypeRecord           = "record"
+	TypeStopRecording    = "stop_recording"
+	TypeWatchDashboard   = "watch_dashboard"
+	TypeUnwatchDashboard = "unwatch_dashboard"
+
+	TypeSubscribeLiveStats   = "subscribe_live_stats"
+	TypeUnsubscribeLiveStats = "unsubscribe_live_stats"
+)
+
+// Messages the server sends
+const (
+	TypeConnection   = "connection"
+	TypeLog          = "log"
+	TypeReplay       = "replay"
+	TypeQueryResult  = "query_result"
+	TypeWidgetUpdate = "widget_update"
+	TypeLiveStats    = "live_stats"
+	TypeBatch        = "batch" // of messages sent in one frame
+	TypeAck          = "ack"
+	TypeError        = "error"
+	TypeStatus       = "status"      // unversioned ack
+	TypeQueryError   = "query_error" // unversioned error
+)
+
+// Error codes
+const (
+	ErrorInvalidMessage     = "invalid_message"
+	ErrorUnsupportedVersion = "unsupported_version"
+	ErrorUnknownType        = "unknown_type"
+	ErrorRequestFailed      = "request_failed"
+)
+
+// ProtocolSchema is the JSON schema of the messages of ProtocolVersion
+//
+//go:embed protocol_schema.json
+var ProtocolSchema []byte
+
+// AckData is the data of an ack message
+type AckData struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// ErrorData is the data of an error message. Ref names what failed when
+// that is not the request itself, such as a live query or a dashboard.
+type ErrorData struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Ref     string `json:"ref,omitempty"`
+}
+
+// ConnectionStats is the data of a stats message
+type ConnectionStats struct {
+	ClientInfo
+	Replay ReplayStats `json:"replay"`
+}
+
+// encodeMessage marshals a message the server sends, in the envelope of
+// ProtocolVersion
+func encodeMessage(msg models.WebSocketMessage) ([]byte, error) {
+	msg.Version = ProtocolVersion
+	return json.Marshal(msg)
+}
+
+// versioned reports whether the client speaks ProtocolVersion
+func (c *Client) versioned() bool {
+	return c.protocol.Load() >= ProtocolVersion
+}
+
+// accept checks the version of a request, switching the client to the
+// versioned protocol when it sends one, and reports whether it can be
+// handled
+func (c *Client) accept(msg models.WebSocketMessage) bool {
+	if msg.Version == 0 {
+		return true
+	}
+	c.protocol.Store(ProtocolVersion)
+	if msg.Version > ProtocolVersion {
+		c.sendErrorCode(ErrorUnsupportedVersion, "", fmt.Sprintf("Unsupported protocol version %d (latest is %d)", msg.Version, ProtocolVersion))
+		return false
+	}
+	return true
+}
+
+// sendStatus acknowledges the request being handled
+func (c *Client) sendStatus(status, message string) {
+	msg := models.WebSocketMessage{
+		Type: TypeStatus,
+		Data: map[string]string{
+			"status":  status,
+			"message": message,
+		},
+	}
+	if c.versioned() {
+		msg = models.WebSocketMessage{
+			Type:   TypeAck,
+			ID:     c.request.ID,
+			Action: c.request.Type,
+			Data:   AckData{Status: status, Message: message},
+		}
+	}
+
+	if msgBytes, err := encodeMessage(msg); err == nil {
+		c.enqueue(msgBytes)
+	}
+}
+
+// sendError fails the request being handled. ref names what failed, if
+// not the request itself.
+func (c *Client) sendError(ref, message string) {
+	c.sendErrorCode(ErrorRequestFailed, ref, message)
+}
+
+func (c *Client) sendErrorCode(code, ref, message string) {
+	if !c.versioned() {
+		c.notifyError(ref, message)
+		return
+	}
+	c.sendMessage(models.WebSocketMessage{
+		Type:   TypeError,
+		ID:     c.request.ID,
+		Action: c.request.Type,
+		Data:   ErrorData{Code: code, Message: message, Ref: ref},
+	})
+}
+
+// notifyError sends an error outside of any request, such as a live query
+// that failed on the server
+func (c *Client) notifyError(ref, message string) {
+	if c.versioned() {
+		c.sendMessage(models.WebSocketMessage{
+			Type: TypeError,
+			Data: ErrorData{Code: ErrorRequestFailed, Message: message, Ref: ref},
+		})
+		return
+	}
+	c.sendMessage(models.WebSocketMessage{
+		Type: TypeQueryError,
+		Data: map[string]string{
+			"id":    ref,
+			"error": message,
+		},
+	})
+}
+
+// handleStats replies with the stats of the connection
+func (c *Client) handleStats() {
+	stats := ConnectionStats{
+		ClientInfo: c.info(),
+		Replay:     c.hub.GetReplayStats(),
+	}
+
+	c.sendMessage(models.WebSocketMessage{
+		Type: TypeStats,
+		ID:   c.request.ID,
+		Data: stats,
+	})
+}
+
+// handleUnknown fails a request of a type the server does not know
+func (c *Client) handleUnknown(msg models.WebSocketMessage) {
+	log.Warn().Str("type", msg.Type).Msg("Unknown message type")
+	if c.versioned() {
+		c.sendErrorCode(ErrorUnknownType, "", "Unknown message type: "+msg.Type)
+	}
+}
@@ -0,0 +1,229 @@
+package websocket
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+const (
+	// maxRecordingLogs bounds the logs one recording captures
+	maxRecordingLogs = 50000
+	// maxRecordingsPerUser bounds the recordings a user keeps at once
+	maxRecordingsPerUser = 10
+	// recordingRetention is how long a recording is kept after it stopped
+	recordingRetention = 24 * time.Hour
+)
+
+// RecordRequest is the data of a record message
+type RecordRequest struct {
+	Name string `json:"name,omitempty"`
+}
+
+// RecordingInfo describes a recording of a live tail
+type RecordingInfo struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name,omitempty"`
+	UserID    string     `json:"user_id"`
+	StartedAt time.Time  `json:"started_at"`
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+	LogCount  int        `json:"log_count"`
+	Truncated bool       `json:"truncated"` // reached maxRecordingLogs
+}
+
+// recording captures the live logs sent to a client while it records,
+// kept by the hub for recordingRetention after it stops so it can be
+// exported
+type recording struct {
+	mu   sync.Mutex
+	info RecordingInfo
+	logs []models.Log
+}
+
+func (rec *recording) capture(logEntry *models.Log) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.info.StoppedAt != nil {
+		return
+	}
+	if len(rec.logs) >= maxRecordingLogs {
+		rec.info.Truncated = true
+		return
+	}
+	rec.logs = append(rec.logs, *logEntry)
+	rec.info.LogCount = len(rec.logs)
+}
+
+func (rec *recording) stop() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.info.StoppedAt == nil {
+		now := time.Now()
+		rec.info.StoppedAt = &now
+	}
+}
+
+func (rec *recording) snapshot() RecordingInfo {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.info
+}
+
+// handleRecord starts recording the live logs the client receives
+func (c *Client) handleRecord(msg models.WebSocketMessage) {
+	var req RecordRequest
+	if err := decodeMessageData(msg.Data, &req); err != nil {
+		c.sendError("", "Invalid recording: "+err.Error())
+		return
+	}
+
+	c.replayMu.Lock()
+	recording := c.recording
+	c.replayMu.Unlock()
+	if recording != nil {
+		c.sendError(recording.info.ID, "Already recording")
+		return
+	}
+
+	recording, err := c.hub.startRecording(c.user, req.Name)
+	if err != nil {
+		c.sendError("", err.Error())
+		return
+	}
+	c.replayMu.Lock()
+	c.recording = recording
+	c.replayMu.Unlock()
+
+	c.sendStatus("recording", recording.info.ID)
+	log.Info().Str("client_id", c.id).Str("recording_id", recording.info.ID).Msg("Tail recording started")
+}
+
+// handleStopRecording stops the client's recording, which can then be
+// exported until it expires
+func (c *Client) handleStopRecording() {
+	c.replayMu.Lock()
+	recording := c.recording
+	c.recording = nil
+	c.replayMu.Unlock()
+	if recording == nil {
+		c.sendError("", "Not recording")
+		return
+	}
+
+	recording.stop()
+	c.sendStatus("recording_stopped", recording.info.ID)
+	log.Info().Str("client_id", c.id).Str("recording_id", recording.info.ID).Int("logs", recording.snapshot().LogCount).Msg("Tail recording stopped")
+}
+
+// closeRecording stops the recording of a disconnecting client
+func (c *Client) closeRecording() {
+	c.replayMu.Lock()
+	recording := c.recording
+	c.recording = nil
+	c.replayMu.Unlock()
+	if recording != nil {
+		recording.stop()
+	}
+}
+
+// startRecording adds a recording for user, dropping expired ones first
+func (h *Hub) startRecording(user auth.User, name string) (*recording, error) {
+	h.recordingsMu.Lock()
+	defer h.recordingsMu.Unlock()
+	h.pruneRecordings(time.Now())
+
+	count := 0
+	for _, rec := range h.recordings {
+		if rec.info.UserID == user.ID {
+			count++
+		}
+	}
+	if count >= maxRecordingsPerUser {
+		return nil, fmt.Errorf("at most %d recordings per user, delete one first", maxRecordingsPerUser)
+	}
+
+	rec := &recording{info: RecordingInfo{
+		ID:        uuid.New().String(),
+		Name:      name,
+		UserID:    user.ID,
+		StartedAt: time.Now(),
+	}}
+	h.recordings[rec.info.ID] = rec
+	return rec, nil
+}
+
+// ListRecordings returns the recordings user can export, newest first.
+// Admins see every user's.
+func (h *Hub) ListRecordings(user auth.User) []RecordingInfo {
+	h.recordingsMu.Lock()
+	defer h.recordingsMu.Unlock()
+	h.pruneRecordings(time.Now())
+
+	recordings := make([]RecordingInfo, 0)
+	for _, rec := range h.recordings {
+		if info := rec.snapshot(); info.UserID == user.ID || user.IsAdmin() {
+			recordings = append(recordings, info)
+		}
+	}
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[i].StartedAt.After(recordings[j].StartedAt)
+	})
+	return recordings
+}
+
+// GetRecording returns a recording of user, or of anyone for admins, and
+// the logs it captured so far
+func (h *Hub) GetRecording(user auth.User, id string) (RecordingInfo, []models.Log, error) {
+	rec, err := h.lookupRecording(user, id)
+	if err != nil {
+		return RecordingInfo{}, nil, err
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.info, append([]models.Log(nil), rec.logs...), nil
+}
+
+// DeleteRecording stops and drops a recording of user, or of anyone for
+// admins
+func (h *Hub) DeleteRecording(user auth.User, id string) error {
+	rec, err := h.lookupRecording(user, id)
+	if err != nil {
+		return err
+	}
+	rec.stop()
+
+	h.recordingsMu.Lock()
+	delete(h.recordings, id)
+	h.recordingsMu.Unlock()
+	return nil
+}
+
+func (h *Hub) lookupRecording(user auth.User, id string) (*recording, error) {
+	h.recordingsMu.Lock()
+	defer h.recordingsMu.Unlock()
+	h.pruneRecordings(time.Now())
+
+	rec, exists := h.recordings[id]
+	// Other users' recordings are not revealed
+	if !exists || (rec.snapshot().UserID != user.ID && !user.IsAdmin()) {
+		return nil, fmt.Errorf("recording not found: %s", id)
+	}
+	return rec, nil
+}
+
+// pruneRecordings drops the recordings that stopped more than
+// recordingRetention before now. Callers hold recordingsMu.
+func (h *Hub) pruneRecordings(now time.Time) {
+	for id, rec := range h.recordings {
+		if info := rec.snapshot(); info.StoppedAt != nil && now.Sub(*info.StoppedAt) > recordingRetention {
+			delete(h.recordings, id)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package websocket
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+)
+
+// replayBufferSize is how many of the latest live logs the hub keeps for
+// clients resuming after a disconnect
+const replayBufferSize = 10000
+
+// ReplayResult is the data of the replay message sent after the logs
+// buffered since a client's cursor. Missed counts the logs after the cursor
+// that had already left the buffer.
+type ReplayResult struct {
+	Since    uint64 `json:"since"`
+	Replayed int    `json:"replayed"`
+	Missed   uint64 `json:"missed"`
+	CaughtUp int    `json:"caught_up"` // missed logs read back from the database
+	Cursor   uint64 `json:"cursor"`    // of the latest buffered log
+}
+
+// ReplayStats describes the replay buffer of a hub
+type ReplayStats struct {
+	Depth       int    `json:"depth"`
+	Capacity    int    `json:"capacity"`
+	Cursor      uint64 `json:"cursor"`
+	Evicted     uint64 `json:"evicted"`      // logs that left the full buffer
+	DroppedLogs uint64 `json:"dropped_logs"` // messages dropped from the full send queues of slow clients
+	MissedLogs  uint64 `json:"missed_logs"`  // asked for by resuming clients after they were evicted
+}
+
+// replayEntry is a buffered log with its cursor, and the log message
+// sent to clients
+type replayEntry struct {
+	seq     uint64
+	log     *models.Log
+	message []byte
+}
+
+// replayBuffer is a ring of the latest live logs, numbered by a cursor
+// that increases by one with every log
+type replayBuffer struct {
+	mu      sync.Mutex
+	entries []replayEntry
+	start   int // index of the oldest entry
+	count   int
+	seq     uint64 // cursor of the latest log
+
+	missed atomic.Uint64
+}
+
+func newReplayBuffer(size int) *replayBuffer {
+	return &replayBuffer{entries: make([]replayEntry, size)}
+}
+
+// add buffers a log, evicting the oldest when full, and returns it with its
+// cursor and log message
+func (b *replayBuffer) add(logEntry *models.Log) (replayEntry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	seq := b.seq + 1
+	message, err := encodeMessage(models.WebSocketMessage{
+		Type: TypeLog,
+		Seq:  seq,
+		Data: logEntry,
+	})
+	if err != nil {
+		return replayEntry{}, err
+	}
+	b.seq = seq
+
+	entry := replayEntry{seq: seq, log: logEntry, message: message}
+	if b.count < len(b.entries) {
+		b.entries[(b.start+b.count)%len(b.entries)] = entry
+		b.count++
+	} else {
+		b.entries[b.start] = entry
+		b.start = (b.start + 1) % len(b.entries)
+	}
+	return entry, nil
+}
+
+// since returns the buffered logs after cursor, oldest first, the cursor of
+// the latest log and how many logs after cursor are no longer buffered
+func (b *replayBuffer) since(cursor uint64) ([]replayEntry, uint64, uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if cursor >= b.seq {
+		return nil, b.seq, 0
+	}
+	oldest := b.seq - uint64(b.count) + 1
+	var missed uint64
+	if cursor+1 < oldest {
+		missed = oldest - cursor - 1
+		cursor = oldest - 1
+	}
+	n := int(b.seq - cursor)
+	entries := make([]replayEntry, n)
+	for i := 0; i < n; i++ {
+		entries[i] = b.entries[(b.start+b.count-n+i)%len(b.entries)]
+	}
+	return entries, b.seq, missed
+}
+
+// evictedSince returns how many logs after cursor are no longer buffered
+// and the time of the oldest buffered log, or now if there is none
+func (b *replayBuffer) evictedSince(cursor uint64) (uint64, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	oldest := b.seq - uint64(b.count) + 1
+	if cursor+1 >= oldest {
+		return 0, time.Time{}
+	}
+	if b.count == 0 {
+		return oldest - cursor - 1, time.Now()
+	}
+	return oldest - cursor - 1, b.entries[b.start].log.Timestamp
+}
+
+func (b *replayBuffer) stats() ReplayStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return ReplayStats{
+		Depth:      b.count,
+		Capacity:   len(b.entries),
+		Cursor:     b.seq,
+		Evicted:    b.seq - uint64(b.count),
+		MissedLogs: b.missed.Load(),
+	}
+}
+
+// GetReplayStats returns the depth of the replay buffer and how many live
+// logs were dropped or missed
+func (h *Hub) GetReplayStats() ReplayStats {
+	stats := h.replay.stats()
+	stats.DroppedLogs = h.sendQueueDropped.Load()
+	return stats
+}
+
+// sendLog sends a live log to the client unless the last replay already
+// went past it, capturing it in the client's recording
+func (c *Client) sendLog(entry replayEntry) {
+	c.replayMu.Lock()
+	defer c.replayMu.Unlock()
+	if entry.seq <= c.replayed {
+		return
+	}
+	c.enqueue(c.logMessage(entry))
+	if c.recording != nil {
+		c.recording.capture(entry.log)
+	}
+}
+
+// replay sends the client the buffered logs after cursor that it wants,
+// preceded by those it missed that were read back from the database when it
+// connected, then a replay message. Live logs up to the latest buffered one
+// are not sent again.
+func (c *Client) replay(cursor uint64) {
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	if !c.hub.clients[c] {
+		return
+	}
+
+	c.replayMu.Lock()
+	entries, latest, missed := c.hub.replay.since(cursor)
+	missedLogs := c.missedLogs
+	c.missedLogs = nil
+	caughtUp := 0
+	for _, logEntry := range missedLogs {
+		if !c.wantsLog(logEntry) {
+			continue
+		}
+		if msg, err := encodeMessage(models.WebSocketMessage{Type: TypeLog, Data: logEntry}); err == nil {
+			c.enqueue(msg)
+			caughtUp++
+			if c.recording != nil {
+				c.recording.capture(logEntry)
+			}
+		}
+	}
+	replayed := 0
+	for _, entry := range entries {
+		if entry.seq <= c.replayed || !c.wantsLog(entry.log) {
+			continue
+		}
+		c.enqueue(c.logMessage(entry))
+		replayed++
+		if c.recording != nil {
+			c.recording.capture(entry.log)
+		}
+	}
+	if latest > c.replayed {
+		c.replayed = latest
+	}
+	c.replayMu.Unlock()
+
+	if missed > 0 && len(missedLogs) == 0 {
+		c.hub.replay.missed.Add(missed)
+		log.Warn().Str("client_id", c.id).Uint64("missed", missed).Msg("Client resumed after logs left the replay buffer")
+	}
+	if msg, err := encodeMessage(models.WebSocketMessage{
+		Type: TypeReplay,
+		Data: ReplayResult{Since: cursor, Replayed: replayed, Missed: missed, CaughtUp: caughtUp, Cursor: latest},
+	}); err == nil {
+		c.enqueue(msg)
+	}
+}
@@ -0,0 +1,198 @@
+package websocket
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+// MetricsRecorder receives the send queue metrics of a hub
+type MetricsRecorder interface {
+	SetGauge(name string, value float64)
+	IncrementCounter(name string, delta int64)
+}
+
+// SendQueueStats describes the send queues of the connected clients.
+// A client is slow while its queue has dropped messages it has not caught
+// up with since.
+type SendQueueStats struct {
+	Capacity    int    `json:"capacity"`
+	Depth       int    `json:"depth"` // messages queued for all clients
+	MaxDepth    int    `json:"max_depth"`
+	SlowClients int    `json:"slow_clients"`
+	Dropped     uint64 `json:"dropped"` // oldest messages dropped from full queues
+	Evicted     uint64 `json:"evicted"` // clients disconnected for staying slow
+}
+
+// sendQueue holds the messages waiting to be written to a client. It never
+// blocks the sender: when full, the oldest message is dropped.
+type sendQueue struct {
+	mu        sync.Mutex
+	messages  [][]byte
+	start     int
+	count     int
+	closed    bool
+	slowSince time.Time // of the first drop since the queue was last empty
+
+	// Signalled when messages are queued or the queue is closed
+	ready chan struct{}
+
+	dropped atomic.Uint64
+}
+
+func newSendQueue(size int) *sendQueue {
+	return &sendQueue{
+		messages: make([][]byte, size),
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// push queues a message, dropping the oldest when full, and reports
+// whether one was dropped. Messages pushed after close are ignored.
+func (q *sendQueue) push(message []byte) bool {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return false
+	}
+	dropped := q.count == len(q.messages)
+	if dropped {
+		q.messages[q.start] = nil
+		q.start = (q.start + 1) % len(q.messages)
+		q.count--
+		if q.slowSince.IsZero() {
+			q.slowSince = time.Now()
+		}
+	}
+	q.messages[(q.start+q.count)%len(q.messages)] = message
+	q.count++
+	q.mu.Unlock()
+
+	if dropped {
+		q.dropped.Add(1)
+	}
+	q.signal()
+	return dropped
+}
+
+// pop takes the oldest message, if any, and reports whether the queue is
+// closed. Emptying the queue ends a slow period.
+func (q *sendQueue) pop() ([]byte, bool, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, false, true
+	}
+	if q.count == 0 {
+		q.slowSince = time.Time{}
+		return nil, false, false
+	}
+	message := q.messages[q.start]
+	q.messages[q.start] = nil
+	q.start = (q.start + 1) % len(q.messages)
+	q.count--
+	if q.count == 0 {
+		q.slowSince = time.Time{}
+	}
+	return message, true, false
+}
+
+// close discards the queued messages and wakes the writer
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.messages = nil
+	q.count = 0
+	q.mu.Unlock()
+	q.signal()
+}
+
+func (q *sendQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// state returns the depth of the queue and since when it has been slow,
+// zero if it is not
+func (q *sendQueue) state() (int, time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.count, q.slowSince
+}
+
+// SetMetrics records send queue metrics in recorder
+func (h *Hub) SetMetrics(recorder MetricsRecorder) {
+	h.metrics = recorder
+}
+
+// GetSendQueueStats returns the depth of the clients' send queues and how
+// many messages were dropped and clients evicted
+func (h *Hub) GetSendQueueStats() SendQueueStats {
+	stats := SendQueueStats{
+		Capacity: h.delivery.SendQueueSize,
+		Dropped:  h.sendQueueDropped.Load(),
+		Evicted:  h.slowEvicted.Load(),
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.clients {
+		depth, slowSince := client.queue.state()
+		stats.Depth += depth
+		if depth > stats.MaxDepth {
+			stats.MaxDepth = depth
+		}
+		if !slowSince.IsZero() {
+			stats.SlowClients++
+		}
+	}
+	return stats
+}
+
+// evictSlowClients disconnects the clients whose send queues stay full for
+// longer than SlowClientTimeout, and records send queue metrics, until the
+// hub stops
+func (h *Hub) evictSlowClients() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		h.mu.Lock()
+		for client := range h.clients {
+			depth, slowSince := client.queue.state()
+			if slowSince.IsZero() || now.Sub(slowSince) < h.delivery.SlowClientTimeout {
+				continue
+			}
+			h.disconnect(client, websocket.CloseTryAgainLater, "slow consumer")
+			h.slowEvicted.Add(1)
+			if h.metrics != nil {
+				h.metrics.IncrementCounter("websocket_slow_client_evictions", 1)
+			}
+			log.Warn().Str("client_id", client.id).Int("queued", depth).Uint64("dropped", client.queue.dropped.Load()).
+				Dur("slow_for", now.Sub(slowSince)).Msg("Evicting slow WebSocket client")
+		}
+		h.mu.Unlock()
+
+		if h.metrics != nil {
+			stats := h.GetSendQueueStats()
+			h.metrics.SetGauge("websocket_connections", float64(h.GetConnectedClients()))
+			h.metrics.SetGauge("websocket_send_queue_depth", float64(stats.Depth))
+			h.metrics.SetGauge("websocket_send_queue_max_depth", float64(stats.MaxDepth))
+			h.metrics.SetGauge("websocket_slow_clients", float64(stats.SlowClients))
+		}
+	}
+}
+
+// enqueue queues a message for the client, counting what it drops
+func (c *Client) enqueue(message []byte) {
+	if c.queue.push(message) {
+		c.hub.sendQueueDropped.Add(1)
+		c.hub.dropRate.Increment(1)
+		if c.hub.metrics != nil {
+			c.hub.metrics.IncrementCounter("websocket_dropped_messages", 1)
+		}
+	}
+}
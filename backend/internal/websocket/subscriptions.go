@@ -0,0 +1,305 @@
+package websocket
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/query"
+)
+
+// Subscription modes
+const (
+	// ModeAggregate re-runs the query and sends the whole result when it changed
+	ModeAggregate = "aggregate"
+	// ModeTail runs the query for rows newer than the last one sent and
+	// appends them; the query filters on the :since parameter
+	ModeTail = "tail"
+)
+
+const (
+	maxSubscriptionsPerClient   = 10
+	defaultSubscriptionInterval = 5 * time.Second
+	minSubscriptionInterval     = time.Second
+	subscriptionQueryTimeout    = 30 // seconds
+)
+
+// SubscriptionRequest is the data of a subscribe message
+type SubscriptionRequest struct {
+	ID         string                 `json:"id"` // chosen by the client, e.g. a widget ID
+	Query      string                 `json:"query"`
+	Mode       string                 `json:"mode,omitempty"`     // aggregate (default) or tail
+	Interval   int                    `json:"interval,omitempty"` // seconds between runs
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	Since      *time.Time             `json:"since,omitempty"` // first :since of a tail, now when empty
+}
+
+// QueryUpdate is the data of a query_result message. Its action is
+// "replace" for aggregate subscriptions and "append" for tails.
+type QueryUpdate struct {
+	ID            string                   `json:"id"`
+	Columns       []query.ColumnInfo       `json:"columns,omitempty"`
+	Rows          []map[string]interface{} `json:"rows"`
+	RowCount      int                      `json:"row_count"`
+	ExecutionTime int64                    `json:"execution_time_ms"`
+	UpdatedAt     time.Time                `json:"updated_at"`
+}
+
+// subscription is a query a client receives live results of
+type subscription struct {
+	SubscriptionRequest
+	interval time.Duration
+	cancel   context.CancelFunc
+	lastHash [sha256.Size]byte // of the last result sent, aggregate mode
+	cursor   time.Time         // newest timestamp sent, tail mode
+}
+
+// handleSubscribe starts a live query, replacing any with the same ID
+func (c *Client) handleSubscribe(msg models.WebSocketMessage) {
+	var req SubscriptionRequest
+	if err := decodeMessageData(msg.Data, &req); err != nil {
+		c.sendError("", "Invalid subscription: "+err.Error())
+		return
+	}
+
+	sub, err := newSubscription(req)
+	if err != nil {
+		c.sendError(req.ID, err.Error())
+		return
+	}
+	if c.hub.queryEngine == nil {
+		c.sendError(req.ID, "Live queries are not available")
+		return
+	}
+
+	c.subsMu.Lock()
+	if previous, exists := c.subs[sub.ID]; exists {
+		previous.cancel()
+	} else if len(c.subs) >= maxSubscriptionsPerClient {
+		c.subsMu.Unlock()
+		c.sendError(sub.ID, fmt.Sprintf("At most %d live queries per connection", maxSubscriptionsPerClient))
+		return
+	}
+	var ctx context.Context
+	ctx, sub.cancel = context.WithCancel(c.ctx)
+	c.subs[sub.ID] = sub
+	c.subsWG.Add(1)
+	c.subsMu.Unlock()
+
+	go c.runSubscription(ctx, sub)
+
+	c.sendStatus("subscribed", sub.ID)
+	log.Debug().Str("client_id", c.id).Str("subscription", sub.ID).Str("mode", sub.Mode).Msg("Live query subscribed")
+}
+
+// handleUnsubscribe stops a live query
+func (c *Client) handleUnsubscribe(msg models.WebSocketMessage) {
+	var req SubscriptionRequest
+	if err := decodeMessageData(msg.Data, &req); err != nil || req.ID == "" {
+		c.sendError("", "Unsubscribe needs the subscription id")
+		return
+	}
+
+	c.subsMu.Lock()
+	sub, exists := c.subs[req.ID]
+	if exists {
+		sub.cancel()
+		delete(c.subs, req.ID)
+	}
+	c.subsMu.Unlock()
+
+	if !exists {
+		c.sendError(req.ID, "Unknown subscription")
+		return
+	}
+	c.sendStatus("unsubscribed", req.ID)
+}
+
+// closeSubscriptions stops every live query and waits for them to return,
+// so none sends after the hub closed the send channel
+func (c *Client) closeSubscriptions() {
+	c.cancel()
+	c.subsWG.Wait()
+}
+
+// subscriptionCount returns the number of live queries of the client
+func (c *Client) subscriptionCount() int {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	return len(c.subs)
+}
+
+func newSubscription(req SubscriptionRequest) (*subscription, error) {
+	if req.ID == "" {
+		return nil, fmt.Errorf("subscription id is required")
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	sub := &subscription{SubscriptionRequest: req, interval: defaultSubscriptionInterval}
+	if req.Interval > 0 {
+		sub.interval = time.Duration(req.Interval) * time.Second
+	}
+	if sub.interval < minSubscriptionInterval {
+		sub.interval = minSubscriptionInterval
+	}
+
+	switch req.Mode {
+	case "", ModeAggregate:
+		sub.Mode = ModeAggregate
+	case ModeTail:
+		if !strings.Contains(req.Query, ":since") && !strings.Contains(req.Query, "${since}") {
+			return nil, fmt.Errorf("tail queries must filter on :since, e.g. WHERE timestamp > :since")
+		}
+		sub.cursor = time.Now().UTC()
+		if req.Since != nil {
+			sub.cursor = req.Since.UTC()
+		}
+	default:
+		return nil, fmt.Errorf("unknown subscription mode: %s", req.Mode)
+	}
+	return sub, nil
+}
+
+// runSubscription runs the query right away and then every interval until
+// the subscription is cancelled
+func (c *Client) runSubscription(ctx context.Context, sub *subscription) {
+	defer c.subsWG.Done()
+
+	ticker := time.NewTicker(sub.interval)
+	defer ticker.Stop()
+
+	for {
+		if !c.isPaused {
+			c.refresh(ctx, sub)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh runs the query of a subscription as the client's user and sends
+// what changed since the last run
+func (c *Client) refresh(ctx context.Context, sub *subscription) {
+	req := &query.QueryRequest{
+		Query:      sub.Query,
+		Parameters: sub.Parameters,
+		Timeout:    subscriptionQueryTimeout,
+	}
+	if sub.Mode == ModeTail {
+		req.Parameters = make(map[string]interface{}, len(sub.Parameters)+1)
+		for name, value := range sub.Parameters {
+			req.Parameters[name] = value
+		}
+		req.Parameters["since"] = sub.cursor.Format(time.RFC3339Nano)
+		req.ParameterDefs = []query.QueryParameter{{Name: "since", Type: "date", Required: true}}
+	}
+
+	response, err := c.hub.queryEngine.Execute(auth.WithUser(ctx, c.user), req)
+	if ctx.Err() != nil {
+		return
+	}
+	if err == nil && response.Error != "" {
+		err = fmt.Errorf("%s", response.Error)
+	}
+	if err != nil {
+		c.notifyError(sub.ID, err.Error())
+		return
+	}
+
+	action := "replace"
+	if sub.Mode == ModeTail {
+		if response.RowCount == 0 {
+			return
+		}
+		newest, ok := newestTimestamp(response.Rows)
+		if !ok {
+			c.notifyError(sub.ID, "Tail queries must select the timestamp column")
+			return
+		}
+		if newest.After(sub.cursor) {
+			sub.cursor = newest
+		}
+		action = "append"
+	} else {
+		encoded, err := json.Marshal(response.Rows)
+		if err != nil {
+			return
+		}
+		hash := sha256.Sum256(encoded)
+		if hash == sub.lastHash {
+			return
+		}
+		sub.lastHash = hash
+	}
+
+	c.sendMessage(models.WebSocketMessage{
+		Type:   TypeQueryResult,
+		Action: action,
+		Data: QueryUpdate{
+			ID:            sub.ID,
+			Columns:       response.Columns,
+			Rows:          response.Rows,
+			RowCount:      response.RowCount,
+			ExecutionTime: response.ExecutionTime,
+			UpdatedAt:     time.Now().UTC(),
+		},
+	})
+}
+
+// newestTimestamp returns the latest timestamp column value of rows
+func newestTimestamp(rows []map[string]interface{}) (time.Time, bool) {
+	var newest time.Time
+	found := false
+	for _, row := range rows {
+		value, ok := row["timestamp"].(string)
+		if !ok {
+			continue
+		}
+		for _, layout := range []string{"2006-01-02 15:04:05.999999999", time.RFC3339Nano} {
+			if t, err := time.Parse(layout, value); err == nil {
+				if !found || t.After(newest) {
+					newest = t
+				}
+				found = true
+				break
+			}
+		}
+	}
+	return newest, found
+}
+
+// sendMessage queues a message unless the client left or its buffer is full
+func (c *Client) sendMessage(msg models.WebSocketMessage) {
+	msgBytes, err := encodeMessage(msg)
+	if err != nil {
+		return
+	}
+
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	if !c.hub.clients[c] {
+		return
+	}
+	c.enqueue(msgBytes)
+}
+
+// decodeMessageData converts the generic data of a message into v
+func decodeMessageData(data interface{}, v interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, v)
+}
@@ -0,0 +1,191 @@
+package websocket
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/search"
+)
+
+// maxTailAttributes bounds the attribute matchers of a tail
+const maxTailAttributes = 20
+
+// filterOperators are the operators of log filters and attribute matchers
+var filterOperators = map[string]bool{
+	"equals": true, "=": true,
+	"not_equals": true, "!=": true,
+	"contains": true, "not_contains": true,
+	"starts_with": true, "ends_with": true,
+}
+
+// TailRequest is the data of a tail message. A connection receives the
+// live logs matching every part given: one of the services, one of the
+// levels, all the attribute matchers and the search expression, in the
+// syntax of the search package. With Since, the buffered logs after that
+// cursor that match are sent first.
+type TailRequest struct {
+	Services   []string           `json:"services,omitempty"`
+	Levels     []string           `json:"levels,omitempty"`
+	Attributes []models.LogFilter `json:"attributes,omitempty"` // field is the attribute name
+	Query      string             `json:"query,omitempty"`
+	Since      *uint64            `json:"since,omitempty"`
+}
+
+// tail is the subscription a client's live logs are filtered by on the
+// server
+type tail struct {
+	services   map[string]bool
+	levels     map[string]bool
+	attributes []models.LogFilter
+	query      *search.Matcher
+}
+
+// handleTail replaces the subscription live logs are filtered by. Until a
+// client sends one it receives every log.
+func (c *Client) handleTail(msg models.WebSocketMessage) {
+	var req TailRequest
+	if err := decodeMessageData(msg.Data, &req); err != nil {
+		c.sendError("", "Invalid tail: "+err.Error())
+		return
+	}
+
+	t, err := newTail(req)
+	if err != nil {
+		c.sendError("", "Invalid tail: "+err.Error())
+		return
+	}
+
+	c.subsMu.Lock()
+	c.tail = t
+	c.channel = ""
+	c.subsMu.Unlock()
+
+	c.sendStatus("tailing", "Tail subscription updated")
+	log.Debug().Str("client_id", c.id).Interface("tail", req).Msg("Client tail updated")
+
+	if req.Since != nil {
+		c.replay(*req.Since)
+	}
+}
+
+// handleUntail drops the tail subscription, so the client receives every
+// log again
+func (c *Client) handleUntail() {
+	c.subsMu.Lock()
+	c.tail = nil
+	c.channel = ""
+	c.subsMu.Unlock()
+
+	c.sendStatus("untailed", "Tail subscription removed")
+}
+
+func newTail(req TailRequest) (*tail, error) {
+	if len(req.Attributes) > maxTailAttributes {
+		return nil, fmt.Errorf("at most %d attribute matchers", maxTailAttributes)
+	}
+	for _, matcher := range req.Attributes {
+		if matcher.Field == "" {
+			return nil, fmt.Errorf("attribute matchers need an attribute name")
+		}
+		if !filterOperators[matcher.Operator] {
+			return nil, fmt.Errorf("unknown operator %q of attribute %s", matcher.Operator, matcher.Field)
+		}
+	}
+
+	t := &tail{
+		services:   lowerSet(req.Services),
+		levels:     lowerSet(req.Levels),
+		attributes: req.Attributes,
+	}
+	if strings.TrimSpace(req.Query) != "" {
+		q, err := search.Parse(req.Query)
+		if err != nil {
+			return nil, err
+		}
+		t.query = q.Matcher()
+	}
+	return t, nil
+}
+
+// matches reports whether a log is one the tail subscribes to
+func (t *tail) matches(logEntry *models.Log) bool {
+	if len(t.services) > 0 && !t.services[strings.ToLower(logEntry.Service)] {
+		return false
+	}
+	if len(t.levels) > 0 && !t.levels[strings.ToLower(logEntry.Level)] {
+		return false
+	}
+	for _, matcher := range t.attributes {
+		value, _ := attributeValue(logEntry, matcher.Field)
+		if !matchValue(value, matcher) {
+			return false
+		}
+	}
+	if t.query != nil {
+		return t.query.Match(func(field string) (string, bool) {
+			return fieldValue(logEntry, field)
+		})
+	}
+	return true
+}
+
+// wantsLog reports whether a live log is sent to the client: it is not
+// paused and the log matches its filters and tail
+func (c *Client) wantsLog(logEntry *models.Log) bool {
+	if c.isPaused || !c.MatchesFilters(logEntry) {
+		return false
+	}
+	if ch, joined := c.joinedChannel(); joined {
+		// A deleted channel sends nothing
+		return ch != nil && ch.tail.matches(logEntry)
+	}
+	c.subsMu.Lock()
+	t := c.tail
+	c.subsMu.Unlock()
+	return t == nil || t.matches(logEntry)
+}
+
+// fieldValue returns a column or, for any other field, an attribute of a
+// log, and whether the log has it
+func fieldValue(logEntry *models.Log, field string) (string, bool) {
+	switch field {
+	case "level":
+		return logEntry.Level, true
+	case "service":
+		return logEntry.Service, true
+	case "message":
+		return logEntry.Message, true
+	case "trace_id":
+		return logEntry.TraceID, true
+	case "span_id":
+		return logEntry.SpanID, true
+	case "id":
+		return logEntry.ID, true
+	}
+	return attributeValue(logEntry, field)
+}
+
+// attributeValue returns an attribute of a log as text, as it is stored
+func attributeValue(logEntry *models.Log, name string) (string, bool) {
+	value, ok := logEntry.Attributes[name]
+	if !ok {
+		return "", false
+	}
+	if s, isString := value.(string); isString {
+		return s, true
+	}
+	return fmt.Sprint(value), true
+}
+
+func lowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			set[strings.ToLower(value)] = true
+		}
+	}
+	return set
+}
@@ -6,160 +6,85 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+
 	"github.com/your-username/click-lite-log-analytics/backend/internal/database"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/models"
-	"github.com/your-username/click-lite-log-analytics/backend/internal/query"
 )
 
-// LogTailer continuously polls for new logs and broadcasts them
+// maxCatchUpLogs bounds the logs a resuming client is caught up on from
+// the database
+const maxCatchUpLogs = replayBufferSize
+
+// CatchUpSource finds the stored logs between two times, oldest first
+type CatchUpSource interface {
+	CatchUp(ctx context.Context, after, before time.Time, limit int) ([]*models.Log, error)
+}
+
+// LogTailer reads back from the database the logs a resuming client missed
+// after they left the hub's replay buffer. Live logs reach the hub from the
+// ingestion pipeline as they are accepted; the database is only read when
+// a client reconnects too late for the buffer.
 type LogTailer struct {
-	db          *database.DB
-	hub         *Hub
-	pollInterval time.Duration
-	batchSize    int
+	db      *database.DB
+	timeout time.Duration
 }
 
-// NewLogTailer creates a new log tailer
-func NewLogTailer(db *database.DB, hub *Hub) *LogTailer {
+// NewLogTailer creates a log tailer reading from db
+func NewLogTailer(db *database.DB) *LogTailer {
 	return &LogTailer{
-		db:           db,
-		hub:          hub,
-		pollInterval: 1 * time.Second, // Poll every second
-		batchSize:    100,              // Fetch up to 100 logs per poll
+		db:      db,
+		timeout: 10 * time.Second,
 	}
 }
 
-// Start begins tailing logs
-func (lt *LogTailer) Start(ctx context.Context) {
-	ticker := time.NewTicker(lt.pollInterval)
-	defer ticker.Stop()
-
-	// Track the last seen timestamp to avoid duplicates
-	lastTimestamp := time.Now().Add(-5 * time.Second) // Start from 5 seconds ago
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Info().Msg("Log tailer stopping")
-			return
-		case <-ticker.C:
-			// Only fetch logs if there are active clients
-			if lt.hub.GetConnectedClients() == 0 {
-				continue
-			}
-
-			// Fetch new logs
-			logs, err := lt.fetchNewLogs(ctx, lastTimestamp)
-			if err != nil {
-				log.Error().Err(err).Msg("Failed to fetch new logs")
-				continue
-			}
-
-			// Broadcast logs to clients
-			for _, logEntry := range logs {
-				lt.hub.BroadcastToClients(logEntry)
-				
-				// Update last timestamp
-				if logEntry.Timestamp.After(lastTimestamp) {
-					lastTimestamp = logEntry.Timestamp
-				}
-			}
+// CatchUp returns up to limit of the logs stored after one time and before
+// another, oldest first
+func (lt *LogTailer) CatchUp(ctx context.Context, after, before time.Time, limit int) ([]*models.Log, error) {
+	ctx, cancel := context.WithTimeout(ctx, lt.timeout)
+	defer cancel()
+
+	const layout = "2006-01-02 15:04:05.000"
+	logs, err := lt.db.QueryLogs(ctx, &models.LogQuery{
+		StartTime: after.UTC().Truncate(time.Second),
+		EndTime:   before.UTC().Add(time.Second),
+		Filter: fmt.Sprintf("timestamp > toDateTime64('%s', 3) AND timestamp < toDateTime64('%s', 3)",
+			after.UTC().Format(layout), before.UTC().Format(layout)),
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read missed logs: %w", err)
+	}
 
-			if len(logs) > 0 {
-				log.Debug().
-					Int("count", len(logs)).
-					Time("last_timestamp", lastTimestamp).
-					Msg("Broadcasted new logs")
-			}
-		}
+	// The newest come first
+	caughtUp := make([]*models.Log, len(logs))
+	for i := range logs {
+		caughtUp[len(logs)-1-i] = &logs[i]
 	}
+	return caughtUp, nil
 }
 
-// fetchNewLogs fetches logs newer than the given timestamp using the query engine
-func (lt *LogTailer) fetchNewLogs(ctx context.Context, since time.Time) ([]*models.Log, error) {
-	// Create query request
-	queryText := fmt.Sprintf(`
-		SELECT 
-			toString(timestamp) as id,
-			timestamp,
-			level,
-			service,
-			message,
-			trace_id
-		FROM logs
-		WHERE timestamp > '%s'
-		ORDER BY timestamp ASC
-		LIMIT %d
-	`, since.Format("2006-01-02 15:04:05.999999"), lt.batchSize)
+// SetCatchUp makes resuming clients that give the time of the last log they
+// received catch up from source on the logs no longer in the replay buffer
+func (h *Hub) SetCatchUp(source CatchUpSource) {
+	h.catchUp = source
+}
 
-	// Get query engine and execute query
-	queryEngine := lt.db.GetQueryEngine()
-	if queryEngine == nil {
-		return nil, fmt.Errorf("query engine not available")
+// catchUp reads the logs a client resuming after cursor missed, if it gave
+// the time of the last log it received and some already left the replay
+// buffer. On failure the replay message reports the logs as missed.
+func (c *Client) catchUp(cursor uint64) []*models.Log {
+	if c.sinceTime == nil || c.hub.catchUp == nil {
+		return nil
 	}
-
-	req := &query.QueryRequest{
-		Query:   queryText,
-		Timeout: 10, // 10 second timeout
+	missed, before := c.hub.replay.evictedSince(cursor)
+	if missed == 0 {
+		return nil
 	}
 
-	response, err := queryEngine.Execute(ctx, req)
+	logs, err := c.hub.catchUp.CatchUp(c.ctx, *c.sinceTime, before, maxCatchUpLogs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
+		log.Error().Err(err).Str("client_id", c.id).Msg("Failed to catch up resuming client")
+		return nil
 	}
-
-	if response.Error != "" {
-		return nil, fmt.Errorf("query error: %s", response.Error)
-	}
-
-	// Convert response rows to models.Log
-	var logs []*models.Log
-	for _, row := range response.Rows {
-		entry := &models.Log{}
-
-		if id, ok := row["id"].(string); ok {
-			entry.ID = id
-		}
-		
-		if timestampStr, ok := row["timestamp"].(string); ok {
-			if ts, err := time.Parse("2006-01-02 15:04:05", timestampStr); err == nil {
-				entry.Timestamp = ts
-			}
-		}
-		
-		if level, ok := row["level"].(string); ok {
-			entry.Level = level
-		}
-		
-		if service, ok := row["service"].(string); ok {
-			entry.Service = service
-		}
-		
-		if message, ok := row["message"].(string); ok {
-			entry.Message = message
-		}
-		
-		if traceID, ok := row["trace_id"].(string); ok {
-			entry.TraceID = traceID
-		}
-
-		// Set empty attributes if none exist
-		if entry.Attributes == nil {
-			entry.Attributes = make(map[string]interface{})
-		}
-
-		logs = append(logs, entry)
-	}
-
-	return logs, nil
+	return logs
 }
-
-// SetPollInterval updates the polling interval
-func (lt *LogTailer) SetPollInterval(interval time.Duration) {
-	lt.pollInterval = interval
-}
-
-// SetBatchSize updates the batch size
-func (lt *LogTailer) SetBatchSize(size int) {
-	lt.batchSize = size
-}
\ No newline at end of file
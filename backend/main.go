@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
@@ -16,6 +17,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/your-username/click-lite-log-analytics/backend/internal/api"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/auth"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/cache"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/cluster"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/config"
@@ -24,9 +26,18 @@ import (
 	"github.com/your-username/click-lite-log-analytics/backend/internal/errors"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/export"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/ingestion"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/mining"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/monitoring"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/notification"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/optimization"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/parsing"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/query"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/querybuilder"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/scheduler"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/slo"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/storage"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/tables"
+	"github.com/your-username/click-lite-log-analytics/backend/internal/telemetry"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/tracing"
 	"github.com/your-username/click-lite-log-analytics/backend/internal/websocket"
 )
@@ -41,9 +52,11 @@ func main() {
 
 	// Setup logger
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	var logOutput io.Writer = os.Stderr
 	if os.Getenv("LOG_LEVEL") == "debug" {
 		zerolog.SetGlobalLevel(zerolog.DebugLevel)
-		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+		logOutput = zerolog.ConsoleWriter{Out: os.Stderr}
+		log.Logger = log.Output(logOutput)
 	} else {
 		zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	}
@@ -53,39 +66,276 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// Ingest the server's own logs, so that Click-Lite can be debugged with
+	// Click-Lite. They are queued until the pipeline starts.
+	var selfMonitor *ingestion.SelfMonitor
+	if cfg.SelfMonitor.Enabled {
+		level, err := zerolog.ParseLevel(cfg.SelfMonitor.Level)
+		if err != nil {
+			log.Warn().Err(err).Str("level", cfg.SelfMonitor.Level).Msg("Invalid self-monitoring level, using info")
+			level = zerolog.InfoLevel
+		}
+		selfMonitor = ingestion.NewSelfMonitor(ingestion.SelfMonitorOptions{
+			Service:      cfg.SelfMonitor.Service,
+			MinLevel:     level,
+			MaxPerSecond: cfg.SelfMonitor.MaxPerSecond,
+		})
+		log.Logger = log.Output(zerolog.MultiLevelWriter(logOutput, selfMonitor))
+	}
+
+	// Export traces and metrics about the server itself over OTLP
+	if cfg.Telemetry.OTLPEndpoint != "" {
+		shutdownTelemetry, err := telemetry.Setup(context.Background(), telemetry.Options{
+			Endpoint:       cfg.Telemetry.OTLPEndpoint,
+			ServiceName:    cfg.Telemetry.ServiceName,
+			ServiceVersion: version,
+			SampleRatio:    float64(cfg.Telemetry.SamplePercent) / 100,
+			MetricInterval: time.Duration(cfg.Telemetry.MetricIntervalMs) * time.Millisecond,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to initialize OpenTelemetry")
+		} else {
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				if err := shutdownTelemetry(ctx); err != nil {
+					log.Error().Err(err).Msg("Failed to flush OpenTelemetry data")
+				}
+			}()
+		}
+	}
+
 	// Initialize database
 	db, err := database.New(cfg.Database)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to initialize database")
 	}
 	defer db.Close()
+	db.GetQueryEngine().SetMaxLimit(cfg.Query.MaxLimit)
+	db.GetQueryEngine().SetAdmissionLimits(cfg.Query.MaxConcurrentPerUser, cfg.Query.MaxQueuedPerUser,
+		time.Duration(cfg.Query.QueueTimeoutSeconds)*time.Second)
+	db.GetQueryEngine().SetCacheOptions(query.ResultCacheOptions{
+		TTL:          time.Duration(cfg.Query.CacheTTLSeconds) * time.Second,
+		Bucket:       time.Duration(cfg.Query.CacheBucketSeconds) * time.Second,
+		RecentWindow: time.Duration(cfg.Query.CacheRecentSeconds) * time.Second,
+		RecentTTL:    time.Duration(cfg.Query.CacheRecentTTLSeconds) * time.Second,
+	})
+	db.GetQueryEngine().SetSlowQueryThreshold(time.Duration(cfg.Query.SlowThresholdMs) * time.Millisecond)
+	tablePatterns := cfg.Query.Tables
+	if len(tablePatterns) == 0 {
+		tablePatterns = tables.DefaultPatterns
+	}
+	lookups := []tables.Lookup{}
+	for _, spec := range cfg.Query.Lookups {
+		lookup, err := tables.ParseLookup(spec)
+		if err != nil {
+			log.Error().Err(err).Msg("Ignoring lookup table")
+			continue
+		}
+		lookups = append(lookups, lookup)
+	}
+	if err := db.GetQueryEngine().GetTables().SetLookups(lookups); err != nil {
+		log.Error().Err(err).Msg("Failed to register lookup tables")
+	}
+	if err := db.GetQueryEngine().GetTables().SetDiscovery(db, tablePatterns); err != nil {
+		log.Error().Err(err).Msg("Failed to discover queryable tables")
+	}
+	if cfg.Query.Storage == "clickhouse" {
+		queryStorage, err := query.NewClickHouseStorage(db)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to initialize saved query storage")
+		} else if err := db.GetQueryEngine().GetQueryStore().SetStorage(queryStorage); err != nil {
+			log.Error().Err(err).Msg("Failed to load saved queries")
+		}
+		if err := db.GetQueryEngine().GetSlowQueryLog().SetStorage(db); err != nil {
+			log.Error().Err(err).Msg("Failed to initialize slow query storage")
+		}
+	}
 
 	// Initialize WebSocket hub for real-time log tailing
 	wsHub := websocket.NewHub()
-	go wsHub.Run()
+	wsHub.SetQueryEngine(db.GetQueryEngine())
+	wsHub.SetCatchUp(websocket.NewLogTailer(db))
+	wsHub.SetDeliveryOptions(websocket.DeliveryOptions{
+		Compression:       cfg.Live.Compression,
+		CompressionLevel:  cfg.Live.CompressionLevel,
+		BatchMaxLatency:   time.Duration(cfg.Live.BatchMaxLatencyMs) * time.Millisecond,
+		BatchMaxMessages:  cfg.Live.BatchMaxMessages,
+		SendQueueSize:     cfg.Live.SendQueueSize,
+		SlowClientTimeout: time.Duration(cfg.Live.SlowClientSeconds) * time.Second,
+	})
+	if err := wsHub.Channels().SetStorage(websocket.NewFileChannelStorage(cfg.Live.ChannelsFile)); err != nil {
+		log.Error().Err(err).Msg("Failed to load tail channels")
+	}
 
-	// Initialize dashboard service (singleton for in-memory storage)
+	// Initialize dashboard service, persisting dashboards and share links
+	// along with saved queries
 	dashboardService := dashboard.NewService(db)
+	if cfg.Query.Storage == "clickhouse" {
+		if dashboardRepository, err := dashboard.NewClickHouseRepository(db); err != nil {
+			log.Error().Err(err).Msg("Failed to initialize dashboard storage")
+		} else if err := dashboardService.SetRepository(dashboardRepository); err != nil {
+			log.Error().Err(err).Msg("Failed to load dashboards")
+		}
+	}
+
+	// Refresh the widgets of dashboards watched over WebSocket on the server
+	dashboardRefresher := dashboard.NewRefreshScheduler(dashboardService)
+	defer dashboardRefresher.Stop()
+	wsHub.SetDashboardWatcher(dashboardRefresher)
+
+	// Initialize saved query builder configurations
+	queryBuilderConfigs := querybuilder.NewConfigStore(querybuilder.NewService(db.GetQueryEngine().GetTables()))
+	if cfg.Query.Storage == "clickhouse" {
+		if configStorage, err := querybuilder.NewClickHouseStorage(db); err != nil {
+			log.Error().Err(err).Msg("Failed to initialize query builder configuration storage")
+		} else if err := queryBuilderConfigs.SetStorage(configStorage); err != nil {
+			log.Error().Err(err).Msg("Failed to load query builder configurations")
+		}
+	}
+
+	// Initialize parsing rulesets
+	ruleSetRegistry := parsing.NewRuleSetRegistry()
+	switch cfg.Parsing.RuleSetsStorage {
+	case "clickhouse":
+		ruleSetStorage, err := parsing.NewClickHouseRuleSetStorage(db)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to initialize ruleset storage")
+		} else if err := ruleSetRegistry.SetStorage(ruleSetStorage); err != nil {
+			log.Error().Err(err).Msg("Failed to load parsing rulesets")
+		}
+	case "file":
+		if err := ruleSetRegistry.SetStorage(parsing.NewFileRuleSetStorage(cfg.Parsing.RuleSetsFile)); err != nil {
+			log.Error().Err(err).Msg("Failed to load parsing rulesets")
+		}
+	}
+
+	pluginStage := parsing.NewPluginStage()
+
+	// Initialize attribute schema registry
+	schemaRegistry := parsing.NewSchemaRegistry(parsing.DefaultSchemaMaxAttributes)
+	if schemaStorage, err := parsing.NewClickHouseSchemaStorage(db); err != nil {
+		log.Error().Err(err).Msg("Failed to initialize attribute schema storage")
+	} else if err := schemaRegistry.SetStorage(schemaStorage); err != nil {
+		log.Error().Err(err).Msg("Failed to load attribute schema")
+	}
 
 	// Initialize monitoring
 	metrics := monitoring.NewMetricsCollector()
+	metrics.SetMaxSeriesPerMetric(cfg.Metrics.MaxSeriesPerMetric)
+	metrics.SetSeriesTTL(time.Duration(cfg.Metrics.SeriesTTLSeconds) * time.Second)
+	for _, spec := range cfg.Metrics.SeriesLimits {
+		name, limit, err := monitoring.ParseCardinalityLimit(spec)
+		if err != nil {
+			log.Error().Err(err).Msg("Ignoring metric cardinality limit")
+			continue
+		}
+		metrics.SetCardinalityLimit(name, limit)
+	}
+	for _, spec := range cfg.Metrics.HistogramBuckets {
+		name, buckets, err := monitoring.ParseHistogramBuckets(spec)
+		if err != nil {
+			log.Error().Err(err).Msg("Ignoring histogram buckets")
+			continue
+		}
+		metrics.SetHistogramBuckets(name, buckets)
+	}
+	db.GetQueryEngine().SetMetrics(metrics)
+	metrics.SetDescription("validation_rejections", "Logs rejected by validation, by ruleset and rule")
+	metrics.SetDescription("parser_attempts", "Attempts to parse a log, by parser and outcome")
+	metrics.SetDescription("parse_duration_ms", "Log parsing duration in milliseconds, by the parser that handled the log")
+	metrics.SetDescription("transform_duration_ms", "Ruleset transformation duration in milliseconds, by parser")
+	metrics.SetDescription("format_cache_lookups", "Lookups of the format last seen from a log's source, by result")
 	metrics.SetDescription("total_logs_ingested", "Total number of logs ingested")
 	metrics.SetDescription("total_queries_executed", "Total number of queries executed")
 	metrics.SetDescription("query_duration_ms", "Query execution duration in milliseconds")
 	metrics.SetDescription("storage_size_bytes", "Storage size in bytes")
+	metrics.SetDescription("websocket_send_queue_depth", "Messages queued for all WebSocket clients")
+	metrics.SetDescription("websocket_send_queue_max_depth", "Messages queued for the slowest WebSocket client")
+	metrics.SetDescription("websocket_slow_clients", "WebSocket clients dropping messages from a full send queue")
+	metrics.SetDescription("websocket_dropped_messages", "Messages dropped from full WebSocket send queues")
+	metrics.SetDescription("websocket_slow_client_evictions", "WebSocket clients disconnected for staying slow")
+	wsHub.SetMetrics(metrics)
+	go wsHub.Run()
+	
+	quarantine, err := parsing.NewQuarantine(db, metrics, cfg.Parsing.Quarantine)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialize quarantine, rejected logs will only be counted")
+		quarantine, _ = parsing.NewQuarantine(db, metrics, false)
+	}
 	
 	healthMonitor := monitoring.NewHealthMonitor(version)
-	healthMonitor.RegisterChecker(monitoring.NewStorageHealthChecker("./data"))
-	healthMonitor.RegisterChecker(monitoring.NewAPIHealthChecker("http://localhost:"+cfg.Server.Port, 5*time.Second))
-	healthMonitor.RegisterChecker(monitoring.NewIngestionHealthChecker(metrics))
+	healthMonitor.RegisterChecker(monitoring.NewStorageHealthChecker("./data",
+		float64(cfg.Health.StorageDegradedFreePercent), float64(cfg.Health.StorageDownFreePercent)))
+	healthMonitor.RegisterChecker(monitoring.NewAPIHealthChecker("http://localhost:"+cfg.Server.Port+"/api/v1/monitoring/health/live", 5*time.Second))
+	healthMonitor.RegisterChecker(monitoring.NewClickHouseHealthChecker(db,
+		time.Duration(cfg.Health.ClickHouseTimeoutMs)*time.Millisecond, time.Duration(cfg.Health.ClickHouseSlowMs)*time.Millisecond))
+	healthMonitor.RegisterChecker(monitoring.NewWebSocketHubHealthChecker(wsHub, 30*time.Second, 0.5))
+	ingestionChecker := monitoring.NewIngestionHealthChecker(metrics)
+	healthMonitor.RegisterChecker(ingestionChecker)
 	healthMonitor.RegisterChecker(monitoring.NewQueryEngineHealthChecker(metrics))
 	
 	alertManager := monitoring.NewAlertManager(metrics)
+	if cfg.Query.Storage == "clickhouse" {
+		if err := alertManager.History().SetStorage(db); err != nil {
+			log.Error().Err(err).Msg("Failed to initialize alert history storage")
+		}
+	}
+	alertManager.SetQueryExecutor(db)
+	if err := alertManager.SetAnomalyRuleStorage(monitoring.NewAnomalyRuleFileStorage(cfg.Alerts.AnomalyRulesFile)); err != nil {
+		log.Error().Err(err).Msg("Failed to load anomaly rules")
+	}
+	forecaster, err := db.NewCapacityForecaster(cfg.Capacity.HistoryDays)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialize capacity forecasting")
+	} else {
+		alertManager.SetCapacityForecaster(forecaster)
+	}
+	if err := alertManager.SetCapacityRuleStorage(monitoring.NewCapacityRuleFileStorage(cfg.Alerts.CapacityRulesFile)); err != nil {
+		log.Error().Err(err).Msg("Failed to load capacity rules")
+	}
 	alertManager.AddListener(monitoring.NewLogAlertListener(log.Logger))
+	notifier := notification.NewNotifier()
+	if err := notifier.SetStorage(notification.NewFileStorage(cfg.Notifications.File)); err != nil {
+		log.Error().Err(err).Msg("Failed to load notification settings")
+	}
+	alertManager.AddListener(notifier)
+	sloManager := slo.NewManager(db, alertManager)
+	if err := sloManager.SetStorage(slo.NewFileStorage(cfg.SLO.File)); err != nil {
+		log.Error().Err(err).Msg("Failed to load SLOs")
+	}
+	absenceMonitor := monitoring.NewAbsenceMonitor(alertManager, time.Duration(cfg.Alerts.AbsenceMinutes)*time.Minute)
+	for _, spec := range cfg.Alerts.AbsenceServices {
+		service, after, err := monitoring.ParseAbsenceThreshold(spec)
+		if err != nil {
+			log.Error().Err(err).Msg("Ignoring absence threshold")
+			continue
+		}
+		absenceMonitor.SetServiceThreshold(service, after)
+	}
 	
 	// Initialize advanced features
 	traceManager := tracing.NewTraceManager()
+	if traceStorage, err := tracing.NewClickHouseTraceStorage(db, cfg.Traces.RetentionDays); err != nil {
+		log.Error().Err(err).Msg("Failed to initialize trace storage, traces are kept for an hour only")
+	} else {
+		traceManager.SetStorage(traceStorage)
+	}
 	errorDetector := errors.NewErrorDetector()
+	if err := errorDetector.SetPatternStorage(errors.NewPatternFileStorage(cfg.Errors.PatternsFile)); err != nil {
+		log.Error().Err(err).Msg("Failed to load error patterns, using the default ones")
+	}
+	if err := errorDetector.SetGroupStateStorage(errors.NewGroupStateFileStorage(cfg.Errors.GroupsFile)); err != nil {
+		log.Error().Err(err).Msg("Failed to load error group states, all groups start as new")
+	}
+	if err := errorDetector.SetBaselineStorage(errors.NewBaselineFileStorage(cfg.Errors.BaselinesFile)); err != nil {
+		log.Error().Err(err).Msg("Failed to load error rate baselines, starting without history")
+	}
+	if statsStorage, err := errors.NewClickHouseStatsStorage(db); err != nil {
+		log.Error().Err(err).Msg("Failed to initialize error stats storage")
+	} else if err := errorDetector.SetStatsStorage(statsStorage); err != nil {
+		log.Error().Err(err).Msg("Failed to load error stats")
+	}
 	exporter := export.NewExporter(db)
 	
 	// Initialize performance optimization components
@@ -121,8 +371,49 @@ func main() {
 			}
 		}
 	}()
-	logTailer := websocket.NewLogTailer(db, wsHub)
-	go logTailer.Start(ctx)
+	if cfg.StatsD.Address != "" {
+		statsd, err := monitoring.NewStatsDSink(metrics, monitoring.StatsDOptions{
+			Address:       cfg.StatsD.Address,
+			Prefix:        cfg.StatsD.Prefix,
+			Tags:          cfg.StatsD.Tags,
+			FlushInterval: time.Duration(cfg.StatsD.FlushIntervalMs) * time.Millisecond,
+			DogStatsD:     cfg.StatsD.DogStatsD,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to initialize StatsD sink")
+		} else {
+			go statsd.Start(ctx)
+		}
+	}
+	go dashboard.NewWidgetAlerter(dashboardService, alertManager).Start(ctx)
+	go sloManager.Start(ctx, time.Minute)
+	go absenceMonitor.Start(ctx, 30*time.Second)
+	if forecaster != nil {
+		go forecaster.Start(ctx, time.Duration(cfg.Capacity.SampleIntervalSeconds)*time.Second)
+	}
+	go metrics.StartSeriesEviction(ctx, time.Minute)
+	go dashboardService.StartShareCleanup(ctx, time.Hour)
+	go schemaRegistry.Start(ctx, time.Minute)
+	go quarantine.Start(ctx, 5*time.Second)
+	go errorDetector.Start(ctx, time.Duration(cfg.Errors.CheckpointIntervalSeconds)*time.Second)
+	go traceManager.Start(ctx, time.Duration(cfg.Traces.FlushIntervalSeconds)*time.Second)
+	go db.GetQueryEngine().GetTables().Start(ctx, time.Minute)
+
+	// Initialize scheduled queries
+	queryScheduler, err := scheduler.NewScheduler(db.GetQueryEngine(), db, alertManager, "./data/exports/scheduled")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialize query scheduler")
+	} else {
+		queryScheduler.SetReportRenderer(dashboardService)
+		if cfg.Query.Storage == "clickhouse" {
+			if scheduleStorage, err := scheduler.NewClickHouseStorage(db); err != nil {
+				log.Error().Err(err).Msg("Failed to initialize schedule storage")
+			} else if err := queryScheduler.SetStorage(scheduleStorage); err != nil {
+				log.Error().Err(err).Msg("Failed to load scheduled queries")
+			}
+		}
+		go queryScheduler.Start(ctx)
+	}
 
 	// Initialize batch processor for ingestion
 	batchProcessor := ingestion.NewBatchProcessor(db, 500, 5*time.Second)
@@ -130,13 +421,26 @@ func main() {
 	
 	// Set up log processor with trace and error detection
 	logProcessor := ingestion.NewLogProcessor(traceManager, errorDetector)
+	logProcessor.SetSchemaRegistry(schemaRegistry)
+	patternMiner := mining.NewMiner(cfg.Patterns.MaxPatterns, float64(cfg.Patterns.SimilarityPercent)/100)
+	logProcessor.SetPatternMiner(patternMiner)
 	batchProcessor.SetProcessor(logProcessor)
+	batchProcessor.SetMetrics(metrics)
+	batchProcessor.SetLiveSink(wsHub)
+	batchProcessor.SetAbsenceMonitor(absenceMonitor)
+	if selfMonitor != nil {
+		go selfMonitor.Start(ctx, batchProcessor)
+	}
+	healthMonitor.RegisterChecker(monitoring.NewBatchQueueHealthChecker(batchProcessor,
+		cfg.Health.BatchQueueDegradedDepth, cfg.Health.BatchQueueDownDepth))
+	ingestionChecker.SetBatchQueue(batchProcessor)
 
 	// Initialize ingestion handlers
-	httpHandler := ingestion.NewHTTPHandlerWithMetrics(batchProcessor, wsHub, metrics)
+	httpHandler := ingestion.NewHTTPHandlerWithMetrics(batchProcessor, metrics)
+	otlpHandler := ingestion.NewOTLPHandler(traceManager)
 	
 	// Start TCP server
-	tcpServer := ingestion.NewTCPServer(":20003", batchProcessor, wsHub)
+	tcpServer := ingestion.NewTCPServer(":20003", batchProcessor)
 	if err := tcpServer.Start(); err != nil {
 		log.Error().Err(err).Msg("Failed to start TCP server")
 	} else {
@@ -144,7 +448,7 @@ func main() {
 	}
 	
 	// Start Syslog server
-	syslogServer := ingestion.NewSyslogServer(":20004", batchProcessor, wsHub)
+	syslogServer := ingestion.NewSyslogServer(":20004", batchProcessor)
 	if err := syslogServer.Start(); err != nil {
 		log.Error().Err(err).Msg("Failed to start Syslog server")
 	} else {
@@ -159,13 +463,16 @@ func main() {
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	r.Use(monitoring.RequestMetrics(metrics))
+	r.Use(telemetry.HTTPMiddleware())
 	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(auth.Middleware(cfg.Auth.AdminToken))
 
 	// CORS
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "http://localhost:3001", "http://localhost:3002", "http://localhost:5173"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key", "X-User-ID", "X-Team-ID", "X-Admin-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300,
@@ -174,15 +481,44 @@ func main() {
 	// API routes
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Get("/health", api.HealthCheck(db))
-		r.Post("/logs", api.IngestLogs(db))
+		r.Post("/logs", api.IngestLogs(db, ruleSetRegistry, pluginStage, quarantine, logProcessor, wsHub, metrics, absenceMonitor))
 		r.Get("/logs", api.QueryLogs(db))
+		r.Get("/search", api.SearchLogs(db))
 		r.Get("/storage/stats", api.StorageStats(db))
+		r.Get("/storage/forecast", api.GetCapacityForecast(forecaster))
 		r.HandleFunc("/ws", websocket.HandleWebSocket(wsHub))
 		r.Get("/ws/stats", api.WebSocketStats(wsHub))
+		r.Delete("/ws/clients/{id}", api.DisconnectWebSocketClient(wsHub))
+		r.Get("/ws/schema", api.WebSocketSchema())
+		r.Route("/ws/channels", func(r chi.Router) {
+			r.Get("/", api.ListTailChannels(wsHub))
+			r.Post("/", api.CreateTailChannel(wsHub))
+			r.Get("/{name}", api.GetTailChannel(wsHub))
+			r.Put("/{name}", api.UpdateTailChannel(wsHub))
+			r.Delete("/{name}", api.DeleteTailChannel(wsHub))
+		})
+		r.Route("/ws/recordings", func(r chi.Router) {
+			r.Get("/", api.ListTailRecordings(wsHub))
+			r.Get("/{id}", api.GetTailRecording(wsHub))
+			r.Delete("/{id}", api.DeleteTailRecording(wsHub))
+			r.Get("/{id}/export", api.NewExportHandler(exporter).ExportTailRecording(wsHub))
+		})
 		
 		// SQL Query endpoints
 		r.Route("/query", func(r chi.Router) {
 			r.Post("/execute", api.ExecuteQuery(db))
+			r.Post("/stream", api.StreamQuery(db))
+			r.Post("/explain", api.ExplainQuery(db))
+			r.Post("/timeseries", api.QueryTimeSeries(db))
+			r.Get("/admission", api.GetQueryAdmissionStats(db))
+			r.Get("/cache", api.GetQueryCacheStats(db))
+			r.Get("/slow", api.ListSlowQueries(db))
+			r.Get("/slow/{id}", api.GetSlowQuery(db))
+			r.Get("/patterns", api.ListQueryPatterns(db))
+			r.Get("/patterns/{fingerprint}", api.GetQueryPattern(db))
+			r.Get("/tables", api.ListTables(db))
+			r.Post("/tables/refresh", api.RefreshTables(db))
+			r.Get("/tables/{name}", api.GetTable(db))
 			r.Get("/saved", api.ListQueries(db))
 			r.Post("/saved", api.SaveQuery(db))
 			r.Get("/saved/{id}", api.GetQuery(db))
@@ -190,14 +526,45 @@ func main() {
 			r.Delete("/saved/{id}", api.DeleteQuery(db))
 			r.Post("/saved/{id}/execute", api.ExecuteSavedQuery(db))
 			r.Get("/saved/{id}/execute", api.ExecuteSavedQuery(db))
+			r.Get("/saved/{id}/parameters", api.GetQueryParameters(db))
+			r.Post("/saved/{id}/share", api.ShareQuery(db))
+			r.Get("/shared/{token}", api.GetSharedQuery(db))
+			r.Get("/shared/{token}/execute", api.ExecuteSharedQuery(db))
+			r.Post("/shared/{token}/execute", api.ExecuteSharedQuery(db))
+			r.Post("/jobs", api.SubmitQueryJob(db))
+			r.Get("/jobs", api.ListQueryJobs(db))
+			r.Get("/jobs/{id}", api.GetQueryJob(db))
+			r.Get("/jobs/{id}/results", api.GetQueryJobResults(db))
+			r.Delete("/jobs/{id}", api.CancelQueryJob(db))
+
+			if queryScheduler != nil {
+				scheduleHandler := api.NewScheduleHandler(queryScheduler)
+				r.Get("/schedules", scheduleHandler.ListSchedules)
+				r.Post("/schedules", scheduleHandler.CreateSchedule)
+				r.Get("/schedules/{id}", scheduleHandler.GetSchedule)
+				r.Put("/schedules/{id}", scheduleHandler.UpdateSchedule)
+				r.Delete("/schedules/{id}", scheduleHandler.DeleteSchedule)
+				r.Post("/schedules/{id}/run", scheduleHandler.RunSchedule)
+				r.Get("/schedules/{id}/runs", scheduleHandler.GetScheduleRuns)
+				r.Get("/schedules/{id}/results", scheduleHandler.GetScheduleResults)
+			}
 		})
 
 		// Query Builder endpoints
 		r.Route("/query-builder", func(r chi.Router) {
-			r.Get("/fields", api.GetAvailableFields(db))
+			r.Get("/fields", api.GetAvailableFields(db, schemaRegistry))
+			r.Get("/fields/{field}/values", api.GetFieldValues(db))
 			r.Post("/generate-sql", api.GenerateSQL(db))
 			r.Post("/execute", api.ExecuteQueryBuilder(db))
 			r.Post("/validate", api.ValidateQueryBuilder(db))
+
+			configHandler := api.NewQueryBuilderConfigHandler(db, queryBuilderConfigs)
+			r.Get("/configs", configHandler.ListConfigs)
+			r.Post("/configs", configHandler.CreateConfig)
+			r.Get("/configs/{id}", configHandler.GetConfig)
+			r.Put("/configs/{id}", configHandler.UpdateConfig)
+			r.Delete("/configs/{id}", configHandler.DeleteConfig)
+			r.Post("/configs/{id}/saved-query", configHandler.ConvertToSavedQuery)
 		})
 
 		// Dashboard endpoints
@@ -208,18 +575,59 @@ func main() {
 			r.Put("/{id}", api.UpdateDashboard(dashboardService))
 			r.Delete("/{id}", api.DeleteDashboard(dashboardService))
 			r.Post("/{id}/share", api.ShareDashboard(dashboardService))
+			r.Get("/{id}/shares", api.ListDashboardShares(dashboardService))
+			r.Put("/{id}/shares/{share_id}", api.UpdateDashboardShare(dashboardService))
+			r.Delete("/{id}/shares/{share_id}", api.RevokeDashboardShare(dashboardService))
+			r.Get("/{id}/shares/{share_id}/accesses", api.GetDashboardShareAccesses(dashboardService))
+			r.Post("/{id}/move", api.MoveDashboard(dashboardService))
+			r.Post("/{id}/clone", api.CloneDashboard(dashboardService))
+			r.Get("/{id}/permissions", api.GetDashboardPermissions(dashboardService))
+			r.Put("/{id}/permissions", api.SetDashboardPermissions(dashboardService))
 			r.Get("/{dashboard_id}/widgets/{widget_id}/query", api.ExecuteWidgetQuery(dashboardService))
 			r.Get("/{dashboard_id}/widgets/{widget_id}/data", api.GetWidgetData(dashboardService))
+			r.Get("/{dashboard_id}/widgets/{widget_id}/drilldown", api.GetWidgetDrillDown(dashboardService))
+			r.Get("/{id}/annotations", api.ListDashboardAnnotations(dashboardService))
+			r.Post("/{id}/annotations", api.CreateDashboardAnnotation(dashboardService))
+			r.Get("/{id}/snapshots", api.ListDashboardSnapshots(dashboardService))
+			r.Post("/{id}/snapshots", api.CreateDashboardSnapshot(dashboardService))
+			r.Get("/{id}/report", api.GetDashboardReport(dashboardService))
+			r.Get("/{id}/data", api.GetDashboardData(dashboardService))
+			r.Get("/{id}/layout", api.GetDashboardLayout(dashboardService))
+		})
+
+		// Dashboard snapshot endpoints
+		r.Get("/snapshots/{id}", api.GetDashboardSnapshot(dashboardService))
+		r.Delete("/snapshots/{id}", api.DeleteDashboardSnapshot(dashboardService))
+
+		// Annotation endpoints, e.g. deploy markers from CI
+		r.Post("/annotations", api.CreateAnnotation(dashboardService))
+		r.Delete("/annotations/{id}", api.DeleteAnnotation(dashboardService))
+		r.Post("/alerts/alertmanager", api.ReceiveAlertmanagerWebhook(dashboardService, cfg.Alerts.WebhookToken))
+
+		// Dashboard folder endpoints
+		r.Route("/dashboard-folders", func(r chi.Router) {
+			r.Get("/", api.ListDashboardFolders(dashboardService))
+			r.Post("/", api.CreateDashboardFolder(dashboardService))
+			r.Get("/{id}", api.GetDashboardFolder(dashboardService))
+			r.Put("/{id}", api.UpdateDashboardFolder(dashboardService))
+			r.Delete("/{id}", api.DeleteDashboardFolder(dashboardService))
+			r.Get("/{id}/permissions", api.GetDashboardFolderPermissions(dashboardService))
+			r.Put("/{id}/permissions", api.SetDashboardFolderPermissions(dashboardService))
 		})
 
 		// Shared dashboard endpoints
 		r.Get("/shared/{token}", api.GetSharedDashboard(dashboardService))
+		r.Get("/shared/{token}/data", api.SharedDashboardRoute(dashboardService, "data", api.GetDashboardData(dashboardService)))
+		r.Get("/shared/{token}/widgets/{widget_id}/data", api.SharedDashboardRoute(dashboardService, "widget_data", api.GetWidgetData(dashboardService)))
+		r.Get("/shared/{token}/widgets/{widget_id}/query", api.SharedDashboardRoute(dashboardService, "widget_query", api.ExecuteWidgetQuery(dashboardService)))
+		r.Get("/shared/snapshots/{token}", api.GetSharedSnapshot(dashboardService))
 		
 		// Ingestion endpoints
 		r.Route("/ingest", func(r chi.Router) {
 			r.Get("/health", httpHandler.HealthCheck())
 			r.Post("/logs", httpHandler.IngestLogs())
 			r.Post("/bulk", httpHandler.BulkIngestLogs())
+			r.Post("/otlp/v1/traces", otlpHandler.IngestTraces())
 		})
 		
 		// Monitoring endpoints
@@ -228,8 +636,87 @@ func main() {
 			r.Get("/health/live", healthMonitor.LivenessHandler())
 			r.Get("/health/ready", healthMonitor.ReadinessHandler())
 			r.Get("/metrics", api.GetMetrics(metrics))
+			r.Get("/metrics/cardinality", api.GetMetricCardinality(metrics))
+			r.Get("/ingestion", api.GetIngestionStats(batchProcessor))
 			r.Get("/alerts", api.GetAlerts(alertManager))
 			r.Get("/alerts/active", api.GetActiveAlerts(alertManager))
+			r.Get("/alerts/history", api.GetAlertHistory(alertManager))
+			r.Get("/alerts/timeline", api.GetAlertTimeline(alertManager))
+			r.Get("/alerts/anomaly-rules", api.ListAnomalyRules(alertManager))
+			r.Post("/alerts/anomaly-rules", api.CreateAnomalyRule(alertManager))
+			r.Get("/alerts/anomaly-rules/{name}", api.GetAnomalyRule(alertManager))
+			r.Delete("/alerts/anomaly-rules/{name}", api.DeleteAnomalyRule(alertManager))
+			r.Get("/alerts/capacity-rules", api.ListCapacityRules(alertManager))
+			r.Post("/alerts/capacity-rules", api.CreateCapacityRule(alertManager))
+			r.Delete("/alerts/capacity-rules/{name}", api.DeleteCapacityRule(alertManager))
+			r.Get("/services/activity", api.ListServiceActivity(absenceMonitor))
+		})
+
+		// Alert notification endpoints
+		r.Route("/notifications", func(r chi.Router) {
+			r.Get("/channels", api.ListNotificationChannels(notifier))
+			r.Post("/channels", api.CreateNotificationChannel(notifier))
+			r.Post("/channels/test", api.TestNotificationConfig(notifier))
+			r.Get("/channels/{name}", api.GetNotificationChannel(notifier))
+			r.Put("/channels/{name}", api.UpdateNotificationChannel(notifier))
+			r.Delete("/channels/{name}", api.DeleteNotificationChannel(notifier))
+			r.Post("/channels/{name}/test", api.TestNotificationChannel(notifier))
+			r.Get("/routes", api.GetNotificationRoutes(notifier))
+			r.Put("/routes", api.SetNotificationRoutes(notifier))
+			r.Get("/deliveries", api.ListNotificationDeliveries(notifier))
+		})
+
+		// Service level objective endpoints
+		r.Route("/slos", func(r chi.Router) {
+			r.Get("/", api.ListSLOs(sloManager))
+			r.Post("/", api.CreateSLO(sloManager))
+			r.Get("/status", api.ListSLOStatuses(sloManager))
+			r.Get("/{name}", api.GetSLO(sloManager))
+			r.Put("/{name}", api.UpdateSLO(sloManager))
+			r.Delete("/{name}", api.DeleteSLO(sloManager))
+			r.Get("/{name}/status", api.GetSLOStatus(sloManager))
+		})
+		
+		// Parsing ruleset endpoints
+		ruleSetHandler := api.NewRuleSetHandler(ruleSetRegistry)
+		r.Route("/rulesets", func(r chi.Router) {
+			r.Get("/", ruleSetHandler.ListRuleSets)
+			r.Post("/", ruleSetHandler.CreateRuleSet)
+			r.Get("/resolve", ruleSetHandler.ResolveRuleSet)
+			r.Get("/{name}", ruleSetHandler.GetRuleSet)
+			r.Put("/{name}", ruleSetHandler.UpdateRuleSet)
+			r.Delete("/{name}", ruleSetHandler.DeleteRuleSet)
+			r.Get("/{name}/versions", ruleSetHandler.GetRuleSetVersions)
+			r.Post("/{name}/rollback", ruleSetHandler.RollbackRuleSet)
+		})
+		
+		// Attribute schema endpoints
+		schemaHandler := api.NewSchemaHandler(schemaRegistry)
+		r.Get("/schema/attributes", schemaHandler.GetAttributes)
+		r.Get("/schema/autocomplete", api.NewAutocompleteHandler(db, schemaRegistry).GetMetadata)
+		
+		// Quarantine endpoints
+		quarantineHandler := api.NewQuarantineHandler(quarantine)
+		r.Route("/quarantine", func(r chi.Router) {
+			r.Get("/", quarantineHandler.ListQuarantined)
+			r.Get("/stats", quarantineHandler.GetQuarantineStats)
+		})
+		
+		// Parsing plugin endpoints
+		pluginHandler := api.NewPluginHandler(pluginStage)
+		r.Route("/plugins", func(r chi.Router) {
+			r.Get("/", pluginHandler.ListPlugins)
+			r.Post("/", pluginHandler.SavePlugin)
+			r.Post("/test", pluginHandler.TestPlugin)
+			r.Get("/{name}", pluginHandler.GetPlugin)
+			r.Put("/{name}", pluginHandler.SavePlugin)
+			r.Delete("/{name}", pluginHandler.DeletePlugin)
+		})
+		
+		// Log templates discovered at ingestion
+		r.Route("/log-patterns", func(r chi.Router) {
+			r.Get("/", api.ListLogPatterns(patternMiner))
+			r.Get("/{id}", api.GetLogPattern(patternMiner))
 		})
 		
 		// Trace correlation endpoints
@@ -242,10 +729,23 @@ func main() {
 		
 		// Error detection endpoints
 		errorHandler := api.NewErrorHandler(errorDetector)
+		errorHandler.SetTraceManager(traceManager)
 		r.Route("/errors", func(r chi.Router) {
 			r.Get("/stats", errorHandler.GetErrorStats)
 			r.Get("/anomalies", errorHandler.GetErrorAnomalies)
 			r.Get("/trends", errorHandler.GetErrorTrends)
+			r.Get("/{pattern}/samples", errorHandler.GetErrorSamples)
+			r.Get("/groups", errorHandler.ListErrorGroups)
+			r.Get("/groups/{fingerprint}", errorHandler.GetErrorGroup)
+			r.Patch("/groups/{fingerprint}", errorHandler.UpdateErrorGroup)
+			r.Get("/groups/{fingerprint}/incident", errorHandler.GetErrorGroupIncident)
+			r.Get("/baselines", errorHandler.ListServiceBaselines)
+			r.Get("/baselines/{service}", errorHandler.GetServiceBaseline)
+			r.Get("/patterns", errorHandler.ListPatterns)
+			r.Post("/patterns", errorHandler.CreatePattern)
+			r.Get("/patterns/{name}", errorHandler.GetPattern)
+			r.Put("/patterns/{name}", errorHandler.UpdatePattern)
+			r.Delete("/patterns/{name}", errorHandler.DeletePattern)
 		})
 		
 		// Export endpoints
@@ -257,6 +757,7 @@ func main() {
 		
 		// Performance optimization endpoints
 		performanceHandler := api.NewPerformanceHandlerChi(queryOptimizer, storageOptimizer, coordinator, statsCache)
+		performanceHandler.SetQueryEngine(db.GetQueryEngine())
 		r.Route("/performance", func(r chi.Router) {
 			// Query optimization
 			r.Post("/optimize-query", performanceHandler.OptimizeQuery)
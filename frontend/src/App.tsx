@@ -10,6 +10,7 @@ import { LogStreamEnhanced } from './components/LogStream/LogStreamEnhanced';
 import { MonitoringPage } from './pages/MonitoringPage';
 import { TracePage } from './pages/TracePage';
 import { ErrorDashboard } from './pages/ErrorDashboard';
+import { PatternsPage } from './pages/PatternsPage';
 import PerformancePage from './pages/PerformancePage';
 
 const theme = createTheme({
@@ -51,6 +52,7 @@ function App() {
               <Route path="monitoring" element={<MonitoringPage />} />
               <Route path="traces" element={<TracePage />} />
               <Route path="errors" element={<ErrorDashboard />} />
+              <Route path="patterns" element={<PatternsPage />} />
               <Route path="performance" element={<PerformancePage />} />
             </Route>
           </Routes>
@@ -25,7 +25,7 @@ import { useParams, useNavigate } from 'react-router-dom';
 import { DashboardWidget } from './DashboardWidget';
 import { AddWidgetDialog } from './AddWidgetDialog';
 import { dashboardApi, handleApiError } from '../../services/api';
-import { Dashboard, DashboardWidget as WidgetType } from '../../types/api';
+import { Dashboard, DashboardWidget as WidgetType, DashboardWidgetResult } from '../../types/api';
 
 export const DashboardView: React.FC = () => {
   const { id } = useParams<{ id: string }>();
@@ -35,6 +35,10 @@ export const DashboardView: React.FC = () => {
   const [refreshing, setRefreshing] = useState(false);
   const [error, setError] = useState<string | null>(null);
   const [addWidgetOpen, setAddWidgetOpen] = useState(false);
+  // Widget data loaded for the whole dashboard in one request; widgets
+  // load their own while it is missing
+  const [widgetResults, setWidgetResults] = useState<Record<string, DashboardWidgetResult>>({});
+  const [loadingWidgets, setLoadingWidgets] = useState(false);
 
   useEffect(() => {
     if (id) {
@@ -50,6 +54,7 @@ export const DashboardView: React.FC = () => {
       const dashboardData = await dashboardApi.get(id);
       setDashboard(dashboardData);
       setError(null);
+      loadWidgetResults();
     } catch (err) {
       setError(handleApiError(err));
     } finally {
@@ -57,11 +62,29 @@ export const DashboardView: React.FC = () => {
     }
   };
 
+  const loadWidgetResults = async (refresh = false) => {
+    if (!id) return;
+
+    try {
+      setLoadingWidgets(true);
+      const response = await dashboardApi.getDashboardData(id, undefined, refresh);
+      const results: Record<string, DashboardWidgetResult> = {};
+      response.widgets.forEach((result) => {
+        results[result.widget_id] = result;
+      });
+      setWidgetResults(results);
+    } catch (err) {
+      // Widgets fall back to loading their own data
+      setWidgetResults({});
+    } finally {
+      setLoadingWidgets(false);
+    }
+  };
+
   const refreshAllWidgets = async () => {
     setRefreshing(true);
-    // Trigger refresh on all widgets
-    // This would be implemented by each widget component
-    setTimeout(() => setRefreshing(false), 1000);
+    await loadWidgetResults(true);
+    setRefreshing(false);
   };
 
   const handleShare = async () => {
@@ -81,9 +104,12 @@ export const DashboardView: React.FC = () => {
   const handleAddWidget = async (widget: Omit<WidgetType, 'id'>) => {
     if (!dashboard) return;
 
+    // New widgets go below the others, which the server requires not to overlap
+    const bottom = Math.max(0, ...dashboard.widgets.map((w) => w.position.y + w.size.height));
     const newWidget: WidgetType = {
       ...widget,
       id: `widget-${Date.now()}`,
+      position: { x: 0, y: bottom },
     };
 
     try {
@@ -258,6 +284,8 @@ export const DashboardView: React.FC = () => {
                 <DashboardWidget
                   widget={widget}
                   dashboardId={dashboard.id!}
+                  result={widgetResults[widget.id]}
+                  awaitingResult={loadingWidgets}
                   onUpdate={(updates) => handleUpdateWidget(widget.id, updates)}
                   onDelete={() => handleDeleteWidget(widget.id)}
                 />
@@ -21,19 +21,28 @@ import { TableWidget } from './TableWidget';
 import { MetricWidget } from './MetricWidget';
 import { TextWidget } from './TextWidget';
 import { dashboardApi, handleApiError } from '../../services/api';
-import { DashboardWidget as WidgetType } from '../../types/api';
+import { DashboardWidget as WidgetType, DashboardWidgetResult, WidgetCacheInfo } from '../../types/api';
 
 interface DashboardWidgetProps {
   widget: WidgetType;
   dashboardId: string;
+  // Data loaded with the rest of the dashboard, used instead of loading it
+  result?: DashboardWidgetResult;
+  awaitingResult?: boolean;
   onUpdate?: (updates: Partial<WidgetType>) => void;
   onDelete: () => void;
   isEditing?: boolean;
 }
 
+// When the data shown was loaded, which for cached data is before it was served
+const loadedAt = (cache?: WidgetCacheInfo): Date =>
+  cache ? new Date(Date.now() - cache.age * 1000) : new Date();
+
 export const DashboardWidget: React.FC<DashboardWidgetProps> = ({
   widget,
   dashboardId,
+  result,
+  awaitingResult = false,
   onUpdate,
   onDelete,
   isEditing = false,
@@ -43,30 +52,45 @@ export const DashboardWidget: React.FC<DashboardWidgetProps> = ({
   const [error, setError] = useState<string | null>(null);
   const [menuAnchor, setMenuAnchor] = useState<HTMLElement | null>(null);
   const [lastRefresh, setLastRefresh] = useState<Date>(new Date());
+  const [stale, setStale] = useState(false);
 
   useEffect(() => {
-    loadWidgetData();
-  }, [widget.id, dashboardId, loadWidgetData]);
+    if (result) {
+      setData(result.data ?? null);
+      setError(result.error ?? null);
+      setLastRefresh(loadedAt(result.cache));
+      setStale(result.cache?.stale ?? false);
+      setLoading(false);
+    } else if (!awaitingResult) {
+      loadWidgetData();
+    }
+  }, [widget.id, dashboardId, result, awaitingResult, loadWidgetData]);
+
+  // Refreshing faster than the query accepts cached results would only
+  // fetch the same result again
+  const freshness = (widget.data_source.query_builder?.freshness ?? 0) * 1000;
+  const refreshRate = widget.refresh_rate ? Math.max(widget.refresh_rate, freshness) : 0;
 
   useEffect(() => {
     // Set up auto-refresh if specified
-    if (widget.refresh_rate && widget.refresh_rate > 0) {
+    if (refreshRate > 0) {
       const interval = setInterval(() => {
         loadWidgetData();
-      }, widget.refresh_rate);
+      }, refreshRate);
 
       return () => clearInterval(interval);
     }
-  }, [widget.refresh_rate, loadWidgetData]);
+  }, [refreshRate, loadWidgetData]);
 
-  const loadWidgetData = async () => {
+  const loadWidgetData = async (refresh = false) => {
     try {
       setLoading(true);
       setError(null);
       
-      const response = await dashboardApi.getWidgetData(dashboardId, widget.id);
+      const response = await dashboardApi.getWidgetData(dashboardId, widget.id, refresh);
       setData(response.data);
-      setLastRefresh(new Date());
+      setLastRefresh(loadedAt(response.cache));
+      setStale(response.cache?.stale ?? false);
     } catch (err) {
       setError(handleApiError(err));
     } finally {
@@ -83,7 +107,7 @@ export const DashboardWidget: React.FC<DashboardWidgetProps> = ({
   };
 
   const handleRefresh = () => {
-    loadWidgetData();
+    loadWidgetData(true);
     handleMenuClose();
   };
 
@@ -202,6 +226,7 @@ export const DashboardWidget: React.FC<DashboardWidgetProps> = ({
           </Typography>
           <Typography variant="caption" color="text.secondary">
             {getRefreshRateLabel(widget.refresh_rate)} • Updated {formatLastRefresh(lastRefresh)}
+            {stale && ' • Refreshing'}
           </Typography>
         </Box>
 
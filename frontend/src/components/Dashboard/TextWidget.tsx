@@ -3,13 +3,36 @@ import {
   Box,
   Typography,
 } from '@mui/material';
+import { TextData } from '../../types/api';
 
 interface TextWidgetProps {
   data: unknown;
   config: Record<string, unknown>;
 }
 
+const isTextData = (data: unknown): data is TextData =>
+  typeof data === 'object' && data !== null && typeof (data as TextData).html === 'string';
+
 export const TextWidget: React.FC<TextWidgetProps> = ({ data, config }) => {
+  // Markdown content is rendered, and escaped, by the server
+  if (isTextData(data)) {
+    return (
+      <Box
+        sx={{
+          p: 2,
+          height: '100%',
+          overflow: 'auto',
+          textAlign: ((config?.text_align as string) || 'left') as any,
+          '& table': { borderCollapse: 'collapse' },
+          '& th, & td': { border: 1, borderColor: 'divider', px: 1 },
+          '& pre': { overflow: 'auto' },
+        }}
+        title={data.errors?.join('\n')}
+        dangerouslySetInnerHTML={{ __html: data.html }}
+      />
+    );
+  }
+
   let content: string = '';
 
   if (typeof data === 'string') {
@@ -22,6 +22,7 @@ import {
   MonitorHeart,
   AccountTree,
   BugReport,
+  Category,
   Speed,
 } from '@mui/icons-material';
 import { useNavigate, useLocation, Outlet } from 'react-router-dom';
@@ -36,6 +37,7 @@ const navigation = [
   { name: 'Monitoring', path: '/monitoring', icon: MonitorHeart },
   { name: 'Traces', path: '/traces', icon: AccountTree },
   { name: 'Error Dashboard', path: '/errors', icon: BugReport },
+  { name: 'Patterns', path: '/patterns', icon: Category },
   { name: 'Performance', path: '/performance', icon: Speed },
 ];
 
@@ -1,9 +1,10 @@
 import { useEffect, useRef, useState, useCallback } from 'react';
-import { Log, LogFilter, WebSocketMessage } from '../types/log';
+import { LiveStats, Log, LogFilter, LogHighlight, TailSubscription, WebSocketMessage } from '../types/log';
 
 interface UseWebSocketOptions {
   url: string;
-  onMessage?: (log: Log) => void;
+  onMessage?: (log: Log, highlights?: LogHighlight[]) => void;
+  onLiveStats?: (stats: LiveStats) => void;
   onConnect?: () => void;
   onDisconnect?: () => void;
   onError?: (error: Event) => void;
@@ -16,6 +17,13 @@ interface UseWebSocketReturn {
   pause: () => void;
   resume: () => void;
   setFilters: (filters: LogFilter[]) => void;
+  setTail: (tail: TailSubscription | null) => void;
+  joinChannel: (channel: string) => void;
+  leaveChannel: () => void;
+  startRecording: (name?: string) => void;
+  stopRecording: () => void;
+  subscribeLiveStats: (interval?: number) => void;
+  unsubscribeLiveStats: () => void;
   disconnect: () => void;
   reconnect: () => void;
 }
@@ -23,6 +31,7 @@ interface UseWebSocketReturn {
 export const useWebSocket = ({
   url,
   onMessage,
+  onLiveStats,
   onConnect,
   onDisconnect,
   onError,
@@ -32,6 +41,10 @@ export const useWebSocket = ({
   const [isPaused, setIsPaused] = useState(false);
   const reconnectTimeout = useRef<number | null>(null);
   const reconnectAttempts = useRef(0);
+  // Cursor and time of the last log received, so a reconnect resumes after
+  // it, from the database if the server no longer buffers what followed
+  const lastSeq = useRef<number | null>(null);
+  const lastTimestamp = useRef<string | null>(null);
 
   const connect = useCallback(() => {
     if (ws.current?.readyState === WebSocket.OPEN) {
@@ -39,7 +52,15 @@ export const useWebSocket = ({
     }
 
     try {
-      ws.current = new WebSocket(url);
+      const separator = url.includes('?') ? '&' : '?';
+      let resumeUrl = url;
+      if (lastSeq.current !== null) {
+        resumeUrl += `${separator}since=${lastSeq.current}`;
+        if (lastTimestamp.current) {
+          resumeUrl += `&since_time=${encodeURIComponent(lastTimestamp.current)}`;
+        }
+      }
+      ws.current = new WebSocket(resumeUrl);
 
       ws.current.onopen = () => {
         setIsConnected(true);
@@ -64,15 +85,27 @@ export const useWebSocket = ({
         onError?.(error);
       };
 
+      const handleMessage = (message: WebSocketMessage) => {
+        if (message.type === 'batch' && Array.isArray(message.data)) {
+          (message.data as WebSocketMessage[]).forEach(handleMessage);
+        } else if (message.type === 'log' && message.data) {
+          if (message.seq !== undefined) {
+            lastSeq.current = message.seq;
+          }
+          lastTimestamp.current = (message.data as Log).timestamp;
+          onMessage?.(message.data as Log, message.highlights);
+        } else if (message.type === 'live_stats' && message.data) {
+          onLiveStats?.(message.data as LiveStats);
+        } else if (message.type === 'status') {
+          handleStatusMessage(message);
+        }
+      };
+
       ws.current.onmessage = (event) => {
+        // Every frame holds one message; under load, versioned clients get
+        // batch messages of several
         try {
-          const message: WebSocketMessage = JSON.parse(event.data);
-          
-          if (message.type === 'log' && message.data) {
-            onMessage?.(message.data as Log);
-          } else if (message.type === 'status') {
-            handleStatusMessage(message);
-          }
+          handleMessage(JSON.parse(event.data));
         } catch (error) {
           // Failed to parse WebSocket message
         }
@@ -80,7 +113,7 @@ export const useWebSocket = ({
     } catch (error) {
       // Failed to connect to WebSocket
     }
-  }, [url, onConnect, onDisconnect, onError, onMessage]);
+  }, [url, onConnect, onDisconnect, onError, onMessage, onLiveStats]);
 
   const handleStatusMessage = (message: WebSocketMessage) => {
     const data = message.data as { status?: string };
@@ -111,6 +144,34 @@ export const useWebSocket = ({
     sendMessage({ type: 'filter', filters });
   }, [sendMessage]);
 
+  const setTail = useCallback((tail: TailSubscription | null) => {
+    sendMessage(tail ? { type: 'tail', data: tail } : { type: 'untail' });
+  }, [sendMessage]);
+
+  const joinChannel = useCallback((channel: string) => {
+    sendMessage({ type: 'join_channel', data: { channel } });
+  }, [sendMessage]);
+
+  const leaveChannel = useCallback(() => {
+    sendMessage({ type: 'leave_channel' });
+  }, [sendMessage]);
+
+  const startRecording = useCallback((name?: string) => {
+    sendMessage({ type: 'record', data: { name } });
+  }, [sendMessage]);
+
+  const stopRecording = useCallback(() => {
+    sendMessage({ type: 'stop_recording' });
+  }, [sendMessage]);
+
+  const subscribeLiveStats = useCallback((interval?: number) => {
+    sendMessage({ type: 'subscribe_live_stats', data: { interval } });
+  }, [sendMessage]);
+
+  const unsubscribeLiveStats = useCallback(() => {
+    sendMessage({ type: 'unsubscribe_live_stats' });
+  }, [sendMessage]);
+
   const disconnect = useCallback(() => {
     if (reconnectTimeout.current) {
       clearTimeout(reconnectTimeout.current);
@@ -143,6 +204,13 @@ export const useWebSocket = ({
     pause,
     resume,
     setFilters,
+    setTail,
+    joinChannel,
+    leaveChannel,
+    startRecording,
+    stopRecording,
+    subscribeLiveStats,
+    unsubscribeLiveStats,
     disconnect,
     reconnect,
   };
@@ -62,6 +62,7 @@ ChartJS.register(
 );
 
 interface ErrorStats {
+  key: string; // category:pattern, as in /errors/{key}/samples
   pattern: string;
   category: string;
   count: number;
@@ -85,6 +86,7 @@ interface ErrorAnomaly {
   type: string;
   pattern: string;
   category: string;
+  service?: string; // of error_rate_baseline anomalies
   current_rate: number;
   threshold: number;
   severity: string;
@@ -270,7 +272,11 @@ export const ErrorDashboard: React.FC = () => {
                 sx={{ mb: 1 }}
               >
                 <AlertTitle>
-                  {anomaly.type === 'high_error_rate' ? 'High Error Rate' : 'Anomaly Detected'}
+                  {anomaly.type === 'high_error_rate'
+                    ? 'High Error Rate'
+                    : anomaly.type === 'error_rate_baseline'
+                      ? 'Unusual Error Rate'
+                      : 'Anomaly Detected'}
                 </AlertTitle>
                 {anomaly.message}
               </Alert>
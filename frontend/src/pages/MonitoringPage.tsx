@@ -73,7 +73,7 @@ interface Alert {
   id: string;
   name: string;
   severity: 'info' | 'warning' | 'critical';
-  status: 'active' | 'resolved';
+  status: 'pending' | 'active' | 'resolved';
   message: string;
   source: string;
   start_time: string;
@@ -0,0 +1,221 @@
+import React, { useEffect, useState } from 'react';
+import {
+  Box,
+  Container,
+  Typography,
+  Paper,
+  IconButton,
+  TextField,
+  InputAdornment,
+  MenuItem,
+  Table,
+  TableBody,
+  TableCell,
+  TableContainer,
+  TableHead,
+  TableRow,
+  Chip,
+  Collapse,
+  LinearProgress,
+  Tooltip,
+} from '@mui/material';
+import {
+  Search,
+  Refresh,
+  Category,
+  KeyboardArrowDown,
+  KeyboardArrowUp,
+} from '@mui/icons-material';
+import { logPatternsApi } from '../services/api';
+import { LogPattern } from '../types/api';
+
+type PatternSort = 'count' | 'first_seen' | 'last_seen';
+
+// Log patterns discovered at ingestion: the templates of the messages,
+// their varying parts replaced with <*>, to spot noisy or new kinds of logs
+export const PatternsPage: React.FC = () => {
+  const [patterns, setPatterns] = useState<LogPattern[]>([]);
+  const [logs, setLogs] = useState(0);
+  const [loading, setLoading] = useState(true);
+  const [search, setSearch] = useState('');
+  const [service, setService] = useState('');
+  const [sort, setSort] = useState<PatternSort>('count');
+  const [expanded, setExpanded] = useState<string | null>(null);
+
+  const fetchPatterns = async () => {
+    try {
+      setLoading(true);
+      const result = await logPatternsApi.list({
+        search: search || undefined,
+        service: service || undefined,
+        sort,
+        limit: 200,
+      });
+      setPatterns(result.patterns);
+      setLogs(result.logs);
+    } catch (error) {
+      console.error('Failed to fetch log patterns:', error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  useEffect(() => {
+    const timer = setTimeout(fetchPatterns, 300);
+    return () => clearTimeout(timer);
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [search, service, sort]);
+
+  const renderTemplate = (template: string) =>
+    template.split(/(<\*>)/).map((part, index) =>
+      part === '<*>' ? (
+        <Box key={index} component="span" sx={{ color: 'primary.main', fontWeight: 'bold' }}>
+          {part}
+        </Box>
+      ) : (
+        <React.Fragment key={index}>{part}</React.Fragment>
+      )
+    );
+
+  return (
+    <Container maxWidth="xl">
+      <Box py={3}>
+        <Box display="flex" justifyContent="space-between" alignItems="center" mb={3}>
+          <Typography variant="h4">
+            <Category sx={{ mr: 1, verticalAlign: 'middle' }} />
+            Log Patterns
+          </Typography>
+          <Box display="flex" alignItems="center" gap={2}>
+            <Typography variant="body2" color="text.secondary">
+              {patterns.length} patterns from {logs.toLocaleString()} logs
+            </Typography>
+            <IconButton onClick={fetchPatterns}>
+              <Refresh />
+            </IconButton>
+          </Box>
+        </Box>
+
+        <Box display="flex" gap={2} mb={3}>
+          <TextField
+            fullWidth
+            placeholder="Search templates..."
+            value={search}
+            onChange={(e) => setSearch(e.target.value)}
+            InputProps={{
+              startAdornment: (
+                <InputAdornment position="start">
+                  <Search />
+                </InputAdornment>
+              ),
+            }}
+          />
+          <TextField
+            label="Service"
+            value={service}
+            onChange={(e) => setService(e.target.value)}
+            sx={{ minWidth: 200 }}
+          />
+          <TextField
+            select
+            label="Sort by"
+            value={sort}
+            onChange={(e) => setSort(e.target.value as PatternSort)}
+            sx={{ minWidth: 180 }}
+          >
+            <MenuItem value="count">Most frequent</MenuItem>
+            <MenuItem value="first_seen">Newest</MenuItem>
+            <MenuItem value="last_seen">Most recent</MenuItem>
+          </TextField>
+        </Box>
+
+        {loading && <LinearProgress sx={{ mb: 1 }} />}
+
+        <TableContainer component={Paper}>
+          <Table size="small">
+            <TableHead>
+              <TableRow>
+                <TableCell />
+                <TableCell>Template</TableCell>
+                <TableCell align="right">Count</TableCell>
+                <TableCell sx={{ width: 160 }}>Share</TableCell>
+                <TableCell>Levels</TableCell>
+                <TableCell>First Seen</TableCell>
+                <TableCell>Last Seen</TableCell>
+              </TableRow>
+            </TableHead>
+            <TableBody>
+              {patterns.map((pattern) => (
+                <React.Fragment key={pattern.id}>
+                  <TableRow hover>
+                    <TableCell>
+                      <IconButton
+                        size="small"
+                        onClick={() => setExpanded(expanded === pattern.id ? null : pattern.id)}
+                      >
+                        {expanded === pattern.id ? <KeyboardArrowUp /> : <KeyboardArrowDown />}
+                      </IconButton>
+                    </TableCell>
+                    <TableCell sx={{ fontFamily: 'monospace', wordBreak: 'break-all' }}>
+                      {renderTemplate(pattern.template)}
+                    </TableCell>
+                    <TableCell align="right">{pattern.count.toLocaleString()}</TableCell>
+                    <TableCell>
+                      <Tooltip title={`${(pattern.share * 100).toFixed(2)}% of logs`}>
+                        <LinearProgress variant="determinate" value={pattern.share * 100} />
+                      </Tooltip>
+                    </TableCell>
+                    <TableCell>
+                      {Object.entries(pattern.levels).map(([level, count]) => (
+                        <Chip
+                          key={level}
+                          size="small"
+                          label={`${level} ${count}`}
+                          color={level === 'error' || level === 'fatal' ? 'error' : level.startsWith('warn') ? 'warning' : 'default'}
+                          sx={{ mr: 0.5 }}
+                        />
+                      ))}
+                    </TableCell>
+                    <TableCell>{new Date(pattern.first_seen).toLocaleString()}</TableCell>
+                    <TableCell>{new Date(pattern.last_seen).toLocaleString()}</TableCell>
+                  </TableRow>
+                  <TableRow>
+                    <TableCell colSpan={7} sx={{ py: 0, borderBottom: expanded === pattern.id ? undefined : 'none' }}>
+                      <Collapse in={expanded === pattern.id} unmountOnExit>
+                        <Box py={2}>
+                          <Typography variant="subtitle2" gutterBottom>
+                            Services: {Object.keys(pattern.services).join(', ') || 'unknown'}
+                          </Typography>
+                          <Typography variant="subtitle2" gutterBottom>
+                            Examples
+                          </Typography>
+                          {pattern.examples.map((example, index) => (
+                            <Typography
+                              key={index}
+                              variant="body2"
+                              sx={{ fontFamily: 'monospace', bgcolor: 'grey.100', p: 1, mb: 0.5, borderRadius: 1 }}
+                            >
+                              {example}
+                            </Typography>
+                          ))}
+                        </Box>
+                      </Collapse>
+                    </TableCell>
+                  </TableRow>
+                </React.Fragment>
+              ))}
+              {!loading && patterns.length === 0 && (
+                <TableRow>
+                  <TableCell colSpan={7} align="center">
+                    <Typography variant="body2" color="text.secondary">
+                      No log patterns found
+                    </Typography>
+                  </TableCell>
+                </TableRow>
+              )}
+            </TableBody>
+          </Table>
+        </TableContainer>
+      </Box>
+    </Container>
+  );
+};
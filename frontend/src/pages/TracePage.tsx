@@ -18,6 +18,8 @@ import {
   Divider,
   LinearProgress,
   Tooltip,
+  FormControlLabel,
+  Switch,
 } from '@mui/material';
 import {
   Search,
@@ -29,18 +31,8 @@ import {
   AccessTime,
 } from '@mui/icons-material';
 import { useNavigate } from 'react-router-dom';
-import api from '../services/api';
-
-interface Trace {
-  trace_id: string;
-  start_time: string;
-  end_time: string;
-  duration: number;
-  service_count: number;
-  span_count: number;
-  error_count: number;
-  services: Record<string, boolean>;
-}
+import api, { tracesApi } from '../services/api';
+import { TraceSummary } from '../types/api';
 
 interface TraceTimeline {
   trace_id: string;
@@ -60,20 +52,29 @@ interface TimelineEvent {
   duration: number;
   status: string;
   logs: number;
+  reported?: boolean;
 }
 
 export const TracePage: React.FC = () => {
   const navigate = useNavigate();
-  const [traces, setTraces] = useState<Trace[]>([]);
+  const [traces, setTraces] = useState<TraceSummary[]>([]);
   const [selectedTrace, setSelectedTrace] = useState<TraceTimeline | null>(null);
   const [loading, setLoading] = useState(true);
   const [searchTerm, setSearchTerm] = useState('');
+  const [service, setService] = useState('');
+  const [minDuration, setMinDuration] = useState('');
+  const [errorsOnly, setErrorsOnly] = useState(false);
 
   const fetchTraces = async () => {
     try {
       setLoading(true);
-      const response = await api.get<{ traces: Trace[] }>('/traces?limit=100');
-      setTraces(response.data.traces || []);
+      const result = await tracesApi.search({
+        service: service || undefined,
+        min_duration: Number(minDuration) > 0 ? Number(minDuration) : undefined,
+        min_errors: errorsOnly ? 1 : undefined,
+        limit: 100,
+      });
+      setTraces(result.traces);
     } catch (error) {
       console.error('Failed to fetch traces:', error);
     } finally {
@@ -91,12 +92,14 @@ export const TracePage: React.FC = () => {
   };
 
   useEffect(() => {
-    fetchTraces();
-  }, []);
+    const timer = setTimeout(fetchTraces, 300);
+    return () => clearTimeout(timer);
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [service, minDuration, errorsOnly]);
 
   const filteredTraces = traces.filter(trace => 
     trace.trace_id.includes(searchTerm) ||
-    Object.keys(trace.services).some(service => 
+    trace.services.some(service => 
       service.toLowerCase().includes(searchTerm.toLowerCase())
     )
   );
@@ -121,7 +124,7 @@ export const TracePage: React.FC = () => {
   };
 
   const formatDuration = (ms: number) => {
-    if (ms < 1000) return `${ms}ms`;
+    if (ms < 1000) return `${Math.round(ms)}ms`;
     if (ms < 60000) return `${(ms / 1000).toFixed(1)}s`;
     return `${(ms / 60000).toFixed(1)}m`;
   };
@@ -154,7 +157,7 @@ export const TracePage: React.FC = () => {
                   </Typography>
                 </Box>
                 <Box sx={{ position: 'relative', height: 30, bgcolor: 'grey.100', borderRadius: 1 }}>
-                  <Tooltip title={`${event.operation} (${formatDuration(event.duration)})`}>
+                  <Tooltip title={`${event.operation} (${formatDuration(event.duration)}${event.reported ? '' : ', from logs'})`}>
                     <Box
                       sx={{
                         position: 'absolute',
@@ -179,14 +182,6 @@ export const TracePage: React.FC = () => {
     );
   };
 
-  if (loading) {
-    return (
-      <Container maxWidth="xl">
-        <LinearProgress />
-      </Container>
-    );
-  }
-
   return (
     <Container maxWidth="xl">
       <Box py={3}>
@@ -200,21 +195,42 @@ export const TracePage: React.FC = () => {
           </IconButton>
         </Box>
 
-        <TextField
-          fullWidth
-          variant="outlined"
-          placeholder="Search by trace ID or service..."
-          value={searchTerm}
-          onChange={(e) => setSearchTerm(e.target.value)}
-          sx={{ mb: 3 }}
-          InputProps={{
-            startAdornment: (
-              <InputAdornment position="start">
-                <Search />
-              </InputAdornment>
-            ),
-          }}
-        />
+        <Box display="flex" alignItems="center" gap={2} mb={3}>
+          <TextField
+            fullWidth
+            variant="outlined"
+            placeholder="Search by trace ID or service..."
+            value={searchTerm}
+            onChange={(e) => setSearchTerm(e.target.value)}
+            InputProps={{
+              startAdornment: (
+                <InputAdornment position="start">
+                  <Search />
+                </InputAdornment>
+              ),
+            }}
+          />
+          <TextField
+            label="Service"
+            value={service}
+            onChange={(e) => setService(e.target.value)}
+            sx={{ minWidth: 200 }}
+          />
+          <TextField
+            label="Min duration (ms)"
+            type="number"
+            value={minDuration}
+            onChange={(e) => setMinDuration(e.target.value)}
+            sx={{ minWidth: 180 }}
+          />
+          <FormControlLabel
+            control={<Switch checked={errorsOnly} onChange={(e) => setErrorsOnly(e.target.checked)} />}
+            label="With errors"
+            sx={{ whiteSpace: 'nowrap' }}
+          />
+        </Box>
+
+        {loading && <LinearProgress sx={{ mb: 1 }} />}
 
         <Grid container spacing={3}>
           <Grid item xs={12} md={selectedTrace ? 4 : 12}>
@@ -248,10 +264,10 @@ export const TracePage: React.FC = () => {
                           secondary={
                             <Box>
                               <Typography variant="caption" display="block">
-                                {Object.keys(trace.services).join(', ')}
+                                {trace.services.join(', ')}
                               </Typography>
                               <Typography variant="caption" color="text.secondary">
-                                {trace.span_count} spans • {formatDuration(trace.duration)}
+                                {trace.span_count} spans • {formatDuration(trace.duration_ms)}
                               </Typography>
                             </Box>
                           }
@@ -8,9 +8,37 @@ import {
   QueryBuilderResponse,
   AvailableFields,
   WidgetDataResponse,
+  DashboardDataResponse,
   DashboardShare,
+  ShareAccessAudit,
+  LayoutBreakpoint,
   ApiResponse,
+  NotificationChannel,
+  NotificationRoute,
+  NotificationDelivery,
+  AlertTransition,
+  AlertEpisode,
+  AlertHistoryFilter,
+  AnomalyRule,
+  AnomalyRuleStatus,
+  SLO,
+  SLOStatus,
+  MetricCardinality,
+  ServiceActivity,
+  CapacityForecast,
+  CapacityRule,
+  BatchQueueStats,
+  ErrorPattern,
+  ErrorGroup,
+  ErrorGroupStatus,
+  ErrorGroupUpdate,
+  ErrorGroupIncident,
+  ServiceBaseline,
+  LogPattern,
+  TraceSummary,
+  TraceTree,
 } from '../types/api';
+import { Log, TailChannel, TailRecording } from '../types/log';
 
 // Base API configuration
 const API_BASE_URL = import.meta.env.VITE_API_URL || 'http://localhost:20002/api/v1';
@@ -109,9 +137,27 @@ export const dashboardApi = {
     return response.data;
   },
 
-  // Get widget data (formatted for charts/metrics)
-  getWidgetData: async (dashboardId: string, widgetId: string): Promise<WidgetDataResponse> => {
-    const response: AxiosResponse<WidgetDataResponse> = await api.get(`/dashboards/${dashboardId}/widgets/${widgetId}/data`);
+  // Get widget data (formatted for charts/metrics), bypassing the cache on refresh
+  getWidgetData: async (dashboardId: string, widgetId: string, refresh = false): Promise<WidgetDataResponse> => {
+    const params = refresh ? { refresh: true } : undefined;
+    const response: AxiosResponse<WidgetDataResponse> = await api.get(`/dashboards/${dashboardId}/widgets/${widgetId}/data`, { params });
+    return response.data;
+  },
+
+  // Get the data of all widgets of a dashboard, or of some, in one request
+  getDashboardData: async (dashboardId: string, widgetIds?: string[], refresh = false): Promise<DashboardDataResponse> => {
+    const params = {
+      ...(widgetIds?.length ? { widgets: widgetIds.join(',') } : {}),
+      ...(refresh ? { refresh: true } : {}),
+    };
+    const response: AxiosResponse<DashboardDataResponse> = await api.get(`/dashboards/${dashboardId}/data`, { params });
+    return response.data;
+  },
+
+  // Get where the widgets of a dashboard sit on a screen width pixels wide
+  getLayout: async (dashboardId: string, width?: number): Promise<LayoutBreakpoint> => {
+    const params = width ? { width } : undefined;
+    const response: AxiosResponse<LayoutBreakpoint> = await api.get(`/dashboards/${dashboardId}/layout`, { params });
     return response.data;
   },
 
@@ -128,9 +174,53 @@ export const dashboardApi = {
     return response.data;
   },
 
+  // List the share links of a dashboard
+  listShares: async (id: string): Promise<DashboardShare[]> => {
+    const response: AxiosResponse<{ shares: DashboardShare[]; total: number }> = await api.get(`/dashboards/${id}/shares`);
+    return response.data.shares;
+  },
+
+  // Change the permissions and expiry of a share link
+  updateShare: async (
+    id: string,
+    shareId: string,
+    permissions: string[],
+    expiresAt?: string
+  ): Promise<DashboardShare> => {
+    const response: AxiosResponse<DashboardShare> = await api.put(`/dashboards/${id}/shares/${shareId}`, {
+      permissions,
+      expires_at: expiresAt,
+    });
+    return response.data;
+  },
+
+  // Revoke a share link
+  revokeShare: async (id: string, shareId: string): Promise<void> => {
+    await api.delete(`/dashboards/${id}/shares/${shareId}`);
+  },
+
+  // Get the audit of a share link's use
+  getShareAccesses: async (id: string, shareId: string): Promise<ShareAccessAudit> => {
+    const response: AxiosResponse<ShareAccessAudit> = await api.get(`/dashboards/${id}/shares/${shareId}/accesses`);
+    return response.data;
+  },
+
   // Get shared dashboard by token
   getShared: async (token: string): Promise<Dashboard> => {
-    const response: AxiosResponse<Dashboard> = await api.get(`/dashboards/shared/${token}`);
+    const response: AxiosResponse<Dashboard> = await api.get(`/shared/${token}`);
+    return response.data;
+  },
+
+  // Get the data of a shared dashboard's widgets, or of some, by token
+  getSharedDashboardData: async (token: string, widgetIds?: string[]): Promise<DashboardDataResponse> => {
+    const params = widgetIds?.length ? { widgets: widgetIds.join(',') } : undefined;
+    const response: AxiosResponse<DashboardDataResponse> = await api.get(`/shared/${token}/data`, { params });
+    return response.data;
+  },
+
+  // Get the data of a shared dashboard's widget by token
+  getSharedWidgetData: async (token: string, widgetId: string): Promise<WidgetDataResponse> => {
+    const response: AxiosResponse<WidgetDataResponse> = await api.get(`/shared/${token}/widgets/${widgetId}/data`);
     return response.data;
   },
 };
@@ -204,6 +294,370 @@ export const savedQueriesApi = {
   },
 };
 
+// Live tail channels API; changes need an admin
+export const tailChannelsApi = {
+  list: async (): Promise<TailChannel[]> => {
+    const response: AxiosResponse<{ channels: TailChannel[] }> = await api.get('/ws/channels');
+    return response.data.channels || [];
+  },
+
+  get: async (name: string): Promise<TailChannel> => {
+    const response: AxiosResponse<TailChannel> = await api.get(`/ws/channels/${name}`);
+    return response.data;
+  },
+
+  create: async (channel: Omit<TailChannel, 'created_at' | 'updated_at'>): Promise<TailChannel> => {
+    const response: AxiosResponse<TailChannel> = await api.post('/ws/channels', channel);
+    return response.data;
+  },
+
+  update: async (name: string, channel: Omit<TailChannel, 'name' | 'created_at' | 'updated_at'>): Promise<TailChannel> => {
+    const response: AxiosResponse<TailChannel> = await api.put(`/ws/channels/${name}`, channel);
+    return response.data;
+  },
+
+  delete: async (name: string): Promise<void> => {
+    await api.delete(`/ws/channels/${name}`);
+  },
+};
+
+// Live tail recordings API
+export const tailRecordingsApi = {
+  // List the recordings of the user
+  list: async (): Promise<TailRecording[]> => {
+    const response: AxiosResponse<{ recordings: TailRecording[] }> = await api.get('/ws/recordings');
+    return response.data.recordings || [];
+  },
+
+  // Get a recording and the logs it captured
+  get: async (id: string): Promise<{ recording: TailRecording; logs: Log[] }> => {
+    const response: AxiosResponse<{ recording: TailRecording; logs: Log[] }> = await api.get(`/ws/recordings/${id}`);
+    return response.data;
+  },
+
+  // Delete a recording
+  delete: async (id: string): Promise<void> => {
+    await api.delete(`/ws/recordings/${id}`);
+  },
+
+  // Export a recording as csv, json or xlsx
+  export: async (id: string, format: 'csv' | 'json' | 'xlsx' = 'csv'): Promise<Blob> => {
+    const response: AxiosResponse<Blob> = await api.get(`/ws/recordings/${id}/export`, {
+      params: { format },
+      responseType: 'blob',
+    });
+    return response.data;
+  },
+};
+
+// Alert history API
+export const alertHistoryApi = {
+  // State changes of alerts, newest first
+  list: async (filter: AlertHistoryFilter = {}): Promise<AlertTransition[]> => {
+    const response: AxiosResponse<{ transitions: AlertTransition[] }> = await api.get('/monitoring/alerts/history', {
+      params: filter,
+    });
+    return response.data.transitions || [];
+  },
+
+  // When alerts were pending, fired and resolved, over the last 24 hours by default
+  timeline: async (filter: AlertHistoryFilter = {}): Promise<AlertEpisode[]> => {
+    const response: AxiosResponse<{ episodes: AlertEpisode[] }> = await api.get('/monitoring/alerts/timeline', {
+      params: filter,
+    });
+    return response.data.episodes || [];
+  },
+};
+
+// Anomaly alert rules API
+type AnomalyRuleInput = Partial<Omit<AnomalyRule, 'created_by' | 'created_at'>> & Pick<AnomalyRule, 'name'>;
+
+export const anomalyRulesApi = {
+  list: async (): Promise<AnomalyRuleStatus[]> => {
+    const response: AxiosResponse<{ rules: AnomalyRuleStatus[] }> = await api.get('/monitoring/alerts/anomaly-rules');
+    return response.data.rules || [];
+  },
+
+  get: async (name: string): Promise<AnomalyRuleStatus> => {
+    const response: AxiosResponse<AnomalyRuleStatus> = await api.get(`/monitoring/alerts/anomaly-rules/${encodeURIComponent(name)}`);
+    return response.data;
+  },
+
+  create: async (rule: AnomalyRuleInput): Promise<AnomalyRule> => {
+    const response: AxiosResponse<AnomalyRule> = await api.post('/monitoring/alerts/anomaly-rules', rule);
+    return response.data;
+  },
+
+  delete: async (name: string): Promise<void> => {
+    await api.delete(`/monitoring/alerts/anomaly-rules/${encodeURIComponent(name)}`);
+  },
+};
+
+// Service activity API, backing absence alerts
+export const serviceActivityApi = {
+  // Silent services first
+  list: async (absentOnly = false): Promise<ServiceActivity[]> => {
+    const response: AxiosResponse<{ services: ServiceActivity[] }> = await api.get('/monitoring/services/activity', {
+      params: absentOnly ? { absent: true } : undefined,
+    });
+    return response.data.services || [];
+  },
+};
+
+// Capacity forecast and capacity alert rules API
+type CapacityRuleInput = Omit<CapacityRule, 'created_by' | 'created_at'>;
+
+export const capacityApi = {
+  forecast: async (days = 30): Promise<CapacityForecast> => {
+    const response: AxiosResponse<{ forecast: CapacityForecast }> = await api.get('/storage/forecast', { params: { days } });
+    return response.data.forecast;
+  },
+
+  listRules: async (): Promise<CapacityRule[]> => {
+    const response: AxiosResponse<{ rules: CapacityRule[] }> = await api.get('/monitoring/alerts/capacity-rules');
+    return response.data.rules || [];
+  },
+
+  createRule: async (rule: CapacityRuleInput): Promise<CapacityRule> => {
+    const response: AxiosResponse<CapacityRule> = await api.post('/monitoring/alerts/capacity-rules', rule);
+    return response.data;
+  },
+
+  deleteRule: async (name: string): Promise<void> => {
+    await api.delete(`/monitoring/alerts/capacity-rules/${encodeURIComponent(name)}`);
+  },
+};
+
+// Error groups API
+export const errorGroupsApi = {
+  // Most frequent first, or most recent first with sort 'last_seen'
+  list: async (params?: {
+    service?: string;
+    status?: ErrorGroupStatus;
+    assignee?: string;
+    sort?: 'count' | 'last_seen';
+    limit?: number;
+  }): Promise<ErrorGroup[]> => {
+    const response: AxiosResponse<{ groups: ErrorGroup[] }> = await api.get('/errors/groups', { params });
+    return response.data.groups || [];
+  },
+
+  get: async (fingerprint: string): Promise<ErrorGroup> => {
+    const response: AxiosResponse<ErrorGroup> = await api.get(`/errors/groups/${encodeURIComponent(fingerprint)}`);
+    return response.data;
+  },
+
+  incident: async (fingerprint: string): Promise<ErrorGroupIncident> => {
+    const response: AxiosResponse<ErrorGroupIncident> = await api.get(
+      `/errors/groups/${encodeURIComponent(fingerprint)}/incident`
+    );
+    return response.data;
+  },
+
+  update: async (fingerprint: string, update: ErrorGroupUpdate): Promise<ErrorGroup> => {
+    const response: AxiosResponse<ErrorGroup> = await api.patch(`/errors/groups/${encodeURIComponent(fingerprint)}`, update);
+    return response.data;
+  },
+};
+
+// Error rate baselines API
+export const errorBaselinesApi = {
+  // Most deviating first
+  list: async (params?: { deviating?: boolean }): Promise<ServiceBaseline[]> => {
+    const response: AxiosResponse<{ baselines: ServiceBaseline[] }> = await api.get('/errors/baselines', { params });
+    return response.data.baselines || [];
+  },
+
+  get: async (service: string): Promise<ServiceBaseline> => {
+    const response: AxiosResponse<ServiceBaseline> = await api.get(`/errors/baselines/${encodeURIComponent(service)}`);
+    return response.data;
+  },
+};
+
+// Traces API
+export const tracesApi = {
+  // Latest started first; durations are in milliseconds, times RFC3339
+  search: async (params?: {
+    service?: string;
+    min_duration?: number;
+    max_duration?: number;
+    min_errors?: number;
+    start?: string;
+    end?: string;
+    limit?: number;
+  }): Promise<{ traces: TraceSummary[]; total: number }> => {
+    const response: AxiosResponse<{ traces: TraceSummary[]; total: number }> = await api.get('/traces', {
+      params,
+    });
+    return { traces: response.data.traces || [], total: response.data.total };
+  },
+
+  get: async (traceId: string): Promise<TraceTree> => {
+    const response: AxiosResponse<TraceTree> = await api.get(`/traces/${encodeURIComponent(traceId)}`);
+    return response.data;
+  },
+};
+
+// Log patterns API
+export const logPatternsApi = {
+  // Most frequent first, newest first with sort 'first_seen'
+  list: async (params?: {
+    service?: string;
+    level?: string;
+    search?: string;
+    sort?: 'count' | 'first_seen' | 'last_seen';
+    limit?: number;
+  }): Promise<{ patterns: LogPattern[]; logs: number }> => {
+    const response: AxiosResponse<{ patterns: LogPattern[]; logs: number }> = await api.get('/log-patterns', {
+      params,
+    });
+    return { patterns: response.data.patterns || [], logs: response.data.logs };
+  },
+
+  get: async (id: string): Promise<LogPattern> => {
+    const response: AxiosResponse<LogPattern> = await api.get(`/log-patterns/${encodeURIComponent(id)}`);
+    return response.data;
+  },
+};
+
+// Error detection patterns API
+type ErrorPatternInput = Partial<Omit<ErrorPattern, 'matches' | 'created_by' | 'created_at' | 'updated_at'>> &
+  Pick<ErrorPattern, 'name' | 'regex'>;
+
+export const errorPatternsApi = {
+  list: async (): Promise<ErrorPattern[]> => {
+    const response: AxiosResponse<{ patterns: ErrorPattern[] }> = await api.get('/errors/patterns');
+    return response.data.patterns || [];
+  },
+
+  create: async (pattern: ErrorPatternInput): Promise<ErrorPattern> => {
+    const response: AxiosResponse<ErrorPattern> = await api.post('/errors/patterns', pattern);
+    return response.data;
+  },
+
+  update: async (name: string, pattern: Omit<ErrorPatternInput, 'name'>): Promise<ErrorPattern> => {
+    const response: AxiosResponse<ErrorPattern> = await api.put(`/errors/patterns/${encodeURIComponent(name)}`, pattern);
+    return response.data;
+  },
+
+  delete: async (name: string): Promise<void> => {
+    await api.delete(`/errors/patterns/${encodeURIComponent(name)}`);
+  },
+};
+
+// Service level objectives API
+type SLOInput = Omit<SLO, 'created_by' | 'created_at' | 'updated_at' | 'window_days'> & { window_days?: number };
+
+export const sloApi = {
+  list: async (): Promise<SLO[]> => {
+    const response: AxiosResponse<{ slos: SLO[] }> = await api.get('/slos');
+    return response.data.slos || [];
+  },
+
+  get: async (name: string): Promise<SLO> => {
+    const response: AxiosResponse<SLO> = await api.get(`/slos/${encodeURIComponent(name)}`);
+    return response.data;
+  },
+
+  create: async (slo: SLOInput): Promise<SLO> => {
+    const response: AxiosResponse<SLO> = await api.post('/slos', slo);
+    return response.data;
+  },
+
+  update: async (name: string, slo: SLOInput): Promise<SLO> => {
+    const response: AxiosResponse<SLO> = await api.put(`/slos/${encodeURIComponent(name)}`, slo);
+    return response.data;
+  },
+
+  delete: async (name: string): Promise<void> => {
+    await api.delete(`/slos/${encodeURIComponent(name)}`);
+  },
+
+  // Compliance, error budget left and burn rates of every SLO
+  statuses: async (): Promise<SLOStatus[]> => {
+    const response: AxiosResponse<{ statuses: SLOStatus[] }> = await api.get('/slos/status');
+    return response.data.statuses || [];
+  },
+
+  status: async (name: string): Promise<SLOStatus> => {
+    const response: AxiosResponse<SLOStatus> = await api.get(`/slos/${encodeURIComponent(name)}/status`);
+    return response.data;
+  },
+};
+
+// Metric cardinality and ingestion internals API
+export const metricsApi = {
+  // Labeled metrics with the most series first
+  cardinality: async (limit?: number): Promise<{ metrics: MetricCardinality[]; total_series: number }> => {
+    const response: AxiosResponse<{ metrics: MetricCardinality[]; total_series: number }> = await api.get(
+      '/monitoring/metrics/cardinality',
+      { params: { limit } }
+    );
+    return { metrics: response.data.metrics || [], total_series: response.data.total_series };
+  },
+
+  ingestion: async (): Promise<BatchQueueStats & { flush_interval_ms: number }> => {
+    const response: AxiosResponse<{ batch_queue: BatchQueueStats; flush_interval_ms: number }> = await api.get(
+      '/monitoring/ingestion'
+    );
+    return { ...response.data.batch_queue, flush_interval_ms: response.data.flush_interval_ms };
+  },
+};
+
+// Alert notifications API
+type NotificationChannelInput = Omit<NotificationChannel, 'created_by' | 'created_at' | 'updated_at'>;
+
+export const notificationsApi = {
+  listChannels: async (): Promise<NotificationChannel[]> => {
+    const response: AxiosResponse<{ channels: NotificationChannel[] }> = await api.get('/notifications/channels');
+    return response.data.channels || [];
+  },
+
+  getChannel: async (name: string): Promise<NotificationChannel> => {
+    const response: AxiosResponse<NotificationChannel> = await api.get(`/notifications/channels/${name}`);
+    return response.data;
+  },
+
+  createChannel: async (channel: NotificationChannelInput): Promise<NotificationChannel> => {
+    const response: AxiosResponse<NotificationChannel> = await api.post('/notifications/channels', channel);
+    return response.data;
+  },
+
+  updateChannel: async (name: string, channel: Omit<NotificationChannelInput, 'name'>): Promise<NotificationChannel> => {
+    const response: AxiosResponse<NotificationChannel> = await api.put(`/notifications/channels/${name}`, channel);
+    return response.data;
+  },
+
+  deleteChannel: async (name: string): Promise<void> => {
+    await api.delete(`/notifications/channels/${name}`);
+  },
+
+  // Send a test notification to a saved channel
+  testChannel: async (name: string): Promise<void> => {
+    await api.post(`/notifications/channels/${name}/test`);
+  },
+
+  // Send a test notification to a channel before saving it
+  testConfig: async (channel: NotificationChannelInput): Promise<void> => {
+    await api.post('/notifications/channels/test', channel);
+  },
+
+  getRoutes: async (): Promise<NotificationRoute[]> => {
+    const response: AxiosResponse<{ routes: NotificationRoute[] }> = await api.get('/notifications/routes');
+    return response.data.routes || [];
+  },
+
+  setRoutes: async (routes: NotificationRoute[]): Promise<NotificationRoute[]> => {
+    const response: AxiosResponse<{ routes: NotificationRoute[] }> = await api.put('/notifications/routes', { routes });
+    return response.data.routes || [];
+  },
+
+  listDeliveries: async (): Promise<NotificationDelivery[]> => {
+    const response: AxiosResponse<{ deliveries: NotificationDelivery[] }> = await api.get('/notifications/deliveries');
+    return response.data.deliveries || [];
+  },
+};
+
 // WebSocket URL for real-time features
 export const getWebSocketUrl = (): string => {
   const wsProtocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
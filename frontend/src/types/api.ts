@@ -1,5 +1,7 @@
 // API Types for Click-Lite Log Analytics
 
+import { Log } from './log';
+
 export interface LogFilter {
   field: string;
   operator: string;
@@ -52,6 +54,7 @@ export interface QueryBuilder {
   id?: string;
   name: string;
   description?: string;
+  tags?: string[];
   fields: QueryField[];
   filters: QueryBuilderFilter[];
   aggregations: QueryAggregation[];
@@ -59,6 +62,7 @@ export interface QueryBuilder {
   order_by: QueryOrderBy[];
   limit?: number;
   time_range?: QueryTimeRange;
+  freshness?: number; // seconds a cached result may be old
   generated_sql?: string;
   created_at?: string;
   updated_at?: string;
@@ -121,6 +125,47 @@ export interface WidgetConfig {
   value_format?: string;
   threshold?: ThresholdConfig;
   custom_options?: Record<string, any>;
+  value_field?: string;
+  label_field?: string;
+  time_field?: string;
+  bins?: number;
+  max_slices?: number;
+  donut?: boolean;
+  log_filters?: LogFilter[];
+  annotation_tags?: string[];
+  alert?: WidgetAlert;
+  drill_down?: WidgetDrillDown;
+  // Markdown of a text widget, with {{variable}} and {{query:id}} placeholders
+  content?: string;
+}
+
+// The content of a text widget, filled in and rendered on the server
+export interface TextData {
+  markdown: string;
+  html: string;
+  errors?: string[];
+}
+
+export interface WidgetAlert {
+  field?: string;
+  operator: '>' | '>=' | '<' | '<=';
+  warning?: number;
+  critical?: number;
+  interval?: number;
+  disabled?: boolean;
+}
+
+export interface WidgetDrillDown {
+  field?: string;
+  query?: string;
+  bucket?: number;
+}
+
+export interface DrillDownLink {
+  url: string;
+  query: string;
+  start_time: string;
+  end_time: string;
 }
 
 export interface WidgetDataSource {
@@ -133,13 +178,14 @@ export interface WidgetDataSource {
 
 export interface DashboardWidget {
   id: string;
-  type: 'chart' | 'table' | 'metric' | 'text';
+  type: 'chart' | 'table' | 'metric' | 'text' | 'histogram' | 'heatmap' | 'pie' | 'log_stream';
   title: string;
   position: WidgetPosition;
   size: WidgetSize;
   config: WidgetConfig;
   data_source: WidgetDataSource;
   refresh_rate?: number;
+  ignore_dashboard_time_range?: boolean;
 }
 
 export interface DashboardLayout {
@@ -147,6 +193,23 @@ export interface DashboardLayout {
   row_height: number;
   grid_gap: number;
   auto_layout: boolean;
+  // Placements for screens narrower than the widgets' own layout
+  breakpoints?: LayoutBreakpoint[];
+}
+
+export interface LayoutBreakpoint {
+  name: string;
+  max_width: number; // pixels
+  columns: number;
+  placements: WidgetPlacement[];
+}
+
+export interface WidgetPlacement {
+  widget_id: string;
+  x: number;
+  y: number;
+  width: number;
+  height: number;
 }
 
 export interface DashboardVariable {
@@ -174,19 +237,120 @@ export interface Dashboard {
   settings: DashboardSettings;
   shared_with?: string[];
   is_public: boolean;
+  folder_id?: string;
+  permissions?: DashboardPermission[];
+  annotation_queries?: AnnotationQuery[];
+  is_template?: boolean;
+  category?: string;
+  created_at?: string;
+  updated_at?: string;
+  created_by?: string;
+}
+
+export interface DashboardFolder {
+  id?: string;
+  name: string;
+  description?: string;
+  parent_id?: string;
+  shared_with?: string[];
+  is_public: boolean;
+  permissions?: DashboardPermission[];
   created_at?: string;
   updated_at?: string;
   created_by?: string;
 }
 
+export type DashboardRole = 'viewer' | 'editor' | 'admin';
+
+export interface DashboardPermission {
+  user?: string;
+  team?: string;
+  role: DashboardRole;
+}
+
+export interface Annotation {
+  id: string;
+  dashboard_id?: string;
+  kind: 'event' | 'deploy' | 'query' | 'alert';
+  title: string;
+  text?: string;
+  tags?: string[];
+  time: string;
+  end_time?: string;
+  query_id?: string;
+  created_at?: string;
+  created_by?: string;
+}
+
+export interface AnnotationQuery {
+  id?: string;
+  name: string;
+  data_source: WidgetDataSource;
+  time_field?: string;
+  end_field?: string;
+  title_field?: string;
+  text_field?: string;
+  tags?: string[];
+  disabled?: boolean;
+}
+
+export interface DashboardSnapshot {
+  id: string;
+  dashboard_id: string;
+  name: string;
+  dashboard: Dashboard;
+  time_range?: QueryTimeRange;
+  widgets: SnapshotWidget[];
+  share_token: string;
+  expires_at?: string;
+  created_at: string;
+  created_by: string;
+}
+
+export interface SnapshotWidget {
+  widget_id: string;
+  type: DashboardWidget['type'];
+  data?: any;
+  annotations?: Annotation[];
+  error?: string;
+}
+
+export interface WidgetUpdate {
+  dashboard_id: string;
+  widget_id: string;
+  type: string;
+  data?: unknown;
+  error?: string;
+  updated_at: string;
+}
+
 export interface DashboardShare {
   id: string;
   dashboard_id: string;
   share_token: string;
   expires_at?: string;
   permissions: string[];
+  revoked_at?: string;
   created_at: string;
   created_by: string;
+  updated_at?: string;
+}
+
+// A use of a share link, or an attempt refused because it expired or was revoked
+export interface ShareAccess {
+  at: string;
+  resource: string;
+  remote_addr?: string;
+  user_agent?: string;
+  denied?: string;
+}
+
+export interface ShareAccessAudit {
+  share_id: string;
+  access_count: number;
+  denied_count: number;
+  last_accessed_at?: string;
+  accesses: ShareAccess[]; // most recent first
 }
 
 export interface ChartDataset {
@@ -213,13 +377,417 @@ export interface MetricData {
   metadata?: Record<string, any>;
 }
 
+// How fresh widget data served from the server's widget cache is
+export interface WidgetCacheInfo {
+  cached_at: string;
+  age: number; // seconds
+  stale: boolean; // being refreshed in the background
+}
+
 export interface WidgetDataResponse {
   widget_id: string;
   type: string;
   data: ChartData | MetricData | Record<string, any>[];
+  cache?: WidgetCacheInfo;
+}
+
+// The data of one widget in a whole dashboard's data, or why it has none
+export interface DashboardWidgetResult {
+  widget_id: string;
+  type: string;
+  data?: ChartData | MetricData | Record<string, any>[];
+  annotations?: Annotation[];
+  cache?: WidgetCacheInfo;
+  error?: string;
+}
+
+export interface DashboardDataResponse {
+  dashboard_id: string;
+  time_range?: QueryTimeRange;
+  widgets: DashboardWidgetResult[];
 }
 
 // API Response Types
+// Alert notification types
+export type NotificationChannelType = 'slack' | 'email' | 'pagerduty' | 'webhook';
+export type AlertSeverity = 'info' | 'warning' | 'critical';
+
+// Secrets come back masked as ********; sending the mask keeps them
+export interface NotificationChannel {
+  name: string;
+  type: NotificationChannelType;
+  description?: string;
+  slack?: {
+    webhook_url: string;
+    channel?: string;
+    username?: string;
+  };
+  email?: {
+    host: string;
+    port?: number;
+    username?: string;
+    password?: string;
+    from: string;
+    to: string[];
+  };
+  pagerduty?: {
+    routing_key: string;
+    url?: string;
+  };
+  webhook?: {
+    url: string;
+    method?: 'POST' | 'PUT' | 'PATCH';
+    headers?: Record<string, string>;
+    body_template?: string;
+    content_type?: string;
+  };
+  created_by?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface NotificationRoute {
+  name: string;
+  severities?: AlertSeverity[];
+  match?: Record<string, string>; // label -> shell pattern
+  channels: string[];
+  send_resolved: boolean;
+  continue: boolean;
+}
+
+export interface NotificationDelivery {
+  channel: string;
+  type: NotificationChannelType;
+  route?: string;
+  alert_id: string;
+  alert_name: string;
+  status: 'firing' | 'resolved';
+  attempts: number;
+  error?: string;
+  sent_at: string;
+}
+
+// Alert history types
+export type AlertStatus = 'pending' | 'active' | 'resolved';
+
+export interface AlertTransition {
+  alert_id: string;
+  name: string;
+  severity: AlertSeverity;
+  source: string;
+  from?: AlertStatus; // absent for a new alert
+  to: AlertStatus;
+  time: string;
+  value?: number;
+  message: string;
+  details?: unknown;
+}
+
+export interface AlertEpisode {
+  alert_id: string;
+  name: string;
+  severity: AlertSeverity;
+  source: string;
+  pending_at?: string;
+  fired_at?: string;
+  resolved_at?: string;
+  status: AlertStatus;
+  message: string;
+  duration_ms: number;
+}
+
+export interface AlertHistoryFilter {
+  name?: string;
+  severity?: AlertSeverity;
+  source?: string;
+  since?: string; // RFC3339
+  until?: string;
+  limit?: number;
+}
+
+// Alert rules flagging a metric or query series that deviates from its own baseline
+export type AnomalyMethod = 'zscore' | 'seasonal';
+
+export interface AnomalyRule {
+  name: string;
+  description?: string;
+  severity: AlertSeverity;
+  metric?: string;
+  labels?: Record<string, string>;
+  query?: string; // SELECT returning one number, e.g. a count of recent logs
+  method: AnomalyMethod;
+  season?: 'daily' | 'weekly';
+  threshold: number; // standard deviations
+  direction: 'above' | 'below' | 'both';
+  window: number; // data points kept, per hour of the season for seasonal rules
+  for?: number; // seconds
+  cooldown?: number; // seconds
+  created_by?: string;
+  created_at: string;
+}
+
+export interface AnomalyBaseline {
+  mean: number;
+  std_dev: number;
+  samples: number;
+  ready: boolean;
+  last_value?: number;
+  last_z_score?: number;
+  last_check?: string;
+}
+
+export interface AnomalyRuleStatus extends AnomalyRule {
+  baseline: AnomalyBaseline;
+}
+
+// Service level objectives computed from log counts
+export interface SLO {
+  name: string;
+  description?: string;
+  service?: string; // all services when empty
+  table?: string;
+  objective: number; // percent of good logs, e.g. 99.9
+  window_days: number;
+  bad_levels?: string[];
+  created_by?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface SLOWindowStatus {
+  window: string; // e.g. "5m", "1h", "3d"
+  total: number;
+  bad: number;
+  error_rate: number;
+  burn_rate: number; // 1 spends exactly the error budget over the SLO window
+}
+
+export interface SLOStatus {
+  slo: string;
+  service?: string;
+  objective: number;
+  window_days: number;
+  total: number;
+  bad: number;
+  compliance: number; // percent
+  error_budget_remaining: number; // fraction, negative once the objective is missed
+  windows: SLOWindowStatus[];
+  fast_burn: boolean;
+  slow_burn: boolean;
+  updated_at: string;
+  error?: string;
+}
+
+// Labeled series of a metric, see /monitoring/metrics/cardinality
+export interface MetricCardinality {
+  name: string;
+  type: 'counter' | 'gauge' | 'histogram';
+  series: number;
+  limit: number; // 0 when unlimited
+  overflowed_updates: number; // updates of label sets over the limit
+  labels: Record<string, number>; // distinct values per label
+}
+
+export interface ServiceActivity {
+  service: string;
+  first_seen: string;
+  last_seen: string;
+  logs: number; // since the server started
+  absence_after_seconds: number; // 0 when the service is not monitored
+  monitored: boolean;
+  absent: boolean;
+}
+
+// Errors grouped by message template, numbers, IDs and addresses replaced
+export interface ErrorGroup {
+  fingerprint: string; // in the error_fingerprint attribute of its logs
+  template: string;
+  count: number;
+  first_seen: string;
+  last_seen: string;
+  services: Record<string, number>;
+  detections: string[]; // e.g. level:error, database:QueryError
+  last_message: string;
+  last_log_id?: string;
+  last_trace_id?: string;
+  trace_ids?: string[]; // latest distinct traces the error occurred in, oldest first
+  hourly: number[]; // occurrences in each of the last 24 hours, current hour last
+  trend: 'new' | 'increasing' | 'decreasing' | 'stable';
+  status: ErrorGroupStatus;
+  assignee?: string;
+  resolved_at?: string;
+  resolved_by?: string;
+  resolved_in_version?: string; // older versions do not reopen the group
+  regressions?: number; // times reopened after being resolved
+  regressed_at?: string;
+  updated_at?: string;
+  updated_by?: string;
+}
+
+// An error group with the traces it occurred in, for the incident view
+export interface ErrorGroupIncident {
+  group: ErrorGroup;
+  traces: TraceSummary[]; // newest first
+  missing_traces: number; // neither cached nor stored anymore
+  services: Record<string, number>; // traces going through each service
+  failed_services: Record<string, number>; // traces with a span in error in each service
+}
+
+export interface TraceSummary {
+  trace_id: string;
+  start_time: string;
+  end_time: string;
+  duration_ms: number;
+  services: string[];
+  failed_services?: string[];
+  span_count: number;
+  error_count: number;
+  root_service?: string;
+  root_operation?: string;
+}
+
+// Trace with its spans nested under the spans that called them
+export interface TraceTree extends TraceSummary {
+  spans: SpanNode[]; // root spans, and spans whose parent logged nothing
+}
+
+export interface SpanNode {
+  span_id: string;
+  parent_id?: string;
+  service: string;
+  operation: string;
+  start_time: string;
+  end_time: string;
+  duration_ms: number;
+  status: string;
+  attributes?: Record<string, any>;
+  logs: Log[];
+  children?: SpanNode[];
+  reported?: boolean; // timed by the service over OTLP, not derived from logs
+}
+
+export type ErrorGroupStatus = 'new' | 'acknowledged' | 'resolved' | 'muted';
+
+export interface ErrorGroupUpdate {
+  status?: ErrorGroupStatus;
+  assignee?: string; // '' to unassign
+  resolved_in_version?: string; // with status 'resolved' only
+}
+
+// Error rate of a service in the current hour, errors per log, against the
+// rates it has on the same hour of previous days and weeks
+export interface ServiceBaseline {
+  service: string;
+  hour: string; // start of the current hour, UTC
+  logs: number;
+  errors: number;
+  error_rate: number;
+  daily?: RateBaseline; // same hour of the previous days
+  weekly?: RateBaseline; // same hour of the week of the previous weeks
+  day_over_day?: number; // error rate over the one of the same hour yesterday
+  score?: number; // z-score against the weekly baseline, or the daily one
+  deviating: boolean;
+}
+
+export interface RateBaseline {
+  mean: number;
+  std_dev: number;
+  samples: number;
+  ratio?: number; // 3 for an error rate three times the usual one
+  expected_errors: number; // at the mean rate, for the logs of the current hour
+  z_score: number;
+}
+
+// Template shared by log messages, discovered at ingestion, with the
+// tokens that vary between them replaced with <*>
+export interface LogPattern {
+  id: string;
+  template: string;
+  count: number;
+  first_seen: string;
+  last_seen: string;
+  services: Record<string, number>;
+  levels: Record<string, number>;
+  examples: string[]; // latest distinct messages, oldest first
+  share: number; // of the logs mined, from 0 to 1
+}
+
+// Error detection patterns, matched against the message of ingested logs
+export interface ErrorPattern {
+  name: string;
+  regex: string;
+  severity: 'low' | 'medium' | 'high' | 'critical';
+  category: string;
+  description?: string;
+  enabled: boolean;
+  matches: number; // since the server started
+  created_by?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+// State of the ingestion batch queue, see /monitoring/ingestion
+export interface BatchQueueStats {
+  depth: number; // logs waiting to be written
+  batch_size: number;
+  in_flight_batches: number;
+  in_flight_logs: number;
+  last_flush: string;
+  last_error?: string;
+  last_error_at?: string;
+  consecutive_failures: number;
+  dropped_logs: number;
+  flushed_batches: number;
+  flush_latency: {
+    samples: number;
+    p50_ms: number;
+    p95_ms: number;
+    p99_ms: number;
+    max_ms: number;
+  };
+}
+
+// Disk usage projected from the growth and retention of each table
+export interface RetentionForecast {
+  table: string;
+  retention_days: number; // 0 when data is kept forever
+  bytes: number;
+  rows: number;
+  oldest_days: number; // 0 when unknown
+  growth_bytes_per_day: number;
+  growth_rows_per_day: number;
+  days_until_steady?: number;
+  steady_state_bytes?: number;
+  projected_bytes: number; // at the end of the forecast
+  days_until_full?: number; // when this table alone would fill the disk
+}
+
+export interface CapacityForecast {
+  generated_at: string;
+  days: number;
+  disk_total_bytes: number;
+  disk_free_bytes: number;
+  history_hours: number;
+  growth_known: boolean; // false until there is enough history
+  days_until_full?: number;
+  full_at?: string;
+  policies: RetentionForecast[];
+  projection: { day: number; time: string; used_bytes: number }[];
+}
+
+// Alert rules firing when the disk is forecast to fill up within a number of days
+export interface CapacityRule {
+  name: string;
+  description?: string;
+  severity: AlertSeverity;
+  table?: string; // counts only this table's growth when set
+  days: number;
+  for?: number; // seconds
+  cooldown?: number; // seconds
+  created_by?: string;
+  created_at: string;
+}
+
 export interface ApiResponse<T> {
   data?: T;
   error?: string;
@@ -237,6 +805,9 @@ export const FILTER_OPERATORS = [
   { value: 'not_equals', label: 'Not Equals' },
   { value: 'contains', label: 'Contains' },
   { value: 'not_contains', label: 'Not Contains' },
+  { value: 'icontains', label: 'Contains (Case Insensitive)' },
+  { value: 'matches_regex', label: 'Matches Regex' },
+  { value: 'not_matches_regex', label: 'Not Matches Regex' },
   { value: 'greater_than', label: 'Greater Than' },
   { value: 'less_than', label: 'Less Than' },
   { value: 'greater_equal', label: 'Greater or Equal' },
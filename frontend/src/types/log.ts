@@ -15,9 +15,84 @@ export interface LogFilter {
   value: string;
 }
 
+// Server-side filter of the live logs a connection receives
+export interface TailSubscription {
+  services?: string[];
+  levels?: string[];
+  attributes?: LogFilter[];
+  query?: string;
+  since?: number;
+}
+
+// A named tail defined on the server, bundling a filter and highlight rules
+export interface TailChannel {
+  name: string;
+  description?: string;
+  filter: TailSubscription;
+  highlights?: {
+    name: string;
+    pattern: string;
+    field?: string;
+    color?: string;
+  }[];
+  created_by?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+// A match of a channel's highlight rule in a live log; offsets index the
+// field's string
+export interface LogHighlight {
+  rule: string;
+  field: string;
+  start: number;
+  end: number;
+  color?: string;
+}
+
+// A recording of the live logs a connection received
+export interface TailRecording {
+  id: string;
+  name?: string;
+  user_id: string;
+  started_at: string;
+  stopped_at?: string;
+  log_count: number;
+  truncated: boolean;
+}
+
+// Rates of the logs ingested over the last minute, pushed by the server
+export interface LiveStats {
+  window_seconds: number;
+  total: number;
+  logs_per_second: number;
+  by_level: Record<string, number>;
+  services: {
+    service: string;
+    logs: number;
+    errors: number;
+    logs_per_second: number;
+    error_rate: number;
+  }[];
+  error_rate: number;
+  at: string;
+}
+
+export interface WebSocketError {
+  code: 'invalid_message' | 'unsupported_version' | 'unknown_type' | 'request_failed';
+  message: string;
+  ref?: string;
+}
+
+// Envelope of WebSocket messages; see GET /api/v1/ws/schema
 export interface WebSocketMessage {
+  v?: number;
   type: string;
+  id?: string;
   action?: string;
+  seq?: number;
+  channel?: string;
+  highlights?: LogHighlight[];
   data?: unknown;
   filters?: LogFilter[];
 }